@@ -0,0 +1,548 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/env"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+)
+
+const (
+	logInfo = 1
+	logWarn = 2
+	logErr  = 3
+)
+
+/*
+SessionPolicy controls how long landlord and tenant sessions last.
+
+- IdleTimeout: How long a session stays valid without any requests. Every request slides the expiry forward by this much.
+
+- MaxLifetime: The longest a session can last from login, however active the user is.
+
+- RememberMeLifetime: The idle timeout and maximum lifetime used instead when the user ticks "remember me" on the login form.
+*/
+type SessionPolicy struct {
+	IdleTimeout        time.Duration
+	MaxLifetime        time.Duration
+	RememberMeLifetime time.Duration
+}
+
+// Session is the session policy used by the app. It holds the defaults until Load is called.
+var Session = SessionPolicy{
+	IdleTimeout:        30 * time.Minute,
+	MaxLifetime:        12 * time.Hour,
+	RememberMeLifetime: 30 * 24 * time.Hour,
+}
+
+/*
+Expiry returns when a session expires if it is used now.
+
+The expiry slides forward with every request but never goes past the session's maximum lifetime.
+
+Arguments:
+
+- startedAt: When the user logged in.
+
+- rememberMe: Whether the user asked to be remembered when they logged in.
+
+- now: The time of the current request.
+
+Returns:
+
+- time.Time: The new expiry time of the session.
+*/
+func (p SessionPolicy) Expiry(startedAt time.Time, rememberMe bool, now time.Time) time.Time {
+	idle, maxLifetime := p.IdleTimeout, p.MaxLifetime
+	if rememberMe {
+		idle, maxLifetime = p.RememberMeLifetime, p.RememberMeLifetime
+	}
+
+	expiry := now.Add(idle)
+	if absolute := startedAt.Add(maxLifetime); expiry.After(absolute) {
+		expiry = absolute
+	}
+	return expiry
+}
+
+/*
+LoginThrottlePolicy controls how failed logins are slowed down to stop passwords being guessed.
+Failures are counted for each account and for each IP address, so both guessing one account's password
+and trying one password against many accounts are slowed down.
+
+- AccountFreeAttempts: Failed logins allowed on an account before each further attempt has to wait.
+
+- IPFreeAttempts: Failed logins allowed from one IP address before each further attempt has to wait.
+
+- BaseDelay: The wait after the first failure past the free attempts. It doubles with every further failure.
+
+- LockoutThreshold: Failed logins after which an account is locked for LockoutDuration, unless a landlord unlocks it.
+
+- LockoutDuration: How long a locked account stays locked. It is also the longest any wait can be.
+
+- AlertThreshold: Failed logins after which the account owner is emailed.
+
+- FailureWindow: Failures older than this are forgotten.
+
+- TrustProxyHeaders: Take the client's IP address from the X-Forwarded-For header set by the hosting platform's proxy.
+Only turn this on behind a proxy that sets the header, otherwise clients can choose their own IP address.
+*/
+type LoginThrottlePolicy struct {
+	AccountFreeAttempts int
+	IPFreeAttempts      int
+	BaseDelay           time.Duration
+	LockoutThreshold    int
+	LockoutDuration     time.Duration
+	AlertThreshold      int
+	FailureWindow       time.Duration
+	TrustProxyHeaders   bool
+}
+
+// Login is the login throttle policy used by the app. It holds the defaults until Load is called.
+var Login = LoginThrottlePolicy{
+	AccountFreeAttempts: 3,
+	IPFreeAttempts:      20,
+	BaseDelay:           15 * time.Second,
+	LockoutThreshold:    10,
+	LockoutDuration:     time.Hour,
+	AlertThreshold:      5,
+	FailureWindow:       24 * time.Hour,
+}
+
+/*
+Delay returns how long to wait before the next login attempt, after a number of failed logins in a row.
+There is no wait for the free attempts, then the wait doubles with every failure up to LockoutDuration.
+
+Arguments:
+
+- failures: The number of failed logins in a row.
+
+- freeAttempts: The failed logins allowed before there is a wait, AccountFreeAttempts or IPFreeAttempts.
+
+Returns:
+
+- time.Duration: How long to wait, or 0 if the next attempt can be made straight away.
+*/
+func (p LoginThrottlePolicy) Delay(failures, freeAttempts int) time.Duration {
+	if failures < freeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := freeAttempts; i < failures && delay < p.LockoutDuration; i++ {
+		delay *= 2
+	}
+	return min(delay, p.LockoutDuration)
+}
+
+/*
+AccountDelay returns how long an account has to wait before its next login attempt.
+Accounts that have reached LockoutThreshold are locked for the whole LockoutDuration.
+
+Arguments:
+
+- failures: The number of failed logins in a row on the account.
+
+Returns:
+
+- time.Duration: How long to wait, or 0 if the next attempt can be made straight away.
+*/
+func (p LoginThrottlePolicy) AccountDelay(failures int) time.Duration {
+	if failures >= p.LockoutThreshold {
+		return p.LockoutDuration
+	}
+	return p.Delay(failures, p.AccountFreeAttempts)
+}
+
+/*
+RegistrationPolicy controls how new landlord accounts are created.
+
+- PublicLandlordRegistration: Let anyone create a landlord account from the new landlord page.
+When it is off, which is the default, landlords can only join by an invitation from the owner.
+
+- InvitationLifetime: How long an emailed invitation can be accepted for.
+*/
+type RegistrationPolicy struct {
+	PublicLandlordRegistration bool
+	InvitationLifetime         time.Duration
+}
+
+// Registration is the registration policy used by the app. It holds the defaults until Load is called.
+var Registration = RegistrationPolicy{
+	InvitationLifetime: 7 * 24 * time.Hour,
+}
+
+/*
+ServerPolicy controls how the HTTP server is run.
+
+- ReadHeaderTimeout: How long a client has to send the request headers.
+
+- ReadTimeout: How long a client has to send the whole request, including uploaded photos.
+
+- WriteTimeout: How long a request has to be answered, from the end of its headers.
+
+- IdleTimeout: How long a kept-alive connection can wait for its next request.
+
+- TLSCertFile and TLSKeyFile: The certificate and private key files to serve HTTPS with. Both or neither must be set.
+When neither is set the server uses plain HTTP, e.g. behind a hosting platform's proxy that handles HTTPS.
+
+- HTTPRedirectPort: When serving HTTPS, a port to listen for plain HTTP on and redirect every request to HTTPS.
+
+- HSTSMaxAge: How long browsers should only use HTTPS for the site, once they have seen it over HTTPS.
+
+- ContentSecurityPolicy: The Content-Security-Policy header sent with every page.
+
+- ReadyCheckSMTP: Whether /readyz also checks the SMTP server can be reached. Emails are retried from the outbox,
+so the app is still ready without it and the result is only reported.
+*/
+type ServerPolicy struct {
+	ReadHeaderTimeout     time.Duration
+	ReadTimeout           time.Duration
+	WriteTimeout          time.Duration
+	IdleTimeout           time.Duration
+	TLSCertFile           string
+	TLSKeyFile            string
+	HTTPRedirectPort      string
+	HSTSMaxAge            time.Duration
+	ContentSecurityPolicy string
+	ReadyCheckSMTP        bool
+}
+
+// TLS reports whether the server serves HTTPS itself.
+func (p ServerPolicy) TLS() bool {
+	return p.TLSCertFile != "" && p.TLSKeyFile != ""
+}
+
+/*
+DefaultContentSecurityPolicy only lets pages use scripts, styles and images from the site itself, plus Google Maps,
+Google Fonts and the Font Awesome stylesheet the templates load. Scripts cannot be inline, so templates attach
+behaviour with data- attributes read by static/js/main.js. Inline styles are allowed, as the templates use style attributes
+and the Maps API adds its own.
+*/
+const DefaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' https://maps.googleapis.com https://maps.gstatic.com; " +
+	"style-src 'self' 'unsafe-inline' https://fonts.googleapis.com https://maxcdn.bootstrapcdn.com; " +
+	"font-src 'self' https://fonts.gstatic.com https://maxcdn.bootstrapcdn.com; " +
+	"img-src 'self' data: https://*.googleapis.com https://*.gstatic.com https://*.google.com; " +
+	"connect-src 'self' https://*.googleapis.com https://*.gstatic.com; " +
+	"object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+// Server is the server policy used by the app. It holds the defaults until Load is called.
+var Server = ServerPolicy{
+	ReadHeaderTimeout:     5 * time.Second,
+	ReadTimeout:           30 * time.Second,
+	WriteTimeout:          60 * time.Second,
+	IdleTimeout:           120 * time.Second,
+	HSTSMaxAge:            365 * 24 * time.Hour,
+	ContentSecurityPolicy: DefaultContentSecurityPolicy,
+}
+
+/*
+RetentionPolicy controls how long records are kept before the retention scheduler purges them.
+A period of 0 keeps the records forever.
+
+- DeniedApplications: How long denied applications are kept after they were denied.
+
+- EndedTenancies: How long the records of a tenant who has moved out are kept after their tenancy ended:
+their account, applications, leases, rent ledger, messages and maintenance requests.
+
+- Messages: How long messages are kept after they were sent.
+
+- Interval: How often the scheduler applies the rules.
+
+- DryRun: Only report what the rules would purge, without purging anything. It is on by default,
+so records are only deleted once purging has been turned on.
+*/
+type RetentionPolicy struct {
+	DeniedApplications time.Duration
+	EndedTenancies     time.Duration
+	Messages           time.Duration
+	Interval           time.Duration
+	DryRun             bool
+}
+
+// Retention is the retention policy used by the app. It holds the defaults until Load is called.
+// Ended tenancies are kept for 6 years, as rent records have to be, and messages are kept until their tenancy is purged.
+// The rules only report what they would purge until RETENTION_DRY_RUN is set to "false".
+var Retention = RetentionPolicy{
+	DeniedApplications: 90 * 24 * time.Hour,
+	EndedTenancies:     6 * 365 * 24 * time.Hour,
+	Interval:           24 * time.Hour,
+	DryRun:             true,
+}
+
+/*
+DatabasePolicy controls how the database schema is kept up to date, and how the app's connections to it are used.
+
+- AutoMigrate: Apply pending migrations when the app connects to the database. When it is off, which is the default,
+pending migrations are only logged and are applied with the -migrate command.
+
+- QueryTimeout: How long a database call, including waiting for a free connection, can take before it is cancelled.
+The request it was made for gets a 503 Service Unavailable.
+
+- MaxOpenConns: The most connections open to the database at once.
+
+- MaxIdleConns: The most unused connections kept open for the next queries. It cannot be more than MaxOpenConns.
+
+- ConnMaxLifetime: How long a connection is used before it is closed and a new one opened, so connections
+move to a new database server after a failover.
+*/
+type DatabasePolicy struct {
+	AutoMigrate     bool
+	QueryTimeout    time.Duration
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// Database is the database policy used by the app. It holds the defaults until Load is called.
+var Database = DatabasePolicy{
+	QueryTimeout:    5 * time.Second,
+	MaxOpenConns:    25,
+	MaxIdleConns:    25,
+	ConnMaxLifetime: 30 * time.Minute,
+}
+
+/*
+Load reads the app configuration from the environment variables, falling back to the env/.env file for any
+that are not set by the hosting platform. Values that are not set in either keep their defaults.
+
+Session settings use Go durations, e.g. "30m", "12h" or "720h":
+
+- SESSION_IDLE_TIMEOUT: Session.IdleTimeout
+
+- SESSION_MAX_LIFETIME: Session.MaxLifetime
+
+- SESSION_REMEMBER_ME_LIFETIME: Session.RememberMeLifetime
+
+Login throttle settings:
+
+- LOGIN_BASE_DELAY: Login.BaseDelay
+
+- LOGIN_LOCKOUT_DURATION: Login.LockoutDuration
+
+- TRUST_PROXY_HEADERS: Login.TrustProxyHeaders, "true" to turn it on
+
+Registration settings:
+
+- ALLOW_PUBLIC_REGISTRATION: Registration.PublicLandlordRegistration, "true" to turn it on
+
+- INVITATION_LIFETIME: Registration.InvitationLifetime
+
+Server settings:
+
+- SERVER_READ_HEADER_TIMEOUT, SERVER_READ_TIMEOUT, SERVER_WRITE_TIMEOUT, SERVER_IDLE_TIMEOUT: the Server timeouts
+
+- TLS_CERT_FILE and TLS_KEY_FILE: Server.TLSCertFile and Server.TLSKeyFile
+
+- HTTP_REDIRECT_PORT: Server.HTTPRedirectPort
+
+- HSTS_MAX_AGE: Server.HSTSMaxAge
+
+- CONTENT_SECURITY_POLICY: Server.ContentSecurityPolicy
+
+Retention settings, with periods in whole days and 0 to keep the records forever:
+
+- RETENTION_DENIED_APPLICATIONS_DAYS: Retention.DeniedApplications
+
+- RETENTION_ENDED_TENANCIES_DAYS: Retention.EndedTenancies
+
+- RETENTION_MESSAGES_DAYS: Retention.Messages
+
+- RETENTION_INTERVAL: Retention.Interval, as a Go duration
+
+- RETENTION_DRY_RUN: Retention.DryRun, "false" to turn it off and purge records
+
+Database settings:
+
+- DB_AUTO_MIGRATE: Database.AutoMigrate, "true" to turn it on
+
+- DB_QUERY_TIMEOUT and DB_CONN_MAX_LIFETIME: Database.QueryTimeout and Database.ConnMaxLifetime, as Go durations
+
+- DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS: Database.MaxOpenConns and Database.MaxIdleConns
+
+Returns:
+
+- error: An error object if any of the settings are invalid.
+*/
+func Load() error {
+	logs.Logs(logInfo, "Loading configuration...")
+
+	// settings from the hosting platform are kept, the .env file only fills in the ones it does not set
+	err := env.LoadEnv("env/.env")
+	if errors.Is(err, fs.ErrNotExist) {
+		logs.Logs(logInfo, "No .env file found. Using settings from the hosting platform and the defaults.")
+	} else if err != nil {
+		logs.Logs(logWarn, fmt.Sprintf("Could not load environment variables from .env file: %s. Using default settings.", err.Error()))
+	}
+
+	session := Session
+	login := Login
+	registration := Registration
+	server := Server
+	retention := Retention
+	database := Database
+	settings := []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"SESSION_IDLE_TIMEOUT", &session.IdleTimeout},
+		{"SESSION_MAX_LIFETIME", &session.MaxLifetime},
+		{"SESSION_REMEMBER_ME_LIFETIME", &session.RememberMeLifetime},
+		{"LOGIN_BASE_DELAY", &login.BaseDelay},
+		{"LOGIN_LOCKOUT_DURATION", &login.LockoutDuration},
+		{"INVITATION_LIFETIME", &registration.InvitationLifetime},
+		{"SERVER_READ_HEADER_TIMEOUT", &server.ReadHeaderTimeout},
+		{"SERVER_READ_TIMEOUT", &server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", &server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", &server.IdleTimeout},
+		{"HSTS_MAX_AGE", &server.HSTSMaxAge},
+		{"RETENTION_INTERVAL", &retention.Interval},
+		{"DB_QUERY_TIMEOUT", &database.QueryTimeout},
+		{"DB_CONN_MAX_LIFETIME", &database.ConnMaxLifetime},
+	}
+	for _, setting := range settings {
+		err := loadDuration(setting.name, setting.value)
+		if err != nil {
+			logs.Logs(logErr, err.Error())
+			return err
+		}
+	}
+
+	retentionPeriods := []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"RETENTION_DENIED_APPLICATIONS_DAYS", &retention.DeniedApplications},
+		{"RETENTION_ENDED_TENANCIES_DAYS", &retention.EndedTenancies},
+		{"RETENTION_MESSAGES_DAYS", &retention.Messages},
+	}
+	for _, period := range retentionPeriods {
+		err := loadDays(period.name, period.value)
+		if err != nil {
+			logs.Logs(logErr, err.Error())
+			return err
+		}
+	}
+	retention.DryRun = os.Getenv("RETENTION_DRY_RUN") != "false"
+
+	connectionLimits := []struct {
+		name  string
+		value *int
+	}{
+		{"DB_MAX_OPEN_CONNS", &database.MaxOpenConns},
+		{"DB_MAX_IDLE_CONNS", &database.MaxIdleConns},
+	}
+	for _, limit := range connectionLimits {
+		err := loadCount(limit.name, limit.value)
+		if err != nil {
+			logs.Logs(logErr, err.Error())
+			return err
+		}
+	}
+	if database.MaxIdleConns > database.MaxOpenConns {
+		err := fmt.Errorf("DB_MAX_IDLE_CONNS (%d) cannot be more than DB_MAX_OPEN_CONNS (%d)", database.MaxIdleConns, database.MaxOpenConns)
+		logs.Logs(logErr, err.Error())
+		return err
+	}
+	database.AutoMigrate = os.Getenv("DB_AUTO_MIGRATE") == "true"
+
+	if session.IdleTimeout > session.MaxLifetime {
+		err := fmt.Errorf("SESSION_IDLE_TIMEOUT (%s) cannot be longer than SESSION_MAX_LIFETIME (%s)", session.IdleTimeout, session.MaxLifetime)
+		logs.Logs(logErr, err.Error())
+		return err
+	}
+	login.TrustProxyHeaders = os.Getenv("TRUST_PROXY_HEADERS") == "true"
+	registration.PublicLandlordRegistration = os.Getenv("ALLOW_PUBLIC_REGISTRATION") == "true"
+
+	server.TLSCertFile = os.Getenv("TLS_CERT_FILE")
+	server.TLSKeyFile = os.Getenv("TLS_KEY_FILE")
+	if (server.TLSCertFile == "") != (server.TLSKeyFile == "") {
+		err := errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+		logs.Logs(logErr, err.Error())
+		return err
+	}
+	server.HTTPRedirectPort = os.Getenv("HTTP_REDIRECT_PORT")
+	if server.HTTPRedirectPort != "" && !server.TLS() {
+		err := errors.New("HTTP_REDIRECT_PORT needs TLS_CERT_FILE and TLS_KEY_FILE to be set")
+		logs.Logs(logErr, err.Error())
+		return err
+	}
+	if policy := os.Getenv("CONTENT_SECURITY_POLICY"); policy != "" {
+		server.ContentSecurityPolicy = policy
+	}
+	server.ReadyCheckSMTP = os.Getenv("READY_CHECK_SMTP") == "true"
+
+	Session = session
+	Login = login
+	Registration = registration
+	Server = server
+	Retention = retention
+	Database = database
+
+	if Registration.PublicLandlordRegistration {
+		logs.Logs(logWarn, "Public landlord registration is on. Anyone can create a landlord account.")
+	}
+
+	logs.Logs(logInfo, fmt.Sprintf("Failed logins are slowed down from %s, and accounts are locked for %s after %d failures.", Login.BaseDelay, Login.LockoutDuration, Login.LockoutThreshold))
+	if Server.TLS() {
+		logs.Logs(logInfo, "The server will serve HTTPS with the certificate in TLS_CERT_FILE.")
+	}
+	if Retention.DryRun {
+		logs.Logs(logInfo, "Retention dry run is on. Records past their retention period are reported but not purged until RETENTION_DRY_RUN=false.")
+	} else {
+		logs.Logs(logWarn, "Retention purging is on. Records past their retention period are deleted.")
+	}
+	logs.Logs(logInfo, fmt.Sprintf("Sessions expire after %s idle, %s at most, or %s with remember me.", Session.IdleTimeout, Session.MaxLifetime, Session.RememberMeLifetime))
+	return nil
+}
+
+// loadDuration sets value from the named environment variable, if it is set.
+func loadDuration(name string, value *time.Duration) error {
+	setting := os.Getenv(name)
+	if setting == "" {
+		return nil
+	}
+
+	duration, err := time.ParseDuration(setting)
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("invalid %s: %s", name, setting)
+	}
+	*value = duration
+	return nil
+}
+
+// loadDays sets value from the named environment variable, a whole number of days, if it is set. 0 is allowed.
+func loadDays(name string, value *time.Duration) error {
+	setting := os.Getenv(name)
+	if setting == "" {
+		return nil
+	}
+
+	days, err := strconv.Atoi(setting)
+	if err != nil || days < 0 {
+		return fmt.Errorf("invalid %s: %s", name, setting)
+	}
+	*value = time.Duration(days) * 24 * time.Hour
+	return nil
+}
+
+// loadCount sets value from the named environment variable, a whole number above 0, if it is set.
+func loadCount(name string, value *int) error {
+	setting := os.Getenv(name)
+	if setting == "" {
+		return nil
+	}
+
+	count, err := strconv.Atoi(setting)
+	if err != nil || count <= 0 {
+		return fmt.Errorf("invalid %s: %s", name, setting)
+	}
+	*value = count
+	return nil
+}
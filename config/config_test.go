@@ -0,0 +1,600 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestSessionPolicyExpiry(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	policy := config.SessionPolicy{
+		IdleTimeout:        30 * time.Minute,
+		MaxLifetime:        2 * time.Hour,
+		RememberMeLifetime: 48 * time.Hour,
+	}
+	startedAt := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		rememberMe     bool
+		now            time.Time
+		expectedExpiry time.Time
+	}{
+		{
+			name:           "Expiry slides with each request",
+			now:            startedAt.Add(10 * time.Minute),
+			expectedExpiry: startedAt.Add(40 * time.Minute),
+		},
+		{
+			name:           "Expiry is capped at the maximum lifetime",
+			now:            startedAt.Add(110 * time.Minute),
+			expectedExpiry: startedAt.Add(2 * time.Hour),
+		},
+		{
+			name:           "Remember me uses the longer lifetime",
+			rememberMe:     true,
+			now:            startedAt.Add(3 * time.Hour),
+			expectedExpiry: startedAt.Add(48 * time.Hour),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expiry := policy.Expiry(startedAt, tc.rememberMe, tc.now)
+			if !expiry.Equal(tc.expectedExpiry) {
+				t.Errorf("Expected expiry %v, got %v", tc.expectedExpiry, expiry)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	defaults := config.Session
+	defer func() { config.Session = defaults }()
+
+	// Test cases
+	testCases := []struct {
+		name        string
+		idleTimeout string
+		maxLifetime string
+		expectError bool
+		expected    time.Duration
+	}{
+		{
+			name:        "Valid durations",
+			idleTimeout: "15m",
+			maxLifetime: "8h",
+			expected:    15 * time.Minute,
+		},
+		{
+			name:        "Invalid duration",
+			idleTimeout: "fifteen minutes",
+			maxLifetime: "8h",
+			expectError: true,
+		},
+		{
+			name:        "Idle timeout longer than the maximum lifetime",
+			idleTimeout: "9h",
+			maxLifetime: "8h",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config.Session = defaults
+			t.Setenv("SESSION_IDLE_TIMEOUT", tc.idleTimeout)
+			t.Setenv("SESSION_MAX_LIFETIME", tc.maxLifetime)
+
+			err := config.Load()
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if config.Session.IdleTimeout != tc.expected {
+				t.Errorf("Expected idle timeout %v, got %v", tc.expected, config.Session.IdleTimeout)
+			}
+		})
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	defaults := config.Session
+	defer func() { config.Session = defaults }()
+
+	// Load reads env/.env from the working directory
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "env"), 0o755); err != nil {
+		t.Fatalf("Failed to create env directory: %s", err.Error())
+	}
+	envFile := "SESSION_IDLE_TIMEOUT=20m\nSESSION_MAX_LIFETIME=6h\n"
+	if err := os.WriteFile(filepath.Join(dir, "env", ".env"), []byte(envFile), 0o600); err != nil {
+		t.Fatalf("Failed to write .env file: %s", err.Error())
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %s", err.Error())
+	}
+	defer os.Chdir(wd)
+
+	// the hosting platform sets the idle timeout but not the maximum lifetime
+	t.Setenv("SESSION_IDLE_TIMEOUT", "15m")
+	t.Setenv("SESSION_MAX_LIFETIME", "")
+	os.Unsetenv("SESSION_MAX_LIFETIME")
+
+	err := config.Load()
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if config.Session.IdleTimeout != 15*time.Minute {
+		t.Errorf("Expected the idle timeout from the hosting platform to be kept, got %v", config.Session.IdleTimeout)
+	}
+	if config.Session.MaxLifetime != 6*time.Hour {
+		t.Errorf("Expected the maximum lifetime to be loaded from the .env file, got %v", config.Session.MaxLifetime)
+	}
+}
+
+func TestLoginThrottlePolicyDelay(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	policy := config.LoginThrottlePolicy{
+		AccountFreeAttempts: 3,
+		IPFreeAttempts:      20,
+		BaseDelay:           15 * time.Second,
+		LockoutThreshold:    10,
+		LockoutDuration:     time.Hour,
+	}
+
+	// Test cases
+	testCases := []struct {
+		name          string
+		failures      int
+		expectedDelay time.Duration
+	}{
+		{
+			name:          "No wait for the free attempts",
+			failures:      2,
+			expectedDelay: 0,
+		},
+		{
+			name:          "First wait after the free attempts",
+			failures:      3,
+			expectedDelay: 15 * time.Second,
+		},
+		{
+			name:          "Wait doubles with each failure",
+			failures:      6,
+			expectedDelay: 2 * time.Minute,
+		},
+		{
+			name:          "Account is locked at the threshold",
+			failures:      10,
+			expectedDelay: time.Hour,
+		},
+		{
+			name:          "Lock does not grow past the lockout duration",
+			failures:      50,
+			expectedDelay: time.Hour,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			delay := policy.AccountDelay(tc.failures)
+			if delay != tc.expectedDelay {
+				t.Errorf("Expected delay %v, got %v", tc.expectedDelay, delay)
+			}
+		})
+	}
+
+	// IP addresses get more free attempts, as several people can share one
+	if delay := policy.Delay(19, policy.IPFreeAttempts); delay != 0 {
+		t.Errorf("Expected no delay before the IP free attempts are used, got %v", delay)
+	}
+	if delay := policy.Delay(1000, policy.IPFreeAttempts); delay != time.Hour {
+		t.Errorf("Expected the IP delay to be capped at %v, got %v", time.Hour, delay)
+	}
+}
+
+func TestLoadRegistration(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	defaults := config.Registration
+	defer func() { config.Registration = defaults }()
+
+	// Test cases
+	testCases := []struct {
+		name               string
+		allowPublic        string
+		invitationLifetime string
+		expectError        bool
+		expectedPublic     bool
+		expectedLifetime   time.Duration
+	}{
+		{
+			name:             "Invite only by default",
+			expectedLifetime: 7 * 24 * time.Hour,
+		},
+		{
+			name:             "Public registration turned on",
+			allowPublic:      "true",
+			expectedPublic:   true,
+			expectedLifetime: 7 * 24 * time.Hour,
+		},
+		{
+			name:               "Shorter invitations",
+			invitationLifetime: "48h",
+			expectedLifetime:   48 * time.Hour,
+		},
+		{
+			name:               "Invalid invitation lifetime",
+			invitationLifetime: "a week",
+			expectError:        true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config.Registration = defaults
+			t.Setenv("SESSION_IDLE_TIMEOUT", "30m")
+			t.Setenv("ALLOW_PUBLIC_REGISTRATION", tc.allowPublic)
+			t.Setenv("INVITATION_LIFETIME", tc.invitationLifetime)
+
+			err := config.Load()
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if config.Registration.PublicLandlordRegistration != tc.expectedPublic {
+				t.Errorf("Expected public registration %t, got %t", tc.expectedPublic, config.Registration.PublicLandlordRegistration)
+			}
+			if config.Registration.InvitationLifetime != tc.expectedLifetime {
+				t.Errorf("Expected invitation lifetime %v, got %v", tc.expectedLifetime, config.Registration.InvitationLifetime)
+			}
+		})
+	}
+}
+
+func TestLoadServer(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	defaults := config.Server
+	defer func() { config.Server = defaults }()
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		certFile       string
+		keyFile        string
+		redirectPort   string
+		writeTimeout   string
+		contentPolicy  string
+		readyCheckSMTP string
+		expectError    bool
+		expectedTLS    bool
+		expectedWrite  time.Duration
+		expectedPolicy string
+		expectedSMTP   bool
+	}{
+		{
+			name:           "Plain HTTP by default",
+			expectedWrite:  60 * time.Second,
+			expectedPolicy: config.DefaultContentSecurityPolicy,
+		},
+		{
+			name:           "HTTPS with a redirect listener",
+			certFile:       "cert.pem",
+			keyFile:        "key.pem",
+			redirectPort:   "80",
+			expectedTLS:    true,
+			expectedWrite:  60 * time.Second,
+			expectedPolicy: config.DefaultContentSecurityPolicy,
+		},
+		{
+			name:           "Longer write timeout and own policy",
+			writeTimeout:   "2m",
+			contentPolicy:  "default-src 'self'",
+			expectedWrite:  2 * time.Minute,
+			expectedPolicy: "default-src 'self'",
+		},
+		{
+			name:           "Readiness checks the SMTP server",
+			readyCheckSMTP: "true",
+			expectedWrite:  60 * time.Second,
+			expectedPolicy: config.DefaultContentSecurityPolicy,
+			expectedSMTP:   true,
+		},
+		{
+			name:        "Certificate without a key",
+			certFile:    "cert.pem",
+			expectError: true,
+		},
+		{
+			name:         "Redirect listener without HTTPS",
+			redirectPort: "80",
+			expectError:  true,
+		},
+		{
+			name:         "Invalid write timeout",
+			writeTimeout: "forever",
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config.Server = defaults
+			t.Setenv("SESSION_IDLE_TIMEOUT", "30m")
+			t.Setenv("TLS_CERT_FILE", tc.certFile)
+			t.Setenv("TLS_KEY_FILE", tc.keyFile)
+			t.Setenv("HTTP_REDIRECT_PORT", tc.redirectPort)
+			t.Setenv("SERVER_WRITE_TIMEOUT", tc.writeTimeout)
+			t.Setenv("CONTENT_SECURITY_POLICY", tc.contentPolicy)
+			t.Setenv("READY_CHECK_SMTP", tc.readyCheckSMTP)
+
+			err := config.Load()
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if config.Server.TLS() != tc.expectedTLS {
+				t.Errorf("Expected TLS %t, got %t", tc.expectedTLS, config.Server.TLS())
+			}
+			if config.Server.WriteTimeout != tc.expectedWrite {
+				t.Errorf("Expected write timeout %v, got %v", tc.expectedWrite, config.Server.WriteTimeout)
+			}
+			if config.Server.ContentSecurityPolicy != tc.expectedPolicy {
+				t.Errorf("Expected content security policy %q, got %q", tc.expectedPolicy, config.Server.ContentSecurityPolicy)
+			}
+			if config.Server.ReadyCheckSMTP != tc.expectedSMTP {
+				t.Errorf("Expected SMTP readiness check %t, got %t", tc.expectedSMTP, config.Server.ReadyCheckSMTP)
+			}
+		})
+	}
+}
+
+func TestLoadRetention(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	defaults := config.Retention
+	defer func() { config.Retention = defaults }()
+
+	day := 24 * time.Hour
+
+	// Test cases
+	testCases := []struct {
+		name                 string
+		deniedApplications   string
+		messages             string
+		interval             string
+		dryRun               string
+		expectError          bool
+		expectedApplications time.Duration
+		expectedTenancies    time.Duration
+		expectedMessages     time.Duration
+		expectedInterval     time.Duration
+		expectedDryRun       bool
+	}{
+		{
+			name:                 "Defaults only report",
+			expectedApplications: 90 * day,
+			expectedTenancies:    6 * 365 * day,
+			expectedInterval:     24 * time.Hour,
+			expectedDryRun:       true,
+		},
+		{
+			name:                 "Messages purged after two years in a dry run",
+			messages:             "730",
+			interval:             "6h",
+			dryRun:               "true",
+			expectedApplications: 90 * day,
+			expectedTenancies:    6 * 365 * day,
+			expectedMessages:     730 * day,
+			expectedInterval:     6 * time.Hour,
+			expectedDryRun:       true,
+		},
+		{
+			name:                 "Purging turned on",
+			dryRun:               "false",
+			expectedApplications: 90 * day,
+			expectedTenancies:    6 * 365 * day,
+			expectedInterval:     24 * time.Hour,
+		},
+		{
+			name:               "Denied applications kept forever",
+			deniedApplications: "0",
+			expectedTenancies:  6 * 365 * day,
+			expectedInterval:   24 * time.Hour,
+			expectedDryRun:     true,
+		},
+		{
+			name:               "Negative period",
+			deniedApplications: "-1",
+			expectError:        true,
+		},
+		{
+			name:        "Period that is not in days",
+			messages:    "2y",
+			expectError: true,
+		},
+		{
+			name:        "Zero interval",
+			interval:    "0s",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config.Retention = defaults
+			t.Setenv("SESSION_IDLE_TIMEOUT", "30m")
+			t.Setenv("RETENTION_DENIED_APPLICATIONS_DAYS", tc.deniedApplications)
+			t.Setenv("RETENTION_MESSAGES_DAYS", tc.messages)
+			t.Setenv("RETENTION_INTERVAL", tc.interval)
+			t.Setenv("RETENTION_DRY_RUN", tc.dryRun)
+
+			err := config.Load()
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if config.Retention.DeniedApplications != tc.expectedApplications {
+				t.Errorf("Expected denied applications kept for %v, got %v", tc.expectedApplications, config.Retention.DeniedApplications)
+			}
+			if config.Retention.EndedTenancies != tc.expectedTenancies {
+				t.Errorf("Expected ended tenancies kept for %v, got %v", tc.expectedTenancies, config.Retention.EndedTenancies)
+			}
+			if config.Retention.Messages != tc.expectedMessages {
+				t.Errorf("Expected messages kept for %v, got %v", tc.expectedMessages, config.Retention.Messages)
+			}
+			if config.Retention.Interval != tc.expectedInterval {
+				t.Errorf("Expected interval %v, got %v", tc.expectedInterval, config.Retention.Interval)
+			}
+			if config.Retention.DryRun != tc.expectedDryRun {
+				t.Errorf("Expected dry run %t, got %t", tc.expectedDryRun, config.Retention.DryRun)
+			}
+		})
+	}
+}
+
+func TestLoadDatabase(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	defaults := config.Database
+	defer func() { config.Database = defaults }()
+
+	// Test cases
+	testCases := []struct {
+		name             string
+		queryTimeout     string
+		maxOpenConns     string
+		maxIdleConns     string
+		connMaxLifetime  string
+		expectError      bool
+		expectedTimeout  time.Duration
+		expectedOpen     int
+		expectedIdle     int
+		expectedLifetime time.Duration
+	}{
+		{
+			name:             "Defaults",
+			expectedTimeout:  5 * time.Second,
+			expectedOpen:     25,
+			expectedIdle:     25,
+			expectedLifetime: 30 * time.Minute,
+		},
+		{
+			name:             "Small pool with a short timeout",
+			queryTimeout:     "2s",
+			maxOpenConns:     "10",
+			maxIdleConns:     "5",
+			connMaxLifetime:  "5m",
+			expectedTimeout:  2 * time.Second,
+			expectedOpen:     10,
+			expectedIdle:     5,
+			expectedLifetime: 5 * time.Minute,
+		},
+		{
+			name:         "More idle connections than open connections",
+			maxOpenConns: "10",
+			expectError:  true,
+		},
+		{
+			name:         "Zero open connections",
+			maxOpenConns: "0",
+			expectError:  true,
+		},
+		{
+			name:         "Connection count that is not a number",
+			maxIdleConns: "ten",
+			expectError:  true,
+		},
+		{
+			name:         "Zero query timeout",
+			queryTimeout: "0s",
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config.Database = defaults
+			t.Setenv("SESSION_IDLE_TIMEOUT", "30m")
+			t.Setenv("DB_QUERY_TIMEOUT", tc.queryTimeout)
+			t.Setenv("DB_MAX_OPEN_CONNS", tc.maxOpenConns)
+			t.Setenv("DB_MAX_IDLE_CONNS", tc.maxIdleConns)
+			t.Setenv("DB_CONN_MAX_LIFETIME", tc.connMaxLifetime)
+
+			err := config.Load()
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if config.Database.QueryTimeout != tc.expectedTimeout {
+				t.Errorf("Expected query timeout %v, got %v", tc.expectedTimeout, config.Database.QueryTimeout)
+			}
+			if config.Database.MaxOpenConns != tc.expectedOpen {
+				t.Errorf("Expected %d open connections, got %d", tc.expectedOpen, config.Database.MaxOpenConns)
+			}
+			if config.Database.MaxIdleConns != tc.expectedIdle {
+				t.Errorf("Expected %d idle connections, got %d", tc.expectedIdle, config.Database.MaxIdleConns)
+			}
+			if config.Database.ConnMaxLifetime != tc.expectedLifetime {
+				t.Errorf("Expected connection lifetime %v, got %v", tc.expectedLifetime, config.Database.ConnMaxLifetime)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+/*
+AcceptTenantApplication accepts a tenancy application in one transaction: the application is marked accepted,
+the tenant's account and lease are created and the emails giving the tenant and landlord the tenant's login details
+are added to the outbox. Nothing is sent until the transaction has committed, and if any step fails nothing is changed.
+
+Accepting an application that has already been accepted changes nothing, so a form sent twice does not create
+a second account or send the emails again.
+
+Arguments:
+
+- landlordEmail: The email of the landlord accepting the application.
+
+- acceptance: The application, the room the tenant is given and the terms of their tenancy and lease.
+
+Returns:
+
+- bool: True if the application was accepted, false if it had already been accepted.
+
+- error: ErrApplicationDecided if the application was denied, ErrRoomFull if the room has no space left,
+sql.ErrNoRows if the application or room does not belong to the landlord, or an error object if the tenant cannot be created.
+*/
+func AcceptTenantApplication(ctx context.Context, landlordEmail string, acceptance TenantAcceptance) (bool, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return false, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	start, err := time.Parse("2006-01-02", acceptance.MoveInDate)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Invalid lease start date: %s", err.Error()))
+		return false, err
+	}
+
+	landlordId, err := GetLandlordIdByEmail(ctx, landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord ID: %s", err.Error()))
+		return false, err
+	}
+
+	// the room must be in one of the landlord's properties
+	room, err := GetLandlordRoom(ctx, landlordEmail, acceptance.RoomID)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to begin transaction: %s", err.Error()))
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// locking the application means the same form sent twice at once is accepted only once
+	var status string
+	var encryptEmail, encryptPassportNumber, encryptFullName []byte
+	query := `
+	SELECT status, encrypt_email, encrypt_passport_number, encrypt_full_name
+	FROM lhp_tenant_application
+	WHERE id = $1 AND landlord_id = $2
+	FOR UPDATE;
+	`
+	err = tx.QueryRowContext(ctx, query, acceptance.ApplicationID, landlordId).Scan(&status, &encryptEmail, &encryptPassportNumber, &encryptFullName)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get tenant application: %s", err.Error()))
+		return false, err
+	}
+	switch status {
+	case "accepted":
+		logs.Logs(logDb, fmt.Sprintf("Tenant application %s has already been accepted", acceptance.ApplicationID))
+		return false, nil
+	case "pending":
+	default:
+		logs.Logs(logDbErr, fmt.Sprintf("Tenant application %s has already been %s", acceptance.ApplicationID, status))
+		return false, ErrApplicationDecided
+	}
+
+	tenantEmail, err := utils.Decrypt(encryptEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to decrypt email: %s", err.Error()))
+		return false, err
+	}
+	passportNumber, err := utils.Decrypt(encryptPassportNumber)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to decrypt passport number: %s", err.Error()))
+		return false, err
+	}
+	tenantUsername, tenantPassword, err := utils.GenerateTenantUsernamePassportNumberAndPassword(string(tenantEmail), string(passportNumber))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to generate tenant username & password: %s", err.Error()))
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE lhp_tenant_application SET status = 'accepted', decided_at = NOW() WHERE id = $1;`, acceptance.ApplicationID)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to update tenant application status: %s", err.Error()))
+		return false, err
+	}
+
+	err = reserveRoom(ctx, tx, room.ID)
+	if err != nil {
+		return false, err
+	}
+
+	args, err := newTenantArgs(landlordId, room, tenantUsername, tenantPassword, acceptance.MoveInDate, acceptance.RentDue, acceptance.MonthlyRent, acceptance.Currency, encryptFullName)
+	if err != nil {
+		return false, err
+	}
+	var tenantId int
+	err = tx.QueryRowContext(ctx, createTenantQuery+" RETURNING id;", args...).Scan(&tenantId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to create new tenant: %s", err.Error()))
+		return false, err
+	}
+
+	endDate := utils.AddMonths(start, acceptance.TermMonths)
+	_, err = tx.ExecContext(ctx, createLeaseQuery, tenantId, LeaseActive, start, endDate, acceptance.TermMonths, acceptance.BreakClauseMonths, acceptance.NoticeDays)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to create lease: %s", err.Error()))
+		return false, err
+	}
+
+	newAccount := NewAccountEmail{
+		LandlordEmail:  landlordEmail,
+		TenantUsername: tenantUsername,
+		TenantPassword: tenantPassword,
+		RoomType:       room.RoomType,
+		MoveInDate:     acceptance.MoveInDate,
+		RentDue:        acceptance.RentDue,
+		MonthlyRent:    acceptance.MonthlyRent,
+		Currency:       acceptance.Currency,
+	}
+	for _, kind := range []string{OutboxTenantNewAccount, OutboxLandlordNewAccount} {
+		err = enqueueEmail(ctx, tx, kind, utils.HashData(string(tenantEmail)), newAccount)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit accepted tenant application: %s", err.Error()))
+		return false, err
+	}
+	logs.Logs(logDb, fmt.Sprintf("Tenant application %s accepted, tenant %d created", acceptance.ApplicationID, tenantId))
+	return true, nil
+}
@@ -0,0 +1,307 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// the previous hash of the first entry in the audit log
+var auditGenesisHash = strings.Repeat("0", 64)
+
+// the advisory lock held while an entry is added, so entries are chained one at a time
+const auditLockKey = 7411
+
+/*
+RecordAudit adds an entry to the end of the audit log. The entry is chained to the one before it by including
+that entry's hash in its own, so any entry that is later changed or removed can be found by VerifyAuditLog.
+The time and hashes are set here, whatever the entry passed in has.
+
+Arguments:
+
+- entry: Who did what to which record, and where from.
+
+Returns:
+
+- error: An error object if the entry cannot be added.
+*/
+func RecordAudit(ctx context.Context, entry AuditEntry) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return err
+	}
+	defer tx.Rollback()
+
+	// only one entry can be chained to the last one
+	_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1);`, auditLockKey)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to lock audit log: %s", err.Error()))
+		return err
+	}
+
+	entry.PrevHash = auditGenesisHash
+	err = tx.QueryRowContext(ctx, `SELECT hash FROM lhp_audit_log ORDER BY id DESC LIMIT 1;`).Scan(&entry.PrevHash)
+	if err != nil && err != sql.ErrNoRows {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get last audit entry: %s", err.Error()))
+		return err
+	}
+
+	// the database keeps microseconds, so the time is truncated before it is hashed
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	entry.Hash = auditHash(entry)
+
+	query := `
+	INSERT INTO lhp_audit_log (created_at, actor_type, actor, landlord_email, action, target_type, target_id, details, ip_address, user_agent, prev_hash, hash)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+	`
+	_, err = tx.ExecContext(ctx, query,
+		entry.CreatedAt,
+		entry.ActorType,
+		entry.Actor,
+		entry.LandlordEmail,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID,
+		entry.Details,
+		entry.IPAddress,
+		entry.UserAgent,
+		entry.PrevHash,
+		entry.Hash,
+	)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to add audit entry: %s", err.Error()))
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit audit entry: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Audit entry added: %s", entry.Action))
+	return nil
+}
+
+// auditHash hashes every field of an audit entry along with the previous entry's hash.
+// The fields are encoded as a JSON array so no two different entries hash the same text.
+func auditHash(entry AuditEntry) string {
+	fields, _ := json.Marshal([]string{
+		entry.PrevHash,
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		entry.ActorType,
+		entry.Actor,
+		entry.LandlordEmail,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID,
+		entry.Details,
+		entry.IPAddress,
+		entry.UserAgent,
+	})
+	return utils.HashData(string(fields))
+}
+
+/*
+GetAuditLog gets the audit log entries about a landlord's records, for the landlord's audit log page and CSV export.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+- filter: Narrows down the entries. Empty fields do not filter.
+
+- limit: The most entries to get, or 0 for every entry.
+
+Returns:
+
+- []AuditEntry: The entries, the newest first.
+
+- error: An error object if the query fails.
+*/
+func GetAuditLog(ctx context.Context, landlordEmail string, filter AuditFilter, limit int) ([]AuditEntry, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	conditions := []string{"landlord_email = $1"}
+	args := []any{landlordEmail}
+	if filter.Actor != "" {
+		args = append(args, "%"+filter.Actor+"%")
+		conditions = append(conditions, fmt.Sprintf("actor ILIKE $%d", len(args)))
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		conditions = append(conditions, fmt.Sprintf("action = $%d", len(args)))
+	}
+	if filter.TargetID != "" {
+		args = append(args, filter.TargetID)
+		conditions = append(conditions, fmt.Sprintf("target_id = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From.UTC())
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To.UTC())
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	query := fmt.Sprintf(`
+	SELECT id, created_at, actor_type, actor, landlord_email, action, target_type, target_id, details, ip_address, user_agent, prev_hash, hash
+	FROM lhp_audit_log
+	WHERE %s
+	ORDER BY id DESC
+	`, strings.Join(conditions, " AND "))
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf("LIMIT $%d", len(args))
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get audit log: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+/*
+VerifyAuditLog checks the whole audit log has not been tampered with, by recomputing each entry's hash and
+checking each entry is chained to the one before it.
+
+Returns:
+
+- int64: The ID of the first entry that was changed, or that follows an entry that was removed. 0 if the log is intact.
+
+- error: An error object if the log cannot be read.
+*/
+func VerifyAuditLog(ctx context.Context) (int64, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return 0, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	SELECT id, created_at, actor_type, actor, landlord_email, action, target_type, target_id, details, ip_address, user_agent, prev_hash, hash
+	FROM lhp_audit_log
+	ORDER BY id;
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get audit log: %s", err.Error()))
+		return 0, err
+	}
+	defer rows.Close()
+
+	prevHash := auditGenesisHash
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return 0, err
+		}
+		if entry.PrevHash != prevHash || auditHash(entry) != entry.Hash {
+			logs.Logs(logWarning, fmt.Sprintf("Audit log chain is broken at entry %d", entry.ID))
+			return entry.ID, nil
+		}
+		prevHash = entry.Hash
+	}
+	return 0, rows.Err()
+}
+
+// scanAuditEntry scans one audit log row selected with every column in table order.
+func scanAuditEntry(rows interface{ Scan(...any) error }) (AuditEntry, error) {
+	var entry AuditEntry
+	err := rows.Scan(
+		&entry.ID,
+		&entry.CreatedAt,
+		&entry.ActorType,
+		&entry.Actor,
+		&entry.LandlordEmail,
+		&entry.Action,
+		&entry.TargetType,
+		&entry.TargetID,
+		&entry.Details,
+		&entry.IPAddress,
+		&entry.UserAgent,
+		&entry.PrevHash,
+		&entry.Hash,
+	)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to scan audit entry: %s", err.Error()))
+	}
+	return entry, err
+}
+
+/*
+GetTenantAuditIdentity gets how a tenant is recorded in the audit log.
+
+Arguments:
+
+- hashEmail: The tenant's hashed email.
+
+Returns:
+
+- int: The tenant's ID, which is recorded as the actor so the log holds no tenant emails.
+
+- string: The email of the tenant's landlord, who can see the entry.
+
+- error: sql.ErrNoRows if there is no such tenant, or an error object if the query fails.
+*/
+func GetTenantAuditIdentity(ctx context.Context, hashEmail string) (int, string, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return 0, "", errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var tenantId int
+	var landlordEmail string
+	query := `
+	SELECT t.id, l.email
+	FROM lhp_tenants t
+	JOIN lhp_landlords l ON l.id = t.landlord_id
+	WHERE t.hash_email = $1;
+	`
+	err := db.QueryRowContext(ctx, query, hashEmail).Scan(&tenantId, &landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get tenant audit identity: %s", err.Error()))
+		return 0, "", err
+	}
+	return tenantId, landlordEmail, nil
+}
@@ -0,0 +1,574 @@
+package db
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+	"github.com/lib/pq"
+)
+
+// ErasedValue replaces personal data that has been erased. It is encrypted like the data it replaces,
+// so pages that decrypt the column still work.
+const ErasedValue = "[erased]"
+
+// subjectTable is a table holding personal data about tenants or applicants.
+type subjectTable struct {
+	name     string
+	byTenant bool   // true if $1 in where is the subject's tenant IDs, false if it is their hashed email
+	where    string // selects the subject's rows
+}
+
+// subjectTables lists every table holding personal data about tenants and applicants, other than maintenance photos,
+// which are exported as files. New tables holding personal data must be added here so subject access exports include them,
+// and handled in EraseDataSubject.
+var subjectTables = []subjectTable{
+	{name: "lhp_tenant_application", where: `hash_email = $1`},
+	{name: "lhp_tenants", where: `hash_email = $1`},
+	{name: "lhp_leases", byTenant: true, where: `tenant_id = ANY($1)`},
+	{name: "lhp_lease_renewals", byTenant: true, where: `lease_id IN (SELECT id FROM lhp_leases WHERE tenant_id = ANY($1))`},
+	{name: "lhp_rent_ledger", byTenant: true, where: `tenant_id = ANY($1)`},
+	{name: "lhp_messages", byTenant: true, where: `(sender_type = 'tenant' AND sender_id = ANY($1)) OR (receiver_type = 'tenant' AND receiver_id = ANY($1))`},
+	{name: "lhp_maintenance_tickets", byTenant: true, where: `tenant_id = ANY($1)`},
+	{name: "lhp_maintenance_ticket_events", byTenant: true, where: `ticket_id IN (SELECT id FROM lhp_maintenance_tickets WHERE tenant_id = ANY($1))`},
+	{name: "lhp_two_factor", where: `user_type = 'tenant' AND user_key = $1`},
+	{name: "lhp_password_resets", where: `user_type = 'tenant' AND user_key = $1`},
+	{name: "lhp_login_throttles", where: `scope = 'account' AND user_type = 'tenant' AND throttle_key = $1`},
+	{name: "lhp_audit_log", byTenant: true, where: `actor_type = 'tenant' AND actor = ANY($1::int[]::text[])`},
+	{name: "lhp_email_outbox", where: `hash_subject = $1`},
+}
+
+// subjectExportSkipped are the columns left out of exports. Hashes and tokens are only used to look records up
+// or to log in, and say nothing about the subject that the rest of the export does not.
+var subjectExportSkipped = map[string]bool{
+	"hash_password":  true,
+	"session_token":  true,
+	"csrf_token":     true,
+	"token_hash":     true,
+	"code_hash":      true,
+	"encrypt_secret": true,
+	"prev_hash":      true,
+	"hash":           true,
+}
+
+/*
+ExportDataSubject collects everything held about a tenant or applicant, decrypted, for a subject access request.
+The subject is found by email: every application made with it, and every tenant account created from those applications.
+Each landlord whose records are included gets an entry in their audit log.
+Each query has its own config.Database.QueryTimeout, so a subject with many records or photos can still be exported.
+
+Arguments:
+
+- email: The subject's email address.
+
+Returns:
+
+- SubjectExport: The subject's records by table, and their maintenance photos. Write it out with WriteZip.
+
+- error: sql.ErrNoRows if nothing is held about the email, or an error object if the records cannot be read or decrypted.
+*/
+func ExportDataSubject(ctx context.Context, email string) (SubjectExport, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return SubjectExport{}, errors.New("database connection is not initialized")
+	}
+
+	hashEmail := utils.HashData(email)
+	tenantIds, applicationIds, err := getDataSubjectIds(ctx, hashEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to find data subject: %s", err.Error()))
+		return SubjectExport{}, err
+	}
+
+	export := SubjectExport{
+		GeneratedAt:    time.Now().UTC(),
+		TenantIDs:      tenantIds,
+		ApplicationIDs: applicationIds,
+		Records:        map[string][]SubjectRecord{},
+		Attachments:    []SubjectAttachment{},
+	}
+	for _, table := range subjectTables {
+		arg := any(hashEmail)
+		if table.byTenant {
+			if len(tenantIds) == 0 {
+				continue
+			}
+			arg = pq.Array(tenantIds)
+		}
+
+		records, err := exportSubjectTable(ctx, table, arg)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to export %s: %s", table.name, err.Error()))
+			return SubjectExport{}, err
+		}
+		if len(records) > 0 {
+			export.Records[table.name] = records
+		}
+	}
+
+	if len(tenantIds) > 0 {
+		export.Attachments, err = exportSubjectAttachments(ctx, tenantIds)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to export maintenance photos: %s", err.Error()))
+			return SubjectExport{}, err
+		}
+	}
+
+	// the export is only handed over once it is on record
+	err = recordDataSubjectAudit(ctx, AuditSubjectExported, tenantIds, applicationIds, fmt.Sprintf("%d tables, %d photos", len(export.Records), len(export.Attachments)))
+	if err != nil {
+		return SubjectExport{}, err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Exported data subject: tenants %v, applications %v", tenantIds, applicationIds))
+	return export, nil
+}
+
+// getDataSubjectIds returns the IDs of the tenant accounts and applications with the hashed email.
+// It returns sql.ErrNoRows if there are none.
+func getDataSubjectIds(ctx context.Context, hashEmail string) ([]int, []int, error) {
+	tenantIds, err := queryIds(ctx, `SELECT id FROM lhp_tenants WHERE hash_email = $1 ORDER BY id;`, hashEmail)
+	if err != nil {
+		return nil, nil, err
+	}
+	applicationIds, err := queryIds(ctx, `SELECT id FROM lhp_tenant_application WHERE hash_email = $1 ORDER BY id;`, hashEmail)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tenantIds) == 0 && len(applicationIds) == 0 {
+		return nil, nil, sql.ErrNoRows
+	}
+	return tenantIds, applicationIds, nil
+}
+
+// queryIds returns the IDs selected by a query.
+func queryIds(ctx context.Context, query string, args ...any) ([]int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// exportSubjectTable reads the subject's rows from one table, leaving out subjectExportSkipped columns and decrypting
+// encrypted ones, which are named without their encrypt_ prefix.
+func exportSubjectTable(ctx context.Context, table subjectTable, arg any) ([]SubjectRecord, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT * FROM %s WHERE %s ORDER BY id;`, table.name, table.where), arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []SubjectRecord
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+
+		record := SubjectRecord{}
+		for i, column := range columns {
+			if subjectExportSkipped[column] || strings.HasPrefix(column, "hash_") {
+				continue
+			}
+			value := values[i]
+			data, isBytes := value.([]byte)
+			switch {
+			case strings.HasPrefix(column, "encrypt_") && isBytes && len(data) > 0:
+				decrypted, err := utils.Decrypt(data)
+				if err != nil {
+					return nil, fmt.Errorf("failed to decrypt %s.%s: %s", table.name, column, err.Error())
+				}
+				value = string(decrypted)
+			case isBytes:
+				value = string(data)
+			}
+			record[strings.TrimPrefix(column, "encrypt_")] = value
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// exportSubjectAttachments reads and decrypts the photos attached to the tenants' maintenance tickets.
+// Each photo is read by its own query, so a tenant with many photos is not cut short by the query timeout.
+func exportSubjectAttachments(ctx context.Context, tenantIds []int) ([]SubjectAttachment, error) {
+	attachments, err := listSubjectAttachments(ctx, tenantIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range attachments {
+		encryptData, err := readSubjectAttachment(ctx, attachments[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		attachments[i].Data, err = utils.Decrypt(encryptData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt maintenance photo %d: %s", attachments[i].ID, err.Error())
+		}
+	}
+	return attachments, nil
+}
+
+// listSubjectAttachments returns the photos attached to the tenants' maintenance tickets, without their data.
+func listSubjectAttachments(ctx context.Context, tenantIds []int) ([]SubjectAttachment, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	SELECT ma.id, ma.ticket_id, ma.file_name, ma.content_type
+	FROM lhp_maintenance_attachments ma
+	JOIN lhp_maintenance_tickets mt ON mt.id = ma.ticket_id
+	WHERE mt.tenant_id = ANY($1)
+	ORDER BY ma.id;
+	`
+	rows, err := db.QueryContext(ctx, query, pq.Array(tenantIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []SubjectAttachment{}
+	for rows.Next() {
+		var attachment SubjectAttachment
+		err := rows.Scan(&attachment.ID, &attachment.TicketID, &attachment.FileName, &attachment.ContentType)
+		if err != nil {
+			return nil, err
+		}
+		// uploaded file names are only used for the base name, so they cannot place files elsewhere in the bundle
+		attachment.File = fmt.Sprintf("attachments/%d-%s", attachment.ID, path.Base(strings.ReplaceAll(attachment.FileName, "\\", "/")))
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// readSubjectAttachment returns the encrypted data of one maintenance photo.
+func readSubjectAttachment(ctx context.Context, attachmentId int) ([]byte, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var encryptData []byte
+	err := db.QueryRowContext(ctx, `SELECT encrypt_data FROM lhp_maintenance_attachments WHERE id = $1;`, attachmentId).Scan(&encryptData)
+	return encryptData, err
+}
+
+/*
+WriteZip writes the export as a ZIP bundle: data.json holds the records, and each maintenance photo
+is stored at its File path under attachments/, named by its ID and original file name.
+
+Arguments:
+
+- w: Where the bundle is written to.
+
+Returns:
+
+- error: An error object if the bundle cannot be written.
+*/
+func (e SubjectExport) WriteZip(w io.Writer) error {
+	bundle := zip.NewWriter(w)
+
+	data, err := bundle.Create("data.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(data)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(e)
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range e.Attachments {
+		file, err := bundle.Create(attachment.File)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(attachment.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return bundle.Close()
+}
+
+/*
+EraseDataSubject erases a tenant or applicant's personal data, found by email as in ExportDataSubject.
+
+Their applications, names, emails, maintenance requests and notes are overwritten with ErasedValue, and the hashes they
+were looked up by are replaced, so nothing left can be tied back to them. Their messages, maintenance photos, unsent emails
+and login data (two-factor, password resets, login throttles) are deleted, and tenant accounts can no longer log in.
+
+Rent ledgers, leases and lease renewals are kept, since financial records must be retained. They are tied to the
+tenant ID only, which no longer leads to a name or email. The audit log is append-only, so its entries are kept too;
+they record tenants by ID and never by name or email.
+
+Tenants must have moved out (their lease ended and account archived) before they can be erased.
+Each landlord whose records are erased gets an entry in their audit log.
+As with ExportDataSubject, each statement has its own config.Database.QueryTimeout.
+
+Arguments:
+
+- email: The subject's email address.
+
+Returns:
+
+- SubjectErasure: The records erased.
+
+- error: sql.ErrNoRows if nothing is held about the email, ErrTenancyActive if a tenant account is not archived yet,
+ErrSubjectNotAudited if the records were erased but the audit log could not be written to,
+or an error object if the records cannot be erased.
+*/
+func EraseDataSubject(ctx context.Context, email string) (SubjectErasure, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return SubjectErasure{}, errors.New("database connection is not initialized")
+	}
+
+	hashEmail := utils.HashData(email)
+	tenantIds, applicationIds, err := getDataSubjectIds(ctx, hashEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to find data subject: %s", err.Error()))
+		return SubjectErasure{}, err
+	}
+	erasure := SubjectErasure{TenantIDs: tenantIds, ApplicationIDs: applicationIds}
+
+	erased, err := utils.Encrypt([]byte(ErasedValue))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt erased value: %s", err.Error()))
+		return SubjectErasure{}, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return SubjectErasure{}, err
+	}
+	defer tx.Rollback()
+
+	var activeTenants int
+	countCtx, cancel := withQueryTimeout(ctx)
+	err = tx.QueryRowContext(countCtx, `SELECT COUNT(*) FROM lhp_tenants WHERE id = ANY($1) AND archived_at IS NULL;`, pq.Array(tenantIds)).Scan(&activeTenants)
+	cancel()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to check tenancies: %s", err.Error()))
+		return SubjectErasure{}, err
+	}
+	if activeTenants > 0 {
+		logs.Logs(logDbErr, fmt.Sprintf("Cannot erase data subject: %d tenant account(s) still active", activeTenants))
+		return SubjectErasure{}, ErrTenancyActive
+	}
+
+	// every encrypted column of an application is personal data
+	var assignments []string
+	for _, table := range encryptedTables {
+		if table.name != "lhp_tenant_application" {
+			continue
+		}
+		for _, column := range table.columns {
+			assignments = append(assignments, column+" = $1")
+		}
+	}
+	query := fmt.Sprintf(`
+	UPDATE lhp_tenant_application
+	SET %s,
+		hash_full_name = 'erased:' || id,
+		hash_dob = 'erased:' || id,
+		hash_passport_number = 'erased:' || id,
+		hash_email = 'erased:' || id
+	WHERE id = ANY($2);
+	`, strings.Join(assignments, ", "))
+	_, err = execWithQueryTimeout(ctx, tx, query, erased, pq.Array(applicationIds))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to erase applications: %s", err.Error()))
+		return SubjectErasure{}, err
+	}
+
+	// the tenancy terms stay with the tenant record, alongside the rent ledger they explain
+	query = `
+	UPDATE lhp_tenants
+	SET encrypt_email = $1,
+		encrypt_tenant_name = $1,
+		hash_email = 'erased:' || id,
+		hash_password = 'erased',
+		session_token = NULL,
+		csrf_token = NULL,
+		token_expiry = NULL
+	WHERE id = ANY($2);
+	`
+	_, err = execWithQueryTimeout(ctx, tx, query, erased, pq.Array(tenantIds))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to erase tenants: %s", err.Error()))
+		return SubjectErasure{}, err
+	}
+
+	result, err := execWithQueryTimeout(ctx, tx, `
+	DELETE FROM lhp_messages
+	WHERE (sender_type = 'tenant' AND sender_id = ANY($1)) OR (receiver_type = 'tenant' AND receiver_id = ANY($1));
+	`, pq.Array(tenantIds))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to delete messages: %s", err.Error()))
+		return SubjectErasure{}, err
+	}
+	erasure.MessagesDeleted, _ = result.RowsAffected()
+
+	result, err = execWithQueryTimeout(ctx, tx, `
+	DELETE FROM lhp_maintenance_attachments
+	WHERE ticket_id IN (SELECT id FROM lhp_maintenance_tickets WHERE tenant_id = ANY($1));
+	`, pq.Array(tenantIds))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to delete maintenance photos: %s", err.Error()))
+		return SubjectErasure{}, err
+	}
+	erasure.AttachmentsDeleted, _ = result.RowsAffected()
+
+	_, err = execWithQueryTimeout(ctx, tx, `UPDATE lhp_maintenance_tickets SET encrypt_description = $1 WHERE tenant_id = ANY($2);`, erased, pq.Array(tenantIds))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to erase maintenance tickets: %s", err.Error()))
+		return SubjectErasure{}, err
+	}
+	_, err = execWithQueryTimeout(ctx, tx, `
+	UPDATE lhp_maintenance_ticket_events
+	SET encrypt_note = $1
+	WHERE encrypt_note IS NOT NULL
+		AND ticket_id IN (SELECT id FROM lhp_maintenance_tickets WHERE tenant_id = ANY($2));
+	`, erased, pq.Array(tenantIds))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to erase maintenance notes: %s", err.Error()))
+		return SubjectErasure{}, err
+	}
+
+	// emails about them that have not been sent yet hold their name, email or login details
+	_, err = execWithQueryTimeout(ctx, tx, `DELETE FROM lhp_email_outbox WHERE hash_subject = $1;`, hashEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to delete unsent emails: %s", err.Error()))
+		return SubjectErasure{}, err
+	}
+
+	loginQueries := []string{
+		`DELETE FROM lhp_two_factor WHERE user_type = 'tenant' AND user_key = $1;`,
+		`DELETE FROM lhp_two_factor_recovery_codes WHERE user_type = 'tenant' AND user_key = $1;`,
+		`DELETE FROM lhp_two_factor_challenges WHERE user_type = 'tenant' AND user_key = $1;`,
+		`DELETE FROM lhp_password_resets WHERE user_type = 'tenant' AND user_key = $1;`,
+		`DELETE FROM lhp_login_throttles WHERE scope = 'account' AND user_type = 'tenant' AND throttle_key = $1;`,
+	}
+	for _, query := range loginQueries {
+		_, err = execWithQueryTimeout(ctx, tx, query, hashEmail)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to delete login data: %s", err.Error()))
+			return SubjectErasure{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit erasure: %s", err.Error()))
+		return SubjectErasure{}, err
+	}
+	logs.Logs(logDb, fmt.Sprintf("Erased data subject: tenants %v, applications %v", tenantIds, applicationIds))
+
+	// the data is already erased, so it is recorded even if the caller has given up waiting,
+	// and a failure to record it is returned alongside the erasure
+	err = recordDataSubjectAudit(context.WithoutCancel(ctx), AuditSubjectErased, tenantIds, applicationIds, fmt.Sprintf("%d messages and %d photos deleted", erasure.MessagesDeleted, erasure.AttachmentsDeleted))
+	if err != nil {
+		return erasure, fmt.Errorf("%w: %w", ErrSubjectNotAudited, err)
+	}
+	return erasure, nil
+}
+
+// execWithQueryTimeout runs one statement of a transaction with its own config.Database.QueryTimeout,
+// so a long transaction is not cut short by the time taken by the statements before it.
+func execWithQueryTimeout(ctx context.Context, tx *sql.Tx, query string, args ...any) (sql.Result, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+	return tx.ExecContext(ctx, query, args...)
+}
+
+// recordDataSubjectAudit adds an entry for a subject access export or erasure to the audit log of each landlord
+// the subject's tenant accounts or applications belong to.
+func recordDataSubjectAudit(ctx context.Context, action string, tenantIds, applicationIds []int, details string) error {
+	query := `
+	SELECT DISTINCT l.email
+	FROM lhp_landlords l
+	WHERE l.id IN (SELECT landlord_id FROM lhp_tenants WHERE id = ANY($1))
+		OR l.id IN (SELECT landlord_id FROM lhp_tenant_application WHERE id = ANY($2));
+	`
+	queryCtx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+	rows, err := db.QueryContext(queryCtx, query, pq.Array(tenantIds), pq.Array(applicationIds))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlords for audit log: %s", err.Error()))
+		return err
+	}
+	var landlordEmails []string
+	for rows.Next() {
+		var landlordEmail string
+		err := rows.Scan(&landlordEmail)
+		if err != nil {
+			rows.Close()
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan landlord for audit log: %s", err.Error()))
+			return err
+		}
+		landlordEmails = append(landlordEmails, landlordEmail)
+	}
+	rows.Close()
+	err = rows.Err()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlords for audit log: %s", err.Error()))
+		return err
+	}
+
+	for _, landlordEmail := range landlordEmails {
+		err := RecordAudit(ctx, AuditEntry{
+			ActorType:     AuditActorOperator,
+			Actor:         "command line",
+			LandlordEmail: landlordEmail,
+			Action:        action,
+			TargetType:    "data_subject",
+			TargetID:      fmt.Sprintf("tenants %v, applications %v", tenantIds, applicationIds),
+			Details:       details,
+		})
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to record %s in audit log: %s", action, err.Error()))
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// subjectResponder answers the queries of ExportDataSubject and EraseDataSubject for a subject with tenant account 7
+// and application 3, whose tenancy is active when activeTenants is 1. Every table has one row about the subject.
+func subjectResponder(t *testing.T, activeTenants int64) func(query string, args []driver.Value) fakeResponse {
+	initTestEncryption()
+	encrypt := func(value string) []byte {
+		data, err := utils.Encrypt([]byte(value))
+		if err != nil {
+			t.Fatalf("Failed to encrypt test data: %s", err.Error())
+		}
+		return data
+	}
+	email := encrypt("tenant@example.com")
+	photo := encrypt("photo data")
+
+	return func(query string, args []driver.Value) fakeResponse {
+		switch {
+		case strings.HasPrefix(query, "SELECT id FROM lhp_tenants WHERE hash_email"):
+			return fakeResponse{columns: []string{"id"}, rows: [][]driver.Value{{int64(7)}}}
+		case strings.HasPrefix(query, "SELECT id FROM lhp_tenant_application WHERE hash_email"):
+			return fakeResponse{columns: []string{"id"}, rows: [][]driver.Value{{int64(3)}}}
+		case strings.HasPrefix(query, "SELECT COUNT(*) FROM lhp_tenants"):
+			return fakeResponse{columns: []string{"count"}, rows: [][]driver.Value{{activeTenants}}}
+		case strings.HasPrefix(query, "SELECT * FROM "):
+			return fakeResponse{
+				columns: []string{"id", "hash_email", "session_token", "encrypt_email", "status"},
+				rows:    [][]driver.Value{{int64(1), "hash", "session", email, []byte("active")}},
+			}
+		case strings.Contains(query, "FROM lhp_maintenance_attachments ma"):
+			return fakeResponse{
+				columns: []string{"id", "ticket_id", "file_name", "content_type"},
+				rows:    [][]driver.Value{{int64(5), int64(2), `..\..\boiler.jpg`, "image/jpeg"}},
+			}
+		case strings.HasPrefix(query, "SELECT encrypt_data FROM lhp_maintenance_attachments"):
+			return fakeResponse{columns: []string{"encrypt_data"}, rows: [][]driver.Value{{photo}}}
+		case strings.HasPrefix(query, "SELECT DISTINCT l.email"):
+			return fakeResponse{columns: []string{"email"}, rows: [][]driver.Value{{"landlord@example.com"}}}
+		case strings.HasPrefix(query, "DELETE FROM lhp_messages"):
+			return fakeResponse{rowsAffected: 4}
+		case strings.HasPrefix(query, "DELETE FROM lhp_maintenance_attachments"):
+			return fakeResponse{rowsAffected: 2}
+		}
+		return fakeResponse{}
+	}
+}
+
+// writesTo returns the statements that change the table
+func writesTo(statements []fakeStatement, table string) []fakeStatement {
+	var writes []fakeStatement
+	for _, statement := range statements {
+		for _, prefix := range []string{"UPDATE ", "DELETE FROM ", "INSERT INTO ", "TRUNCATE "} {
+			if strings.HasPrefix(statement.query, prefix+table+" ") {
+				writes = append(writes, statement)
+			}
+		}
+	}
+	return writes
+}
+
+func TestEraseDataSubjectActiveTenancy(t *testing.T) {
+	fake := useFakeDB(t, subjectResponder(t, 1))
+
+	_, err := EraseDataSubject(context.Background(), "tenant@example.com")
+	if !errors.Is(err, ErrTenancyActive) {
+		t.Fatalf("Expected ErrTenancyActive, got %v", err)
+	}
+
+	statements := fake.ran()
+	for _, statement := range statements {
+		if strings.HasPrefix(statement.query, "UPDATE ") || strings.HasPrefix(statement.query, "DELETE ") || statement.query == "COMMIT" {
+			t.Errorf("Expected nothing to be changed, ran: %s", statement.query)
+		}
+	}
+	if last := statements[len(statements)-1].query; last != "ROLLBACK" {
+		t.Errorf("Expected the transaction to be rolled back, last ran: %s", last)
+	}
+}
+
+func TestEraseDataSubject(t *testing.T) {
+	fake := useFakeDB(t, subjectResponder(t, 0))
+
+	erasure, err := EraseDataSubject(context.Background(), "tenant@example.com")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(erasure.TenantIDs) != 1 || erasure.TenantIDs[0] != 7 || len(erasure.ApplicationIDs) != 1 || erasure.ApplicationIDs[0] != 3 {
+		t.Errorf("Expected tenant 7 and application 3 to be erased, got tenants %v and applications %v", erasure.TenantIDs, erasure.ApplicationIDs)
+	}
+	if erasure.MessagesDeleted != 4 || erasure.AttachmentsDeleted != 2 {
+		t.Errorf("Expected 4 messages and 2 photos deleted, got %d and %d", erasure.MessagesDeleted, erasure.AttachmentsDeleted)
+	}
+	statements := fake.ran()
+
+	t.Run("Application and tenant personal data overwritten", func(t *testing.T) {
+		columns := map[string][]string{
+			"lhp_tenant_application": {"hash_email", "hash_full_name", "hash_passport_number"},
+			"lhp_tenants":            {"encrypt_email", "encrypt_tenant_name", "hash_email", "hash_password", "session_token"},
+		}
+		for _, table := range encryptedTables {
+			if table.name == "lhp_tenant_application" {
+				columns[table.name] = append(columns[table.name], table.columns...)
+			}
+		}
+
+		for table, tableColumns := range columns {
+			writes := writesTo(statements, table)
+			if len(writes) != 1 || !strings.HasPrefix(writes[0].query, "UPDATE ") {
+				t.Fatalf("Expected %s to be updated once, got %v", table, writes)
+			}
+			for _, column := range tableColumns {
+				if !strings.Contains(writes[0].query, column+" = ") {
+					t.Errorf("Expected %s.%s to be overwritten", table, column)
+				}
+			}
+			erased, ok := writes[0].args[0].([]byte)
+			if !ok {
+				t.Fatalf("Expected %s to be overwritten with an encrypted value, got %T", table, writes[0].args[0])
+			}
+			if decrypted, err := utils.Decrypt(erased); err != nil || string(decrypted) != ErasedValue {
+				t.Errorf("Expected %s to be overwritten with %q, got %q", table, ErasedValue, decrypted)
+			}
+		}
+	})
+
+	t.Run("Rent ledger and lease kept", func(t *testing.T) {
+		for _, table := range []string{"lhp_rent_ledger", "lhp_leases", "lhp_lease_renewals"} {
+			if writes := writesTo(statements, table); len(writes) != 0 {
+				t.Errorf("Expected %s to be kept, ran: %v", table, writes)
+			}
+		}
+		// the audit log is only added to
+		for _, write := range writesTo(statements, "lhp_audit_log") {
+			if !strings.HasPrefix(write.query, "INSERT INTO ") {
+				t.Errorf("Expected the audit log to be kept, ran: %s", write.query)
+			}
+		}
+	})
+
+	t.Run("Messages, photos, unsent emails and login data deleted", func(t *testing.T) {
+		for _, table := range []string{"lhp_messages", "lhp_maintenance_attachments", "lhp_email_outbox", "lhp_two_factor", "lhp_password_resets", "lhp_login_throttles"} {
+			writes := writesTo(statements, table)
+			if len(writes) != 1 || !strings.HasPrefix(writes[0].query, "DELETE FROM ") {
+				t.Errorf("Expected the subject's rows in %s to be deleted, got %v", table, writes)
+			}
+		}
+	})
+
+	t.Run("Erasure committed and audited", func(t *testing.T) {
+		committed := false
+		for _, statement := range statements {
+			committed = committed || statement.query == "COMMIT"
+		}
+		if !committed {
+			t.Errorf("Expected the erasure to be committed")
+		}
+		audits := writesTo(statements, "lhp_audit_log")
+		if len(audits) != 1 || audits[0].args[3] != "landlord@example.com" || audits[0].args[4] != AuditSubjectErased {
+			t.Errorf("Expected the erasure in the landlord's audit log, got %v", audits)
+		}
+	})
+}
+
+func TestEraseDataSubjectNotAudited(t *testing.T) {
+	respond := subjectResponder(t, 0)
+	fake := useFakeDB(t, func(query string, args []driver.Value) fakeResponse {
+		if strings.HasPrefix(query, "INSERT INTO lhp_audit_log") {
+			return fakeResponse{err: errors.New("audit log unavailable")}
+		}
+		return respond(query, args)
+	})
+
+	// the erasure is committed before it is audited, so it is returned along with the error
+	erasure, err := EraseDataSubject(context.Background(), "tenant@example.com")
+	if !errors.Is(err, ErrSubjectNotAudited) {
+		t.Fatalf("Expected ErrSubjectNotAudited, got %v", err)
+	}
+	if len(erasure.TenantIDs) != 1 || erasure.TenantIDs[0] != 7 || erasure.MessagesDeleted != 4 {
+		t.Errorf("Expected the erasure of tenant 7 to be returned, got %v", erasure)
+	}
+	committed := false
+	for _, statement := range fake.ran() {
+		committed = committed || statement.query == "COMMIT"
+	}
+	if !committed {
+		t.Errorf("Expected the erasure to be committed")
+	}
+}
+
+func TestEraseDataSubjectUnknown(t *testing.T) {
+	useFakeDB(t, nil)
+
+	_, err := EraseDataSubject(context.Background(), "nobody@example.com")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestExportDataSubject(t *testing.T) {
+	fake := useFakeDB(t, subjectResponder(t, 1))
+
+	export, err := ExportDataSubject(context.Background(), "tenant@example.com")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// every table holding personal data is read, including unsent emails
+	for _, table := range subjectTables {
+		records, ok := export.Records[table.name]
+		if !ok || len(records) != 1 {
+			t.Errorf("Expected the export to include %s", table.name)
+			continue
+		}
+		record := records[0]
+		if record["email"] != "tenant@example.com" {
+			t.Errorf("Expected %s.encrypt_email to be exported decrypted as email, got %v", table.name, record["email"])
+		}
+		if record["status"] != "active" {
+			t.Errorf("Expected %s.status to be exported as text, got %v", table.name, record["status"])
+		}
+		for _, column := range []string{"hash_email", "session_token", "encrypt_email"} {
+			if _, ok := record[column]; ok {
+				t.Errorf("Expected %s.%s to be left out of the export", table.name, column)
+			}
+		}
+	}
+	if _, ok := export.Records["lhp_email_outbox"]; !ok {
+		t.Errorf("Expected the export to include lhp_email_outbox")
+	}
+
+	if len(export.Attachments) != 1 || string(export.Attachments[0].Data) != "photo data" {
+		t.Fatalf("Expected the maintenance photo to be exported decrypted, got %v", export.Attachments)
+	}
+	if export.Attachments[0].File != "attachments/5-boiler.jpg" {
+		t.Errorf("Expected the photo to be stored under attachments by its base name, got %s", export.Attachments[0].File)
+	}
+
+	audits := writesTo(fake.ran(), "lhp_audit_log")
+	if len(audits) != 1 || audits[0].args[4] != AuditSubjectExported {
+		t.Errorf("Expected the export in the landlord's audit log, got %v", audits)
+	}
+}
+
+func TestSubjectTablesCoverEncryptedTables(t *testing.T) {
+	// every encrypted table is about tenants or applicants, so each must be exported;
+	// maintenance photos are exported as files rather than records
+	exported := map[string]bool{"lhp_maintenance_attachments": true}
+	for _, table := range subjectTables {
+		exported[table.name] = true
+	}
+	for _, table := range encryptedTables {
+		if !exported[table.name] {
+			t.Errorf("Expected %s to be in subjectTables", table.name)
+		}
+	}
+}
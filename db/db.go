@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 
 	_ "github.com/lib/pq"
 
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/env"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
@@ -60,17 +62,60 @@ func ConnectDB() error {
 		logs.Logs(logDbErr, "Database connection is empty!")
 		return errors.New("database connection not established")
 	}
+	db.SetMaxOpenConns(config.Database.MaxOpenConns)
+	db.SetMaxIdleConns(config.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(config.Database.ConnMaxLifetime)
+
 	err = db.Ping()
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Cannot ping database: %s", err.Error()))
 		return err
 	}
 	logs.Logs(logDb, "Database connection established.")
+
+	if config.Database.AutoMigrate {
+		_, err = MigrateUp()
+		return err
+	}
+
+	// without DB_AUTO_MIGRATE the schema is left alone, but the app may not work until pending migrations are applied
+	states, err := GetMigrationStatus()
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, state := range states {
+		if !state.Applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		logs.Logs(logWarning, fmt.Sprintf("%d database migrations are pending. Run with -migrate or set DB_AUTO_MIGRATE=true to apply them.", pending))
+	}
 	return nil
 }
 
 /*
-CreateNewLandlord creates a new landlord in the database.
+Ping checks the database connection made by ConnectDB is still alive, for the app's readiness check.
+
+Returns:
+
+- error: An error object if the database cannot be reached.
+*/
+func Ping(ctx context.Context) error {
+	if db == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return db.PingContext(ctx)
+}
+
+/*
+CreateNewLandlord creates a new landlord in the database. The first landlord account becomes the owner,
+who manages the other accounts and invites new landlords.
 
 Arguments:
 
@@ -82,12 +127,15 @@ Returns:
 
 - error: An error object if the landlord cannot be created.
 */
-func CreateNewLandlord(landlordEmail, landlordPassword string) error {
+func CreateNewLandlord(ctx context.Context, landlordEmail, landlordPassword string) error {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is empty!")
 		return errors.New("database connection not established")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	hashPassword, err := utils.HashedPassword(landlordPassword)
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Error hashing password: %s", err.Error()))
@@ -95,10 +143,10 @@ func CreateNewLandlord(landlordEmail, landlordPassword string) error {
 	}
 
 	query := `
-	INSERT INTO lhp_landlords (email, password, created_at)
-	VALUES ($1, $2, NOW());
+	INSERT INTO lhp_landlords (email, password, created_at, account_role)
+	VALUES ($1, $2, NOW(), CASE WHEN EXISTS (SELECT 1 FROM lhp_landlords) THEN 'landlord' ELSE 'owner' END);
 	`
-	_, err = db.Exec(query, landlordEmail, hashPassword)
+	_, err = db.ExecContext(ctx, query, landlordEmail, hashPassword)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Error creating new landlord: %s", err.Error()))
 		return err
@@ -106,12 +154,15 @@ func CreateNewLandlord(landlordEmail, landlordPassword string) error {
 	return nil
 }
 
-func GetTenantNameByEmail(email string) (string, error) {
+func GetTenantNameByEmail(ctx context.Context, email string) (string, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is empty!")
 		return "", errors.New("database connection not established")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	hashEmail := utils.HashData(email)
 
 	var encryptedTenantName string
@@ -120,7 +171,7 @@ func GetTenantNameByEmail(email string) (string, error) {
 	FROM lhp_tenant_application 
 	WHERE hash_email = $1;
 	`
-	err := db.QueryRow(query, hashEmail).Scan(&encryptedTenantName)
+	err := db.QueryRowContext(ctx, query, hashEmail).Scan(&encryptedTenantName)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Error getting tenant name: %s", err.Error()))
 		return "", err
@@ -129,12 +180,15 @@ func GetTenantNameByEmail(email string) (string, error) {
 	return encryptedTenantName, nil
 }
 
-func GetTenantNameByHashEmail(email string) (string, error) {
+func GetTenantNameByHashEmail(ctx context.Context, email string) (string, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is empty!")
 		return "", errors.New("database connection not established")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var encryptedTenantName string
 
 	query := `
@@ -142,7 +196,7 @@ func GetTenantNameByHashEmail(email string) (string, error) {
 	FROM lhp_tenants 
 	WHERE hash_email = $1;
 	`
-	err := db.QueryRow(query, email).Scan(&encryptedTenantName)
+	err := db.QueryRowContext(ctx, query, email).Scan(&encryptedTenantName)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Error getting tenant name: %s", err.Error()))
 		return "", err
@@ -154,18 +208,20 @@ func GetTenantNameByHashEmail(email string) (string, error) {
 /*
 CreateNewTenant creates a new tenant record in the database.
 
-This function checks if the database connection is initialized and retrieves the landlord email from environment variables.
-If the landlord email is not found, it attempts to load it from a .env file. It then retrieves the landlord ID using the
-landlord email. The tenant's email and password are hashed and encrypted along with other tenant details such as room type,
+This function checks if the database connection is initialized and retrieves the landlord ID using the
+landlord email. The tenant is given a room in one of the landlord's properties, which must still have space for them.
+The tenant's email and password are hashed and encrypted along with other tenant details such as room type,
 move-in date, rent due, and monthly rent. These details are then inserted into the lhp_tenants table.
 
 Arguments:
 
+- landlordEmail: The email of the landlord accepting the tenant.
+
 - tenantEmail: The email address of the tenant.
 
 - tenantPassword: The password for the tenant's account.
 
-- roomType: The type of room assigned to the tenant.
+- roomId: The ID of the room assigned to the tenant.
 
 - moveInDate: The date the tenant will move in.
 
@@ -177,133 +233,148 @@ Arguments:
 
 Returns:
 
-- error: An error object if the tenant cannot be created.
+- error: ErrRoomFull if the room has no space left, or an error object if the tenant cannot be created.
 */
-func CreateNewTenant(tenantEmail, tenantPassword, roomType, moveInDate, rentDue, monthlyRent, currency string) error {
+func CreateNewTenant(ctx context.Context, landlordEmail string, roomId int, tenantEmail, tenantPassword, moveInDate, rentDue, monthlyRent, currency string) error {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return errors.New("database connection is not initialized")
 	}
 
-	// get landlord email via environment variable
-	if os.Getenv("LANDLORD_EMAIL") == "" {
-		logs.Logs(logWarning, "Could not get landlord email from hosting platform. Loading from .env file...")
-		err := env.LoadEnv("env/.env")
-		if err != nil {
-			logs.Logs(logErr, fmt.Sprintf("Could not load environment variables from .env file: %s", err.Error()))
-			return err
-		}
-	}
-
-	landlordEmail := os.Getenv("LANDLORD_EMAIL")
-	if landlordEmail == "" {
-		logs.Logs(logDbErr, "Landlord email is empty!")
-		return errors.New("landlord email is empty")
-	}
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
 	// get landlord id
-	landlordId, err := GetLandlordIdByEmail(landlordEmail)
+	landlordId, err := GetLandlordIdByEmail(ctx, landlordEmail)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord ID: %s", err.Error()))
 		return err
 	}
 
+	// the room must be in one of the landlord's properties
+	room, err := GetLandlordRoom(ctx, landlordEmail, roomId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get room: %s", err.Error()))
+		return err
+	}
+
 	// get encrypted tenant name via tenantEmail
-	encryptedTenantName, err := GetTenantNameByEmail(tenantEmail)
+	encryptedTenantName, err := GetTenantNameByEmail(ctx, tenantEmail)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to get tenant name: %s", err.Error()))
 		return err
 	}
 
+	args, err := newTenantArgs(landlordId, room, tenantEmail, tenantPassword, moveInDate, rentDue, monthlyRent, currency, encryptedTenantName)
+	if err != nil {
+		return err
+	}
+	return insertTenantIntoRoom(ctx, roomId, createTenantQuery+";", args...)
+}
+
+// createTenantQuery inserts a tenant given a room by their landlord, taking the arguments made by newTenantArgs.
+const createTenantQuery = `
+	INSERT INTO lhp_tenants (
+		landlord_id,
+		hash_email,
+		hash_password,
+		created_at,
+		encrypt_email,
+		encrypt_room_type,
+		encrypt_move_in_date,
+		encrypt_rent_due,
+		encrypt_monthly_rent,
+		currency,
+		encrypt_tenant_name,
+		property_id,
+		room_id
+	)
+	VALUES ($1, $2, $3, NOW(), $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+/*
+newTenantArgs hashes and encrypts the details of a new tenant into the arguments of createTenantQuery.
+
+Arguments:
+
+- landlordId: The ID of the landlord accepting the tenant.
+
+- room: The room the tenant is given.
+
+- tenantEmail: The email address of the tenant, also their username.
+
+- tenantPassword: The password for the tenant's account.
+
+- moveInDate, rentDue, monthlyRent, currency: The terms of the tenancy.
+
+- encryptedTenantName: The tenant's name, as encrypted in their application.
+
+Returns:
+
+- []any: The arguments of createTenantQuery.
+
+- error: An error object if a detail cannot be hashed or encrypted.
+*/
+func newTenantArgs(landlordId int, room Room, tenantEmail, tenantPassword, moveInDate, rentDue, monthlyRent, currency string, encryptedTenantName any) ([]any, error) {
 	// hash & encrypt identifiers
 	hashEmail := utils.HashData(tenantEmail)
-	hashPassword := utils.HashData(tenantPassword)
-
-	encrypt_email, err := utils.Encrypt([]byte(tenantEmail))
+	hashPassword, err := utils.HashedPassword(tenantPassword)
 	if err != nil {
-		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt email: %s", err.Error()))
-		return err
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to hash password: %s", err.Error()))
+		return nil, err
 	}
 
-	encrypt_password, err := utils.Encrypt([]byte(tenantPassword))
+	encrypt_email, err := utils.Encrypt([]byte(tenantEmail))
 	if err != nil {
-		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt password: %s", err.Error()))
-		return err
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt email: %s", err.Error()))
+		return nil, err
 	}
 
-	encrypt_room_type, err := utils.Encrypt([]byte(roomType))
+	encrypt_room_type, err := utils.Encrypt([]byte(room.RoomType))
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt room type: %s", err.Error()))
-		return err
+		return nil, err
 	}
 
 	encrypt_move_in_date, err := utils.Encrypt([]byte(moveInDate))
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt move in date: %s", err.Error()))
-		return err
+		return nil, err
 	}
 
 	encrypt_rent_due, err := utils.Encrypt([]byte(rentDue))
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt rent due: %s", err.Error()))
-		return err
+		return nil, err
 	}
 
 	encrypt_monthly_rent, err := utils.Encrypt([]byte(monthlyRent))
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt monthly rent: %s", err.Error()))
-		return err
+		return nil, err
 	}
 
-	query := `
-	INSERT INTO lhp_tenants (
-		landlord_id,
-		hash_email,
-		hash_password,
-		created_at,
-		encrypt_email,
-		encrypt_password,
-		encrypt_room_type,
-		encrypt_move_in_date,
-		encrypt_rent_due,
-		encrypt_monthly_rent,
-		currency,
-		encrypt_tenant_name
-	)
-	VALUES ($1, $2, $3, NOW(), $4, $5, $6, $7, $8, $9, $10, $11);
-	`
-	_, err = db.Exec(query, landlordId, hashEmail, hashPassword, encrypt_email, encrypt_password, encrypt_room_type, encrypt_move_in_date, encrypt_rent_due, encrypt_monthly_rent, currency, encryptedTenantName)
-	if err != nil {
-		logs.Logs(logDbErr, fmt.Sprintf("Failed to create new tenant: %s", err.Error()))
-		return err
-	}
-	return nil
+	return []any{landlordId, hashEmail, hashPassword, encrypt_email, encrypt_room_type, encrypt_move_in_date, encrypt_rent_due, encrypt_monthly_rent, currency, encryptedTenantName, room.PropertyID, room.ID}, nil
 }
 
-func ManuallyCreateNewTenant(tenantFullName, tenantPassportID, tenantEmail, roomType, moveInDate, rentDue, monthlyRent, currency string) error {
+func ManuallyCreateNewTenant(ctx context.Context, landlordEmail string, roomId int, tenantFullName, tenantPassportID, tenantEmail, moveInDate, rentDue, monthlyRent, currency string) error {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return errors.New("database connection is not initialized")
 	}
 
-	// get landlord email via environment variable
-	if os.Getenv("LANDLORD_EMAIL") == "" {
-		logs.Logs(logWarning, "Could not get landlord email from hosting platform. Loading from .env file...")
-		err := env.LoadEnv("env/.env")
-		if err != nil {
-			logs.Logs(logErr, fmt.Sprintf("Could not load environment variables from .env file: %s", err.Error()))
-			return err
-		}
-	}
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
-	landlordEmail := os.Getenv("LANDLORD_EMAIL")
-	if landlordEmail == "" {
-		logs.Logs(logDbErr, "Landlord email is empty!")
-		return errors.New("landlord email is empty")
+	// the room must be in one of the landlord's properties
+	room, err := GetLandlordRoom(ctx, landlordEmail, roomId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get room: %s", err.Error()))
+		return err
 	}
+	roomType := room.RoomType
 
 	// get landlord id
-	landlordId, err := GetLandlordIdByEmail(landlordEmail)
+	landlordId, err := GetLandlordIdByEmail(ctx, landlordEmail)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord ID: %s", err.Error()))
 		return err
@@ -317,7 +388,11 @@ func ManuallyCreateNewTenant(tenantFullName, tenantPassportID, tenantEmail, room
 	}
 
 	hashEmail := utils.HashData(tenantUsername)
-	hashPassword := utils.HashData(tenantPassword)
+	hashPassword, err := utils.HashedPassword(tenantPassword)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to hash password: %s", err.Error()))
+		return err
+	}
 
 	encryptName, err := utils.Encrypt([]byte(tenantFullName))
 	if err != nil {
@@ -331,12 +406,6 @@ func ManuallyCreateNewTenant(tenantFullName, tenantPassportID, tenantEmail, room
 		return err
 	}
 
-	encryptPassword, err := utils.Encrypt([]byte(tenantPassword)) // important that we encrypt the newly generated password here
-	if err != nil {
-		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt password: %s", err.Error()))
-		return err
-	}
-
 	encryptRoomType, err := utils.Encrypt([]byte(roomType))
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt room type: %s", err.Error()))
@@ -368,70 +437,93 @@ func ManuallyCreateNewTenant(tenantFullName, tenantPassportID, tenantEmail, room
 		hash_password,
 		encrypt_tenant_name,
 		encrypt_email,
-		encrypt_password,
 		encrypt_room_type,
 		encrypt_move_in_date,
 		encrypt_rent_due,
 		encrypt_monthly_rent,
 		currency,
+		property_id,
+		room_id,
 		created_at
 	)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW());
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW());
 	`
-	_, err = db.Exec(query, landlordId, hashEmail, hashPassword, encryptName, encryptEmail, encryptPassword, encryptRoomType, encryptMoveInDate, encryptRentDue, encryptMonthlyRent, currency)
+	return insertTenantIntoRoom(ctx, roomId, query, landlordId, hashEmail, hashPassword, encryptName, encryptEmail, encryptRoomType, encryptMoveInDate, encryptRentDue, encryptMonthlyRent, currency, room.PropertyID, roomId)
+}
+
+/*
+insertTenantIntoRoom runs the query creating a new tenant in the same transaction as the check that their room
+still has space, so two tenants cannot be given the last space in a room at the same time.
+
+Arguments:
+
+- roomId: The ID of the room the tenant is given.
+
+- query: The INSERT query creating the tenant.
+
+- args: The arguments of the query.
+
+Returns:
+
+- error: ErrRoomFull if the room has no space left, or an error object if the tenant cannot be created.
+*/
+func insertTenantIntoRoom(ctx context.Context, roomId int, query string, args ...any) error {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		logs.Logs(logDbErr, fmt.Sprintf("Failed to create new tenant: %s", err.Error()))
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to begin transaction: %s", err.Error()))
 		return err
 	}
-	return nil
-}
+	defer tx.Rollback()
 
-func GetEncryptedPasswordByTenantEmail(email string) (string, error) {
-	if db == nil {
-		logs.Logs(logDbErr, "Database connection is not initialized")
-		return "", errors.New("database connection is not initialized")
+	err = reserveRoom(ctx, tx, roomId)
+	if err != nil {
+		return err
 	}
 
-	hashEmail := utils.HashData(email)
-	var encryptedPassword string
-
-	query := `
-	SELECT encrypt_password 
-	FROM lhp_tenants 
-	WHERE hash_email=$1;
-	`
-	err := db.QueryRow(query, hashEmail).Scan(&encryptedPassword)
+	_, err = tx.ExecContext(ctx, query, args...)
 	if err != nil {
-		return "", err
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to create new tenant: %s", err.Error()))
+		return err
 	}
 
-	return encryptedPassword, nil
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit new tenant: %s", err.Error()))
+		return err
+	}
+	return nil
 }
 
 /*
 AuthenticateLandlord checks if the provided email and password match the stored credentials.
 
 It returns true if the credentials are correct, otherwise false. An error is returned if the query fails.
+Suspended accounts are only reported as suspended once the right password has been given.
 
 Returns:
 
 - bool: True if the credentials are correct, otherwise false.
 
-- error: An error if the query fails.
+- error: ErrLandlordSuspended if the account is suspended, or an error if the query fails.
 */
-func AuthenticateLandlord(email, password string) (bool, error) {
+func AuthenticateLandlord(ctx context.Context, email, password string) (bool, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return false, errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var hashedPassword string
+	var suspendedAt sql.NullTime
 	query := `
-	SELECT password 
-	FROM lhp_landlords 
-	WHERE email=$1;
+	SELECT l.password, COALESCE(l.suspended_at, e.suspended_at)
+	FROM lhp_landlords l
+	LEFT JOIN lhp_landlords e ON e.id = l.works_for
+	WHERE l.email=$1;
 	`
-	err := db.QueryRow(query, email).Scan(&hashedPassword)
+	err := db.QueryRowContext(ctx, query, email).Scan(&hashedPassword, &suspendedAt)
 	if err != nil {
 		return false, err
 	}
@@ -440,6 +532,9 @@ func AuthenticateLandlord(email, password string) (bool, error) {
 	if !ok {
 		return false, errors.New("invalid password")
 	}
+	if suspendedAt.Valid {
+		return false, ErrLandlordSuspended
+	}
 
 	return true, nil
 }
@@ -447,54 +542,144 @@ func AuthenticateLandlord(email, password string) (bool, error) {
 /*
 AuthenticateTenant checks if the provided email and password match the stored credentials.
 
-It returns true if the credentials are correct, otherwise false. An error is returned if the query fails.
+Tenant passwords are hashed with bcrypt. Tenants created before then still have an unsalted SHA-256 hash,
+which is checked instead and replaced with a bcrypt hash once the tenant has proved they know the password.
+
+Arguments:
+
+- username: The hashed email of the tenant.
+
+- password: The password the tenant entered.
 
 Returns:
 
 - bool: True if the credentials are correct, otherwise false.
 
-- error: An error if the query fails.
+- error: An error if the tenant is not found, the password is wrong or the query fails.
 */
-func AuthenticateTenant(username, password string) (bool, error) {
+func AuthenticateTenant(ctx context.Context, username, password string) (bool, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return false, errors.New("database connection is not initialized")
 	}
 
-	var (
-		hashEmail    string
-		hashPassword string
-	)
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var hashPassword string
 	query := `
-	SELECT hash_email, hash_password 
+	SELECT hash_password 
 	FROM lhp_tenants 
 	WHERE hash_email=$1
-		AND hash_password=$2;
+		AND archived_at IS NULL;
 	`
-	err := db.QueryRow(query, username, password).Scan(&hashEmail, &hashPassword)
+	err := db.QueryRowContext(ctx, query, username).Scan(&hashPassword)
 	if err != nil {
 		return false, err
 	}
 
-	// verify username and password
-	verifyUsername := utils.VerifyHash(username, hashEmail)
-	if !verifyUsername {
-		return false, errors.New("invalid username")
+	if utils.IsBcryptHash(hashPassword) {
+		ok := utils.CheckPasswordHash(password, hashPassword)
+		if !ok {
+			return false, errors.New("invalid password")
+		}
+		return true, nil
 	}
-	verifyPassword := utils.VerifyHash(password, hashPassword)
+
+	// verify the legacy SHA-256 hash, then upgrade it to bcrypt
+	verifyPassword := utils.VerifyHash(utils.HashData(password), hashPassword)
 	if !verifyPassword {
 		return false, errors.New("invalid password")
 	}
 
+	err = upgradeTenantPasswordHash(ctx, username, hashPassword, password)
+	if err != nil {
+		// the tenant still logs in, the hash is upgraded at their next login instead
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to upgrade tenant password hash: %s", err.Error()))
+	}
 	return true, nil
 }
 
 /*
-UpdateLandlordSessionTokens generates new session and CSRF tokens for a given user and updates their expiry time in the database.
+upgradeTenantPasswordHash replaces a tenant's legacy SHA-256 password hash with a bcrypt hash.
+The old hash is part of the update, so a password changed in the meantime is never overwritten.
+*/
+func upgradeTenantPasswordHash(ctx context.Context, hashEmail, legacyHash, password string) error {
+	hashPassword, err := utils.HashedPassword(password)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE lhp_tenants
+	SET hash_password = $1
+	WHERE hash_email = $2 AND hash_password = $3;
+	`
+	_, err = db.ExecContext(ctx, query, hashPassword, hashEmail, legacyHash)
+	if err != nil {
+		return err
+	}
+
+	logs.Logs(logDb, "Tenant password hash upgraded to bcrypt")
+	return nil
+}
+
+/*
+StartLandlordSession starts a new session for a landlord who has just logged in and generates their first tokens.
 
 Arguments:
 
-- email: A string representing the email for which the tokens should be updated.
+- email: The email of the landlord.
+
+- rememberMe: Whether the landlord asked to stay logged in, which gives the session the longer remember me lifetime.
+
+Returns:
+
+- string: The newly generated session token.
+
+- string: The newly generated CSRF token.
+
+- time.Time: The expiry time for the new tokens.
+
+- error: An error object if the session cannot be started.
+*/
+func StartLandlordSession(ctx context.Context, email string, rememberMe bool) (string, string, time.Time, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return "", "", time.Time{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// the CSRF token is only changed when a session starts, so forms rendered during the session stay valid
+	csrfToken, err := utils.GenerateToken(32)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to generate CSRF token: %s", err.Error()))
+		return "", "", time.Time{}, err
+	}
+
+	query := `
+	UPDATE lhp_landlords
+	SET session_started_at=NOW(), session_remember_me=$1, csrf_token=$2
+	WHERE email=$3;
+	`
+	_, err = db.ExecContext(ctx, query, rememberMe, csrfToken, email)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start landlord session: %s", err.Error()))
+		return "", "", time.Time{}, err
+	}
+	return UpdateLandlordSessionTokens(ctx, email)
+}
+
+/*
+StartTenantSession starts a new session for a tenant who has just logged in and generates their first tokens.
+
+Arguments:
+
+- hashEmail: The hashed email of the tenant.
+
+- rememberMe: Whether the tenant asked to stay logged in, which gives the session the longer remember me lifetime.
 
 Returns:
 
@@ -504,32 +689,99 @@ Returns:
 
 - time.Time: The expiry time for the new tokens.
 
+- error: An error object if the session cannot be started.
+*/
+func StartTenantSession(ctx context.Context, hashEmail string, rememberMe bool) (string, string, time.Time, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return "", "", time.Time{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// the CSRF token is only changed when a session starts, so forms rendered during the session stay valid
+	csrfToken, err := utils.GenerateToken(32)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to generate CSRF token: %s", err.Error()))
+		return "", "", time.Time{}, err
+	}
+
+	query := `
+	UPDATE lhp_tenants
+	SET session_started_at=NOW(), session_remember_me=$1, csrf_token=$2
+	WHERE hash_email=$3;
+	`
+	_, err = db.ExecContext(ctx, query, rememberMe, csrfToken, hashEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start tenant session: %s", err.Error()))
+		return "", "", time.Time{}, err
+	}
+	return UpdateTenantSessionTokens(ctx, hashEmail)
+}
+
+/*
+UpdateLandlordSessionTokens generates a new session token for a given user and updates their expiry time in the database.
+The CSRF token is kept for the whole session, so it can be rendered into forms and checked when they are sent.
+
+The expiry follows the session policy in config.Session: it slides forward by the idle timeout on every call,
+but never past the maximum lifetime of the session.
+
+Arguments:
+
+- email: A string representing the email for which the tokens should be updated.
+
+Returns:
+
+- string: The newly generated session token.
+
+- string: The session's CSRF token.
+
+- time.Time: The expiry time for the new tokens.
+
 - error: An error object if the tokens cannot be generated or updated in the database.
 */
-func UpdateLandlordSessionTokens(email string) (string, string, time.Time, error) {
+func UpdateLandlordSessionTokens(ctx context.Context, email string) (string, string, time.Time, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return "", "", time.Time{}, errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	sessionToken, err := utils.GenerateToken(32)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to generate session token: %s", err.Error()))
 		return "", "", time.Time{}, err
 	}
-	csrfToken, err := utils.GenerateToken(32)
+	// slide the expiry forward, without going past the maximum lifetime of the session
+	var startedAt sql.NullTime
+	var rememberMe bool
+	err = db.QueryRowContext(ctx, `
+	SELECT session_started_at, session_remember_me
+	FROM lhp_landlords
+	WHERE email=$1;
+	`, email).Scan(&startedAt, &rememberMe)
 	if err != nil {
-		logs.Logs(logDbErr, fmt.Sprintf("Failed to generate CSRF token: %s", err.Error()))
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get session start: %s", err.Error()))
 		return "", "", time.Time{}, err
 	}
-	expiry := time.Now().Add(30 * time.Second) // 30 seconds validity
+	now := time.Now()
+	if !startedAt.Valid {
+		startedAt.Time = now
+	}
+	expiry := config.Session.Expiry(startedAt.Time, rememberMe, now)
 
+	// the CSRF token stays the same for the whole session, see StartLandlordSession
 	query := `
-	UPDATE lhp_landlords 
-	SET session_token=$1, csrf_token=$2, token_expiry=$3 
-	WHERE email=$4;
+	UPDATE lhp_landlords
+	SET session_token=$1, token_expiry=$2
+	WHERE email=$3
+	RETURNING csrf_token;
 	`
-	_, err = db.Exec(query, sessionToken, csrfToken, expiry, email)
+	var csrfToken string
+	err = db.QueryRowContext(ctx, query, sessionToken, expiry, email).Scan(&csrfToken)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to update session tokens: %s", err.Error()))
 		return "", "", time.Time{}, err
@@ -539,30 +791,47 @@ func UpdateLandlordSessionTokens(email string) (string, string, time.Time, error
 	return sessionToken, csrfToken, expiry, nil
 }
 
-func UpdateTenantSessionTokens(hash_email string) (string, string, time.Time, error) {
+func UpdateTenantSessionTokens(ctx context.Context, hash_email string) (string, string, time.Time, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return "", "", time.Time{}, errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	sessionToken, err := utils.GenerateToken(32)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to generate session token: %s", err.Error()))
 		return "", "", time.Time{}, err
 	}
-	csrfToken, err := utils.GenerateToken(32)
+	// slide the expiry forward, without going past the maximum lifetime of the session
+	var startedAt sql.NullTime
+	var rememberMe bool
+	err = db.QueryRowContext(ctx, `
+	SELECT session_started_at, session_remember_me
+	FROM lhp_tenants
+	WHERE hash_email=$1;
+	`, hash_email).Scan(&startedAt, &rememberMe)
 	if err != nil {
-		logs.Logs(logDbErr, fmt.Sprintf("Failed to generate CSRF token: %s", err.Error()))
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get session start: %s", err.Error()))
 		return "", "", time.Time{}, err
 	}
-	expiry := time.Now().Add(30 * time.Second) // 30 seconds validity
+	now := time.Now()
+	if !startedAt.Valid {
+		startedAt.Time = now
+	}
+	expiry := config.Session.Expiry(startedAt.Time, rememberMe, now)
 
+	// the CSRF token stays the same for the whole session, see StartTenantSession
 	query := `
-	UPDATE lhp_tenants 
-	SET session_token=$1, csrf_token=$2, token_expiry=$3 
-	WHERE hash_email=$4;
+	UPDATE lhp_tenants
+	SET session_token=$1, token_expiry=$2
+	WHERE hash_email=$3
+	RETURNING csrf_token;
 	`
-	_, err = db.Exec(query, sessionToken, csrfToken, expiry, hash_email)
+	var csrfToken string
+	err = db.QueryRowContext(ctx, query, sessionToken, expiry, hash_email).Scan(&csrfToken)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to update session tokens: %s", err.Error()))
 		return "", "", time.Time{}, err
@@ -586,19 +855,22 @@ Returns:
 
 - error: An error object if the user is not found in the database or an error occurs while querying the database.
 */
-func GetEmailFromLandlordSessionToken(sessionToken string) (string, error) {
+func GetEmailFromLandlordSessionToken(ctx context.Context, sessionToken string) (string, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return "", errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var email string
 	query := `
 	SELECT email 
 	FROM lhp_landlords 
-	WHERE session_token=$1;
+	WHERE session_token=$1 AND suspended_at IS NULL;
 	`
-	err := db.QueryRow(query, sessionToken).Scan(&email)
+	err := db.QueryRowContext(ctx, query, sessionToken).Scan(&email)
 
 	if err == sql.ErrNoRows {
 		logs.Logs(logDbErr, "User not found")
@@ -613,19 +885,22 @@ func GetEmailFromLandlordSessionToken(sessionToken string) (string, error) {
 	return email, nil
 }
 
-func GetEmailFromTenantSessionToken(sessionToken string) (string, error) {
+func GetEmailFromTenantSessionToken(ctx context.Context, sessionToken string) (string, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return "", errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var email string
 	query := `
 	SELECT email 
 	FROM lhp_tenants 
 	WHERE session_token=$1;
 	`
-	err := db.QueryRow(query, sessionToken).Scan(&email)
+	err := db.QueryRowContext(ctx, query, sessionToken).Scan(&email)
 
 	if err == sql.ErrNoRows {
 		logs.Logs(logDbErr, "User not found")
@@ -656,20 +931,24 @@ Returns:
 
 - error: An error object if the user is not found in the database or an error occurs while querying the database.
 */
-func ValidateLandlordSessionToken(email, sessionToken string) (bool, error) {
+func ValidateLandlordSessionToken(ctx context.Context, email, sessionToken string) (bool, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return false, errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// query DB to get the stored session token
 	var dbSessionToken string
+	var tokenExpiry sql.NullTime
 	query := `
-	SELECT session_token
+	SELECT session_token, token_expiry
 	FROM lhp_landlords
 	WHERE email = $1;
 	`
-	err := db.QueryRow(query, email).Scan(&dbSessionToken)
+	err := db.QueryRowContext(ctx, query, email).Scan(&dbSessionToken, &tokenExpiry)
 
 	if err == sql.ErrNoRows {
 		logs.Logs(logDbErr, "User not found")
@@ -687,23 +966,33 @@ func ValidateLandlordSessionToken(email, sessionToken string) (bool, error) {
 		return false, nil
 	}
 
+	// the session must not have expired, whatever the cookie says
+	if !tokenExpiry.Valid || time.Now().After(tokenExpiry.Time) {
+		logs.Logs(logDbErr, "Session has expired")
+		return false, nil
+	}
+
 	return true, nil
 }
 
-func ValidateTenantSessionToken(hashEmail, sessionToken string) (bool, error) {
+func ValidateTenantSessionToken(ctx context.Context, hashEmail, sessionToken string) (bool, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return false, errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// query DB to get the stored session token
 	var dbSessionToken string
+	var tokenExpiry sql.NullTime
 	query := `
-	SELECT session_token
+	SELECT session_token, token_expiry
 	FROM lhp_tenants
 	WHERE hash_email = $1;
 	`
-	err := db.QueryRow(query, hashEmail).Scan(&dbSessionToken)
+	err := db.QueryRowContext(ctx, query, hashEmail).Scan(&dbSessionToken, &tokenExpiry)
 
 	if err == sql.ErrNoRows {
 		logs.Logs(logDbErr, "User not found")
@@ -720,6 +1009,12 @@ func ValidateTenantSessionToken(hashEmail, sessionToken string) (bool, error) {
 		logs.Logs(logDbErr, "Invalid session token")
 		return false, nil
 	}
+
+	// the session must not have expired, whatever the cookie says
+	if !tokenExpiry.Valid || time.Now().After(tokenExpiry.Time) {
+		logs.Logs(logDbErr, "Session has expired")
+		return false, nil
+	}
 	return true, nil
 }
 
@@ -739,12 +1034,15 @@ Returns:
 
 - error: An error object if an error occurs while querying the database or if the database connection is not initialized.
 */
-func ValidateLandlordCSRFToken(email, csrfToken string) (bool, error) {
+func ValidateLandlordCSRFToken(ctx context.Context, email, csrfToken string) (bool, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return false, errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// query DB to get the stored CSRF token
 	var dbCSRFToken string
 	query := `
@@ -752,7 +1050,7 @@ func ValidateLandlordCSRFToken(email, csrfToken string) (bool, error) {
 	FROM lhp_landlords 
 	WHERE email=$1;
 	`
-	err := db.QueryRow(query, email).Scan(&dbCSRFToken)
+	err := db.QueryRowContext(ctx, query, email).Scan(&dbCSRFToken)
 	if err != nil {
 		return false, err
 	}
@@ -764,12 +1062,15 @@ func ValidateLandlordCSRFToken(email, csrfToken string) (bool, error) {
 	return true, nil
 }
 
-func ValidateTenantCSRFToken(hashEmail, csrfToken string) (bool, error) {
+func ValidateTenantCSRFToken(ctx context.Context, hashEmail, csrfToken string) (bool, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return false, errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// query DB to get the stored CSRF token
 	var dbCSRFToken string
 	query := `
@@ -777,7 +1078,7 @@ func ValidateTenantCSRFToken(hashEmail, csrfToken string) (bool, error) {
 	FROM lhp_tenants 
 	WHERE hash_email=$1;
 	`
-	err := db.QueryRow(query, hashEmail).Scan(&dbCSRFToken)
+	err := db.QueryRowContext(ctx, query, hashEmail).Scan(&dbCSRFToken)
 	if err != nil {
 		return false, err
 	}
@@ -800,36 +1101,42 @@ Returns:
 
 - error: An error object if the logout operation fails (e.g. if the database connection is not initialized).
 */
-func LogoutLandlord(email string) error {
+func LogoutLandlord(ctx context.Context, email string) error {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 	UPDATE lhp_landlords 
 	SET session_token=NULL, csrf_token=NULL, token_expiry=NULL 
 	WHERE email=$1;
 	`
-	_, err := db.Exec(query, email)
+	_, err := db.ExecContext(ctx, query, email)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func LogoutTenant(hashEmail string) error {
+func LogoutTenant(ctx context.Context, hashEmail string) error {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 	UPDATE lhp_tenants 
 	SET session_token=NULL, csrf_token=NULL, token_expiry=NULL 
 	WHERE hash_email=$1;
 	`
-	_, err := db.Exec(query, hashEmail)
+	_, err := db.ExecContext(ctx, query, hashEmail)
 	if err != nil {
 		return err
 	}
@@ -849,31 +1156,37 @@ Returns:
 
 - error: An error object if the user is not found in the database or an error occurs while querying the database.
 */
-func GetLandlordIdByEmail(email string) (int, error) {
+func GetLandlordIdByEmail(ctx context.Context, email string) (int, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return 0, errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var landlordId int
 	query := `
 	SELECT id 
 	FROM lhp_landlords 
 	WHERE email=$1;
 	`
-	err := db.QueryRow(query, email).Scan(&landlordId)
+	err := db.QueryRowContext(ctx, query, email).Scan(&landlordId)
 	if err != nil {
 		return 0, err
 	}
 	return landlordId, nil
 }
 
-func GetTenantIdByEmail(email string) (int, error) {
+func GetTenantIdByEmail(ctx context.Context, email string) (int, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return 0, errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var tenantId int
 
 	query := `
@@ -881,26 +1194,29 @@ func GetTenantIdByEmail(email string) (int, error) {
 	FROM lhp_tenants 
 	WHERE hash_email=$1;
 	`
-	err := db.QueryRow(query, email).Scan(&tenantId)
+	err := db.QueryRowContext(ctx, query, email).Scan(&tenantId)
 	if err != nil {
 		return 0, err
 	}
 	return tenantId, nil
 }
 
-func GetTenantEncryptedEmailById(tenantId int) (string, error) {
+func GetTenantEncryptedEmailById(ctx context.Context, tenantId int) (string, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return "", errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var encryptedEmail string
 	query := `
 	SELECT encrypt_email
 	FROM lhp_tenants
 	WHERE id=$1;
 	`
-	err := db.QueryRow(query, tenantId).Scan(&encryptedEmail)
+	err := db.QueryRowContext(ctx, query, tenantId).Scan(&encryptedEmail)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to get encrypted email: %s", err.Error()))
 		return "", err
@@ -909,12 +1225,15 @@ func GetTenantEncryptedEmailById(tenantId int) (string, error) {
 	return encryptedEmail, nil
 }
 
-func SendMessage(senderId int, senderType string, receiverID int, receiverType string, message string) error {
+func SendMessage(ctx context.Context, senderId int, senderType string, receiverID int, receiverType string, message string) error {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	encryptMessage, err := utils.Encrypt([]byte(message))
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt message: %s", err.Error()))
@@ -933,7 +1252,7 @@ func SendMessage(senderId int, senderType string, receiverID int, receiverType s
 		)
 	VALUES ($1, $2, $3, $4, $5, NOW());
 	`
-	_, err = db.Exec(
+	_, err = db.ExecContext(ctx,
 		query,
 		senderId,
 		senderType,
@@ -954,13 +1273,15 @@ func SendMessage(senderId int, senderType string, receiverID int, receiverType s
 SaveTenantApplicationForm saves a tenant application form to the database.
 
 The function takes in all the values from the tenant application form and stores them in the database.
-The function first checks if the database connection is initialized and if the landlord email is not empty.
-Then, it gets the landlord ID from the database and hashes the identifiers.
+The function first checks if the database connection is initialized.
+Then, it gets the landlord who owns the chosen property and hashes the identifiers.
 Next, the function encrypts the data using the aes encryption algorithm.
 Finally, the function executes a SQL query to store the tenant application form to the database.
 
 Arguments:
 
+- propertyId: The ID of the property the tenant is applying for.
+
 - fullName: The tenant's full name.
 
 - dateOfBirth: The tenant's date of birth.
@@ -1013,9 +1334,11 @@ Arguments:
 
 Returns:
 
-- error: An error object if the database connection is not initialized or if the landlord email is empty.
+- error: An error object if the database connection is not initialized or if the property does not exist.
 */
 func SaveTenantApplicationForm(
+	ctx context.Context,
+	propertyId int,
 	fullName,
 	dateOfBirth,
 	passportNumber,
@@ -1047,28 +1370,16 @@ func SaveTenantApplicationForm(
 		return errors.New("database connection is not initialized")
 	}
 
-	// get landlord email via environment variable
-	if os.Getenv("LANDLORD_EMAIL") == "" {
-		logs.Logs(logWarning, "Could not get landlord email from hosting platform. Loading from .env file...")
-		err := env.LoadEnv("env/.env")
-		if err != nil {
-			logs.Logs(logErr, fmt.Sprintf("Could not load environment variables from .env file: %s", err.Error()))
-			return err
-		}
-	}
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
-	landlordEmail := os.Getenv("LANDLORD_EMAIL")
-	if landlordEmail == "" {
-		logs.Logs(logDbErr, "Landlord email is empty!")
-		return errors.New("landlord email is empty")
-	}
-
-	// get landlord id
-	landlordId, err := GetLandlordIdByEmail(landlordEmail)
+	// the application goes to the landlord who owns the property
+	property, err := GetPropertyById(ctx, propertyId)
 	if err != nil {
-		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord ID: %s", err.Error()))
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get property: %s", err.Error()))
 		return err
 	}
+	landlordId := property.LandlordID
 
 	// hash identifiers
 	hashFullName := utils.HashData(fullName)
@@ -1233,6 +1544,7 @@ func SaveTenantApplicationForm(
 	query := `
 	INSERT INTO lhp_tenant_application (
 		landlord_id,
+		property_id,
 		hash_full_name,
 		hash_dob,
 		hash_passport_number,
@@ -1266,14 +1578,15 @@ func SaveTenantApplicationForm(
 		created_at
 	)
 	VALUES (
-		$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, NOW() 
+		$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, NOW() 
 	);
 	`
 
 	// execute query
-	_, err = db.Exec(
+	_, err = db.ExecContext(ctx,
 		query,
 		landlordId,
+		propertyId,
 		hashFullName,
 		hashDob,
 		hashPassportNumber,
@@ -1315,42 +1628,32 @@ func SaveTenantApplicationForm(
 }
 
 /*
-GetAllTenantApplications retrieves all tenant applications associated with the current landlord.
+GetAllTenantApplications retrieves all tenant applications associated with the given landlord.
 
-This function checks if the database connection is initialized and retrieves the landlord's email from environment variables.
-If the email is not found, it attempts to load it from a .env file. It then retrieves the landlord ID using the landlord email.
+This function checks if the database connection is initialized and retrieves the landlord ID using the landlord email.
 The function queries the database for all tenant applications related to this landlord ID, ordered by creation date in descending order.
 
+Arguments:
+
+- landlordEmail: The email of the landlord authenticated by the session.
+
 Returns:
 
 - []GetLandlordApplications: A slice containing the tenant applications.
 
 - error: An error object if the tenant applications cannot be retrieved.
 */
-func GetAllTenantApplications() ([]GetLandlordApplications, error) {
+func GetAllTenantApplications(ctx context.Context, landlordEmail string) ([]GetLandlordApplications, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return nil, errors.New("database connection is not initialized")
 	}
 
-	// get landlord email via environment variable
-	if os.Getenv("LANDLORD_EMAIL") == "" {
-		logs.Logs(logWarning, "Could not get landlord email from hosting platform. Loading from .env file...")
-		err := env.LoadEnv("env/.env")
-		if err != nil {
-			logs.Logs(logErr, fmt.Sprintf("Could not load environment variables from .env file: %s", err.Error()))
-			return nil, err
-		}
-	}
-
-	landlordEmail := os.Getenv("LANDLORD_EMAIL")
-	if landlordEmail == "" {
-		logs.Logs(logDbErr, "Landlord email is empty!")
-		return nil, errors.New("landlord email is empty")
-	}
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
 	// get landlord id
-	landlordId, err := GetLandlordIdByEmail(landlordEmail)
+	landlordId, err := GetLandlordIdByEmail(ctx, landlordEmail)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord ID: %s", err.Error()))
 		return nil, err
@@ -1358,39 +1661,41 @@ func GetAllTenantApplications() ([]GetLandlordApplications, error) {
 
 	query := `
 	SELECT 
-		id,
-		status,
-		encrypt_full_name,
-		encrypt_dob,
-		encrypt_passport_number,
-		encrypt_phone_number,
-		encrypt_email,
-		encrypt_occupation,
-		encrypt_employer,
-		encrypt_employer_number,
-		encrypt_emergency_contact,
-		encrypt_emergency_number,
-		encrypt_emergency_address,
-		encrypt_if_evicted,
-		encrypt_evicted_reason,
-		encrypt_if_convicted,
-		encrypt_convicted_reason,
-		encrypt_smoke,
-		encrypt_pets,
-		encrypt_if_vehicle,
-		encrypt_vehicle_reg,
-		encrypt_have_children,
-		encrypt_children,
-		encrypt_refused_rent,
-		encrypt_refused_rent_reason,
-		encrypt_unstable_income,
-		encrypt_income_reason,
-		created_at
-	FROM lhp_tenant_application
-	WHERE landlord_id = $1
-	ORDER BY created_at DESC;
+		a.id,
+		a.status,
+		a.encrypt_full_name,
+		a.encrypt_dob,
+		a.encrypt_passport_number,
+		a.encrypt_phone_number,
+		a.encrypt_email,
+		a.encrypt_occupation,
+		a.encrypt_employer,
+		a.encrypt_employer_number,
+		a.encrypt_emergency_contact,
+		a.encrypt_emergency_number,
+		a.encrypt_emergency_address,
+		a.encrypt_if_evicted,
+		a.encrypt_evicted_reason,
+		a.encrypt_if_convicted,
+		a.encrypt_convicted_reason,
+		a.encrypt_smoke,
+		a.encrypt_pets,
+		a.encrypt_if_vehicle,
+		a.encrypt_vehicle_reg,
+		a.encrypt_have_children,
+		a.encrypt_children,
+		a.encrypt_refused_rent,
+		a.encrypt_refused_rent_reason,
+		a.encrypt_unstable_income,
+		a.encrypt_income_reason,
+		COALESCE(p.name, ''),
+		a.created_at
+	FROM lhp_tenant_application a
+	LEFT JOIN lhp_properties p ON p.id = a.property_id
+	WHERE a.landlord_id = $1
+	ORDER BY a.created_at DESC;
 	`
-	rows, err := db.Query(query, landlordId)
+	rows, err := db.QueryContext(ctx, query, landlordId)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to get tenant applications: %s", err.Error()))
 		return nil, err
@@ -1429,6 +1734,7 @@ func GetAllTenantApplications() ([]GetLandlordApplications, error) {
 			&tenant.RefusedReason,
 			&tenant.UnstableIncome,
 			&tenant.UnstableReason,
+			&tenant.PropertyName,
 			&tenant.CreatedAt,
 		)
 		if err != nil {
@@ -1450,6 +1756,8 @@ func GetAllTenantApplications() ([]GetLandlordApplications, error) {
 
 /*
 UpdateTenantApplicationStatus updates the status of a tenant application in the database.
+Only pending applications are changed, so a decision that is sent again, or sent from an old form,
+cannot undo an application that was accepted or denied in the meantime.
 
 Arguments:
 
@@ -1459,25 +1767,36 @@ Arguments:
 
 Returns:
 
-- error: An error if the status cannot be updated in the database.
+- error: ErrApplicationDecided if the application is no longer pending, or an error if the status cannot be updated in the database.
 */
-func UpdateTenantApplicationStatus(id string, status string) error {
+func UpdateTenantApplicationStatus(ctx context.Context, id string, status string) error {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	logs.Logs(logDb, "Updating tenant application status...")
 	query := `
 	UPDATE lhp_tenant_application
-	SET status = $1
-	WHERE id = $2;
+	SET status = $1, decided_at = NOW()
+	WHERE id = $2 AND status = 'pending';
 	`
-	_, err := db.Exec(query, status, id)
+	result, err := db.ExecContext(ctx, query, status, id)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to update tenant application status: %s", err.Error()))
 		return err
 	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		logs.Logs(logDbErr, fmt.Sprintf("Tenant application %s is no longer pending", id))
+		return ErrApplicationDecided
+	}
 	logs.Logs(logDb, "Tenant application status updated successfully")
 	return nil
 }
@@ -1497,38 +1816,44 @@ Returns:
 
 - error: An error object if there is an issue retrieving the data from the database.
 */
-func GetTenantEmailAndPassportNumberViaApplicationID(id string) (string, string, error) {
+func GetTenantEmailAndPassportNumberViaApplicationID(ctx context.Context, id string) (string, string, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return "", "", errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var email, passportNumber string
 	query := `
 	SELECT encrypt_email, encrypt_passport_number
 	FROM lhp_tenant_application
 	WHERE id = $1;
 	`
-	err := db.QueryRow(query, id).Scan(&email, &passportNumber)
+	err := db.QueryRowContext(ctx, query, id).Scan(&email, &passportNumber)
 	if err != nil {
 		return "", "", err
 	}
 	return email, passportNumber, nil
 }
 
-func GetHashedEmailFromTenantSessionToken(sessionToken string) (string, error) {
+func GetHashedEmailFromTenantSessionToken(ctx context.Context, sessionToken string) (string, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return "", errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var hashEmail string
 	query := `
 	SELECT hash_email 
 	FROM lhp_tenants 
 	WHERE session_token=$1;
 	`
-	err := db.QueryRow(query, sessionToken).Scan(&hashEmail)
+	err := db.QueryRowContext(ctx, query, sessionToken).Scan(&hashEmail)
 
 	if err == sql.ErrNoRows {
 		logs.Logs(logDbErr, "User not found")
@@ -1543,24 +1868,71 @@ func GetHashedEmailFromTenantSessionToken(sessionToken string) (string, error) {
 	return hashEmail, nil
 }
 
-func UpdateTenantPassword(hashEmail, newPasswordHash, newPassword string) error {
+/*
+GetCSRFTokenFromSessionToken returns the CSRF token of the landlord or tenant session a session token belongs to.
+It is used to check the token sent with a form against the session the form was sent from.
+
+Arguments:
+
+- sessionToken: The session token from the site-wide session cookie.
+
+Returns:
+
+- string: The session's CSRF token.
+
+- error: sql.ErrNoRows if the session token does not belong to a current session, or an error object if the query fails.
+*/
+func GetCSRFTokenFromSessionToken(ctx context.Context, sessionToken string) (string, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return "", errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var csrfToken string
+	query := `
+	SELECT csrf_token
+	FROM lhp_landlords
+	WHERE session_token=$1 AND csrf_token IS NOT NULL AND token_expiry > NOW()
+	UNION ALL
+	SELECT csrf_token
+	FROM lhp_tenants
+	WHERE session_token=$1 AND csrf_token IS NOT NULL AND token_expiry > NOW()
+	LIMIT 1;
+	`
+	err := db.QueryRowContext(ctx, query, sessionToken).Scan(&csrfToken)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to get CSRF token from session token: %s", err.Error()))
+		}
+		return "", err
+	}
+	return csrfToken, nil
+}
+
+func UpdateTenantPassword(ctx context.Context, hashEmail, newPassword string) error {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return errors.New("database connection is not initialized")
 	}
 
-	ecnryptNewPassword, err := utils.Encrypt([]byte(newPassword))
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	newPasswordHash, err := utils.HashedPassword(newPassword)
 	if err != nil {
-		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt password: %s", err.Error()))
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to hash password: %s", err.Error()))
 		return err
 	}
 
 	query := `
 	UPDATE lhp_tenants
-	SET hash_password = $1, encrypt_password = $2
-	WHERE hash_email = $3;
+	SET hash_password = $1
+	WHERE hash_email = $2;
 	`
-	_, err = db.Exec(query, newPasswordHash, ecnryptNewPassword, hashEmail)
+	_, err = db.ExecContext(ctx, query, newPasswordHash, hashEmail)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to update password: %s", err.Error()))
 		return err
@@ -1570,12 +1942,15 @@ func UpdateTenantPassword(hashEmail, newPasswordHash, newPassword string) error
 	return nil
 }
 
-func GetTenantInformationByHashEmail(hashEmail string) (GetTenantInformation, error) {
+func GetTenantInformationByHashEmail(ctx context.Context, hashEmail string) (GetTenantInformation, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return GetTenantInformation{}, errors.New("database connection is not initialized")
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 	SELECT
 		encrypt_email,
@@ -1587,7 +1962,7 @@ func GetTenantInformationByHashEmail(hashEmail string) (GetTenantInformation, er
 	FROM lhp_tenants
 	WHERE hash_email = $1;
 	`
-	row, err := db.Query(query, hashEmail)
+	row, err := db.QueryContext(ctx, query, hashEmail)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to get tenant information: %s", err.Error()))
 		return GetTenantInformation{}, err
@@ -1621,23 +1996,26 @@ func GetTenantInformationByHashEmail(hashEmail string) (GetTenantInformation, er
 	return tenantInformation, nil
 }
 
-func GetTenantsByLandlordEmail(landlordEmail string) ([]LandlordTenants, error) {
+func GetTenantsByLandlordEmail(ctx context.Context, landlordEmail string) ([]LandlordTenants, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return nil, errors.New("database connection is not initialized")
 	}
 
-	landlordId, err := GetLandlordIdByEmail(landlordEmail)
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	landlordId, err := GetLandlordIdByEmail(ctx, landlordEmail)
 	if err != nil {
 		return nil, err
 	}
 
 	query := `
-	SELECT id, encrypt_tenant_name
+	SELECT id, encrypt_tenant_name, currency
     FROM lhp_tenants
     WHERE landlord_id = $1;
 	`
-	rows, err := db.Query(query, landlordId)
+	rows, err := db.QueryContext(ctx, query, landlordId)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to get tenants: %s", err.Error()))
 		return nil, err
@@ -1650,6 +2028,7 @@ func GetTenantsByLandlordEmail(landlordEmail string) ([]LandlordTenants, error)
 		err := rows.Scan(
 			&tenant.ID,
 			&tenant.EncryptTenantName,
+			&tenant.Currency,
 		)
 		if err != nil {
 			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan tenants: %s", err.Error()))
@@ -1660,34 +2039,14 @@ func GetTenantsByLandlordEmail(landlordEmail string) ([]LandlordTenants, error)
 	return tenants, nil
 }
 
-func GetMessageBetweenLandlordsAndTenant(tenantID string) ([]Message, error) {
+func GetMessageBetweenLandlordsAndTenant(ctx context.Context, landlordId int, tenantID string) ([]Message, error) {
 	if db == nil {
 		logs.Logs(logDbErr, "Database connection is not initialized")
 		return nil, errors.New("database connection is not initialized")
 	}
 
-	// get landlord email via environment variable
-	if os.Getenv("LANDLORD_EMAIL") == "" {
-		logs.Logs(logWarning, "Could not get landlord email from hosting platform. Loading from .env file...")
-		err := env.LoadEnv("env/.env")
-		if err != nil {
-			logs.Logs(logErr, fmt.Sprintf("Could not load environment variables from .env file: %s", err.Error()))
-			return nil, err
-		}
-	}
-
-	landlordEmail := os.Getenv("LANDLORD_EMAIL")
-	if landlordEmail == "" {
-		logs.Logs(logDbErr, "Landlord email is empty!")
-		return nil, errors.New("landlord email is empty")
-	}
-
-	// get landlord id
-	landlordId, err := GetLandlordIdByEmail(landlordEmail)
-	if err != nil {
-		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord ID: %s", err.Error()))
-		return nil, err
-	}
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
 	// covert tenant id to int
 	tenantIDInt, err := strconv.Atoi(tenantID)
@@ -1699,10 +2058,11 @@ func GetMessageBetweenLandlordsAndTenant(tenantID string) ([]Message, error) {
 	query := `
 	SELECT sender_id, sender_type, receiver_id, receiver_type, encrypt_message, sent_at
 	FROM lhp_messages
-	WHERE (sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1);
+	WHERE (sender_type = 'landlord' AND sender_id = $1 AND receiver_id = $2)
+		OR (sender_type = 'tenant' AND sender_id = $2 AND receiver_id = $1);
 	`
 
-	rows, err := db.Query(query, landlordId, tenantIDInt)
+	rows, err := db.QueryContext(ctx, query, landlordId, tenantIDInt)
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Failed to get messages: %s", err.Error()))
 		return nil, err
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// fakeResponse is what the fake database answers a statement with
+type fakeResponse struct {
+	columns      []string
+	rows         [][]driver.Value
+	rowsAffected int64
+	err          error
+}
+
+// fakeStatement is a statement run against the fake database, with its whitespace collapsed so it can be matched.
+// Transactions are recorded as the statements BEGIN, COMMIT and ROLLBACK.
+type fakeStatement struct {
+	query string
+	args  []driver.Value
+}
+
+// fakeDB is a database/sql connector whose connections answer every statement with respond,
+// so the SQL a function runs can be checked without PostgreSQL
+type fakeDB struct {
+	mu         sync.Mutex
+	respond    func(query string, args []driver.Value) fakeResponse
+	statements []fakeStatement
+}
+
+var setupTestEncryption sync.Once
+
+// initTestEncryption starts reading the logs and loads an encryption key, once for every test in the package
+func initTestEncryption() {
+	setupTestEncryption.Do(func() {
+		go logs.LogProcessor()
+		if os.Getenv("MASTER_KEY") == "" && os.Getenv("ENCRYPTION_KEYS") == "" {
+			os.Setenv("MASTER_KEY", "test-master-key-0123456789abcdef")
+		}
+		utils.InitEncryption()
+	})
+}
+
+// useFakeDB points the package's database at a fake database answering with respond until the test ends
+func useFakeDB(t *testing.T, respond func(query string, args []driver.Value) fakeResponse) *fakeDB {
+	initTestEncryption()
+
+	fake := &fakeDB{respond: respond}
+	previous := db
+	db = sql.OpenDB(fake)
+	t.Cleanup(func() {
+		db.Close()
+		db = previous
+	})
+	return fake
+}
+
+// record adds a statement to the ones run, and returns the response to it
+func (f *fakeDB) record(query string, args []driver.NamedValue) fakeResponse {
+	query = strings.Join(strings.Fields(query), " ")
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	f.mu.Lock()
+	f.statements = append(f.statements, fakeStatement{query: query, args: values})
+	f.mu.Unlock()
+
+	if f.respond == nil {
+		return fakeResponse{}
+	}
+	return f.respond(query, values)
+}
+
+// ran returns the statements run so far, in order
+func (f *fakeDB) ran() []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]fakeStatement(nil), f.statements...)
+}
+
+func (f *fakeDB) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{db: f}, nil
+}
+
+func (f *fakeDB) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+// fakeDriver is only used through fakeDB, which makes its connections
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("fake database connections are made by fakeDB")
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake database does not prepare statements")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.db.record("BEGIN", nil)
+	return fakeTx{db: c.db}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	response := c.db.record(query, args)
+	if response.err != nil {
+		return nil, response.err
+	}
+	return driver.RowsAffected(response.rowsAffected), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	response := c.db.record(query, args)
+	if response.err != nil {
+		return nil, response.err
+	}
+	return &fakeRows{columns: response.columns, rows: response.rows}, nil
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx fakeTx) Commit() error {
+	tx.db.record("COMMIT", nil)
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.record("ROLLBACK", nil)
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,361 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// the purpose invitation tokens are signed for, so they cannot be used as any other signed token
+const invitationTokenPurpose = "landlord-invitation"
+
+/*
+CreateLandlordInvitation creates an invitation to create a landlord account, to be emailed to the person invited.
+The token is signed and expires after config.Registration.InvitationLifetime. Any earlier invitation to the same email
+stops working, and only a hash of the new token is stored.
+
+Arguments:
+
+- invitedBy: The email of the account sending the invitation.
+
+- email: The email of the person invited. Their account is created with this email.
+
+- accountRole: The role the new account is given.
+
+Returns:
+
+- string: The invitation token to email.
+
+- time.Time: When the invitation expires.
+
+- error: ErrLandlordExists if there is already an account with the email, or an error object if the invitation cannot be stored.
+*/
+func CreateLandlordInvitation(ctx context.Context, invitedBy, email, accountRole string) (string, time.Time, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return "", time.Time{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM lhp_landlords WHERE email = $1);`, email).Scan(&exists)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to check landlord for invitation: %s", err.Error()))
+		return "", time.Time{}, err
+	}
+	if exists {
+		return "", time.Time{}, ErrLandlordExists
+	}
+
+	nonce, err := utils.GenerateToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(config.Registration.InvitationLifetime)
+	token := utils.SignToken(invitationTokenPurpose, nonce, expiresAt)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return "", time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	// only the most recent invitation to an email works
+	query := `
+	UPDATE lhp_landlord_invitations
+	SET revoked_at = NOW()
+	WHERE email = $1 AND accepted_at IS NULL AND revoked_at IS NULL;
+	`
+	_, err = tx.ExecContext(ctx, query, email)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to revoke old invitations: %s", err.Error()))
+		return "", time.Time{}, err
+	}
+
+	query = `
+	INSERT INTO lhp_landlord_invitations (email, account_role, token_hash, invited_by, expires_at, created_at)
+	SELECT $1, $2, $3, id, $5, NOW()
+	FROM lhp_landlords
+	WHERE email = $4;
+	`
+	result, err := tx.ExecContext(ctx, query, email, accountRole, utils.HashData(token), invitedBy, expiresAt)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to create invitation: %s", err.Error()))
+		return "", time.Time{}, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if rows == 0 {
+		return "", time.Time{}, fmt.Errorf("inviting account not found")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit invitation: %s", err.Error()))
+		return "", time.Time{}, err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Invitation created for a new %s account", accountRole))
+	return token, expiresAt, nil
+}
+
+/*
+GetLandlordInvitation gets the invitation a token is for, without using it up.
+The signature and expiry of the token are checked before the database is queried.
+
+Arguments:
+
+- token: The invitation token from the emailed link.
+
+Returns:
+
+- LandlordInvitation: The invitation.
+
+- error: ErrInvalidInvitation if the token is not signed, has expired, or its invitation has been accepted or revoked.
+*/
+func GetLandlordInvitation(ctx context.Context, token string) (LandlordInvitation, error) {
+	var invitation LandlordInvitation
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return invitation, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := utils.VerifySignedToken(invitationTokenPurpose, token, time.Now())
+	if err != nil {
+		return invitation, ErrInvalidInvitation
+	}
+
+	query := `
+	SELECT i.id, i.email, i.account_role, l.email, i.expires_at, i.created_at
+	FROM lhp_landlord_invitations i
+	JOIN lhp_landlords l ON l.id = i.invited_by
+	WHERE i.token_hash = $1 AND i.accepted_at IS NULL AND i.revoked_at IS NULL AND i.expires_at > NOW();
+	`
+	err = db.QueryRowContext(ctx, query, utils.HashData(token)).Scan(
+		&invitation.ID,
+		&invitation.Email,
+		&invitation.AccountRole,
+		&invitation.InvitedBy,
+		&invitation.ExpiresAt,
+		&invitation.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return invitation, ErrInvalidInvitation
+	}
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get invitation: %s", err.Error()))
+		return invitation, err
+	}
+	return invitation, nil
+}
+
+/*
+AcceptLandlordInvitation creates the account an invitation is for, with the invited email and role.
+A staff account works for the landlord who invited them.
+The invitation is used up in the same transaction as the account is created, so each invitation works only once.
+
+Arguments:
+
+- token: The invitation token from the emailed link.
+
+- password: The password chosen for the account, which is hashed with bcrypt before it is stored.
+
+Returns:
+
+- string: The email of the new account.
+
+- error: ErrInvalidInvitation if the invitation cannot be used, ErrLandlordExists if the email already has an account,
+or an error object if the account cannot be created.
+*/
+func AcceptLandlordInvitation(ctx context.Context, token, password string) (string, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return "", errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := utils.VerifySignedToken(invitationTokenPurpose, token, time.Now())
+	if err != nil {
+		return "", ErrInvalidInvitation
+	}
+
+	hashPassword, err := utils.HashedPassword(password)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to hash password: %s", err.Error()))
+		return "", err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var invitationId, invitedBy int
+	var email, accountRole string
+	query := `
+	SELECT id, email, account_role, invited_by
+	FROM lhp_landlord_invitations
+	WHERE token_hash = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+	FOR UPDATE;
+	`
+	err = tx.QueryRowContext(ctx, query, utils.HashData(token)).Scan(&invitationId, &email, &accountRole, &invitedBy)
+	if err == sql.ErrNoRows {
+		return "", ErrInvalidInvitation
+	}
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get invitation: %s", err.Error()))
+		return "", err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM lhp_landlords WHERE email = $1);`, email).Scan(&exists)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to check landlord for invitation: %s", err.Error()))
+		return "", err
+	}
+	if exists {
+		return "", ErrLandlordExists
+	}
+
+	// staff work for the landlord who invited them
+	var worksFor sql.NullInt64
+	if accountRole == LandlordRoleStaff {
+		worksFor = sql.NullInt64{Int64: int64(invitedBy), Valid: true}
+	}
+	query = `
+	INSERT INTO lhp_landlords (email, password, account_role, works_for, created_at)
+	VALUES ($1, $2, $3, $4, NOW());
+	`
+	_, err = tx.ExecContext(ctx, query, email, hashPassword, accountRole, worksFor)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to create invited landlord: %s", err.Error()))
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE lhp_landlord_invitations SET accepted_at = NOW() WHERE id = $1;`, invitationId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to accept invitation: %s", err.Error()))
+		return "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit invitation: %s", err.Error()))
+		return "", err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Invitation accepted, new %s account created", accountRole))
+	return email, nil
+}
+
+/*
+GetPendingLandlordInvitations gets the invitations that can still be accepted, for the owner's admin page.
+
+Returns:
+
+- []LandlordInvitation: The invitations, the newest first.
+
+- error: An error object if the query fails.
+*/
+func GetPendingLandlordInvitations(ctx context.Context) ([]LandlordInvitation, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	SELECT i.id, i.email, i.account_role, l.email, i.expires_at, i.created_at
+	FROM lhp_landlord_invitations i
+	JOIN lhp_landlords l ON l.id = i.invited_by
+	WHERE i.accepted_at IS NULL AND i.revoked_at IS NULL AND i.expires_at > NOW()
+	ORDER BY i.created_at DESC;
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get pending invitations: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []LandlordInvitation
+	for rows.Next() {
+		var invitation LandlordInvitation
+		err := rows.Scan(
+			&invitation.ID,
+			&invitation.Email,
+			&invitation.AccountRole,
+			&invitation.InvitedBy,
+			&invitation.ExpiresAt,
+			&invitation.CreatedAt,
+		)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan invitation: %s", err.Error()))
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+/*
+RevokeLandlordInvitation stops an invitation from being accepted.
+
+Arguments:
+
+- invitationId: The ID of the invitation.
+
+Returns:
+
+- error: sql.ErrNoRows if there is no such invitation waiting to be accepted, or an error object if the query fails.
+*/
+func RevokeLandlordInvitation(ctx context.Context, invitationId int) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	UPDATE lhp_landlord_invitations
+	SET revoked_at = NOW()
+	WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL;
+	`
+	result, err := db.ExecContext(ctx, query, invitationId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to revoke invitation: %s", err.Error()))
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Invitation %d revoked", invitationId))
+	return nil
+}
@@ -0,0 +1,320 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+)
+
+/*
+CountLandlords counts the landlord accounts, including suspended ones.
+
+Returns:
+
+- int: The number of landlord accounts.
+
+- error: An error object if the query fails.
+*/
+func CountLandlords(ctx context.Context) (int, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return 0, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM lhp_landlords;`).Scan(&count)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to count landlords: %s", err.Error()))
+		return 0, err
+	}
+	return count, nil
+}
+
+/*
+GetLandlordAccess gets what a landlord dashboard account can see and do. Staff work with the records of the landlord
+they work for, and can only do what that landlord has granted them.
+
+Arguments:
+
+- email: The email of the account.
+
+Returns:
+
+- LandlordAccess: The account's role, the landlord whose records it works with, and a staff member's permissions.
+
+- error: sql.ErrNoRows if there is no such account, ErrLandlordSuspended if the account or the landlord a staff member
+works for is suspended, or an error object if the query fails.
+*/
+func GetLandlordAccess(ctx context.Context, email string) (LandlordAccess, error) {
+	var access LandlordAccess
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return access, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var staffId int
+	var suspended bool
+	query := `
+	SELECT l.id, l.account_role, COALESCE(e.email, l.email), l.suspended_at IS NOT NULL OR e.suspended_at IS NOT NULL
+	FROM lhp_landlords l
+	LEFT JOIN lhp_landlords e ON e.id = l.works_for
+	WHERE l.email = $1;
+	`
+	err := db.QueryRowContext(ctx, query, email).Scan(&staffId, &access.AccountRole, &access.LandlordEmail, &suspended)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord access: %s", err.Error()))
+		return access, err
+	}
+	if suspended {
+		return access, ErrLandlordSuspended
+	}
+	if access.AccountRole != LandlordRoleStaff {
+		return access, nil
+	}
+
+	access.Permissions, err = getStaffPermissions(ctx, staffId)
+	if err != nil {
+		return access, err
+	}
+	return access, nil
+}
+
+// getStaffPermissions gets the permissions granted to a staff member, in the order of StaffPermissions.
+func getStaffPermissions(ctx context.Context, staffId int) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT permission FROM lhp_staff_permissions WHERE staff_id = $1;`, staffId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get staff permissions: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	granted := map[string]bool{}
+	for rows.Next() {
+		var permission string
+		err := rows.Scan(&permission)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan staff permission: %s", err.Error()))
+			return nil, err
+		}
+		granted[permission] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var permissions []string
+	for _, permission := range StaffPermissions {
+		if granted[permission] {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions, nil
+}
+
+/*
+GetLandlordAccounts gets every account that can log in to the landlord dashboard, for the owner's admin page.
+
+Returns:
+
+- []LandlordAccount: The accounts, the owner first and then the oldest first.
+
+- error: An error object if the query fails.
+*/
+func GetLandlordAccounts(ctx context.Context) ([]LandlordAccount, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	SELECT l.id, l.email, l.account_role, COALESCE(e.email, ''), l.created_at, l.suspended_at
+	FROM lhp_landlords l
+	LEFT JOIN lhp_landlords e ON e.id = l.works_for
+	ORDER BY l.account_role = 'owner' DESC, l.created_at, l.id;
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord accounts: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []LandlordAccount
+	for rows.Next() {
+		var account LandlordAccount
+		err := rows.Scan(&account.ID, &account.Email, &account.AccountRole, &account.WorksFor, &account.CreatedAt, &account.SuspendedAt)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan landlord account: %s", err.Error()))
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+/*
+SetLandlordSuspended suspends or reinstates a landlord account. Suspending an account logs it out everywhere,
+and it cannot log in again until it is reinstated. The owner's account cannot be suspended.
+
+Arguments:
+
+- landlordId: The ID of the account.
+
+- suspended: True to suspend the account, false to reinstate it.
+
+Returns:
+
+- error: sql.ErrNoRows if there is no such account or it is the owner's, or an error object if the query fails.
+*/
+func SetLandlordSuspended(ctx context.Context, landlordId int, suspended bool) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	UPDATE lhp_landlords
+	SET suspended_at = NOW(), session_token = NULL, csrf_token = NULL, token_expiry = NULL
+	WHERE id = $1 AND account_role <> 'owner' AND suspended_at IS NULL;
+	`
+	if !suspended {
+		query = `
+		UPDATE lhp_landlords
+		SET suspended_at = NULL
+		WHERE id = $1 AND account_role <> 'owner' AND suspended_at IS NOT NULL;
+		`
+	}
+	result, err := db.ExecContext(ctx, query, landlordId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to update landlord suspension: %s", err.Error()))
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Landlord account %d suspended: %t", landlordId, suspended))
+	return nil
+}
+
+/*
+RemoveLandlordAccount deletes a landlord account along with its login settings. Accounts that still have tenants,
+applications, properties, maintenance tickets, messages or staff are kept, so no records are lost, and should be suspended instead.
+The owner's account cannot be removed.
+
+Arguments:
+
+- landlordId: The ID of the account.
+
+Returns:
+
+- error: sql.ErrNoRows if there is no such account or it is the owner's, ErrLandlordHasRecords if the account still has records,
+or an error object if the account cannot be removed.
+*/
+func RemoveLandlordAccount(ctx context.Context, landlordId int) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return err
+	}
+	defer tx.Rollback()
+
+	var email string
+	query := `
+	SELECT email
+	FROM lhp_landlords
+	WHERE id = $1 AND account_role <> 'owner'
+	FOR UPDATE;
+	`
+	err = tx.QueryRowContext(ctx, query, landlordId).Scan(&email)
+	if err == sql.ErrNoRows {
+		return sql.ErrNoRows
+	}
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord account: %s", err.Error()))
+		return err
+	}
+
+	var hasRecords bool
+	query = `
+	SELECT EXISTS (SELECT 1 FROM lhp_tenants WHERE landlord_id = $1)
+		OR EXISTS (SELECT 1 FROM lhp_tenant_application WHERE landlord_id = $1)
+		OR EXISTS (SELECT 1 FROM lhp_properties WHERE landlord_id = $1)
+		OR EXISTS (SELECT 1 FROM lhp_maintenance_tickets WHERE landlord_id = $1)
+		OR EXISTS (SELECT 1 FROM lhp_messages WHERE (sender_type = 'landlord' AND sender_id = $1) OR (receiver_type = 'landlord' AND receiver_id = $1))
+		OR EXISTS (SELECT 1 FROM lhp_landlords WHERE works_for = $1);
+	`
+	err = tx.QueryRowContext(ctx, query, landlordId).Scan(&hasRecords)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to check landlord records: %s", err.Error()))
+		return err
+	}
+	if hasRecords {
+		return ErrLandlordHasRecords
+	}
+
+	err = deleteLandlordAccount(ctx, tx, landlordId, email)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit landlord removal: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Landlord account %d removed", landlordId))
+	return nil
+}
+
+// deleteLandlordAccount deletes a landlord dashboard account and its login settings, once it has been checked that nothing else refers to it.
+func deleteLandlordAccount(ctx context.Context, tx *sql.Tx, landlordId int, email string) error {
+	// login settings are stored by email, so they would otherwise be picked up by a new account with the same email
+	for _, table := range []string{"lhp_two_factor", "lhp_two_factor_recovery_codes", "lhp_two_factor_challenges", "lhp_password_resets"} {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE user_type = 'landlord' AND user_key = $1;`, table), email)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to remove landlord login settings from %s: %s", table, err.Error()))
+			return err
+		}
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM lhp_login_throttles WHERE scope = 'account' AND user_type = 'landlord' AND throttle_key = $1;`, email)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to remove landlord login throttle: %s", err.Error()))
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM lhp_landlords WHERE id = $1;`, landlordId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to remove landlord account: %s", err.Error()))
+		return err
+	}
+	return nil
+}
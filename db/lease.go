@@ -0,0 +1,621 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// leaseColumns are the lease columns read by scanLease, in order.
+const leaseColumns = `id, tenant_id, status, start_date, end_date, term_months, break_clause_months, notice_days,
+	notice_given_by, notice_date, move_out_date, ended_at`
+
+// createLeaseQuery inserts an active lease, taking the tenant ID, status, start and end dates and the terms of the lease.
+const createLeaseQuery = `
+	INSERT INTO lhp_leases (tenant_id, status, start_date, end_date, term_months, break_clause_months, notice_days, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NOW());
+	`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLease(row rowScanner) (Lease, error) {
+	var lease Lease
+	err := row.Scan(
+		&lease.ID,
+		&lease.TenantID,
+		&lease.Status,
+		&lease.StartDate,
+		&lease.EndDate,
+		&lease.TermMonths,
+		&lease.BreakClauseMonths,
+		&lease.NoticeDays,
+		&lease.NoticeGivenBy,
+		&lease.NoticeDate,
+		&lease.MoveOutDate,
+		&lease.EndedAt,
+	)
+	return lease, err
+}
+
+/*
+CreateLease creates the lease for a new tenant.
+
+The end date is calculated from the start date and the term of the lease.
+
+Arguments:
+
+- tenantId: The ID of the tenant the lease belongs to.
+
+- startDate: The date the lease starts (usually the move in date), in the format YYYY-MM-DD.
+
+- termMonths: The length of the lease in months.
+
+- breakClauseMonths: The number of months after the start date when the lease may be ended early. 0 means no break clause.
+
+- noticeDays: The number of days notice either side must give to end the lease.
+
+Returns:
+
+- error: An error object if the start date is invalid or the lease cannot be stored.
+*/
+func CreateLease(ctx context.Context, tenantId int, startDate string, termMonths, breakClauseMonths, noticeDays int) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Invalid lease start date: %s", err.Error()))
+		return err
+	}
+	endDate := utils.AddMonths(start, termMonths)
+
+	_, err = db.ExecContext(ctx, createLeaseQuery, tenantId, LeaseActive, start, endDate, termMonths, breakClauseMonths, noticeDays)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to create lease: %s", err.Error()))
+		return err
+	}
+	logs.Logs(logDb, fmt.Sprintf("Lease created for tenant %d", tenantId))
+	return nil
+}
+
+/*
+GetLeaseByTenantId returns the lease of a tenant.
+
+Arguments:
+
+- tenantId: The ID of the tenant.
+
+Returns:
+
+- Lease: The tenant's lease.
+
+- error: sql.ErrNoRows if the tenant has no lease, or an error object if the query fails.
+*/
+func GetLeaseByTenantId(ctx context.Context, tenantId int) (Lease, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return Lease{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + leaseColumns + `
+	FROM lhp_leases
+	WHERE tenant_id = $1;
+	`
+	lease, err := scanLease(db.QueryRowContext(ctx, query, tenantId))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to get lease: %s", err.Error()))
+		}
+		return Lease{}, err
+	}
+	return lease, nil
+}
+
+/*
+GetLeasesByLandlordEmail returns every tenant of a landlord along with their lease and any open renewal offer.
+
+Tenants without a lease are included so the landlord can create one for them.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+Returns:
+
+- []LandlordLease: The landlord's tenants and their leases.
+
+- error: An error object if the query fails.
+*/
+func GetLeasesByLandlordEmail(ctx context.Context, landlordEmail string) ([]LandlordLease, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	landlordId, err := GetLandlordIdByEmail(ctx, landlordEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	SELECT id, encrypt_tenant_name
+	FROM lhp_tenants
+	WHERE landlord_id = $1
+	ORDER BY id;
+	`
+	rows, err := db.QueryContext(ctx, query, landlordId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord leases: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leases []LandlordLease
+	for rows.Next() {
+		var landlordLease LandlordLease
+		err := rows.Scan(&landlordLease.TenantID, &landlordLease.EncryptTenantName)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan landlord leases: %s", err.Error()))
+			return nil, err
+		}
+		leases = append(leases, landlordLease)
+	}
+	err = rows.Err()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord leases: %s", err.Error()))
+		return nil, err
+	}
+
+	// load the lease details once the tenant rows are closed
+	for i := range leases {
+		lease, err := GetLeaseByTenantId(ctx, leases[i].TenantID)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		leases[i].Lease = &lease
+
+		renewal, err := GetOpenRenewalOffer(ctx, lease.ID)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		leases[i].OpenRenewal = &renewal
+	}
+	return leases, nil
+}
+
+/*
+OfferLeaseRenewal offers the tenant a renewal of their lease.
+
+Any renewal offer that has not been answered yet is withdrawn, so a tenant only ever has one open offer.
+Only active leases can be renewed.
+
+Arguments:
+
+- leaseId: The ID of the lease to renew.
+
+- termMonths: The length of the renewal in months.
+
+- monthlyRent: The monthly rent for the renewed lease, e.g. "1200.00".
+
+Returns:
+
+- error: An error object if the lease cannot be renewed or the offer cannot be stored.
+*/
+func OfferLeaseRenewal(ctx context.Context, leaseId, termMonths int, monthlyRent string) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if termMonths <= 0 {
+		return errors.New("renewal term must be at least one month")
+	}
+	_, err := utils.ParseAmount(monthlyRent)
+	if err != nil {
+		return err
+	}
+
+	var status string
+	err = db.QueryRowContext(ctx, `SELECT status FROM lhp_leases WHERE id = $1;`, leaseId).Scan(&status)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get lease status: %s", err.Error()))
+		return err
+	}
+	if status != LeaseActive {
+		return fmt.Errorf("a lease with status %s cannot be renewed", status)
+	}
+
+	encryptMonthlyRent, err := utils.Encrypt([]byte(monthlyRent))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt renewal rent: %s", err.Error()))
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE lhp_lease_renewals
+	SET status = $1, responded_at = NOW()
+	WHERE lease_id = $2 AND status = $3;
+	`, RenewalWithdrawn, leaseId, RenewalOffered)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to withdraw previous renewal offers: %s", err.Error()))
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO lhp_lease_renewals (lease_id, status, term_months, encrypt_monthly_rent, offered_at)
+	VALUES ($1, $2, $3, $4, NOW());
+	`, leaseId, RenewalOffered, termMonths, encryptMonthlyRent)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to create renewal offer: %s", err.Error()))
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit renewal offer: %s", err.Error()))
+		return err
+	}
+	logs.Logs(logDb, fmt.Sprintf("Renewal offered for lease %d", leaseId))
+	return nil
+}
+
+/*
+GetOpenRenewalOffer returns the renewal offer on a lease that the tenant has not answered yet.
+
+Arguments:
+
+- leaseId: The ID of the lease.
+
+Returns:
+
+- LeaseRenewal: The open renewal offer.
+
+- error: sql.ErrNoRows if there is no open offer, or an error object if the query fails.
+*/
+func GetOpenRenewalOffer(ctx context.Context, leaseId int) (LeaseRenewal, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return LeaseRenewal{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var renewal LeaseRenewal
+	query := `
+	SELECT id, lease_id, status, term_months, encrypt_monthly_rent, offered_at, responded_at
+	FROM lhp_lease_renewals
+	WHERE lease_id = $1 AND status = $2
+	ORDER BY offered_at DESC
+	LIMIT 1;
+	`
+	err := db.QueryRowContext(ctx, query, leaseId, RenewalOffered).Scan(
+		&renewal.ID,
+		&renewal.LeaseID,
+		&renewal.Status,
+		&renewal.TermMonths,
+		&renewal.EncryptMonthlyRent,
+		&renewal.OfferedAt,
+		&renewal.RespondedAt,
+	)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to get renewal offer: %s", err.Error()))
+		}
+		return LeaseRenewal{}, err
+	}
+	return renewal, nil
+}
+
+/*
+RespondToRenewalOffer records the tenant's answer to the open renewal offer on their lease.
+
+When the offer is accepted the lease end date is extended by the renewal term and the tenant's
+monthly rent is updated to the renewal rent. Rent already charged is not changed.
+
+Arguments:
+
+- leaseId: The ID of the lease.
+
+- accept: True if the tenant accepts the offer, false if they decline it.
+
+Returns:
+
+- error: An error object if there is no open offer or the lease cannot be updated.
+*/
+func RespondToRenewalOffer(ctx context.Context, leaseId int, accept bool) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	renewal, err := GetOpenRenewalOffer(ctx, leaseId)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return err
+	}
+	defer tx.Rollback()
+
+	status := RenewalDeclined
+	if accept {
+		status = RenewalAccepted
+
+		var tenantId int
+		var endDate time.Time
+		var leaseStatus string
+		err = tx.QueryRowContext(ctx, `
+		SELECT tenant_id, end_date, status
+		FROM lhp_leases
+		WHERE id = $1
+		FOR UPDATE;
+		`, leaseId).Scan(&tenantId, &endDate, &leaseStatus)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to get lease: %s", err.Error()))
+			return err
+		}
+		if leaseStatus != LeaseActive {
+			return fmt.Errorf("a lease with status %s cannot be renewed", leaseStatus)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+		UPDATE lhp_leases
+		SET end_date = $1, term_months = term_months + $2
+		WHERE id = $3;
+		`, utils.AddMonths(endDate, renewal.TermMonths), renewal.TermMonths, leaseId)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to extend lease: %s", err.Error()))
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+		UPDATE lhp_tenants
+		SET encrypt_monthly_rent = $1
+		WHERE id = $2;
+		`, renewal.EncryptMonthlyRent, tenantId)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to update tenant rent: %s", err.Error()))
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE lhp_lease_renewals
+	SET status = $1, responded_at = NOW()
+	WHERE id = $2;
+	`, status, renewal.ID)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to update renewal offer: %s", err.Error()))
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit renewal response: %s", err.Error()))
+		return err
+	}
+	logs.Logs(logDb, fmt.Sprintf("Renewal offer %d %s", renewal.ID, status))
+	return nil
+}
+
+/*
+ServeLeaseNotice records a notice to quit on an active lease.
+
+The notice is checked against the lease's notice period and break clause before it is stored.
+Any open renewal offer is withdrawn.
+
+Arguments:
+
+- leaseId: The ID of the lease.
+
+- givenBy: Who gave notice, either "landlord" or "tenant".
+
+- noticeDate: The date notice was given, in the format YYYY-MM-DD.
+
+- moveOutDate: The date the tenant will move out, in the format YYYY-MM-DD.
+
+Returns:
+
+- error: An error object if the notice does not respect the lease terms or cannot be stored.
+*/
+func ServeLeaseNotice(ctx context.Context, leaseId int, givenBy, noticeDate, moveOutDate string) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if givenBy != "landlord" && givenBy != "tenant" {
+		return fmt.Errorf("invalid notice giver: %s", givenBy)
+	}
+	notice, err := time.Parse("2006-01-02", noticeDate)
+	if err != nil {
+		return fmt.Errorf("invalid notice date: %s", noticeDate)
+	}
+	moveOut, err := time.Parse("2006-01-02", moveOutDate)
+	if err != nil {
+		return fmt.Errorf("invalid move out date: %s", moveOutDate)
+	}
+
+	query := `SELECT ` + leaseColumns + `
+	FROM lhp_leases
+	WHERE id = $1;
+	`
+	lease, err := scanLease(db.QueryRowContext(ctx, query, leaseId))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get lease: %s", err.Error()))
+		return err
+	}
+	if lease.Status != LeaseActive {
+		return fmt.Errorf("notice cannot be given on a lease with status %s", lease.Status)
+	}
+
+	err = utils.ValidateLeaseNotice(lease.StartDate, lease.EndDate, lease.BreakClauseMonths, lease.NoticeDays, notice, moveOut)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE lhp_leases
+	SET status = $1, notice_given_by = $2, notice_date = $3, move_out_date = $4
+	WHERE id = $5;
+	`, LeaseNotice, givenBy, notice, moveOut, leaseId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to record notice: %s", err.Error()))
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE lhp_lease_renewals
+	SET status = $1, responded_at = NOW()
+	WHERE lease_id = $2 AND status = $3;
+	`, RenewalWithdrawn, leaseId, RenewalOffered)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to withdraw renewal offers: %s", err.Error()))
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit notice: %s", err.Error()))
+		return err
+	}
+	logs.Logs(logDb, fmt.Sprintf("Notice given by %s on lease %d", givenBy, leaseId))
+	return nil
+}
+
+/*
+EndLease ends a tenancy.
+
+The lease is marked as ended and the tenant is archived: their session is cleared and they can no longer log in.
+The tenant record, rent ledger and message history are kept.
+
+Arguments:
+
+- leaseId: The ID of the lease to end.
+
+Returns:
+
+- error: An error object if the lease has already ended or cannot be updated.
+*/
+func EndLease(ctx context.Context, leaseId int) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return err
+	}
+	defer tx.Rollback()
+
+	var tenantId int
+	var status string
+	err = tx.QueryRowContext(ctx, `
+	SELECT tenant_id, status
+	FROM lhp_leases
+	WHERE id = $1
+	FOR UPDATE;
+	`, leaseId).Scan(&tenantId, &status)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get lease: %s", err.Error()))
+		return err
+	}
+	if status == LeaseEnded {
+		return errors.New("lease has already ended")
+	}
+
+	// keep the move out date if notice was given, otherwise the tenancy ends today
+	_, err = tx.ExecContext(ctx, `
+	UPDATE lhp_leases
+	SET status = $1, ended_at = NOW(), move_out_date = COALESCE(move_out_date, CURRENT_DATE)
+	WHERE id = $2;
+	`, LeaseEnded, leaseId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to end lease: %s", err.Error()))
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE lhp_lease_renewals
+	SET status = $1, responded_at = NOW()
+	WHERE lease_id = $2 AND status = $3;
+	`, RenewalWithdrawn, leaseId, RenewalOffered)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to withdraw renewal offers: %s", err.Error()))
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE lhp_tenants
+	SET archived_at = NOW(), session_token = NULL, csrf_token = NULL, token_expiry = NULL
+	WHERE id = $1;
+	`, tenantId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to archive tenant: %s", err.Error()))
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit end of lease: %s", err.Error()))
+		return err
+	}
+	logs.Logs(logDb, fmt.Sprintf("Lease %d ended and tenant %d archived", leaseId, tenantId))
+	return nil
+}
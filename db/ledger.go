@@ -0,0 +1,288 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+/*
+GenerateRentCharges adds a monthly rent charge to the ledger for every rent due date
+from the tenant's first due date up to today, or up to the move out date once the tenant has given notice.
+
+The rent terms are read from the tenant's encrypted rent due and monthly rent columns.
+Charges that already exist for a period are left untouched, so this is safe to call on every page load.
+
+Arguments:
+
+- tenantId: The ID of the tenant to generate rent charges for.
+
+Returns:
+
+- error: An error object if the rent terms cannot be read or the charges cannot be stored.
+*/
+func GenerateRentCharges(ctx context.Context, tenantId int) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var encryptRentDue, encryptMonthlyRent []byte
+	var moveOutDate sql.NullTime
+	query := `
+	SELECT t.encrypt_rent_due, t.encrypt_monthly_rent, l.move_out_date
+	FROM lhp_tenants t
+	LEFT JOIN lhp_leases l ON l.tenant_id = t.id
+	WHERE t.id = $1;
+	`
+	err := db.QueryRowContext(ctx, query, tenantId).Scan(&encryptRentDue, &encryptMonthlyRent, &moveOutDate)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get tenant rent terms: %s", err.Error()))
+		return err
+	}
+
+	rentDue, err := utils.Decrypt(encryptRentDue)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to decrypt rent due: %s", err.Error()))
+		return err
+	}
+	monthlyRent, err := utils.Decrypt(encryptMonthlyRent)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to decrypt monthly rent: %s", err.Error()))
+		return err
+	}
+
+	// make sure the stored rent can be used in balance calculations before charging it
+	_, err = utils.ParseAmount(string(monthlyRent))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Invalid monthly rent for tenant %d: %s", tenantId, err.Error()))
+		return err
+	}
+
+	// no rent is charged for the months after the tenant moves out
+	until := time.Now()
+	if moveOutDate.Valid && moveOutDate.Time.Before(until) {
+		until = moveOutDate.Time.AddDate(0, 0, -1)
+	}
+
+	dueDates, err := utils.RentDueDates(string(rentDue), until)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Invalid rent due date for tenant %d: %s", tenantId, err.Error()))
+		return err
+	}
+
+	// get the periods that have already been charged
+	rows, err := db.QueryContext(ctx, `
+	SELECT period
+	FROM lhp_rent_ledger
+	WHERE tenant_id = $1 AND entry_type = $2;
+	`, tenantId, RentCharge)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get rent charges: %s", err.Error()))
+		return err
+	}
+	defer rows.Close()
+
+	charged := make(map[string]bool)
+	for rows.Next() {
+		var period string
+		err := rows.Scan(&period)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan rent charge: %s", err.Error()))
+			return err
+		}
+		charged[period] = true
+	}
+	err = rows.Err()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get rent charges: %s", err.Error()))
+		return err
+	}
+
+	insertQuery := `
+	INSERT INTO lhp_rent_ledger (tenant_id, entry_type, period, entry_date, encrypt_amount, created_at)
+	VALUES ($1, $2, $3, $4, $5, NOW())
+	ON CONFLICT (tenant_id, entry_type, period) DO NOTHING;
+	`
+	for _, dueDate := range dueDates {
+		period := dueDate.Format("2006-01")
+		if charged[period] {
+			continue
+		}
+
+		encryptAmount, err := utils.Encrypt(monthlyRent)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt rent charge: %s", err.Error()))
+			return err
+		}
+
+		_, err = db.ExecContext(ctx, insertQuery, tenantId, RentCharge, period, dueDate, encryptAmount)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to create rent charge: %s", err.Error()))
+			return err
+		}
+		logs.Logs(logDb, fmt.Sprintf("Rent charge for %s added to tenant %d ledger", period, tenantId))
+	}
+	return nil
+}
+
+/*
+RecordRentPayment stores a rent payment made by a tenant in the ledger.
+
+Arguments:
+
+- tenantId: The ID of the tenant who made the payment.
+
+- amount: The amount paid, e.g. "1200.00".
+
+- paidOn: The date the payment was made, in the format YYYY-MM-DD.
+
+- method: How the payment was made, e.g. bank transfer or cash.
+
+- reference: The payment reference, if any.
+
+Returns:
+
+- error: An error object if the payment is invalid or cannot be stored.
+*/
+func RecordRentPayment(ctx context.Context, tenantId int, amount, paidOn, method, reference string) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := utils.ParseAmount(amount)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Invalid payment amount: %s", err.Error()))
+		return err
+	}
+
+	paidOnDate, err := time.Parse("2006-01-02", paidOn)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Invalid payment date: %s", err.Error()))
+		return fmt.Errorf("invalid payment date: %s", paidOn)
+	}
+
+	encryptAmount, err := utils.Encrypt([]byte(amount))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt payment amount: %s", err.Error()))
+		return err
+	}
+	encryptMethod, err := utils.Encrypt([]byte(method))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt payment method: %s", err.Error()))
+		return err
+	}
+	encryptReference, err := utils.Encrypt([]byte(reference))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt payment reference: %s", err.Error()))
+		return err
+	}
+
+	query := `
+	INSERT INTO lhp_rent_ledger (
+		tenant_id,
+		entry_type,
+		entry_date,
+		encrypt_amount,
+		encrypt_method,
+		encrypt_reference,
+		created_at
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, NOW());
+	`
+	_, err = db.ExecContext(ctx, query, tenantId, RentPayment, paidOnDate, encryptAmount, encryptMethod, encryptReference)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to record rent payment: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Rent payment recorded for tenant %d", tenantId))
+	return nil
+}
+
+/*
+GetRentLedgerByTenantId returns every charge and payment in a tenant's rent ledger,
+oldest first, so a running balance can be calculated.
+
+Arguments:
+
+- tenantId: The ID of the tenant to get the ledger for.
+
+Returns:
+
+- []RentLedgerEntry: The tenant's ledger entries with the amounts still encrypted.
+
+- error: An error object if the ledger cannot be retrieved.
+*/
+func GetRentLedgerByTenantId(ctx context.Context, tenantId int) ([]RentLedgerEntry, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	SELECT
+		id,
+		tenant_id,
+		entry_type,
+		COALESCE(period, ''),
+		entry_date,
+		encrypt_amount,
+		encrypt_method,
+		encrypt_reference,
+		created_at
+	FROM lhp_rent_ledger
+	WHERE tenant_id = $1
+	ORDER BY entry_date ASC, id ASC;
+	`
+	rows, err := db.QueryContext(ctx, query, tenantId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get rent ledger: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ledger []RentLedgerEntry
+	for rows.Next() {
+		var entry RentLedgerEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.TenantID,
+			&entry.EntryType,
+			&entry.Period,
+			&entry.EntryDate,
+			&entry.EncryptAmount,
+			&entry.EncryptMethod,
+			&entry.EncryptReference,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan rent ledger: %s", err.Error()))
+			return nil, err
+		}
+		ledger = append(ledger, entry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get rent ledger: %s", err.Error()))
+		return nil, err
+	}
+
+	return ledger, nil
+}
@@ -0,0 +1,470 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+)
+
+/*
+GetLoginLockout checks whether a login has to wait because of earlier failed logins,
+either on the account or from the IP address the login comes from.
+
+Arguments:
+
+- userType: Either "landlord" or "tenant".
+
+- userKey: The landlord's email, or the tenant's hashed email.
+
+- ip: The IP address of the client.
+
+Returns:
+
+- time.Time: When the next login can be tried, or the zero time if it can be tried now.
+
+- error: An error object if the query fails.
+*/
+func GetLoginLockout(ctx context.Context, userType, userKey, ip string) (time.Time, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return time.Time{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var lockedUntil sql.NullTime
+	query := `
+	SELECT MAX(locked_until)
+	FROM lhp_login_throttles
+	WHERE locked_until > NOW()
+		AND ((scope = 'account' AND user_type = $1 AND throttle_key = $2) OR (scope = 'ip' AND throttle_key = $3));
+	`
+	err := db.QueryRowContext(ctx, query, userType, userKey, ip).Scan(&lockedUntil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to check login lockout: %s", err.Error()))
+		return time.Time{}, err
+	}
+	return lockedUntil.Time, nil
+}
+
+/*
+RecordLoginFailure counts a failed login against the account and the IP address it came from,
+and sets how long each has to wait before the next attempt following config.Login.
+
+The account is counted whether or not it exists, so a login for an unknown account looks the same as a wrong password.
+
+Arguments:
+
+- userType: Either "landlord" or "tenant".
+
+- userKey: The landlord's email, or the tenant's hashed email.
+
+- ip: The IP address of the client.
+
+Returns:
+
+- LoginFailure: The failures on the account, when the next login can be tried and whether to email the account owner.
+
+- error: An error object if the failure cannot be recorded.
+*/
+func RecordLoginFailure(ctx context.Context, userType, userKey, ip string) (LoginFailure, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return LoginFailure{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return LoginFailure{}, err
+	}
+	defer tx.Rollback()
+
+	accountId, failures, alerted, accountLockedUntil, err := recordThrottleFailure(ctx, tx, "account", userType, userKey, config.Login.AccountDelay)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to record account login failure: %s", err.Error()))
+		return LoginFailure{}, err
+	}
+
+	ipDelay := func(failures int) time.Duration { return config.Login.Delay(failures, config.Login.IPFreeAttempts) }
+	_, _, _, ipLockedUntil, err := recordThrottleFailure(ctx, tx, "ip", "", ip, ipDelay)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to record IP login failure: %s", err.Error()))
+		return LoginFailure{}, err
+	}
+
+	result := LoginFailure{Failures: failures, LockedUntil: accountLockedUntil}
+	if ipLockedUntil.After(result.LockedUntil) {
+		result.LockedUntil = ipLockedUntil
+	}
+
+	// the owner is only alerted once, until they log in or the failures are forgotten
+	if failures >= config.Login.AlertThreshold && !alerted {
+		_, err = tx.ExecContext(ctx, `UPDATE lhp_login_throttles SET alerted_at = NOW() WHERE id = $1;`, accountId)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to record login alert: %s", err.Error()))
+			return LoginFailure{}, err
+		}
+		result.SendAlert = true
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit login failure: %s", err.Error()))
+		return LoginFailure{}, err
+	}
+
+	if failures >= config.Login.LockoutThreshold {
+		logs.Logs(logWarning, fmt.Sprintf("%s account locked after %d failed logins", userType, failures))
+	}
+	return result, nil
+}
+
+/*
+recordThrottleFailure adds one failure to an account or IP address and sets when it can next try to log in.
+Failures older than config.Login.FailureWindow are forgotten, so the count starts again from one.
+It returns the row id, the failures in a row, whether the owner has been alerted, and the time it is locked until.
+*/
+func recordThrottleFailure(ctx context.Context, tx *sql.Tx, scope, userType, throttleKey string, delay func(int) time.Duration) (int, int, bool, time.Time, error) {
+	forgetBefore := time.Now().Add(-config.Login.FailureWindow)
+
+	var id, failures int
+	var alertedAt sql.NullTime
+	query := `
+	INSERT INTO lhp_login_throttles (scope, user_type, throttle_key, failures, last_failure_at)
+	VALUES ($1, $2, $3, 1, NOW())
+	ON CONFLICT (scope, user_type, throttle_key) DO UPDATE
+	SET failures = CASE WHEN lhp_login_throttles.last_failure_at < $4 THEN 1 ELSE lhp_login_throttles.failures + 1 END,
+		alerted_at = CASE WHEN lhp_login_throttles.last_failure_at < $4 THEN NULL ELSE lhp_login_throttles.alerted_at END,
+		last_failure_at = NOW()
+	RETURNING id, failures, alerted_at;
+	`
+	err := tx.QueryRowContext(ctx, query, scope, userType, throttleKey, forgetBefore).Scan(&id, &failures, &alertedAt)
+	if err != nil {
+		return 0, 0, false, time.Time{}, err
+	}
+
+	var lockedUntil time.Time
+	if wait := delay(failures); wait > 0 {
+		lockedUntil = time.Now().Add(wait)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE lhp_login_throttles SET locked_until = $1 WHERE id = $2;`, sql.NullTime{Time: lockedUntil, Valid: !lockedUntil.IsZero()}, id)
+	if err != nil {
+		return 0, 0, false, time.Time{}, err
+	}
+	return id, failures, alertedAt.Valid, lockedUntil, nil
+}
+
+/*
+ClearLoginFailures forgets the failed logins on an account once it has logged in.
+Failures from the IP address are kept, so logging in to one account does not reset the count against others.
+
+Arguments:
+
+- userType: Either "landlord" or "tenant".
+
+- userKey: The landlord's email, or the tenant's hashed email.
+
+Returns:
+
+- error: An error object if the failures cannot be cleared.
+*/
+func ClearLoginFailures(ctx context.Context, userType, userKey string) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	DELETE FROM lhp_login_throttles
+	WHERE scope = 'account' AND user_type = $1 AND throttle_key = $2;
+	`
+	_, err := db.ExecContext(ctx, query, userType, userKey)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to clear login failures: %s", err.Error()))
+		return err
+	}
+	return nil
+}
+
+/*
+GetLockedTenantAccounts gets the landlord's tenants whose accounts are locked after too many failed logins.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+Returns:
+
+- []LockedAccount: The locked tenant accounts, the longest locked first.
+
+- error: An error object if the query fails.
+*/
+func GetLockedTenantAccounts(ctx context.Context, landlordEmail string) ([]LockedAccount, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	SELECT t.id, t.encrypt_tenant_name, lt.failures, lt.locked_until
+	FROM lhp_login_throttles lt
+	JOIN lhp_tenants t ON t.hash_email = lt.throttle_key
+	JOIN lhp_landlords l ON l.id = t.landlord_id
+	WHERE lt.scope = 'account' AND lt.user_type = 'tenant' AND lt.failures >= $1 AND lt.locked_until > NOW()
+		AND l.email = $2 AND t.archived_at IS NULL
+	ORDER BY lt.locked_until DESC;
+	`
+	rows, err := db.QueryContext(ctx, query, config.Login.LockoutThreshold, landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get locked tenant accounts: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []LockedAccount
+	for rows.Next() {
+		var account LockedAccount
+		err := rows.Scan(&account.TenantID, &account.EncryptTenantName, &account.Failures, &account.LockedUntil)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan locked tenant account: %s", err.Error()))
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+/*
+UnlockTenantAccount clears the failed logins on one of the landlord's tenants, so they can log in straight away.
+Locks on IP addresses are not changed, they expire on their own.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+- tenantId: The ID of the tenant to unlock.
+
+Returns:
+
+- error: sql.ErrNoRows if the tenant does not belong to the landlord or is not locked, or an error object if the query fails.
+*/
+func UnlockTenantAccount(ctx context.Context, landlordEmail string, tenantId int) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	DELETE FROM lhp_login_throttles lt
+	USING lhp_tenants t, lhp_landlords l
+	WHERE lt.scope = 'account' AND lt.user_type = 'tenant' AND lt.throttle_key = t.hash_email
+		AND l.id = t.landlord_id AND t.id = $1 AND l.email = $2;
+	`
+	result, err := db.ExecContext(ctx, query, tenantId, landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to unlock tenant account: %s", err.Error()))
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Tenant account %d unlocked by landlord", tenantId))
+	return nil
+}
+
+/*
+GetLockedLandlordAccounts gets every landlord dashboard account that is locked after too many failed logins, for the owner's admin page.
+
+Returns:
+
+- []LockedLandlordAccount: The locked accounts, the longest locked first.
+
+- error: An error object if the query fails.
+*/
+func GetLockedLandlordAccounts(ctx context.Context) ([]LockedLandlordAccount, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	SELECT l.id, l.email, l.account_role, lt.failures, lt.locked_until
+	FROM lhp_login_throttles lt
+	JOIN lhp_landlords l ON l.email = lt.throttle_key
+	WHERE lt.scope = 'account' AND lt.user_type = 'landlord' AND lt.failures >= $1 AND lt.locked_until > NOW()
+	ORDER BY lt.locked_until DESC;
+	`
+	return queryLockedLandlordAccounts(ctx, query, config.Login.LockoutThreshold)
+}
+
+/*
+GetLockedStaffAccounts gets the landlord's staff whose accounts are locked after too many failed logins.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+Returns:
+
+- []LockedLandlordAccount: The locked staff accounts, the longest locked first.
+
+- error: An error object if the query fails.
+*/
+func GetLockedStaffAccounts(ctx context.Context, landlordEmail string) ([]LockedLandlordAccount, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	SELECT s.id, s.email, s.account_role, lt.failures, lt.locked_until
+	FROM lhp_login_throttles lt
+	JOIN lhp_landlords s ON s.email = lt.throttle_key
+	JOIN lhp_landlords l ON l.id = s.works_for
+	WHERE lt.scope = 'account' AND lt.user_type = 'landlord' AND lt.failures >= $1 AND lt.locked_until > NOW()
+		AND s.account_role = 'staff' AND l.email = $2
+	ORDER BY lt.locked_until DESC;
+	`
+	return queryLockedLandlordAccounts(ctx, query, config.Login.LockoutThreshold, landlordEmail)
+}
+
+// queryLockedLandlordAccounts runs a query for locked landlord dashboard accounts and scans the rows it returns.
+func queryLockedLandlordAccounts(ctx context.Context, query string, args ...any) ([]LockedLandlordAccount, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get locked landlord accounts: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []LockedLandlordAccount
+	for rows.Next() {
+		var account LockedLandlordAccount
+		err := rows.Scan(&account.ID, &account.Email, &account.AccountRole, &account.Failures, &account.LockedUntil)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan locked landlord account: %s", err.Error()))
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+/*
+UnlockLandlordAccount clears the failed logins on a landlord dashboard account, so it can log in straight away.
+Locks on IP addresses are not changed, they expire on their own.
+
+Arguments:
+
+- landlordId: The ID of the account to unlock.
+
+Returns:
+
+- error: sql.ErrNoRows if there is no such account or it is not locked, or an error object if the query fails.
+*/
+func UnlockLandlordAccount(ctx context.Context, landlordId int) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	DELETE FROM lhp_login_throttles lt
+	USING lhp_landlords l
+	WHERE lt.scope = 'account' AND lt.user_type = 'landlord' AND lt.throttle_key = l.email AND l.id = $1;
+	`
+	result, err := db.ExecContext(ctx, query, landlordId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to unlock landlord account: %s", err.Error()))
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Landlord account %d unlocked by owner", landlordId))
+	return nil
+}
+
+/*
+UnlockStaffAccount clears the failed logins on one of the landlord's staff, so they can log in straight away.
+Locks on IP addresses are not changed, they expire on their own.
+
+Arguments:
+
+- landlordEmail: The email of the landlord the staff member works for.
+
+- staffId: The ID of the staff member's account.
+
+Returns:
+
+- error: sql.ErrNoRows if the staff member does not work for the landlord or is not locked, or an error object if the query fails.
+*/
+func UnlockStaffAccount(ctx context.Context, landlordEmail string, staffId int) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	DELETE FROM lhp_login_throttles lt
+	USING lhp_landlords s, lhp_landlords l
+	WHERE lt.scope = 'account' AND lt.user_type = 'landlord' AND lt.throttle_key = s.email
+		AND s.account_role = 'staff' AND l.id = s.works_for AND s.id = $1 AND l.email = $2;
+	`
+	result, err := db.ExecContext(ctx, query, staffId, landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to unlock staff account: %s", err.Error()))
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Staff account %d unlocked by landlord", staffId))
+	return nil
+}
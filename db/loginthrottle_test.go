@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+)
+
+// throttleResponder answers the failure upserts of RecordLoginFailure with the failures each scope reaches
+func throttleResponder(accountFailures, ipFailures int64) func(query string, args []driver.Value) fakeResponse {
+	return func(query string, args []driver.Value) fakeResponse {
+		if !strings.HasPrefix(query, "INSERT INTO lhp_login_throttles") {
+			return fakeResponse{rowsAffected: 1}
+		}
+		row := []driver.Value{int64(1), accountFailures, nil}
+		if args[0] == "ip" {
+			row = []driver.Value{int64(2), ipFailures, nil}
+		}
+		return fakeResponse{columns: []string{"id", "failures", "alerted_at"}, rows: [][]driver.Value{row}}
+	}
+}
+
+// lockedUntilSet returns the lock set on the throttle row by RecordLoginFailure, or nil if it was cleared
+func lockedUntilSet(t *testing.T, statements []fakeStatement, id int64) driver.Value {
+	for _, statement := range statements {
+		if strings.HasPrefix(statement.query, "UPDATE lhp_login_throttles SET locked_until") && statement.args[1] == id {
+			return statement.args[0]
+		}
+	}
+	t.Fatalf("Expected the lock on throttle %d to be set", id)
+	return nil
+}
+
+func TestRecordLoginFailure(t *testing.T) {
+	t.Run("Account and IP address counted within the window", func(t *testing.T) {
+		fake := useFakeDB(t, throttleResponder(1, 1))
+
+		before := time.Now()
+		_, err := RecordLoginFailure(context.Background(), "landlord", "test@example.com", "198.51.100.1")
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+
+		var scopes []string
+		for _, statement := range fake.ran() {
+			if !strings.HasPrefix(statement.query, "INSERT INTO lhp_login_throttles") {
+				continue
+			}
+			scopes = append(scopes, statement.args[0].(string)+"/"+statement.args[1].(string)+"/"+statement.args[2].(string))
+			// failures from before the window are forgotten rather than added to
+			forgetBefore := statement.args[3].(time.Time)
+			expected := before.Add(-config.Login.FailureWindow)
+			if forgetBefore.Before(expected.Add(-time.Second)) || forgetBefore.After(expected.Add(time.Second)) {
+				t.Errorf("Expected failures before %v to be forgotten, got %v", expected, forgetBefore)
+			}
+		}
+		if len(scopes) != 2 || scopes[0] != "account/landlord/test@example.com" || scopes[1] != "ip//198.51.100.1" {
+			t.Errorf("Expected the failure counted against the account and the IP address, got %v", scopes)
+		}
+	})
+
+	t.Run("Account locked at the threshold", func(t *testing.T) {
+		fake := useFakeDB(t, throttleResponder(int64(config.Login.LockoutThreshold), 1))
+
+		failure, err := RecordLoginFailure(context.Background(), "tenant", "hash", "198.51.100.1")
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if wait := time.Until(failure.LockedUntil); wait < config.Login.LockoutDuration-time.Minute || wait > config.Login.LockoutDuration {
+			t.Errorf("Expected the account to be locked for %v, got %v", config.Login.LockoutDuration, wait)
+		}
+		if locked, ok := lockedUntilSet(t, fake.ran(), 1).(time.Time); !ok || !locked.Equal(failure.LockedUntil) {
+			t.Errorf("Expected the account's lock to be saved, got %v", locked)
+		}
+		if !failure.SendAlert {
+			t.Errorf("Expected the owner to be alerted past the alert threshold")
+		}
+	})
+
+	t.Run("Free attempts do not wait", func(t *testing.T) {
+		fake := useFakeDB(t, throttleResponder(1, 1))
+
+		failure, err := RecordLoginFailure(context.Background(), "landlord", "test@example.com", "198.51.100.1")
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if !failure.LockedUntil.IsZero() || failure.SendAlert {
+			t.Errorf("Expected no wait and no alert after one failure, got %v", failure)
+		}
+		if locked := lockedUntilSet(t, fake.ran(), 1); locked != nil {
+			t.Errorf("Expected no lock on the account, got %v", locked)
+		}
+	})
+
+	t.Run("IP address locked past its free attempts", func(t *testing.T) {
+		useFakeDB(t, throttleResponder(1, int64(config.Login.IPFreeAttempts)))
+
+		failure, err := RecordLoginFailure(context.Background(), "landlord", "test@example.com", "198.51.100.1")
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if failure.LockedUntil.IsZero() {
+			t.Errorf("Expected the login to wait for the IP address")
+		}
+		if failure.Failures != 1 {
+			t.Errorf("Expected the account's failures to be reported, got %d", failure.Failures)
+		}
+	})
+}
+
+func TestClearLoginFailures(t *testing.T) {
+	fake := useFakeDB(t, nil)
+
+	err := ClearLoginFailures(context.Background(), "landlord", "test@example.com")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// failures from the IP address are kept, so logging in to one account does not reset the count against others
+	statements := fake.ran()
+	if len(statements) != 1 || !strings.Contains(statements[0].query, "scope = 'account'") {
+		t.Fatalf("Expected only the account's failures to be cleared, ran %v", statements)
+	}
+	if statements[0].args[0] != "landlord" || statements[0].args[1] != "test@example.com" {
+		t.Errorf("Expected the landlord account's failures to be cleared, got %v", statements[0].args)
+	}
+}
+
+func TestUnlockAccounts(t *testing.T) {
+	unlocks := []struct {
+		name   string
+		unlock func() error
+	}{
+		{name: "Tenant", unlock: func() error { return UnlockTenantAccount(context.Background(), "landlord@example.com", 7) }},
+		{name: "Landlord", unlock: func() error { return UnlockLandlordAccount(context.Background(), 7) }},
+		{name: "Staff", unlock: func() error { return UnlockStaffAccount(context.Background(), "landlord@example.com", 7) }},
+	}
+
+	for _, tc := range unlocks {
+		t.Run(tc.name, func(t *testing.T) {
+			var rowsAffected int64
+			fake := useFakeDB(t, func(query string, args []driver.Value) fakeResponse {
+				return fakeResponse{rowsAffected: rowsAffected}
+			})
+
+			// an account that is not locked, or not the caller's to unlock, matches no rows
+			if err := tc.unlock(); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows, got %v", err)
+			}
+
+			rowsAffected = 1
+			if err := tc.unlock(); err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+
+			// IP address locks expire on their own
+			for _, statement := range fake.ran() {
+				if !strings.Contains(statement.query, "lt.scope = 'account'") {
+					t.Errorf("Expected only account locks to be cleared, ran: %s", statement.query)
+				}
+			}
+		})
+	}
+}
+
+func TestGetLockedStaffAccounts(t *testing.T) {
+	lockedUntil := time.Now().Add(time.Hour)
+	fake := useFakeDB(t, func(query string, args []driver.Value) fakeResponse {
+		return fakeResponse{
+			columns: []string{"id", "email", "account_role", "failures", "locked_until"},
+			rows:    [][]driver.Value{{int64(4), "staff@example.com", LandlordRoleStaff, int64(10), lockedUntil}},
+		}
+	})
+
+	accounts, err := GetLockedStaffAccounts(context.Background(), "landlord@example.com")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(accounts) != 1 || accounts[0].ID != 4 || accounts[0].Email != "staff@example.com" || accounts[0].Failures != 10 {
+		t.Fatalf("Expected the locked staff account, got %v", accounts)
+	}
+
+	// only the landlord's own staff past the lockout threshold are listed
+	statement := fake.ran()[0]
+	if !strings.Contains(statement.query, "s.account_role = 'staff'") || statement.args[0] != int64(config.Login.LockoutThreshold) || statement.args[1] != "landlord@example.com" {
+		t.Errorf("Expected the landlord's locked staff to be queried, ran %s with %v", statement.query, statement.args)
+	}
+}
@@ -0,0 +1,634 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// ticketQuery selects maintenance tickets along with the tenant, property and room they were raised for.
+const ticketQuery = `
+	SELECT
+		m.id,
+		m.tenant_id,
+		t.encrypt_tenant_name,
+		COALESCE(p.name, ''),
+		COALESCE(r.name, ''),
+		m.category,
+		m.priority,
+		m.encrypt_description,
+		m.status,
+		m.scheduled_for,
+		m.created_at,
+		m.updated_at
+	FROM lhp_maintenance_tickets m
+	JOIN lhp_tenants t ON t.id = m.tenant_id
+	LEFT JOIN lhp_properties p ON p.id = m.property_id
+	LEFT JOIN lhp_rooms r ON r.id = m.room_id
+`
+
+// attachmentQuery selects maintenance ticket photos along with the tenant and landlord who may view them.
+const attachmentQuery = `
+	SELECT a.id, a.ticket_id, a.file_name, a.content_type, a.encrypt_data, a.created_at
+	FROM lhp_maintenance_attachments a
+	JOIN lhp_maintenance_tickets m ON m.id = a.ticket_id
+`
+
+func scanTicket(row rowScanner) (MaintenanceTicket, error) {
+	var ticket MaintenanceTicket
+	err := row.Scan(
+		&ticket.ID,
+		&ticket.TenantID,
+		&ticket.EncryptTenantName,
+		&ticket.PropertyName,
+		&ticket.RoomName,
+		&ticket.Category,
+		&ticket.Priority,
+		&ticket.EncryptDescription,
+		&ticket.Status,
+		&ticket.ScheduledFor,
+		&ticket.CreatedAt,
+		&ticket.UpdatedAt,
+	)
+	return ticket, err
+}
+
+/*
+CreateMaintenanceTicket opens a new maintenance ticket for a tenant's room, along with any photos they attached.
+The ticket, its photos and the first entry of its audit trail are stored in one transaction.
+
+Arguments:
+
+- tenantHashEmail: The hashed email of the tenant opening the ticket.
+
+- category: The kind of repair needed (e.g. plumbing, electrical).
+
+- priority: How urgent the repair is (low, medium, high or urgent).
+
+- description: The tenant's description of the problem.
+
+- attachments: The photos uploaded with the ticket.
+
+Returns:
+
+- int: The ID of the new ticket.
+
+- error: An error object if the ticket is invalid or cannot be stored.
+*/
+func CreateMaintenanceTicket(ctx context.Context, tenantHashEmail, category, priority, description string, attachments []NewMaintenanceAttachment) (int, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return 0, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := utils.ValidateMaintenanceTicket(category, priority, description)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Invalid maintenance ticket: %s", err.Error()))
+		return 0, err
+	}
+
+	encryptDescription, err := utils.Encrypt([]byte(description))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt ticket description: %s", err.Error()))
+		return 0, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// the ticket is raised against the landlord, property and room the tenant currently lives in
+	var tenantId, landlordId int
+	var propertyId, roomId sql.NullInt64
+	query := `
+	SELECT id, landlord_id, property_id, room_id
+	FROM lhp_tenants
+	WHERE hash_email = $1;
+	`
+	err = tx.QueryRowContext(ctx, query, tenantHashEmail).Scan(&tenantId, &landlordId, &propertyId, &roomId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get tenant: %s", err.Error()))
+		return 0, err
+	}
+
+	var ticketId int
+	query = `
+	INSERT INTO lhp_maintenance_tickets (
+		tenant_id,
+		landlord_id,
+		property_id,
+		room_id,
+		category,
+		priority,
+		encrypt_description,
+		status,
+		created_at,
+		updated_at
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+	RETURNING id;
+	`
+	err = tx.QueryRowContext(ctx, query, tenantId, landlordId, propertyId, roomId, category, priority, encryptDescription, TicketOpen).Scan(&ticketId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to create maintenance ticket: %s", err.Error()))
+		return 0, err
+	}
+
+	for _, attachment := range attachments {
+		encryptData, err := utils.Encrypt(attachment.Data)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt ticket attachment: %s", err.Error()))
+			return 0, err
+		}
+
+		query = `
+		INSERT INTO lhp_maintenance_attachments (ticket_id, file_name, content_type, encrypt_data, created_at)
+		VALUES ($1, $2, $3, $4, NOW());
+		`
+		_, err = tx.ExecContext(ctx, query, ticketId, attachment.FileName, attachment.ContentType, encryptData)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to save ticket attachment: %s", err.Error()))
+			return 0, err
+		}
+	}
+
+	err = recordTicketEvent(ctx, tx, ticketId, sql.NullString{}, TicketOpen, "tenant", tenantId, "")
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit maintenance ticket: %s", err.Error()))
+		return 0, err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Maintenance ticket %d opened by tenant %d", ticketId, tenantId))
+	return ticketId, nil
+}
+
+/*
+GetMaintenanceTicketsByTenantHashEmail retrieves every maintenance ticket a tenant has opened, newest first,
+along with the details of their photos and status changes.
+
+Arguments:
+
+- tenantHashEmail: The hashed email of the tenant.
+
+Returns:
+
+- []MaintenanceTicket: The tenant's tickets with the description still encrypted.
+
+- error: An error object if the tickets cannot be retrieved.
+*/
+func GetMaintenanceTicketsByTenantHashEmail(ctx context.Context, tenantHashEmail string) ([]MaintenanceTicket, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return getMaintenanceTickets(ctx, ticketQuery+`WHERE t.hash_email = $1 ORDER BY m.created_at DESC;`, tenantHashEmail)
+}
+
+/*
+GetMaintenanceTicketsByLandlordEmail retrieves the maintenance tickets raised for the landlord's properties,
+with unresolved tickets first and the most urgent at the top.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+Returns:
+
+- []MaintenanceTicket: The landlord's tickets with the tenant name and description still encrypted.
+
+- error: An error object if the tickets cannot be retrieved.
+*/
+func GetMaintenanceTicketsByLandlordEmail(ctx context.Context, landlordEmail string) ([]MaintenanceTicket, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	landlordId, err := GetLandlordIdByEmail(ctx, landlordEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	query := ticketQuery + `
+	WHERE m.landlord_id = $1
+	ORDER BY
+		m.status = 'resolved',
+		CASE m.priority WHEN 'urgent' THEN 0 WHEN 'high' THEN 1 WHEN 'medium' THEN 2 ELSE 3 END,
+		m.created_at;
+	`
+	return getMaintenanceTickets(ctx, query, landlordId)
+}
+
+/*
+GetLandlordMaintenanceTicket retrieves a single maintenance ticket, as long as it was raised for one of the landlord's properties.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+- ticketId: The ID of the ticket.
+
+Returns:
+
+- MaintenanceTicket: The ticket. sql.ErrNoRows is returned if it does not exist or belongs to another landlord.
+
+- error: An error object if the ticket cannot be retrieved.
+*/
+func GetLandlordMaintenanceTicket(ctx context.Context, landlordEmail string, ticketId int) (MaintenanceTicket, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return MaintenanceTicket{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	landlordId, err := GetLandlordIdByEmail(ctx, landlordEmail)
+	if err != nil {
+		return MaintenanceTicket{}, err
+	}
+
+	tickets, err := getMaintenanceTickets(ctx, ticketQuery+`WHERE m.landlord_id = $1 AND m.id = $2;`, landlordId, ticketId)
+	if err != nil {
+		return MaintenanceTicket{}, err
+	}
+	if len(tickets) == 0 {
+		return MaintenanceTicket{}, sql.ErrNoRows
+	}
+	return tickets[0], nil
+}
+
+/*
+UpdateMaintenanceTicket moves one of the landlord's maintenance tickets to a new status and records the change in the ticket's audit trail.
+Tickets only move forward from open to scheduled, in progress and resolved, and a visit date is required to schedule a ticket.
+The email telling the tenant about the change is added to the outbox in the same transaction.
+
+Arguments:
+
+- landlordEmail: The email of the landlord updating the ticket.
+
+- ticketId: The ID of the ticket.
+
+- status: The new status of the ticket.
+
+- priority: The (possibly re-triaged) priority of the ticket.
+
+- scheduledFor: The date of the repair visit, in the format YYYY-MM-DD. Empty to keep the current date.
+
+- note: An optional note for the tenant explaining the change.
+
+Returns:
+
+- error: An error object if the ticket does not belong to the landlord, the change is not allowed, or it cannot be stored.
+*/
+func UpdateMaintenanceTicket(ctx context.Context, landlordEmail string, ticketId int, status, priority, scheduledFor, note string) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := utils.ValidateTicketPriority(priority)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Invalid ticket priority: %s", err.Error()))
+		return err
+	}
+
+	var visitDate sql.NullTime
+	if scheduledFor != "" {
+		date, err := time.Parse("2006-01-02", scheduledFor)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Invalid visit date: %s", err.Error()))
+			return fmt.Errorf("invalid visit date: %s", scheduledFor)
+		}
+		visitDate = sql.NullTime{Time: date, Valid: true}
+	}
+
+	landlordId, err := GetLandlordIdByEmail(ctx, landlordEmail)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return err
+	}
+	defer tx.Rollback()
+
+	// lock the ticket so two updates cannot both move it from the same status
+	var currentStatus, category, tenantHashEmail string
+	var currentVisitDate sql.NullTime
+	var encryptTenantEmail []byte
+	query := `
+	SELECT m.status, m.scheduled_for, m.category, t.encrypt_email, t.hash_email
+	FROM lhp_maintenance_tickets m
+	JOIN lhp_tenants t ON t.id = m.tenant_id
+	WHERE m.id = $1 AND m.landlord_id = $2
+	FOR UPDATE OF m;
+	`
+	err = tx.QueryRowContext(ctx, query, ticketId, landlordId).Scan(&currentStatus, &currentVisitDate, &category, &encryptTenantEmail, &tenantHashEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get maintenance ticket: %s", err.Error()))
+		return err
+	}
+
+	err = utils.ValidateTicketTransition(currentStatus, status)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Invalid ticket status change: %s", err.Error()))
+		return err
+	}
+
+	if !visitDate.Valid {
+		visitDate = currentVisitDate
+	}
+	if status == TicketScheduled && !visitDate.Valid {
+		logs.Logs(logDbErr, "A visit date is required to schedule a maintenance ticket")
+		return errors.New("a visit date is required to schedule a maintenance ticket")
+	}
+
+	query = `
+	UPDATE lhp_maintenance_tickets
+	SET status = $1, priority = $2, scheduled_for = $3, updated_at = NOW()
+	WHERE id = $4;
+	`
+	_, err = tx.ExecContext(ctx, query, status, priority, visitDate, ticketId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to update maintenance ticket: %s", err.Error()))
+		return err
+	}
+
+	err = recordTicketEvent(ctx, tx, ticketId, sql.NullString{String: currentStatus, Valid: true}, status, "landlord", landlordId, note)
+	if err != nil {
+		return err
+	}
+
+	tenantEmail, err := utils.Decrypt(encryptTenantEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to decrypt tenant email: %s", err.Error()))
+		return err
+	}
+	update := TicketUpdateEmail{
+		TenantEmail: string(tenantEmail),
+		TicketID:    ticketId,
+		Category:    category,
+		Status:      status,
+		Note:        note,
+	}
+	if visitDate.Valid {
+		update.ScheduledFor = visitDate.Time.Format("2006-01-02")
+	}
+	err = enqueueEmail(ctx, tx, OutboxTenantTicketUpdate, tenantHashEmail, update)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit maintenance ticket update: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Maintenance ticket %d moved from %s to %s", ticketId, currentStatus, status))
+	return nil
+}
+
+/*
+GetTenantMaintenanceAttachment retrieves a photo attached to one of the tenant's own maintenance tickets.
+
+Arguments:
+
+- tenantHashEmail: The hashed email of the tenant.
+
+- attachmentId: The ID of the attachment.
+
+Returns:
+
+- MaintenanceAttachment: The attachment with its data still encrypted. sql.ErrNoRows is returned if it belongs to another tenant.
+
+- error: An error object if the attachment cannot be retrieved.
+*/
+func GetTenantMaintenanceAttachment(ctx context.Context, tenantHashEmail string, attachmentId int) (MaintenanceAttachment, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return MaintenanceAttachment{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := attachmentQuery + `
+	JOIN lhp_tenants t ON t.id = m.tenant_id
+	WHERE t.hash_email = $1 AND a.id = $2;
+	`
+	return getMaintenanceAttachment(ctx, query, tenantHashEmail, attachmentId)
+}
+
+/*
+GetLandlordMaintenanceAttachment retrieves a photo attached to a maintenance ticket raised for one of the landlord's properties.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+- attachmentId: The ID of the attachment.
+
+Returns:
+
+- MaintenanceAttachment: The attachment with its data still encrypted. sql.ErrNoRows is returned if it belongs to another landlord.
+
+- error: An error object if the attachment cannot be retrieved.
+*/
+func GetLandlordMaintenanceAttachment(ctx context.Context, landlordEmail string, attachmentId int) (MaintenanceAttachment, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return MaintenanceAttachment{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := attachmentQuery + `
+	JOIN lhp_landlords l ON l.id = m.landlord_id
+	WHERE l.email = $1 AND a.id = $2;
+	`
+	return getMaintenanceAttachment(ctx, query, landlordEmail, attachmentId)
+}
+
+func getMaintenanceAttachment(ctx context.Context, query string, owner string, attachmentId int) (MaintenanceAttachment, error) {
+	var attachment MaintenanceAttachment
+	err := db.QueryRowContext(ctx, query, owner, attachmentId).Scan(
+		&attachment.ID,
+		&attachment.TicketID,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.EncryptData,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get ticket attachment: %s", err.Error()))
+		return MaintenanceAttachment{}, err
+	}
+	return attachment, nil
+}
+
+// getMaintenanceTickets runs a ticket query and loads the attachments and audit trail of each ticket it returns.
+func getMaintenanceTickets(ctx context.Context, query string, args ...any) ([]MaintenanceTicket, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get maintenance tickets: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tickets []MaintenanceTicket
+	for rows.Next() {
+		ticket, err := scanTicket(rows)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan maintenance ticket: %s", err.Error()))
+			return nil, err
+		}
+		tickets = append(tickets, ticket)
+	}
+	err = rows.Err()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get maintenance tickets: %s", err.Error()))
+		return nil, err
+	}
+	rows.Close()
+
+	// load the ticket details once the ticket rows are closed
+	for i := range tickets {
+		tickets[i].Attachments, err = getTicketAttachments(ctx, tickets[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		tickets[i].Events, err = getTicketEvents(ctx, tickets[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tickets, nil
+}
+
+// getTicketAttachments lists the photos attached to a ticket, without loading the photos themselves.
+func getTicketAttachments(ctx context.Context, ticketId int) ([]MaintenanceAttachment, error) {
+	query := `
+	SELECT id, ticket_id, file_name, content_type, created_at
+	FROM lhp_maintenance_attachments
+	WHERE ticket_id = $1
+	ORDER BY id;
+	`
+	rows, err := db.QueryContext(ctx, query, ticketId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get ticket attachments: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []MaintenanceAttachment
+	for rows.Next() {
+		var attachment MaintenanceAttachment
+		err := rows.Scan(&attachment.ID, &attachment.TicketID, &attachment.FileName, &attachment.ContentType, &attachment.CreatedAt)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan ticket attachment: %s", err.Error()))
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// getTicketEvents returns the audit trail of a ticket, oldest first.
+func getTicketEvents(ctx context.Context, ticketId int) ([]MaintenanceEvent, error) {
+	query := `
+	SELECT id, ticket_id, from_status, to_status, changed_by_type, changed_by_id, encrypt_note, created_at
+	FROM lhp_maintenance_ticket_events
+	WHERE ticket_id = $1
+	ORDER BY created_at, id;
+	`
+	rows, err := db.QueryContext(ctx, query, ticketId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get ticket events: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []MaintenanceEvent
+	for rows.Next() {
+		var event MaintenanceEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.TicketID,
+			&event.FromStatus,
+			&event.ToStatus,
+			&event.ChangedByType,
+			&event.ChangedByID,
+			&event.EncryptNote,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan ticket event: %s", err.Error()))
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// recordTicketEvent adds a status change to a ticket's audit trail, as part of the transaction that made the change.
+func recordTicketEvent(ctx context.Context, tx *sql.Tx, ticketId int, fromStatus sql.NullString, toStatus, changedByType string, changedById int, note string) error {
+	var encryptNote []byte
+	if note != "" {
+		var err error
+		encryptNote, err = utils.Encrypt([]byte(note))
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt ticket note: %s", err.Error()))
+			return err
+		}
+	}
+
+	query := `
+	INSERT INTO lhp_maintenance_ticket_events (
+		ticket_id,
+		from_status,
+		to_status,
+		changed_by_type,
+		changed_by_id,
+		encrypt_note,
+		created_at
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, NOW());
+	`
+	_, err := tx.ExecContext(ctx, query, ticketId, fromStatus, toStatus, changedByType, changedById, encryptNote)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to record ticket event: %s", err.Error()))
+		return err
+	}
+	return nil
+}
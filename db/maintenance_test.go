@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+func TestUpdateMaintenanceTicketQueuesEmail(t *testing.T) {
+	initTestEncryption()
+	tenantEmail, err := utils.Encrypt([]byte("tenant@example.com"))
+	if err != nil {
+		t.Fatalf("Failed to encrypt test data: %s", err.Error())
+	}
+
+	fake := useFakeDB(t, func(query string, args []driver.Value) fakeResponse {
+		switch {
+		case strings.HasPrefix(query, "SELECT id FROM lhp_landlords"):
+			return fakeResponse{columns: []string{"id"}, rows: [][]driver.Value{{int64(1)}}}
+		case strings.HasPrefix(query, "SELECT m.status, m.scheduled_for"):
+			return fakeResponse{
+				columns: []string{"status", "scheduled_for", "category", "encrypt_email", "hash_email"},
+				rows:    [][]driver.Value{{TicketOpen, nil, "heating", tenantEmail, "tenant-hash"}},
+			}
+		}
+		return fakeResponse{rowsAffected: 1}
+	})
+
+	err = UpdateMaintenanceTicket(context.Background(), "landlord@example.com", 4, TicketScheduled, "urgent", "2025-03-01", "Morning visit")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// the email is added in the transaction, so it is only sent if the update commits
+	statements := fake.ran()
+	queued, committed := -1, -1
+	for i, statement := range statements {
+		if strings.HasPrefix(statement.query, "INSERT INTO lhp_email_outbox") {
+			queued = i
+		}
+		if statement.query == "COMMIT" {
+			committed = i
+		}
+	}
+	if queued == -1 || committed < queued {
+		t.Fatalf("Expected the email to be added to the outbox before the update commits, ran %v", statements)
+	}
+
+	insert := statements[queued]
+	if insert.args[0] != OutboxTenantTicketUpdate || insert.args[2] != "tenant-hash" {
+		t.Errorf("Expected a ticket update email about the tenant, got %v", insert.args)
+	}
+	payload, err := utils.Decrypt(insert.args[1].([]byte))
+	if err != nil {
+		t.Fatalf("Failed to decrypt outbox payload: %s", err.Error())
+	}
+	var update TicketUpdateEmail
+	if err := json.Unmarshal(payload, &update); err != nil {
+		t.Fatalf("Failed to decode outbox payload: %s", err.Error())
+	}
+	expected := TicketUpdateEmail{TenantEmail: "tenant@example.com", TicketID: 4, Category: "heating", Status: TicketScheduled, ScheduledFor: "2025-03-01", Note: "Morning visit"}
+	if update != expected {
+		t.Errorf("Expected the email %+v, got %+v", expected, update)
+	}
+}
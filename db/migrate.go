@@ -0,0 +1,361 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+)
+
+// migrationFiles holds the schema migrations, each a pair of files named NNNN_name.up.sql and NNNN_name.down.sql.
+// New migrations take the next version number; a migration that has been released is never edited.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFileName matches a migration file, capturing its version, name and direction
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// the advisory lock held while migrations run, so two instances starting together do not both apply them
+const migrationLockKey = 7412
+
+// create the table that records which migrations have been applied
+const createSchemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+`
+
+// migration is one versioned change to the schema, with the SQL to apply it and to roll it back.
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// loadMigrations reads the migrations in the migrations directory of files, normally migrationFiles, in order of version.
+// Every migration must have exactly one up and one down file.
+func loadMigrations(files fs.FS) ([]migration, error) {
+	names, err := fs.Glob(files, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*migration{}
+	for _, file := range names {
+		match := migrationFileName.FindStringSubmatch(file[len("migrations/"):])
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s is not named NNNN_name.up.sql or NNNN_name.down.sql", file)
+		}
+		version, _ := strconv.Atoi(match[1])
+		contents, err := fs.ReadFile(files, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: match[2]}
+			byVersion[version] = m
+		}
+		if m.name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.name, match[2])
+		}
+		// 0001_name and 1_name are the same version, so only one of them can be loaded
+		script := &m.up
+		if match[3] == "down" {
+			script = &m.down
+		}
+		if *script != "" {
+			return nil, fmt.Errorf("migration %04d_%s has two %s files", version, m.name, match[3])
+		}
+		*script = string(contents)
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// pendingMigrations returns the migrations that have not been applied, in the order to apply them.
+func pendingMigrations(migrations []migration, applied map[int]time.Time) []migration {
+	var pending []migration
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// rollbackMigrations returns the most recently applied migrations to roll back, newest first, up to steps of them.
+// It returns ErrUnknownMigration if one of them was applied by a newer version of the app, which has its down file.
+func rollbackMigrations(migrations []migration, applied map[int]time.Time, steps int) ([]migration, error) {
+	byVersion := map[int]migration{}
+	for _, m := range migrations {
+		byVersion[m.version] = m
+	}
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	var rollback []migration
+	for _, version := range versions {
+		if len(rollback) == steps {
+			break
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			return nil, fmt.Errorf("migration %04d: %w", version, ErrUnknownMigration)
+		}
+		rollback = append(rollback, m)
+	}
+	return rollback, nil
+}
+
+// migrationQuerier is the connection pool or the single locked connection migrations are read through.
+type migrationQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// appliedMigrations reads when each applied migration was applied, by version. It is empty if no migration has ever run.
+func appliedMigrations(ctx context.Context, q migrationQuerier) (map[int]time.Time, error) {
+	applied := map[int]time.Time{}
+
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL;`).Scan(&exists)
+	if err != nil || !exists {
+		return applied, err
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		err := rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// withMigrationLock runs fn on one connection while holding the migration lock.
+func withMigrationLock(fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, migrationLockKey)
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1);`, migrationLockKey)
+
+	_, err = conn.ExecContext(ctx, createSchemaMigrationsTable)
+	if err != nil {
+		return err
+	}
+	return fn(ctx, conn)
+}
+
+// runMigration runs one direction of a migration, and records or removes it in schema_migrations, in one transaction.
+func runMigration(ctx context.Context, conn *sql.Conn, m migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if up {
+		_, err = tx.ExecContext(ctx, m.up)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, NOW());`, m.version, m.name)
+		}
+	} else {
+		_, err = tx.ExecContext(ctx, m.down)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1;`, m.version)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+/*
+MigrateUp applies every migration that has not been applied yet, in order of version.
+
+Each migration is applied in its own transaction together with its row in schema_migrations, so a migration that fails
+leaves no trace and the ones before it stay applied. An advisory lock stops two instances applying migrations at once.
+
+The early migrations only create what does not exist yet, so a database made before migrations were added
+takes them on without being changed.
+
+Returns:
+
+- int: The number of migrations applied.
+
+- error: An error object if a migration cannot be applied.
+*/
+func MigrateUp() (int, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return 0, errors.New("database connection is not initialized")
+	}
+
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to load migrations: %s", err.Error()))
+		return 0, err
+	}
+
+	count := 0
+	err = withMigrationLock(func(ctx context.Context, conn *sql.Conn) error {
+		// read after taking the lock, so migrations applied by another instance meanwhile are not applied again
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range pendingMigrations(migrations, applied) {
+			logs.Logs(logDb, fmt.Sprintf("Applying migration %04d_%s...", m.version, m.name))
+			err := runMigration(ctx, conn, m, true)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", m.version, m.name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to apply migrations: %s", err.Error()))
+		return count, err
+	}
+	logs.Logs(logDb, fmt.Sprintf("Applied %d migrations. The database schema is up to date.", count))
+	return count, nil
+}
+
+/*
+MigrateDown rolls back the most recently applied migrations, newest first. Rolling back a migration that
+dropped data does not bring the data back.
+
+Arguments:
+
+- steps: The number of migrations to roll back.
+
+Returns:
+
+- int: The number of migrations rolled back.
+
+- error: ErrUnknownMigration if a migration to roll back was applied by a newer version of the app,
+or an error object if a migration cannot be rolled back.
+*/
+func MigrateDown(steps int) (int, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return 0, errors.New("database connection is not initialized")
+	}
+
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to load migrations: %s", err.Error()))
+		return 0, err
+	}
+
+	count := 0
+	err = withMigrationLock(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		rollback, err := rollbackMigrations(migrations, applied, steps)
+		if err != nil {
+			return err
+		}
+		for _, m := range rollback {
+			logs.Logs(logDb, fmt.Sprintf("Rolling back migration %04d_%s...", m.version, m.name))
+			err := runMigration(ctx, conn, m, false)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", m.version, m.name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to roll back migrations: %s", err.Error()))
+		return count, err
+	}
+	logs.Logs(logDb, fmt.Sprintf("Rolled back %d migrations.", count))
+	return count, nil
+}
+
+/*
+GetMigrationStatus lists every migration and whether it has been applied, in order of version.
+It does not change the database, so it can be run before schema_migrations exists.
+
+Returns:
+
+- []MigrationState: The migrations known to this version of the app, followed by any applied by a newer version.
+
+- error: An error object if the migrations cannot be read.
+*/
+func GetMigrationStatus() ([]MigrationState, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to load migrations: %s", err.Error()))
+		return nil, err
+	}
+	applied, err := appliedMigrations(context.Background(), db)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get applied migrations: %s", err.Error()))
+		return nil, err
+	}
+
+	var states []MigrationState
+	for _, m := range migrations {
+		appliedAt, ok := applied[m.version]
+		states = append(states, MigrationState{Version: m.version, Name: m.name, Applied: ok, AppliedAt: appliedAt})
+		delete(applied, m.version)
+	}
+	var unknown []MigrationState
+	for version, appliedAt := range applied {
+		unknown = append(unknown, MigrationState{Version: version, Applied: true, AppliedAt: appliedAt})
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Version < unknown[j].Version })
+	return append(states, unknown...), nil
+}
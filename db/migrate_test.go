@@ -0,0 +1,282 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// migrationFixture returns a migrations directory holding a file with each name, whose contents are its name
+func migrationFixture(names ...string) fstest.MapFS {
+	files := fstest.MapFS{}
+	for _, name := range names {
+		files["migrations/"+name] = &fstest.MapFile{Data: []byte(name)}
+	}
+	return files
+}
+
+func TestLoadMigrations(t *testing.T) {
+	// Test cases
+	testCases := []struct {
+		name             string
+		files            fstest.MapFS
+		expectError      bool
+		expectedVersions []int
+	}{
+		{
+			name: "Pairs in order of version",
+			files: migrationFixture(
+				"0010_add_leases.up.sql", "0010_add_leases.down.sql",
+				"0002_add_rooms.up.sql", "0002_add_rooms.down.sql",
+				"0001_create_tables.up.sql", "0001_create_tables.down.sql",
+			),
+			expectedVersions: []int{1, 2, 10},
+		},
+		{
+			name:             "No migrations",
+			files:            fstest.MapFS{},
+			expectedVersions: []int{},
+		},
+		{
+			name:        "Missing down file",
+			files:       migrationFixture("0001_create_tables.up.sql"),
+			expectError: true,
+		},
+		{
+			name:        "Missing up file",
+			files:       migrationFixture("0001_create_tables.down.sql"),
+			expectError: true,
+		},
+		{
+			name:        "Name with capitals",
+			files:       migrationFixture("0001_CreateTables.up.sql", "0001_CreateTables.down.sql"),
+			expectError: true,
+		},
+		{
+			name:        "Name without a version",
+			files:       migrationFixture("create_tables.up.sql", "create_tables.down.sql"),
+			expectError: true,
+		},
+		{
+			name:        "Name without a direction",
+			files:       migrationFixture("0001_create_tables.sql"),
+			expectError: true,
+		},
+		{
+			name: "Duplicate version with two names",
+			files: migrationFixture(
+				"0001_create_tables.up.sql", "0001_create_tables.down.sql",
+				"0001_add_rooms.up.sql", "0001_add_rooms.down.sql",
+			),
+			expectError: true,
+		},
+		{
+			name: "Duplicate version written two ways",
+			files: migrationFixture(
+				"0001_create_tables.up.sql", "0001_create_tables.down.sql",
+				"1_create_tables.up.sql",
+			),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			migrations, err := loadMigrations(tc.files)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(migrations) != len(tc.expectedVersions) {
+				t.Fatalf("Expected %d migrations, got %d", len(tc.expectedVersions), len(migrations))
+			}
+			for i, m := range migrations {
+				if m.version != tc.expectedVersions[i] {
+					t.Errorf("Expected migration %d to be version %d, got %d", i, tc.expectedVersions[i], m.version)
+				}
+				if m.up == "" || m.down == "" || m.up == m.down {
+					t.Errorf("Expected migration %d to have its own up and down files, got %q and %q", m.version, m.up, m.down)
+				}
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatalf("Expected the embedded migrations to load but got: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations, got none")
+	}
+	// released migrations are numbered from 1 without gaps, so a new one takes the next number
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("Expected migration %04d_%s to be version %d", m.version, m.name, i+1)
+		}
+	}
+}
+
+func TestMigrationRoundTrip(t *testing.T) {
+	migrations, err := loadMigrations(migrationFixture(
+		"0001_create_tables.up.sql", "0001_create_tables.down.sql",
+		"0002_add_rooms.up.sql", "0002_add_rooms.down.sql",
+		"0003_add_leases.up.sql", "0003_add_leases.down.sql",
+	))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// applied stands in for schema_migrations, and ran lists the scripts run against the database
+	applied := map[int]time.Time{}
+	var ran []string
+	migrateUp := func() {
+		for _, m := range pendingMigrations(migrations, applied) {
+			ran = append(ran, m.up)
+			applied[m.version] = time.Now()
+		}
+	}
+	migrateDown := func(steps int) {
+		rollback, err := rollbackMigrations(migrations, applied, steps)
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		for _, m := range rollback {
+			ran = append(ran, m.down)
+			delete(applied, m.version)
+		}
+	}
+
+	migrateUp()
+	migrateDown(2)
+	migrateUp()
+	migrateUp() // nothing is pending, so nothing runs again
+	migrateDown(5)
+
+	expected := []string{
+		"0001_create_tables.up.sql", "0002_add_rooms.up.sql", "0003_add_leases.up.sql",
+		"0003_add_leases.down.sql", "0002_add_rooms.down.sql",
+		"0002_add_rooms.up.sql", "0003_add_leases.up.sql",
+		"0003_add_leases.down.sql", "0002_add_rooms.down.sql", "0001_create_tables.down.sql",
+	}
+	if len(ran) != len(expected) {
+		t.Fatalf("Expected scripts %v, ran %v", expected, ran)
+	}
+	for i := range expected {
+		if ran[i] != expected[i] {
+			t.Errorf("Expected script %d to be %s, ran %s", i, expected[i], ran[i])
+		}
+	}
+	if len(applied) != 0 {
+		t.Errorf("Expected no migrations applied after rolling back all of them, got %v", applied)
+	}
+}
+
+func TestRollbackUnknownMigration(t *testing.T) {
+	migrations, err := loadMigrations(migrationFixture("0001_create_tables.up.sql", "0001_create_tables.down.sql"))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// version 2 was applied by a newer version of the app, which has its down file
+	applied := map[int]time.Time{1: time.Now(), 2: time.Now()}
+	_, err = rollbackMigrations(migrations, applied, 1)
+	if !errors.Is(err, ErrUnknownMigration) {
+		t.Errorf("Expected ErrUnknownMigration, got %v", err)
+	}
+
+	if pending := pendingMigrations(migrations, applied); len(pending) != 0 {
+		t.Errorf("Expected no pending migrations, got %d", len(pending))
+	}
+}
+
+func TestMigrateUpDownUp(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	latest := migrations[len(migrations)-1]
+
+	// applied stands in for schema_migrations in the fake database
+	applied := map[int64]time.Time{}
+	fake := useFakeDB(t, func(query string, args []driver.Value) fakeResponse {
+		switch {
+		case strings.HasPrefix(query, "SELECT to_regclass('schema_migrations')"):
+			return fakeResponse{columns: []string{"exists"}, rows: [][]driver.Value{{true}}}
+		case strings.HasPrefix(query, "SELECT version, applied_at FROM schema_migrations"):
+			response := fakeResponse{columns: []string{"version", "applied_at"}}
+			for version, appliedAt := range applied {
+				response.rows = append(response.rows, []driver.Value{version, appliedAt})
+			}
+			return response
+		case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+			applied[args[0].(int64)] = time.Now()
+		case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+			delete(applied, args[0].(int64))
+		}
+		return fakeResponse{}
+	})
+
+	// scripts lists the migration scripts run, by version, with down scripts negative
+	scripts := func() []int {
+		var ran []int
+		for _, statement := range fake.ran() {
+			for _, m := range migrations {
+				switch statement.query {
+				case strings.Join(strings.Fields(m.up), " "):
+					ran = append(ran, m.version)
+				case strings.Join(strings.Fields(m.down), " "):
+					ran = append(ran, -m.version)
+				}
+			}
+		}
+		return ran
+	}
+
+	count, err := MigrateUp()
+	if err != nil || count != len(migrations) {
+		t.Fatalf("Expected %d migrations applied, got %d: %v", len(migrations), count, err)
+	}
+	count, err = MigrateDown(2)
+	if err != nil || count != 2 {
+		t.Fatalf("Expected 2 migrations rolled back, got %d: %v", count, err)
+	}
+	if _, ok := applied[int64(latest.version)]; ok {
+		t.Errorf("Expected migration %d to be rolled back", latest.version)
+	}
+	count, err = MigrateUp()
+	if err != nil || count != 2 {
+		t.Fatalf("Expected 2 migrations applied again, got %d: %v", count, err)
+	}
+	count, err = MigrateUp()
+	if err != nil || count != 0 {
+		t.Fatalf("Expected nothing to apply, got %d: %v", count, err)
+	}
+
+	expected := []int{}
+	for _, m := range migrations {
+		expected = append(expected, m.version)
+	}
+	expected = append(expected, -latest.version, -(latest.version - 1), latest.version-1, latest.version)
+	ran := scripts()
+	if len(ran) != len(expected) {
+		t.Fatalf("Expected scripts %v, ran %v", expected, ran)
+	}
+	for i := range expected {
+		if ran[i] != expected[i] {
+			t.Errorf("Expected script %d to be %d, ran %d", i, expected[i], ran[i])
+		}
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("Expected %d migrations recorded, got %d", len(migrations), len(applied))
+	}
+}
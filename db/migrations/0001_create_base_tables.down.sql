@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS lhp_messages;
+DROP TABLE IF EXISTS lhp_tenant_application;
+DROP TABLE IF EXISTS lhp_tenants;
+DROP TABLE IF EXISTS lhp_landlords;
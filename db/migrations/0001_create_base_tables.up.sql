@@ -0,0 +1,74 @@
+-- the tables the app started with. Databases made before migrations already have them, so they are left as they are
+CREATE TABLE IF NOT EXISTS lhp_landlords (
+	id SERIAL PRIMARY KEY,
+	email VARCHAR(255) NOT NULL UNIQUE,
+	password TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	session_token TEXT,
+	csrf_token TEXT,
+	token_expiry TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS lhp_tenants (
+	id SERIAL PRIMARY KEY,
+	landlord_id INTEGER NOT NULL REFERENCES lhp_landlords(id),
+	hash_email VARCHAR(64) NOT NULL,
+	hash_password TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	encrypt_email BYTEA,
+	encrypt_password BYTEA,
+	encrypt_room_type BYTEA,
+	encrypt_move_in_date BYTEA,
+	encrypt_rent_due BYTEA,
+	encrypt_monthly_rent BYTEA,
+	currency VARCHAR(10),
+	encrypt_tenant_name BYTEA,
+	session_token TEXT,
+	csrf_token TEXT,
+	token_expiry TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS lhp_tenant_application (
+	id SERIAL PRIMARY KEY,
+	landlord_id INTEGER NOT NULL REFERENCES lhp_landlords(id),
+	hash_full_name VARCHAR(64) NOT NULL,
+	hash_dob VARCHAR(64) NOT NULL,
+	hash_passport_number VARCHAR(64) NOT NULL,
+	hash_email VARCHAR(64) NOT NULL,
+	status VARCHAR(10) NOT NULL DEFAULT 'pending',
+	encrypt_full_name BYTEA,
+	encrypt_dob BYTEA,
+	encrypt_passport_number BYTEA,
+	encrypt_phone_number BYTEA,
+	encrypt_email BYTEA,
+	encrypt_occupation BYTEA,
+	encrypt_employer BYTEA,
+	encrypt_employer_number BYTEA,
+	encrypt_emergency_contact BYTEA,
+	encrypt_emergency_number BYTEA,
+	encrypt_emergency_address BYTEA,
+	encrypt_if_evicted BYTEA,
+	encrypt_evicted_reason BYTEA,
+	encrypt_if_convicted BYTEA,
+	encrypt_convicted_reason BYTEA,
+	encrypt_smoke BYTEA,
+	encrypt_pets BYTEA,
+	encrypt_if_vehicle BYTEA,
+	encrypt_vehicle_reg BYTEA,
+	encrypt_have_children BYTEA,
+	encrypt_children BYTEA,
+	encrypt_refused_rent BYTEA,
+	encrypt_refused_rent_reason BYTEA,
+	encrypt_unstable_income BYTEA,
+	encrypt_income_reason BYTEA,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS lhp_messages (
+	sender_id INTEGER NOT NULL,
+	sender_type VARCHAR(10) NOT NULL,
+	receiver_id INTEGER NOT NULL,
+	receiver_type VARCHAR(10) NOT NULL,
+	encrypt_message BYTEA NOT NULL,
+	sent_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
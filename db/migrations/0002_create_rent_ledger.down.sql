@@ -0,0 +1 @@
+DROP TABLE IF EXISTS lhp_rent_ledger;
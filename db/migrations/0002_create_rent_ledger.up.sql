@@ -0,0 +1,13 @@
+-- create the rent ledger table, one row per monthly charge or recorded payment
+CREATE TABLE IF NOT EXISTS lhp_rent_ledger (
+	id SERIAL PRIMARY KEY,
+	tenant_id INTEGER NOT NULL REFERENCES lhp_tenants(id),
+	entry_type VARCHAR(10) NOT NULL,
+	period VARCHAR(7),
+	entry_date DATE NOT NULL,
+	encrypt_amount BYTEA NOT NULL,
+	encrypt_method BYTEA,
+	encrypt_reference BYTEA,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (tenant_id, entry_type, period)
+);
@@ -0,0 +1,16 @@
+-- create the leases table, one lease per tenant covering the term, break clause, notice and end of tenancy
+CREATE TABLE IF NOT EXISTS lhp_leases (
+	id SERIAL PRIMARY KEY,
+	tenant_id INTEGER NOT NULL UNIQUE REFERENCES lhp_tenants(id),
+	status VARCHAR(10) NOT NULL DEFAULT 'active',
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	term_months INTEGER NOT NULL,
+	break_clause_months INTEGER NOT NULL DEFAULT 0,
+	notice_days INTEGER NOT NULL DEFAULT 30,
+	notice_given_by VARCHAR(10),
+	notice_date DATE,
+	move_out_date DATE,
+	ended_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
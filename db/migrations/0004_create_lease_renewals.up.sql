@@ -0,0 +1,10 @@
+-- create the lease renewals table, one row per renewal offered to a tenant
+CREATE TABLE IF NOT EXISTS lhp_lease_renewals (
+	id SERIAL PRIMARY KEY,
+	lease_id INTEGER NOT NULL REFERENCES lhp_leases(id),
+	status VARCHAR(10) NOT NULL DEFAULT 'offered',
+	term_months INTEGER NOT NULL,
+	encrypt_monthly_rent BYTEA NOT NULL,
+	offered_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	responded_at TIMESTAMP
+);
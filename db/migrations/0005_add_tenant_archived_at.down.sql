@@ -0,0 +1 @@
+ALTER TABLE lhp_tenants DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,2 @@
+-- archived tenants keep their records and messages but can no longer log in
+ALTER TABLE lhp_tenants ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
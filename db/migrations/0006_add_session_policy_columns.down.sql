@@ -0,0 +1,6 @@
+ALTER TABLE lhp_landlords
+	DROP COLUMN IF EXISTS session_started_at,
+	DROP COLUMN IF EXISTS session_remember_me;
+ALTER TABLE lhp_tenants
+	DROP COLUMN IF EXISTS session_started_at,
+	DROP COLUMN IF EXISTS session_remember_me;
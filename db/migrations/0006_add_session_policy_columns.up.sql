@@ -0,0 +1,7 @@
+-- track when each session started and whether the user asked to be remembered, for the session policy
+ALTER TABLE lhp_landlords
+	ADD COLUMN IF NOT EXISTS session_started_at TIMESTAMP,
+	ADD COLUMN IF NOT EXISTS session_remember_me BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE lhp_tenants
+	ADD COLUMN IF NOT EXISTS session_started_at TIMESTAMP,
+	ADD COLUMN IF NOT EXISTS session_remember_me BOOLEAN NOT NULL DEFAULT FALSE;
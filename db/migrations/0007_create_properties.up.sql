@@ -0,0 +1,8 @@
+-- create the properties table, each landlord can own several properties that tenants and applications belong to
+CREATE TABLE IF NOT EXISTS lhp_properties (
+	id SERIAL PRIMARY KEY,
+	landlord_id INTEGER NOT NULL REFERENCES lhp_landlords(id),
+	name VARCHAR(100) NOT NULL,
+	address TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+ALTER TABLE lhp_tenants DROP COLUMN IF EXISTS property_id;
+ALTER TABLE lhp_tenant_application DROP COLUMN IF EXISTS property_id;
@@ -0,0 +1,3 @@
+-- tie tenants and applications to the property they are for
+ALTER TABLE lhp_tenants ADD COLUMN IF NOT EXISTS property_id INTEGER REFERENCES lhp_properties(id);
+ALTER TABLE lhp_tenant_application ADD COLUMN IF NOT EXISTS property_id INTEGER REFERENCES lhp_properties(id);
@@ -0,0 +1,14 @@
+-- create the rooms table, the lettable rooms (optionally grouped into units) of each property
+CREATE TABLE IF NOT EXISTS lhp_rooms (
+	id SERIAL PRIMARY KEY,
+	property_id INTEGER NOT NULL REFERENCES lhp_properties(id),
+	unit VARCHAR(100) NOT NULL DEFAULT '',
+	name VARCHAR(100) NOT NULL,
+	room_type VARCHAR(30) NOT NULL,
+	capacity INTEGER NOT NULL DEFAULT 1 CHECK (capacity > 0),
+	amenities TEXT NOT NULL DEFAULT '',
+	default_rent NUMERIC(10, 2) NOT NULL DEFAULT 0,
+	currency VARCHAR(30) NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (property_id, unit, name)
+);
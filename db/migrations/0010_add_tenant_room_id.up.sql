@@ -0,0 +1,2 @@
+-- tie tenants to the room they live in
+ALTER TABLE lhp_tenants ADD COLUMN IF NOT EXISTS room_id INTEGER REFERENCES lhp_rooms(id);
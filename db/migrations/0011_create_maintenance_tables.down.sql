@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS lhp_maintenance_ticket_events;
+DROP TABLE IF EXISTS lhp_maintenance_attachments;
+DROP TABLE IF EXISTS lhp_maintenance_tickets;
@@ -0,0 +1,34 @@
+-- create the maintenance ticket tables: the tickets tenants open, the photos attached to them,
+-- and an audit trail of every status change
+CREATE TABLE IF NOT EXISTS lhp_maintenance_tickets (
+	id SERIAL PRIMARY KEY,
+	tenant_id INTEGER NOT NULL REFERENCES lhp_tenants(id),
+	landlord_id INTEGER NOT NULL REFERENCES lhp_landlords(id),
+	property_id INTEGER REFERENCES lhp_properties(id),
+	room_id INTEGER REFERENCES lhp_rooms(id),
+	category VARCHAR(30) NOT NULL,
+	priority VARCHAR(10) NOT NULL,
+	encrypt_description BYTEA NOT NULL,
+	status VARCHAR(15) NOT NULL DEFAULT 'open',
+	scheduled_for DATE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE TABLE IF NOT EXISTS lhp_maintenance_attachments (
+	id SERIAL PRIMARY KEY,
+	ticket_id INTEGER NOT NULL REFERENCES lhp_maintenance_tickets(id),
+	file_name VARCHAR(255) NOT NULL,
+	content_type VARCHAR(100) NOT NULL,
+	encrypt_data BYTEA NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE TABLE IF NOT EXISTS lhp_maintenance_ticket_events (
+	id SERIAL PRIMARY KEY,
+	ticket_id INTEGER NOT NULL REFERENCES lhp_maintenance_tickets(id),
+	from_status VARCHAR(15),
+	to_status VARCHAR(15) NOT NULL,
+	changed_by_type VARCHAR(10) NOT NULL,
+	changed_by_id INTEGER NOT NULL,
+	encrypt_note BYTEA,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+-- the id added to lhp_messages is kept, as databases made before migrations may have had it already
+DROP TABLE IF EXISTS lhp_reencryption_progress;
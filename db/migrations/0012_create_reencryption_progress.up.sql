@@ -0,0 +1,11 @@
+-- make sure every table with encrypted columns has an id the re-encryption job can walk in order,
+-- and record how far the job has got through each table so it can resume after being stopped
+ALTER TABLE lhp_messages ADD COLUMN IF NOT EXISTS id SERIAL;
+CREATE TABLE IF NOT EXISTS lhp_reencryption_progress (
+	table_name VARCHAR(100) PRIMARY KEY,
+	key_id VARCHAR(255) NOT NULL,
+	last_id INTEGER NOT NULL DEFAULT 0,
+	rows_reencrypted INTEGER NOT NULL DEFAULT 0,
+	completed_at TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+-- the column comes back empty, the reversible passwords it held cannot be restored
+ALTER TABLE lhp_tenants ADD COLUMN IF NOT EXISTS encrypt_password BYTEA;
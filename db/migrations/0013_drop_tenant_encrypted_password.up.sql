@@ -0,0 +1,2 @@
+-- tenant passwords are hashed with bcrypt, so the reversible copy of each password is no longer kept
+ALTER TABLE lhp_tenants DROP COLUMN IF EXISTS encrypt_password;
@@ -0,0 +1,10 @@
+-- create the password resets table, only a hash of each emailed reset token is stored
+CREATE TABLE IF NOT EXISTS lhp_password_resets (
+	id SERIAL PRIMARY KEY,
+	user_type VARCHAR(10) NOT NULL,
+	user_key VARCHAR(255) NOT NULL,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
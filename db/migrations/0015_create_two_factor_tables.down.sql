@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS lhp_two_factor_challenges;
+DROP TABLE IF EXISTS lhp_two_factor_recovery_codes;
+DROP TABLE IF EXISTS lhp_two_factor;
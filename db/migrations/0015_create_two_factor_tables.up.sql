@@ -0,0 +1,33 @@
+-- create the two-factor authentication tables: the encrypted TOTP secret of each user who has turned it on,
+-- their hashed one-time recovery codes, and logins waiting for their second step
+CREATE TABLE IF NOT EXISTS lhp_two_factor (
+	id SERIAL PRIMARY KEY,
+	user_type VARCHAR(10) NOT NULL,
+	user_key VARCHAR(255) NOT NULL,
+	encrypt_secret BYTEA NOT NULL,
+	enabled_at TIMESTAMP,
+	last_used_step BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (user_type, user_key)
+);
+
+CREATE TABLE IF NOT EXISTS lhp_two_factor_recovery_codes (
+	id SERIAL PRIMARY KEY,
+	user_type VARCHAR(10) NOT NULL,
+	user_key VARCHAR(255) NOT NULL,
+	code_hash VARCHAR(64) NOT NULL,
+	used_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS lhp_two_factor_challenges (
+	id SERIAL PRIMARY KEY,
+	user_type VARCHAR(10) NOT NULL,
+	user_key VARCHAR(255) NOT NULL,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	remember_me BOOLEAN NOT NULL DEFAULT FALSE,
+	attempts INT NOT NULL DEFAULT 0,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
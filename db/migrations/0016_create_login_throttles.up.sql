@@ -0,0 +1,12 @@
+-- create the login throttles table, which counts failed logins for each account and each IP address
+CREATE TABLE IF NOT EXISTS lhp_login_throttles (
+	id SERIAL PRIMARY KEY,
+	scope VARCHAR(10) NOT NULL,
+	user_type VARCHAR(10) NOT NULL DEFAULT '',
+	throttle_key VARCHAR(255) NOT NULL,
+	failures INT NOT NULL DEFAULT 0,
+	last_failure_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	locked_until TIMESTAMP,
+	alerted_at TIMESTAMP,
+	UNIQUE (scope, user_type, throttle_key)
+);
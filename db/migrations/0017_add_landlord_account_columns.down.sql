@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS lhp_landlords_one_owner;
+ALTER TABLE lhp_landlords
+	DROP COLUMN IF EXISTS account_role,
+	DROP COLUMN IF EXISTS suspended_at;
@@ -0,0 +1,9 @@
+-- give every landlord account a role and let accounts be suspended. There is only ever one owner, who manages the other accounts,
+-- and a site that already has landlords makes the first of them the owner
+ALTER TABLE lhp_landlords
+	ADD COLUMN IF NOT EXISTS account_role VARCHAR(10) NOT NULL DEFAULT 'landlord',
+	ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP;
+CREATE UNIQUE INDEX IF NOT EXISTS lhp_landlords_one_owner ON lhp_landlords (account_role) WHERE account_role = 'owner';
+UPDATE lhp_landlords SET account_role = 'owner'
+WHERE id = (SELECT MIN(id) FROM lhp_landlords)
+	AND NOT EXISTS (SELECT 1 FROM lhp_landlords WHERE account_role = 'owner');
@@ -0,0 +1,12 @@
+-- create the landlord invitations table, only a hash of each emailed invitation token is stored
+CREATE TABLE IF NOT EXISTS lhp_landlord_invitations (
+	id SERIAL PRIMARY KEY,
+	email VARCHAR(255) NOT NULL,
+	account_role VARCHAR(10) NOT NULL,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	invited_by INTEGER NOT NULL REFERENCES lhp_landlords(id) ON DELETE CASCADE,
+	expires_at TIMESTAMP NOT NULL,
+	accepted_at TIMESTAMP,
+	revoked_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
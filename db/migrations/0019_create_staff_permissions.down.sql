@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS lhp_staff_permissions;
+ALTER TABLE lhp_landlords DROP COLUMN IF EXISTS works_for;
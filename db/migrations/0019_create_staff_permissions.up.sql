@@ -0,0 +1,8 @@
+-- staff accounts work under a landlord, and each staff member is only allowed what the landlord has granted them
+ALTER TABLE lhp_landlords ADD COLUMN IF NOT EXISTS works_for INTEGER REFERENCES lhp_landlords(id);
+CREATE TABLE IF NOT EXISTS lhp_staff_permissions (
+	staff_id INTEGER NOT NULL REFERENCES lhp_landlords(id) ON DELETE CASCADE,
+	permission VARCHAR(30) NOT NULL,
+	granted_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (staff_id, permission)
+);
@@ -0,0 +1,29 @@
+-- the audit log is append-only: each entry stores the hash of the one before it, so editing or removing an entry
+-- breaks the chain, and the trigger stops entries being changed or deleted through the database
+CREATE TABLE IF NOT EXISTS lhp_audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	actor_type VARCHAR(10) NOT NULL,
+	actor VARCHAR(255) NOT NULL,
+	landlord_email VARCHAR(255) NOT NULL,
+	action VARCHAR(50) NOT NULL,
+	target_type VARCHAR(30) NOT NULL,
+	target_id VARCHAR(255) NOT NULL,
+	details TEXT NOT NULL,
+	ip_address VARCHAR(45) NOT NULL,
+	user_agent TEXT NOT NULL,
+	prev_hash VARCHAR(64) NOT NULL,
+	hash VARCHAR(64) NOT NULL UNIQUE
+);
+CREATE INDEX IF NOT EXISTS lhp_audit_log_landlord_idx ON lhp_audit_log (landlord_email, created_at);
+CREATE OR REPLACE FUNCTION lhp_audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'lhp_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS lhp_audit_log_no_change ON lhp_audit_log;
+CREATE TRIGGER lhp_audit_log_no_change BEFORE UPDATE OR DELETE ON lhp_audit_log
+	FOR EACH ROW EXECUTE PROCEDURE lhp_audit_log_append_only();
+DROP TRIGGER IF EXISTS lhp_audit_log_no_truncate ON lhp_audit_log;
+CREATE TRIGGER lhp_audit_log_no_truncate BEFORE TRUNCATE ON lhp_audit_log
+	FOR EACH STATEMENT EXECUTE PROCEDURE lhp_audit_log_append_only();
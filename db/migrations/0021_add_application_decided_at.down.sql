@@ -0,0 +1 @@
+ALTER TABLE lhp_tenant_application DROP COLUMN IF EXISTS decided_at;
@@ -0,0 +1,3 @@
+-- record when each application was accepted or denied, so denied applications can be purged once their retention period is over.
+-- Applications decided before the column was added are treated as decided when they were made
+ALTER TABLE lhp_tenant_application ADD COLUMN IF NOT EXISTS decided_at TIMESTAMP;
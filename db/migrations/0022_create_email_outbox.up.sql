@@ -0,0 +1,12 @@
+-- emails added in the same transaction as the change they are about, and sent once it has committed.
+-- The payload is encrypted as it can hold a new tenant's password, and rows are deleted once the email is sent or given up on
+CREATE TABLE IF NOT EXISTS lhp_email_outbox (
+	id BIGSERIAL PRIMARY KEY,
+	kind VARCHAR(50) NOT NULL,
+	encrypt_payload BYTEA NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP NOT NULL,
+	last_error TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS lhp_email_outbox_next_attempt_idx ON lhp_email_outbox (next_attempt_at);
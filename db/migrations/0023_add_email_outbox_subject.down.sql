@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS lhp_email_outbox_hash_subject_idx;
+ALTER TABLE lhp_email_outbox DROP COLUMN IF EXISTS hash_subject;
@@ -0,0 +1,4 @@
+-- record which tenant or applicant each outbox email is about, by their hashed email, so subject access exports
+-- and erasures include emails that have not been sent yet. Emails added before the column was added match no one
+ALTER TABLE lhp_email_outbox ADD COLUMN IF NOT EXISTS hash_subject VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS lhp_email_outbox_hash_subject_idx ON lhp_email_outbox (hash_subject);
@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// outboxClaimPeriod is how long a claimed email is hidden from other dispatchers while it is being sent.
+// If the app stops before the email is completed or retried, it is sent again once this has passed.
+const outboxClaimPeriod = 5 * time.Minute
+
+/*
+enqueueEmail adds an email to the outbox in the transaction making the change it is about,
+so the email is only sent if the change commits, and is sent even if the app stops straight after.
+
+Arguments:
+
+- tx: The transaction making the change.
+
+- kind: One of the Outbox constants, saying which email to send.
+
+- hashSubject: The hashed email of the tenant or applicant the email is about, so subject access exports and erasures find it.
+
+- payload: The details of the email, stored as encrypted JSON.
+
+Returns:
+
+- error: An error object if the email cannot be added.
+*/
+func enqueueEmail(ctx context.Context, tx *sql.Tx, kind, hashSubject string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to encode %s email: %s", kind, err.Error()))
+		return err
+	}
+
+	encryptPayload, err := utils.Encrypt(data)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt %s email: %s", kind, err.Error()))
+		return err
+	}
+
+	query := `
+	INSERT INTO lhp_email_outbox (kind, encrypt_payload, hash_subject, created_at, next_attempt_at)
+	VALUES ($1, $2, $3, NOW(), NOW());
+	`
+	_, err = tx.ExecContext(ctx, query, kind, encryptPayload, hashSubject)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to add %s email to the outbox: %s", kind, err.Error()))
+		return err
+	}
+	return nil
+}
+
+/*
+ClaimOutboxEmails takes the emails that are due to be sent, oldest first. Claimed emails are not due again
+for a few minutes, so two copies of the app sending the outbox at the same time do not send an email twice.
+Each claimed email must be completed, retried or abandoned once sending it has been tried.
+An email whose payload cannot be decrypted can never be sent, so it is abandoned rather than holding up the rest.
+
+Arguments:
+
+- limit: The most emails to claim.
+
+Returns:
+
+- []OutboxEmail: The claimed emails, with their payloads decrypted.
+
+- error: An error object if the emails cannot be claimed.
+*/
+func ClaimOutboxEmails(ctx context.Context, limit int) ([]OutboxEmail, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	UPDATE lhp_email_outbox
+	SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+	WHERE id IN (
+		SELECT id
+		FROM lhp_email_outbox
+		WHERE next_attempt_at <= NOW()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, kind, encrypt_payload, attempts;
+	`
+	rows, err := db.QueryContext(ctx, query, limit, int(outboxClaimPeriod.Seconds()))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to claim outbox emails: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []OutboxEmail
+	var undecryptable []int64
+	for rows.Next() {
+		var email OutboxEmail
+		var encryptPayload []byte
+		err = rows.Scan(&email.ID, &email.Kind, &encryptPayload, &email.Attempts)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan outbox email: %s", err.Error()))
+			return nil, err
+		}
+		email.Payload, err = utils.Decrypt(encryptPayload)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to decrypt outbox email %d: %s", email.ID, err.Error()))
+			undecryptable = append(undecryptable, email.ID)
+			continue
+		}
+		emails = append(emails, email)
+	}
+	if err = rows.Err(); err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to claim outbox emails: %s", err.Error()))
+		return nil, err
+	}
+	rows.Close()
+
+	// if it cannot be abandoned now, it is claimed and tried again once its claim runs out
+	for _, id := range undecryptable {
+		AbandonOutboxEmail(ctx, id, "payload cannot be decrypted")
+	}
+	return emails, nil
+}
+
+/*
+CompleteOutboxEmail removes an email from the outbox once it has been sent.
+
+Arguments:
+
+- id: The ID of the outbox email.
+
+Returns:
+
+- error: An error object if the email cannot be removed.
+*/
+func CompleteOutboxEmail(ctx context.Context, id int64) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, `DELETE FROM lhp_email_outbox WHERE id = $1;`, id)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to complete outbox email %d: %s", id, err.Error()))
+		return err
+	}
+	return nil
+}
+
+/*
+RetryOutboxEmail records that sending an email failed and when to try again.
+
+Arguments:
+
+- id: The ID of the outbox email.
+
+- sendErr: Why sending the email failed.
+
+- retryAt: When to try sending the email again.
+
+Returns:
+
+- error: An error object if the failure cannot be recorded.
+*/
+func RetryOutboxEmail(ctx context.Context, id int64, sendErr string, retryAt time.Time) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	UPDATE lhp_email_outbox
+	SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+	WHERE id = $1;
+	`
+	_, err := db.ExecContext(ctx, query, id, sendErr, retryAt)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to retry outbox email %d: %s", id, err.Error()))
+		return err
+	}
+	return nil
+}
+
+/*
+AbandonOutboxEmail removes an email that has failed to send too many times. The failure is logged,
+as the payload can hold a password and is not kept.
+
+Arguments:
+
+- id: The ID of the outbox email.
+
+- sendErr: Why sending the email last failed.
+
+Returns:
+
+- error: An error object if the email cannot be removed.
+*/
+func AbandonOutboxEmail(ctx context.Context, id int64, sendErr string) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var kind string
+	err := db.QueryRowContext(ctx, `DELETE FROM lhp_email_outbox WHERE id = $1 RETURNING kind;`, id).Scan(&kind)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to abandon outbox email %d: %s", id, err.Error()))
+		return err
+	}
+	logs.Logs(logDbErr, fmt.Sprintf("Gave up sending %s email %d: %s", kind, id, sendErr))
+	return nil
+}
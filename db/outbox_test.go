@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+func TestClaimOutboxEmailsUndecryptable(t *testing.T) {
+	initTestEncryption()
+	payload, err := utils.Encrypt([]byte(`{"tenant_username":"tenant@example.com"}`))
+	if err != nil {
+		t.Fatalf("Failed to encrypt test data: %s", err.Error())
+	}
+
+	// the second of three claimed emails was encrypted with a key that is no longer loaded
+	fake := useFakeDB(t, func(query string, args []driver.Value) fakeResponse {
+		switch {
+		case strings.HasPrefix(query, "UPDATE lhp_email_outbox"):
+			return fakeResponse{
+				columns: []string{"id", "kind", "encrypt_payload", "attempts"},
+				rows: [][]driver.Value{
+					{int64(1), OutboxTenantNewAccount, payload, int64(0)},
+					{int64(2), OutboxTenantNewAccount, []byte("not encrypted"), int64(0)},
+					{int64(3), OutboxLandlordNewAccount, payload, int64(2)},
+				},
+			}
+		case strings.HasPrefix(query, "DELETE FROM lhp_email_outbox"):
+			return fakeResponse{columns: []string{"kind"}, rows: [][]driver.Value{{OutboxTenantNewAccount}}}
+		}
+		return fakeResponse{}
+	})
+
+	emails, err := ClaimOutboxEmails(context.Background(), 20)
+	if err != nil {
+		t.Fatalf("Expected the rest of the batch to be claimed, got: %v", err)
+	}
+	if len(emails) != 2 || emails[0].ID != 1 || emails[1].ID != 3 || emails[1].Attempts != 2 {
+		t.Fatalf("Expected emails 1 and 3 to be claimed, got %v", emails)
+	}
+	if string(emails[0].Payload) != `{"tenant_username":"tenant@example.com"}` {
+		t.Errorf("Expected the payload to be decrypted, got %s", emails[0].Payload)
+	}
+
+	// the email that can never be sent is abandoned rather than claimed again forever
+	deletes := writesTo(fake.ran(), "lhp_email_outbox")
+	var abandoned []driver.Value
+	for _, statement := range deletes {
+		if strings.HasPrefix(statement.query, "DELETE FROM ") {
+			abandoned = append(abandoned, statement.args[0])
+		}
+	}
+	if len(abandoned) != 1 || abandoned[0] != int64(2) {
+		t.Errorf("Expected email 2 to be abandoned, got %v", abandoned)
+	}
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+)
+
+// ownershipQueries check that a record belongs to a landlord, by the record's ID ($1) and the landlord's email ($2)
+var ownershipQueries = map[string]string{
+	ResourceTenant: `
+	SELECT EXISTS (
+		SELECT 1
+		FROM lhp_tenants t
+		JOIN lhp_landlords l ON l.id = t.landlord_id
+		WHERE t.id = $1 AND l.email = $2
+	);
+	`,
+	ResourceApplication: `
+	SELECT EXISTS (
+		SELECT 1
+		FROM lhp_tenant_application a
+		JOIN lhp_landlords l ON l.id = a.landlord_id
+		WHERE a.id = $1 AND l.email = $2
+	);
+	`,
+	ResourceProperty: `
+	SELECT EXISTS (
+		SELECT 1
+		FROM lhp_properties p
+		JOIN lhp_landlords l ON l.id = p.landlord_id
+		WHERE p.id = $1 AND l.email = $2
+	);
+	`,
+	ResourceMaintenanceTicket: `
+	SELECT EXISTS (
+		SELECT 1
+		FROM lhp_maintenance_tickets mt
+		JOIN lhp_landlords l ON l.id = mt.landlord_id
+		WHERE mt.id = $1 AND l.email = $2
+	);
+	`,
+	ResourceMaintenancePhoto: `
+	SELECT EXISTS (
+		SELECT 1
+		FROM lhp_maintenance_attachments ma
+		JOIN lhp_maintenance_tickets mt ON mt.id = ma.ticket_id
+		JOIN lhp_landlords l ON l.id = mt.landlord_id
+		WHERE ma.id = $1 AND l.email = $2
+	);
+	`,
+}
+
+/*
+LandlordOwnsResource checks whether a record belongs to a landlord. Every landlord route that takes
+the ID of a record from the request is checked with this before its handler runs, see middleware.RequireOwnership.
+
+Arguments:
+
+- landlordEmail: The email of the landlord whose records the user works with.
+
+- resource: One of the Resource constants, e.g. ResourceTenant.
+
+- id: The ID of the record.
+
+Returns:
+
+- bool: True if the record belongs to the landlord, false if it belongs to another landlord or does not exist.
+
+- error: ErrUnknownResource if the resource type cannot be checked, or an error object if the check fails.
+*/
+func LandlordOwnsResource(ctx context.Context, landlordEmail, resource string, id int) (bool, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return false, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query, ok := ownershipQueries[resource]
+	if !ok {
+		logs.Logs(logDbErr, fmt.Sprintf("Cannot check ownership of unknown resource: %s", resource))
+		return false, ErrUnknownResource
+	}
+
+	var owned bool
+	err := db.QueryRowContext(ctx, query, id, landlordEmail).Scan(&owned)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to check %s ownership: %s", resource, err.Error()))
+		return false, err
+	}
+	return owned, nil
+}
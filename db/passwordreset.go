@@ -0,0 +1,237 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// how long an emailed password reset link can be used for
+const passwordResetLifetime = time.Hour
+
+/*
+CreatePasswordReset creates a single-use password reset token for a landlord or tenant.
+Any reset links the user was sent before stop working, and only a hash of the new token is stored.
+
+Arguments:
+
+- userType: Either "landlord" or "tenant".
+
+- userKey: The landlord's email, or the tenant's hashed email.
+
+Returns:
+
+- string: The reset token to email to the user.
+
+- error: sql.ErrNoRows if there is no such user, or an error object if the token cannot be stored.
+*/
+func CreatePasswordReset(ctx context.Context, userType, userKey string) (string, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return "", errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var query string
+	switch userType {
+	case "landlord":
+		query = `SELECT COUNT(*) FROM lhp_landlords WHERE email = $1 AND suspended_at IS NULL;`
+	case "tenant":
+		query = `SELECT COUNT(*) FROM lhp_tenants WHERE hash_email = $1 AND archived_at IS NULL;`
+	default:
+		return "", fmt.Errorf("invalid user type: %s", userType)
+	}
+
+	var count int
+	err := db.QueryRowContext(ctx, query, userKey).Scan(&count)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to check user for password reset: %s", err.Error()))
+		return "", err
+	}
+	if count == 0 {
+		return "", sql.ErrNoRows
+	}
+
+	token, err := utils.GenerateToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// only the most recent reset link works
+	query = `
+	UPDATE lhp_password_resets
+	SET used_at = NOW()
+	WHERE user_type = $1 AND user_key = $2 AND used_at IS NULL;
+	`
+	_, err = tx.ExecContext(ctx, query, userType, userKey)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to expire old password resets: %s", err.Error()))
+		return "", err
+	}
+
+	query = `
+	INSERT INTO lhp_password_resets (user_type, user_key, token_hash, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, NOW());
+	`
+	_, err = tx.ExecContext(ctx, query, userType, userKey, utils.HashData(token), time.Now().Add(passwordResetLifetime))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to create password reset: %s", err.Error()))
+		return "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit password reset: %s", err.Error()))
+		return "", err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Password reset created for %s", userType))
+	return token, nil
+}
+
+/*
+ValidatePasswordResetToken checks a password reset token can still be used, without using it up.
+
+Arguments:
+
+- userType: Either "landlord" or "tenant".
+
+- token: The reset token from the emailed link.
+
+Returns:
+
+- error: ErrInvalidResetToken if the token is unknown, already used or expired.
+*/
+func ValidatePasswordResetToken(ctx context.Context, userType, token string) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var id int
+	query := `
+	SELECT id
+	FROM lhp_password_resets
+	WHERE user_type = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > NOW();
+	`
+	err := db.QueryRowContext(ctx, query, userType, utils.HashData(token)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to check password reset: %s", err.Error()))
+		return err
+	}
+	return nil
+}
+
+/*
+ResetPassword sets a new password using a password reset token, then logs the user out everywhere.
+The token is used up in the same transaction as the password change, so each link works only once.
+
+Arguments:
+
+- userType: Either "landlord" or "tenant".
+
+- token: The reset token from the emailed link.
+
+- newPassword: The new password, which is hashed with bcrypt before it is stored.
+
+Returns:
+
+- error: ErrInvalidResetToken if the token is unknown, already used or expired, or an error object if the password cannot be changed.
+*/
+func ResetPassword(ctx context.Context, userType, token, newPassword string) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	hashPassword, err := utils.HashedPassword(newPassword)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to hash password: %s", err.Error()))
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return err
+	}
+	defer tx.Rollback()
+
+	var resetId int
+	var userKey string
+	query := `
+	SELECT id, user_key
+	FROM lhp_password_resets
+	WHERE user_type = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > NOW()
+	FOR UPDATE;
+	`
+	err = tx.QueryRowContext(ctx, query, userType, utils.HashData(token)).Scan(&resetId, &userKey)
+	if err == sql.ErrNoRows {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get password reset: %s", err.Error()))
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE lhp_password_resets SET used_at = NOW() WHERE id = $1;`, resetId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to use password reset: %s", err.Error()))
+		return err
+	}
+
+	// set the new password and end every existing session
+	switch userType {
+	case "landlord":
+		query = `
+		UPDATE lhp_landlords
+		SET password = $1, session_token = NULL, csrf_token = NULL, token_expiry = NULL
+		WHERE email = $2;
+		`
+	case "tenant":
+		query = `
+		UPDATE lhp_tenants
+		SET hash_password = $1, session_token = NULL, csrf_token = NULL, token_expiry = NULL
+		WHERE hash_email = $2;
+		`
+	default:
+		return fmt.Errorf("invalid user type: %s", userType)
+	}
+	_, err = tx.ExecContext(ctx, query, hashPassword, userKey)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to reset password: %s", err.Error()))
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit password reset: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Password reset for %s", userType))
+	return nil
+}
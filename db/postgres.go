@@ -0,0 +1,515 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+)
+
+// Postgres is the Store backed by the PostgreSQL database opened by ConnectDB.
+// Each method runs the package function of the same name.
+type Postgres struct{}
+
+// the compiler checks that Postgres has every method of Store
+var _ Store = Postgres{}
+
+// LandlordStore
+
+func (Postgres) CreateNewLandlord(ctx context.Context, landlordEmail, landlordPassword string) error {
+	return CreateNewLandlord(ctx, landlordEmail, landlordPassword)
+}
+
+func (Postgres) AuthenticateLandlord(ctx context.Context, email, password string) (bool, error) {
+	return AuthenticateLandlord(ctx, email, password)
+}
+
+func (Postgres) GetLandlordIdByEmail(ctx context.Context, email string) (int, error) {
+	return GetLandlordIdByEmail(ctx, email)
+}
+
+func (Postgres) CountLandlords(ctx context.Context) (int, error) {
+	return CountLandlords(ctx)
+}
+
+func (Postgres) GetLandlordAccess(ctx context.Context, email string) (LandlordAccess, error) {
+	return GetLandlordAccess(ctx, email)
+}
+
+func (Postgres) GetLandlordAccounts(ctx context.Context) ([]LandlordAccount, error) {
+	return GetLandlordAccounts(ctx)
+}
+
+func (Postgres) SetLandlordSuspended(ctx context.Context, landlordId int, suspended bool) error {
+	return SetLandlordSuspended(ctx, landlordId, suspended)
+}
+
+func (Postgres) RemoveLandlordAccount(ctx context.Context, landlordId int) error {
+	return RemoveLandlordAccount(ctx, landlordId)
+}
+
+func (Postgres) LandlordOwnsResource(ctx context.Context, landlordEmail, resource string, id int) (bool, error) {
+	return LandlordOwnsResource(ctx, landlordEmail, resource, id)
+}
+
+// InvitationStore
+
+func (Postgres) CreateLandlordInvitation(ctx context.Context, invitedBy, email, accountRole string) (string, time.Time, error) {
+	return CreateLandlordInvitation(ctx, invitedBy, email, accountRole)
+}
+
+func (Postgres) GetLandlordInvitation(ctx context.Context, token string) (LandlordInvitation, error) {
+	return GetLandlordInvitation(ctx, token)
+}
+
+func (Postgres) AcceptLandlordInvitation(ctx context.Context, token, password string) (string, error) {
+	return AcceptLandlordInvitation(ctx, token, password)
+}
+
+func (Postgres) GetPendingLandlordInvitations(ctx context.Context) ([]LandlordInvitation, error) {
+	return GetPendingLandlordInvitations(ctx)
+}
+
+func (Postgres) RevokeLandlordInvitation(ctx context.Context, invitationId int) error {
+	return RevokeLandlordInvitation(ctx, invitationId)
+}
+
+// StaffStore
+
+func (Postgres) GetStaffAccounts(ctx context.Context, landlordEmail string) ([]StaffAccount, error) {
+	return GetStaffAccounts(ctx, landlordEmail)
+}
+
+func (Postgres) SetStaffPermissions(ctx context.Context, landlordEmail string, staffId int, permissions []string) error {
+	return SetStaffPermissions(ctx, landlordEmail, staffId, permissions)
+}
+
+func (Postgres) RemoveStaffAccount(ctx context.Context, landlordEmail string, staffId int) error {
+	return RemoveStaffAccount(ctx, landlordEmail, staffId)
+}
+
+func (Postgres) GetPendingStaffInvitations(ctx context.Context, landlordEmail string) ([]LandlordInvitation, error) {
+	return GetPendingStaffInvitations(ctx, landlordEmail)
+}
+
+func (Postgres) RevokeStaffInvitation(ctx context.Context, landlordEmail string, invitationId int) error {
+	return RevokeStaffInvitation(ctx, landlordEmail, invitationId)
+}
+
+// TenantStore
+
+func (Postgres) CreateNewTenant(ctx context.Context, landlordEmail string, roomId int, tenantEmail, tenantPassword, moveInDate, rentDue, monthlyRent, currency string) error {
+	return CreateNewTenant(ctx, landlordEmail, roomId, tenantEmail, tenantPassword, moveInDate, rentDue, monthlyRent, currency)
+}
+
+func (Postgres) ManuallyCreateNewTenant(ctx context.Context, landlordEmail string, roomId int, tenantFullName, tenantPassportID, tenantEmail, moveInDate, rentDue, monthlyRent, currency string) error {
+	return ManuallyCreateNewTenant(ctx, landlordEmail, roomId, tenantFullName, tenantPassportID, tenantEmail, moveInDate, rentDue, monthlyRent, currency)
+}
+
+func (Postgres) AuthenticateTenant(ctx context.Context, username, password string) (bool, error) {
+	return AuthenticateTenant(ctx, username, password)
+}
+
+func (Postgres) UpdateTenantPassword(ctx context.Context, hashEmail, newPassword string) error {
+	return UpdateTenantPassword(ctx, hashEmail, newPassword)
+}
+
+func (Postgres) GetTenantNameByEmail(ctx context.Context, email string) (string, error) {
+	return GetTenantNameByEmail(ctx, email)
+}
+
+func (Postgres) GetTenantNameByHashEmail(ctx context.Context, email string) (string, error) {
+	return GetTenantNameByHashEmail(ctx, email)
+}
+
+func (Postgres) GetTenantIdByEmail(ctx context.Context, email string) (int, error) {
+	return GetTenantIdByEmail(ctx, email)
+}
+
+func (Postgres) GetTenantEncryptedEmailById(ctx context.Context, tenantId int) (string, error) {
+	return GetTenantEncryptedEmailById(ctx, tenantId)
+}
+
+func (Postgres) GetTenantInformationByHashEmail(ctx context.Context, hashEmail string) (GetTenantInformation, error) {
+	return GetTenantInformationByHashEmail(ctx, hashEmail)
+}
+
+func (Postgres) GetTenantsByLandlordEmail(ctx context.Context, landlordEmail string) ([]LandlordTenants, error) {
+	return GetTenantsByLandlordEmail(ctx, landlordEmail)
+}
+
+func (Postgres) GetLandlordByTenantHashEmail(ctx context.Context, hashEmail string) (int, string, error) {
+	return GetLandlordByTenantHashEmail(ctx, hashEmail)
+}
+
+// SessionStore
+
+func (Postgres) StartLandlordSession(ctx context.Context, email string, rememberMe bool) (string, string, time.Time, error) {
+	return StartLandlordSession(ctx, email, rememberMe)
+}
+
+func (Postgres) StartTenantSession(ctx context.Context, hashEmail string, rememberMe bool) (string, string, time.Time, error) {
+	return StartTenantSession(ctx, hashEmail, rememberMe)
+}
+
+func (Postgres) UpdateLandlordSessionTokens(ctx context.Context, email string) (string, string, time.Time, error) {
+	return UpdateLandlordSessionTokens(ctx, email)
+}
+
+func (Postgres) UpdateTenantSessionTokens(ctx context.Context, hash_email string) (string, string, time.Time, error) {
+	return UpdateTenantSessionTokens(ctx, hash_email)
+}
+
+func (Postgres) GetEmailFromLandlordSessionToken(ctx context.Context, sessionToken string) (string, error) {
+	return GetEmailFromLandlordSessionToken(ctx, sessionToken)
+}
+
+func (Postgres) GetEmailFromTenantSessionToken(ctx context.Context, sessionToken string) (string, error) {
+	return GetEmailFromTenantSessionToken(ctx, sessionToken)
+}
+
+func (Postgres) GetHashedEmailFromTenantSessionToken(ctx context.Context, sessionToken string) (string, error) {
+	return GetHashedEmailFromTenantSessionToken(ctx, sessionToken)
+}
+
+func (Postgres) GetCSRFTokenFromSessionToken(ctx context.Context, sessionToken string) (string, error) {
+	return GetCSRFTokenFromSessionToken(ctx, sessionToken)
+}
+
+func (Postgres) ValidateLandlordSessionToken(ctx context.Context, email, sessionToken string) (bool, error) {
+	return ValidateLandlordSessionToken(ctx, email, sessionToken)
+}
+
+func (Postgres) ValidateTenantSessionToken(ctx context.Context, hashEmail, sessionToken string) (bool, error) {
+	return ValidateTenantSessionToken(ctx, hashEmail, sessionToken)
+}
+
+func (Postgres) ValidateLandlordCSRFToken(ctx context.Context, email, csrfToken string) (bool, error) {
+	return ValidateLandlordCSRFToken(ctx, email, csrfToken)
+}
+
+func (Postgres) ValidateTenantCSRFToken(ctx context.Context, hashEmail, csrfToken string) (bool, error) {
+	return ValidateTenantCSRFToken(ctx, hashEmail, csrfToken)
+}
+
+func (Postgres) LogoutLandlord(ctx context.Context, email string) error {
+	return LogoutLandlord(ctx, email)
+}
+
+func (Postgres) LogoutTenant(ctx context.Context, hashEmail string) error {
+	return LogoutTenant(ctx, hashEmail)
+}
+
+// ApplicationStore
+
+func (Postgres) SaveTenantApplicationForm(
+	ctx context.Context,
+	propertyId int,
+	fullName,
+	dateOfBirth,
+	passportNumber,
+	phoneNumber,
+	email,
+	occupation,
+	employer,
+	employerNumber,
+	emergencyContactName,
+	emergencyContactNumber,
+	emergencyContactAddress,
+	ifEvicted,
+	evictedReason,
+	ifConvicted,
+	convictedReason,
+	smoke,
+	pets,
+	ifVehicle,
+	vehicleReg,
+	haveChildren,
+	children,
+	refusedRent,
+	refusedRentReason,
+	unstableIncome,
+	incomeReason string,
+) error {
+	return SaveTenantApplicationForm(ctx, propertyId, fullName, dateOfBirth, passportNumber, phoneNumber, email, occupation, employer, employerNumber, emergencyContactName, emergencyContactNumber, emergencyContactAddress, ifEvicted, evictedReason, ifConvicted, convictedReason, smoke, pets, ifVehicle, vehicleReg, haveChildren, children, refusedRent, refusedRentReason, unstableIncome, incomeReason)
+}
+
+func (Postgres) GetAllTenantApplications(ctx context.Context, landlordEmail string) ([]GetLandlordApplications, error) {
+	return GetAllTenantApplications(ctx, landlordEmail)
+}
+
+func (Postgres) UpdateTenantApplicationStatus(ctx context.Context, id string, status string) error {
+	return UpdateTenantApplicationStatus(ctx, id, status)
+}
+
+func (Postgres) GetTenantEmailAndPassportNumberViaApplicationID(ctx context.Context, id string) (string, string, error) {
+	return GetTenantEmailAndPassportNumberViaApplicationID(ctx, id)
+}
+
+func (Postgres) AcceptTenantApplication(ctx context.Context, landlordEmail string, acceptance TenantAcceptance) (bool, error) {
+	return AcceptTenantApplication(ctx, landlordEmail, acceptance)
+}
+
+// MessageStore
+
+func (Postgres) SendMessage(ctx context.Context, senderId int, senderType string, receiverID int, receiverType string, message string) error {
+	return SendMessage(ctx, senderId, senderType, receiverID, receiverType, message)
+}
+
+func (Postgres) GetMessageBetweenLandlordsAndTenant(ctx context.Context, landlordId int, tenantID string) ([]Message, error) {
+	return GetMessageBetweenLandlordsAndTenant(ctx, landlordId, tenantID)
+}
+
+// PropertyStore
+
+func (Postgres) CreateProperty(ctx context.Context, landlordEmail, name, address string) error {
+	return CreateProperty(ctx, landlordEmail, name, address)
+}
+
+func (Postgres) GetAllProperties(ctx context.Context) ([]Property, error) {
+	return GetAllProperties(ctx)
+}
+
+func (Postgres) GetPropertiesByLandlordEmail(ctx context.Context, landlordEmail string) ([]Property, error) {
+	return GetPropertiesByLandlordEmail(ctx, landlordEmail)
+}
+
+func (Postgres) GetPropertyById(ctx context.Context, propertyId int) (Property, error) {
+	return GetPropertyById(ctx, propertyId)
+}
+
+func (Postgres) GetPropertyLandlordEmail(ctx context.Context, propertyId int) (string, error) {
+	return GetPropertyLandlordEmail(ctx, propertyId)
+}
+
+func (Postgres) PropertyBelongsToLandlord(ctx context.Context, propertyId int, landlordEmail string) (bool, error) {
+	return PropertyBelongsToLandlord(ctx, propertyId, landlordEmail)
+}
+
+func (Postgres) CreateRoom(ctx context.Context, landlordEmail string, propertyId int, unit, name, roomType string, capacity int, amenities, defaultRent, currency string) error {
+	return CreateRoom(ctx, landlordEmail, propertyId, unit, name, roomType, capacity, amenities, defaultRent, currency)
+}
+
+func (Postgres) GetRoomsByLandlordEmail(ctx context.Context, landlordEmail string) ([]Room, error) {
+	return GetRoomsByLandlordEmail(ctx, landlordEmail)
+}
+
+func (Postgres) GetLandlordRoom(ctx context.Context, landlordEmail string, roomId int) (Room, error) {
+	return GetLandlordRoom(ctx, landlordEmail, roomId)
+}
+
+// LeaseStore
+
+func (Postgres) CreateLease(ctx context.Context, tenantId int, startDate string, termMonths, breakClauseMonths, noticeDays int) error {
+	return CreateLease(ctx, tenantId, startDate, termMonths, breakClauseMonths, noticeDays)
+}
+
+func (Postgres) GetLeaseByTenantId(ctx context.Context, tenantId int) (Lease, error) {
+	return GetLeaseByTenantId(ctx, tenantId)
+}
+
+func (Postgres) GetLeasesByLandlordEmail(ctx context.Context, landlordEmail string) ([]LandlordLease, error) {
+	return GetLeasesByLandlordEmail(ctx, landlordEmail)
+}
+
+func (Postgres) OfferLeaseRenewal(ctx context.Context, leaseId, termMonths int, monthlyRent string) error {
+	return OfferLeaseRenewal(ctx, leaseId, termMonths, monthlyRent)
+}
+
+func (Postgres) GetOpenRenewalOffer(ctx context.Context, leaseId int) (LeaseRenewal, error) {
+	return GetOpenRenewalOffer(ctx, leaseId)
+}
+
+func (Postgres) RespondToRenewalOffer(ctx context.Context, leaseId int, accept bool) error {
+	return RespondToRenewalOffer(ctx, leaseId, accept)
+}
+
+func (Postgres) ServeLeaseNotice(ctx context.Context, leaseId int, givenBy, noticeDate, moveOutDate string) error {
+	return ServeLeaseNotice(ctx, leaseId, givenBy, noticeDate, moveOutDate)
+}
+
+func (Postgres) EndLease(ctx context.Context, leaseId int) error {
+	return EndLease(ctx, leaseId)
+}
+
+// RentLedgerStore
+
+func (Postgres) GenerateRentCharges(ctx context.Context, tenantId int) error {
+	return GenerateRentCharges(ctx, tenantId)
+}
+
+func (Postgres) RecordRentPayment(ctx context.Context, tenantId int, amount, paidOn, method, reference string) error {
+	return RecordRentPayment(ctx, tenantId, amount, paidOn, method, reference)
+}
+
+func (Postgres) GetRentLedgerByTenantId(ctx context.Context, tenantId int) ([]RentLedgerEntry, error) {
+	return GetRentLedgerByTenantId(ctx, tenantId)
+}
+
+// MaintenanceStore
+
+func (Postgres) CreateMaintenanceTicket(ctx context.Context, tenantHashEmail, category, priority, description string, attachments []NewMaintenanceAttachment) (int, error) {
+	return CreateMaintenanceTicket(ctx, tenantHashEmail, category, priority, description, attachments)
+}
+
+func (Postgres) GetMaintenanceTicketsByTenantHashEmail(ctx context.Context, tenantHashEmail string) ([]MaintenanceTicket, error) {
+	return GetMaintenanceTicketsByTenantHashEmail(ctx, tenantHashEmail)
+}
+
+func (Postgres) GetMaintenanceTicketsByLandlordEmail(ctx context.Context, landlordEmail string) ([]MaintenanceTicket, error) {
+	return GetMaintenanceTicketsByLandlordEmail(ctx, landlordEmail)
+}
+
+func (Postgres) GetLandlordMaintenanceTicket(ctx context.Context, landlordEmail string, ticketId int) (MaintenanceTicket, error) {
+	return GetLandlordMaintenanceTicket(ctx, landlordEmail, ticketId)
+}
+
+func (Postgres) UpdateMaintenanceTicket(ctx context.Context, landlordEmail string, ticketId int, status, priority, scheduledFor, note string) error {
+	return UpdateMaintenanceTicket(ctx, landlordEmail, ticketId, status, priority, scheduledFor, note)
+}
+
+func (Postgres) GetTenantMaintenanceAttachment(ctx context.Context, tenantHashEmail string, attachmentId int) (MaintenanceAttachment, error) {
+	return GetTenantMaintenanceAttachment(ctx, tenantHashEmail, attachmentId)
+}
+
+func (Postgres) GetLandlordMaintenanceAttachment(ctx context.Context, landlordEmail string, attachmentId int) (MaintenanceAttachment, error) {
+	return GetLandlordMaintenanceAttachment(ctx, landlordEmail, attachmentId)
+}
+
+// LoginThrottleStore
+
+func (Postgres) GetLoginLockout(ctx context.Context, userType, userKey, ip string) (time.Time, error) {
+	return GetLoginLockout(ctx, userType, userKey, ip)
+}
+
+func (Postgres) RecordLoginFailure(ctx context.Context, userType, userKey, ip string) (LoginFailure, error) {
+	return RecordLoginFailure(ctx, userType, userKey, ip)
+}
+
+func (Postgres) ClearLoginFailures(ctx context.Context, userType, userKey string) error {
+	return ClearLoginFailures(ctx, userType, userKey)
+}
+
+func (Postgres) GetLockedTenantAccounts(ctx context.Context, landlordEmail string) ([]LockedAccount, error) {
+	return GetLockedTenantAccounts(ctx, landlordEmail)
+}
+
+func (Postgres) UnlockTenantAccount(ctx context.Context, landlordEmail string, tenantId int) error {
+	return UnlockTenantAccount(ctx, landlordEmail, tenantId)
+}
+
+func (Postgres) GetLockedLandlordAccounts(ctx context.Context) ([]LockedLandlordAccount, error) {
+	return GetLockedLandlordAccounts(ctx)
+}
+
+func (Postgres) UnlockLandlordAccount(ctx context.Context, landlordId int) error {
+	return UnlockLandlordAccount(ctx, landlordId)
+}
+
+func (Postgres) GetLockedStaffAccounts(ctx context.Context, landlordEmail string) ([]LockedLandlordAccount, error) {
+	return GetLockedStaffAccounts(ctx, landlordEmail)
+}
+
+func (Postgres) UnlockStaffAccount(ctx context.Context, landlordEmail string, staffId int) error {
+	return UnlockStaffAccount(ctx, landlordEmail, staffId)
+}
+
+// PasswordResetStore
+
+func (Postgres) CreatePasswordReset(ctx context.Context, userType, userKey string) (string, error) {
+	return CreatePasswordReset(ctx, userType, userKey)
+}
+
+func (Postgres) ValidatePasswordResetToken(ctx context.Context, userType, token string) error {
+	return ValidatePasswordResetToken(ctx, userType, token)
+}
+
+func (Postgres) ResetPassword(ctx context.Context, userType, token, newPassword string) error {
+	return ResetPassword(ctx, userType, token, newPassword)
+}
+
+// TwoFactorStore
+
+func (Postgres) GetTwoFactorStatus(ctx context.Context, userType, userKey string) (TwoFactorStatus, error) {
+	return GetTwoFactorStatus(ctx, userType, userKey)
+}
+
+func (Postgres) BeginTwoFactorSetup(ctx context.Context, userType, userKey string) (string, error) {
+	return BeginTwoFactorSetup(ctx, userType, userKey)
+}
+
+func (Postgres) ConfirmTwoFactorSetup(ctx context.Context, userType, userKey, code string) ([]string, error) {
+	return ConfirmTwoFactorSetup(ctx, userType, userKey, code)
+}
+
+func (Postgres) DisableTwoFactor(ctx context.Context, userType, userKey, code string) error {
+	return DisableTwoFactor(ctx, userType, userKey, code)
+}
+
+func (Postgres) StartTwoFactorLogin(ctx context.Context, userType, userKey string, rememberMe bool) (string, error) {
+	return StartTwoFactorLogin(ctx, userType, userKey, rememberMe)
+}
+
+func (Postgres) CompleteTwoFactorLogin(ctx context.Context, userType, token, code string) (string, bool, error) {
+	return CompleteTwoFactorLogin(ctx, userType, token, code)
+}
+
+// AuditStore
+
+func (Postgres) RecordAudit(ctx context.Context, entry AuditEntry) error {
+	return RecordAudit(ctx, entry)
+}
+
+func (Postgres) GetAuditLog(ctx context.Context, landlordEmail string, filter AuditFilter, limit int) ([]AuditEntry, error) {
+	return GetAuditLog(ctx, landlordEmail, filter, limit)
+}
+
+func (Postgres) VerifyAuditLog(ctx context.Context) (int64, error) {
+	return VerifyAuditLog(ctx)
+}
+
+func (Postgres) GetTenantAuditIdentity(ctx context.Context, hashEmail string) (int, string, error) {
+	return GetTenantAuditIdentity(ctx, hashEmail)
+}
+
+// OutboxStore
+
+func (Postgres) ClaimOutboxEmails(ctx context.Context, limit int) ([]OutboxEmail, error) {
+	return ClaimOutboxEmails(ctx, limit)
+}
+
+func (Postgres) CompleteOutboxEmail(ctx context.Context, id int64) error {
+	return CompleteOutboxEmail(ctx, id)
+}
+
+func (Postgres) RetryOutboxEmail(ctx context.Context, id int64, sendErr string, retryAt time.Time) error {
+	return RetryOutboxEmail(ctx, id, sendErr, retryAt)
+}
+
+func (Postgres) AbandonOutboxEmail(ctx context.Context, id int64, sendErr string) error {
+	return AbandonOutboxEmail(ctx, id, sendErr)
+}
+
+// OperatorStore
+
+func (Postgres) ExportDataSubject(ctx context.Context, email string) (SubjectExport, error) {
+	return ExportDataSubject(ctx, email)
+}
+
+func (Postgres) EraseDataSubject(ctx context.Context, email string) (SubjectErasure, error) {
+	return EraseDataSubject(ctx, email)
+}
+
+func (Postgres) ApplyRetention(ctx context.Context, policy config.RetentionPolicy, dryRun bool) ([]RetentionResult, error) {
+	return ApplyRetention(ctx, policy, dryRun)
+}
+
+func (Postgres) ReencryptAll(ctx context.Context, batchSize int) error {
+	return ReencryptAll(ctx, batchSize)
+}
+
+// HealthStore
+
+func (Postgres) Ping(ctx context.Context) error {
+	return Ping(ctx)
+}
@@ -0,0 +1,313 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+)
+
+/*
+CreateProperty adds a new property to the portfolio of a landlord.
+
+Arguments:
+
+- landlordEmail: The email of the landlord who owns the property.
+
+- name: The name the property is known by, shown to applicants on the tenancy form.
+
+- address: The address of the property.
+
+Returns:
+
+- error: An error object if the landlord cannot be found or the property cannot be stored.
+*/
+func CreateProperty(ctx context.Context, landlordEmail, name, address string) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	name = strings.TrimSpace(name)
+	address = strings.TrimSpace(address)
+	if name == "" || address == "" {
+		logs.Logs(logDbErr, "Property name and address are required")
+		return errors.New("property name and address are required")
+	}
+
+	landlordId, err := GetLandlordIdByEmail(ctx, landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord ID: %s", err.Error()))
+		return err
+	}
+
+	query := `
+	INSERT INTO lhp_properties (landlord_id, name, address, created_at)
+	VALUES ($1, $2, $3, NOW());
+	`
+	_, err = db.ExecContext(ctx, query, landlordId, name, address)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to create property: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logDb, "Property created successfully")
+	return nil
+}
+
+/*
+GetAllProperties retrieves every property from every landlord, so applicants can pick which property they are applying for.
+
+Returns:
+
+- []Property: A slice containing all properties, ordered by name.
+
+- error: An error object if the properties cannot be retrieved.
+*/
+func GetAllProperties(ctx context.Context) ([]Property, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	SELECT id, landlord_id, name, address
+	FROM lhp_properties
+	ORDER BY name;
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get properties: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanProperties(rows)
+}
+
+/*
+GetPropertiesByLandlordEmail retrieves the properties owned by a landlord.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+Returns:
+
+- []Property: A slice containing the landlord's properties, ordered by name.
+
+- error: An error object if the properties cannot be retrieved.
+*/
+func GetPropertiesByLandlordEmail(ctx context.Context, landlordEmail string) ([]Property, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	landlordId, err := GetLandlordIdByEmail(ctx, landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord ID: %s", err.Error()))
+		return nil, err
+	}
+
+	query := `
+	SELECT id, landlord_id, name, address
+	FROM lhp_properties
+	WHERE landlord_id = $1
+	ORDER BY name;
+	`
+	rows, err := db.QueryContext(ctx, query, landlordId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord properties: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanProperties(rows)
+}
+
+/*
+GetPropertyById retrieves a single property.
+
+Arguments:
+
+- propertyId: The ID of the property.
+
+Returns:
+
+- Property: The property. sql.ErrNoRows is returned if it does not exist.
+
+- error: An error object if the property cannot be retrieved.
+*/
+func GetPropertyById(ctx context.Context, propertyId int) (Property, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return Property{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var property Property
+	query := `
+	SELECT id, landlord_id, name, address
+	FROM lhp_properties
+	WHERE id = $1;
+	`
+	err := db.QueryRowContext(ctx, query, propertyId).Scan(&property.ID, &property.LandlordID, &property.Name, &property.Address)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get property: %s", err.Error()))
+		return Property{}, err
+	}
+	return property, nil
+}
+
+/*
+PropertyBelongsToLandlord checks if a property is owned by the given landlord.
+
+Arguments:
+
+- propertyId: The ID of the property.
+
+- landlordEmail: The email of the landlord.
+
+Returns:
+
+- bool: True if the property belongs to the landlord, false otherwise.
+
+- error: An error object if the check cannot be carried out.
+*/
+func PropertyBelongsToLandlord(ctx context.Context, propertyId int, landlordEmail string) (bool, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return false, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	query := `
+	SELECT EXISTS (
+		SELECT 1
+		FROM lhp_properties p
+		JOIN lhp_landlords l ON l.id = p.landlord_id
+		WHERE p.id = $1 AND l.email = $2
+	);
+	`
+	err := db.QueryRowContext(ctx, query, propertyId, landlordEmail).Scan(&exists)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to check property landlord: %s", err.Error()))
+		return false, err
+	}
+	return exists, nil
+}
+
+/*
+GetLandlordByTenantHashEmail retrieves the landlord a tenant rents from.
+
+Arguments:
+
+- hashEmail: The hashed email of the tenant.
+
+Returns:
+
+- int: The ID of the landlord.
+
+- string: The email of the landlord.
+
+- error: An error object if the landlord cannot be found.
+*/
+func GetLandlordByTenantHashEmail(ctx context.Context, hashEmail string) (int, string, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return 0, "", errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var landlordId int
+	var landlordEmail string
+	query := `
+	SELECT l.id, l.email
+	FROM lhp_tenants t
+	JOIN lhp_landlords l ON l.id = t.landlord_id
+	WHERE t.hash_email = $1;
+	`
+	err := db.QueryRowContext(ctx, query, hashEmail).Scan(&landlordId, &landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord of tenant: %s", err.Error()))
+		return 0, "", err
+	}
+	return landlordId, landlordEmail, nil
+}
+
+/*
+GetPropertyLandlordEmail retrieves the email of the landlord who owns a property, so they can be told about applications for it.
+
+Arguments:
+
+- propertyId: The ID of the property.
+
+Returns:
+
+- string: The email of the landlord.
+
+- error: sql.ErrNoRows if the property does not exist, or an error object if the landlord cannot be retrieved.
+*/
+func GetPropertyLandlordEmail(ctx context.Context, propertyId int) (string, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return "", errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var landlordEmail string
+	query := `
+	SELECT l.email
+	FROM lhp_properties p
+	JOIN lhp_landlords l ON l.id = p.landlord_id
+	WHERE p.id = $1;
+	`
+	err := db.QueryRowContext(ctx, query, propertyId).Scan(&landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get landlord of property: %s", err.Error()))
+		return "", err
+	}
+	return landlordEmail, nil
+}
+
+func scanProperties(rows *sql.Rows) ([]Property, error) {
+	var properties []Property
+	for rows.Next() {
+		var property Property
+		err := rows.Scan(&property.ID, &property.LandlordID, &property.Name, &property.Address)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan property: %s", err.Error()))
+			return nil, err
+		}
+		properties = append(properties, property)
+	}
+
+	err := rows.Err()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get properties: %s", err.Error()))
+		return nil, err
+	}
+	return properties, nil
+}
@@ -0,0 +1,270 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// encryptedTable is a table with columns encrypted by utils.Encrypt.
+type encryptedTable struct {
+	name    string
+	columns []string
+}
+
+// encryptedTables lists every encrypted column in the database. New encrypted columns must be added here
+// so the re-encryption job moves them onto a new key when the encryption key is rotated.
+var encryptedTables = []encryptedTable{
+	{
+		name: "lhp_tenants",
+		columns: []string{
+			"encrypt_email",
+			"encrypt_room_type",
+			"encrypt_move_in_date",
+			"encrypt_rent_due",
+			"encrypt_monthly_rent",
+			"encrypt_tenant_name",
+		},
+	},
+	{
+		name: "lhp_tenant_application",
+		columns: []string{
+			"encrypt_full_name",
+			"encrypt_dob",
+			"encrypt_passport_number",
+			"encrypt_phone_number",
+			"encrypt_email",
+			"encrypt_occupation",
+			"encrypt_employer",
+			"encrypt_employer_number",
+			"encrypt_emergency_contact",
+			"encrypt_emergency_number",
+			"encrypt_emergency_address",
+			"encrypt_if_evicted",
+			"encrypt_evicted_reason",
+			"encrypt_if_convicted",
+			"encrypt_convicted_reason",
+			"encrypt_smoke",
+			"encrypt_pets",
+			"encrypt_if_vehicle",
+			"encrypt_vehicle_reg",
+			"encrypt_have_children",
+			"encrypt_children",
+			"encrypt_refused_rent",
+			"encrypt_refused_rent_reason",
+			"encrypt_unstable_income",
+			"encrypt_income_reason",
+		},
+	},
+	{name: "lhp_messages", columns: []string{"encrypt_message"}},
+	{name: "lhp_rent_ledger", columns: []string{"encrypt_amount", "encrypt_method", "encrypt_reference"}},
+	{name: "lhp_lease_renewals", columns: []string{"encrypt_monthly_rent"}},
+	{name: "lhp_maintenance_tickets", columns: []string{"encrypt_description"}},
+	{name: "lhp_maintenance_attachments", columns: []string{"encrypt_data"}},
+	{name: "lhp_maintenance_ticket_events", columns: []string{"encrypt_note"}},
+	{name: "lhp_two_factor", columns: []string{"encrypt_secret"}},
+	{name: "lhp_email_outbox", columns: []string{"encrypt_payload"}},
+}
+
+/*
+ReencryptAll rewrites every encrypted column in the database under the active encryption key,
+so an old key can be removed from ENCRYPTION_KEYS once the job has finished.
+
+Each table is walked in order of id, one batch per transaction. The last id of each committed batch
+is saved in lhp_reencryption_progress, so if the job is stopped it carries on from where it got to
+the next time it is run. Progress is reset when the active key changes. Values already under the
+active key are left untouched, so running the job again is safe.
+
+Arguments:
+
+- batchSize: The number of rows to re-encrypt in each transaction.
+
+Returns:
+
+- error: An error object if any table cannot be re-encrypted.
+*/
+func ReencryptAll(ctx context.Context, batchSize int) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+	if batchSize <= 0 {
+		return errors.New("batch size must be greater than 0")
+	}
+
+	keyID := utils.ActiveKeyID()
+	if keyID == "" {
+		logs.Logs(logDbErr, "Encryption keys have not been initialised")
+		return errors.New("encryption keys have not been initialised")
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Re-encrypting database with key %s...", keyID))
+	for _, table := range encryptedTables {
+		err := reencryptTable(ctx, table, keyID, batchSize)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to re-encrypt %s: %s", table.name, err.Error()))
+			return err
+		}
+	}
+	logs.Logs(logDb, fmt.Sprintf("Database re-encrypted with key %s.", keyID))
+	return nil
+}
+
+// reencryptTable re-encrypts one table, resuming after the last id saved for the active key.
+func reencryptTable(ctx context.Context, table encryptedTable, keyID string, batchSize int) error {
+	var progressKeyID string
+	var lastId, rowsReencrypted int
+	var completedAt sql.NullTime
+	query := `
+	SELECT key_id, last_id, rows_reencrypted, completed_at
+	FROM lhp_reencryption_progress
+	WHERE table_name = $1;
+	`
+	readCtx, cancel := withQueryTimeout(ctx)
+	err := db.QueryRowContext(readCtx, query, table.name).Scan(&progressKeyID, &lastId, &rowsReencrypted, &completedAt)
+	cancel()
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	// a new key means starting the table again from the beginning
+	if err == sql.ErrNoRows || progressKeyID != keyID {
+		lastId, rowsReencrypted, completedAt = 0, 0, sql.NullTime{}
+	}
+	if completedAt.Valid {
+		logs.Logs(logDb, fmt.Sprintf("%s is already re-encrypted with key %s", table.name, keyID))
+		return nil
+	}
+	if lastId > 0 {
+		logs.Logs(logDb, fmt.Sprintf("Resuming re-encryption of %s after id %d", table.name, lastId))
+	}
+
+	for {
+		batchLastId, batchRows, done, err := reencryptBatch(ctx, table, keyID, lastId, rowsReencrypted, batchSize)
+		if err != nil {
+			return err
+		}
+		lastId = batchLastId
+		rowsReencrypted += batchRows
+		if done {
+			break
+		}
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Re-encrypted %d rows of %s with key %s", rowsReencrypted, table.name, keyID))
+	return nil
+}
+
+/*
+reencryptBatch re-encrypts the next batch of rows after lastId and saves the job's progress in the same transaction,
+so the progress never runs ahead of the rows that were actually rewritten.
+It returns the last id in the batch, the number of rows rewritten, and whether the table is finished.
+*/
+func reencryptBatch(ctx context.Context, table encryptedTable, keyID string, lastId, rowsReencrypted, batchSize int) (int, int, bool, error) {
+	// each batch gets its own deadline, so a large table can take as long as it needs
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`
+	SELECT id, %s
+	FROM %s
+	WHERE id > $1
+	ORDER BY id
+	LIMIT $2
+	FOR UPDATE;
+	`, strings.Join(table.columns, ", "), table.name)
+	rows, err := tx.QueryContext(ctx, query, lastId, batchSize)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	type encryptedRow struct {
+		id     int
+		values [][]byte
+	}
+	var batch []encryptedRow
+	for rows.Next() {
+		row := encryptedRow{values: make([][]byte, len(table.columns))}
+		dest := []any{&row.id}
+		for i := range row.values {
+			dest = append(dest, &row.values[i])
+		}
+		err := rows.Scan(dest...)
+		if err != nil {
+			rows.Close()
+			return 0, 0, false, err
+		}
+		batch = append(batch, row)
+	}
+	rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	// rewrite the rows once the batch has been read, only touching values that are not under the active key yet
+	batchRows := 0
+	for _, row := range batch {
+		var assignments []string
+		var args []any
+		for i, value := range row.values {
+			if len(value) == 0 || !utils.NeedsReencryption(value) {
+				continue
+			}
+			reencrypted, err := utils.Reencrypt(value)
+			if err != nil {
+				return 0, 0, false, fmt.Errorf("failed to re-encrypt %s.%s for id %d: %s", table.name, table.columns[i], row.id, err.Error())
+			}
+			args = append(args, reencrypted)
+			assignments = append(assignments, fmt.Sprintf("%s = $%d", table.columns[i], len(args)))
+		}
+		if len(assignments) == 0 {
+			continue
+		}
+
+		args = append(args, row.id)
+		query := fmt.Sprintf(`UPDATE %s SET %s WHERE id = $%d;`, table.name, strings.Join(assignments, ", "), len(args))
+		_, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		batchRows++
+	}
+
+	done := len(batch) < batchSize
+	if len(batch) > 0 {
+		lastId = batch[len(batch)-1].id
+	}
+
+	query = `
+	INSERT INTO lhp_reencryption_progress (table_name, key_id, last_id, rows_reencrypted, completed_at, updated_at)
+	VALUES ($1, $2, $3, $4, CASE WHEN $5 THEN NOW() END, NOW())
+	ON CONFLICT (table_name) DO UPDATE
+	SET key_id = EXCLUDED.key_id,
+		last_id = EXCLUDED.last_id,
+		rows_reencrypted = EXCLUDED.rows_reencrypted,
+		completed_at = EXCLUDED.completed_at,
+		updated_at = NOW();
+	`
+	_, err = tx.ExecContext(ctx, query, table.name, keyID, lastId, rowsReencrypted+batchRows, done)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return lastId, batchRows, done, nil
+}
@@ -0,0 +1,264 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/lib/pq"
+)
+
+// retentionRule finds and purges one kind of record once it is past its retention period.
+type retentionRule struct {
+	name       string
+	period     func(policy config.RetentionPolicy) time.Duration
+	candidates string                                                 // selects the landlord email and ID of each record older than the cutoff ($1)
+	purge      func(ctx context.Context, tx *sql.Tx, ids []int) error // deletes the records and everything that belongs to them
+}
+
+// purgedTenantKeys selects the hashed emails of the tenants being purged ($1) that no other tenant account uses,
+// so the login data and applications of someone who has since moved in again are kept.
+const purgedTenantKeys = `(
+	SELECT hash_email FROM lhp_tenants WHERE id = ANY($1)
+	EXCEPT
+	SELECT hash_email FROM lhp_tenants WHERE id <> ALL($1)
+)`
+
+// retentionRules lists the retention rules, in the order they are applied.
+var retentionRules = []retentionRule{
+	{
+		name:   RetentionDeniedApplications,
+		period: func(policy config.RetentionPolicy) time.Duration { return policy.DeniedApplications },
+		candidates: `
+		SELECT COALESCE(l.email, ''), a.id
+		FROM lhp_tenant_application a
+		LEFT JOIN lhp_landlords l ON l.id = a.landlord_id
+		WHERE a.status = 'denied' AND COALESCE(a.decided_at, a.created_at) < $1
+		ORDER BY a.id;
+		`,
+		purge: func(ctx context.Context, tx *sql.Tx, ids []int) error {
+			_, err := tx.ExecContext(ctx, `DELETE FROM lhp_tenant_application WHERE id = ANY($1);`, pq.Array(ids))
+			return err
+		},
+	},
+	{
+		name:   RetentionEndedTenancies,
+		period: func(policy config.RetentionPolicy) time.Duration { return policy.EndedTenancies },
+		candidates: `
+		SELECT COALESCE(l.email, ''), t.id
+		FROM lhp_tenants t
+		LEFT JOIN lhp_landlords l ON l.id = t.landlord_id
+		WHERE t.archived_at < $1
+		ORDER BY t.id;
+		`,
+		purge: func(ctx context.Context, tx *sql.Tx, ids []int) error {
+			// child records go first, and the tenant accounts last
+			queries := []string{
+				`DELETE FROM lhp_lease_renewals WHERE lease_id IN (SELECT id FROM lhp_leases WHERE tenant_id = ANY($1));`,
+				`DELETE FROM lhp_leases WHERE tenant_id = ANY($1);`,
+				`DELETE FROM lhp_rent_ledger WHERE tenant_id = ANY($1);`,
+				`DELETE FROM lhp_maintenance_attachments WHERE ticket_id IN (SELECT id FROM lhp_maintenance_tickets WHERE tenant_id = ANY($1));`,
+				`DELETE FROM lhp_maintenance_ticket_events WHERE ticket_id IN (SELECT id FROM lhp_maintenance_tickets WHERE tenant_id = ANY($1));`,
+				`DELETE FROM lhp_maintenance_tickets WHERE tenant_id = ANY($1);`,
+				`DELETE FROM lhp_messages WHERE (sender_type = 'tenant' AND sender_id = ANY($1)) OR (receiver_type = 'tenant' AND receiver_id = ANY($1));`,
+				`DELETE FROM lhp_two_factor WHERE user_type = 'tenant' AND user_key IN ` + purgedTenantKeys + `;`,
+				`DELETE FROM lhp_two_factor_recovery_codes WHERE user_type = 'tenant' AND user_key IN ` + purgedTenantKeys + `;`,
+				`DELETE FROM lhp_two_factor_challenges WHERE user_type = 'tenant' AND user_key IN ` + purgedTenantKeys + `;`,
+				`DELETE FROM lhp_password_resets WHERE user_type = 'tenant' AND user_key IN ` + purgedTenantKeys + `;`,
+				`DELETE FROM lhp_login_throttles WHERE scope = 'account' AND user_type = 'tenant' AND throttle_key IN ` + purgedTenantKeys + `;`,
+				`DELETE FROM lhp_tenant_application WHERE status <> 'pending' AND hash_email IN ` + purgedTenantKeys + `;`,
+				`DELETE FROM lhp_tenants WHERE id = ANY($1);`,
+			}
+			for _, query := range queries {
+				_, err := tx.ExecContext(ctx, query, pq.Array(ids))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		name:   RetentionMessages,
+		period: func(policy config.RetentionPolicy) time.Duration { return policy.Messages },
+		candidates: `
+		SELECT COALESCE(l.email, ''), m.id
+		FROM lhp_messages m
+		LEFT JOIN lhp_landlords l ON l.id = CASE WHEN m.sender_type = 'landlord' THEN m.sender_id ELSE m.receiver_id END
+		WHERE m.sent_at < $1
+		ORDER BY m.id;
+		`,
+		purge: func(ctx context.Context, tx *sql.Tx, ids []int) error {
+			_, err := tx.ExecContext(ctx, `DELETE FROM lhp_messages WHERE id = ANY($1);`, pq.Array(ids))
+			return err
+		},
+	},
+}
+
+/*
+ApplyRetention purges the records that are past their retention period under each rule of the policy.
+Rules with a period of 0 keep their records forever and are skipped.
+
+Each rule's records are purged in one transaction. Every purge is added to the audit log of the landlord
+the records belonged to, listing the IDs purged.
+
+Arguments:
+
+- policy: The retention periods to apply.
+
+- dryRun: Only find the records that would be purged, without purging them or adding to the audit log.
+
+Returns:
+
+- []RetentionResult: What each rule purged, or would have purged, for the rules that were applied.
+
+- error: An error object if a rule cannot be applied. Rules applied before it have already purged their records.
+*/
+func ApplyRetention(ctx context.Context, policy config.RetentionPolicy, dryRun bool) ([]RetentionResult, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	now := time.Now()
+	var results []RetentionResult
+	for _, rule := range retentionRules {
+		period := rule.period(policy)
+		if period <= 0 {
+			continue
+		}
+
+		result, err := applyRetentionRule(ctx, rule, now.Add(-period), dryRun)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to apply %s retention: %s", rule.name, err.Error()))
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// applyRetentionRule purges the records older than the cutoff under one rule, then records the purge in the audit log.
+func applyRetentionRule(ctx context.Context, rule retentionRule, cutoff time.Time, dryRun bool) (RetentionResult, error) {
+	result := RetentionResult{Rule: rule.name, Cutoff: cutoff, DryRun: dryRun, ByLandlord: map[string][]int{}}
+
+	// each rule gets its own deadline, as the rules before it may have taken a while
+	txCtx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(txCtx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(txCtx, rule.candidates, cutoff)
+	if err != nil {
+		return result, err
+	}
+	var ids []int
+	for rows.Next() {
+		var landlordEmail string
+		var id int
+		err := rows.Scan(&landlordEmail, &id)
+		if err != nil {
+			rows.Close()
+			return result, err
+		}
+		result.ByLandlord[landlordEmail] = append(result.ByLandlord[landlordEmail], id)
+		ids = append(ids, id)
+	}
+	rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return result, err
+	}
+
+	if dryRun || len(ids) == 0 {
+		return result, nil
+	}
+
+	err = rule.purge(txCtx, tx, ids)
+	if err != nil {
+		return result, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return result, err
+	}
+	logs.Logs(logDb, fmt.Sprintf("Purged %d %s older than %s", len(ids), rule.name, cutoff.Format("2006-01-02")))
+
+	// the records are already purged, so a failure to record it has been logged and the other landlords are still recorded
+	landlordEmails := make([]string, 0, len(result.ByLandlord))
+	for landlordEmail := range result.ByLandlord {
+		landlordEmails = append(landlordEmails, landlordEmail)
+	}
+	sort.Strings(landlordEmails)
+	for _, landlordEmail := range landlordEmails {
+		// records of landlords whose accounts have been removed have no audit log to go in
+		if landlordEmail == "" {
+			continue
+		}
+		purged := result.ByLandlord[landlordEmail]
+		RecordAudit(ctx, AuditEntry{
+			ActorType:     AuditActorSystem,
+			Actor:         "retention",
+			LandlordEmail: landlordEmail,
+			Action:        AuditRetentionPurged,
+			TargetType:    rule.name,
+			Details:       fmt.Sprintf("%d older than %s: %s", len(purged), cutoff.Format("2006-01-02"), joinIds(purged)),
+		})
+	}
+	return result, nil
+}
+
+// joinIds lists IDs separated by commas.
+func joinIds(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+/*
+StartRetentionScheduler applies config.Retention when the app starts, then again every config.Retention.Interval.
+In a dry run each rule's findings are only logged. It runs until the app stops, so start it in its own goroutine.
+*/
+func StartRetentionScheduler() {
+	logs.Logs(logDb, fmt.Sprintf("Applying retention rules every %s...", config.Retention.Interval))
+	ticker := time.NewTicker(config.Retention.Interval)
+	defer ticker.Stop()
+
+	for {
+		results, err := ApplyRetention(context.Background(), config.Retention, config.Retention.DryRun)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Retention rules stopped early: %s", err.Error()))
+		}
+		LogRetentionReport(results)
+		<-ticker.C
+	}
+}
+
+// LogRetentionReport logs what each retention rule purged, or in a dry run would have purged, by landlord.
+func LogRetentionReport(results []RetentionResult) {
+	for _, result := range results {
+		verb := "Purged"
+		if result.DryRun {
+			verb = "Dry run, would purge"
+		}
+		if len(result.ByLandlord) == 0 {
+			logs.Logs(logDb, fmt.Sprintf("%s no %s older than %s", verb, result.Rule, result.Cutoff.Format("2006-01-02")))
+			continue
+		}
+		for landlordEmail, ids := range result.ByLandlord {
+			logs.Logs(logDb, fmt.Sprintf("%s %d %s older than %s for landlord %s: %s", verb, len(ids), result.Rule, result.Cutoff.Format("2006-01-02"), landlordEmail, joinIds(ids)))
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+)
+
+// retentionResponder answers the candidate queries of each retention rule: denied applications 3 and 4 and ended
+// tenant account 7 of landlord@example.com, ended tenant account 8 of a landlord whose account has been removed,
+// and message 11 of other@example.com.
+func retentionResponder(query string, args []driver.Value) fakeResponse {
+	candidates := map[string][][]driver.Value{
+		"FROM lhp_tenant_application a": {{"landlord@example.com", int64(3)}, {"landlord@example.com", int64(4)}},
+		"FROM lhp_tenants t":            {{"landlord@example.com", int64(7)}, {"", int64(8)}},
+		"FROM lhp_messages m":           {{"other@example.com", int64(11)}},
+	}
+	for from, rows := range candidates {
+		if strings.HasPrefix(query, "SELECT COALESCE(l.email, '')") && strings.Contains(query, from) {
+			return fakeResponse{columns: []string{"email", "id"}, rows: rows}
+		}
+	}
+	return fakeResponse{rowsAffected: 1}
+}
+
+// candidateQueries returns the candidate queries run, by the table they select from
+func candidateQueries(statements []fakeStatement) map[string]fakeStatement {
+	queries := map[string]fakeStatement{}
+	for _, statement := range statements {
+		if !strings.HasPrefix(statement.query, "SELECT COALESCE(l.email, '')") {
+			continue
+		}
+		for _, table := range []string{"lhp_tenant_application", "lhp_tenants", "lhp_messages"} {
+			if strings.Contains(statement.query, "FROM "+table+" ") {
+				queries[table] = statement
+			}
+		}
+	}
+	return queries
+}
+
+// allRetentionRules is a policy that applies every rule
+var allRetentionRules = config.RetentionPolicy{
+	DeniedApplications: 90 * 24 * time.Hour,
+	EndedTenancies:     6 * 365 * 24 * time.Hour,
+	Messages:           2 * 365 * 24 * time.Hour,
+}
+
+func TestApplyRetentionCutoffs(t *testing.T) {
+	fake := useFakeDB(t, retentionResponder)
+	policy := config.RetentionPolicy{DeniedApplications: 90 * 24 * time.Hour, EndedTenancies: 6 * 365 * 24 * time.Hour}
+
+	before := time.Now()
+	results, err := ApplyRetention(context.Background(), policy, true)
+	after := time.Now()
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// messages have a period of 0, so they are kept forever and their rule is not applied
+	if len(results) != 2 || results[0].Rule != RetentionDeniedApplications || results[1].Rule != RetentionEndedTenancies {
+		t.Fatalf("Expected the denied applications and ended tenancies rules to be applied, got %v", results)
+	}
+	queries := candidateQueries(fake.ran())
+	if _, ok := queries["lhp_messages"]; ok {
+		t.Errorf("Expected no messages to be looked for")
+	}
+
+	tables := map[string]string{RetentionDeniedApplications: "lhp_tenant_application", RetentionEndedTenancies: "lhp_tenants"}
+	periods := map[string]time.Duration{RetentionDeniedApplications: policy.DeniedApplications, RetentionEndedTenancies: policy.EndedTenancies}
+	for _, result := range results {
+		period := periods[result.Rule]
+		if result.Cutoff.Before(before.Add(-period)) || result.Cutoff.After(after.Add(-period)) {
+			t.Errorf("Expected the %s cutoff to be %v ago, got %v", result.Rule, period, result.Cutoff)
+		}
+
+		query := queries[tables[result.Rule]]
+		if cutoff, ok := query.args[0].(time.Time); !ok || !cutoff.Equal(result.Cutoff) {
+			t.Errorf("Expected %s older than %v to be looked for, got %v", result.Rule, result.Cutoff, query.args)
+		}
+		// a record from exactly the cutoff has not been kept for the whole period yet
+		if !strings.Contains(query.query, "< $1 ") {
+			t.Errorf("Expected only %s strictly older than the cutoff to be looked for, ran: %s", result.Rule, query.query)
+		}
+	}
+}
+
+func TestApplyRetentionDryRun(t *testing.T) {
+	fake := useFakeDB(t, retentionResponder)
+
+	results, err := ApplyRetention(context.Background(), allRetentionRules, true)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// what would be purged is still reported
+	if len(results) != 3 {
+		t.Fatalf("Expected every rule to be applied, got %v", results)
+	}
+	for _, result := range results {
+		if !result.DryRun {
+			t.Errorf("Expected the %s result to be a dry run", result.Rule)
+		}
+	}
+	if ids := results[1].ByLandlord["landlord@example.com"]; len(ids) != 1 || ids[0] != 7 {
+		t.Errorf("Expected tenant 7 to be reported for landlord@example.com, got %v", results[1].ByLandlord)
+	}
+	if ids := results[1].ByLandlord[""]; len(ids) != 1 || ids[0] != 8 {
+		t.Errorf("Expected tenant 8 to be reported without a landlord, got %v", results[1].ByLandlord)
+	}
+
+	for _, statement := range fake.ran() {
+		if strings.HasPrefix(statement.query, "DELETE ") || strings.HasPrefix(statement.query, "INSERT ") || statement.query == "COMMIT" {
+			t.Errorf("Expected nothing to be purged or audited in a dry run, ran: %s", statement.query)
+		}
+	}
+}
+
+func TestApplyRetentionPurge(t *testing.T) {
+	fake := useFakeDB(t, retentionResponder)
+
+	results, err := ApplyRetention(context.Background(), allRetentionRules, false)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(results) != 3 || results[0].DryRun {
+		t.Fatalf("Expected every rule to purge, got %v", results)
+	}
+	statements := fake.ran()
+
+	t.Run("Records past their period deleted", func(t *testing.T) {
+		purged := map[string]string{"lhp_tenant_application": "{3,4}", "lhp_tenants": "{7,8}", "lhp_messages": "{11}"}
+		for table, ids := range purged {
+			// tenants' messages and applications are purged with them, so only the rule's own delete is by ID
+			var deleted []string
+			for _, write := range writesTo(statements, table) {
+				if write.query == fmt.Sprintf("DELETE FROM %s WHERE id = ANY($1);", table) {
+					deleted = append(deleted, fmt.Sprint(write.args[0]))
+				}
+			}
+			if len(deleted) != 1 || deleted[0] != ids {
+				t.Errorf("Expected %s %s to be deleted, got %v", table, ids, deleted)
+			}
+		}
+
+		commits := 0
+		for _, statement := range statements {
+			if statement.query == "COMMIT" {
+				commits++
+			}
+		}
+		// each rule commits its purge, then each audit entry is committed
+		if commits != 3+3 {
+			t.Errorf("Expected 3 purges and 3 audit entries to be committed, got %d commits", commits)
+		}
+	})
+
+	t.Run("Purges audited for each landlord", func(t *testing.T) {
+		var audited []string
+		for _, write := range writesTo(statements, "lhp_audit_log") {
+			if write.args[4] != AuditRetentionPurged {
+				t.Errorf("Expected a retention purge in the audit log, got %v", write.args[4])
+			}
+			audited = append(audited, fmt.Sprintf("%s %s: %s", write.args[3], write.args[5], write.args[7]))
+		}
+
+		// the removed landlord has no audit log, so tenant 8 is not recorded
+		expected := []string{
+			"landlord@example.com " + RetentionDeniedApplications + ": 2 older than",
+			"landlord@example.com " + RetentionEndedTenancies + ": 1 older than",
+			"other@example.com " + RetentionMessages + ": 1 older than",
+		}
+		if len(audited) != len(expected) {
+			t.Fatalf("Expected %d audit entries, got %v", len(expected), audited)
+		}
+		for i, prefix := range expected {
+			if !strings.HasPrefix(audited[i], prefix) {
+				t.Errorf("Expected audit entry %q, got %q", prefix, audited[i])
+			}
+		}
+		if !strings.HasSuffix(audited[0], ": 3, 4") {
+			t.Errorf("Expected the purged IDs to be listed, got %q", audited[0])
+		}
+	})
+}
+
+func TestApplyRetentionKeepsFinancialRecords(t *testing.T) {
+	// rent records have to be kept for 6 years, which the default ended tenancies period covers
+	if config.Retention.EndedTenancies < 6*365*24*time.Hour {
+		t.Errorf("Expected ended tenancies to be kept for at least 6 years by default, got %v", config.Retention.EndedTenancies)
+	}
+
+	// only the ended tenancies rule purges rent ledgers and leases, so they are kept while it keeps tenancies forever
+	fake := useFakeDB(t, retentionResponder)
+	policy := allRetentionRules
+	policy.EndedTenancies = 0
+
+	_, err := ApplyRetention(context.Background(), policy, false)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	statements := fake.ran()
+	for _, table := range []string{"lhp_rent_ledger", "lhp_leases", "lhp_lease_renewals", "lhp_tenants"} {
+		if writes := writesTo(statements, table); len(writes) != 0 {
+			t.Errorf("Expected %s to be kept, ran: %v", table, writes)
+		}
+	}
+	if writes := writesTo(statements, "lhp_messages"); len(writes) != 1 {
+		t.Errorf("Expected the other rules to purge, got %v", writes)
+	}
+}
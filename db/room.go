@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+)
+
+// roomQuery selects the rooms of a landlord's properties with the number of current (not archived) tenants in each.
+const roomQuery = `
+	SELECT
+		r.id,
+		r.property_id,
+		p.name,
+		r.unit,
+		r.name,
+		r.room_type,
+		r.capacity,
+		r.amenities,
+		r.default_rent,
+		r.currency,
+		(SELECT COUNT(*) FROM lhp_tenants t WHERE t.room_id = r.id AND t.archived_at IS NULL)
+	FROM lhp_rooms r
+	JOIN lhp_properties p ON p.id = r.property_id
+	JOIN lhp_landlords l ON l.id = p.landlord_id
+	WHERE l.email = $1
+`
+
+func scanRoom(row rowScanner) (Room, error) {
+	var room Room
+	err := row.Scan(
+		&room.ID,
+		&room.PropertyID,
+		&room.PropertyName,
+		&room.Unit,
+		&room.Name,
+		&room.RoomType,
+		&room.Capacity,
+		&room.Amenities,
+		&room.DefaultRent,
+		&room.Currency,
+		&room.Occupants,
+	)
+	return room, err
+}
+
+/*
+CreateRoom adds a room to one of the landlord's properties.
+
+Arguments:
+
+- landlordEmail: The email of the landlord who owns the property.
+
+- propertyId: The ID of the property the room is in.
+
+- unit: The unit (e.g. flat or floor) the room is in. Empty if the property is not split into units.
+
+- name: The name or number of the room.
+
+- roomType: The type of room (e.g. Single, Double).
+
+- capacity: The number of tenants the room can hold.
+
+- amenities: A free text description of the room's amenities.
+
+- defaultRent: The monthly rent usually charged for the room.
+
+- currency: The currency of the default rent.
+
+Returns:
+
+- error: An error object if the property does not belong to the landlord or the room cannot be stored.
+*/
+func CreateRoom(ctx context.Context, landlordEmail string, propertyId int, unit, name, roomType string, capacity int, amenities, defaultRent, currency string) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	ownsProperty, err := PropertyBelongsToLandlord(ctx, propertyId, landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to check property: %s", err.Error()))
+		return err
+	}
+	if !ownsProperty {
+		logs.Logs(logDbErr, "Property does not belong to landlord")
+		return errors.New("property does not belong to landlord")
+	}
+
+	query := `
+	INSERT INTO lhp_rooms (property_id, unit, name, room_type, capacity, amenities, default_rent, currency, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW());
+	`
+	_, err = db.ExecContext(ctx, query, propertyId, unit, name, roomType, capacity, amenities, defaultRent, currency)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to create room: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logDb, "Room created successfully")
+	return nil
+}
+
+/*
+GetRoomsByLandlordEmail retrieves every room in the landlord's properties along with how many tenants live in each.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+Returns:
+
+- []Room: A slice containing the rooms, ordered by property, unit and room name.
+
+- error: An error object if the rooms cannot be retrieved.
+*/
+func GetRoomsByLandlordEmail(ctx context.Context, landlordEmail string) ([]Room, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, roomQuery+`ORDER BY p.name, r.unit, r.name;`, landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get rooms: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rooms []Room
+	for rows.Next() {
+		room, err := scanRoom(rows)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan room: %s", err.Error()))
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get rooms: %s", err.Error()))
+		return nil, err
+	}
+	return rooms, nil
+}
+
+/*
+GetLandlordRoom retrieves a single room, as long as it is in one of the landlord's properties.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+- roomId: The ID of the room.
+
+Returns:
+
+- Room: The room. sql.ErrNoRows is returned if it does not exist or belongs to another landlord.
+
+- error: An error object if the room cannot be retrieved.
+*/
+func GetLandlordRoom(ctx context.Context, landlordEmail string, roomId int) (Room, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return Room{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	room, err := scanRoom(db.QueryRowContext(ctx, roomQuery+`AND r.id = $2;`, landlordEmail, roomId))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get room: %s", err.Error()))
+		return Room{}, err
+	}
+	return room, nil
+}
+
+/*
+reserveRoom locks a room for the rest of the transaction and checks it still has space for another tenant.
+Locking the room row means two tenants being added at the same time cannot both take the last space.
+
+Arguments:
+
+- tx: The transaction the new tenant is being created in.
+
+- roomId: The ID of the room.
+
+Returns:
+
+- error: ErrRoomFull if the room has no space left, or an error object if the room cannot be checked.
+*/
+func reserveRoom(ctx context.Context, tx *sql.Tx, roomId int) error {
+	var capacity int
+	err := tx.QueryRowContext(ctx, `SELECT capacity FROM lhp_rooms WHERE id = $1 FOR UPDATE;`, roomId).Scan(&capacity)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to lock room: %s", err.Error()))
+		return err
+	}
+
+	var occupants int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM lhp_tenants WHERE room_id = $1 AND archived_at IS NULL;`, roomId).Scan(&occupants)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to count room occupants: %s", err.Error()))
+		return err
+	}
+
+	if occupants >= capacity {
+		logs.Logs(logDbErr, fmt.Sprintf("Room %d is fully occupied", roomId))
+		return ErrRoomFull
+	}
+	return nil
+}
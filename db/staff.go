@@ -0,0 +1,329 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+)
+
+/*
+GetStaffAccounts gets the staff members working for a landlord, for the landlord's staff page.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+Returns:
+
+- []StaffAccount: The staff members and their permissions, the oldest first.
+
+- error: An error object if the query fails.
+*/
+func GetStaffAccounts(ctx context.Context, landlordEmail string) ([]StaffAccount, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	SELECT s.id, s.email, s.created_at, s.suspended_at
+	FROM lhp_landlords s
+	JOIN lhp_landlords l ON l.id = s.works_for
+	WHERE l.email = $1 AND s.account_role = 'staff'
+	ORDER BY s.created_at, s.id;
+	`
+	rows, err := db.QueryContext(ctx, query, landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get staff accounts: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var staff []StaffAccount
+	for rows.Next() {
+		var account StaffAccount
+		err := rows.Scan(&account.ID, &account.Email, &account.CreatedAt, &account.SuspendedAt)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan staff account: %s", err.Error()))
+			return nil, err
+		}
+		staff = append(staff, account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range staff {
+		staff[i].Permissions, err = getStaffPermissions(ctx, staff[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return staff, nil
+}
+
+/*
+SetStaffPermissions replaces the permissions a landlord has granted one of their staff members.
+The change applies from the staff member's next request, without them having to log in again.
+
+Arguments:
+
+- landlordEmail: The email of the landlord the staff member works for.
+
+- staffId: The ID of the staff member's account.
+
+- permissions: The permissions to grant, each one of StaffPermissions. Permissions not listed are taken away.
+
+Returns:
+
+- error: sql.ErrNoRows if the staff member does not work for the landlord, ErrInvalidPermission if a permission is unknown,
+or an error object if the permissions cannot be saved.
+*/
+func SetStaffPermissions(ctx context.Context, landlordEmail string, staffId int, permissions []string) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	for _, permission := range permissions {
+		if !isStaffPermission(permission) {
+			return ErrInvalidPermission
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockStaffAccount(ctx, tx, landlordEmail, staffId, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM lhp_staff_permissions WHERE staff_id = $1;`, staffId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to clear staff permissions: %s", err.Error()))
+		return err
+	}
+	query := `
+	INSERT INTO lhp_staff_permissions (staff_id, permission, granted_at)
+	VALUES ($1, $2, NOW())
+	ON CONFLICT (staff_id, permission) DO NOTHING;
+	`
+	for _, permission := range permissions {
+		_, err = tx.ExecContext(ctx, query, staffId, permission)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to grant staff permission: %s", err.Error()))
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit staff permissions: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Permissions updated for staff account %d", staffId))
+	return nil
+}
+
+/*
+RemoveStaffAccount deletes the account of a staff member working for a landlord, along with its permissions and login settings.
+Records a staff member works with belong to the landlord, so nothing else is lost.
+
+Arguments:
+
+- landlordEmail: The email of the landlord the staff member works for.
+
+- staffId: The ID of the staff member's account.
+
+Returns:
+
+- error: sql.ErrNoRows if the staff member does not work for the landlord, or an error object if the account cannot be removed.
+*/
+func RemoveStaffAccount(ctx context.Context, landlordEmail string, staffId int) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return err
+	}
+	defer tx.Rollback()
+
+	var email string
+	err = lockStaffAccount(ctx, tx, landlordEmail, staffId, &email)
+	if err != nil {
+		return err
+	}
+
+	err = deleteLandlordAccount(ctx, tx, staffId, email)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit staff removal: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Staff account %d removed", staffId))
+	return nil
+}
+
+/*
+GetPendingStaffInvitations gets the staff invitations a landlord has sent that can still be accepted.
+
+Arguments:
+
+- landlordEmail: The email of the landlord.
+
+Returns:
+
+- []LandlordInvitation: The invitations, the newest first.
+
+- error: An error object if the query fails.
+*/
+func GetPendingStaffInvitations(ctx context.Context, landlordEmail string) ([]LandlordInvitation, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	SELECT i.id, i.email, i.account_role, l.email, i.expires_at, i.created_at
+	FROM lhp_landlord_invitations i
+	JOIN lhp_landlords l ON l.id = i.invited_by
+	WHERE l.email = $1 AND i.account_role = 'staff'
+		AND i.accepted_at IS NULL AND i.revoked_at IS NULL AND i.expires_at > NOW()
+	ORDER BY i.created_at DESC;
+	`
+	rows, err := db.QueryContext(ctx, query, landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get pending staff invitations: %s", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []LandlordInvitation
+	for rows.Next() {
+		var invitation LandlordInvitation
+		err := rows.Scan(
+			&invitation.ID,
+			&invitation.Email,
+			&invitation.AccountRole,
+			&invitation.InvitedBy,
+			&invitation.ExpiresAt,
+			&invitation.CreatedAt,
+		)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to scan invitation: %s", err.Error()))
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+/*
+RevokeStaffInvitation stops a staff invitation a landlord has sent from being accepted.
+
+Arguments:
+
+- landlordEmail: The email of the landlord who sent the invitation.
+
+- invitationId: The ID of the invitation.
+
+Returns:
+
+- error: sql.ErrNoRows if the landlord has no such invitation waiting to be accepted, or an error object if the query fails.
+*/
+func RevokeStaffInvitation(ctx context.Context, landlordEmail string, invitationId int) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+	UPDATE lhp_landlord_invitations
+	SET revoked_at = NOW()
+	WHERE id = $1 AND account_role = 'staff' AND accepted_at IS NULL AND revoked_at IS NULL
+		AND invited_by = (SELECT id FROM lhp_landlords WHERE email = $2);
+	`
+	result, err := db.ExecContext(ctx, query, invitationId, landlordEmail)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to revoke staff invitation: %s", err.Error()))
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Staff invitation %d revoked", invitationId))
+	return nil
+}
+
+// lockStaffAccount locks a staff member's account for the rest of the transaction, if they work for the landlord.
+// The staff member's email is stored in email when it is not nil.
+func lockStaffAccount(ctx context.Context, tx *sql.Tx, landlordEmail string, staffId int, email *string) error {
+	var staffEmail string
+	query := `
+	SELECT s.email
+	FROM lhp_landlords s
+	JOIN lhp_landlords l ON l.id = s.works_for
+	WHERE s.id = $1 AND s.account_role = 'staff' AND l.email = $2
+	FOR UPDATE OF s;
+	`
+	err := tx.QueryRowContext(ctx, query, staffId, landlordEmail).Scan(&staffEmail)
+	if err == sql.ErrNoRows {
+		return sql.ErrNoRows
+	}
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get staff account: %s", err.Error()))
+		return err
+	}
+	if email != nil {
+		*email = staffEmail
+	}
+	return nil
+}
+
+// isStaffPermission checks a permission is one a landlord can grant their staff.
+func isStaffPermission(permission string) bool {
+	for _, staffPermission := range StaffPermissions {
+		if permission == staffPermission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+)
+
+/*
+Store is everything the app reads from and writes to its database. Handlers and middleware are given a Store
+rather than calling the package functions, so they can be run against testutil.MockDB in tests.
+Every method takes the context of the request it is serving, so its queries stop when the request does.
+
+Postgres is the Store used by the app. Connecting to the database, migrating its schema and the retention scheduler
+are not part of Store, as they set up the Postgres database itself.
+*/
+type Store interface {
+	LandlordStore
+	InvitationStore
+	StaffStore
+	TenantStore
+	SessionStore
+	ApplicationStore
+	MessageStore
+	PropertyStore
+	LeaseStore
+	RentLedgerStore
+	MaintenanceStore
+	LoginThrottleStore
+	PasswordResetStore
+	TwoFactorStore
+	AuditStore
+	OutboxStore
+	OperatorStore
+	HealthStore
+}
+
+// LandlordStore reads and writes landlord accounts and what they can access.
+type LandlordStore interface {
+	CreateNewLandlord(ctx context.Context, landlordEmail, landlordPassword string) error
+	AuthenticateLandlord(ctx context.Context, email, password string) (bool, error)
+	GetLandlordIdByEmail(ctx context.Context, email string) (int, error)
+	CountLandlords(ctx context.Context) (int, error)
+	GetLandlordAccess(ctx context.Context, email string) (LandlordAccess, error)
+	GetLandlordAccounts(ctx context.Context) ([]LandlordAccount, error)
+	SetLandlordSuspended(ctx context.Context, landlordId int, suspended bool) error
+	RemoveLandlordAccount(ctx context.Context, landlordId int) error
+	LandlordOwnsResource(ctx context.Context, landlordEmail, resource string, id int) (bool, error)
+}
+
+// InvitationStore reads and writes the invitations the owner sends to new landlords.
+type InvitationStore interface {
+	CreateLandlordInvitation(ctx context.Context, invitedBy, email, accountRole string) (string, time.Time, error)
+	GetLandlordInvitation(ctx context.Context, token string) (LandlordInvitation, error)
+	AcceptLandlordInvitation(ctx context.Context, token, password string) (string, error)
+	GetPendingLandlordInvitations(ctx context.Context) ([]LandlordInvitation, error)
+	RevokeLandlordInvitation(ctx context.Context, invitationId int) error
+}
+
+// StaffStore reads and writes the staff accounts that work for a landlord.
+type StaffStore interface {
+	GetStaffAccounts(ctx context.Context, landlordEmail string) ([]StaffAccount, error)
+	SetStaffPermissions(ctx context.Context, landlordEmail string, staffId int, permissions []string) error
+	RemoveStaffAccount(ctx context.Context, landlordEmail string, staffId int) error
+	GetPendingStaffInvitations(ctx context.Context, landlordEmail string) ([]LandlordInvitation, error)
+	RevokeStaffInvitation(ctx context.Context, landlordEmail string, invitationId int) error
+}
+
+// TenantStore reads and writes tenant accounts.
+type TenantStore interface {
+	CreateNewTenant(ctx context.Context, landlordEmail string, roomId int, tenantEmail, tenantPassword, moveInDate, rentDue, monthlyRent, currency string) error
+	ManuallyCreateNewTenant(ctx context.Context, landlordEmail string, roomId int, tenantFullName, tenantPassportID, tenantEmail, moveInDate, rentDue, monthlyRent, currency string) error
+	AuthenticateTenant(ctx context.Context, username, password string) (bool, error)
+	UpdateTenantPassword(ctx context.Context, hashEmail, newPassword string) error
+	GetTenantNameByEmail(ctx context.Context, email string) (string, error)
+	GetTenantNameByHashEmail(ctx context.Context, email string) (string, error)
+	GetTenantIdByEmail(ctx context.Context, email string) (int, error)
+	GetTenantEncryptedEmailById(ctx context.Context, tenantId int) (string, error)
+	GetTenantInformationByHashEmail(ctx context.Context, hashEmail string) (GetTenantInformation, error)
+	GetTenantsByLandlordEmail(ctx context.Context, landlordEmail string) ([]LandlordTenants, error)
+	GetLandlordByTenantHashEmail(ctx context.Context, hashEmail string) (int, string, error)
+}
+
+// SessionStore starts, checks and ends the login sessions of landlords and tenants.
+type SessionStore interface {
+	StartLandlordSession(ctx context.Context, email string, rememberMe bool) (string, string, time.Time, error)
+	StartTenantSession(ctx context.Context, hashEmail string, rememberMe bool) (string, string, time.Time, error)
+	UpdateLandlordSessionTokens(ctx context.Context, email string) (string, string, time.Time, error)
+	UpdateTenantSessionTokens(ctx context.Context, hash_email string) (string, string, time.Time, error)
+	GetEmailFromLandlordSessionToken(ctx context.Context, sessionToken string) (string, error)
+	GetEmailFromTenantSessionToken(ctx context.Context, sessionToken string) (string, error)
+	GetHashedEmailFromTenantSessionToken(ctx context.Context, sessionToken string) (string, error)
+	GetCSRFTokenFromSessionToken(ctx context.Context, sessionToken string) (string, error)
+	ValidateLandlordSessionToken(ctx context.Context, email, sessionToken string) (bool, error)
+	ValidateTenantSessionToken(ctx context.Context, hashEmail, sessionToken string) (bool, error)
+	ValidateLandlordCSRFToken(ctx context.Context, email, csrfToken string) (bool, error)
+	ValidateTenantCSRFToken(ctx context.Context, hashEmail, csrfToken string) (bool, error)
+	LogoutLandlord(ctx context.Context, email string) error
+	LogoutTenant(ctx context.Context, hashEmail string) error
+}
+
+// ApplicationStore reads and writes tenancy applications.
+type ApplicationStore interface {
+	SaveTenantApplicationForm(
+		ctx context.Context,
+		propertyId int,
+		fullName,
+		dateOfBirth,
+		passportNumber,
+		phoneNumber,
+		email,
+		occupation,
+		employer,
+		employerNumber,
+		emergencyContactName,
+		emergencyContactNumber,
+		emergencyContactAddress,
+		ifEvicted,
+		evictedReason,
+		ifConvicted,
+		convictedReason,
+		smoke,
+		pets,
+		ifVehicle,
+		vehicleReg,
+		haveChildren,
+		children,
+		refusedRent,
+		refusedRentReason,
+		unstableIncome,
+		incomeReason string,
+	) error
+	GetAllTenantApplications(ctx context.Context, landlordEmail string) ([]GetLandlordApplications, error)
+	UpdateTenantApplicationStatus(ctx context.Context, id string, status string) error
+	GetTenantEmailAndPassportNumberViaApplicationID(ctx context.Context, id string) (string, string, error)
+	AcceptTenantApplication(ctx context.Context, landlordEmail string, acceptance TenantAcceptance) (bool, error)
+}
+
+// MessageStore reads and writes the messages between landlords and tenants.
+type MessageStore interface {
+	SendMessage(ctx context.Context, senderId int, senderType string, receiverID int, receiverType string, message string) error
+	GetMessageBetweenLandlordsAndTenant(ctx context.Context, landlordId int, tenantID string) ([]Message, error)
+}
+
+// PropertyStore reads and writes properties and their rooms.
+type PropertyStore interface {
+	CreateProperty(ctx context.Context, landlordEmail, name, address string) error
+	GetAllProperties(ctx context.Context) ([]Property, error)
+	GetPropertiesByLandlordEmail(ctx context.Context, landlordEmail string) ([]Property, error)
+	GetPropertyById(ctx context.Context, propertyId int) (Property, error)
+	GetPropertyLandlordEmail(ctx context.Context, propertyId int) (string, error)
+	PropertyBelongsToLandlord(ctx context.Context, propertyId int, landlordEmail string) (bool, error)
+	CreateRoom(ctx context.Context, landlordEmail string, propertyId int, unit, name, roomType string, capacity int, amenities, defaultRent, currency string) error
+	GetRoomsByLandlordEmail(ctx context.Context, landlordEmail string) ([]Room, error)
+	GetLandlordRoom(ctx context.Context, landlordEmail string, roomId int) (Room, error)
+}
+
+// LeaseStore reads and writes leases, renewals and notices.
+type LeaseStore interface {
+	CreateLease(ctx context.Context, tenantId int, startDate string, termMonths, breakClauseMonths, noticeDays int) error
+	GetLeaseByTenantId(ctx context.Context, tenantId int) (Lease, error)
+	GetLeasesByLandlordEmail(ctx context.Context, landlordEmail string) ([]LandlordLease, error)
+	OfferLeaseRenewal(ctx context.Context, leaseId, termMonths int, monthlyRent string) error
+	GetOpenRenewalOffer(ctx context.Context, leaseId int) (LeaseRenewal, error)
+	RespondToRenewalOffer(ctx context.Context, leaseId int, accept bool) error
+	ServeLeaseNotice(ctx context.Context, leaseId int, givenBy, noticeDate, moveOutDate string) error
+	EndLease(ctx context.Context, leaseId int) error
+}
+
+// RentLedgerStore reads and writes the rent charges and payments of tenants.
+type RentLedgerStore interface {
+	GenerateRentCharges(ctx context.Context, tenantId int) error
+	RecordRentPayment(ctx context.Context, tenantId int, amount, paidOn, method, reference string) error
+	GetRentLedgerByTenantId(ctx context.Context, tenantId int) ([]RentLedgerEntry, error)
+}
+
+// MaintenanceStore reads and writes maintenance tickets and their photos.
+type MaintenanceStore interface {
+	CreateMaintenanceTicket(ctx context.Context, tenantHashEmail, category, priority, description string, attachments []NewMaintenanceAttachment) (int, error)
+	GetMaintenanceTicketsByTenantHashEmail(ctx context.Context, tenantHashEmail string) ([]MaintenanceTicket, error)
+	GetMaintenanceTicketsByLandlordEmail(ctx context.Context, landlordEmail string) ([]MaintenanceTicket, error)
+	GetLandlordMaintenanceTicket(ctx context.Context, landlordEmail string, ticketId int) (MaintenanceTicket, error)
+	UpdateMaintenanceTicket(ctx context.Context, landlordEmail string, ticketId int, status, priority, scheduledFor, note string) error
+	GetTenantMaintenanceAttachment(ctx context.Context, tenantHashEmail string, attachmentId int) (MaintenanceAttachment, error)
+	GetLandlordMaintenanceAttachment(ctx context.Context, landlordEmail string, attachmentId int) (MaintenanceAttachment, error)
+}
+
+// LoginThrottleStore counts failed logins and locks accounts.
+type LoginThrottleStore interface {
+	GetLoginLockout(ctx context.Context, userType, userKey, ip string) (time.Time, error)
+	RecordLoginFailure(ctx context.Context, userType, userKey, ip string) (LoginFailure, error)
+	ClearLoginFailures(ctx context.Context, userType, userKey string) error
+	GetLockedTenantAccounts(ctx context.Context, landlordEmail string) ([]LockedAccount, error)
+	UnlockTenantAccount(ctx context.Context, landlordEmail string, tenantId int) error
+	GetLockedLandlordAccounts(ctx context.Context) ([]LockedLandlordAccount, error)
+	UnlockLandlordAccount(ctx context.Context, landlordId int) error
+	GetLockedStaffAccounts(ctx context.Context, landlordEmail string) ([]LockedLandlordAccount, error)
+	UnlockStaffAccount(ctx context.Context, landlordEmail string, staffId int) error
+}
+
+// PasswordResetStore issues and redeems password reset links.
+type PasswordResetStore interface {
+	CreatePasswordReset(ctx context.Context, userType, userKey string) (string, error)
+	ValidatePasswordResetToken(ctx context.Context, userType, token string) error
+	ResetPassword(ctx context.Context, userType, token, newPassword string) error
+}
+
+// TwoFactorStore sets up and checks two-factor authentication.
+type TwoFactorStore interface {
+	GetTwoFactorStatus(ctx context.Context, userType, userKey string) (TwoFactorStatus, error)
+	BeginTwoFactorSetup(ctx context.Context, userType, userKey string) (string, error)
+	ConfirmTwoFactorSetup(ctx context.Context, userType, userKey, code string) ([]string, error)
+	DisableTwoFactor(ctx context.Context, userType, userKey, code string) error
+	StartTwoFactorLogin(ctx context.Context, userType, userKey string, rememberMe bool) (string, error)
+	CompleteTwoFactorLogin(ctx context.Context, userType, token, code string) (string, bool, error)
+}
+
+// AuditStore adds to and reads the audit log.
+type AuditStore interface {
+	RecordAudit(ctx context.Context, entry AuditEntry) error
+	GetAuditLog(ctx context.Context, landlordEmail string, filter AuditFilter, limit int) ([]AuditEntry, error)
+	VerifyAuditLog(ctx context.Context) (int64, error)
+	GetTenantAuditIdentity(ctx context.Context, hashEmail string) (int, string, error)
+}
+
+// OutboxStore hands out the emails waiting in the outbox and records whether they were sent.
+type OutboxStore interface {
+	ClaimOutboxEmails(ctx context.Context, limit int) ([]OutboxEmail, error)
+	CompleteOutboxEmail(ctx context.Context, id int64) error
+	RetryOutboxEmail(ctx context.Context, id int64, sendErr string, retryAt time.Time) error
+	AbandonOutboxEmail(ctx context.Context, id int64, sendErr string) error
+}
+
+// OperatorStore runs the jobs operators start from the command line.
+type OperatorStore interface {
+	ExportDataSubject(ctx context.Context, email string) (SubjectExport, error)
+	EraseDataSubject(ctx context.Context, email string) (SubjectErasure, error)
+	ApplyRetention(ctx context.Context, policy config.RetentionPolicy, dryRun bool) ([]RetentionResult, error)
+	ReencryptAll(ctx context.Context, batchSize int) error
+}
+
+// HealthStore checks the database can be reached.
+type HealthStore interface {
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+)
+
+// queryCanceled is the PostgreSQL error code for a statement cancelled because its deadline passed
+const queryCanceled = "57014"
+
+// withQueryTimeout gives a store function config.Database.QueryTimeout to run its queries in, or less if ctx ends sooner.
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, config.Database.QueryTimeout)
+}
+
+/*
+IsTimeout reports whether a store function failed because its queries ran out of time, either waiting for a
+connection from the pool or waiting for PostgreSQL to answer. Handlers answer these with 503 Service Unavailable,
+as the request may succeed when the database is less busy.
+
+Arguments:
+
+- err: The error returned by a store function.
+
+Returns:
+
+- bool: True if the error is a timeout.
+*/
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == queryCanceled
+}
@@ -0,0 +1,514 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+const (
+	twoFactorLoginLifetime = 5 * time.Minute // how long a user has to enter their code after their password
+	twoFactorLoginAttempts = 5               // wrong codes allowed before the user has to enter their password again
+)
+
+/*
+GetTwoFactorStatus gets the two-factor authentication settings of a landlord or tenant.
+
+Arguments:
+
+- userType: Either "landlord" or "tenant".
+
+- userKey: The landlord's email, or the tenant's hashed email.
+
+Returns:
+
+- TwoFactorStatus: Whether two-factor authentication is on, and the decrypted secret while it is being set up.
+
+- error: An error object if the settings cannot be read.
+*/
+func GetTwoFactorStatus(ctx context.Context, userType, userKey string) (TwoFactorStatus, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return TwoFactorStatus{}, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var status TwoFactorStatus
+	var encryptSecret []byte
+	var enabledAt sql.NullTime
+	query := `
+	SELECT encrypt_secret, enabled_at
+	FROM lhp_two_factor
+	WHERE user_type = $1 AND user_key = $2;
+	`
+	err := db.QueryRowContext(ctx, query, userType, userKey).Scan(&encryptSecret, &enabledAt)
+	if err == sql.ErrNoRows {
+		return status, nil
+	}
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get two-factor status: %s", err.Error()))
+		return status, err
+	}
+
+	if enabledAt.Valid {
+		status.Enabled = true
+		status.EnabledAt = enabledAt.Time
+
+		query = `
+		SELECT COUNT(*)
+		FROM lhp_two_factor_recovery_codes
+		WHERE user_type = $1 AND user_key = $2 AND used_at IS NULL;
+		`
+		err = db.QueryRowContext(ctx, query, userType, userKey).Scan(&status.RecoveryCodesLeft)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to count recovery codes: %s", err.Error()))
+			return status, err
+		}
+		return status, nil
+	}
+
+	// the secret is only shown while the user is adding it to their authenticator app
+	secret, err := utils.Decrypt(encryptSecret)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to decrypt two-factor secret: %s", err.Error()))
+		return status, err
+	}
+	status.PendingSecret = string(secret)
+	return status, nil
+}
+
+/*
+BeginTwoFactorSetup creates a new TOTP secret for a landlord or tenant who is turning on two-factor authentication.
+The secret is stored encrypted, and is not used at login until the user confirms it with a code from their app.
+
+Arguments:
+
+- userType: Either "landlord" or "tenant".
+
+- userKey: The landlord's email, or the tenant's hashed email.
+
+Returns:
+
+- string: The new base32 encoded TOTP secret.
+
+- error: An error object if two-factor authentication is already on or the secret cannot be stored.
+*/
+func BeginTwoFactorSetup(ctx context.Context, userType, userKey string) (string, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return "", errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	encryptSecret, err := utils.Encrypt([]byte(secret))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to encrypt two-factor secret: %s", err.Error()))
+		return "", err
+	}
+
+	// starting again replaces a secret that was never confirmed, but never one that is in use
+	query := `
+	INSERT INTO lhp_two_factor (user_type, user_key, encrypt_secret, created_at)
+	VALUES ($1, $2, $3, NOW())
+	ON CONFLICT (user_type, user_key) DO UPDATE
+	SET encrypt_secret = EXCLUDED.encrypt_secret, last_used_step = 0, created_at = NOW()
+	WHERE lhp_two_factor.enabled_at IS NULL;
+	`
+	result, err := db.ExecContext(ctx, query, userType, userKey, encryptSecret)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to store two-factor secret: %s", err.Error()))
+		return "", err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if rows == 0 {
+		return "", errors.New("two-factor authentication is already turned on")
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Two-factor setup started for %s", userType))
+	return secret, nil
+}
+
+/*
+ConfirmTwoFactorSetup turns on two-factor authentication once the user has entered a code from their authenticator app,
+which proves the app has the secret. New recovery codes are created at the same time and replace any old ones.
+
+Arguments:
+
+- userType: Either "landlord" or "tenant".
+
+- userKey: The landlord's email, or the tenant's hashed email.
+
+- code: The code from the user's authenticator app.
+
+Returns:
+
+- []string: The recovery codes, to show to the user once. Only their hashes are stored.
+
+- error: ErrInvalidTwoFactorCode if the code is wrong, or an error object if two-factor authentication cannot be turned on.
+*/
+func ConfirmTwoFactorSetup(ctx context.Context, userType, userKey, code string) ([]string, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var encryptSecret []byte
+	query := `
+	SELECT encrypt_secret
+	FROM lhp_two_factor
+	WHERE user_type = $1 AND user_key = $2 AND enabled_at IS NULL
+	FOR UPDATE;
+	`
+	err = tx.QueryRowContext(ctx, query, userType, userKey).Scan(&encryptSecret)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("two-factor setup has not been started")
+	}
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get two-factor secret: %s", err.Error()))
+		return nil, err
+	}
+
+	secret, err := utils.Decrypt(encryptSecret)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to decrypt two-factor secret: %s", err.Error()))
+		return nil, err
+	}
+	step, ok := utils.ValidateTOTP(string(secret), code, time.Now(), 0)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	query = `
+	UPDATE lhp_two_factor
+	SET enabled_at = NOW(), last_used_step = $1
+	WHERE user_type = $2 AND user_key = $3;
+	`
+	_, err = tx.ExecContext(ctx, query, step, userType, userKey)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to turn on two-factor authentication: %s", err.Error()))
+		return nil, err
+	}
+
+	recoveryCodes, err := replaceRecoveryCodes(ctx, tx, userType, userKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit two-factor setup: %s", err.Error()))
+		return nil, err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Two-factor authentication turned on for %s", userType))
+	return recoveryCodes, nil
+}
+
+/*
+DisableTwoFactor turns off two-factor authentication and removes the user's secret and recovery codes.
+The user must enter a current code (or a recovery code), so a stolen session alone cannot turn it off.
+
+Arguments:
+
+- userType: Either "landlord" or "tenant".
+
+- userKey: The landlord's email, or the tenant's hashed email.
+
+- code: A code from the user's authenticator app, or one of their recovery codes.
+
+Returns:
+
+- error: ErrInvalidTwoFactorCode if the code is wrong, or an error object if two-factor authentication cannot be turned off.
+*/
+func DisableTwoFactor(ctx context.Context, userType, userKey, code string) error {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return err
+	}
+	defer tx.Rollback()
+
+	ok, err := checkTwoFactorCode(ctx, tx, userType, userKey, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM lhp_two_factor WHERE user_type = $1 AND user_key = $2;`, userType, userKey)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to turn off two-factor authentication: %s", err.Error()))
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM lhp_two_factor_recovery_codes WHERE user_type = $1 AND user_key = $2;`, userType, userKey)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to delete recovery codes: %s", err.Error()))
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit turning off two-factor authentication: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logDb, fmt.Sprintf("Two-factor authentication turned off for %s", userType))
+	return nil
+}
+
+/*
+StartTwoFactorLogin records a login that has passed the password check and is waiting for the user's two-factor code.
+No session is started until the code has been checked by CompleteTwoFactorLogin.
+
+Arguments:
+
+- userType: Either "landlord" or "tenant".
+
+- userKey: The landlord's email, or the tenant's hashed email.
+
+- rememberMe: Whether the user asked to stay logged in, which is applied once the login is complete.
+
+Returns:
+
+- string: A token identifying the login, kept in a short-lived cookie. Only its hash is stored.
+
+- error: An error object if the login cannot be stored.
+*/
+func StartTwoFactorLogin(ctx context.Context, userType, userKey string, rememberMe bool) (string, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return "", errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	token, err := utils.GenerateToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	query := `
+	INSERT INTO lhp_two_factor_challenges (user_type, user_key, token_hash, remember_me, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, NOW());
+	`
+	_, err = db.ExecContext(ctx, query, userType, userKey, utils.HashData(token), rememberMe, time.Now().Add(twoFactorLoginLifetime))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start two-factor login: %s", err.Error()))
+		return "", err
+	}
+	return token, nil
+}
+
+/*
+CompleteTwoFactorLogin checks the code entered in the second step of a login.
+A code from the user's authenticator app can only be used once, and a recovery code is used up when it is entered.
+After too many wrong codes the login is ended and the user has to enter their password again.
+
+Arguments:
+
+- userType: Either "landlord" or "tenant".
+
+- token: The token from the two-factor login cookie.
+
+- code: A code from the user's authenticator app, or one of their recovery codes.
+
+Returns:
+
+- string: The landlord's email, or the tenant's hashed email, to start the session for.
+It is also returned with ErrInvalidTwoFactorCode, so the wrong code can be counted as a failed login.
+
+- bool: Whether the user asked to stay logged in.
+
+- error: ErrInvalidTwoFactorCode if the code is wrong, ErrInvalidTwoFactorLogin if the login is unknown, used or expired,
+or an error object if the code cannot be checked.
+*/
+func CompleteTwoFactorLogin(ctx context.Context, userType, token, code string) (string, bool, error) {
+	if db == nil {
+		logs.Logs(logDbErr, "Database connection is not initialized")
+		return "", false, errors.New("database connection is not initialized")
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to start transaction: %s", err.Error()))
+		return "", false, err
+	}
+	defer tx.Rollback()
+
+	var challengeId int
+	var userKey string
+	var rememberMe bool
+	query := `
+	SELECT id, user_key, remember_me
+	FROM lhp_two_factor_challenges
+	WHERE user_type = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > NOW() AND attempts < $3
+	FOR UPDATE;
+	`
+	err = tx.QueryRowContext(ctx, query, userType, utils.HashData(token), twoFactorLoginAttempts).Scan(&challengeId, &userKey, &rememberMe)
+	if err == sql.ErrNoRows {
+		return "", false, ErrInvalidTwoFactorLogin
+	}
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get two-factor login: %s", err.Error()))
+		return "", false, err
+	}
+
+	ok, err := checkTwoFactorCode(ctx, tx, userType, userKey, code)
+	if err != nil {
+		return "", false, err
+	}
+
+	// a wrong code counts against the login, a right one ends it so the token cannot be used again
+	if ok {
+		query = `UPDATE lhp_two_factor_challenges SET used_at = NOW() WHERE id = $1;`
+	} else {
+		query = `UPDATE lhp_two_factor_challenges SET attempts = attempts + 1 WHERE id = $1;`
+	}
+	_, err = tx.ExecContext(ctx, query, challengeId)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to update two-factor login: %s", err.Error()))
+		return "", false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to commit two-factor login: %s", err.Error()))
+		return "", false, err
+	}
+
+	if !ok {
+		logs.Logs(logWarning, fmt.Sprintf("Wrong two-factor code entered for %s", userType))
+		return userKey, false, ErrInvalidTwoFactorCode
+	}
+	return userKey, rememberMe, nil
+}
+
+/*
+checkTwoFactorCode checks a code against a user's TOTP secret and, failing that, their unused recovery codes.
+The user's two-factor row is locked so the same TOTP code cannot be accepted twice by requests running at the same time.
+A matching TOTP code moves last_used_step forward and a matching recovery code is marked as used.
+*/
+func checkTwoFactorCode(ctx context.Context, tx *sql.Tx, userType, userKey, code string) (bool, error) {
+	var encryptSecret []byte
+	var lastUsedStep int64
+	query := `
+	SELECT encrypt_secret, last_used_step
+	FROM lhp_two_factor
+	WHERE user_type = $1 AND user_key = $2 AND enabled_at IS NOT NULL
+	FOR UPDATE;
+	`
+	err := tx.QueryRowContext(ctx, query, userType, userKey).Scan(&encryptSecret, &lastUsedStep)
+	if err == sql.ErrNoRows {
+		return false, errors.New("two-factor authentication is not turned on")
+	}
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to get two-factor secret: %s", err.Error()))
+		return false, err
+	}
+
+	secret, err := utils.Decrypt(encryptSecret)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to decrypt two-factor secret: %s", err.Error()))
+		return false, err
+	}
+
+	step, ok := utils.ValidateTOTP(string(secret), code, time.Now(), lastUsedStep)
+	if ok {
+		query = `
+		UPDATE lhp_two_factor
+		SET last_used_step = $1
+		WHERE user_type = $2 AND user_key = $3;
+		`
+		_, err = tx.ExecContext(ctx, query, step, userType, userKey)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to update two-factor step: %s", err.Error()))
+			return false, err
+		}
+		return true, nil
+	}
+
+	query = `
+	UPDATE lhp_two_factor_recovery_codes
+	SET used_at = NOW()
+	WHERE user_type = $1 AND user_key = $2 AND code_hash = $3 AND used_at IS NULL;
+	`
+	result, err := tx.ExecContext(ctx, query, userType, userKey, utils.HashData(utils.NormaliseRecoveryCode(code)))
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to use recovery code: %s", err.Error()))
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows > 0 {
+		logs.Logs(logDb, fmt.Sprintf("Recovery code used for %s", userType))
+	}
+	return rows > 0, nil
+}
+
+// replaceRecoveryCodes deletes a user's recovery codes and stores the hashes of a new set, returning the new codes.
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userType, userKey string) ([]string, error) {
+	_, err := tx.ExecContext(ctx, `DELETE FROM lhp_two_factor_recovery_codes WHERE user_type = $1 AND user_key = $2;`, userType, userKey)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Failed to delete recovery codes: %s", err.Error()))
+		return nil, err
+	}
+
+	recoveryCodes, err := utils.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	INSERT INTO lhp_two_factor_recovery_codes (user_type, user_key, code_hash, created_at)
+	VALUES ($1, $2, $3, NOW());
+	`
+	for _, code := range recoveryCodes {
+		_, err = tx.ExecContext(ctx, query, userType, userKey, utils.HashData(code))
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Failed to store recovery code: %s", err.Error()))
+			return nil, err
+		}
+	}
+	return recoveryCodes, nil
+}
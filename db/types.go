@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"time"
 )
 
@@ -10,9 +11,137 @@ const (
 	logErr     = 3
 	logDb      = 4
 	logDbErr   = 5
+
+	RentCharge  = "charge"
+	RentPayment = "payment"
+
+	LeaseActive = "active"
+	LeaseNotice = "notice"
+	LeaseEnded  = "ended"
+
+	RenewalOffered   = "offered"
+	RenewalAccepted  = "accepted"
+	RenewalDeclined  = "declined"
+	RenewalWithdrawn = "withdrawn"
+
+	TicketOpen       = "open"
+	TicketScheduled  = "scheduled"
+	TicketInProgress = "in_progress"
+	TicketResolved   = "resolved"
+
+	LandlordRoleOwner    = "owner"
+	LandlordRoleLandlord = "landlord"
+	LandlordRoleStaff    = "staff"
+
+	PermissionViewApplications   = "view_applications"
+	PermissionDecideApplications = "decide_applications"
+	PermissionMessageTenants     = "message_tenants"
+	PermissionViewFinancials     = "view_financials"
+	PermissionRevealPII          = "reveal_pii"
+	PermissionManageTenants      = "manage_tenants"
+	PermissionManageProperties   = "manage_properties"
+	PermissionManageMaintenance  = "manage_maintenance"
+
+	AuditActorTenant   = "tenant"   // landlord dashboard accounts are recorded by their account role
+	AuditActorOperator = "operator" // actions run from the command line by whoever operates the site
+	AuditActorSystem   = "system"   // actions the app takes by itself, such as purging records past their retention period
+
+	AuditApplicationViewed     = "application.viewed"
+	AuditApplicationAccepted   = "application.accepted"
+	AuditApplicationDenied     = "application.denied"
+	AuditTenantPasswordChanged = "tenant.password_changed"
+	AuditTenantUnlocked        = "tenant.unlocked"
+	AuditTwoFactorEnabled      = "two_factor.enabled"
+	AuditTwoFactorDisabled     = "two_factor.disabled"
+	AuditStaffInvited          = "staff.invited"
+	AuditStaffInviteRevoked    = "staff.invitation_revoked"
+	AuditStaffPermissions      = "staff.permissions_changed"
+	AuditStaffRemoved          = "staff.removed"
+	AuditStaffUnlocked         = "staff.unlocked"
+	AuditAccountInvited        = "account.invited"
+	AuditAccountInviteRevoked  = "account.invitation_revoked"
+	AuditAccountSuspended      = "account.suspended"
+	AuditAccountReinstated     = "account.reinstated"
+	AuditAccountRemoved        = "account.removed"
+	AuditAccountUnlocked       = "account.unlocked"
+	AuditLogExported           = "audit.exported"
+	AuditAccessDenied          = "access.denied"
+	AuditSubjectExported       = "subject.exported"
+	AuditSubjectErased         = "subject.erased"
+	AuditRetentionPurged       = "retention.purged"
+
+	RetentionDeniedApplications = "denied_applications"
+	RetentionEndedTenancies     = "ended_tenancies"
+	RetentionMessages           = "messages"
+
+	// kinds of email sent through the outbox
+	OutboxTenantNewAccount   = "tenant_new_account"
+	OutboxLandlordNewAccount = "landlord_new_account"
+	OutboxTenantTicketUpdate = "tenant_ticket_update"
+
+	// records a landlord route can take the ID of, also used as the audit log's target types
+	ResourceTenant            = "tenant"
+	ResourceApplication       = "application"
+	ResourceProperty          = "property"
+	ResourceMaintenanceTicket = "maintenance_ticket"
+	ResourceMaintenancePhoto  = "maintenance_photo"
 )
 
+// AuditActions lists the actions recorded in the audit log, in the order they are offered as filters
+var AuditActions = []string{
+	AuditApplicationViewed,
+	AuditApplicationAccepted,
+	AuditApplicationDenied,
+	AuditTenantPasswordChanged,
+	AuditTenantUnlocked,
+	AuditTwoFactorEnabled,
+	AuditTwoFactorDisabled,
+	AuditStaffInvited,
+	AuditStaffInviteRevoked,
+	AuditStaffPermissions,
+	AuditStaffRemoved,
+	AuditStaffUnlocked,
+	AuditAccountInvited,
+	AuditAccountInviteRevoked,
+	AuditAccountSuspended,
+	AuditAccountReinstated,
+	AuditAccountRemoved,
+	AuditAccountUnlocked,
+	AuditLogExported,
+	AuditAccessDenied,
+	AuditSubjectExported,
+	AuditSubjectErased,
+	AuditRetentionPurged,
+}
+
+// StaffPermissions lists the permissions a landlord can grant their staff, in the order they are shown
+var StaffPermissions = []string{
+	PermissionViewApplications,
+	PermissionDecideApplications,
+	PermissionMessageTenants,
+	PermissionViewFinancials,
+	PermissionRevealPII,
+	PermissionManageTenants,
+	PermissionManageProperties,
+	PermissionManageMaintenance,
+}
+
 var (
+	ErrRoomFull              = errors.New("room is fully occupied")                        // returned when a tenant is given a room with no space left
+	ErrInvalidResetToken     = errors.New("password reset link is invalid or has expired") // returned when a reset token is unknown, used or expired
+	ErrInvalidTwoFactorCode  = errors.New("two-factor code is incorrect")                  // returned when a TOTP or recovery code does not match
+	ErrInvalidTwoFactorLogin = errors.New("two-factor login has expired")                  // returned when a login waiting for its second step is unknown, used or expired
+	ErrInvalidInvitation     = errors.New("invitation is invalid or has expired")          // returned when an invitation token is unknown, accepted, revoked or expired
+	ErrLandlordExists        = errors.New("landlord account already exists")               // returned when an invitation is for an email that already has an account
+	ErrLandlordSuspended     = errors.New("landlord account is suspended")                 // returned when a suspended landlord logs in with the right password
+	ErrLandlordHasRecords    = errors.New("landlord account has records")                  // returned when removing an account that still has tenants, properties, messages or staff
+	ErrInvalidPermission     = errors.New("permission cannot be granted to staff")         // returned when granting a permission that is not in StaffPermissions
+	ErrUnknownResource       = errors.New("resource type cannot be checked for ownership") // returned when checking ownership of a type of record with no ownership query
+	ErrTenancyActive         = errors.New("tenant still has an active tenancy")            // returned when erasing a tenant whose lease has not ended
+	ErrUnknownMigration      = errors.New("migration is not known to this version")        // returned when rolling back a migration applied by a newer version of the app
+	ErrApplicationDecided    = errors.New("application has already been decided")          // returned when accepting an application that was denied, or deciding one that is no longer pending
+	ErrSubjectNotAudited     = errors.New("erasure was not recorded in the audit log")     // returned alongside a data subject erasure that was committed but not audited
+
 	db *sql.DB // global DB variable to hold DB connection
 )
 
@@ -44,9 +173,39 @@ type GetLandlordApplications struct {
 	RefusedReason           []byte `json:"refused_reason"`
 	UnstableIncome          []byte `json:"unstable_income"`
 	UnstableReason          []byte `json:"unstable_reason"`
+	PropertyName            string `json:"property_name"`
 	CreatedAt               string `json:"created_at"`
 }
 
+type Property struct {
+	ID         int
+	LandlordID int
+	Name       string
+	Address    string
+}
+
+type Room struct {
+	ID           int
+	PropertyID   int
+	PropertyName string
+	Unit         string
+	Name         string
+	RoomType     string
+	Capacity     int
+	Amenities    string
+	DefaultRent  string
+	Currency     string
+	Occupants    int // current tenants living in the room
+}
+
+// Vacancies returns the number of tenants the room still has space for.
+func (room Room) Vacancies() int {
+	if room.Occupants >= room.Capacity {
+		return 0
+	}
+	return room.Capacity - room.Occupants
+}
+
 type GetTenantInformation struct {
 	Email       []byte `json:"encrypt_email"`
 	RoomType    []byte `json:"encrypt_room_type"`
@@ -59,6 +218,7 @@ type GetTenantInformation struct {
 type LandlordTenants struct {
 	ID                int    `json:"id"`
 	EncryptTenantName []byte `json:"encrypt_tenant_name"`
+	Currency          string `json:"currency"`
 }
 
 type Message struct {
@@ -72,3 +232,275 @@ type Message struct {
 	Message        string
 	SentAt         time.Time
 }
+
+type RentLedgerEntry struct {
+	ID               int       `json:"id"`
+	TenantID         int       `json:"tenant_id"`
+	EntryType        string    `json:"entry_type"`
+	Period           string    `json:"period"`
+	EntryDate        time.Time `json:"entry_date"`
+	EncryptAmount    []byte    `json:"encrypt_amount"`
+	EncryptMethod    []byte    `json:"encrypt_method"`
+	EncryptReference []byte    `json:"encrypt_reference"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type Lease struct {
+	ID                int            `json:"id"`
+	TenantID          int            `json:"tenant_id"`
+	Status            string         `json:"status"`
+	StartDate         time.Time      `json:"start_date"`
+	EndDate           time.Time      `json:"end_date"`
+	TermMonths        int            `json:"term_months"`
+	BreakClauseMonths int            `json:"break_clause_months"`
+	NoticeDays        int            `json:"notice_days"`
+	NoticeGivenBy     sql.NullString `json:"notice_given_by"`
+	NoticeDate        sql.NullTime   `json:"notice_date"`
+	MoveOutDate       sql.NullTime   `json:"move_out_date"`
+	EndedAt           sql.NullTime   `json:"ended_at"`
+}
+
+type LeaseRenewal struct {
+	ID                 int          `json:"id"`
+	LeaseID            int          `json:"lease_id"`
+	Status             string       `json:"status"`
+	TermMonths         int          `json:"term_months"`
+	EncryptMonthlyRent []byte       `json:"encrypt_monthly_rent"`
+	OfferedAt          time.Time    `json:"offered_at"`
+	RespondedAt        sql.NullTime `json:"responded_at"`
+}
+
+type LandlordLease struct {
+	TenantID          int           `json:"tenant_id"`
+	EncryptTenantName []byte        `json:"encrypt_tenant_name"`
+	Lease             *Lease        `json:"lease"`
+	OpenRenewal       *LeaseRenewal `json:"open_renewal"`
+}
+
+type MaintenanceTicket struct {
+	ID                 int                     `json:"id"`
+	TenantID           int                     `json:"tenant_id"`
+	EncryptTenantName  []byte                  `json:"encrypt_tenant_name"`
+	PropertyName       string                  `json:"property_name"`
+	RoomName           string                  `json:"room_name"`
+	Category           string                  `json:"category"`
+	Priority           string                  `json:"priority"`
+	EncryptDescription []byte                  `json:"encrypt_description"`
+	Status             string                  `json:"status"`
+	ScheduledFor       sql.NullTime            `json:"scheduled_for"`
+	CreatedAt          time.Time               `json:"created_at"`
+	UpdatedAt          time.Time               `json:"updated_at"`
+	Attachments        []MaintenanceAttachment `json:"attachments"`
+	Events             []MaintenanceEvent      `json:"events"`
+}
+
+type MaintenanceAttachment struct {
+	ID          int       `json:"id"`
+	TicketID    int       `json:"ticket_id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	EncryptData []byte    `json:"encrypt_data"` // only loaded when the attachment itself is requested
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NewMaintenanceAttachment is a photo uploaded with a new maintenance ticket, before it is encrypted and stored.
+type NewMaintenanceAttachment struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+type MaintenanceEvent struct {
+	ID            int            `json:"id"`
+	TicketID      int            `json:"ticket_id"`
+	FromStatus    sql.NullString `json:"from_status"`
+	ToStatus      string         `json:"to_status"`
+	ChangedByType string         `json:"changed_by_type"`
+	ChangedByID   int            `json:"changed_by_id"`
+	EncryptNote   []byte         `json:"encrypt_note"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// TwoFactorStatus describes a user's two-factor authentication settings.
+type TwoFactorStatus struct {
+	Enabled           bool      // two-factor authentication is on and is checked at login
+	EnabledAt         time.Time // when it was turned on
+	RecoveryCodesLeft int       // unused recovery codes
+	PendingSecret     string    // the decrypted secret while setup has been started but not confirmed
+}
+
+// LoginFailure is the result of recording a failed login.
+type LoginFailure struct {
+	Failures    int       // failed logins in a row on the account
+	LockedUntil time.Time // when the next login can be tried, the later of the account and IP address waits
+	SendAlert   bool      // the account owner should be emailed, which only happens once until they log in
+}
+
+// LockedAccount is a tenant account that cannot log in until its lock expires or a landlord unlocks it.
+type LockedAccount struct {
+	TenantID          int       `json:"tenant_id"`
+	EncryptTenantName []byte    `json:"encrypt_tenant_name"`
+	Failures          int       `json:"failures"`
+	LockedUntil       time.Time `json:"locked_until"`
+}
+
+// LockedLandlordAccount is a landlord dashboard account that cannot log in until its lock expires or it is unlocked,
+// by the owner on the admin page, or by the landlord a staff member works for on the staff page.
+type LockedLandlordAccount struct {
+	ID          int       `json:"id"`
+	Email       string    `json:"email"`
+	AccountRole string    `json:"account_role"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// LandlordAccount is an account that can log in to the landlord dashboard, as listed on the owner's admin page.
+type LandlordAccount struct {
+	ID          int          `json:"id"`
+	Email       string       `json:"email"`
+	AccountRole string       `json:"account_role"`
+	WorksFor    string       `json:"works_for"` // the email of the landlord a staff member works for, empty for other accounts
+	CreatedAt   time.Time    `json:"created_at"`
+	SuspendedAt sql.NullTime `json:"suspended_at"`
+}
+
+// LandlordAccess is what a logged in landlord dashboard account can see and do.
+type LandlordAccess struct {
+	AccountRole   string   // LandlordRoleOwner, LandlordRoleLandlord or LandlordRoleStaff
+	LandlordEmail string   // the landlord whose records the account works with, the account's own email unless it is staff
+	Permissions   []string // what a staff member has been granted, empty for other accounts
+}
+
+// StaffAccount is a staff member working for a landlord, with the permissions the landlord has granted them.
+type StaffAccount struct {
+	ID          int          `json:"id"`
+	Email       string       `json:"email"`
+	CreatedAt   time.Time    `json:"created_at"`
+	SuspendedAt sql.NullTime `json:"suspended_at"`
+	Permissions []string     `json:"permissions"`
+}
+
+// AuditEntry is one entry in the append-only audit log of sensitive actions.
+type AuditEntry struct {
+	ID            int64     `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	ActorType     string    `json:"actor_type"`     // the landlord account role, or AuditActorTenant
+	Actor         string    `json:"actor"`          // the landlord account's email, or the tenant's ID
+	LandlordEmail string    `json:"landlord_email"` // the landlord whose records the action concerns, who can see the entry
+	Action        string    `json:"action"`         // one of AuditActions
+	TargetType    string    `json:"target_type"`
+	TargetID      string    `json:"target_id"`
+	Details       string    `json:"details"`
+	IPAddress     string    `json:"ip_address"`
+	UserAgent     string    `json:"user_agent"`
+	PrevHash      string    `json:"prev_hash"`
+	Hash          string    `json:"hash"`
+}
+
+// SubjectRecord is one row of personal data held about a data subject, by column name, with encrypted columns decrypted.
+type SubjectRecord map[string]any
+
+// SubjectAttachment is a maintenance photo uploaded by a data subject, decrypted.
+type SubjectAttachment struct {
+	ID          int    `json:"id"`
+	TicketID    int    `json:"ticket_id"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	File        string `json:"file"` // where the photo is in the ZIP bundle
+	Data        []byte `json:"-"`
+}
+
+// SubjectExport is everything held about a data subject (a tenant or applicant), for a subject access request.
+type SubjectExport struct {
+	GeneratedAt    time.Time                  `json:"generated_at"`
+	TenantIDs      []int                      `json:"tenant_ids"`
+	ApplicationIDs []int                      `json:"application_ids"`
+	Records        map[string][]SubjectRecord `json:"records"` // rows by table name
+	Attachments    []SubjectAttachment        `json:"attachments"`
+}
+
+// SubjectErasure is what was erased for a data subject, and what was kept.
+type SubjectErasure struct {
+	TenantIDs          []int `json:"tenant_ids"`
+	ApplicationIDs     []int `json:"application_ids"`
+	MessagesDeleted    int64 `json:"messages_deleted"`
+	AttachmentsDeleted int64 `json:"attachments_deleted"`
+}
+
+// RetentionResult is what one retention rule purged, or in a dry run would have purged.
+type RetentionResult struct {
+	Rule       string           `json:"rule"`        // one of the Retention constants
+	Cutoff     time.Time        `json:"cutoff"`      // records older than this are purged
+	DryRun     bool             `json:"dry_run"`     // true if nothing was purged
+	ByLandlord map[string][]int `json:"by_landlord"` // the IDs of the records by the email of the landlord they belonged to
+}
+
+// AuditFilter narrows down the audit log entries shown to a landlord. Empty fields do not filter.
+type AuditFilter struct {
+	Actor    string    // part of the actor's email or tenant ID
+	Action   string    // one of AuditActions
+	TargetID string    // the exact target ID
+	From     time.Time // entries on or after this time
+	To       time.Time // entries before this time
+}
+
+// LandlordInvitation is an emailed invitation to create a landlord account.
+type LandlordInvitation struct {
+	ID          int       `json:"id"`
+	Email       string    `json:"email"`
+	AccountRole string    `json:"account_role"`
+	InvitedBy   string    `json:"invited_by"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// MigrationState is whether one schema migration has been applied to the database.
+type MigrationState struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`       // empty for a migration applied by a newer version of the app
+	Applied   bool      `json:"applied"`    // true if the migration is recorded in schema_migrations
+	AppliedAt time.Time `json:"applied_at"` // when the migration was applied, if it has been
+}
+
+// TenantAcceptance is what a landlord decides when accepting a tenancy application.
+type TenantAcceptance struct {
+	ApplicationID     string
+	RoomID            int
+	MoveInDate        string // in the format YYYY-MM-DD, also the start of the lease
+	RentDue           string
+	MonthlyRent       string
+	Currency          string
+	TermMonths        int
+	BreakClauseMonths int
+	NoticeDays        int
+}
+
+// NewAccountEmail is the payload of the outbox emails telling a new tenant and their landlord the tenant's login details.
+type NewAccountEmail struct {
+	LandlordEmail  string `json:"landlord_email"` // the landlord who accepted the tenant, who the landlord email is sent to
+	TenantUsername string `json:"tenant_username"`
+	TenantPassword string `json:"tenant_password"`
+	RoomType       string `json:"room_type"`
+	MoveInDate     string `json:"move_in_date"`
+	RentDue        string `json:"rent_due"`
+	MonthlyRent    string `json:"monthly_rent"`
+	Currency       string `json:"currency"`
+}
+
+// TicketUpdateEmail is the payload of the outbox email telling a tenant their maintenance ticket has changed status.
+type TicketUpdateEmail struct {
+	TenantEmail  string `json:"tenant_email"`
+	TicketID     int    `json:"ticket_id"`
+	Category     string `json:"category"`
+	Status       string `json:"status"`
+	ScheduledFor string `json:"scheduled_for"` // the visit date as YYYY-MM-DD, empty if none is set
+	Note         string `json:"note"`
+}
+
+// OutboxEmail is an email waiting in the outbox to be sent, with its payload decrypted.
+type OutboxEmail struct {
+	ID       int64
+	Kind     string // one of the Outbox constants
+	Payload  []byte // JSON, e.g. a NewAccountEmail
+	Attempts int    // how many times sending it has failed
+}
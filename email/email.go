@@ -1,7 +1,9 @@
 package email
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/smtp"
 	"os"
 
@@ -19,12 +21,16 @@ If the primary and secondary email addresses are the same, it sets the secondary
 Finally, it creates an email message and sends it to the landlord using the smtp.SendMail function.
 If the email cannot be sent, it logs an error and returns the error.
 
+Arguments:
+
+- landlordEmail: The email address of the landlord who owns the property applied for.
+
 Returns:
 
 - error: An error if the email cannot be sent.
 */
-func NotifyLandlordNewApplication() error {
-	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" || os.Getenv("NOTIFY_LANDLORD_EMAIL") == "" {
+func NotifyLandlordNewApplication(landlordEmail string) error {
+	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" {
 		logs.Logs(logWarn, "Could not get email credentials from hosting platform. Loading from .env file...")
 		err := env.LoadEnv("env/.env")
 		if err != nil {
@@ -32,12 +38,14 @@ func NotifyLandlordNewApplication() error {
 		}
 	}
 
-	// Update smptUser, smptPassword, recipient, and ccEmail variables
+	// Update smptUser, smptPassword, and ccEmail variables
 	smptUser = os.Getenv("LHP_EMAIL")
 	smptPassword = os.Getenv("LHP_EMAIL_PASSWORD")
-	recipient = os.Getenv("NOTIFY_LANDLORD_EMAIL")
 	ccEmail = os.Getenv("LHP_EMAIL")
 
+	// set recipient to the landlord of the property applied for
+	recipient := landlordEmail
+
 	if smptUser == "" || smptPassword == "" || recipient == "" || ccEmail == "" {
 		logs.Logs(logErr, "Email credentials are empty!")
 		return fmt.Errorf("email credentials are empty")
@@ -84,7 +92,7 @@ Returns:
 - error: An error if the email cannot be sent.
 */
 func NotifyTenantApplicationProcessing(tenantEmail string) error {
-	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" || os.Getenv("NOTIFY_LANDLORD_EMAIL") == "" {
+	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" {
 		logs.Logs(logWarn, "Could not get email credentials from hosting platform. Loading from .env file...")
 		err := env.LoadEnv("env/.env")
 		if err != nil {
@@ -133,7 +141,7 @@ https://lilyshiddenparadise.com
 }
 
 func NotifyTenantNewAccount(tenantUsername, tenantPassword, roomType, moveInDate, rentDue, monthlyRent, currency string) error {
-	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" || os.Getenv("NOTIFY_LANDLORD_EMAIL") == "" {
+	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" {
 		logs.Logs(logWarn, "Could not get email credentials from hosting platform. Loading from .env file...")
 		err := env.LoadEnv("env/.env")
 		if err != nil {
@@ -197,8 +205,23 @@ https://lilyshiddenparadise.com
 	return nil
 }
 
-func NotifyLandlordNewAccount(tenantUsername, tenantPassword, roomType, moveInDate, rentDue, monthlyRent, currency string) error {
-	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" || os.Getenv("NOTIFY_LANDLORD_EMAIL") == "" {
+/*
+NotifyLandlordNewAccount sends the landlord the login details of their new tenant.
+
+Arguments:
+
+- landlordEmail: The email address of the landlord who accepted the tenant.
+
+- tenantUsername, tenantPassword: The tenant's login details.
+
+- roomType, moveInDate, rentDue, monthlyRent, currency: The terms of the tenancy.
+
+Returns:
+
+- error: An error if the email cannot be sent.
+*/
+func NotifyLandlordNewAccount(landlordEmail, tenantUsername, tenantPassword, roomType, moveInDate, rentDue, monthlyRent, currency string) error {
+	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" {
 		logs.Logs(logWarn, "Could not get email credentials from hosting platform. Loading from .env file...")
 		err := env.LoadEnv("env/.env")
 		if err != nil {
@@ -206,12 +229,14 @@ func NotifyLandlordNewAccount(tenantUsername, tenantPassword, roomType, moveInDa
 		}
 	}
 
-	// Update smptUser, smptPassword, recipient, and ccEmail variables
+	// Update smptUser, smptPassword, and ccEmail variables
 	smptUser = os.Getenv("LHP_EMAIL")
 	smptPassword = os.Getenv("LHP_EMAIL_PASSWORD")
-	recipient = os.Getenv("NOTIFY_LANDLORD_EMAIL")
 	ccEmail = os.Getenv("LHP_EMAIL")
 
+	// set recipient to the landlord who accepted the tenant
+	recipient := landlordEmail
+
 	if smptUser == "" || smptPassword == "" || recipient == "" || ccEmail == "" {
 		logs.Logs(logErr, "Email credentials are empty!")
 		return fmt.Errorf("email credentials are empty")
@@ -263,9 +288,11 @@ https://lilyshiddenparadise.com
 func NotifyLandlordNewMessageFromTenant(tenantName, landlordEmail, messageFromTenant string) error {
 	smptUser = os.Getenv("LHP_EMAIL")
 	smptPassword = os.Getenv("LHP_EMAIL_PASSWORD")
-	recipient = os.Getenv("NOTIFY_LANDLORD_EMAIL")
 	ccEmail = os.Getenv("LHP_EMAIL")
 
+	// set recipient to the tenant's landlord
+	recipient := landlordEmail
+
 	if smptUser == "" || smptPassword == "" || recipient == "" || ccEmail == "" {
 		logs.Logs(logErr, "Email credentials are empty!")
 		return fmt.Errorf("email credentials are empty")
@@ -309,3 +336,378 @@ func NotifyTenantNewMessageFromLandlord(tenantEmail, messageFromLandlord string)
 	logs.Logs(logInfo, "Email sent successfully. Landlord notified of new message from tenant.")
 	return nil
 }
+
+/*
+NotifyLandlordNewMaintenanceTicket emails the landlord when one of their tenants opens a maintenance ticket.
+
+Arguments:
+
+- landlordEmail: The landlord's email address to send the email to.
+
+- tenantName: The full name of the tenant who opened the ticket.
+
+- ticketId: The ID of the new ticket.
+
+- category: The kind of repair needed.
+
+- priority: How urgent the tenant says the repair is.
+
+Returns:
+
+- error: An error if the email cannot be sent.
+*/
+func NotifyLandlordNewMaintenanceTicket(landlordEmail, tenantName string, ticketId int, category, priority string) error {
+	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" {
+		logs.Logs(logWarn, "Could not get email credentials from hosting platform. Loading from .env file...")
+		err := env.LoadEnv("env/.env")
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Unable to load environment variables: %s", err.Error()))
+		}
+	}
+
+	// Update smptUser, smptPassword, recipient, and ccEmail variables
+	smptUser = os.Getenv("LHP_EMAIL")
+	smptPassword = os.Getenv("LHP_EMAIL_PASSWORD")
+	ccEmail = os.Getenv("LHP_EMAIL")
+
+	// set recipient to the landlord of the tenant's property
+	recipient := landlordEmail
+
+	if smptUser == "" || smptPassword == "" || recipient == "" || ccEmail == "" {
+		logs.Logs(logErr, "Email credentials are empty!")
+		return fmt.Errorf("email credentials are empty")
+	}
+
+	if recipient == ccEmail {
+		logs.Logs(logWarn, "Primary and secondary email addresses are the same, skipping CC")
+		ccEmail = ""
+	}
+
+	subject := fmt.Sprintf("New Maintenance Request #%d from %s", ticketId, tenantName)
+	body := fmt.Sprintf(`
+%s has opened a new maintenance request.
+
+TICKET: #%d
+CATEGORY: %s
+PRIORITY: %s
+
+Log in to your landlord dashboard to triage the request.
+
+Login  Now: https://lilyshiddenparadise.com/login/landlord
+
+
+Yours sincerely,
+
+Lily's Hidden Paradise
+https://lilyshiddenparadise.com
+	`, tenantName, ticketId, category, priority)
+
+	auth := smtp.PlainAuth("", smptUser, smptPassword, smptHost)
+	err := smtp.SendMail(smptHost+":"+smptPort, auth, smptUser, []string{recipient, ccEmail}, []byte("Subject: "+subject+"\n\n"+body))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to send email: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logInfo, "Email sent successfully. Landlord notified of new maintenance ticket.")
+	return nil
+}
+
+/*
+NotifyTenantMaintenanceTicketStatus emails the tenant when the landlord changes the status of their maintenance ticket.
+
+Arguments:
+
+- tenantEmail: The tenant's email address to send the email to.
+
+- ticketId: The ID of the ticket.
+
+- category: The kind of repair needed.
+
+- status: The new status of the ticket.
+
+- scheduledFor: The date of the repair visit, if one has been scheduled.
+
+- note: The landlord's note about the change, if any.
+
+Returns:
+
+- error: An error if the email cannot be sent.
+*/
+func NotifyTenantMaintenanceTicketStatus(tenantEmail string, ticketId int, category, status, scheduledFor, note string) error {
+	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" {
+		logs.Logs(logWarn, "Could not get email credentials from hosting platform. Loading from .env file...")
+		err := env.LoadEnv("env/.env")
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Unable to load environment variables: %s", err.Error()))
+		}
+	}
+
+	// Update smptUser, smptPassword, recipient, and ccEmail variables
+	smptUser = os.Getenv("LHP_EMAIL")
+	smptPassword = os.Getenv("LHP_EMAIL_PASSWORD")
+	ccEmail = os.Getenv("LHP_EMAIL")
+
+	// set recipient to tenant email
+	recipient := tenantEmail
+
+	if smptUser == "" || smptPassword == "" || recipient == "" || ccEmail == "" {
+		logs.Logs(logErr, "Email credentials are empty!")
+		return fmt.Errorf("email credentials are empty")
+	}
+
+	if recipient == ccEmail {
+		logs.Logs(logWarn, "Primary and secondary email addresses are the same, skipping CC")
+		ccEmail = ""
+	}
+
+	if scheduledFor == "" {
+		scheduledFor = "Not scheduled yet"
+	}
+	if note == "" {
+		note = "None"
+	}
+
+	subject := fmt.Sprintf("Maintenance Request #%d Update", ticketId)
+	body := fmt.Sprintf(`
+Your %s maintenance request has been updated.
+
+TICKET: #%d
+STATUS: %s
+VISIT DATE: %s
+NOTE FROM YOUR LANDLORD: %s
+
+Log in to your tenant dashboard to see the full history of your request.
+
+Login  Now: https://lilyshiddenparadise.com/login/tenant
+
+
+Yours sincerely,
+
+Lily's Hidden Paradise
+https://lilyshiddenparadise.com
+	`, category, ticketId, status, scheduledFor, note)
+
+	auth := smtp.PlainAuth("", smptUser, smptPassword, smptHost)
+	err := smtp.SendMail(smptHost+":"+smptPort, auth, smptUser, []string{recipient, ccEmail}, []byte("Subject: "+subject+"\n\n"+body))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to send email: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logInfo, "Email sent successfully. Tenant notified of maintenance ticket update.")
+	return nil
+}
+
+/*
+NotifyPasswordReset emails a landlord or tenant a link to reset their password.
+
+Arguments:
+
+- userEmail: The email address of the user who asked to reset their password.
+
+- resetLink: The single-use link to the reset password page.
+
+Returns:
+
+- error: An error if the email cannot be sent.
+*/
+func NotifyPasswordReset(userEmail, resetLink string) error {
+	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" {
+		logs.Logs(logWarn, "Could not get email credentials from hosting platform. Loading from .env file...")
+		err := env.LoadEnv("env/.env")
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Unable to load environment variables: %s", err.Error()))
+		}
+	}
+
+	// Update smptUser and smptPassword variables
+	smptUser = os.Getenv("LHP_EMAIL")
+	smptPassword = os.Getenv("LHP_EMAIL_PASSWORD")
+
+	// only the user receives the reset link, it is never copied to the shared inbox
+	recipient := userEmail
+
+	if smptUser == "" || smptPassword == "" || recipient == "" {
+		logs.Logs(logErr, "Email credentials are empty!")
+		return fmt.Errorf("email credentials are empty")
+	}
+
+	subject := "Reset Your Password"
+	body := fmt.Sprintf(`
+We received a request to reset the password for your Lily's Hidden Paradise account.
+
+Reset your password: %s
+
+This link can only be used once and expires in 1 hour.
+If you did not ask to reset your password you can ignore this email, your password has not been changed.
+
+
+Yours sincerely,
+
+Lily's Hidden Paradise
+https://lilyshiddenparadise.com
+	`, resetLink)
+
+	auth := smtp.PlainAuth("", smptUser, smptPassword, smptHost)
+	err := smtp.SendMail(smptHost+":"+smptPort, auth, smptUser, []string{recipient}, []byte("Subject: "+subject+"\n\n"+body))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to send email: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logInfo, "Email sent successfully. User sent a password reset link.")
+	return nil
+}
+
+/*
+NotifyRepeatedLoginFailures warns a landlord or tenant that someone has repeatedly failed to log in to their account.
+
+Arguments:
+
+- userEmail: The email address of the account.
+
+- failures: The number of failed logins in a row.
+
+- forgotPasswordLink: The link to reset the account's password.
+
+Returns:
+
+- error: An error if the email cannot be sent.
+*/
+func NotifyRepeatedLoginFailures(userEmail string, failures int, forgotPasswordLink string) error {
+	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" {
+		logs.Logs(logWarn, "Could not get email credentials from hosting platform. Loading from .env file...")
+		err := env.LoadEnv("env/.env")
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Unable to load environment variables: %s", err.Error()))
+		}
+	}
+
+	// Update smptUser and smptPassword variables
+	smptUser = os.Getenv("LHP_EMAIL")
+	smptPassword = os.Getenv("LHP_EMAIL_PASSWORD")
+
+	// only the account owner is warned
+	recipient := userEmail
+
+	if smptUser == "" || smptPassword == "" || recipient == "" {
+		logs.Logs(logErr, "Email credentials are empty!")
+		return fmt.Errorf("email credentials are empty")
+	}
+
+	subject := "Failed Login Attempts On Your Account"
+	body := fmt.Sprintf(`
+There have been %d failed attempts to log in to your Lily's Hidden Paradise account.
+Further attempts are being slowed down, and your account will be locked for a while if they continue.
+
+If this was you, you can reset your password here: %s
+
+If this was not you, someone may be trying to guess your password. We recommend you reset your password
+and turn on two-factor authentication from your account page.
+
+
+Yours sincerely,
+
+Lily's Hidden Paradise
+https://lilyshiddenparadise.com
+	`, failures, forgotPasswordLink)
+
+	auth := smtp.PlainAuth("", smptUser, smptPassword, smptHost)
+	err := smtp.SendMail(smptHost+":"+smptPort, auth, smptUser, []string{recipient}, []byte("Subject: "+subject+"\n\n"+body))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to send email: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logInfo, "Email sent successfully. User warned of repeated failed logins.")
+	return nil
+}
+
+/*
+NotifyLandlordInvitation emails someone an invitation to create an account on the landlord dashboard.
+
+Arguments:
+
+- inviteeEmail: The email address of the person invited.
+
+- invitedBy: The email address of the account that sent the invitation.
+
+- accountRole: The role the new account will have.
+
+- inviteLink: The link to accept the invitation.
+
+- expiresAt: When the invitation stops working, already formatted for the email.
+
+Returns:
+
+- error: An error if the email cannot be sent.
+*/
+func NotifyLandlordInvitation(inviteeEmail, invitedBy, accountRole, inviteLink, expiresAt string) error {
+	if os.Getenv("LHP_EMAIL") == "" || os.Getenv("LHP_EMAIL_PASSWORD") == "" {
+		logs.Logs(logWarn, "Could not get email credentials from hosting platform. Loading from .env file...")
+		err := env.LoadEnv("env/.env")
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Unable to load environment variables: %s", err.Error()))
+		}
+	}
+
+	// Update smptUser and smptPassword variables
+	smptUser = os.Getenv("LHP_EMAIL")
+	smptPassword = os.Getenv("LHP_EMAIL_PASSWORD")
+
+	// only the person invited receives the link, it is never copied to the shared inbox
+	recipient := inviteeEmail
+
+	if smptUser == "" || smptPassword == "" || recipient == "" {
+		logs.Logs(logErr, "Email credentials are empty!")
+		return fmt.Errorf("email credentials are empty")
+	}
+
+	subject := "You Have Been Invited To Lily's Hidden Paradise"
+	body := fmt.Sprintf(`
+%s has invited you to create a %s account on the Lily's Hidden Paradise landlord dashboard.
+
+Create your account: %s
+
+This link can only be used once and expires on %s.
+If you were not expecting this invitation you can ignore this email.
+
+
+Yours sincerely,
+
+Lily's Hidden Paradise
+https://lilyshiddenparadise.com
+	`, invitedBy, accountRole, inviteLink, expiresAt)
+
+	auth := smtp.PlainAuth("", smptUser, smptPassword, smptHost)
+	err := smtp.SendMail(smptHost+":"+smptPort, auth, smptUser, []string{recipient}, []byte("Subject: "+subject+"\n\n"+body))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to send email: %s", err.Error()))
+		return err
+	}
+
+	logs.Logs(logInfo, "Email sent successfully. Landlord invitation sent.")
+	return nil
+}
+
+/*
+CheckSMTP checks the SMTP server emails are sent through can be reached, for the app's readiness check.
+It only opens a connection, nothing is sent. The connection is given up on after smtpCheckTimeout,
+so an unreachable server does not hold up the readiness check.
+
+Returns:
+
+- error: An error if the SMTP server cannot be reached in time.
+*/
+func CheckSMTP(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpCheckTimeout)
+	defer cancel()
+
+	dialer := net.Dialer{Timeout: smtpCheckTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(smptHost, smptPort))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
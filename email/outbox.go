@@ -0,0 +1,118 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+)
+
+// outboxWake asks the outbox dispatcher to check for emails straight away
+var outboxWake = make(chan struct{}, 1)
+
+// sendOutbox sends one outbox email. Tests replace it, so nothing is emailed.
+var sendOutbox = sendOutboxEmail
+
+// WakeOutbox tells the outbox dispatcher that emails have been added, so they are sent without waiting
+// for the next check. It does not block.
+func WakeOutbox() {
+	select {
+	case outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+/*
+StartOutboxDispatcher sends the emails waiting in the outbox when the app starts, then every outboxInterval
+or when WakeOutbox is called. It runs until the app stops, so start it in its own goroutine.
+
+Arguments:
+
+- store: The store holding the outbox.
+*/
+func StartOutboxDispatcher(store db.OutboxStore) {
+	logs.Logs(logInfo, fmt.Sprintf("Sending outbox emails every %s...", outboxInterval))
+	ticker := time.NewTicker(outboxInterval)
+	defer ticker.Stop()
+
+	for {
+		DeliverOutbox(context.Background(), store)
+		select {
+		case <-ticker.C:
+		case <-outboxWake:
+		}
+	}
+}
+
+/*
+DeliverOutbox sends the emails that are due in the outbox. Sent emails are removed from the outbox.
+Emails that fail are tried again later, waiting longer after each failure, and are given up on after outboxMaxAttempts.
+
+Arguments:
+
+- store: The store holding the outbox.
+
+Returns:
+
+- int: The number of emails sent.
+*/
+func DeliverOutbox(ctx context.Context, store db.OutboxStore) int {
+	emails, err := store.ClaimOutboxEmails(ctx, outboxBatchSize)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to claim outbox emails: %s", err.Error()))
+		return 0
+	}
+
+	sent := 0
+	for _, email := range emails {
+		err = sendOutbox(email)
+		if err == nil {
+			sent++
+			err = store.CompleteOutboxEmail(ctx, email.ID)
+			if err != nil {
+				logs.Logs(logErr, fmt.Sprintf("Failed to complete outbox email %d, it may be sent again: %s", email.ID, err.Error()))
+			}
+			continue
+		}
+
+		logs.Logs(logWarn, fmt.Sprintf("Failed to send %s email %d: %s", email.Kind, email.ID, err.Error()))
+		if email.Attempts+1 >= outboxMaxAttempts {
+			err = store.AbandonOutboxEmail(ctx, email.ID, err.Error())
+		} else {
+			retryAt := time.Now().Add(outboxRetryDelay << email.Attempts)
+			err = store.RetryOutboxEmail(ctx, email.ID, err.Error(), retryAt)
+		}
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Failed to record failed outbox email %d: %s", email.ID, err.Error()))
+		}
+	}
+	return sent
+}
+
+// sendOutboxEmail sends one outbox email with the Notify function for its kind.
+func sendOutboxEmail(email db.OutboxEmail) error {
+	switch email.Kind {
+	case db.OutboxTenantNewAccount, db.OutboxLandlordNewAccount:
+		var account db.NewAccountEmail
+		err := json.Unmarshal(email.Payload, &account)
+		if err != nil {
+			return err
+		}
+		if email.Kind == db.OutboxLandlordNewAccount {
+			return NotifyLandlordNewAccount(account.LandlordEmail, account.TenantUsername, account.TenantPassword, account.RoomType, account.MoveInDate, account.RentDue, account.MonthlyRent, account.Currency)
+		}
+		return NotifyTenantNewAccount(account.TenantUsername, account.TenantPassword, account.RoomType, account.MoveInDate, account.RentDue, account.MonthlyRent, account.Currency)
+	case db.OutboxTenantTicketUpdate:
+		var update db.TicketUpdateEmail
+		err := json.Unmarshal(email.Payload, &update)
+		if err != nil {
+			return err
+		}
+		return NotifyTenantMaintenanceTicketStatus(update.TenantEmail, update.TicketID, update.Category, update.Status, update.ScheduledFor, update.Note)
+	default:
+		return fmt.Errorf("unknown outbox email kind %q", email.Kind)
+	}
+}
@@ -0,0 +1,212 @@
+package email
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+)
+
+// fakeOutboxRow is an email in the fake outbox, with what the dispatcher has recorded about it
+type fakeOutboxRow struct {
+	email         db.OutboxEmail
+	nextAttemptAt time.Time
+	lastError     string
+	sent          bool
+	abandoned     bool
+}
+
+// fakeOutbox is a db.OutboxStore keeping the outbox in memory the way the real table does,
+// so claimed emails are hidden until they are retried or their claim runs out
+type fakeOutbox struct {
+	rows map[int64]*fakeOutboxRow
+}
+
+func newFakeOutbox(emails ...db.OutboxEmail) *fakeOutbox {
+	outbox := &fakeOutbox{rows: map[int64]*fakeOutboxRow{}}
+	for _, email := range emails {
+		outbox.rows[email.ID] = &fakeOutboxRow{email: email}
+	}
+	return outbox
+}
+
+func (f *fakeOutbox) ClaimOutboxEmails(ctx context.Context, limit int) ([]db.OutboxEmail, error) {
+	var emails []db.OutboxEmail
+	for _, row := range f.rows {
+		if !row.sent && !row.abandoned && !row.nextAttemptAt.After(time.Now()) {
+			emails = append(emails, row.email)
+		}
+	}
+	sort.Slice(emails, func(i, j int) bool { return emails[i].ID < emails[j].ID })
+	if len(emails) > limit {
+		emails = emails[:limit]
+	}
+	for _, email := range emails {
+		f.rows[email.ID].nextAttemptAt = time.Now().Add(5 * time.Minute)
+	}
+	return emails, nil
+}
+
+func (f *fakeOutbox) CompleteOutboxEmail(ctx context.Context, id int64) error {
+	row, exists := f.rows[id]
+	if !exists {
+		return sql.ErrNoRows
+	}
+	row.sent = true
+	return nil
+}
+
+func (f *fakeOutbox) RetryOutboxEmail(ctx context.Context, id int64, sendErr string, retryAt time.Time) error {
+	row, exists := f.rows[id]
+	if !exists {
+		return sql.ErrNoRows
+	}
+	row.email.Attempts++
+	row.lastError = sendErr
+	row.nextAttemptAt = retryAt
+	return nil
+}
+
+func (f *fakeOutbox) AbandonOutboxEmail(ctx context.Context, id int64, sendErr string) error {
+	row, exists := f.rows[id]
+	if !exists {
+		return sql.ErrNoRows
+	}
+	row.abandoned = true
+	row.lastError = sendErr
+	return nil
+}
+
+// dueNow makes every waiting email due, as if its retry time had passed
+func (f *fakeOutbox) dueNow() {
+	for _, row := range f.rows {
+		row.nextAttemptAt = time.Time{}
+	}
+}
+
+// useSendOutbox sends outbox emails with send until the test ends
+func useSendOutbox(t *testing.T, send func(email db.OutboxEmail) error) {
+	previous := sendOutbox
+	sendOutbox = send
+	t.Cleanup(func() { sendOutbox = previous })
+}
+
+func TestDeliverOutbox(t *testing.T) {
+	go logs.LogProcessor()
+
+	t.Run("Sent email is completed", func(t *testing.T) {
+		outbox := newFakeOutbox(db.OutboxEmail{ID: 1, Kind: db.OutboxTenantNewAccount}, db.OutboxEmail{ID: 2, Kind: db.OutboxLandlordNewAccount})
+		var sent []int64
+		useSendOutbox(t, func(email db.OutboxEmail) error {
+			sent = append(sent, email.ID)
+			return nil
+		})
+
+		if count := DeliverOutbox(context.Background(), outbox); count != 2 {
+			t.Errorf("Expected 2 emails sent, got %d", count)
+		}
+		if len(sent) != 2 || sent[0] != 1 || sent[1] != 2 {
+			t.Errorf("Expected emails 1 and 2 to be sent oldest first, got %v", sent)
+		}
+		for id, row := range outbox.rows {
+			if !row.sent || row.email.Attempts != 0 {
+				t.Errorf("Expected email %d to be completed, got %+v", id, row)
+			}
+		}
+
+		// completed emails are not sent again
+		if count := DeliverOutbox(context.Background(), outbox); count != 0 || len(sent) != 2 {
+			t.Errorf("Expected nothing more to be sent, sent %v", sent)
+		}
+	})
+
+	t.Run("Failed email is retried later", func(t *testing.T) {
+		outbox := newFakeOutbox(db.OutboxEmail{ID: 1, Kind: db.OutboxTenantNewAccount})
+		useSendOutbox(t, func(email db.OutboxEmail) error { return errors.New("connection refused") })
+
+		before := time.Now()
+		if count := DeliverOutbox(context.Background(), outbox); count != 0 {
+			t.Errorf("Expected no emails sent, got %d", count)
+		}
+		row := outbox.rows[1]
+		if row.sent || row.abandoned || row.email.Attempts != 1 || row.lastError != "connection refused" {
+			t.Fatalf("Expected the failure to be recorded for a retry, got %+v", row)
+		}
+		if wait := row.nextAttemptAt.Sub(before); wait < outboxRetryDelay || wait > outboxRetryDelay+time.Second {
+			t.Errorf("Expected a retry after %v, got %v", outboxRetryDelay, wait)
+		}
+
+		// the email is not due again until its retry time
+		if DeliverOutbox(context.Background(), outbox); row.email.Attempts != 1 {
+			t.Errorf("Expected the email to wait for its retry, got %d attempts", row.email.Attempts)
+		}
+	})
+
+	t.Run("Wait doubles after each failure", func(t *testing.T) {
+		outbox := newFakeOutbox(db.OutboxEmail{ID: 1, Kind: db.OutboxTenantNewAccount, Attempts: 3})
+		useSendOutbox(t, func(email db.OutboxEmail) error { return errors.New("connection refused") })
+
+		before := time.Now()
+		DeliverOutbox(context.Background(), outbox)
+		expected := outboxRetryDelay * 8
+		if wait := outbox.rows[1].nextAttemptAt.Sub(before); wait < expected || wait > expected+time.Second {
+			t.Errorf("Expected a retry after %v following 4 failures, got %v", expected, wait)
+		}
+	})
+
+	t.Run("Email given up on after too many failures", func(t *testing.T) {
+		outbox := newFakeOutbox(db.OutboxEmail{ID: 1, Kind: db.OutboxTenantNewAccount})
+		attempts := 0
+		useSendOutbox(t, func(email db.OutboxEmail) error {
+			attempts++
+			return errors.New("mailbox unavailable")
+		})
+
+		// each retry is made due straight away, as if its wait had passed
+		for i := 0; i < outboxMaxAttempts+2; i++ {
+			DeliverOutbox(context.Background(), outbox)
+			outbox.dueNow()
+		}
+		row := outbox.rows[1]
+		if !row.abandoned || row.sent {
+			t.Fatalf("Expected the email to be abandoned, got %+v", row)
+		}
+		if attempts != outboxMaxAttempts {
+			t.Errorf("Expected %d attempts before giving up, got %d", outboxMaxAttempts, attempts)
+		}
+		if row.lastError != "mailbox unavailable" {
+			t.Errorf("Expected the last failure to be recorded, got %q", row.lastError)
+		}
+	})
+
+	t.Run("Unknown kind is not sent", func(t *testing.T) {
+		outbox := newFakeOutbox(db.OutboxEmail{ID: 1, Kind: "unknown"})
+
+		DeliverOutbox(context.Background(), outbox)
+		if row := outbox.rows[1]; row.sent || row.email.Attempts != 1 {
+			t.Errorf("Expected an email of an unknown kind to fail, got %+v", row)
+		}
+	})
+}
+
+func TestWakeOutbox(t *testing.T) {
+	// waking an outbox that is already due to be checked does not block
+	WakeOutbox()
+	WakeOutbox()
+
+	select {
+	case <-outboxWake:
+	default:
+		t.Fatal("Expected the outbox dispatcher to be woken")
+	}
+	select {
+	case <-outboxWake:
+		t.Error("Expected one wake-up however often the outbox is woken")
+	default:
+	}
+}
@@ -1,6 +1,9 @@
 package email
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 const (
 	logInfo  = 1
@@ -8,11 +11,17 @@ const (
 	logErr   = 3
 	smptHost = "smtp.gmail.com"
 	smptPort = "587"
+
+	outboxInterval    = 30 * time.Second // how often the outbox is checked for emails to send
+	outboxBatchSize   = 20               // the most outbox emails sent each time it is checked
+	outboxRetryDelay  = time.Minute      // the wait before retrying a failed email, doubled after each failure
+	outboxMaxAttempts = 8                // the number of failures after which an email is given up on
+
+	smtpCheckTimeout = 3 * time.Second // the longest the readiness check waits to connect to the SMTP server
 )
 
 var (
 	smptUser     = os.Getenv("LHP_EMAIL")
 	smptPassword = os.Getenv("LHP_EMAIL_PASSWORD")
-	recipient    = os.Getenv("NOTIFY_LANDLORD_EMAIL") // 1st destination email
-	ccEmail      = os.Getenv("LHP_EMAIL")             // 2nd destination email
+	ccEmail      = os.Getenv("LHP_EMAIL") // 2nd destination email
 )
@@ -10,6 +10,9 @@ import (
 LoadEnv parses a file with environment variables in the format KEY=VALUE and
 sets the variables in the current process's environment.
 
+Variables already set in the environment, e.g. by the hosting platform, are
+kept, so the file only fills in the ones that are missing.
+
 Lines starting with "#" are ignored as are empty lines. If a line does not
 contain a "=", it is skipped.
 
@@ -36,7 +39,10 @@ func LoadEnv(filename string) error {
 		}
 		key := strings.TrimSpace(parts[0])
 		value := strings.TrimSpace(parts[1])
-		// set environment variable
+		// set environment variable, unless it is already set
+		if _, exists := os.LookupEnv(key); exists {
+			continue
+		}
 		os.Setenv(key, value)
 	}
 	return scanner.Err()
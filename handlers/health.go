@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/email"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// Version is the version of the app reported by /version. It is set when the app is built, e.g.
+// go build -ldflags "-X github.com/Bevs-n-Devs/lilyshiddenparadise/handlers.Version=v1.2.0"
+var Version = "dev"
+
+const (
+	checkOK      = "ok"
+	checkFailed  = "failed"
+	checkSkipped = "skipped"
+)
+
+// readiness is the body of /readyz. Each check is checkOK, checkFailed or checkSkipped.
+type readiness struct {
+	Ready      bool   `json:"ready"`
+	Database   string `json:"database"`
+	Encryption string `json:"encryption"`
+	Templates  string `json:"templates"`
+	SMTP       string `json:"smtp"` // optional, the app is ready without it as emails are retried from the outbox
+}
+
+// versionInfo is the body of /version.
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuiltAt   string `json:"built_at,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // true if the binary was built from a checkout with uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+// Healthz answers 200 OK while the process is up, for liveness probes. It checks nothing else,
+// so a busy or unreachable database does not get the app restarted.
+func (s *Server) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]string{"status": checkOK})
+}
+
+/*
+Readyz answers 200 OK when the app can serve requests, for readiness probes, and 503 Service Unavailable
+when a critical dependency is missing: the database cannot be pinged, no encryption key is loaded
+or the templates have not been parsed. The SMTP server is only checked when config.Server.ReadyCheckSMTP is set,
+and does not affect readiness. The body lists the result of each check; failures are logged with their reasons.
+*/
+func (s *Server) Readyz(w http.ResponseWriter, r *http.Request) {
+	result := readiness{Database: checkOK, Encryption: checkOK, Templates: checkOK, SMTP: checkSkipped}
+
+	err := s.store.Ping(r.Context())
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Not ready, database cannot be reached: %s", err.Error()))
+		result.Database = checkFailed
+	}
+
+	err = utils.EncryptionReady()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Not ready, %s", err.Error()))
+		result.Encryption = checkFailed
+	}
+
+	if Templates == nil || len(Templates.Templates()) == 0 {
+		logs.Logs(logErr, "Not ready, templates have not been parsed")
+		result.Templates = checkFailed
+	}
+
+	if config.Server.ReadyCheckSMTP {
+		result.SMTP = checkOK
+		err = email.CheckSMTP(r.Context())
+		if err != nil {
+			logs.Logs(logWarn, fmt.Sprintf("SMTP server cannot be reached, emails will be retried: %s", err.Error()))
+			result.SMTP = checkFailed
+		}
+	}
+
+	result.Ready = result.Database == checkOK && result.Encryption == checkOK && result.Templates == checkOK
+	status := http.StatusOK
+	if !result.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, result)
+}
+
+// Version answers with the version of the app and the commit it was built from, when the build recorded it.
+func (s *Server) Version(w http.ResponseWriter, r *http.Request) {
+	info := versionInfo{Version: Version, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				info.BuiltAt = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// writeJSON writes body as the JSON response with the status code.
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error writing JSON response: %s", err.Error()))
+	}
+}
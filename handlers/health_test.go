@@ -0,0 +1,82 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// Define test cases
+	testCases := []struct {
+		name                 string
+		url                  string
+		expectedStatusCode   int
+		expectedBodyContains string
+		failDB               bool
+	}{
+		{
+			name:                 "Process is up",
+			url:                  "/healthz",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: `"status":"ok"`,
+		},
+		{
+			name:                 "Ready",
+			url:                  "/readyz",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: `"ready":true,"database":"ok","encryption":"ok","templates":"ok","smtp":"skipped"`,
+		},
+		{
+			name:                 "Process is up when the database is down",
+			url:                  "/healthz",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: `"status":"ok"`,
+			failDB:               true, // /healthz makes no query, so the failure is used by the next case
+		},
+		{
+			name:                 "Not ready when the database cannot be reached",
+			url:                  "/readyz",
+			expectedStatusCode:   http.StatusServiceUnavailable,
+			expectedBodyContains: `"ready":false,"database":"failed"`,
+			failDB:               true,
+		},
+		{
+			name:                 "Version",
+			url:                  "/version",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: `"version":"dev"`,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Set up mock database to fail if needed
+			if tc.failDB {
+				testutil.TestEnvironment.DB.SetFailNextOperation("database error")
+			}
+
+			rr := testutil.ServeTestRequest(httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the response body
+			if !strings.Contains(rr.Body.String(), tc.expectedBodyContains) {
+				t.Errorf("Expected response body to contain '%s', got '%s'", tc.expectedBodyContains, rr.Body.String())
+			}
+		})
+	}
+}
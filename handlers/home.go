@@ -7,7 +7,7 @@ import (
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 )
 
-func Home(w http.ResponseWriter, r *http.Request) {
+func (s *Server) Home(w http.ResponseWriter, r *http.Request) {
 	// get any error messages
 	authenticationError := r.URL.Query().Get("authenticationError")
 
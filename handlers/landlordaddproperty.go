@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+)
+
+func (s *Server) LandlordAddProperty(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// extract data from form
+	propertyName := r.FormValue("propertyName")
+	propertyAddress := r.FormValue("propertyAddress")
+
+	err = s.store.CreateProperty(r.Context(), landlordEmail, propertyName, propertyAddress)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to add property: %s. Redirecting back to landlord properties page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/properties?validationError=BAD+REQUEST+400:+Failed+to+add+property.+Please+give+the+property+a+name+and+address", http.StatusSeeOther)
+		return
+	}
+
+	logs.Logs(logInfo, "Property added. Redirecting back to landlord properties page.")
+	http.Redirect(w, r, "/landlord/dashboard/properties", http.StatusSeeOther)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+func (s *Server) LandlordAddRoom(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// extract data from form
+	propertyId := r.FormValue("propertyId")
+	unit := r.FormValue("unit")
+	roomName := r.FormValue("roomName")
+	roomType := r.FormValue("roomType")
+	amenities := r.FormValue("amenities")
+	currency := r.FormValue("currency")
+
+	propertyIdInt, err := strconv.Atoi(propertyId)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid property ID: %s. Redirecting back to landlord properties page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/properties?validationError=BAD+REQUEST+400:+Please+select+a+property", http.StatusSeeOther)
+		return
+	}
+
+	capacity, defaultRent, err := utils.ParseRoomDetails(roomName, roomType, r.FormValue("capacity"), r.FormValue("defaultRent"))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid room details: %s. Redirecting back to landlord properties page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/properties?validationError=BAD+REQUEST+400:+Invalid+room+details.+Please+check+the+name,+type,+capacity+and+rent", http.StatusSeeOther)
+		return
+	}
+
+	err = s.store.CreateRoom(r.Context(), landlordEmail, propertyIdInt, unit, roomName, roomType, capacity, amenities, defaultRent, currency)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to add room: %s. Redirecting back to landlord properties page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/properties?validationError=BAD+REQUEST+400:+Failed+to+add+room.+Room+names+must+be+unique+within+a+unit", http.StatusSeeOther)
+		return
+	}
+
+	logs.Logs(logInfo, "Room added. Redirecting back to landlord properties page.")
+	http.Redirect(w, r, "/landlord/dashboard/properties", http.StatusSeeOther)
+}
@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/email"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// the owner's page for managing landlord accounts
+const landlordAdminPath = "/landlord/dashboard/admin"
+
+func (s *Server) LandlordAdmin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the owner authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+
+	// get any error messages
+	var showData ShowLandlordAdmin
+	showData.CSRFToken = principal.CSRFToken
+	showData.InviteSent = r.URL.Query().Get("inviteSent")
+	showData.Error.ValidationError = r.URL.Query().Get("validationError")
+	showData.Error.InternalServerError = r.URL.Query().Get("internalServerError")
+
+	accounts, err := s.store.GetLandlordAccounts(r.Context())
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get landlord accounts: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get landlord accounts: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	for _, account := range accounts {
+		showAccount := ShowLandlordAccount{
+			ID:          account.ID,
+			Email:       account.Email,
+			AccountRole: account.AccountRole,
+			WorksFor:    account.WorksFor,
+			CreatedAt:   account.CreatedAt.Format("2006-01-02"),
+			IsOwner:     account.AccountRole == db.LandlordRoleOwner,
+		}
+		if account.SuspendedAt.Valid {
+			showAccount.SuspendedAt = account.SuspendedAt.Time.Format("2006-01-02 15:04")
+		}
+		showData.Accounts = append(showData.Accounts, showAccount)
+	}
+
+	// get the accounts, including staff, that cannot log in after too many failed attempts
+	lockedAccounts, err := s.store.GetLockedLandlordAccounts(r.Context())
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get locked landlord accounts: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get locked landlord accounts: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	showData.LockedAccounts = showLockedLandlordAccounts(lockedAccounts)
+
+	invitations, err := s.store.GetPendingLandlordInvitations(r.Context())
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get pending invitations: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get pending invitations: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	for _, invitation := range invitations {
+		showData.Invitations = append(showData.Invitations, ShowPendingInvitation{
+			ID:          invitation.ID,
+			Email:       invitation.Email,
+			AccountRole: invitation.AccountRole,
+			InvitedBy:   invitation.InvitedBy,
+			ExpiresAt:   invitation.ExpiresAt.Format("2006-01-02 15:04"),
+		})
+	}
+
+	err = Templates.ExecuteTemplate(w, "landlordAdmin.html", showData)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Unable to load landlord admin page: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Unable to load landlord admin page: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) LandlordAdminInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the owner authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	inviteeEmail := strings.TrimSpace(r.FormValue("inviteeEmail"))
+	if !utils.ValidateEmail(inviteeEmail) {
+		logs.Logs(logErr, "Invalid invitee email. Redirecting back to landlord admin page")
+		http.Redirect(w, r, landlordAdminPath+"?validationError=BAD+REQUEST+400:+Invalid+email+address", http.StatusSeeOther)
+		return
+	}
+
+	token, expiresAt, err := s.store.CreateLandlordInvitation(r.Context(), principal.Email, inviteeEmail, db.LandlordRoleLandlord)
+	if err == db.ErrLandlordExists {
+		logs.Logs(logWarn, "Invitation sent to an email that already has an account. Redirecting back to landlord admin page")
+		http.Redirect(w, r, landlordAdminPath+"?validationError=CONFLICT+409:+An+account+with+this+email+already+exists", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to create invitation: %s", err.Error()))
+		http.Redirect(w, r, landlordAdminPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+invitation", http.StatusSeeOther)
+		return
+	}
+
+	inviteLink := fmt.Sprintf("%s/invitation?token=%s", siteURL, url.QueryEscape(token))
+	err = email.NotifyLandlordInvitation(inviteeEmail, principal.Email, db.LandlordRoleLandlord, inviteLink, expiresAt.Format("2 January 2006 at 15:04"))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to send invitation email: %s", err.Error()))
+		http.Redirect(w, r, landlordAdminPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+send+invitation+email", http.StatusSeeOther)
+		return
+	}
+
+	middleware.RecordAudit(s.store, r, db.AuditAccountInvited, "invitation", inviteeEmail, "")
+	logs.Logs(logInfo, "Landlord invitation sent")
+	http.Redirect(w, r, landlordAdminPath+"?inviteSent="+url.QueryEscape("Invitation sent to "+inviteeEmail), http.StatusSeeOther)
+}
+
+func (s *Server) LandlordAdminRevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	invitationIdInt, err := strconv.Atoi(r.FormValue("invitationId"))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid invitation ID: %s. Redirecting back to landlord admin page", err.Error()))
+		http.Redirect(w, r, landlordAdminPath+"?validationError=BAD+REQUEST+400:+Invalid+invitation", http.StatusSeeOther)
+		return
+	}
+
+	err = s.store.RevokeLandlordInvitation(r.Context(), invitationIdInt)
+	if err == sql.ErrNoRows {
+		logs.Logs(logWarn, "Invitation is not waiting to be accepted. Redirecting back to landlord admin page")
+		http.Redirect(w, r, landlordAdminPath+"?validationError=BAD+REQUEST+400:+Invitation+has+already+been+accepted+or+revoked", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to revoke invitation: %s", err.Error()))
+		http.Redirect(w, r, landlordAdminPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+revoke+invitation", http.StatusSeeOther)
+		return
+	}
+	middleware.RecordAudit(s.store, r, db.AuditAccountInviteRevoked, "invitation", strconv.Itoa(invitationIdInt), "")
+
+	http.Redirect(w, r, landlordAdminPath, http.StatusSeeOther)
+}
+
+func (s *Server) LandlordAdminSuspendAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	landlordIdInt, err := strconv.Atoi(r.FormValue("landlordId"))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid landlord ID: %s. Redirecting back to landlord admin page", err.Error()))
+		http.Redirect(w, r, landlordAdminPath+"?validationError=BAD+REQUEST+400:+Invalid+account", http.StatusSeeOther)
+		return
+	}
+
+	var suspend bool
+	switch r.FormValue("action") {
+	case "suspend":
+		suspend = true
+	case "reinstate":
+		suspend = false
+	default:
+		logs.Logs(logErr, "Invalid suspend action. Redirecting back to landlord admin page")
+		http.Redirect(w, r, landlordAdminPath+"?validationError=BAD+REQUEST+400:+Invalid+action", http.StatusSeeOther)
+		return
+	}
+
+	// the owner's own account is never matched, so the owner cannot lock themselves out
+	err = s.store.SetLandlordSuspended(r.Context(), landlordIdInt, suspend)
+	if err == sql.ErrNoRows {
+		logs.Logs(logWarn, "Landlord account cannot be suspended or reinstated. Redirecting back to landlord admin page")
+		http.Redirect(w, r, landlordAdminPath+"?validationError=BAD+REQUEST+400:+This+account+cannot+be+changed", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to update landlord account: %s", err.Error()))
+		http.Redirect(w, r, landlordAdminPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+update+account", http.StatusSeeOther)
+		return
+	}
+	action := db.AuditAccountReinstated
+	if suspend {
+		action = db.AuditAccountSuspended
+	}
+	middleware.RecordAudit(s.store, r, action, "landlord", strconv.Itoa(landlordIdInt), "")
+
+	http.Redirect(w, r, landlordAdminPath, http.StatusSeeOther)
+}
+
+func (s *Server) LandlordAdminRemoveAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	landlordIdInt, err := strconv.Atoi(r.FormValue("landlordId"))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid landlord ID: %s. Redirecting back to landlord admin page", err.Error()))
+		http.Redirect(w, r, landlordAdminPath+"?validationError=BAD+REQUEST+400:+Invalid+account", http.StatusSeeOther)
+		return
+	}
+
+	err = s.store.RemoveLandlordAccount(r.Context(), landlordIdInt)
+	if err == db.ErrLandlordHasRecords {
+		logs.Logs(logWarn, "Landlord account still has records. Redirecting back to landlord admin page")
+		http.Redirect(w, r, landlordAdminPath+"?validationError=CONFLICT+409:+This+account+still+has+tenants,+properties+or+messages.+Suspend+it+instead.", http.StatusSeeOther)
+		return
+	}
+	if err == sql.ErrNoRows {
+		logs.Logs(logWarn, "Landlord account cannot be removed. Redirecting back to landlord admin page")
+		http.Redirect(w, r, landlordAdminPath+"?validationError=BAD+REQUEST+400:+This+account+cannot+be+removed", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to remove landlord account: %s", err.Error()))
+		http.Redirect(w, r, landlordAdminPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+remove+account", http.StatusSeeOther)
+		return
+	}
+	middleware.RecordAudit(s.store, r, db.AuditAccountRemoved, "landlord", strconv.Itoa(landlordIdInt), "")
+
+	http.Redirect(w, r, landlordAdminPath, http.StatusSeeOther)
+}
+
+func (s *Server) LandlordAdminUnlockAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	landlordIdInt, err := strconv.Atoi(r.FormValue("landlordId"))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid landlord ID: %s. Redirecting back to landlord admin page", err.Error()))
+		http.Redirect(w, r, landlordAdminPath+"?validationError=BAD+REQUEST+400:+Invalid+account", http.StatusSeeOther)
+		return
+	}
+
+	err = s.store.UnlockLandlordAccount(r.Context(), landlordIdInt)
+	if err == sql.ErrNoRows {
+		logs.Logs(logWarn, "Landlord account is not locked. Redirecting back to landlord admin page")
+		http.Redirect(w, r, landlordAdminPath+"?validationError=BAD+REQUEST+400:+Account+is+not+locked", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to unlock landlord account: %s", err.Error()))
+		http.Redirect(w, r, landlordAdminPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+unlock+account", http.StatusSeeOther)
+		return
+	}
+	middleware.RecordAudit(s.store, r, db.AuditAccountUnlocked, "landlord", strconv.Itoa(landlordIdInt), "")
+
+	http.Redirect(w, r, landlordAdminPath, http.StatusSeeOther)
+}
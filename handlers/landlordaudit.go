@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+)
+
+const (
+	landlordAuditPath = "/landlord/dashboard/audit"
+	auditPageLimit    = 200 // the most entries shown on the page, the CSV export has every entry
+)
+
+// parseAuditFilter reads the audit log filter from the query string. Dates are whole days, and the to date is included.
+func parseAuditFilter(query url.Values) (db.AuditFilter, error) {
+	filter := db.AuditFilter{
+		Actor:    strings.TrimSpace(query.Get("actor")),
+		Action:   query.Get("action"),
+		TargetID: strings.TrimSpace(query.Get("target")),
+	}
+
+	if filter.Action != "" {
+		known := false
+		for _, action := range db.AuditActions {
+			if filter.Action == action {
+				known = true
+			}
+		}
+		if !known {
+			return filter, errors.New("unknown action")
+		}
+	}
+
+	var err error
+	if from := query.Get("from"); from != "" {
+		filter.From, err = time.Parse("2006-01-02", from)
+		if err != nil {
+			return filter, errors.New("invalid from date")
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		filter.To, err = time.Parse("2006-01-02", to)
+		if err != nil {
+			return filter, errors.New("invalid to date")
+		}
+		filter.To = filter.To.AddDate(0, 0, 1)
+	}
+	return filter, nil
+}
+
+// auditFilterQuery keeps only the filter fields of a query string, so the export link uses the same filter as the page.
+func auditFilterQuery(query url.Values) string {
+	filterQuery := url.Values{}
+	for _, key := range []string{"actor", "action", "target", "from", "to"} {
+		if value := query.Get(key); value != "" {
+			filterQuery.Set(key, value)
+		}
+	}
+	return filterQuery.Encode()
+}
+
+// csvCell stops a value from being run as a formula when the CSV export is opened in a spreadsheet.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
+
+func (s *Server) LandlordAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	query := r.URL.Query()
+	showData := ShowLandlordAuditLog{
+		Actions:   db.AuditActions,
+		Actor:     query.Get("actor"),
+		Action:    query.Get("action"),
+		Target:    query.Get("target"),
+		From:      query.Get("from"),
+		To:        query.Get("to"),
+		ExportURL: landlordAuditPath + "/export?" + auditFilterQuery(query),
+	}
+
+	filter, err := parseAuditFilter(query)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid audit log filter: %s", err.Error()))
+		showData.Error.ValidationError = "BAD REQUEST 400: Invalid filter, " + err.Error()
+		filter = db.AuditFilter{}
+	}
+
+	// one more entry than is shown is asked for, to tell whether there are more
+	entries, err := s.store.GetAuditLog(r.Context(), landlordEmail, filter, auditPageLimit+1)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get audit log: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get audit log: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if len(entries) > auditPageLimit {
+		entries = entries[:auditPageLimit]
+		showData.Truncated = true
+	}
+	for _, entry := range entries {
+		showData.Entries = append(showData.Entries, ShowAuditEntry{
+			ID:         entry.ID,
+			CreatedAt:  entry.CreatedAt.Format("2006-01-02 15:04:05"),
+			ActorType:  entry.ActorType,
+			Actor:      entry.Actor,
+			Action:     entry.Action,
+			TargetType: entry.TargetType,
+			TargetID:   entry.TargetID,
+			Details:    entry.Details,
+			IPAddress:  entry.IPAddress,
+			UserAgent:  entry.UserAgent,
+		})
+	}
+
+	showData.BrokenAt, err = s.store.VerifyAuditLog(r.Context())
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to verify audit log: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to verify audit log: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = Templates.ExecuteTemplate(w, "landlordAudit.html", showData)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Unable to load landlord audit log page: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Unable to load landlord audit log page: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) LandlordAuditLogExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid audit log filter: %s. Redirecting back to landlord audit log page", err.Error()))
+		http.Redirect(w, r, landlordAuditPath+"?"+auditFilterQuery(r.URL.Query()), http.StatusSeeOther)
+		return
+	}
+
+	entries, err := s.store.GetAuditLog(r.Context(), landlordEmail, filter, 0)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get audit log: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get audit log: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// exporting the log is itself recorded, before anything is sent
+	err = middleware.RecordAudit(s.store, r, db.AuditLogExported, "audit_log", "", fmt.Sprintf("%d entries, filter: %s", len(entries), auditFilterQuery(r.URL.Query())))
+	if err != nil {
+		http.Error(w, "Failed to record audit log export", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="lhp-audit-log-%s.csv"`, time.Now().Format("20060102")))
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "time_utc", "actor_type", "actor", "action", "target_type", "target_id", "details", "ip_address", "user_agent", "prev_hash", "hash"})
+	for _, entry := range entries {
+		writer.Write([]string{
+			fmt.Sprint(entry.ID),
+			entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+			csvCell(entry.ActorType),
+			csvCell(entry.Actor),
+			csvCell(entry.Action),
+			csvCell(entry.TargetType),
+			csvCell(entry.TargetID),
+			csvCell(entry.Details),
+			csvCell(entry.IPAddress),
+			csvCell(entry.UserAgent),
+			entry.PrevHash,
+			entry.Hash,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to write audit log export: %s", err.Error()))
+	}
+}
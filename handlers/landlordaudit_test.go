@@ -0,0 +1,85 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestLandlordAuditLog(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// Define test cases, none of which reach the database
+	testCases := []struct {
+		name               string
+		handler            http.HandlerFunc
+		method             string
+		target             string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name:               "Audit log page with invalid method",
+			handler:            testutil.TestEnvironment.Handlers.LandlordAuditLog,
+			method:             http.MethodPost,
+			target:             "/landlord/dashboard/audit",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedLocation:   "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method",
+		},
+		{
+			name:               "Export with invalid method",
+			handler:            testutil.TestEnvironment.Handlers.LandlordAuditLogExport,
+			method:             http.MethodPost,
+			target:             "/landlord/dashboard/audit/export",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedLocation:   "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method",
+		},
+		{
+			name:               "Export with an unknown action",
+			handler:            testutil.TestEnvironment.Handlers.LandlordAuditLogExport,
+			method:             http.MethodGet,
+			target:             "/landlord/dashboard/audit/export?action=made.up&page=2",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/landlord/dashboard/audit?action=made.up",
+		},
+		{
+			name:               "Export with an invalid date",
+			handler:            testutil.TestEnvironment.Handlers.LandlordAuditLogExport,
+			method:             http.MethodGet,
+			target:             "/landlord/dashboard/audit/export?from=17-10-2026&actor=staff",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/landlord/dashboard/audit?actor=staff&from=17-10-2026",
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Create a request
+			req := httptest.NewRequest(tc.method, tc.target, nil)
+
+			// Create a response recorder
+			rr := httptest.NewRecorder()
+
+			// Call the handler
+			tc.handler(rr, req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location
+			location := rr.Header().Get("Location")
+			if location != tc.expectedLocation {
+				t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestLandlordDashboard(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// Define test cases
+	testCases := []struct {
+		name               string
+		request            func() *http.Request
+		expectedStatusCode int
+		expectedLocation   string
+		failDB             bool
+		failDBReason       string
+		timeoutDB          bool
+	}{
+		{
+			name: "Valid session",
+			request: func() *http.Request {
+				return testutil.LandlordRequest(http.MethodGet, "/landlord/dashboard", nil, "test@example.com")
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "Missing session cookie",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/landlord/dashboard", nil)
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord",
+		},
+		{
+			name: "Invalid session token",
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/landlord/dashboard", nil)
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: "invalid_session_token"})
+				req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "csrf_token_test@example.com"})
+				return req
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord",
+		},
+		{
+			name: "Database error when validating session",
+			request: func() *http.Request {
+				return testutil.LandlordRequest(http.MethodGet, "/landlord/dashboard", nil, "test@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord",
+			failDB:             true,
+			failDBReason:       "database error",
+		},
+		{
+			name: "Database timeout when validating session",
+			request: func() *http.Request {
+				return testutil.LandlordRequest(http.MethodGet, "/landlord/dashboard", nil, "test@example.com")
+			},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			timeoutDB:          true,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.request()
+
+			// Set up mock database to fail if needed
+			if tc.failDB {
+				testutil.TestEnvironment.DB.SetFailNextOperation(tc.failDBReason)
+			}
+			if tc.timeoutDB {
+				testutil.TestEnvironment.DB.SetTimeoutNextOperation()
+			}
+
+			rr := testutil.ServeTestRequest(req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location if applicable
+			if tc.expectedLocation != "" {
+				location := rr.Header().Get("Location")
+				if location != tc.expectedLocation {
+					t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+				}
+			}
+		})
+	}
+}
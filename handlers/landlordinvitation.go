@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+func (s *Server) LandlordInvitation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to home page.", r.Method))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// the invitation token is in the URL, so do not leak it to other sites
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	showData := ShowLandlordInvitation{Token: r.URL.Query().Get("token")}
+	showData.Error.ConfirmPasswordError = r.URL.Query().Get("confirmPasswordError")
+	showData.Error.InternalServerError = r.URL.Query().Get("internalServerError")
+
+	invitation, err := s.store.GetLandlordInvitation(r.Context(), showData.Token)
+	if err == db.ErrInvalidInvitation {
+		showData.Error.AuthenticationError = "This invitation is invalid, has already been used or has expired. Please ask for a new invitation."
+	} else if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to check invitation: %s", err.Error()))
+		showData.Error.InternalServerError = "INTERNAL SERVER ERROR 500: Failed to check invitation"
+	} else {
+		showData.Email = invitation.Email
+		showData.InvitedBy = invitation.InvitedBy
+		showData.ExpiresAt = invitation.ExpiresAt.Format("2006-01-02 15:04")
+	}
+
+	err = Templates.ExecuteTemplate(w, "landlordInvitation.html", showData)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Unable to load invitation page: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Unable to load invitation page: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) SubmitLandlordInvitation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to home page.", r.Method))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// extract data from form
+	token := r.FormValue("token")
+	landlordPassword := r.FormValue("landlordPassword")
+	confirmPassword := r.FormValue("confirmPassword")
+
+	invitationPage := "/invitation?token=" + url.QueryEscape(token)
+
+	// validate passwords
+	if landlordPassword == "" || !utils.ValidateNewPassword(landlordPassword, confirmPassword) {
+		logs.Logs(logErr, "Passwords do not match. Redirecting back to invitation page")
+		http.Redirect(w, r, invitationPage+"&confirmPasswordError=Passwords+do+not+match.+Please+try+again.", http.StatusSeeOther)
+		return
+	}
+
+	_, err = s.store.AcceptLandlordInvitation(r.Context(), token, landlordPassword)
+	if err == db.ErrInvalidInvitation {
+		logs.Logs(logWarn, "Invalid invitation token. Redirecting back to invitation page")
+		http.Redirect(w, r, invitationPage, http.StatusSeeOther)
+		return
+	}
+	if err == db.ErrLandlordExists {
+		logs.Logs(logWarn, "Invitation accepted for an email that already has an account. Redirecting to landlord login page")
+		http.Redirect(w, r, "/login/landlord?authenticationError=CONFLICT+409:+An+account+with+this+email+already+exists.+Please+login.", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to accept invitation: %s", err.Error()))
+		http.Redirect(w, r, invitationPage+"&internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+account", http.StatusSeeOther)
+		return
+	}
+
+	logs.Logs(logInfo, "Invited landlord account created successfully")
+	http.Redirect(w, r, "/login/landlord?accountCreated=Your+account+has+been+created.+Please+login.", http.StatusSeeOther)
+}
@@ -0,0 +1,114 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestLandlordRegistration(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	defaults := config.Registration
+	defer func() { config.Registration = defaults }()
+
+	// Define test cases, none of which reach the database
+	testCases := []struct {
+		name               string
+		handler            http.HandlerFunc
+		publicRegistration bool
+		method             string
+		target             string
+		formValues         map[string]string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name:               "Registration is invite only by default",
+			handler:            testutil.TestEnvironment.Handlers.SubmitNewLandlord,
+			method:             http.MethodPost,
+			target:             "/new/landlord/submit",
+			formValues:         map[string]string{"landlordEmail": "landlord@example.com", "landlordPassword": "password", "confirmPassword": "password"},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/new/landlord",
+		},
+		{
+			name:               "Public registration checks passwords",
+			handler:            testutil.TestEnvironment.Handlers.SubmitNewLandlord,
+			publicRegistration: true,
+			method:             http.MethodPost,
+			target:             "/new/landlord/submit",
+			formValues:         map[string]string{"landlordEmail": "landlord@example.com", "landlordPassword": "password", "confirmPassword": "different"},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/new/landlord?confirmPasswordError=Passwords+do+not+match.+Please+try+again.",
+		},
+		{
+			name:               "Invitation page with invalid method",
+			handler:            testutil.TestEnvironment.Handlers.LandlordInvitation,
+			method:             http.MethodPost,
+			target:             "/invitation?token=abc",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "Accept invitation with invalid method",
+			handler:            testutil.TestEnvironment.Handlers.SubmitLandlordInvitation,
+			method:             http.MethodGet,
+			target:             "/invitation/submit",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "Accept invitation with passwords that do not match",
+			handler:            testutil.TestEnvironment.Handlers.SubmitLandlordInvitation,
+			method:             http.MethodPost,
+			target:             "/invitation/submit",
+			formValues:         map[string]string{"token": "abc.123.sig", "landlordPassword": "password", "confirmPassword": "different"},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/invitation?token=abc.123.sig&confirmPasswordError=Passwords+do+not+match.+Please+try+again.",
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config.Registration.PublicLandlordRegistration = tc.publicRegistration
+
+			// Create form data
+			form := url.Values{}
+			for key, value := range tc.formValues {
+				form.Add(key, value)
+			}
+
+			// Create a request
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			// Create a response recorder
+			rr := httptest.NewRecorder()
+
+			// Call the handler
+			tc.handler(rr, req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location
+			location := rr.Header().Get("Location")
+			if location != tc.expectedLocation {
+				t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+			}
+		})
+	}
+}
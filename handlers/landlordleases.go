@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+func (s *Server) LandlordLeases(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// get any error messages
+	var showData ShowLandlordLeases
+	showData.CSRFToken = principal.CSRFToken
+	showData.Error.ValidationError = r.URL.Query().Get("validationError")
+	showData.Error.InternalServerError = r.URL.Query().Get("internalServerError")
+
+	// get all tenants for the landlord and their leases
+	leases, err := s.store.GetLeasesByLandlordEmail(r.Context(), landlordEmail)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get landlord leases: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get landlord leases: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	for _, landlordLease := range leases {
+		tenantName, err := utils.Decrypt(landlordLease.EncryptTenantName)
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Failed to decrypt tenant name: %s", err.Error()))
+			http.Error(w, fmt.Sprintf("Failed to decrypt tenant name: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		showLease := ShowLease{TenantID: landlordLease.TenantID}
+		if landlordLease.Lease != nil {
+			showLease, err = buildLease(*landlordLease.Lease, landlordLease.OpenRenewal)
+			if err != nil {
+				logs.Logs(logErr, fmt.Sprintf("Failed to get lease for tenant %d: %s", landlordLease.TenantID, err.Error()))
+				http.Error(w, fmt.Sprintf("Failed to get lease: %s", err.Error()), http.StatusInternalServerError)
+				return
+			}
+		}
+		showLease.TenantName = string(tenantName)
+		showData.Leases = append(showData.Leases, showLease)
+	}
+
+	// direct user to protected landlord leases page
+	err = Templates.ExecuteTemplate(w, "landlordLeases.html", showData)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Unable to load landlord leases: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Unable to load landlord leases: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
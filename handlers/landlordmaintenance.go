@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+)
+
+func (s *Server) LandlordMaintenance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// get any error messages
+	var showData ShowMaintenanceTickets
+	showData.CSRFToken = principal.CSRFToken
+	showData.Error.ValidationError = r.URL.Query().Get("validationError")
+	showData.Error.InternalServerError = r.URL.Query().Get("internalServerError")
+
+	tickets, err := s.store.GetMaintenanceTicketsByLandlordEmail(r.Context(), landlordEmail)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get maintenance tickets: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get maintenance tickets: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	showData.Tickets, err = buildMaintenanceTickets(tickets)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to get maintenance tickets: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get maintenance tickets: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = Templates.ExecuteTemplate(w, "landlordMaintenance.html", showData)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Unable to load landlord maintenance page: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Unable to load landlord maintenance page: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -11,76 +12,19 @@ import (
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
 )
 
-func LandlordManageApplications(w http.ResponseWriter, r *http.Request) {
+func (s *Server) LandlordManageApplications(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		logs.Logs(logErr, "Invalid request method. Redirecting back to landlord login page.")
 		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
 		return
 	}
 
-	// deny the request if the authorization fails
-	err := middleware.AuthenticateLandlordRequest(r)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error authenticating landlord: %s. Redirecting to landlord login page", err.Error()))
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord", http.StatusSeeOther)
-		return
-	}
-
-	// get session cookie
-	sessionToken, err := utils.CheckSessionToken(r)
-	if err != nil {
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+session+token", http.StatusSeeOther)
-		return
-	}
-
-	// get landlord emial from session cookie
-	landlordEmail, err := db.GetEmailFromLandlordSessionToken(sessionToken.Value)
-	if err != nil {
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+landlord+email+from+session+token", http.StatusSeeOther)
-		return
-	}
-
-	// update the landlord's session token, CSRF token and expiry time in the database
-	// this will be doen for each request
-	newSessionToken, newCsrfToken, newExpiryTime, err := db.UpdateLandlordSessionTokens(landlordEmail)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error updating landlord session tokens: %s. Redirecting to landlord login page", err.Error()))
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+update+session+tokens", http.StatusSeeOther)
-		return
-	}
-
-	// TODO! Set new cookies for each available page via tenant applications page
-
-	// set new cookies for landlord dashboard - after successful form submission
-	createLandlordDashboardSessionCookie := middleware.LandlordDashboardSessionCookie(w, newSessionToken, newExpiryTime)
-	if !createLandlordDashboardSessionCookie {
-		logs.Logs(logErr, "Failed to get session cookie for landlord dashboard. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+session+cookie", http.StatusSeeOther)
-		return
-	}
-	createLandordDashboardCSRFTokenCookie := middleware.LandlordDashboardCSRFTokenCookie(w, newCsrfToken, newExpiryTime)
-	if !createLandordDashboardCSRFTokenCookie {
-		logs.Logs(logErr, "Failed to get CSRF token cookie for landlord dashboard. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+CSRF+token+cookie", http.StatusSeeOther)
-		return
-	}
-
-	// set new cookies for landlord manage applications - if values from submission form are missing
-	createLandlordTenantApplicationsSessionCookie := middleware.LandlordDashboardTenantApplicationsSessionCookie(w, newSessionToken, newExpiryTime)
-	if !createLandlordTenantApplicationsSessionCookie {
-		logs.Logs(logErr, "Failed to get session cookie for landlord tenant applications. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+session+cookie", http.StatusSeeOther)
-		return
-	}
-	createLandordTenantApplictionsCSRFTokenCookie := middleware.LandlordDashboardTenantApplicationsCSRFTokenCookie(w, newCsrfToken, newExpiryTime)
-	if !createLandordTenantApplictionsCSRFTokenCookie {
-		logs.Logs(logErr, "Failed to get CSRF token cookie for landlord tenant applications. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+CSRF+token+cookie", http.StatusSeeOther)
-		return
-	}
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
 
 	// parse form data
-	err = r.ParseForm()
+	err := r.ParseForm()
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
 		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
@@ -90,7 +34,7 @@ func LandlordManageApplications(w http.ResponseWriter, r *http.Request) {
 	// extract data from form
 	applicationId := r.FormValue("applicationId")
 	applicationResult := r.FormValue("applicationResult")
-	roomType := r.FormValue("roomType")
+	roomId := r.FormValue("roomId")
 	moveInDate := r.FormValue("moveInDate")
 	rentDue := r.FormValue("rentDue")
 	monthlyRent := r.FormValue("monthlyRent")
@@ -98,88 +42,103 @@ func LandlordManageApplications(w http.ResponseWriter, r *http.Request) {
 
 	// if the application has been denied
 	if applicationResult == "denied" {
-		err = db.UpdateTenantApplicationStatus(applicationId, applicationResult)
+		err = s.store.UpdateTenantApplicationStatus(r.Context(), applicationId, applicationResult)
 		if err != nil {
+			if middleware.StoreUnavailable(w, err) {
+				return
+			}
+			// an application accepted from another tab, or denied already, is left as it is
+			if errors.Is(err, db.ErrApplicationDecided) {
+				logs.Logs(logErr, "Tenant application has already been decided. Redirecting back to landlord tenant applications page")
+				http.Redirect(w, r, "/landlord/dashboard/tenant-applications?validationError=BAD+REQUEST+400:+This+application+has+already+been+decided", http.StatusSeeOther)
+				return
+			}
 			logs.Logs(logErr, fmt.Sprintf("Error updating tenant application status: %s", err.Error()))
 			http.Error(w, fmt.Sprintf("Error updating tenant application status: %s", err.Error()), http.StatusInternalServerError)
 			return
 		}
+		middleware.RecordAudit(s.store, r, db.AuditApplicationDenied, "application", applicationId, "")
 		http.Redirect(w, r, "/landlord/dashboard", http.StatusSeeOther)
-	}
-
-	// validate the form data
-	err = utils.ValidateManageTenantApplication(applicationResult, roomType, moveInDate, rentDue, monthlyRent, currency)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error validating tenant application form data: %s. Redirecting back to landlord tenant applications page", err.Error()))
-		http.Redirect(w, r, "/landlord/dashboard/tenant-applications?validationError=BAD+REQUEST+400:+Error+validating+tenant+application+form+data.+Missing+parameters.", http.StatusSeeOther)
 		return
 	}
 
-	// update the tenant application status
-	err = db.UpdateTenantApplicationStatus(applicationId, applicationResult)
+	// the tenant can only be given one of the landlord's rooms. Whether it still has space is checked when the
+	// application is accepted, as the room is full if this form is sent again after the tenant was given it
+	room, err := s.getLandlordRoom(r.Context(), landlordEmail, roomId)
 	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error updating tenant application status: %s", err.Error()))
-		http.Error(w, fmt.Sprintf("Error updating tenant application status: %s", err.Error()), http.StatusInternalServerError)
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Invalid room: %s. Redirecting back to landlord tenant applications page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/tenant-applications?validationError=BAD+REQUEST+400:+Please+select+a+vacant+room", http.StatusSeeOther)
 		return
 	}
+	roomType := room.RoomType
 
-	// TODO: get email & passport number via applicationID from database
-	encryptEmail, encryptPassportNumber, err := db.GetTenantEmailAndPassportNumberViaApplicationID(applicationId)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error getting tenant email & passport number from database: %s", err.Error()))
-		http.Redirect(w, r, "/landlord/dashboard?internalServerError=INTERNAL+SERVER+ERROR+500:+Error+getting+tenant+email+&+passport+number+from+database", http.StatusSeeOther)
-		return
+	// the room's default rent is used when no rent is given
+	if monthlyRent == "" {
+		monthlyRent = room.DefaultRent
 	}
-
-	tenantEmail, err := (utils.Decrypt([]byte(encryptEmail)))
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error decrypting email: %s", err.Error()))
-		http.Error(w, fmt.Sprintf("Error decrypting email: %s", err.Error()), http.StatusInternalServerError)
-		return
+	if currency == "" {
+		currency = room.Currency
 	}
-	strTenantEmail := string(tenantEmail)
 
-	tenantPassportNumber, err := (utils.Decrypt([]byte(encryptPassportNumber)))
+	// validate the form data
+	err = utils.ValidateManageTenantApplication(applicationResult, roomType, moveInDate, rentDue, monthlyRent, currency)
 	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error decrypting passport number: %s", err.Error()))
-		http.Error(w, fmt.Sprintf("Error decrypting passport number: %s", err.Error()), http.StatusInternalServerError)
+		logs.Logs(logErr, fmt.Sprintf("Error validating tenant application form data: %s. Redirecting back to landlord tenant applications page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/tenant-applications?validationError=BAD+REQUEST+400:+Error+validating+tenant+application+form+data.+Missing+parameters.", http.StatusSeeOther)
 		return
 	}
-	strTenantPassportNumber := string(tenantPassportNumber)
 
-	// TODO: generate new tenant username & password
-	tenantUsername, tenantPassword, err := utils.GenerateTenantUsernamePassportNumberAndPassword(strTenantEmail, strTenantPassportNumber)
+	// lease terms are optional, a 12 month lease with 30 days notice is used by default
+	termMonths, breakClauseMonths, noticeDays, err := utils.ParseLeaseTerms(r.FormValue("leaseTerm"), r.FormValue("breakClause"), r.FormValue("noticePeriod"))
 	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error generating tenant username & password: %s", err.Error()))
-		http.Error(w, fmt.Sprintf("Error generating tenant username & password: %s", err.Error()), http.StatusInternalServerError)
-		return
-	}
-
-	logs.Logs(logInfo, fmt.Sprintf("New tenant username: %s, New tenant password: %s", tenantUsername, tenantPassword))
-
-	// TODO: send email to tenant with new username & password
-	err = email.NotifyTenantNewAccount(tenantUsername, tenantPassword, roomType, moveInDate, rentDue, monthlyRent, currency)
+		logs.Logs(logErr, fmt.Sprintf("Invalid lease terms: %s. Redirecting back to landlord tenant applications page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/tenant-applications?validationError=BAD+REQUEST+400:+Invalid+lease+terms.+The+break+clause+must+be+before+the+end+of+the+lease", http.StatusSeeOther)
+		return
+	}
+
+	// the application is accepted, the tenant and their lease created and their login details queued to be emailed
+	// in one transaction, so nothing is sent unless it all commits and sending the form again changes nothing
+	accepted, err := s.store.AcceptTenantApplication(r.Context(), landlordEmail, db.TenantAcceptance{
+		ApplicationID:     applicationId,
+		RoomID:            room.ID,
+		MoveInDate:        moveInDate,
+		RentDue:           rentDue,
+		MonthlyRent:       monthlyRent,
+		Currency:          currency,
+		TermMonths:        termMonths,
+		BreakClauseMonths: breakClauseMonths,
+		NoticeDays:        noticeDays,
+	})
 	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Failed to send email notification to tenant: %s", err.Error()))
-		http.Error(w, fmt.Sprintf("Failed to send email notification to tenant: %s", err.Error()), http.StatusInternalServerError)
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		if errors.Is(err, db.ErrApplicationDecided) {
+			logs.Logs(logErr, "Tenant application has already been denied. Redirecting back to landlord tenant applications page")
+			http.Redirect(w, r, "/landlord/dashboard/tenant-applications?validationError=BAD+REQUEST+400:+This+application+has+already+been+denied", http.StatusSeeOther)
+			return
+		}
+		if errors.Is(err, db.ErrRoomFull) {
+			logs.Logs(logErr, "Room is fully occupied. Redirecting back to landlord tenant applications page")
+			http.Redirect(w, r, "/landlord/dashboard/tenant-applications?validationError=BAD+REQUEST+400:+Please+select+a+vacant+room", http.StatusSeeOther)
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to accept tenant application: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to accept tenant application: %s", err.Error()), http.StatusInternalServerError)
 		return
 	}
-
-	// TODO: send email to landlord with tenant username & password
-	err = email.NotifyLandlordNewAccount(tenantUsername, tenantPassword, roomType, moveInDate, rentDue, monthlyRent, currency)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Failed to send email notification to landlord: %s", err.Error()))
-		http.Error(w, fmt.Sprintf("Failed to send email notification to landlord: %s", err.Error()), http.StatusInternalServerError)
+	if !accepted {
+		logs.Logs(logInfo, fmt.Sprintf("Tenant application %s has already been accepted. Redirecting to landlord dashboard", applicationId))
+		http.Redirect(w, r, "/landlord/dashboard", http.StatusSeeOther)
 		return
 	}
+	middleware.RecordAudit(s.store, r, db.AuditApplicationAccepted, "application", applicationId, fmt.Sprintf("room %d, move in %s", room.ID, moveInDate))
 
-	// TODO: Save tenant to database
-	err = db.CreateNewTenant(tenantUsername, tenantPassword, roomType, moveInDate, rentDue, monthlyRent, currency)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Failed to save tenant to database: %s", err.Error()))
-		http.Error(w, fmt.Sprintf("Failed to save tenant to database: %s", err.Error()), http.StatusInternalServerError)
-		return
-	}
+	// the new account emails are sent by the outbox dispatcher now the transaction has committed
+	email.WakeOutbox()
 
 	http.Redirect(w, r, "/landlord/dashboard", http.StatusSeeOther)
 }
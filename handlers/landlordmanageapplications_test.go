@@ -0,0 +1,335 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestLandlordTenantApplications(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// Define test cases
+	testCases := []struct {
+		name               string
+		request            func() *http.Request
+		expectedStatusCode int
+		expectedLocation   string
+		expectedBody       string
+		failDB             bool
+		failDBReason       string
+	}{
+		{
+			name: "Valid session",
+			request: func() *http.Request {
+				return testutil.LandlordRequest(http.MethodGet, "/landlord/dashboard/tenant-applications", nil, "test@example.com")
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "John Doe",
+		},
+		{
+			name: "Missing session cookie",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/landlord/dashboard/tenant-applications", nil)
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord",
+		},
+		{
+			name: "Database error when validating session",
+			request: func() *http.Request {
+				return testutil.LandlordRequest(http.MethodGet, "/landlord/dashboard/tenant-applications", nil, "test@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord",
+			failDB:             true,
+			failDBReason:       "database error",
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.request()
+
+			// Set up mock database to fail if needed
+			if tc.failDB {
+				testutil.TestEnvironment.DB.SetFailNextOperation(tc.failDBReason)
+			}
+
+			rr := testutil.ServeTestRequest(req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location if applicable
+			if tc.expectedLocation != "" {
+				location := rr.Header().Get("Location")
+				if location != tc.expectedLocation {
+					t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+				}
+			}
+
+			// Check the decrypted application is shown if applicable
+			if tc.expectedBody != "" && !strings.Contains(rr.Body.String(), tc.expectedBody) {
+				t.Errorf("Expected response body to contain '%s'", tc.expectedBody)
+			}
+		})
+	}
+}
+
+func TestLandlordManageApplications(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// a second landlord, who cannot decide the test landlord's applications
+	testutil.TestEnvironment.DB.CreateNewLandlord(context.Background(), "other@example.com", "password123")
+
+	// rooms the application cannot be accepted into
+	fullRoomId := fullRoom(t, "manage-full@example.com")
+	otherRoomId := otherLandlordRoom(t, "other@example.com")
+
+	// Define test cases
+	testCases := []struct {
+		name               string
+		request            func() *http.Request
+		expectedStatusCode int
+		expectedLocation   string
+		expectedStatus     string
+		failDB             bool
+		failDBReason       string
+	}{
+		{
+			name: "Missing session cookie",
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/landlord/dashboard/manage-applications", strings.NewReader("applicationId=2&applicationResult=denied"))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord",
+			expectedStatus:     "pending",
+		},
+		{
+			name: "Invalid CSRF token",
+			request: func() *http.Request {
+				form := url.Values{"applicationId": {"2"}, "applicationResult": {"denied"}, "csrf_token": {"invalid_csrf_token"}}
+				return testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/manage-applications", form, "test@example.com")
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedStatus:     "pending",
+		},
+		{
+			name: "Application of another landlord",
+			request: func() *http.Request {
+				form := url.Values{"applicationId": {"2"}, "applicationResult": {"denied"}}
+				return testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/manage-applications", form, "other@example.com")
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedStatus:     "pending",
+		},
+		{
+			name: "Accept without a vacant room",
+			request: func() *http.Request {
+				form := url.Values{"applicationId": {"2"}, "applicationResult": {"accepted"}, "roomId": {"99"}}
+				return testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/manage-applications", form, "test@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/landlord/dashboard/tenant-applications?validationError=BAD+REQUEST+400:+Please+select+a+vacant+room",
+			expectedStatus:     "pending",
+		},
+		{
+			name: "Accept into a room that is full",
+			request: func() *http.Request {
+				form := url.Values{"applicationId": {"2"}, "applicationResult": {"accepted"}, "roomId": {fullRoomId}, "moveInDate": {"2025-02-01"}, "rentDue": {"1"}}
+				return testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/manage-applications", form, "test@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/landlord/dashboard/tenant-applications?validationError=BAD+REQUEST+400:+Please+select+a+vacant+room",
+			expectedStatus:     "pending",
+		},
+		{
+			name: "Accept into a room of another landlord",
+			request: func() *http.Request {
+				form := url.Values{"applicationId": {"2"}, "applicationResult": {"accepted"}, "roomId": {otherRoomId}, "moveInDate": {"2025-02-01"}, "rentDue": {"1"}}
+				return testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/manage-applications", form, "test@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/landlord/dashboard/tenant-applications?validationError=BAD+REQUEST+400:+Please+select+a+vacant+room",
+			expectedStatus:     "pending",
+		},
+		{
+			name: "Database error when checking the form token",
+			request: func() *http.Request {
+				form := url.Values{"applicationId": {"2"}, "applicationResult": {"denied"}}
+				return testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/manage-applications", form, "test@example.com")
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedStatus:     "pending",
+			failDB:             true,
+			failDBReason:       "database error",
+		},
+		{
+			name: "Valid status update - Deny",
+			request: func() *http.Request {
+				form := url.Values{"applicationId": {"2"}, "applicationResult": {"denied"}}
+				return testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/manage-applications", form, "test@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/landlord/dashboard",
+			expectedStatus:     "denied",
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.request()
+
+			// Set up mock database to fail if needed
+			if tc.failDB {
+				testutil.TestEnvironment.DB.SetFailNextOperation(tc.failDBReason)
+			}
+
+			rr := testutil.ServeTestRequest(req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location if applicable
+			if tc.expectedLocation != "" {
+				location := rr.Header().Get("Location")
+				if location != tc.expectedLocation {
+					t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+				}
+			}
+
+			// Check the application's status in the database
+			applications, err := testutil.TestEnvironment.DB.GetAllTenantApplications(context.Background(), "test@example.com")
+			if err != nil || len(applications) != 2 {
+				t.Fatalf("Expected 2 applications, got %d: %v", len(applications), err)
+			}
+			if applications[1].Status != tc.expectedStatus {
+				t.Errorf("Expected application status '%s', got '%s'", tc.expectedStatus, applications[1].Status)
+			}
+		})
+	}
+
+	// Check the decision was audited
+	var audited bool
+	for _, entry := range testutil.TestEnvironment.DB.AuditLog() {
+		if entry.Action == db.AuditApplicationDenied && entry.TargetID == "2" {
+			audited = true
+		}
+	}
+	if !audited {
+		t.Errorf("Expected the denied application to be recorded in the audit log")
+	}
+}
+
+func TestLandlordManageApplicationsAccept(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// an application and a room of its own, as the shared applications and rooms are decided and filled by other tests
+	ctx := context.Background()
+	applicationId := testutil.AddTestApplication("Accepted Doe", "accepted@example.com")
+	testutil.TestEnvironment.DB.CreateRoom(ctx, "test@example.com", 1, "2", "Room 2", "Double Room", 1, "", "1200", "USD")
+	rooms, _ := testutil.TestEnvironment.DB.GetRoomsByLandlordEmail(ctx, "test@example.com")
+	roomId := strconv.Itoa(rooms[len(rooms)-1].ID)
+	tenantsBefore, _ := testutil.TestEnvironment.DB.GetTenantsByLandlordEmail(ctx, "test@example.com")
+
+	// the same form is sent twice, as when a landlord resubmits it, and must only accept the application once
+	for i := 0; i < 2; i++ {
+		form := url.Values{
+			"applicationId":     {applicationId},
+			"applicationResult": {"accepted"},
+			"roomId":            {roomId},
+			"moveInDate":        {"2025-02-01"},
+			"rentDue":           {"1"},
+		}
+		req := testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/manage-applications", form, "test@example.com")
+		rr := testutil.ServeTestRequest(req)
+
+		if rr.Code != http.StatusSeeOther {
+			t.Fatalf("Expected status code %d, got %d", http.StatusSeeOther, rr.Code)
+		}
+		if location := rr.Header().Get("Location"); location != "/landlord/dashboard" {
+			t.Errorf("Expected redirect to '/landlord/dashboard', got '%s'", location)
+		}
+	}
+
+	// Check the tenant was created once
+	tenants, err := testutil.TestEnvironment.DB.GetTenantsByLandlordEmail(ctx, "test@example.com")
+	if err != nil || len(tenants) != len(tenantsBefore)+1 {
+		t.Errorf("Expected %d tenants, got %d: %v", len(tenantsBefore)+1, len(tenants), err)
+	}
+
+	// Check the new account emails were queued once, for the tenant and the landlord
+	emails := testutil.TestEnvironment.DB.OutboxEmails()
+	if len(emails) != 2 {
+		t.Fatalf("Expected 2 outbox emails, got %d", len(emails))
+	}
+	if emails[0].Kind != db.OutboxTenantNewAccount || emails[1].Kind != db.OutboxLandlordNewAccount {
+		t.Errorf("Expected tenant and landlord new account emails, got '%s' and '%s'", emails[0].Kind, emails[1].Kind)
+	}
+	if !strings.Contains(string(emails[0].Payload), "accepted@example.com") {
+		t.Errorf("Expected the new account email to be for accepted@example.com, got %s", emails[0].Payload)
+	}
+	if !strings.Contains(string(emails[1].Payload), `"landlord_email":"test@example.com"`) {
+		t.Errorf("Expected the landlord email to be sent to test@example.com, got %s", emails[1].Payload)
+	}
+
+	// Check the acceptance was audited once
+	var audited int
+	for _, entry := range testutil.TestEnvironment.DB.AuditLog() {
+		if entry.Action == db.AuditApplicationAccepted && entry.TargetID == applicationId {
+			audited++
+		}
+	}
+	if audited != 1 {
+		t.Errorf("Expected the accepted application to be audited once, got %d", audited)
+	}
+
+	// a deny sent afterwards, as from a stale form or another tab, leaves the accepted application as it is
+	form := url.Values{"applicationId": {applicationId}, "applicationResult": {"denied"}}
+	req := testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/manage-applications", form, "test@example.com")
+	rr := testutil.ServeTestRequest(req)
+	expectedLocation := "/landlord/dashboard/tenant-applications?validationError=BAD+REQUEST+400:+This+application+has+already+been+decided"
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != expectedLocation {
+		t.Errorf("Expected redirect to '%s', got %d to '%s'", expectedLocation, rr.Code, rr.Header().Get("Location"))
+	}
+	applications, err := testutil.TestEnvironment.DB.GetAllTenantApplications(ctx, "test@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get tenant applications: %s", err.Error())
+	}
+	for _, application := range applications {
+		if strconv.Itoa(application.ID) == applicationId && application.Status != "accepted" {
+			t.Errorf("Expected the application to stay accepted, got '%s'", application.Status)
+		}
+	}
+	for _, entry := range testutil.TestEnvironment.DB.AuditLog() {
+		if entry.Action == db.AuditApplicationDenied && entry.TargetID == applicationId {
+			t.Errorf("Expected no denial to be audited for an accepted application")
+		}
+	}
+}
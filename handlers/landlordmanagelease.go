@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+func (s *Server) LandlordManageLease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// extract data from form
+	tenantID := r.FormValue("tenantId")
+	leaseAction := r.FormValue("leaseAction")
+
+	// the route only runs for the landlord's own tenants, see middleware.RequireOwnership
+	tenantIdInt, err := strconv.Atoi(tenantID)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid tenant ID: %s. Redirecting back to landlord leases page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/leases?validationError=BAD+REQUEST+400:+Please+select+a+tenant", http.StatusSeeOther)
+		return
+	}
+
+	if leaseAction == "create" {
+		termMonths, breakClauseMonths, noticeDays, err := utils.ParseLeaseTerms(r.FormValue("leaseTerm"), r.FormValue("breakClause"), r.FormValue("noticePeriod"))
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Invalid lease terms: %s. Redirecting back to landlord leases page", err.Error()))
+			http.Redirect(w, r, "/landlord/dashboard/leases?validationError=BAD+REQUEST+400:+Invalid+lease+terms.+The+break+clause+must+be+before+the+end+of+the+lease", http.StatusSeeOther)
+			return
+		}
+
+		err = s.store.CreateLease(r.Context(), tenantIdInt, r.FormValue("leaseStart"), termMonths, breakClauseMonths, noticeDays)
+		if err != nil {
+			if middleware.StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Failed to create lease: %s. Redirecting back to landlord leases page", err.Error()))
+			http.Redirect(w, r, "/landlord/dashboard/leases?validationError=BAD+REQUEST+400:+Failed+to+create+lease.+Please+check+the+start+date", http.StatusSeeOther)
+			return
+		}
+
+		logs.Logs(logInfo, "Lease created. Redirecting back to landlord leases page.")
+		http.Redirect(w, r, "/landlord/dashboard/leases", http.StatusSeeOther)
+		return
+	}
+
+	// every other action works on the tenant's existing lease
+	lease, err := s.store.GetLeaseByTenantId(r.Context(), tenantIdInt)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get tenant lease: %s. Redirecting back to landlord leases page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/leases?validationError=NOT+FOUND+404:+Lease+not+found", http.StatusSeeOther)
+		return
+	}
+
+	switch leaseAction {
+	case "offer-renewal":
+		renewalTerm, err := strconv.Atoi(r.FormValue("renewalTerm"))
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Invalid renewal term: %s. Redirecting back to landlord leases page", err.Error()))
+			http.Redirect(w, r, "/landlord/dashboard/leases?validationError=BAD+REQUEST+400:+Invalid+renewal+term", http.StatusSeeOther)
+			return
+		}
+		err = s.store.OfferLeaseRenewal(r.Context(), lease.ID, renewalTerm, r.FormValue("renewalRent"))
+		if err != nil {
+			if middleware.StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Failed to offer lease renewal: %s. Redirecting back to landlord leases page", err.Error()))
+			http.Redirect(w, r, "/landlord/dashboard/leases?validationError=BAD+REQUEST+400:+Failed+to+offer+renewal.+Only+active+leases+can+be+renewed", http.StatusSeeOther)
+			return
+		}
+		logs.Logs(logInfo, "Lease renewal offered. Redirecting back to landlord leases page.")
+
+	case "serve-notice":
+		err = s.store.ServeLeaseNotice(r.Context(), lease.ID, "landlord", r.FormValue("noticeDate"), r.FormValue("moveOutDate"))
+		if err != nil {
+			if middleware.StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Failed to serve notice: %s. Redirecting back to landlord leases page", err.Error()))
+			http.Redirect(w, r, "/landlord/dashboard/leases?validationError=BAD+REQUEST+400:+"+url.QueryEscape(err.Error()), http.StatusSeeOther)
+			return
+		}
+		logs.Logs(logInfo, "Notice served. Redirecting back to landlord leases page.")
+
+	case "end":
+		err = s.store.EndLease(r.Context(), lease.ID)
+		if err != nil {
+			if middleware.StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Failed to end lease: %s. Redirecting back to landlord leases page", err.Error()))
+			http.Redirect(w, r, "/landlord/dashboard/leases?validationError=BAD+REQUEST+400:+Failed+to+end+lease", http.StatusSeeOther)
+			return
+		}
+		logs.Logs(logInfo, "Lease ended and tenant archived. Redirecting back to landlord leases page.")
+
+	default:
+		logs.Logs(logErr, fmt.Sprintf("Invalid lease action: %s. Redirecting back to landlord leases page", leaseAction))
+		http.Redirect(w, r, "/landlord/dashboard/leases?validationError=BAD+REQUEST+400:+Invalid+lease+action", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/landlord/dashboard/leases", http.StatusSeeOther)
+}
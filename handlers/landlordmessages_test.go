@@ -0,0 +1,193 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestLandlordMessages(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// Define test cases
+	testCases := []struct {
+		name               string
+		request            func() *http.Request
+		expectedStatusCode int
+		expectedLocation   string
+		expectedBody       string
+		failDB             bool
+		failDBReason       string
+	}{
+		{
+			name: "Valid session",
+			request: func() *http.Request {
+				return testutil.LandlordRequest(http.MethodGet, "/landlord/dashboard/messages", nil, "test@example.com")
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "Tenant Doe",
+		},
+		{
+			name: "Missing session cookie",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/landlord/dashboard/messages", nil)
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord",
+		},
+		{
+			name: "Database error when validating session",
+			request: func() *http.Request {
+				return testutil.LandlordRequest(http.MethodGet, "/landlord/dashboard/messages", nil, "test@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord",
+			failDB:             true,
+			failDBReason:       "database error",
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.request()
+
+			// Set up mock database to fail if needed
+			if tc.failDB {
+				testutil.TestEnvironment.DB.SetFailNextOperation(tc.failDBReason)
+			}
+
+			rr := testutil.ServeTestRequest(req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location if applicable
+			if tc.expectedLocation != "" {
+				location := rr.Header().Get("Location")
+				if location != tc.expectedLocation {
+					t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+				}
+			}
+
+			// Check the decrypted tenant names are shown if applicable
+			if tc.expectedBody != "" && !strings.Contains(rr.Body.String(), tc.expectedBody) {
+				t.Errorf("Expected response body to contain '%s'", tc.expectedBody)
+			}
+		})
+	}
+}
+
+func TestSendMessageToTenant(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// a second landlord, who cannot message the test landlord's tenants
+	testutil.TestEnvironment.DB.CreateNewLandlord(context.Background(), "other@example.com", "password123")
+
+	// Define test cases
+	testCases := []struct {
+		name               string
+		request            func() *http.Request
+		expectedStatusCode int
+		expectedLocation   string
+		expectedMessage    string
+		failDB             bool
+		failDBReason       string
+	}{
+		{
+			name: "Valid message send",
+			request: func() *http.Request {
+				form := url.Values{"landlordMessage": {"Hello from your landlord"}}
+				return testutil.LandlordRequest(http.MethodPost, "/landlord/send-message/1", form, "test@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/landlord/dashboard/messages/tenant/1",
+			expectedMessage:    "Hello from your landlord",
+		},
+		{
+			name: "Missing session cookie",
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/landlord/send-message/1", strings.NewReader("landlordMessage=Hello"))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord",
+		},
+		{
+			name: "Invalid CSRF token",
+			request: func() *http.Request {
+				form := url.Values{"landlordMessage": {"Hello"}, "csrf_token": {"invalid_csrf_token"}}
+				return testutil.LandlordRequest(http.MethodPost, "/landlord/send-message/1", form, "test@example.com")
+			},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "Tenant of another landlord",
+			request: func() *http.Request {
+				form := url.Values{"landlordMessage": {"Hello"}}
+				return testutil.LandlordRequest(http.MethodPost, "/landlord/send-message/1", form, "other@example.com")
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Database error when checking the form token",
+			request: func() *http.Request {
+				form := url.Values{"landlordMessage": {"Hello"}}
+				return testutil.LandlordRequest(http.MethodPost, "/landlord/send-message/1", form, "test@example.com")
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			failDB:             true,
+			failDBReason:       "database error",
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.request()
+
+			// Set up mock database to fail if needed
+			if tc.failDB {
+				testutil.TestEnvironment.DB.SetFailNextOperation(tc.failDBReason)
+			}
+
+			rr := testutil.ServeTestRequest(req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location if applicable
+			if tc.expectedLocation != "" {
+				location := rr.Header().Get("Location")
+				if location != tc.expectedLocation {
+					t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+				}
+			}
+
+			// Check the message is shown in the conversation if applicable
+			if tc.expectedMessage != "" {
+				conversation := testutil.ServeTestRequest(testutil.LandlordRequest(http.MethodGet, tc.expectedLocation, nil, "test@example.com"))
+				if !strings.Contains(conversation.Body.String(), tc.expectedMessage) {
+					t.Errorf("Expected conversation to contain '%s'", tc.expectedMessage)
+				}
+			}
+		})
+	}
+}
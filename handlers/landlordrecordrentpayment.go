@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+)
+
+func (s *Server) LandlordRecordRentPayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// extract data from form
+	tenantID := r.FormValue("tenantId")
+	amount := r.FormValue("amount")
+	paidOn := r.FormValue("paidOn")
+	method := r.FormValue("method")
+	reference := r.FormValue("reference")
+
+	// the route only runs for the landlord's own tenants, see middleware.RequireOwnership
+	tenantIdInt, err := strconv.Atoi(tenantID)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid tenant ID: %s. Redirecting back to landlord rent page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/rent?validationError=BAD+REQUEST+400:+Please+select+a+tenant", http.StatusSeeOther)
+		return
+	}
+
+	if method == "" {
+		logs.Logs(logErr, "Payment method is missing. Redirecting back to landlord rent page")
+		http.Redirect(w, r, "/landlord/dashboard/rent?validationError=BAD+REQUEST+400:+Payment+method+is+required", http.StatusSeeOther)
+		return
+	}
+
+	// record the payment in the tenant's ledger
+	err = s.store.RecordRentPayment(r.Context(), tenantIdInt, amount, paidOn, method, reference)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to record rent payment: %s. Redirecting back to landlord rent page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/rent?validationError=BAD+REQUEST+400:+Failed+to+record+payment.+Please+check+the+amount+and+date", http.StatusSeeOther)
+		return
+	}
+
+	logs.Logs(logInfo, "Rent payment recorded. Redirecting back to landlord rent page.")
+	http.Redirect(w, r, "/landlord/dashboard/rent?tenant="+tenantID, http.StatusSeeOther)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+func (s *Server) LandlordRentLedger(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// get any error messages
+	var showData ShowLandlordRentLedger
+	showData.CSRFToken = principal.CSRFToken
+	showData.Error.ValidationError = r.URL.Query().Get("validationError")
+	showData.Error.InternalServerError = r.URL.Query().Get("internalServerError")
+
+	// get the tenant selected to view their full ledger, if any
+	selectedTenantId := 0
+	if selectedTenant := r.URL.Query().Get("tenant"); selectedTenant != "" {
+		var err error
+		selectedTenantId, err = strconv.Atoi(selectedTenant)
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Invalid tenant ID: %s", err.Error()))
+			http.Redirect(w, r, "/landlord/dashboard/rent?validationError=BAD+REQUEST+400:+Invalid+tenant+ID", http.StatusSeeOther)
+			return
+		}
+	}
+
+	// get all tenants for the landlord
+	tenants, err := s.store.GetTenantsByLandlordEmail(r.Context(), landlordEmail)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get landlord tenants: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get landlord tenants: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	for _, tenant := range tenants {
+		tenantName, err := utils.Decrypt(tenant.EncryptTenantName)
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Failed to decrypt tenant name: %s", err.Error()))
+			http.Error(w, fmt.Sprintf("Failed to decrypt tenant name: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		rentLedger, err := s.getTenantRentLedger(r.Context(), tenant.ID, tenant.Currency)
+		if err != nil {
+			if middleware.StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Failed to get rent ledger for tenant %d: %s", tenant.ID, err.Error()))
+			http.Error(w, fmt.Sprintf("Failed to get rent ledger: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		rentLedger.TenantName = string(tenantName)
+
+		if tenant.ID == selectedTenantId {
+			selected := rentLedger
+			showData.SelectedTenant = &selected
+		}
+
+		// only the summary is shown in the tenants list
+		rentLedger.Entries = nil
+		showData.Tenants = append(showData.Tenants, rentLedger)
+	}
+
+	// direct user to protected landlord rent page
+	err = Templates.ExecuteTemplate(w, "landlordRentLedger.html", showData)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Unable to load landlord rent ledger: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Unable to load landlord rent ledger: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/email"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// the landlord's page for managing their staff
+const landlordStaffPath = "/landlord/dashboard/staff"
+
+// what each staff permission lets a staff member do, as shown to landlords and staff
+var staffPermissionLabels = map[string]string{
+	db.PermissionViewApplications:   "View tenant applications",
+	db.PermissionDecideApplications: "Accept or deny tenant applications",
+	db.PermissionMessageTenants:     "Message tenants",
+	db.PermissionViewFinancials:     "View rent and record payments",
+	db.PermissionRevealPII:          "See sensitive personal details",
+	db.PermissionManageTenants:      "Manage tenants and leases",
+	db.PermissionManageProperties:   "Manage properties and rooms",
+	db.PermissionManageMaintenance:  "Manage maintenance tickets",
+}
+
+// staffPermissionNames gets the labels of the permissions a staff member has been granted, in the order of db.StaffPermissions.
+func staffPermissionNames(permissions []string) []string {
+	var names []string
+	for _, permission := range db.StaffPermissions {
+		for _, granted := range permissions {
+			if granted == permission {
+				names = append(names, staffPermissionLabels[permission])
+			}
+		}
+	}
+	return names
+}
+
+func (s *Server) LandlordStaff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// get any error messages
+	var showData ShowLandlordStaff
+	showData.CSRFToken = principal.CSRFToken
+	showData.InviteSent = r.URL.Query().Get("inviteSent")
+	showData.Error.ValidationError = r.URL.Query().Get("validationError")
+	showData.Error.InternalServerError = r.URL.Query().Get("internalServerError")
+
+	staff, err := s.store.GetStaffAccounts(r.Context(), landlordEmail)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get staff accounts: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get staff accounts: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	for _, account := range staff {
+		showAccount := ShowStaffAccount{
+			ID:        account.ID,
+			Email:     account.Email,
+			CreatedAt: account.CreatedAt.Format("2006-01-02"),
+		}
+		if account.SuspendedAt.Valid {
+			showAccount.SuspendedAt = account.SuspendedAt.Time.Format("2006-01-02 15:04")
+		}
+		for _, permission := range db.StaffPermissions {
+			showPermission := ShowStaffPermission{Name: permission, Label: staffPermissionLabels[permission]}
+			for _, granted := range account.Permissions {
+				if granted == permission {
+					showPermission.Granted = true
+				}
+			}
+			showAccount.Permissions = append(showAccount.Permissions, showPermission)
+		}
+		showData.Staff = append(showData.Staff, showAccount)
+	}
+
+	// get the staff who cannot log in after too many failed attempts
+	lockedAccounts, err := s.store.GetLockedStaffAccounts(r.Context(), landlordEmail)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get locked staff accounts: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get locked staff accounts: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	showData.LockedAccounts = showLockedLandlordAccounts(lockedAccounts)
+
+	invitations, err := s.store.GetPendingStaffInvitations(r.Context(), landlordEmail)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get pending staff invitations: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get pending staff invitations: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	for _, invitation := range invitations {
+		showData.Invitations = append(showData.Invitations, ShowPendingInvitation{
+			ID:          invitation.ID,
+			Email:       invitation.Email,
+			AccountRole: invitation.AccountRole,
+			InvitedBy:   invitation.InvitedBy,
+			ExpiresAt:   invitation.ExpiresAt.Format("2006-01-02 15:04"),
+		})
+	}
+
+	err = Templates.ExecuteTemplate(w, "landlordStaff.html", showData)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Unable to load landlord staff page: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Unable to load landlord staff page: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) LandlordStaffInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	inviteeEmail := strings.TrimSpace(r.FormValue("inviteeEmail"))
+	if !utils.ValidateEmail(inviteeEmail) {
+		logs.Logs(logErr, "Invalid invitee email. Redirecting back to landlord staff page")
+		http.Redirect(w, r, landlordStaffPath+"?validationError=BAD+REQUEST+400:+Invalid+email+address", http.StatusSeeOther)
+		return
+	}
+
+	// new staff start without permissions, which the landlord grants once they have accepted
+	token, expiresAt, err := s.store.CreateLandlordInvitation(r.Context(), landlordEmail, inviteeEmail, db.LandlordRoleStaff)
+	if err == db.ErrLandlordExists {
+		logs.Logs(logWarn, "Staff invitation sent to an email that already has an account. Redirecting back to landlord staff page")
+		http.Redirect(w, r, landlordStaffPath+"?validationError=CONFLICT+409:+An+account+with+this+email+already+exists", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to create staff invitation: %s", err.Error()))
+		http.Redirect(w, r, landlordStaffPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+invitation", http.StatusSeeOther)
+		return
+	}
+
+	inviteLink := fmt.Sprintf("%s/invitation?token=%s", siteURL, url.QueryEscape(token))
+	err = email.NotifyLandlordInvitation(inviteeEmail, landlordEmail, db.LandlordRoleStaff, inviteLink, expiresAt.Format("2 January 2006 at 15:04"))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to send staff invitation email: %s", err.Error()))
+		http.Redirect(w, r, landlordStaffPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+send+invitation+email", http.StatusSeeOther)
+		return
+	}
+
+	middleware.RecordAudit(s.store, r, db.AuditStaffInvited, "invitation", inviteeEmail, "")
+	logs.Logs(logInfo, "Staff invitation sent")
+	http.Redirect(w, r, landlordStaffPath+"?inviteSent="+url.QueryEscape("Invitation sent to "+inviteeEmail), http.StatusSeeOther)
+}
+
+func (s *Server) LandlordStaffPermissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	staffIdInt, err := strconv.Atoi(r.FormValue("staffId"))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid staff ID: %s. Redirecting back to landlord staff page", err.Error()))
+		http.Redirect(w, r, landlordStaffPath+"?validationError=BAD+REQUEST+400:+Invalid+staff+member", http.StatusSeeOther)
+		return
+	}
+
+	// unticked permissions are not submitted, so they are taken away
+	err = s.store.SetStaffPermissions(r.Context(), landlordEmail, staffIdInt, r.Form["permission"])
+	if err == db.ErrInvalidPermission {
+		logs.Logs(logErr, "Invalid staff permission. Redirecting back to landlord staff page")
+		http.Redirect(w, r, landlordStaffPath+"?validationError=BAD+REQUEST+400:+Invalid+permission", http.StatusSeeOther)
+		return
+	}
+	if err == sql.ErrNoRows {
+		logs.Logs(logWarn, "Staff member does not work for this landlord. Redirecting back to landlord staff page")
+		http.Redirect(w, r, landlordStaffPath+"?validationError=BAD+REQUEST+400:+Invalid+staff+member", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to update staff permissions: %s", err.Error()))
+		http.Redirect(w, r, landlordStaffPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+update+permissions", http.StatusSeeOther)
+		return
+	}
+	middleware.RecordAudit(s.store, r, db.AuditStaffPermissions, "staff", strconv.Itoa(staffIdInt), strings.Join(r.Form["permission"], ","))
+
+	http.Redirect(w, r, landlordStaffPath, http.StatusSeeOther)
+}
+
+func (s *Server) LandlordStaffRemove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	staffIdInt, err := strconv.Atoi(r.FormValue("staffId"))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid staff ID: %s. Redirecting back to landlord staff page", err.Error()))
+		http.Redirect(w, r, landlordStaffPath+"?validationError=BAD+REQUEST+400:+Invalid+staff+member", http.StatusSeeOther)
+		return
+	}
+
+	err = s.store.RemoveStaffAccount(r.Context(), landlordEmail, staffIdInt)
+	if err == sql.ErrNoRows {
+		logs.Logs(logWarn, "Staff member does not work for this landlord. Redirecting back to landlord staff page")
+		http.Redirect(w, r, landlordStaffPath+"?validationError=BAD+REQUEST+400:+Invalid+staff+member", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to remove staff account: %s", err.Error()))
+		http.Redirect(w, r, landlordStaffPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+remove+staff+member", http.StatusSeeOther)
+		return
+	}
+	middleware.RecordAudit(s.store, r, db.AuditStaffRemoved, "staff", strconv.Itoa(staffIdInt), "")
+
+	http.Redirect(w, r, landlordStaffPath, http.StatusSeeOther)
+}
+
+func (s *Server) LandlordStaffUnlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	staffIdInt, err := strconv.Atoi(r.FormValue("staffId"))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid staff ID: %s. Redirecting back to landlord staff page", err.Error()))
+		http.Redirect(w, r, landlordStaffPath+"?validationError=BAD+REQUEST+400:+Invalid+staff+member", http.StatusSeeOther)
+		return
+	}
+
+	// only the landlord's own staff can be unlocked
+	err = s.store.UnlockStaffAccount(r.Context(), landlordEmail, staffIdInt)
+	if err == sql.ErrNoRows {
+		logs.Logs(logWarn, "Staff account is not locked or does not work for this landlord. Redirecting back to landlord staff page")
+		http.Redirect(w, r, landlordStaffPath+"?validationError=BAD+REQUEST+400:+Staff+account+is+not+locked", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to unlock staff account: %s", err.Error()))
+		http.Redirect(w, r, landlordStaffPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+unlock+staff+member", http.StatusSeeOther)
+		return
+	}
+	middleware.RecordAudit(s.store, r, db.AuditStaffUnlocked, "staff", strconv.Itoa(staffIdInt), "")
+
+	http.Redirect(w, r, landlordStaffPath, http.StatusSeeOther)
+}
+
+func (s *Server) LandlordStaffRevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	invitationIdInt, err := strconv.Atoi(r.FormValue("invitationId"))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid invitation ID: %s. Redirecting back to landlord staff page", err.Error()))
+		http.Redirect(w, r, landlordStaffPath+"?validationError=BAD+REQUEST+400:+Invalid+invitation", http.StatusSeeOther)
+		return
+	}
+
+	err = s.store.RevokeStaffInvitation(r.Context(), landlordEmail, invitationIdInt)
+	if err == sql.ErrNoRows {
+		logs.Logs(logWarn, "Staff invitation is not waiting to be accepted. Redirecting back to landlord staff page")
+		http.Redirect(w, r, landlordStaffPath+"?validationError=BAD+REQUEST+400:+Invitation+has+already+been+accepted+or+revoked", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to revoke staff invitation: %s", err.Error()))
+		http.Redirect(w, r, landlordStaffPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+revoke+invitation", http.StatusSeeOther)
+		return
+	}
+	middleware.RecordAudit(s.store, r, db.AuditStaffInviteRevoked, "invitation", strconv.Itoa(invitationIdInt), "")
+
+	http.Redirect(w, r, landlordStaffPath, http.StatusSeeOther)
+}
@@ -0,0 +1,115 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestLandlordStaff(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// Define test cases, none of which reach the database
+	testCases := []struct {
+		name               string
+		handler            http.HandlerFunc
+		method             string
+		target             string
+		formValues         map[string]string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name:               "Staff page with invalid method",
+			handler:            testutil.TestEnvironment.Handlers.LandlordStaff,
+			method:             http.MethodPost,
+			target:             "/landlord/dashboard/staff",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedLocation:   "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method",
+		},
+		{
+			name:               "Invite staff with an invalid email",
+			handler:            testutil.TestEnvironment.Handlers.LandlordStaffInvite,
+			method:             http.MethodPost,
+			target:             "/landlord/dashboard/staff/invite",
+			formValues:         map[string]string{"inviteeEmail": "not-an-email"},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/landlord/dashboard/staff?validationError=BAD+REQUEST+400:+Invalid+email+address",
+		},
+		{
+			name:               "Set permissions with invalid method",
+			handler:            testutil.TestEnvironment.Handlers.LandlordStaffPermissions,
+			method:             http.MethodGet,
+			target:             "/landlord/dashboard/staff/permissions",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedLocation:   "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method",
+		},
+		{
+			name:               "Set permissions with an invalid staff ID",
+			handler:            testutil.TestEnvironment.Handlers.LandlordStaffPermissions,
+			method:             http.MethodPost,
+			target:             "/landlord/dashboard/staff/permissions",
+			formValues:         map[string]string{"staffId": "abc", "permission": "view_applications"},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/landlord/dashboard/staff?validationError=BAD+REQUEST+400:+Invalid+staff+member",
+		},
+		{
+			name:               "Remove staff with an invalid staff ID",
+			handler:            testutil.TestEnvironment.Handlers.LandlordStaffRemove,
+			method:             http.MethodPost,
+			target:             "/landlord/dashboard/staff/remove",
+			formValues:         map[string]string{"staffId": ""},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/landlord/dashboard/staff?validationError=BAD+REQUEST+400:+Invalid+staff+member",
+		},
+		{
+			name:               "Revoke staff invitation with an invalid ID",
+			handler:            testutil.TestEnvironment.Handlers.LandlordStaffRevokeInvitation,
+			method:             http.MethodPost,
+			target:             "/landlord/dashboard/staff/revoke-invitation",
+			formValues:         map[string]string{"invitationId": "abc"},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/landlord/dashboard/staff?validationError=BAD+REQUEST+400:+Invalid+invitation",
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Create form data
+			form := url.Values{}
+			for key, value := range tc.formValues {
+				form.Add(key, value)
+			}
+
+			// Create a request
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			// Create a response recorder
+			rr := httptest.NewRecorder()
+
+			// Call the handler
+			tc.handler(rr, req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location
+			location := rr.Header().Get("Location")
+			if location != tc.expectedLocation {
+				t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// fullRoom moves a tenant, who did not apply online, into a room for one person and returns the room's ID
+func fullRoom(t *testing.T, tenantEmail string) string {
+	ctx := context.Background()
+	testutil.TestEnvironment.DB.CreateRoom(ctx, "test@example.com", 1, "F", "Full Room", "Single Room", 1, "", "900", "USD")
+	rooms, _ := testutil.TestEnvironment.DB.GetRoomsByLandlordEmail(ctx, "test@example.com")
+	roomId := rooms[len(rooms)-1].ID
+	err := testutil.TestEnvironment.DB.ManuallyCreateNewTenant(ctx, "test@example.com", roomId, "Full Room Tenant", "AB1234567", tenantEmail, "2025-01-01", "1", "900", "USD")
+	if err != nil {
+		t.Fatalf("Failed to create tenant: %s", err.Error())
+	}
+	return strconv.Itoa(roomId)
+}
+
+// otherLandlordRoom adds a vacant room to a property of another landlord and returns the room's ID
+func otherLandlordRoom(t *testing.T, landlordEmail string) string {
+	ctx := context.Background()
+	testutil.TestEnvironment.DB.CreateNewLandlord(ctx, landlordEmail, "password123")
+	testutil.TestEnvironment.DB.CreateProperty(ctx, landlordEmail, "Other House", "2 Other Street")
+	properties, _ := testutil.TestEnvironment.DB.GetPropertiesByLandlordEmail(ctx, landlordEmail)
+	err := testutil.TestEnvironment.DB.CreateRoom(ctx, landlordEmail, properties[0].ID, "1", "Other Room", "Single Room", 2, "", "800", "USD")
+	if err != nil {
+		t.Fatalf("Failed to create room: %s", err.Error())
+	}
+	rooms, _ := testutil.TestEnvironment.DB.GetRoomsByLandlordEmail(ctx, landlordEmail)
+	return strconv.Itoa(rooms[len(rooms)-1].ID)
+}
+
+func TestLandlordSubmitNewTenantRoom(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// Define test cases
+	testCases := []struct {
+		name        string
+		tenantEmail string
+		roomId      string
+	}{
+		{
+			name:        "Room that is full",
+			tenantEmail: "new-full@example.com",
+			roomId:      fullRoom(t, "submit-full@example.com"),
+		},
+		{
+			name:        "Room of another landlord",
+			tenantEmail: "new-other@example.com",
+			roomId:      otherLandlordRoom(t, "submit-other@example.com"),
+		},
+		{
+			name:        "Room that does not exist",
+			tenantEmail: "new-missing@example.com",
+			roomId:      "999",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			form := url.Values{
+				"tenantFullName": {"Room Tenant"},
+				"passportNumber": {"AB1234567"},
+				"tenantEmail":    {tc.tenantEmail},
+				"roomId":         {tc.roomId},
+				"moveInDate":     {"2025-03-01"},
+				"rentDue":        {"1"},
+			}
+			rr := testutil.ServeTestRequest(testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/new-tenant/submit", form, "test@example.com"))
+
+			if status := rr.Code; status != http.StatusSeeOther {
+				t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusSeeOther)
+			}
+			expectedLocation := "/landlord/dashboard/new-tenant?validationError=BAD+REQUEST+400:+Please+select+a+vacant+room"
+			if location := rr.Header().Get("Location"); location != expectedLocation {
+				t.Errorf("Handler returned wrong redirect location: got '%s' want '%s'", location, expectedLocation)
+			}
+
+			if _, err := testutil.TestEnvironment.DB.GetTenantIdByEmail(context.Background(), utils.HashData(tc.tenantEmail)); err == nil {
+				t.Errorf("Expected no tenant to be created")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+)
+
+func (s *Server) LandlordUnlockTenantAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	tenantIdInt, err := strconv.Atoi(r.FormValue("tenantId"))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid tenant ID: %s. Redirecting back to landlord tenants page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/tenants?validationError=BAD+REQUEST+400:+Invalid+tenant", http.StatusSeeOther)
+		return
+	}
+
+	// only the landlord's own tenants can be unlocked
+	err = s.store.UnlockTenantAccount(r.Context(), landlordEmail, tenantIdInt)
+	if err == sql.ErrNoRows {
+		logs.Logs(logWarn, "Tenant account is not locked or does not belong to landlord. Redirecting back to landlord tenants page")
+		http.Redirect(w, r, "/landlord/dashboard/tenants?validationError=BAD+REQUEST+400:+Tenant+account+is+not+locked", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to unlock tenant account: %s", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/tenants?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+unlock+tenant+account", http.StatusSeeOther)
+		return
+	}
+	middleware.RecordAudit(s.store, r, db.AuditTenantUnlocked, "tenant", strconv.Itoa(tenantIdInt), "")
+
+	http.Redirect(w, r, "/landlord/dashboard/tenants", http.StatusSeeOther)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/email"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+func (s *Server) LandlordUpdateMaintenanceTicket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
+		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// extract data from form
+	ticketID := r.FormValue("ticketId")
+	status := r.FormValue("status")
+	priority := r.FormValue("priority")
+	scheduledFor := r.FormValue("scheduledFor")
+	note := r.FormValue("note")
+
+	ticketId, err := strconv.Atoi(ticketID)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid ticket ID: %s. Redirecting back to landlord maintenance page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/maintenance?validationError=BAD+REQUEST+400:+Please+select+a+ticket", http.StatusSeeOther)
+		return
+	}
+
+	// only update tickets raised for the landlord's own properties
+	ticket, err := s.store.GetLandlordMaintenanceTicket(r.Context(), landlordEmail, ticketId)
+	if err == sql.ErrNoRows {
+		logs.Logs(logErr, fmt.Sprintf("Ticket %d does not belong to the landlord. Redirecting back to landlord maintenance page", ticketId))
+		http.Redirect(w, r, "/landlord/dashboard/maintenance?validationError=NOT+FOUND+404:+Ticket+not+found", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get maintenance ticket: %s", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/maintenance?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+get+ticket", http.StatusSeeOther)
+		return
+	}
+
+	err = utils.ValidateTicketTransition(ticket.Status, status)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid ticket status change: %s. Redirecting back to landlord maintenance page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/maintenance?validationError="+url.QueryEscape("BAD REQUEST 400: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	err = s.store.UpdateMaintenanceTicket(r.Context(), landlordEmail, ticketId, status, priority, scheduledFor, note)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to update maintenance ticket: %s. Redirecting back to landlord maintenance page", err.Error()))
+		http.Redirect(w, r, "/landlord/dashboard/maintenance?validationError="+url.QueryEscape("BAD REQUEST 400: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	// the tenant's email is sent by the outbox dispatcher now the update has committed
+	email.WakeOutbox()
+
+	http.Redirect(w, r, "/landlord/dashboard/maintenance", http.StatusSeeOther)
+}
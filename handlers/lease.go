@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+/*
+buildLease formats a lease and its open renewal offer, if any, for the landlord leases page and the tenant account page.
+*/
+func buildLease(lease db.Lease, renewal *db.LeaseRenewal) (ShowLease, error) {
+	showLease := ShowLease{
+		TenantID:          lease.TenantID,
+		HasLease:          true,
+		Status:            lease.Status,
+		StartDate:         lease.StartDate.Format("2006-01-02"),
+		EndDate:           lease.EndDate.Format("2006-01-02"),
+		TermMonths:        lease.TermMonths,
+		BreakClauseMonths: lease.BreakClauseMonths,
+		NoticeDays:        lease.NoticeDays,
+		NoticeGivenBy:     lease.NoticeGivenBy.String,
+	}
+	if lease.BreakClauseMonths > 0 {
+		showLease.BreakDate = utils.AddMonths(lease.StartDate, lease.BreakClauseMonths).Format("2006-01-02")
+	}
+	if lease.NoticeDate.Valid {
+		showLease.NoticeDate = lease.NoticeDate.Time.Format("2006-01-02")
+	}
+	if lease.MoveOutDate.Valid {
+		showLease.MoveOutDate = lease.MoveOutDate.Time.Format("2006-01-02")
+	}
+
+	if renewal != nil {
+		monthlyRent, err := utils.Decrypt(renewal.EncryptMonthlyRent)
+		if err != nil {
+			return ShowLease{}, fmt.Errorf("failed to decrypt renewal rent: %s", err.Error())
+		}
+		showLease.Renewal = &ShowLeaseRenewal{
+			TermMonths:  renewal.TermMonths,
+			MonthlyRent: string(monthlyRent),
+			NewEndDate:  utils.AddMonths(lease.EndDate, renewal.TermMonths).Format("2006-01-02"),
+			OfferedAt:   renewal.OfferedAt.Format("2006-01-02"),
+		}
+	}
+
+	return showLease, nil
+}
+
+/*
+createTenantLease creates the lease for a tenant who has just been added, using the move in date as the start of the lease.
+*/
+func (s *Server) createTenantLease(ctx context.Context, tenantEmail, moveInDate string, termMonths, breakClauseMonths, noticeDays int) error {
+	tenantId, err := s.store.GetTenantIdByEmail(ctx, utils.HashData(tenantEmail))
+	if err != nil {
+		return err
+	}
+	return s.store.CreateLease(ctx, tenantId, moveInDate, termMonths, breakClauseMonths, noticeDays)
+}
@@ -0,0 +1,124 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// tenantLease gets the lease of a tenant, failing the test if they have none
+func tenantLease(t *testing.T, tenantEmail string) db.Lease {
+	ctx := context.Background()
+	tenantId, err := testutil.TestEnvironment.DB.GetTenantIdByEmail(ctx, utils.HashData(tenantEmail))
+	if err != nil {
+		t.Fatalf("Failed to get tenant ID: %s", err.Error())
+	}
+	lease, err := testutil.TestEnvironment.DB.GetLeaseByTenantId(ctx, tenantId)
+	if err != nil {
+		t.Fatalf("Failed to get lease: %s", err.Error())
+	}
+	return lease
+}
+
+func TestLandlordSubmitNewTenantLease(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// Define test cases
+	testCases := []struct {
+		name               string
+		tenantEmail        string
+		leaseTerm          string
+		breakClause        string
+		noticePeriod       string
+		expectedEndDate    string
+		expectedBreak      int
+		expectedNotice     int
+		expectedTerm       int
+		expectedNoTenant   bool
+		expectedStatusCode int
+	}{
+		{
+			name:            "Lease with a break clause",
+			tenantEmail:     "lease-break@example.com",
+			leaseTerm:       "6",
+			breakClause:     "3",
+			noticePeriod:    "60",
+			expectedEndDate: "2025-09-01",
+			expectedTerm:    6,
+			expectedBreak:   3,
+			expectedNotice:  60,
+		},
+		{
+			name:            "Lease with the default terms",
+			tenantEmail:     "lease-default@example.com",
+			expectedEndDate: "2026-03-01",
+			expectedTerm:    12,
+			expectedNotice:  30,
+		},
+		{
+			name:               "Break clause after the end of the lease",
+			tenantEmail:        "lease-invalid@example.com",
+			leaseTerm:          "6",
+			breakClause:        "6",
+			expectedNoTenant:   true,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			testutil.TestEnvironment.DB.CreateRoom(ctx, "test@example.com", 1, "L", "Room for "+tc.tenantEmail, "Single Room", 1, "", "900", "USD")
+			rooms, _ := testutil.TestEnvironment.DB.GetRoomsByLandlordEmail(ctx, "test@example.com")
+
+			form := url.Values{
+				"tenantFullName": {"Lease Tenant"},
+				"passportNumber": {"AB1234567"},
+				"tenantEmail":    {tc.tenantEmail},
+				"roomId":         {strconv.Itoa(rooms[len(rooms)-1].ID)},
+				"moveInDate":     {"2025-03-01"},
+				"rentDue":        {"1"},
+				"leaseTerm":      {tc.leaseTerm},
+				"breakClause":    {tc.breakClause},
+				"noticePeriod":   {tc.noticePeriod},
+			}
+			rr := testutil.ServeTestRequest(testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/new-tenant/submit", form, "test@example.com"))
+
+			if tc.expectedNoTenant {
+				if rr.Code != tc.expectedStatusCode {
+					t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, tc.expectedStatusCode)
+				}
+				if _, err := testutil.TestEnvironment.DB.GetTenantIdByEmail(ctx, utils.HashData(tc.tenantEmail)); err == nil {
+					t.Errorf("Expected no tenant to be created with invalid lease terms")
+				}
+				return
+			}
+
+			// the lease is created with the tenant, before their login details are emailed, which tests cannot do
+			lease := tenantLease(t, tc.tenantEmail)
+			if lease.Status != db.LeaseActive {
+				t.Errorf("Expected an active lease, got '%s'", lease.Status)
+			}
+			if start := lease.StartDate.Format("2006-01-02"); start != "2025-03-01" {
+				t.Errorf("Expected the lease to start on the move in date 2025-03-01, got %s", start)
+			}
+			if end := lease.EndDate.Format("2006-01-02"); end != tc.expectedEndDate {
+				t.Errorf("Expected the lease to end on %s, got %s", tc.expectedEndDate, end)
+			}
+			if lease.TermMonths != tc.expectedTerm || lease.BreakClauseMonths != tc.expectedBreak || lease.NoticeDays != tc.expectedNotice {
+				t.Errorf("Expected a %d month lease with a %d month break clause and %d days notice, got %d, %d and %d",
+					tc.expectedTerm, tc.expectedBreak, tc.expectedNotice, lease.TermMonths, lease.BreakClauseMonths, lease.NoticeDays)
+			}
+		})
+	}
+}
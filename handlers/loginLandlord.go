@@ -7,18 +7,22 @@ import (
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 )
 
-func LoginLandlord(w http.ResponseWriter, r *http.Request) {
+func (s *Server) LoginLandlord(w http.ResponseWriter, r *http.Request) {
 	// get any error messages
 	badRequestError := r.URL.Query().Get("badRequest")
 	notFoundError := r.URL.Query().Get("notFound")
 	authenticationError := r.URL.Query().Get("authenticationError")
 	internalServerError := r.URL.Query().Get("internalServerError")
+	passwordReset := r.URL.Query().Get("passwordReset")
+	accountCreated := r.URL.Query().Get("accountCreated")
 
 	data := ErrorMessages{
 		BadRequestError:     badRequestError,
 		NotFoundError:       notFoundError,
 		AuthenticationError: authenticationError,
 		InternalServerError: internalServerError,
+		PasswordReset:       passwordReset,
+		AccountCreated:      accountCreated,
 	}
 
 	err := Templates.ExecuteTemplate(w, "loginLandlord.html", data)
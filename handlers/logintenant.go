@@ -7,18 +7,20 @@ import (
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 )
 
-func LoginTenant(w http.ResponseWriter, r *http.Request) {
+func (s *Server) LoginTenant(w http.ResponseWriter, r *http.Request) {
 	// get any error messages
 	badRequestError := r.URL.Query().Get("badRequest")
 	notFoundError := r.URL.Query().Get("notFound")
 	authenticationError := r.URL.Query().Get("authenticationError")
 	internalServerError := r.URL.Query().Get("internalServerError")
+	passwordReset := r.URL.Query().Get("passwordReset")
 
 	data := ErrorMessages{
 		BadRequestError:     badRequestError,
 		NotFoundError:       notFoundError,
 		AuthenticationError: authenticationError,
 		InternalServerError: internalServerError,
+		PasswordReset:       passwordReset,
 	}
 
 	err := Templates.ExecuteTemplate(w, "loginTenant.html", data)
@@ -6,7 +6,6 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/Bevs-n-Devs/lilyshiddenparadise/handlers"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
 )
@@ -48,7 +47,7 @@ func TestLoginTenant(t *testing.T) {
 			rr := httptest.NewRecorder()
 			
 			// Call the handler
-			handlers.LoginTenant(rr, req)
+			testutil.TestEnvironment.Handlers.LoginTenant(rr, req)
 			
 			// Check the status code
 			if rr.Code != tc.expectedStatusCode {
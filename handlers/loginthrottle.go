@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/email"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+)
+
+// lockoutRedirect sends the user back to the login page, telling them how long to wait before trying again.
+func lockoutRedirect(w http.ResponseWriter, r *http.Request, role string, lockedUntil time.Time) {
+	minutes := int(math.Ceil(time.Until(lockedUntil).Minutes()))
+	message := fmt.Sprintf("TOO MANY REQUESTS 429: Too many failed login attempts. Please try again in %d minute(s).", max(minutes, 1))
+	http.Redirect(w, r, "/login/"+role+"?authenticationError="+url.QueryEscape(message), http.StatusSeeOther)
+}
+
+/*
+loginLockedOut checks whether a login has to wait because of earlier failed logins on the account or from the client's IP address.
+If it does, the user is sent back to the login page and true is returned. The password is not checked while the login is locked,
+so a correct guess cannot be told apart from a wrong one.
+*/
+func (s *Server) loginLockedOut(w http.ResponseWriter, r *http.Request, role, userKey string) bool {
+	lockedUntil, err := s.store.GetLoginLockout(r.Context(), role, userKey, middleware.ClientIP(r))
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return true
+		}
+		logs.Logs(logErr, fmt.Sprintf("Error checking login lockout: %s. Redirecting back to %s login page", err.Error(), role))
+		http.Redirect(w, r, "/login/"+role+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+check+login", http.StatusSeeOther)
+		return true
+	}
+	if lockedUntil.IsZero() {
+		return false
+	}
+
+	logs.Logs(logWarn, fmt.Sprintf("Login to %s account refused until %s after too many failed logins", role, lockedUntil.Format(time.RFC3339)))
+	lockoutRedirect(w, r, role, lockedUntil)
+	return true
+}
+
+/*
+recordLoginFailure counts a failed login against the account and the client's IP address,
+and emails the account owner once the failures reach config.Login.AlertThreshold.
+No email is sent for accounts that do not exist, which is when userEmail is empty.
+It returns when the next login can be tried, or the zero time if it can be tried straight away.
+*/
+func (s *Server) recordLoginFailure(r *http.Request, role, userKey, userEmail string) time.Time {
+	failure, err := s.store.RecordLoginFailure(r.Context(), role, userKey, middleware.ClientIP(r))
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error recording failed login: %s", err.Error()))
+		return time.Time{}
+	}
+
+	if failure.SendAlert && userEmail != "" {
+		forgotPasswordLink := fmt.Sprintf("%s/forgot-password?role=%s", siteURL, role)
+		err = email.NotifyRepeatedLoginFailures(userEmail, failure.Failures, forgotPasswordLink)
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Failed to send failed login alert: %s", err.Error()))
+		}
+	}
+	return failure.LockedUntil
+}
+
+// showLockedLandlordAccounts formats locked landlord and staff accounts for the admin and staff pages.
+func showLockedLandlordAccounts(accounts []db.LockedLandlordAccount) []ShowLockedLandlordAccount {
+	var showAccounts []ShowLockedLandlordAccount
+	for _, account := range accounts {
+		showAccounts = append(showAccounts, ShowLockedLandlordAccount{
+			ID:          account.ID,
+			Email:       account.Email,
+			AccountRole: account.AccountRole,
+			Failures:    account.Failures,
+			LockedUntil: account.LockedUntil.Format("2006-01-02 15:04"),
+		})
+	}
+	return showAccounts
+}
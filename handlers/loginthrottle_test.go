@@ -0,0 +1,198 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+// landlordLogin posts the landlord login form from the IP address
+func landlordLogin(ip, email, password string) *httptest.ResponseRecorder {
+	form := url.Values{"landlordEmail": {email}, "landlordPassword": {password}}
+	req := httptest.NewRequest(http.MethodPost, "/login/landlord/submit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = ip + ":1234"
+	return testutil.ServeTestRequest(req)
+}
+
+// lockedOut reports whether the login was refused until earlier failures have been waited out
+func lockedOut(rr *httptest.ResponseRecorder) bool {
+	return strings.Contains(rr.Header().Get("Location"), "TOO+MANY+REQUESTS+429")
+}
+
+// accountFailures gets the failed logins counted against a landlord account
+func accountFailures(email string) int {
+	for _, throttle := range testutil.TestEnvironment.DB.LoginThrottles() {
+		if throttle.Scope == "account" && throttle.UserType == "landlord" && throttle.ThrottleKey == email {
+			return throttle.Failures
+		}
+	}
+	return 0
+}
+
+// useLoginPolicy sets the login throttle policy until the test ends, starting with no failed logins
+func useLoginPolicy(t *testing.T, policy config.LoginThrottlePolicy) {
+	defaults := config.Login
+	config.Login = policy
+	testutil.TestEnvironment.DB.ClearLoginThrottles()
+	t.Cleanup(func() {
+		config.Login = defaults
+		testutil.TestEnvironment.DB.ClearLoginThrottles()
+	})
+}
+
+func TestLoginThrottle(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	dashboard := "/landlord/dashboard"
+
+	t.Run("Account locked at the threshold", func(t *testing.T) {
+		useLoginPolicy(t, config.LoginThrottlePolicy{
+			AccountFreeAttempts: 10,
+			IPFreeAttempts:      100,
+			BaseDelay:           time.Minute,
+			LockoutThreshold:    10,
+			LockoutDuration:     time.Hour,
+			AlertThreshold:      100,
+			FailureWindow:       24 * time.Hour,
+		})
+
+		for i := 1; i <= 10; i++ {
+			if rr := landlordLogin("198.51.100.1", "test@example.com", "wrongpassword"); lockedOut(rr) {
+				t.Fatalf("Expected failed login %d to be checked, got '%s'", i, rr.Header().Get("Location"))
+			}
+		}
+
+		// the right password is not checked while the account is locked
+		rr := landlordLogin("198.51.100.1", "test@example.com", "password123")
+		if !lockedOut(rr) {
+			t.Fatalf("Expected the account to be locked after 10 failed logins, got '%s'", rr.Header().Get("Location"))
+		}
+		// the account is locked, not the IP address, so logins from elsewhere wait too
+		if rr := landlordLogin("198.51.100.2", "test@example.com", "password123"); !lockedOut(rr) {
+			t.Errorf("Expected the account to be locked from every IP address, got '%s'", rr.Header().Get("Location"))
+		}
+
+		locked, err := testutil.TestEnvironment.DB.GetLockedLandlordAccounts(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to get locked landlord accounts: %s", err.Error())
+		}
+		if len(locked) != 1 || locked[0].Email != "test@example.com" || locked[0].Failures != 10 {
+			t.Fatalf("Expected test@example.com to be listed as locked after 10 failures, got %v", locked)
+		}
+
+		// once the owner unlocks the account it can log in straight away
+		err = testutil.TestEnvironment.DB.UnlockLandlordAccount(context.Background(), locked[0].ID)
+		if err != nil {
+			t.Fatalf("Failed to unlock landlord account: %s", err.Error())
+		}
+		if location := landlordLogin("198.51.100.1", "test@example.com", "password123").Header().Get("Location"); location != dashboard {
+			t.Errorf("Expected an unlocked account to log in, got '%s'", location)
+		}
+	})
+
+	t.Run("Failures outside the window are forgotten", func(t *testing.T) {
+		useLoginPolicy(t, config.LoginThrottlePolicy{
+			AccountFreeAttempts: 3,
+			IPFreeAttempts:      100,
+			BaseDelay:           time.Minute,
+			LockoutThreshold:    10,
+			LockoutDuration:     time.Hour,
+			AlertThreshold:      100,
+			FailureWindow:       time.Hour,
+		})
+
+		landlordLogin("198.51.100.3", "test@example.com", "wrongpassword")
+		landlordLogin("198.51.100.3", "test@example.com", "wrongpassword")
+		testutil.TestEnvironment.DB.AgeLoginFailures(time.Hour + time.Minute)
+
+		// a third failure in the window would make the next login wait
+		rr := landlordLogin("198.51.100.3", "test@example.com", "wrongpassword")
+		if lockedOut(rr) {
+			t.Fatalf("Expected the failure to be checked, got '%s'", rr.Header().Get("Location"))
+		}
+		if failures := accountFailures("test@example.com"); failures != 1 {
+			t.Errorf("Expected the count to start again after the window, got %d failures", failures)
+		}
+		if location := landlordLogin("198.51.100.3", "test@example.com", "password123").Header().Get("Location"); location != dashboard {
+			t.Errorf("Expected the login not to wait, got '%s'", location)
+		}
+	})
+
+	t.Run("Failures from one IP address slow down every account", func(t *testing.T) {
+		useLoginPolicy(t, config.LoginThrottlePolicy{
+			AccountFreeAttempts: 10,
+			IPFreeAttempts:      3,
+			BaseDelay:           time.Minute,
+			LockoutThreshold:    10,
+			LockoutDuration:     time.Hour,
+			AlertThreshold:      100,
+			FailureWindow:       24 * time.Hour,
+		})
+
+		// one password tried against several accounts, none of which fail often enough to wait
+		for _, email := range []string{"first@example.com", "second@example.com", "third@example.com"} {
+			landlordLogin("198.51.100.4", email, "password123")
+		}
+		if failures := accountFailures("test@example.com"); failures != 0 {
+			t.Fatalf("Expected no failures on test@example.com, got %d", failures)
+		}
+
+		if rr := landlordLogin("198.51.100.4", "test@example.com", "password123"); !lockedOut(rr) {
+			t.Errorf("Expected logins from the IP address to wait, got '%s'", rr.Header().Get("Location"))
+		}
+		if location := landlordLogin("198.51.100.5", "test@example.com", "password123").Header().Get("Location"); location != dashboard {
+			t.Errorf("Expected logins from another IP address not to wait, got '%s'", location)
+		}
+	})
+
+	t.Run("Successful login resets the account's failures", func(t *testing.T) {
+		useLoginPolicy(t, config.LoginThrottlePolicy{
+			AccountFreeAttempts: 3,
+			IPFreeAttempts:      100,
+			BaseDelay:           time.Minute,
+			LockoutThreshold:    10,
+			LockoutDuration:     time.Hour,
+			AlertThreshold:      100,
+			FailureWindow:       24 * time.Hour,
+		})
+
+		landlordLogin("198.51.100.6", "test@example.com", "wrongpassword")
+		landlordLogin("198.51.100.6", "test@example.com", "wrongpassword")
+		if location := landlordLogin("198.51.100.6", "test@example.com", "password123").Header().Get("Location"); location != dashboard {
+			t.Fatalf("Expected the login to succeed, got '%s'", location)
+		}
+		if failures := accountFailures("test@example.com"); failures != 0 {
+			t.Errorf("Expected the account's failures to be cleared, got %d", failures)
+		}
+
+		// without the reset, these would be the third and fourth failures and the login would wait
+		landlordLogin("198.51.100.6", "test@example.com", "wrongpassword")
+		landlordLogin("198.51.100.6", "test@example.com", "wrongpassword")
+		if location := landlordLogin("198.51.100.6", "test@example.com", "password123").Header().Get("Location"); location != dashboard {
+			t.Errorf("Expected the login not to wait, got '%s'", location)
+		}
+
+		// failures from the IP address are kept, so logging in to one account does not reset the count against others
+		ipFailures := 0
+		for _, throttle := range testutil.TestEnvironment.DB.LoginThrottles() {
+			if throttle.Scope == "ip" && throttle.ThrottleKey == "198.51.100.6" {
+				ipFailures = throttle.Failures
+			}
+		}
+		if ipFailures != 4 {
+			t.Errorf("Expected 4 failures kept against the IP address, got %d", ipFailures)
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+)
+
+// how long the two-factor login cookie lasts, matching the lifetime of the login in the database
+const twoFactorLoginCookieLifetime = 5 * time.Minute
+
+/*
+startLoginSession starts a session for a landlord or tenant who has finished logging in,
+sets the session cookies and sends them to their dashboard.
+*/
+func (s *Server) startLoginSession(w http.ResponseWriter, r *http.Request, role, userKey string, rememberMe bool) {
+	// a completed login forgets the earlier failed logins on the account
+	err := s.store.ClearLoginFailures(r.Context(), role, userKey)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error clearing failed logins: %s", err.Error()))
+	}
+
+	startSession := s.store.StartLandlordSession
+	if role == TENANT {
+		startSession = s.store.StartTenantSession
+	}
+
+	// start a new session, remember me gives the session a longer lifetime
+	sessionToken, csrfToken, expiryTime, err := startSession(r.Context(), userKey, rememberMe)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error updating %s session tokens: %s", role, err.Error()))
+		http.Error(w, fmt.Sprintf("Error updating %s session tokens: %s", role, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// set session cookie
+	createSessionCookie := middleware.SessionCookie(w, sessionToken, expiryTime)
+	if !createSessionCookie {
+		logs.Logs(logErr, fmt.Sprintf("Failed to create session cookie. Redirecting back to %s login page...", role))
+		http.Redirect(w, r, "/login/"+role+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+session+cookie", http.StatusInternalServerError)
+		return
+	}
+
+	// set csrf cookie
+	createCsrfCookie := middleware.CSRFTokenCookie(w, csrfToken, expiryTime)
+	if !createCsrfCookie {
+		logs.Logs(logErr, fmt.Sprintf("Failed to create CSRF cookie. Redirecting back to %s login page...", role))
+		http.Redirect(w, r, "/login/"+role+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+CSRF+cookie", http.StatusInternalServerError)
+		return
+	}
+
+	// redirect to the dashboard if authentication is successful
+	http.Redirect(w, r, "/"+role+"/dashboard", http.StatusSeeOther)
+}
+
+/*
+continueLogin finishes a login once the password has been checked. Users who have turned on two-factor authentication
+are sent to enter a code from their authenticator app first, everyone else gets their session straight away.
+*/
+func (s *Server) continueLogin(w http.ResponseWriter, r *http.Request, role, userKey string, rememberMe bool) {
+	twoFactor, err := s.store.GetTwoFactorStatus(r.Context(), role, userKey)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Error getting two-factor status: %s. Redirecting back to %s login page", err.Error(), role))
+		http.Redirect(w, r, "/login/"+role+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+check+two-factor+authentication", http.StatusSeeOther)
+		return
+	}
+
+	if !twoFactor.Enabled {
+		s.startLoginSession(w, r, role, userKey, rememberMe)
+		return
+	}
+
+	// no session is started until the second step has been passed
+	token, err := s.store.StartTwoFactorLogin(r.Context(), role, userKey, rememberMe)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Error starting two-factor login: %s. Redirecting back to %s login page", err.Error(), role))
+		http.Redirect(w, r, "/login/"+role+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+start+two-factor+login", http.StatusSeeOther)
+		return
+	}
+	middleware.TwoFactorLoginCookie(w, token, time.Now().Add(twoFactorLoginCookieLifetime))
+	http.Redirect(w, r, "/login/two-factor?role="+role, http.StatusSeeOther)
+}
+
+func (s *Server) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to home page.", r.Method))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	role := r.URL.Query().Get("role")
+	if role != LANDLORD && role != TENANT {
+		http.NotFound(w, r)
+		return
+	}
+
+	// the page is only useful part way through a login
+	_, err := r.Cookie("two_factor_token")
+	if err != nil {
+		http.Redirect(w, r, "/login/"+role, http.StatusSeeOther)
+		return
+	}
+
+	// get any error messages
+	showData := ShowTwoFactorLogin{Role: role}
+	showData.Error.ValidationError = r.URL.Query().Get("validationError")
+
+	err = Templates.ExecuteTemplate(w, "loginTwoFactor.html", showData)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Unable to load two-factor login page: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Unable to load two-factor login page: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) SubmitLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to home page.", r.Method))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// extract data from form
+	role := r.FormValue("role")
+	code := r.FormValue("code")
+
+	if role != LANDLORD && role != TENANT {
+		http.NotFound(w, r)
+		return
+	}
+
+	cookie, err := r.Cookie("two_factor_token")
+	if err != nil {
+		logs.Logs(logErr, "Two-factor login cookie not found. Redirecting back to login page")
+		http.Redirect(w, r, "/login/"+role+"?authenticationError=UNAUTHORIZED+401:+Your+login+has+expired.+Please+login+again.", http.StatusSeeOther)
+		return
+	}
+
+	userKey, rememberMe, err := s.store.CompleteTwoFactorLogin(r.Context(), role, cookie.Value, code)
+	if err == db.ErrInvalidTwoFactorCode {
+		// wrong codes count as failed logins, so guessing codes is slowed down like guessing passwords
+		alertEmail, err := s.twoFactorAccountName(r.Context(), role, userKey)
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Failed to get account email for failed login alert: %s", err.Error()))
+		}
+		lockedUntil := s.recordLoginFailure(r, role, userKey, alertEmail)
+		if !lockedUntil.IsZero() {
+			middleware.DeleteTwoFactorLoginCookie(w)
+			lockoutRedirect(w, r, role, lockedUntil)
+			return
+		}
+		http.Redirect(w, r, "/login/two-factor?role="+role+"&validationError=UNAUTHORIZED+401:+The+code+is+incorrect.+Please+try+again.", http.StatusSeeOther)
+		return
+	}
+	if err == db.ErrInvalidTwoFactorLogin {
+		middleware.DeleteTwoFactorLoginCookie(w)
+		http.Redirect(w, r, "/login/"+role+"?authenticationError=UNAUTHORIZED+401:+Your+login+has+expired.+Please+login+again.", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Error checking two-factor code: %s", err.Error()))
+		http.Redirect(w, r, "/login/"+role+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+check+two-factor+code", http.StatusSeeOther)
+		return
+	}
+
+	middleware.DeleteTwoFactorLoginCookie(w)
+	s.startLoginSession(w, r, role, userKey, rememberMe)
+}
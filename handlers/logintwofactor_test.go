@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestLoginTwoFactor(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// Define test cases, none of which reach the database
+	testCases := []struct {
+		name               string
+		handler            http.HandlerFunc
+		method             string
+		target             string
+		formValues         map[string]string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name:               "Invalid method",
+			handler:            testutil.TestEnvironment.Handlers.LoginTwoFactor,
+			method:             http.MethodPost,
+			target:             "/login/two-factor?role=landlord",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name:               "Unknown role",
+			handler:            testutil.TestEnvironment.Handlers.LoginTwoFactor,
+			method:             http.MethodGet,
+			target:             "/login/two-factor?role=admin",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "No login in progress",
+			handler:            testutil.TestEnvironment.Handlers.LoginTwoFactor,
+			method:             http.MethodGet,
+			target:             "/login/two-factor?role=tenant",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/tenant",
+		},
+		{
+			name:               "Submit without login cookie",
+			handler:            testutil.TestEnvironment.Handlers.SubmitLoginTwoFactor,
+			method:             http.MethodPost,
+			target:             "/login/two-factor/submit",
+			formValues:         map[string]string{"role": "landlord", "code": "123456"},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/landlord?authenticationError=UNAUTHORIZED+401:+Your+login+has+expired.+Please+login+again.",
+		},
+		{
+			name:               "Submit with unknown role",
+			handler:            testutil.TestEnvironment.Handlers.SubmitLoginTwoFactor,
+			method:             http.MethodPost,
+			target:             "/login/two-factor/submit",
+			formValues:         map[string]string{"role": "admin", "code": "123456"},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Create form data
+			form := url.Values{}
+			for key, value := range tc.formValues {
+				form.Add(key, value)
+			}
+
+			// Create a request
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			// Create a response recorder
+			rr := httptest.NewRecorder()
+
+			// Call the handler
+			tc.handler(rr, req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location
+			location := rr.Header().Get("Location")
+			if location != tc.expectedLocation {
+				t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+			}
+		})
+	}
+}
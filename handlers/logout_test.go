@@ -0,0 +1,151 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// logoutTestCase is a logout request and the response it should get
+type logoutTestCase struct {
+	name               string
+	request            func() *http.Request
+	expectedStatusCode int
+	expectedLocation   string
+	failDB             bool
+	failDBReason       string
+}
+
+// runLogoutTests runs logout test cases, checking that a successful logout expires the session cookie
+func runLogoutTests(t *testing.T, testCases []logoutTestCase, loggedOut func() bool) {
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.request()
+
+			// Set up mock database to fail if needed
+			if tc.failDB {
+				testutil.TestEnvironment.DB.SetFailNextOperation(tc.failDBReason)
+			}
+
+			rr := testutil.ServeTestRequest(req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location if applicable
+			if tc.expectedLocation != "" {
+				location := rr.Header().Get("Location")
+				if location != tc.expectedLocation {
+					t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+				}
+			}
+
+			// Check that the session has ended and its cookie is expired if logout was successful
+			if tc.expectedLocation == "/" {
+				if !loggedOut() {
+					t.Errorf("Expected session to be removed from the database")
+				}
+				found := false
+				for _, cookie := range rr.Result().Cookies() {
+					if cookie.Name == "session_token" && cookie.Expires.Before(time.Now()) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("Expected session_token cookie to be deleted")
+				}
+			}
+		})
+	}
+}
+
+func TestLogoutLandlord(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// Define test cases
+	testCases := []logoutTestCase{
+		{
+			name: "Valid logout",
+			request: func() *http.Request {
+				return testutil.LandlordRequest(http.MethodGet, "/logout-landlord", nil, "test@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name: "Missing session cookie",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/logout-landlord", nil)
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/?authenticationError=UNAUTHORIZED+401:+Error+authenticating+user",
+		},
+		{
+			name: "Database error during logout",
+			request: func() *http.Request {
+				return testutil.LandlordRequest(http.MethodGet, "/logout-landlord", nil, "test@example.com")
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			failDB:             true,
+			failDBReason:       "database error",
+		},
+	}
+
+	runLogoutTests(t, testCases, func() bool {
+		_, err := testutil.TestEnvironment.DB.GetEmailFromLandlordSessionToken(context.Background(), "session_token_test@example.com")
+		return err != nil
+	})
+}
+
+func TestLogoutTenant(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// Define test cases
+	testCases := []logoutTestCase{
+		{
+			name: "Valid logout",
+			request: func() *http.Request {
+				return testutil.TenantRequest(http.MethodGet, "/logout-tenant", nil, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/",
+		},
+		{
+			name: "Missing session cookie",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/logout-tenant", nil)
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/?authenticationError=UNAUTHORIZED+401:+Error+authenticating+user",
+		},
+		{
+			name: "Database error during logout",
+			request: func() *http.Request {
+				return testutil.TenantRequest(http.MethodGet, "/logout-tenant", nil, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			failDB:             true,
+			failDBReason:       "database error",
+		},
+	}
+
+	runLogoutTests(t, testCases, func() bool {
+		_, err := testutil.TestEnvironment.DB.GetHashedEmailFromTenantSessionToken(context.Background(), "session_token_"+utils.HashData("tenant@example.com"))
+		return err != nil
+	})
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+const (
+	maxTicketPhotos    = 5
+	maxTicketPhotoSize = 5 << 20 // 5MB per photo
+	// the whole upload is capped so an oversized request is rejected before it is read into memory
+	maxTicketUploadSize = maxTicketPhotos*maxTicketPhotoSize + 1<<20
+)
+
+// the photo types a tenant can attach to a maintenance ticket
+var ticketPhotoTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+/*
+buildMaintenanceTickets decrypts maintenance tickets for the tenant and landlord maintenance pages.
+*/
+func buildMaintenanceTickets(tickets []db.MaintenanceTicket) ([]ShowMaintenanceTicket, error) {
+	showTickets := []ShowMaintenanceTicket{}
+	for _, ticket := range tickets {
+		tenantName, err := utils.Decrypt(ticket.EncryptTenantName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt tenant name: %s", err.Error())
+		}
+		description, err := utils.Decrypt(ticket.EncryptDescription)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt ticket description: %s", err.Error())
+		}
+
+		showTicket := ShowMaintenanceTicket{
+			ID:           ticket.ID,
+			TenantName:   string(tenantName),
+			PropertyName: ticket.PropertyName,
+			RoomName:     ticket.RoomName,
+			Category:     ticket.Category,
+			Priority:     ticket.Priority,
+			Description:  string(description),
+			Status:       ticket.Status,
+			CreatedAt:    ticket.CreatedAt.Format("2006-01-02 15:04"),
+			UpdatedAt:    ticket.UpdatedAt.Format("2006-01-02 15:04"),
+			Resolved:     ticket.Status == db.TicketResolved,
+		}
+		if ticket.ScheduledFor.Valid {
+			showTicket.ScheduledFor = ticket.ScheduledFor.Time.Format("2006-01-02")
+		}
+
+		for _, attachment := range ticket.Attachments {
+			showTicket.Attachments = append(showTicket.Attachments, ShowMaintenanceAttachment{
+				ID:       attachment.ID,
+				FileName: attachment.FileName,
+			})
+		}
+
+		for _, event := range ticket.Events {
+			showEvent := ShowMaintenanceEvent{
+				FromStatus: event.FromStatus.String,
+				ToStatus:   event.ToStatus,
+				ChangedBy:  event.ChangedByType,
+				ChangedAt:  event.CreatedAt.Format("2006-01-02 15:04"),
+			}
+			if len(event.EncryptNote) > 0 {
+				note, err := utils.Decrypt(event.EncryptNote)
+				if err != nil {
+					return nil, fmt.Errorf("failed to decrypt ticket note: %s", err.Error())
+				}
+				showEvent.Note = string(note)
+			}
+			showTicket.Events = append(showTicket.Events, showEvent)
+		}
+
+		showTickets = append(showTickets, showTicket)
+	}
+	return showTickets, nil
+}
+
+/*
+readTicketPhotos reads the photos attached to a new maintenance ticket from a parsed multipart form.
+The type of each photo is detected from its contents rather than trusted from the browser.
+*/
+func readTicketPhotos(r *http.Request) ([]db.NewMaintenanceAttachment, error) {
+	var attachments []db.NewMaintenanceAttachment
+	if r.MultipartForm == nil {
+		return attachments, nil
+	}
+
+	files := r.MultipartForm.File["photos"]
+	if len(files) > maxTicketPhotos {
+		return nil, fmt.Errorf("no more than %d photos can be attached", maxTicketPhotos)
+	}
+
+	for _, fileHeader := range files {
+		// browsers send an empty file part when no photo was chosen
+		if fileHeader.Filename == "" && fileHeader.Size == 0 {
+			continue
+		}
+		if fileHeader.Size > maxTicketPhotoSize {
+			return nil, fmt.Errorf("%s is larger than 5MB", fileHeader.Filename)
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(file, maxTicketPhotoSize+1))
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxTicketPhotoSize {
+			return nil, fmt.Errorf("%s is larger than 5MB", fileHeader.Filename)
+		}
+
+		contentType := http.DetectContentType(data)
+		if !ticketPhotoTypes[contentType] {
+			return nil, errors.New("photos must be JPEG, PNG, GIF or WebP images")
+		}
+
+		attachments = append(attachments, db.NewMaintenanceAttachment{
+			FileName:    filepath.Base(strings.ReplaceAll(fileHeader.Filename, "\\", "/")),
+			ContentType: contentType,
+			Data:        data,
+		})
+	}
+	return attachments, nil
+}
+
+/*
+serveTicketPhoto decrypts a maintenance ticket photo and writes it to the response.
+*/
+func serveTicketPhoto(w http.ResponseWriter, attachment db.MaintenanceAttachment) error {
+	data, err := utils.Decrypt(attachment.EncryptData)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt ticket photo: %s", err.Error())
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", attachment.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,271 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// pngPhoto is the start of a PNG file, padded to the size, which is enough for its type to be detected
+func pngPhoto(size int) []byte {
+	photo := make([]byte, size)
+	copy(photo, "\x89PNG\r\n\x1a\n")
+	return photo
+}
+
+// ticketPhoto is a photo attached to a new maintenance ticket
+type ticketPhoto struct {
+	fileName string
+	data     []byte
+}
+
+// newTicketRequest creates a multipart request from a logged in tenant opening a maintenance ticket with the photos
+func newTicketRequest(t *testing.T, tenantEmail string, photos ...ticketPhoto) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for field, value := range map[string]string{"category": "plumbing", "priority": "high", "description": "The kitchen tap is leaking"} {
+		form.WriteField(field, value)
+	}
+	for _, photo := range photos {
+		part, err := form.CreateFormFile("photos", photo.fileName)
+		if err != nil {
+			t.Fatalf("Failed to create photo part: %s", err.Error())
+		}
+		part.Write(photo.data)
+	}
+	form.Close()
+
+	// the CSRF token is sent in its header, as the form is only read by the handler
+	req := testutil.TenantRequest(http.MethodPost, "/tenant/dashboard/maintenance/new", nil, tenantEmail)
+	csrfCookie, _ := req.Cookie("csrf_token")
+	req.Header.Set("X-CSRF-Token", csrfCookie.Value)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.ContentLength = int64(body.Len())
+	req.Body = io.NopCloser(&body)
+	return req
+}
+
+// addTenantInOwnRoom moves a tenant into a room of their own in the test landlord's property
+func addTenantInOwnRoom(t *testing.T, name, tenantEmail string) {
+	ctx := context.Background()
+	testutil.AddTestApplication(name, tenantEmail)
+	testutil.TestEnvironment.DB.CreateRoom(ctx, "test@example.com", 1, "M", "Room for "+name, "Single Room", 1, "", "900", "USD")
+	rooms, _ := testutil.TestEnvironment.DB.GetRoomsByLandlordEmail(ctx, "test@example.com")
+	err := testutil.TestEnvironment.DB.CreateNewTenant(ctx, "test@example.com", rooms[len(rooms)-1].ID, tenantEmail, "password123", "2025-01-01", "1", "900", "USD")
+	if err != nil {
+		t.Fatalf("Failed to create tenant: %s", err.Error())
+	}
+}
+
+// tenantTickets gets the maintenance tickets a tenant has opened
+func tenantTickets(t *testing.T, tenantEmail string) []db.MaintenanceTicket {
+	tickets, err := testutil.TestEnvironment.DB.GetMaintenanceTicketsByTenantHashEmail(context.Background(), utils.HashData(tenantEmail))
+	if err != nil {
+		t.Fatalf("Failed to get maintenance tickets: %s", err.Error())
+	}
+	return tickets
+}
+
+func TestTenantNewMaintenanceTicket(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	addTenantInOwnRoom(t, "Upload Tenant", "upload-tenant@example.com")
+
+	tooMany := make([]ticketPhoto, 6)
+	for i := range tooMany {
+		tooMany[i] = ticketPhoto{"leak.png", pngPhoto(512)}
+	}
+
+	// Define test cases
+	testCases := []struct {
+		name             string
+		photos           []ticketPhoto
+		expectedLocation string
+		expectedPhotos   int
+	}{
+		{
+			name:             "Ticket with a photo",
+			photos:           []ticketPhoto{{`C:\photos\leak.png`, pngPhoto(512)}},
+			expectedLocation: "/tenant/dashboard/maintenance",
+			expectedPhotos:   1,
+		},
+		{
+			name:             "Photo that is not an image",
+			photos:           []ticketPhoto{{"leak.png", []byte("#!/bin/sh\necho not a photo\n")}},
+			expectedLocation: "/tenant/dashboard/maintenance?validationError=" + url.QueryEscape("BAD REQUEST 400: photos must be JPEG, PNG, GIF or WebP images"),
+			expectedPhotos:   -1,
+		},
+		{
+			name:             "Photo larger than 5MB",
+			photos:           []ticketPhoto{{"leak.png", pngPhoto(5<<20 + 1)}},
+			expectedLocation: "/tenant/dashboard/maintenance?validationError=" + url.QueryEscape("BAD REQUEST 400: leak.png is larger than 5MB"),
+			expectedPhotos:   -1,
+		},
+		{
+			name:             "More than 5 photos",
+			photos:           tooMany,
+			expectedLocation: "/tenant/dashboard/maintenance?validationError=" + url.QueryEscape("BAD REQUEST 400: no more than 5 photos can be attached"),
+			expectedPhotos:   -1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			before := len(tenantTickets(t, "upload-tenant@example.com"))
+
+			rr := testutil.ServeTestRequest(newTicketRequest(t, "upload-tenant@example.com", tc.photos...))
+
+			if status := rr.Code; status != http.StatusSeeOther {
+				t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusSeeOther)
+			}
+			if location := rr.Header().Get("Location"); location != tc.expectedLocation {
+				t.Errorf("Handler returned wrong redirect location: got '%s' want '%s'", location, tc.expectedLocation)
+			}
+
+			tickets := tenantTickets(t, "upload-tenant@example.com")
+			if tc.expectedPhotos < 0 {
+				// a rejected photo rejects the whole ticket
+				if len(tickets) != before {
+					t.Errorf("Expected no ticket to be opened, got %d tickets", len(tickets))
+				}
+				return
+			}
+			if len(tickets) != before+1 {
+				t.Fatalf("Expected a ticket to be opened, got %d tickets", len(tickets))
+			}
+			attachments := tickets[0].Attachments
+			if len(attachments) != tc.expectedPhotos {
+				t.Fatalf("Expected %d photos, got %d", tc.expectedPhotos, len(attachments))
+			}
+			if attachments[0].ContentType != "image/png" || attachments[0].FileName != "leak.png" {
+				t.Errorf("Expected leak.png detected as image/png, got %s as %s", attachments[0].FileName, attachments[0].ContentType)
+			}
+		})
+	}
+}
+
+func TestTenantMaintenancePhoto(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	addTenantInOwnRoom(t, "Photo Tenant", "photo-tenant@example.com")
+	photo := pngPhoto(512)
+	_, err := testutil.TestEnvironment.DB.CreateMaintenanceTicket(context.Background(), utils.HashData("photo-tenant@example.com"), "plumbing", "high", "The shower is leaking",
+		[]db.NewMaintenanceAttachment{{FileName: "shower.png", ContentType: "image/png", Data: photo}})
+	if err != nil {
+		t.Fatalf("Failed to create maintenance ticket: %s", err.Error())
+	}
+	photoId := tenantTickets(t, "photo-tenant@example.com")[0].Attachments[0].ID
+	target := "/tenant/dashboard/maintenance/photo?id=" + strconv.Itoa(photoId)
+
+	t.Run("Photo from the tenant's own ticket", func(t *testing.T) {
+		rr := testutil.ServeTestRequest(testutil.TenantRequest(http.MethodGet, target, nil, "photo-tenant@example.com"))
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("Handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		if !bytes.Equal(rr.Body.Bytes(), photo) {
+			t.Errorf("Expected the decrypted photo to be served")
+		}
+		if contentType := rr.Header().Get("Content-Type"); contentType != "image/png" {
+			t.Errorf("Expected the photo served as image/png, got %s", contentType)
+		}
+	})
+
+	t.Run("Photo from another tenant's ticket", func(t *testing.T) {
+		rr := testutil.ServeTestRequest(testutil.TenantRequest(http.MethodGet, target, nil, "tenant@example.com"))
+
+		// the photo is not found, rather than forbidden, so tenants cannot tell which photos exist
+		if status := rr.Code; status != http.StatusNotFound {
+			t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+		}
+		if bytes.Contains(rr.Body.Bytes(), photo[:8]) {
+			t.Errorf("Expected the photo not to be served to another tenant")
+		}
+	})
+}
+
+func TestLandlordUpdateMaintenanceTicket(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	addTenantInOwnRoom(t, "Ticket Tenant", "ticket-tenant@example.com")
+	ticketId, err := testutil.TestEnvironment.DB.CreateMaintenanceTicket(context.Background(), utils.HashData("ticket-tenant@example.com"), "heating", "urgent", "The boiler is not working", nil)
+	if err != nil {
+		t.Fatalf("Failed to create maintenance ticket: %s", err.Error())
+	}
+
+	// ticketUpdates gets the emails waiting in the outbox about the ticket
+	ticketUpdates := func() []db.TicketUpdateEmail {
+		var updates []db.TicketUpdateEmail
+		for _, email := range testutil.TestEnvironment.DB.OutboxEmails() {
+			if email.Kind != db.OutboxTenantTicketUpdate {
+				continue
+			}
+			var update db.TicketUpdateEmail
+			if err := json.Unmarshal(email.Payload, &update); err != nil {
+				t.Fatalf("Failed to decode outbox email: %s", err.Error())
+			}
+			if update.TicketID == ticketId {
+				updates = append(updates, update)
+			}
+		}
+		return updates
+	}
+
+	t.Run("Status change emails the tenant", func(t *testing.T) {
+		form := url.Values{"ticketId": {strconv.Itoa(ticketId)}, "status": {"scheduled"}, "priority": {"urgent"}, "scheduledFor": {"2025-03-01"}, "note": {"An engineer will visit in the morning"}}
+		rr := testutil.ServeTestRequest(testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/maintenance/update", form, "test@example.com"))
+
+		if location := rr.Header().Get("Location"); location != "/landlord/dashboard/maintenance" {
+			t.Fatalf("Handler returned wrong redirect location: got '%s' want '/landlord/dashboard/maintenance'", location)
+		}
+
+		updates := ticketUpdates()
+		if len(updates) != 1 {
+			t.Fatalf("Expected one email to the tenant in the outbox, got %d", len(updates))
+		}
+		expected := db.TicketUpdateEmail{
+			TenantEmail:  "ticket-tenant@example.com",
+			TicketID:     ticketId,
+			Category:     "heating",
+			Status:       "scheduled",
+			ScheduledFor: "2025-03-01",
+			Note:         "An engineer will visit in the morning",
+		}
+		if updates[0] != expected {
+			t.Errorf("Expected the email %+v, got %+v", expected, updates[0])
+		}
+	})
+
+	t.Run("Refused status change sends nothing", func(t *testing.T) {
+		form := url.Values{"ticketId": {strconv.Itoa(ticketId)}, "status": {"open"}, "priority": {"urgent"}}
+		rr := testutil.ServeTestRequest(testutil.LandlordRequest(http.MethodPost, "/landlord/dashboard/maintenance/update", form, "test@example.com"))
+
+		if location := rr.Header().Get("Location"); !strings.HasPrefix(location, "/landlord/dashboard/maintenance?validationError=") {
+			t.Errorf("Expected the ticket not to move back to open, got '%s'", location)
+		}
+		if updates := ticketUpdates(); len(updates) != 1 {
+			t.Errorf("Expected no more emails to the tenant, got %d", len(updates))
+		}
+	})
+}
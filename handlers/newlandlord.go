@@ -1,18 +1,42 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 )
 
-func NewLandlord(w http.ResponseWriter, r *http.Request) {
+/*
+landlordRegistrationOpen reports whether anyone can create a landlord account from the new landlord page.
+Registration is invite only unless config.Registration.PublicLandlordRegistration is on, except on a new site
+with no landlords yet, where the first account created becomes the owner.
+If the landlords cannot be counted registration is treated as closed.
+*/
+func (s *Server) landlordRegistrationOpen(ctx context.Context) bool {
+	if config.Registration.PublicLandlordRegistration {
+		return true
+	}
+
+	count, err := s.store.CountLandlords(ctx)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error counting landlords: %s. Treating landlord registration as closed", err.Error()))
+		return false
+	}
+	return count == 0
+}
+
+func (s *Server) NewLandlord(w http.ResponseWriter, r *http.Request) {
 	// get error message (if any)
 	confirmPasswordError := r.URL.Query().Get("confirmPasswordError")
 
-	data := ErrorMessages{
-		ConfirmPasswordError: confirmPasswordError,
+	data := ShowNewLandlord{
+		ErrorMessages: ErrorMessages{
+			ConfirmPasswordError: confirmPasswordError,
+		},
+		RegistrationOpen: s.landlordRegistrationOpen(r.Context()),
 	}
 
 	// pass error message to HTML template
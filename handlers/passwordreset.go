@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/email"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// validResetRole checks the role of a password reset request, so it can be used in redirects and queries.
+func validResetRole(role string) bool {
+	return role == LANDLORD || role == TENANT
+}
+
+func (s *Server) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to home page.", r.Method))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	role := r.URL.Query().Get("role")
+	if !validResetRole(role) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// get any error messages
+	showData := ShowPasswordReset{Role: role, Sent: r.URL.Query().Get("sent") == "true"}
+	showData.Error.ValidationError = r.URL.Query().Get("validationError")
+	showData.Error.InternalServerError = r.URL.Query().Get("internalServerError")
+
+	err := Templates.ExecuteTemplate(w, "forgotPassword.html", showData)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Unable to load forgot password page: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Unable to load forgot password page: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) SubmitForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to home page.", r.Method))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// extract data from form
+	role := r.FormValue("role")
+	userEmail := r.FormValue("email")
+
+	if !validResetRole(role) {
+		http.NotFound(w, r)
+		return
+	}
+	if userEmail == "" {
+		http.Redirect(w, r, "/forgot-password?role="+role+"&validationError=BAD+REQUEST+400:+Please+enter+your+email+address", http.StatusSeeOther)
+		return
+	}
+
+	// tenants are stored by the hash of their email
+	userKey := userEmail
+	if role == TENANT {
+		userKey = utils.HashData(userEmail)
+	}
+
+	token, err := s.store.CreatePasswordReset(r.Context(), role, userKey)
+	if err == sql.ErrNoRows {
+		// show the same message whether or not the account exists, so accounts cannot be discovered here
+		logs.Logs(logWarn, fmt.Sprintf("Password reset requested for unknown %s account", role))
+		http.Redirect(w, r, "/forgot-password?role="+role+"&sent=true", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to create password reset: %s", err.Error()))
+		http.Redirect(w, r, "/forgot-password?role="+role+"&internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+password+reset", http.StatusSeeOther)
+		return
+	}
+
+	resetLink := fmt.Sprintf("%s/reset-password?role=%s&token=%s", siteURL, role, url.QueryEscape(token))
+	err = email.NotifyPasswordReset(userEmail, resetLink)
+	if err != nil {
+		// an error here would only be shown for accounts that exist, so the same message is shown as when it is sent
+		logs.Logs(logErr, fmt.Sprintf("Failed to send password reset email: %s", err.Error()))
+	}
+
+	http.Redirect(w, r, "/forgot-password?role="+role+"&sent=true", http.StatusSeeOther)
+}
+
+func (s *Server) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to home page.", r.Method))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	role := r.URL.Query().Get("role")
+	if !validResetRole(role) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// the reset token is in the URL, so do not leak it to other sites
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	showData := ShowPasswordReset{Role: role, Token: r.URL.Query().Get("token")}
+	showData.Error.ValidationError = r.URL.Query().Get("validationError")
+
+	err := s.store.ValidatePasswordResetToken(r.Context(), role, showData.Token)
+	if err == db.ErrInvalidResetToken {
+		showData.Error.AuthenticationError = "This password reset link is invalid, has already been used or has expired."
+	} else if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to check password reset: %s", err.Error()))
+		showData.Error.InternalServerError = "INTERNAL SERVER ERROR 500: Failed to check password reset link"
+	}
+
+	err = Templates.ExecuteTemplate(w, "resetPassword.html", showData)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Unable to load reset password page: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Unable to load reset password page: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) SubmitResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to home page.", r.Method))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// extract data from form
+	role := r.FormValue("role")
+	token := r.FormValue("token")
+	newPassword := r.FormValue("newPassword")
+	confirmPassword := r.FormValue("confirmPassword")
+
+	if !validResetRole(role) {
+		http.NotFound(w, r)
+		return
+	}
+	resetPage := fmt.Sprintf("/reset-password?role=%s&token=%s", role, url.QueryEscape(token))
+
+	// validate new password is the same as confirmed password
+	if newPassword == "" || !utils.ValidateNewPassword(newPassword, confirmPassword) {
+		logs.Logs(logErr, "New password and confirmed password do not match. Redirecting back to reset password page")
+		http.Redirect(w, r, resetPage+"&validationError=BAD+REQUEST+400:+New+password+and+confirmed+password+do+not+match", http.StatusSeeOther)
+		return
+	}
+
+	err = s.store.ResetPassword(r.Context(), role, token, newPassword)
+	if err == db.ErrInvalidResetToken {
+		logs.Logs(logWarn, "Invalid password reset token. Redirecting back to reset password page")
+		http.Redirect(w, r, resetPage, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to reset password: %s", err.Error()))
+		http.Redirect(w, r, resetPage+"&validationError=INTERNAL+SERVER+ERROR+500:+Failed+to+reset+password", http.StatusSeeOther)
+		return
+	}
+
+	// every session was ended by the reset, so the user logs in again with the new password
+	http.Redirect(w, r, "/login/"+role+"?passwordReset=Your+password+has+been+reset.+Please+login+with+your+new+password.", http.StatusSeeOther)
+}
@@ -0,0 +1,189 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// resetPasswordRequest posts a new password with the reset token, as the reset password page does
+func resetPasswordRequest(role, token, newPassword string) *http.Request {
+	form := url.Values{"role": {role}, "token": {token}, "newPassword": {newPassword}, "confirmPassword": {newPassword}}
+	req := httptest.NewRequest(http.MethodPost, "/reset-password/submit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+// newPasswordReset creates a password reset for the user, returning the token that would be emailed to them
+func newPasswordReset(t *testing.T, role, userKey string) string {
+	token, err := testutil.TestEnvironment.DB.CreatePasswordReset(context.Background(), role, userKey)
+	if err != nil {
+		t.Fatalf("Failed to create password reset: %s", err.Error())
+	}
+	return token
+}
+
+func TestSubmitForgotPassword(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// no email can be sent in tests, so a reset link that is emailed shows the send failure
+	t.Setenv("LHP_EMAIL", "")
+	t.Setenv("LHP_EMAIL_PASSWORD", "")
+
+	// Define test cases
+	testCases := []struct {
+		name             string
+		form             url.Values
+		expectedLocation string
+		expectedResets   int // how many more reset links are stored
+	}{
+		{
+			name:             "Unknown landlord email",
+			form:             url.Values{"role": {"landlord"}, "email": {"nobody@example.com"}},
+			expectedLocation: "/forgot-password?role=landlord&sent=true",
+		},
+		{
+			name:             "Unknown tenant email",
+			form:             url.Values{"role": {"tenant"}, "email": {"nobody@example.com"}},
+			expectedLocation: "/forgot-password?role=tenant&sent=true",
+		},
+		{
+			name:             "Missing email",
+			form:             url.Values{"role": {"landlord"}},
+			expectedLocation: "/forgot-password?role=landlord&validationError=BAD+REQUEST+400:+Please+enter+your+email+address",
+		},
+		{
+			name:             "Known landlord email",
+			form:             url.Values{"role": {"landlord"}, "email": {"test@example.com"}},
+			expectedLocation: "/forgot-password?role=landlord&sent=true",
+			expectedResets:   1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resets := len(testutil.TestEnvironment.DB.PasswordResets())
+
+			req := httptest.NewRequest(http.MethodPost, "/forgot-password/submit", strings.NewReader(tc.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := testutil.ServeTestRequest(req)
+
+			if rr.Code != http.StatusSeeOther {
+				t.Errorf("Expected status code %d, got %d", http.StatusSeeOther, rr.Code)
+			}
+			if location := rr.Header().Get("Location"); location != tc.expectedLocation {
+				t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+			}
+			// an unknown account gets the same answer as a known one, with no link created to email
+			if added := len(testutil.TestEnvironment.DB.PasswordResets()) - resets; added != tc.expectedResets {
+				t.Errorf("Expected %d password resets to be created, got %d", tc.expectedResets, added)
+			}
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// the other tests log in with the original password
+	t.Cleanup(func() {
+		token := newPasswordReset(t, "landlord", "test@example.com")
+		testutil.TestEnvironment.DB.ResetPassword(context.Background(), "landlord", token, "password123")
+	})
+
+	loggedIn := "/login/landlord?passwordReset=Your+password+has+been+reset.+Please+login+with+your+new+password."
+
+	t.Run("Stored token is hashed", func(t *testing.T) {
+		token := newPasswordReset(t, "landlord", "test@example.com")
+
+		resets := testutil.TestEnvironment.DB.PasswordResets()
+		stored := resets[len(resets)-1].TokenHash
+		if stored == token || stored != utils.HashData(token) {
+			t.Errorf("Expected the hash of the token to be stored, got %q", stored)
+		}
+	})
+
+	t.Run("Token works only once", func(t *testing.T) {
+		token := newPasswordReset(t, "landlord", "test@example.com")
+
+		rr := testutil.ServeTestRequest(resetPasswordRequest("landlord", token, "firstpassword"))
+		if location := rr.Header().Get("Location"); location != loggedIn {
+			t.Fatalf("Expected redirect to '%s', got '%s'", loggedIn, location)
+		}
+
+		rr = testutil.ServeTestRequest(resetPasswordRequest("landlord", token, "secondpassword"))
+		expectedLocation := "/reset-password?role=landlord&token=" + url.QueryEscape(token)
+		if location := rr.Header().Get("Location"); location != expectedLocation {
+			t.Errorf("Expected redirect to '%s', got '%s'", expectedLocation, location)
+		}
+		if valid, _ := testutil.TestEnvironment.DB.AuthenticateLandlord(context.Background(), "test@example.com", "firstpassword"); !valid {
+			t.Errorf("Expected the password set with the first use of the token to be kept")
+		}
+
+		// the reset page says the link has been used
+		req := httptest.NewRequest(http.MethodGet, expectedLocation, nil)
+		rr = testutil.ServeTestRequest(req)
+		if !strings.Contains(rr.Body.String(), "has already been used") {
+			t.Errorf("Expected the reset page to say the link cannot be used")
+		}
+	})
+
+	t.Run("Newer link replaces older link", func(t *testing.T) {
+		oldToken := newPasswordReset(t, "landlord", "test@example.com")
+		newPasswordReset(t, "landlord", "test@example.com")
+
+		err := testutil.TestEnvironment.DB.ValidatePasswordResetToken(context.Background(), "landlord", oldToken)
+		if err == nil {
+			t.Errorf("Expected the older reset link to stop working")
+		}
+	})
+
+	t.Run("Expired token is rejected", func(t *testing.T) {
+		token := newPasswordReset(t, "landlord", "test@example.com")
+		testutil.TestEnvironment.DB.ExpirePasswordResets(time.Hour + time.Minute)
+
+		rr := testutil.ServeTestRequest(resetPasswordRequest("landlord", token, "expiredpassword"))
+		expectedLocation := "/reset-password?role=landlord&token=" + url.QueryEscape(token)
+		if location := rr.Header().Get("Location"); location != expectedLocation {
+			t.Errorf("Expected redirect to '%s', got '%s'", expectedLocation, location)
+		}
+		if valid, _ := testutil.TestEnvironment.DB.AuthenticateLandlord(context.Background(), "test@example.com", "expiredpassword"); valid {
+			t.Errorf("Expected the password not to be changed with an expired token")
+		}
+	})
+
+	t.Run("Reset ends existing sessions", func(t *testing.T) {
+		sessionToken, _, _, err := testutil.TestEnvironment.DB.StartLandlordSession(context.Background(), "test@example.com", false)
+		if err != nil {
+			t.Fatalf("Failed to start landlord session: %s", err.Error())
+		}
+		token := newPasswordReset(t, "landlord", "test@example.com")
+
+		rr := testutil.ServeTestRequest(resetPasswordRequest("landlord", token, "sessionpassword"))
+		if location := rr.Header().Get("Location"); location != loggedIn {
+			t.Fatalf("Expected redirect to '%s', got '%s'", loggedIn, location)
+		}
+
+		// the dashboard sends the old session back to the login page
+		req := httptest.NewRequest(http.MethodGet, "/landlord/dashboard", nil)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+		rr = testutil.ServeTestRequest(req)
+		if rr.Code != http.StatusSeeOther || !strings.HasPrefix(rr.Header().Get("Location"), "/login/landlord") {
+			t.Errorf("Expected the old session to be sent to the login page, got %d to '%s'", rr.Code, rr.Header().Get("Location"))
+		}
+	})
+}
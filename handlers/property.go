@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+)
+
+// showRoom converts a room from the database into a room to display in the HTML templates.
+func showRoom(room db.Room) ShowRoom {
+	return ShowRoom{
+		ID:           room.ID,
+		PropertyName: room.PropertyName,
+		Unit:         room.Unit,
+		Name:         room.Name,
+		RoomType:     room.RoomType,
+		Capacity:     room.Capacity,
+		Amenities:    room.Amenities,
+		DefaultRent:  room.DefaultRent,
+		Currency:     room.Currency,
+		Occupants:    room.Occupants,
+		Vacancies:    room.Vacancies(),
+	}
+}
+
+// showProperties converts properties from the database into properties to display in the HTML templates,
+// with each property's rooms and its total capacity, occupants and vacancies.
+func showProperties(properties []db.Property, rooms []db.Room) []ShowProperty {
+	showProperties := []ShowProperty{}
+	for _, property := range properties {
+		showProperty := ShowProperty{
+			ID:      property.ID,
+			Name:    property.Name,
+			Address: property.Address,
+		}
+		for _, room := range rooms {
+			if room.PropertyID != property.ID {
+				continue
+			}
+			showProperty.Rooms = append(showProperty.Rooms, showRoom(room))
+			showProperty.Capacity += room.Capacity
+			showProperty.Occupants += room.Occupants
+			showProperty.Vacancies += room.Vacancies()
+		}
+		showProperties = append(showProperties, showProperty)
+	}
+	return showProperties
+}
+
+// vacantRooms returns the rooms that still have space for another tenant.
+func vacantRooms(rooms []db.Room) []ShowRoom {
+	vacant := []ShowRoom{}
+	for _, room := range rooms {
+		if room.Vacancies() > 0 {
+			vacant = append(vacant, showRoom(room))
+		}
+	}
+	return vacant
+}
+
+/*
+getLandlordRoom gets the room chosen on a landlord form, checking it is one of the landlord's rooms.
+
+Arguments:
+
+- landlordEmail: The email of the landlord authenticated by the session.
+
+- roomId: The room ID from the form.
+
+Returns:
+
+- db.Room: The chosen room.
+
+- error: An error object if the room is not valid.
+*/
+func (s *Server) getLandlordRoom(ctx context.Context, landlordEmail, roomId string) (db.Room, error) {
+	roomIdInt, err := strconv.Atoi(roomId)
+	if err != nil {
+		return db.Room{}, err
+	}
+	return s.store.GetLandlordRoom(ctx, landlordEmail, roomIdInt)
+}
+
+/*
+getVacantRoom gets the room chosen on a landlord form, checking it is one of the landlord's rooms and still has space.
+
+Arguments:
+
+- landlordEmail: The email of the landlord authenticated by the session.
+
+- roomId: The room ID from the form.
+
+Returns:
+
+- db.Room: The chosen room.
+
+- error: db.ErrRoomFull if the room has no space left, or an error object if the room is not valid.
+*/
+func (s *Server) getVacantRoom(ctx context.Context, landlordEmail, roomId string) (db.Room, error) {
+	room, err := s.getLandlordRoom(ctx, landlordEmail, roomId)
+	if err != nil {
+		return db.Room{}, err
+	}
+
+	if room.Vacancies() == 0 {
+		return db.Room{}, db.ErrRoomFull
+	}
+	return room, nil
+}
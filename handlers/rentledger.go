@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+/*
+buildRentLedger decrypts a tenant's ledger entries and calculates the running balance,
+totals and arrears shown on the tenant dashboard and the landlord rent page.
+
+A positive balance is money owed by the tenant. Because charges are only generated up to today,
+a positive balance is also the amount in arrears.
+*/
+func buildRentLedger(tenantId int, currency string, entries []db.RentLedgerEntry) (ShowRentLedger, error) {
+	ledger := ShowRentLedger{
+		TenantID: tenantId,
+		Currency: currency,
+	}
+
+	var balance, totalCharged, totalPaid int64
+	var lastPayment time.Time
+
+	for _, entry := range entries {
+		decryptAmount, err := utils.Decrypt(entry.EncryptAmount)
+		if err != nil {
+			return ShowRentLedger{}, fmt.Errorf("failed to decrypt ledger amount: %s", err.Error())
+		}
+		amount, err := utils.ParseAmount(string(decryptAmount))
+		if err != nil {
+			return ShowRentLedger{}, err
+		}
+
+		showEntry := ShowRentLedgerEntry{
+			Date:      entry.EntryDate.Format("2006-01-02"),
+			EntryType: entry.EntryType,
+			Period:    entry.Period,
+		}
+
+		switch entry.EntryType {
+		case db.RentCharge:
+			balance += amount
+			totalCharged += amount
+			showEntry.Charge = utils.FormatAmount(amount)
+		case db.RentPayment:
+			balance -= amount
+			totalPaid += amount
+			showEntry.Payment = utils.FormatAmount(amount)
+
+			if entry.EncryptMethod != nil {
+				method, err := utils.Decrypt(entry.EncryptMethod)
+				if err != nil {
+					return ShowRentLedger{}, fmt.Errorf("failed to decrypt payment method: %s", err.Error())
+				}
+				showEntry.Method = string(method)
+			}
+			if entry.EncryptReference != nil {
+				reference, err := utils.Decrypt(entry.EncryptReference)
+				if err != nil {
+					return ShowRentLedger{}, fmt.Errorf("failed to decrypt payment reference: %s", err.Error())
+				}
+				showEntry.Reference = string(reference)
+			}
+			if entry.EntryDate.After(lastPayment) {
+				lastPayment = entry.EntryDate
+			}
+		}
+
+		showEntry.Balance = utils.FormatAmount(balance)
+		ledger.Entries = append(ledger.Entries, showEntry)
+	}
+
+	ledger.TotalCharged = utils.FormatAmount(totalCharged)
+	ledger.TotalPaid = utils.FormatAmount(totalPaid)
+	ledger.Balance = utils.FormatAmount(balance)
+	ledger.Arrears = utils.FormatAmount(0)
+	if balance > 0 {
+		ledger.InArrears = true
+		ledger.Arrears = utils.FormatAmount(balance)
+	}
+	if !lastPayment.IsZero() {
+		ledger.LastPayment = lastPayment.Format("2006-01-02")
+	}
+
+	return ledger, nil
+}
+
+/*
+getTenantRentLedger brings the tenant's monthly charges up to date and returns their decrypted ledger.
+*/
+func (s *Server) getTenantRentLedger(ctx context.Context, tenantId int, currency string) (ShowRentLedger, error) {
+	err := s.store.GenerateRentCharges(ctx, tenantId)
+	if err != nil {
+		return ShowRentLedger{}, err
+	}
+
+	entries, err := s.store.GetRentLedgerByTenantId(ctx, tenantId)
+	if err != nil {
+		return ShowRentLedger{}, err
+	}
+
+	return buildRentLedger(tenantId, currency, entries)
+}
@@ -6,59 +6,34 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/email"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
 )
 
-func SendMessageToTenant(w http.ResponseWriter, r *http.Request) {
+func (s *Server) SendMessageToTenant(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
 		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
 		return
 	}
 
-	// deny the request if the authorization fails
-	err := middleware.AuthenticateLandlordRequest(r)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error authenticating landlord: %s. Redirecting to landlord login page", err.Error()))
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord", http.StatusSeeOther)
-		return
-	}
-
-	// get session cookie
-	sessionToken, err := utils.CheckSessionToken(r)
-	if err != nil {
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+session+token", http.StatusSeeOther)
-		return
-	}
-
-	// get landlord emial from session cookie
-	landlordEmail, err := db.GetEmailFromLandlordSessionToken(sessionToken.Value)
-	if err != nil {
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+landlord+email+from+session+token", http.StatusSeeOther)
-		return
-	}
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
 
 	// get landlord id from email
-	landlordId, err := db.GetLandlordIdByEmail(landlordEmail)
+	landlordId, err := s.store.GetLandlordIdByEmail(r.Context(), landlordEmail)
 	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
 		logs.Logs(logErr, fmt.Sprintf("Error getting landlord ID: %s. Redirecting to landlord login page", err.Error()))
 		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+landlord+ID", http.StatusSeeOther)
 		return
 	}
 
-	// update the landlord's session token, CSRF token and expiry time in the database
-	// this will be doen for each request
-	newSessionToken, newCsrfToken, newExpiryTime, err := db.UpdateLandlordSessionTokens(landlordEmail)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error updating landlord session tokens: %s. Redirecting to landlord login page", err.Error()))
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+update+session+tokens", http.StatusSeeOther)
-		return
-	}
-
 	// get the URL prefix of the current page
 	tenantID := strings.TrimPrefix(r.URL.Path, "/landlord/send-message/")
 	// convert tenant id to int
@@ -69,20 +44,6 @@ func SendMessageToTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO! Set cookies for each available page
-	createLandlordTenantMessagesSessionCookie := middleware.LandlordTenantMessagesSessionCookie(w, tenantID, newSessionToken, newExpiryTime)
-	if !createLandlordTenantMessagesSessionCookie {
-		logs.Logs(logErr, "Error creating landlord tenant messages session cookie. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+create+landlord+tenant+messages+session+cookie", http.StatusSeeOther)
-		return
-	}
-	createLandlordTenantMessagesCSRFTokenCookie := middleware.LandlordTenantMessagesCSRFTokenCookie(w, tenantID, newCsrfToken, newExpiryTime)
-	if !createLandlordTenantMessagesCSRFTokenCookie {
-		logs.Logs(logErr, "Error creating landlord tenant messages CSRF token cookie. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+create+landlord+tenant+messages+CSRF+token+cookie", http.StatusSeeOther)
-		return
-	}
-
 	// TODO: extract data from form
 	err = r.ParseForm()
 	if err != nil {
@@ -94,8 +55,11 @@ func SendMessageToTenant(w http.ResponseWriter, r *http.Request) {
 	// TODO: save message to database
 	landlordMessage := r.FormValue("landlordMessage")
 
-	err = db.SendMessage(landlordId, LANDLORD, tenantIdInt, TENANT, landlordMessage)
+	err = s.store.SendMessage(r.Context(), landlordId, LANDLORD, tenantIdInt, TENANT, landlordMessage)
 	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
 		logs.Logs(logErr, fmt.Sprintf("Error sending message to tenant: %s", err.Error()))
 		http.Error(w, fmt.Sprintf("Error sending message to tenant: %s", err.Error()), http.StatusInternalServerError)
 		return
@@ -103,8 +67,11 @@ func SendMessageToTenant(w http.ResponseWriter, r *http.Request) {
 
 	// TODO: send email notification to tenant
 	// get tenant email
-	encryptTenantEmail, err := db.GetTenantEncryptedEmailById(tenantIdInt)
+	encryptTenantEmail, err := s.store.GetTenantEncryptedEmailById(r.Context(), tenantIdInt)
 	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
 		logs.Logs(logErr, fmt.Sprintf("Error getting tenant email: %s", err.Error()))
 		http.Error(w, fmt.Sprintf("Error getting tenant email: %s", err.Error()), http.StatusInternalServerError)
 		return
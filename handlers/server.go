@@ -1,77 +1,224 @@
 package handlers
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
 )
 
-func StartHTTPServer() {
-	logs.Logs(logInfo, "Starting HTTP server...")
+// publicForms are the forms on pages anyone can open. They do not use the session, so they are not sent
+// with a CSRF token, and a user who is logged in can still send them.
+var publicForms = []string{
+	"/tenancy-form/submit",
+	"/new/landlord/submit",
+	"/invitation/submit",
+	"/login/landlord/submit",
+	"/login/tenant/submit",
+	"/login/two-factor/submit",
+	"/forgot-password/submit",
+	"/reset-password/submit",
+}
 
-	InitTemplates()
+// Server serves the app's pages. Handlers read and write records through its store,
+// so they can be run against testutil.MockDB in tests.
+type Server struct {
+	store db.Store
+}
 
-	// initialise encryption functions
-	err := utils.InitEncryption()
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error initialising encryption functions: %s", err.Error()))
-	}
+// NewServer returns a server whose handlers use the store, normally db.Postgres.
+func NewServer(store db.Store) *Server {
+	return &Server{store: store}
+}
+
+// Routes returns the handler for every route of the app, wrapped in the middleware every request goes through.
+func (s *Server) Routes() http.Handler {
+	// routes are registered on the app's own mux rather than http.DefaultServeMux,
+	// so nothing another package registers on the default mux is served
+	mux := http.NewServeMux()
 
 	// Static file server for assets like CSS, JS, images
 	var staticFiles = http.FileServer(http.Dir("./static"))
-	http.Handle("/static/", http.StripPrefix("/static/", staticFiles))
+	mux.Handle("/static/", http.StripPrefix("/static/", staticFiles))
 
 	// define routes
-	http.HandleFunc("/", Home)
-	http.HandleFunc("/tenancy-form", TenancyForm)
-	http.HandleFunc("/tenancy-form/submit", SubmitTenantForm)
-	http.HandleFunc("/new/landlord", NewLandlord)
-	http.HandleFunc("/new/landlord/submit", SubmitNewLandlord)
-	http.HandleFunc("/login/landlord", LoginLandlord)
-	http.HandleFunc("/login/landlord/submit", SubmitLoginLandlord)
-	http.HandleFunc("/login/tenant", LoginTenant)
-	http.HandleFunc("/login/tenant/submit", SubmitLoginTenant)
+	mux.HandleFunc("/", s.Home)
+	mux.HandleFunc("/tenancy-form", s.TenancyForm)
+	mux.HandleFunc("/tenancy-form/submit", s.SubmitTenantForm)
+	mux.HandleFunc("/new/landlord", s.NewLandlord)
+	mux.HandleFunc("/new/landlord/submit", s.SubmitNewLandlord)
+	mux.HandleFunc("/invitation", s.LandlordInvitation)
+	mux.HandleFunc("/invitation/submit", s.SubmitLandlordInvitation)
+	mux.HandleFunc("/login/landlord", s.LoginLandlord)
+	mux.HandleFunc("/login/landlord/submit", s.SubmitLoginLandlord)
+	mux.HandleFunc("/login/tenant", s.LoginTenant)
+	mux.HandleFunc("/login/tenant/submit", s.SubmitLoginTenant)
+	mux.HandleFunc("/login/two-factor", s.LoginTwoFactor)
+	mux.HandleFunc("/login/two-factor/submit", s.SubmitLoginTwoFactor)
+	mux.HandleFunc("/forgot-password", s.ForgotPassword)
+	mux.HandleFunc("/forgot-password/submit", s.SubmitForgotPassword)
+	mux.HandleFunc("/reset-password", s.ResetPassword)
+	mux.HandleFunc("/reset-password/submit", s.SubmitResetPassword)
+
+	// probes for the hosting platform: /healthz while the process is up, /readyz while its dependencies are
+	mux.HandleFunc("/healthz", s.Healthz)
+	mux.HandleFunc("/readyz", s.Readyz)
+	mux.HandleFunc("/version", s.Version)
+
+	// protected routes go through the session middleware, which authenticates the user,
+	// rotates their tokens, sets the site-wide session cookies and adds the user to the request context
+	landlord := func(handler http.HandlerFunc) http.Handler { return middleware.RequireLandlord(s.store, handler) }
+	tenant := func(handler http.HandlerFunc) http.Handler { return middleware.RequireTenant(s.store, handler) }
+	owner := func(handler http.HandlerFunc) http.Handler { return middleware.RequireOwner(s.store, handler) }
+	// landlords have every permission, staff only the ones their landlord has granted them
+	permitted := func(permission string, handler http.HandlerFunc) http.Handler {
+		return middleware.RequireLandlordPermission(s.store, permission, handler)
+	}
+	accountHolder := func(handler http.HandlerFunc) http.Handler { return middleware.RequireAccountHolder(s.store, handler) }
+	// routes that take the ID of a record only run for records that belong to the landlord the user works with,
+	// any other ID gets a 404 and is added to the audit log
+	owned := func(resource string, id middleware.IDSource, handler http.HandlerFunc) http.HandlerFunc {
+		return middleware.RequireOwnership(s.store, resource, id, handler).ServeHTTP
+	}
 
 	// protected landlord routes
-	http.HandleFunc("/logout-landlord", LogoutLandlord)
-	http.HandleFunc("/landlord/dashboard", LandlordDashboard)
-	http.HandleFunc("/landlord/dashboard/tenants", LandlordDashboardTenants)
-	http.HandleFunc("/landlord/dashboard/tenant-applications", LandlordTenantApplications)
-	http.HandleFunc("/landlord/dashboard/manage-applications", LandlordManageApplications)
-	http.HandleFunc("/landlord/dashboard/new-tenant", LandlordNewTenant)
-	http.HandleFunc("/landlord/dashboard/new-tenant/submit", LandlordSubmitNewTenant)
-	http.HandleFunc("/landlord/dashboard/messages", LandlordMessages)
-	http.HandleFunc("/landlord/dashboard/messages/tenant/", LandlordTenantMessages)
-	http.HandleFunc("/landlord/send-message/", SendMessageToTenant)
+	mux.HandleFunc("/logout-landlord", s.LogoutLandlord)
+	mux.Handle("/landlord/dashboard", landlord(s.LandlordDashboard))
+	mux.Handle("/landlord/dashboard/tenants", permitted(db.PermissionManageTenants, s.LandlordDashboardTenants))
+	mux.Handle("/landlord/dashboard/tenants/unlock", permitted(db.PermissionManageTenants, owned(db.ResourceTenant, middleware.IDFromForm("tenantId"), s.LandlordUnlockTenantAccount)))
+	mux.Handle("/landlord/dashboard/properties", permitted(db.PermissionManageProperties, s.LandlordProperties))
+	mux.Handle("/landlord/dashboard/properties/add", permitted(db.PermissionManageProperties, s.LandlordAddProperty))
+	mux.Handle("/landlord/dashboard/properties/add-room", permitted(db.PermissionManageProperties, owned(db.ResourceProperty, middleware.IDFromForm("propertyId"), s.LandlordAddRoom)))
+	mux.Handle("/landlord/dashboard/rent", permitted(db.PermissionViewFinancials, owned(db.ResourceTenant, middleware.IDFromQuery("tenant"), s.LandlordRentLedger)))
+	mux.Handle("/landlord/dashboard/rent/record-payment", permitted(db.PermissionViewFinancials, owned(db.ResourceTenant, middleware.IDFromForm("tenantId"), s.LandlordRecordRentPayment)))
+	mux.Handle("/landlord/dashboard/leases", permitted(db.PermissionManageTenants, s.LandlordLeases))
+	mux.Handle("/landlord/dashboard/leases/manage", permitted(db.PermissionManageTenants, owned(db.ResourceTenant, middleware.IDFromForm("tenantId"), s.LandlordManageLease)))
+	mux.Handle("/landlord/dashboard/tenant-applications", permitted(db.PermissionViewApplications, s.LandlordTenantApplications))
+	mux.Handle("/landlord/dashboard/manage-applications", permitted(db.PermissionDecideApplications, owned(db.ResourceApplication, middleware.IDFromForm("applicationId"), s.LandlordManageApplications)))
+	mux.Handle("/landlord/dashboard/new-tenant", permitted(db.PermissionManageTenants, s.LandlordNewTenant))
+	mux.Handle("/landlord/dashboard/new-tenant/submit", permitted(db.PermissionManageTenants, s.LandlordSubmitNewTenant))
+	mux.Handle("/landlord/dashboard/maintenance", permitted(db.PermissionManageMaintenance, s.LandlordMaintenance))
+	mux.Handle("/landlord/dashboard/maintenance/update", permitted(db.PermissionManageMaintenance, owned(db.ResourceMaintenanceTicket, middleware.IDFromForm("ticketId"), s.LandlordUpdateMaintenanceTicket)))
+	mux.Handle("/landlord/dashboard/maintenance/photo", permitted(db.PermissionManageMaintenance, owned(db.ResourceMaintenancePhoto, middleware.IDFromQuery("id"), s.LandlordMaintenancePhoto)))
+	mux.Handle("/landlord/dashboard/security", landlord(s.TwoFactorSettings))
+	mux.Handle("/landlord/dashboard/security/setup", landlord(s.SetupTwoFactor))
+	mux.Handle("/landlord/dashboard/security/confirm", landlord(s.ConfirmTwoFactor))
+	mux.Handle("/landlord/dashboard/security/disable", landlord(s.DisableTwoFactor))
+	mux.Handle("/landlord/dashboard/messages", permitted(db.PermissionMessageTenants, s.LandlordMessages))
+	mux.Handle("/landlord/dashboard/admin", owner(s.LandlordAdmin))
+	mux.Handle("/landlord/dashboard/admin/invite", owner(s.LandlordAdminInvite))
+	mux.Handle("/landlord/dashboard/admin/revoke-invitation", owner(s.LandlordAdminRevokeInvitation))
+	mux.Handle("/landlord/dashboard/admin/suspend", owner(s.LandlordAdminSuspendAccount))
+	mux.Handle("/landlord/dashboard/admin/remove", owner(s.LandlordAdminRemoveAccount))
+	mux.Handle("/landlord/dashboard/admin/unlock", owner(s.LandlordAdminUnlockAccount))
+	mux.Handle("/landlord/dashboard/staff", accountHolder(s.LandlordStaff))
+	mux.Handle("/landlord/dashboard/staff/invite", accountHolder(s.LandlordStaffInvite))
+	mux.Handle("/landlord/dashboard/staff/permissions", accountHolder(s.LandlordStaffPermissions))
+	mux.Handle("/landlord/dashboard/staff/remove", accountHolder(s.LandlordStaffRemove))
+	mux.Handle("/landlord/dashboard/staff/unlock", accountHolder(s.LandlordStaffUnlock))
+	mux.Handle("/landlord/dashboard/staff/revoke-invitation", accountHolder(s.LandlordStaffRevokeInvitation))
+	mux.Handle("/landlord/dashboard/audit", accountHolder(s.LandlordAuditLog))
+	mux.Handle("/landlord/dashboard/audit/export", accountHolder(s.LandlordAuditLogExport))
+	mux.Handle("/landlord/dashboard/messages/tenant/", permitted(db.PermissionMessageTenants, owned(db.ResourceTenant, middleware.IDFromPath("/landlord/dashboard/messages/tenant/"), s.LandlordTenantMessages)))
+	mux.Handle("/landlord/send-message/", permitted(db.PermissionMessageTenants, owned(db.ResourceTenant, middleware.IDFromPath("/landlord/send-message/"), s.SendMessageToTenant)))
 
 	// protected tenant routes
-	http.HandleFunc("/tenant/dashboard", TenantDashboard)
-	http.HandleFunc("/logout-tenant", LogoutTenant)
-	http.HandleFunc("/tenant/dashboard/account", TenantAccount)
-	http.HandleFunc("/tenant/update-password", UpdateTenantPassword)
-	http.HandleFunc("/tenant/dashboard/messages", TenantMessages)
-	http.HandleFunc("/tenant/send-message", SendMessageToLandlord)
+	mux.Handle("/tenant/dashboard", tenant(s.TenantDashboard))
+	mux.HandleFunc("/logout-tenant", s.LogoutTenant)
+	mux.Handle("/tenant/dashboard/account", tenant(s.TenantAccount))
+	mux.Handle("/tenant/dashboard/account/lease", tenant(s.TenantManageLease))
+	mux.Handle("/tenant/update-password", tenant(s.UpdateTenantPassword))
+	mux.Handle("/tenant/dashboard/account/security", tenant(s.TwoFactorSettings))
+	mux.Handle("/tenant/dashboard/account/security/setup", tenant(s.SetupTwoFactor))
+	mux.Handle("/tenant/dashboard/account/security/confirm", tenant(s.ConfirmTwoFactor))
+	mux.Handle("/tenant/dashboard/account/security/disable", tenant(s.DisableTwoFactor))
+	mux.Handle("/tenant/dashboard/maintenance", tenant(s.TenantMaintenance))
+	mux.Handle("/tenant/dashboard/maintenance/new", tenant(s.TenantNewMaintenanceTicket))
+	mux.Handle("/tenant/dashboard/maintenance/photo", tenant(s.TenantMaintenancePhoto))
+	mux.Handle("/tenant/dashboard/messages", tenant(s.TenantMessages))
+	mux.Handle("/tenant/send-message", tenant(s.SendMessageToLandlord))
+
+	// every route is wrapped in ProtectForms, which refuses form posts from other sites
+	// and checks the CSRF token that logged in pages render into their forms, other than the public forms,
+	// and in SecurityHeaders, which sets the content security policy and other security headers
+	return middleware.SecurityHeaders(middleware.ProtectForms(s.store, mux, publicForms...))
+}
+
+// StartHTTPServer serves the app's routes until the server stops.
+func (s *Server) StartHTTPServer() {
+	logs.Logs(logInfo, "Starting HTTP server...")
+
+	InitTemplates()
+
+	// initialise encryption functions. The server still starts without them so /healthz answers,
+	// but /readyz reports it is not ready, so no requests are sent to it
+	err := utils.InitEncryption()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error initialising encryption functions, the app will not report ready: %s", err.Error()))
+	}
+
+	handler := s.Routes()
 
 	// initialise port for application
 	httpPort := os.Getenv("PORT") // attempt to get port from hosting platform
+	listenHost := ""
 
 	// start server on local machine if hosting platform port is not set
 	if httpPort == "" {
-		logs.Logs(logWarn, fmt.Sprintf("Could not get PORT from hosting platform. Defaulting to http://localhost:%s...", localPort))
+		logs.Logs(logWarn, fmt.Sprintf("Could not get PORT from hosting platform. Defaulting to localhost:%s...", localPort))
 		httpPort = localPort
-		err := http.ListenAndServe("localhost:"+localPort, nil)
+		listenHost = "localhost"
+	}
+	server := newHTTPServer(net.JoinHostPort(listenHost, httpPort), handler)
+
+	// serve HTTPS when a certificate is configured, otherwise the hosting platform's proxy handles HTTPS
+	if config.Server.TLS() {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		if config.Server.HTTPRedirectPort != "" {
+			go startHTTPSRedirect(net.JoinHostPort(listenHost, config.Server.HTTPRedirectPort), httpPort)
+		}
+
+		logs.Logs(logInfo, fmt.Sprintf("HTTPS server running on https://localhost:%s", httpPort))
+		err = server.ListenAndServeTLS(config.Server.TLSCertFile, config.Server.TLSKeyFile)
 		if err != nil {
-			logs.Logs(logErr, fmt.Sprintf("Failed to start HTTP server: %s", err.Error()))
+			logs.Logs(logErr, fmt.Sprintf("Error starting HTTPS server: %s", err.Error()))
 		}
+		return
 	}
 
-	// start server on hosting platform port
 	logs.Logs(logInfo, fmt.Sprintf("HTTP server running on http://localhost:%s", httpPort))
-	err = http.ListenAndServe(":"+httpPort, nil)
+	err = server.ListenAndServe()
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Error starting HTTP server: %s", err.Error()))
 	}
 }
+
+// newHTTPServer returns a server for the handler with the timeouts in config.Server,
+// so slow or idle clients cannot hold connections open.
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: config.Server.ReadHeaderTimeout,
+		ReadTimeout:       config.Server.ReadTimeout,
+		WriteTimeout:      config.Server.WriteTimeout,
+		IdleTimeout:       config.Server.IdleTimeout,
+		MaxHeaderBytes:    1 << 20,
+	}
+}
+
+// startHTTPSRedirect listens for plain HTTP on addr and redirects every request to HTTPS on httpsPort.
+func startHTTPSRedirect(addr, httpsPort string) {
+	logs.Logs(logInfo, fmt.Sprintf("Redirecting HTTP on %s to HTTPS", addr))
+	err := newHTTPServer(addr, middleware.RedirectToHTTPS(httpsPort)).ListenAndServe()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error starting HTTP redirect server: %s", err.Error()))
+	}
+}
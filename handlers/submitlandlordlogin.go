@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 
@@ -9,7 +10,7 @@ import (
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
 )
 
-func SubmitLoginLandlord(w http.ResponseWriter, r *http.Request) {
+func (s *Server) SubmitLoginLandlord(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to login landlord page.", r.Method))
 		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
@@ -28,9 +29,30 @@ func SubmitLoginLandlord(w http.ResponseWriter, r *http.Request) {
 	landlordEmail := r.FormValue("landlordEmail")
 	landlordPassword := r.FormValue("landlordPassword")
 
+	// logins that have failed too often have to wait before the password is checked again
+	if s.loginLockedOut(w, r, LANDLORD, landlordEmail) {
+		return
+	}
+
 	// check if landlord exists in database
-	exists, err := db.AuthenticateLandlord(landlordEmail, landlordPassword)
+	exists, err := s.store.AuthenticateLandlord(r.Context(), landlordEmail, landlordPassword)
+	if err == db.ErrLandlordSuspended {
+		// the password was right, so this is not counted as a failed login
+		logs.Logs(logWarn, "Suspended landlord tried to login. Redirecting back to landlord login page")
+		http.Redirect(w, r, "/login/landlord?authenticationError=FORBIDDEN+403:+Your+account+has+been+suspended.+Please+contact+the+owner.", http.StatusSeeOther)
+		return
+	}
 	if err != nil {
+		// a timeout says nothing about the password, so it is not counted as a failed login
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		// failures are counted for unknown accounts too, but only known accounts are emailed about them
+		alertEmail := landlordEmail
+		if err == sql.ErrNoRows {
+			alertEmail = ""
+		}
+		s.recordLoginFailure(r, LANDLORD, landlordEmail, alertEmail)
 		logs.Logs(logErr, fmt.Sprintf("Error authenticating landlord: %s. Redirecting back to landlord login page", err.Error()))
 		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord", http.StatusSeeOther)
 		return
@@ -42,30 +64,7 @@ func SubmitLoginLandlord(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// add tokens & expiry time to database
-	sessionToken, csrfToken, expiryTime, err := db.UpdateLandlordSessionTokens(landlordEmail)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error updating landlord session tokens: %s", err.Error()))
-		http.Error(w, fmt.Sprintf("Error updating landlord session tokens: %s", err.Error()), http.StatusInternalServerError)
-		return
-	}
-
-	// set session cookie
-	createLandlordDashboardSessionCookie := middleware.LandlordDashboardSessionCookie(w, sessionToken, expiryTime)
-	if !createLandlordDashboardSessionCookie {
-		logs.Logs(logErr, "Failed to create session cookie. Redirecting back to landlord login page...")
-		http.Redirect(w, r, "/login/landlord?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+session+cookie", http.StatusInternalServerError)
-		return
-	}
-
-	// set csrf cookie
-	createLandlordDashboardCSRFCookie := middleware.LandlordDashboardCSRFTokenCookie(w, csrfToken, expiryTime)
-	if !createLandlordDashboardCSRFCookie {
-		logs.Logs(logErr, "Failed to create CSRF cookie. Redirecting back to landlord login page...")
-		http.Redirect(w, r, "/login/landlord?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+CSRF+cookie", http.StatusInternalServerError)
-		return
-	}
-
-	// redirect to landlord dashboard if authentication is successful
-	http.Redirect(w, r, "/landlord/dashboard", http.StatusSeeOther)
+	// accounts with two-factor authentication enter a code from their authenticator app before the session starts
+	rememberMe := r.FormValue("rememberMe") == "on"
+	s.continueLogin(w, r, LANDLORD, landlordEmail, rememberMe)
 }
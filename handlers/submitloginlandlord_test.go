@@ -7,7 +7,6 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/Bevs-n-Devs/lilyshiddenparadise/handlers"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
 )
@@ -55,7 +54,7 @@ func TestSubmitLoginLandlord_Simple(t *testing.T) {
 			rr := httptest.NewRecorder()
 
 			// Call the handler
-			handlers.SubmitLoginLandlord(rr, req)
+			testutil.TestEnvironment.Handlers.SubmitLoginLandlord(rr, req)
 
 			// Check the status code
 			if rr.Code != tc.expectedStatusCode {
@@ -1,19 +1,20 @@
 package handlers
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 
-	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
 )
 
-func SubmitLoginTenant(w http.ResponseWriter, r *http.Request) {
+func (s *Server) SubmitLoginTenant(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to login tenant page.", r.Method))
 		http.Redirect(w, r, "/login/tenant?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
 	}
 
 	// parse form data
@@ -28,12 +29,26 @@ func SubmitLoginTenant(w http.ResponseWriter, r *http.Request) {
 	tenantEmail := r.FormValue("tenantEmail")
 	tenantPassword := r.FormValue("tenantPassword")
 
-	// hash username & password
+	// hash username, the password is checked against its bcrypt hash (legacy SHA-256 hashes are upgraded on login)
 	hashUsername := utils.HashData(tenantEmail)
-	hashPassword := utils.HashData(tenantPassword)
 
-	authenticate, err := db.AuthenticateTenant(hashUsername, hashPassword)
+	// logins that have failed too often have to wait before the password is checked again
+	if s.loginLockedOut(w, r, TENANT, hashUsername) {
+		return
+	}
+
+	authenticate, err := s.store.AuthenticateTenant(r.Context(), hashUsername, tenantPassword)
 	if err != nil {
+		// a timeout says nothing about the password, so it is not counted as a failed login
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		// failures are counted for unknown accounts too, but only known accounts are emailed about them
+		alertEmail := tenantEmail
+		if err == sql.ErrNoRows {
+			alertEmail = ""
+		}
+		s.recordLoginFailure(r, TENANT, hashUsername, alertEmail)
 		logs.Logs(logErr, fmt.Sprintf("Error authenticating tenant: %s. Redirecting back to tenant login page", err.Error()))
 		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant", http.StatusSeeOther)
 		return
@@ -45,30 +60,7 @@ func SubmitLoginTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// add tokens & expiry time to database
-	sessionToken, csrfToken, expiryTime, err := db.UpdateTenantSessionTokens(hashUsername)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error updating tenant session tokens: %s", err.Error()))
-		http.Error(w, fmt.Sprintf("Error updating tenant session tokens: %s", err.Error()), http.StatusInternalServerError)
-		return
-	}
-
-	// set session cookie for tenant dashboard
-	createTenantDashboardSessionCookie := middleware.TenantDashboardSessionCookie(w, sessionToken, expiryTime)
-	if !createTenantDashboardSessionCookie {
-		logs.Logs(logErr, "Failed to create session cookie. Redirecting back to tenant login page...")
-		http.Redirect(w, r, "/login/tenant?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+session+cookie", http.StatusInternalServerError)
-		return
-	}
-
-	// set csrf cookie for tenant dashboard
-	createTenantDashboardCsrfCookie := middleware.TenantDashboardCSRFTokenCookie(w, csrfToken, expiryTime)
-	if !createTenantDashboardCsrfCookie {
-		logs.Logs(logErr, "Failed to create CSRF cookie. Redirecting back to tenant login page...")
-		http.Redirect(w, r, "/login/tenant?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+CSRF+cookie", http.StatusInternalServerError)
-		return
-	}
-
-	// redirect to tenant dashboard if authentication is successful
-	http.Redirect(w, r, "/tenant/dashboard", http.StatusSeeOther)
+	// accounts with two-factor authentication enter a code from their authenticator app before the session starts
+	rememberMe := r.FormValue("rememberMe") == "on"
+	s.continueLogin(w, r, TENANT, hashUsername, rememberMe)
 }
@@ -4,18 +4,25 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
 )
 
-func SubmitNewLandlord(w http.ResponseWriter, r *http.Request) {
+func (s *Server) SubmitNewLandlord(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		logs.Logs(logWarn, fmt.Sprintf("Invalid request method: %s. Redirecting back to create new landlord page.", r.Method))
 		http.Redirect(w, r, "/new/landlord", http.StatusSeeOther)
 		return
 	}
 
+	// landlords join by invitation unless public registration has been turned on
+	if !s.landlordRegistrationOpen(r.Context()) {
+		logs.Logs(logWarn, "Landlord registration is invite only. Redirecting back to create new landlord page.")
+		http.Redirect(w, r, "/new/landlord", http.StatusSeeOther)
+		return
+	}
+
 	// parse form data
 	err := r.ParseForm()
 	if err != nil {
@@ -36,9 +43,11 @@ func SubmitNewLandlord(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: add logic to create new landlord in db
-	err = db.CreateNewLandlord(landlordEmail, landlordPassword)
+	err = s.store.CreateNewLandlord(r.Context(), landlordEmail, landlordPassword)
 	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
 		logs.Logs(logErr, fmt.Sprintf("Error creating new landlord: %s", err.Error()))
 		http.Error(w, fmt.Sprintf("Error creating new landlord: %s", err.Error()), http.StatusInternalServerError)
 		return
@@ -3,14 +3,14 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
-	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/email"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
 )
 
-func SubmitTenantForm(w http.ResponseWriter, r *http.Request) {
+func (s *Server) SubmitTenantForm(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to home page.", r.Method)) // this can be redirected back to the form page later on..
 		http.Redirect(w, r, "/", http.StatusSeeOther)
@@ -26,6 +26,7 @@ func SubmitTenantForm(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// extract data from form
+	propertyId := r.FormValue("propertyId")
 	fullName := r.FormValue("fullName")
 	dateOfBirth := r.FormValue("dob")
 	passportNumber := r.FormValue("passportNumber")
@@ -52,6 +53,14 @@ func SubmitTenantForm(w http.ResponseWriter, r *http.Request) {
 	unstableIncome := r.FormValue("unstableIncome")
 	incomeReason := r.FormValue("incomeReason")
 
+	// the application must be for one of the listed properties
+	propertyIdInt, err := strconv.Atoi(propertyId)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid form data: Invalid property ID: %s", err.Error()))
+		http.Redirect(w, r, "/tenancy-form?propertyError=Please+select+the+property+you+are+applying+for", http.StatusSeeOther)
+		return
+	}
+
 	// validate the user age - check if over 18
 	if !utils.ValidateAge(dateOfBirth) {
 		logs.Logs(logErr, "Invalid form data: User is not 18 years or older.")
@@ -115,7 +124,9 @@ func SubmitTenantForm(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// save form data to database
-	err = db.SaveTenantApplicationForm(
+	err = s.store.SaveTenantApplicationForm(
+		r.Context(),
+		propertyIdInt,
 		fullName,
 		dateOfBirth,
 		passportNumber,
@@ -148,7 +159,15 @@ func SubmitTenantForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = email.NotifyLandlordNewApplication()
+	// the application is emailed to the landlord who owns the property
+	landlordEmail, err := s.store.GetPropertyLandlordEmail(r.Context(), propertyIdInt)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to get landlord of property: %s", err.Error()))
+		http.Redirect(w, r, "/tenancy-form?emailError=Failed+to+send+email+notification+to+landlord", http.StatusSeeOther)
+		return
+	}
+
+	err = email.NotifyLandlordNewApplication(landlordEmail)
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Failed to send email notification to landlord: %s", err.Error()))
 		http.Redirect(w, r, "/tenancy-form?emailError=Failed+to+send+email+notification+to+landlord", http.StatusSeeOther)
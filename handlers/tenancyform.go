@@ -7,8 +7,9 @@ import (
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 )
 
-func TenancyForm(w http.ResponseWriter, r *http.Request) {
+func (s *Server) TenancyForm(w http.ResponseWriter, r *http.Request) {
 	// collect any error messages
+	propertyError := r.URL.Query().Get("propertyError")
 	ageError := r.URL.Query().Get("ageError")
 	evictedError := r.URL.Query().Get("evictedError")
 	convictedError := r.URL.Query().Get("convictedError")
@@ -20,6 +21,7 @@ func TenancyForm(w http.ResponseWriter, r *http.Request) {
 	emailError := r.URL.Query().Get("emailError")
 
 	data := ErrorMessages{
+		PropertyError:       propertyError,
 		AgeError:            ageError,
 		EvictedError:        evictedError,
 		ConvictedError:      convictedError,
@@ -31,8 +33,18 @@ func TenancyForm(w http.ResponseWriter, r *http.Request) {
 		EmailError:          emailError,
 	}
 
+	showData := ShowTenancyForm{ErrorMessages: data}
+
+	// applicants choose which property they are applying for
+	properties, err := s.store.GetAllProperties(r.Context())
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to get properties for tenancy form: %s", err.Error()))
+		showData.DatabaseError = "Unable to load the list of properties. Please try again later."
+	}
+	showData.Properties = showProperties(properties, nil)
+
 	// pass error messages to HTML template
-	err := Templates.ExecuteTemplate(w, "tenancyForm.html", data)
+	err = Templates.ExecuteTemplate(w, "tenancyForm.html", showData)
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Unable to load tenancy page: %s", err.Error()))
 		http.Error(w, fmt.Sprintf("Unable to load tenancy page: %s", err.Error()), http.StatusInternalServerError)
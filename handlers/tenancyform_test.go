@@ -6,7 +6,6 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/Bevs-n-Devs/lilyshiddenparadise/handlers"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
 )
@@ -36,6 +35,18 @@ func TestTenancyForm(t *testing.T) {
 			expectedStatusCode:   http.StatusOK,
 			expectedBodyContains: "Tenancy Form", // Changed to match what's actually in the page
 		},
+		{
+			name:                 "Tenancy form asks which property the application is for",
+			url:                  "/tenancy-form",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: `name="propertyId"`,
+		},
+		{
+			name:                 "Tenancy form with property error",
+			url:                  "/tenancy-form?propertyError=Please+select+the+property+you+are+applying+for",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: "Please select the property you are applying for",
+		},
 	}
 
 	// Run test cases
@@ -48,7 +59,7 @@ func TestTenancyForm(t *testing.T) {
 			rr := httptest.NewRecorder()
 			
 			// Call the handler
-			handlers.TenancyForm(rr, req)
+			testutil.TestEnvironment.Handlers.TenancyForm(rr, req)
 			
 			// Check the status code
 			if rr.Code != tc.expectedStatusCode {
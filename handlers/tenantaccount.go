@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 
@@ -10,121 +11,23 @@ import (
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
 )
 
-func TenantAccount(w http.ResponseWriter, r *http.Request) {
+func (s *Server) TenantAccount(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to tenant login page.", r.Method))
 		http.Redirect(w, r, "/login/tenant?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
 		return
 	}
 
-	// deny the request if the authorization fails
-	err := middleware.AuthenticateTenantRequest(r)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error authenticating tenant: %s. Redirecting to tenant login page", err.Error()))
-		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant", http.StatusSeeOther)
-		return
-	}
-
-	// get session cookie
-	sessionToken, err := utils.CheckSessionToken(r)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error getting session token: %s. Redirecting to tenant login page", err.Error()))
-		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant.+Failed+to+get+session+token", http.StatusSeeOther)
-		return
-	}
-
-	// get tenant email from session cookie
-	tenantEmail, err := db.GetHashedEmailFromTenantSessionToken(sessionToken.Value)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error getting tenant email from session token: %s. Redirecting to tenant login page", err.Error()))
-		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant.+Failed+to+get+tenant+email+from+session+token", http.StatusSeeOther)
-		return
-	}
-
-	// update the tenant's session token, CSRF token and expiry time in the database
-	// this will be done for each request
-	newSessionToken, newCsrfToken, newExpiryTime, err := db.UpdateTenantSessionTokens(tenantEmail)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error updating tenant session tokens: %s. Redirecting to tenant login page", err.Error()))
-		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant.+Failed+to+update+session+tokens", http.StatusSeeOther)
-		return
-	}
-
-	// TODO! Set cookies for each available page via tenant dashboard page
-
-	// set session cookies for tenant dashboard
-	createTenantDashboardSessionCookie := middleware.TenantDashboardSessionCookie(w, newSessionToken, newExpiryTime)
-	if !createTenantDashboardSessionCookie {
-		logs.Logs(logErr, "Failed to create session cookie. Redirecting back to tenant login page...")
-		http.Redirect(w, r, "/login/tenant?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+session+cookie", http.StatusInternalServerError)
-		return
-	}
-	createTenantDashboardCsrfCookie := middleware.TenantDashboardCSRFTokenCookie(w, newCsrfToken, newExpiryTime)
-	if !createTenantDashboardCsrfCookie {
-		logs.Logs(logErr, "Failed to create CSRF cookie. Redirecting back to tenant login page...")
-		http.Redirect(w, r, "/login/tenant?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+CSRF+cookie", http.StatusInternalServerError)
-		return
-	}
-
-	// set cookie for tenant account page
-	createTenantAccountSessionCookie := middleware.TenantDashboardAccountSessionCookie(w, newSessionToken, newExpiryTime)
-	if !createTenantAccountSessionCookie {
-		logs.Logs(logErr, "Failed to create session cookie. Redirecting back to tenant login page...")
-		http.Redirect(w, r, "/login/tenant?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+session+cookie", http.StatusInternalServerError)
-		return
-	}
-	createTenantAccountCsrfCookie := middleware.TenantDashboardAccountCSRFTokenCookie(w, newCsrfToken, newExpiryTime)
-	if !createTenantAccountCsrfCookie {
-		logs.Logs(logErr, "Failed to create CSRF cookie. Redirecting back to tenant login page...")
-		http.Redirect(w, r, "/login/tenant?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+CSRF+cookie", http.StatusInternalServerError)
-		return
-	}
-
-	// set session cookies to send message to landlord
-	createSendLandlordMessageSessionCookie := middleware.SendMessageToLandlordSessionCookie(w, newSessionToken, newExpiryTime)
-	if !createSendLandlordMessageSessionCookie {
-		logs.Logs(logErr, "Failed to create session cookie. Redirecting back to tenant login page...")
-		http.Redirect(w, r, "/login/tenant?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+session+cookie", http.StatusInternalServerError)
-		return
-	}
-	createSendLandlordMessageCsrfCookie := middleware.SendMessageToLandlordCSRFTokenCookie(w, newCsrfToken, newExpiryTime)
-	if !createSendLandlordMessageCsrfCookie {
-		logs.Logs(logErr, "Failed to create CSRF cookie. Redirecting back to tenant login page...")
-		http.Redirect(w, r, "/login/tenant?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+CSRF+cookie", http.StatusInternalServerError)
-		return
-	}
-
-	// set session cookies to logout tenant
-	createTenantLogoutSessionCookie := middleware.LogoutTenantSessionCookie(w, newSessionToken)
-	if !createTenantLogoutSessionCookie {
-		logs.Logs(logErr, "Failed to create session cookie. Redirecting back to tenant login page...")
-		http.Redirect(w, r, "/login/tenant?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+session+cookie", http.StatusInternalServerError)
-		return
-	}
-	createTenantLogoutCsrfCookie := middleware.LogoutTenantCSRFTokenCookie(w, newCsrfToken)
-	if !createTenantLogoutCsrfCookie {
-		logs.Logs(logErr, "Failed to create CSRF cookie. Redirecting back to tenant login page...")
-		http.Redirect(w, r, "/login/tenant?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+CSRF+cookie", http.StatusInternalServerError)
-		return
-	}
-
-	// set session cookies to update tenant password
-	createUpdateTenantPasswordSessionCookie := middleware.UpdateTenantPasswordSessionCookie(w, newSessionToken, newExpiryTime)
-	if !createUpdateTenantPasswordSessionCookie {
-		logs.Logs(logErr, "Failed to create session cookie. Redirecting back to tenant login page...")
-		http.Redirect(w, r, "/login/tenant?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+session+cookie", http.StatusInternalServerError)
-		return
-	}
-	createUpdateTenantPasswordCsrfCookie := middleware.UpdateTenantPasswordCSRFTokenCookie(w, newCsrfToken, newExpiryTime)
-	if !createUpdateTenantPasswordCsrfCookie {
-		logs.Logs(logErr, "Failed to create CSRF cookie. Redirecting back to tenant login page...")
-		http.Redirect(w, r, "/login/tenant?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+create+CSRF+cookie", http.StatusInternalServerError)
-		return
-	}
+	// get the tenant (hashed email) authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	tenantEmail := principal.Email
 
 	// TODO: get the tenants application details / tenancy agreement
-	tenantInfo, err := db.GetTenantInformationByHashEmail(tenantEmail)
+	tenantInfo, err := s.store.GetTenantInformationByHashEmail(r.Context(), tenantEmail)
 	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
 		logs.Logs(logErr, fmt.Sprintf("Failed to get tenant information: %s", err.Error()))
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -135,8 +38,10 @@ func TenantAccount(w http.ResponseWriter, r *http.Request) {
 
 	// decrypt encrypted tenant information
 	var showData ShowTenantInformation
+	showData.CSRFToken = principal.CSRFToken
 
 	showData.Error.AuthenticationError = authenticationError
+	showData.Error.ValidationError = r.URL.Query().Get("validationError")
 	showData.Currency = tenantInfo.Currency
 
 	getEmail, err := utils.Decrypt(tenantInfo.Email)
@@ -179,6 +84,50 @@ func TenantAccount(w http.ResponseWriter, r *http.Request) {
 	}
 	showData.MonthlyRent = string(getMonthlyRent)
 
+	// get the tenant's lease and any renewal offer waiting for an answer
+	tenantId, err := s.store.GetTenantIdByEmail(r.Context(), tenantEmail)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get tenant ID: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get tenant ID: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	lease, err := s.store.GetLeaseByTenantId(r.Context(), tenantId)
+	if err != nil && err != sql.ErrNoRows {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get tenant lease: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get tenant lease: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		var openRenewal *db.LeaseRenewal
+		renewal, err := s.store.GetOpenRenewalOffer(r.Context(), lease.ID)
+		if err != nil && err != sql.ErrNoRows {
+			if middleware.StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Failed to get renewal offer: %s", err.Error()))
+			http.Error(w, fmt.Sprintf("Failed to get renewal offer: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		if err == nil {
+			openRenewal = &renewal
+		}
+
+		showLease, err := buildLease(lease, openRenewal)
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Failed to get tenant lease: %s", err.Error()))
+			http.Error(w, fmt.Sprintf("Failed to get tenant lease: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		showData.Lease = &showLease
+	}
+
 	// direct user to protected tenant account page
 	err = Templates.ExecuteTemplate(w, "tenantAccount.html", showData)
 	if err != nil {
@@ -3,6 +3,8 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
@@ -10,20 +12,16 @@ import (
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
 )
 
-func LandlordTenantApplications(w http.ResponseWriter, r *http.Request) {
+func (s *Server) LandlordTenantApplications(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to landlord login page.", r.Method))
 		http.Redirect(w, r, "/login/landlord?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
 		return
 	}
 
-	// deny the request if the authorization fails
-	err := middleware.AuthenticateLandlordRequest(r)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error authenticating landlord: %s. Redirecting to landlord login page", err.Error()))
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord", http.StatusSeeOther)
-		return
-	}
+	// get the landlord authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	landlordEmail := principal.LandlordEmail
 
 	// get any error messages
 	validationError := r.URL.Query().Get("validationError")
@@ -31,118 +29,12 @@ func LandlordTenantApplications(w http.ResponseWriter, r *http.Request) {
 		ValidationError: validationError,
 	}
 
-	// get session cookie
-	sessionToken, err := utils.CheckSessionToken(r)
-	if err != nil {
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+session+token", http.StatusSeeOther)
-		return
-	}
-
-	// get landlord emial from session cookie
-	landlordEmail, err := db.GetEmailFromLandlordSessionToken(sessionToken.Value)
-	if err != nil {
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+landlord+email+from+session+token", http.StatusSeeOther)
-		return
-	}
-
-	// update the landlord's session token, CSRF token and expiry time in the database
-	// this will be doen for each request
-	newSessionToken, newCsrfToken, newExpiryTime, err := db.UpdateLandlordSessionTokens(landlordEmail)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error updating landlord session tokens: %s. Redirecting to landlord login page", err.Error()))
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+update+session+tokens", http.StatusSeeOther)
-		return
-	}
-
-	// TODO! Set new cookies for each available page via tenant applications page
-
-	// set new cookies for landlord dashboard
-	createLandlordDashboardSessionCookie := middleware.LandlordDashboardSessionCookie(w, newSessionToken, newExpiryTime)
-	if !createLandlordDashboardSessionCookie {
-		logs.Logs(logErr, "Failed to get session cookie for landlord dashboard. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+session+cookie", http.StatusSeeOther)
-		return
-	}
-	createLandordDashboardCSRFTokenCookie := middleware.LandlordDashboardCSRFTokenCookie(w, newCsrfToken, newExpiryTime)
-	if !createLandordDashboardCSRFTokenCookie {
-		logs.Logs(logErr, "Failed to get CSRF token cookie for landlord dashboard. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+CSRF+token+cookie", http.StatusSeeOther)
-		return
-	}
-
-	// set new cookies for landlord tenant applications
-	createLandlordTenantApplicationsSessionCookie := middleware.LandlordDashboardTenantApplicationsSessionCookie(w, newSessionToken, newExpiryTime)
-	if !createLandlordTenantApplicationsSessionCookie {
-		logs.Logs(logErr, "Failed to get session cookie for landlord tenant applications. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+session+cookie", http.StatusSeeOther)
-		return
-	}
-	createLandordTenantApplictionsCSRFTokenCookie := middleware.LandlordDashboardTenantApplicationsCSRFTokenCookie(w, newCsrfToken, newExpiryTime)
-	if !createLandordTenantApplictionsCSRFTokenCookie {
-		logs.Logs(logErr, "Failed to get CSRF token cookie for landlord tenant applications. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+CSRF+token+cookie", http.StatusSeeOther)
-		return
-	}
-
-	// set new cookies for landlord manage applications
-	createLandlordManageApplicationsSessionCookie := middleware.LandlordManageApplicationsSessionCookie(w, newSessionToken, newExpiryTime)
-	if !createLandlordManageApplicationsSessionCookie {
-		logs.Logs(logErr, "Failed to get session cookie for landlord manage applications. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+session+cookie", http.StatusSeeOther)
-		return
-	}
-	createLandordManageApplicationsCSRFTokenCookie := middleware.LandlordManageApplicationsCSRFTokenCookie(w, newCsrfToken, newExpiryTime)
-	if !createLandordManageApplicationsCSRFTokenCookie {
-		logs.Logs(logErr, "Failed to get CSRF token cookie for landlord manage applications. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+CSRF+token+cookie", http.StatusSeeOther)
-		return
-	}
-
-	// set new cookies for landlord tenants dashboard page
-	createLandlordDashboardTenantsSessionCookie := middleware.LandlordDashboardTenantsSessionCookie(w, newSessionToken, newExpiryTime)
-	if !createLandlordDashboardTenantsSessionCookie {
-		logs.Logs(logErr, "Failed to get session cookie for landlord tenants dashboard. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+session+cookie", http.StatusSeeOther)
-		return
-	}
-	createLandlordDashboardTenantsCSRFTokenCookie := middleware.LandlordDashboardTenantsCSRFTokenCookie(w, newCsrfToken, newExpiryTime)
-	if !createLandlordDashboardTenantsCSRFTokenCookie {
-		logs.Logs(logErr, "Failed to get CSRF token cookie for landlord tenants dashboard. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+CSRF+token+cookie", http.StatusSeeOther)
-		return
-	}
-
-	// set new cookies to message dashboard page
-	createLanlordMessageDashboardSessionCookies := middleware.LandlordMessagesDashboardSessionCookie(w, newSessionToken, newExpiryTime)
-	if !createLanlordMessageDashboardSessionCookies {
-		logs.Logs(logErr, "Failed to get session cookie for landlord message dashboard. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+session+cookie", http.StatusSeeOther)
-		return
-	}
-	createLanlordMessageDashboardCSRFTokenCookie := middleware.LandlordMessagesDashboardCSRFTokenCookie(w, newCsrfToken, newExpiryTime)
-	if !createLanlordMessageDashboardCSRFTokenCookie {
-		logs.Logs(logErr, "Failed to get CSRF token cookie for landlord message dashboard. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+get+CSRF+token+cookie", http.StatusSeeOther)
-		return
-	}
-
-	// set cookie to logout landlord
-	logoutSessionCookie := middleware.LogoutLandlordSessionCookie(w, newSessionToken)
-	if !logoutSessionCookie {
-		logs.Logs(logErr, "Failed to create session cookie for landlord. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+create+session+cookie", http.StatusSeeOther)
-		return
-	}
-	logoutCSRFTokenCookie := middleware.LogoutLandlordCSRFTokenCookie(w, newCsrfToken)
-	if !logoutCSRFTokenCookie {
-		logs.Logs(logErr, "Failed to create CSRF token cookie for landlord. Redirecting to landlord login page")
-		http.Redirect(w, r, "/login/landlord?authenticationError=UNAUTHORIZED+401:+Error+authenticating+landlord.+Failed+to+create+CSRF+token+cookie", http.StatusSeeOther)
-		return
-	}
-
 	// get tenant applications from database
-	getTenantApplications, err := db.GetAllTenantApplications()
+	getTenantApplications, err := s.store.GetAllTenantApplications(r.Context(), landlordEmail)
 	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
 		logs.Logs(logErr, fmt.Sprintf("Failed to get tenant applications: %s", err.Error()))
 		http.Error(w, fmt.Sprintf("Failed to get tenant applications: %s", err.Error()), http.StatusInternalServerError)
 		return
@@ -158,6 +50,7 @@ func LandlordTenantApplications(w http.ResponseWriter, r *http.Request) {
 		convertedData.ID = getTenantApplications[index].ID
 		convertedData.Status = getTenantApplications[index].Status
 		convertedData.CreatedAt = getTenantApplications[index].CreatedAt
+		convertedData.PropertyName = getTenantApplications[index].PropertyName
 
 		getTenantApplications[index].FullName, err = utils.Decrypt(getTenantApplications[index].FullName)
 		if err != nil {
@@ -351,16 +244,57 @@ func LandlordTenantApplications(w http.ResponseWriter, r *http.Request) {
 		}
 		convertedData.UnstableReason = string(getTenantApplications[index].UnstableReason)
 
+		// staff only see sensitive personal details if they have been allowed to
+		if !principal.Can(db.PermissionRevealPII) {
+			hideApplicationPII(&convertedData)
+		}
+
 		// append data to showTenanryApplications slice
 		showTenantApplications = append(showTenantApplications, convertedData)
 	}
 
+	// accepted applicants can only be given a room that still has space
+	rooms, err := s.store.GetRoomsByLandlordEmail(r.Context(), landlordEmail)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get landlord rooms: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get landlord rooms: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
 	showData := struct {
 		TenantApplications []ShowLandlordApplications
+		VacantRooms        []ShowRoom
 		ErrorMessage       string
+		PIIHidden          bool
+		CanDecide          bool
+		CSRFToken          string
 	}{
 		TenantApplications: showTenantApplications,
+		VacantRooms:        vacantRooms(rooms),
 		ErrorMessage:       data.ValidationError,
+		PIIHidden:          !principal.Can(db.PermissionRevealPII),
+		CanDecide:          principal.Can(db.PermissionDecideApplications),
+		CSRFToken:          principal.CSRFToken,
+	}
+
+	// record who has seen the decrypted applications before they are shown
+	if len(showTenantApplications) > 0 {
+		var applicationIds []string
+		for _, application := range showTenantApplications {
+			applicationIds = append(applicationIds, strconv.Itoa(application.ID))
+		}
+		details := "sensitive details shown"
+		if showData.PIIHidden {
+			details = "sensitive details hidden"
+		}
+		err = middleware.RecordAudit(s.store, r, db.AuditApplicationViewed, "application", strings.Join(applicationIds, ","), details)
+		if err != nil {
+			http.Error(w, "Failed to record access to tenant applications", http.StatusInternalServerError)
+			return
+		}
 	}
 
 	// direct user to protected tenant applications
@@ -370,3 +304,29 @@ func LandlordTenantApplications(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Unable to load landlord tenant applications: %s", err.Error()), http.StatusInternalServerError)
 	}
 }
+
+/*
+hideApplicationPII replaces the sensitive personal details in a tenant application with piiHidden,
+for staff who have not been allowed to see them. Reasons are only replaced if the applicant gave one,
+so the page still shows which questions were answered.
+*/
+func hideApplicationPII(application *ShowLandlordApplications) {
+	for _, field := range []*string{
+		&application.Dob,
+		&application.PassportNumber,
+		&application.PhoneNumber,
+		&application.Email,
+		&application.EmployerNumber,
+		&application.EmergencyContact,
+		&application.EmergencyContactNumber,
+		&application.EmergencyContactAddress,
+		&application.EvictedReason,
+		&application.ConvictedReason,
+		&application.RefusedReason,
+		&application.UnstableReason,
+	} {
+		if *field != "" {
+			*field = piiHidden
+		}
+	}
+}
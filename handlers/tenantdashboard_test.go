@@ -5,18 +5,20 @@ import (
 	"net/http/httptest"
 	"testing"
 
-	"github.com/Bevs-n-Devs/lilyshiddenparadise/handlers"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
 )
 
 func TestTenantDashboard(t *testing.T) {
-	// Use the global test environment
-	testutil.TestMain(nil)
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
 
 	// Define test cases
 	testCases := []struct {
 		name               string
-		setupRequest       func(*http.Request)
+		request            func() *http.Request
 		expectedStatusCode int
 		expectedLocation   string
 		failDB             bool
@@ -24,49 +26,37 @@ func TestTenantDashboard(t *testing.T) {
 	}{
 		{
 			name: "Valid session",
-			setupRequest: func(req *http.Request) {
-				// Set up session cookie
-				cookie := &http.Cookie{
-					Name:  "session_token",
-					Value: "session_token_hash_tenant@example.com",
-				}
-				req.AddCookie(cookie)
+			request: func() *http.Request {
+				return testutil.TenantRequest(http.MethodGet, "/tenant/dashboard", nil, "tenant@example.com")
 			},
 			expectedStatusCode: http.StatusOK,
 		},
 		{
 			name: "Missing session cookie",
-			setupRequest: func(req *http.Request) {
-				// No session cookie
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/tenant/dashboard", nil)
 			},
 			expectedStatusCode: http.StatusSeeOther,
-			expectedLocation:   "/login/tenant?authenticationError=Please login to access this page",
+			expectedLocation:   "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant",
 		},
 		{
 			name: "Invalid session token",
-			setupRequest: func(req *http.Request) {
-				// Set up invalid session cookie
-				cookie := &http.Cookie{
-					Name:  "session_token",
-					Value: "invalid_session_token",
-				}
-				req.AddCookie(cookie)
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/tenant/dashboard", nil)
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: "invalid_session_token"})
+				req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "csrf_token_invalid"})
+				return req
 			},
 			expectedStatusCode: http.StatusSeeOther,
-			expectedLocation:   "/login/tenant?authenticationError=Please login to access this page",
+			expectedLocation:   "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant",
 		},
 		{
 			name: "Database error when validating session",
-			setupRequest: func(req *http.Request) {
-				// Set up session cookie
-				cookie := &http.Cookie{
-					Name:  "session_token",
-					Value: "session_token_hash_tenant@example.com",
-				}
-				req.AddCookie(cookie)
+			request: func() *http.Request {
+				return testutil.TenantRequest(http.MethodGet, "/tenant/dashboard", nil, "tenant@example.com")
 			},
 			expectedStatusCode: http.StatusSeeOther,
-			expectedLocation:   "/login/tenant?authenticationError=Internal server error",
+			expectedLocation:   "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant",
 			failDB:             true,
 			failDBReason:       "database error",
 		},
@@ -75,28 +65,20 @@ func TestTenantDashboard(t *testing.T) {
 	// Run test cases
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
+			req := tc.request()
+
 			// Set up mock database to fail if needed
 			if tc.failDB {
 				testutil.TestEnvironment.DB.SetFailNextOperation(tc.failDBReason)
 			}
-			
-			// Create a request
-			req := httptest.NewRequest(http.MethodGet, "/tenant/dashboard", nil)
-			
-			// Set up the request
-			tc.setupRequest(req)
-			
-			// Create a response recorder
-			rr := httptest.NewRecorder()
-			
-			// Call the handler
-			handlers.TenantDashboard(rr, req)
-			
+
+			rr := testutil.ServeTestRequest(req)
+
 			// Check the status code
 			if rr.Code != tc.expectedStatusCode {
 				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
 			}
-			
+
 			// Check the redirect location if applicable
 			if tc.expectedLocation != "" {
 				location := rr.Header().Get("Location")
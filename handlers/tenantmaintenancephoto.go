@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+)
+
+func (s *Server) TenantMaintenancePhoto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to tenant login page.", r.Method))
+		http.Redirect(w, r, "/login/tenant?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the tenant (hashed email) authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	tenantEmail := principal.Email
+
+	photoId, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// tenants can only see photos from their own tickets
+	photo, err := s.store.GetTenantMaintenanceAttachment(r.Context(), tenantEmail, photoId)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get maintenance photo: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get maintenance photo: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = serveTicketPhoto(w, photo)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to send maintenance photo: %s", err.Error()))
+	}
+}
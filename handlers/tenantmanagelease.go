@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+)
+
+func (s *Server) TenantManageLease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to tenant login page.", r.Method))
+		http.Redirect(w, r, "/login/tenant?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the tenant (hashed email) authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	tenantEmail := principal.Email
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// get the tenant's lease
+	tenantId, err := s.store.GetTenantIdByEmail(r.Context(), tenantEmail)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get tenant ID: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get tenant ID: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	lease, err := s.store.GetLeaseByTenantId(r.Context(), tenantId)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get tenant lease: %s. Redirecting back to tenant account page", err.Error()))
+		http.Redirect(w, r, "/tenant/dashboard/account?validationError=NOT+FOUND+404:+Lease+not+found", http.StatusSeeOther)
+		return
+	}
+
+	leaseAction := r.FormValue("leaseAction")
+	switch leaseAction {
+	case "accept-renewal", "decline-renewal":
+		err = s.store.RespondToRenewalOffer(r.Context(), lease.ID, leaseAction == "accept-renewal")
+		if err != nil {
+			if middleware.StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Failed to respond to renewal offer: %s. Redirecting back to tenant account page", err.Error()))
+			http.Redirect(w, r, "/tenant/dashboard/account?validationError=BAD+REQUEST+400:+Failed+to+respond+to+the+renewal+offer", http.StatusSeeOther)
+			return
+		}
+		logs.Logs(logInfo, fmt.Sprintf("Tenant responded to renewal offer: %s. Redirecting back to tenant account page.", leaseAction))
+
+	case "give-notice":
+		err = s.store.ServeLeaseNotice(r.Context(), lease.ID, "tenant", time.Now().Format("2006-01-02"), r.FormValue("moveOutDate"))
+		if err != nil {
+			if middleware.StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Failed to give notice: %s. Redirecting back to tenant account page", err.Error()))
+			http.Redirect(w, r, "/tenant/dashboard/account?validationError=BAD+REQUEST+400:+"+url.QueryEscape(err.Error()), http.StatusSeeOther)
+			return
+		}
+		logs.Logs(logInfo, "Tenant gave notice. Redirecting back to tenant account page.")
+
+	default:
+		logs.Logs(logErr, fmt.Sprintf("Invalid lease action: %s. Redirecting back to tenant account page", leaseAction))
+		http.Redirect(w, r, "/tenant/dashboard/account?validationError=BAD+REQUEST+400:+Invalid+lease+action", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/tenant/dashboard/account", http.StatusSeeOther)
+}
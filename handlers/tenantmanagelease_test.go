@@ -0,0 +1,145 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+func TestTenantManageLease(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// notice is given today, so the leases and move out dates are relative to it
+	today, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+	date := func(t time.Time) string { return t.Format("2006-01-02") }
+
+	// a lease that started 4 months ago with a break clause after 6 months, so it can be ended in 2 months
+	breakDate := utils.AddMonths(today.AddDate(0, -4, 0), 6)
+
+	// Define test cases
+	testCases := []struct {
+		name              string
+		tenantEmail       string
+		start             time.Time
+		breakClauseMonths int
+		form              url.Values
+		expectedLocation  string
+		expectedStatus    string
+	}{
+		{
+			name:             "Notice to move out at the end of the lease",
+			tenantEmail:      "notice-end@example.com",
+			start:            today.AddDate(0, -10, 0),
+			form:             url.Values{"leaseAction": {"give-notice"}, "moveOutDate": {date(utils.AddMonths(today.AddDate(0, -10, 0), 12))}},
+			expectedLocation: "/tenant/dashboard/account",
+			expectedStatus:   db.LeaseNotice,
+		},
+		{
+			name:             "Notice shorter than the notice period",
+			tenantEmail:      "notice-short@example.com",
+			start:            today.AddDate(0, -12, 10),
+			form:             url.Values{"leaseAction": {"give-notice"}, "moveOutDate": {date(utils.AddMonths(today.AddDate(0, -12, 10), 12))}},
+			expectedLocation: "/tenant/dashboard/account?validationError=BAD+REQUEST+400:+" + url.QueryEscape("move out date must be at least 30 days after the notice date"),
+			expectedStatus:   db.LeaseActive,
+		},
+		{
+			name:             "Move out before the end of a lease without a break clause",
+			tenantEmail:      "notice-no-break@example.com",
+			start:            today.AddDate(0, -1, 0),
+			form:             url.Values{"leaseAction": {"give-notice"}, "moveOutDate": {date(today.AddDate(0, 0, 60))}},
+			expectedLocation: "/tenant/dashboard/account?validationError=BAD+REQUEST+400:+" + url.QueryEscape("this lease has no break clause, so it cannot end before the end date"),
+			expectedStatus:   db.LeaseActive,
+		},
+		{
+			name:              "Move out before the break date",
+			tenantEmail:       "notice-before-break@example.com",
+			start:             today.AddDate(0, -4, 0),
+			breakClauseMonths: 6,
+			form:              url.Values{"leaseAction": {"give-notice"}, "moveOutDate": {date(breakDate.AddDate(0, 0, -1))}},
+			expectedLocation:  "/tenant/dashboard/account?validationError=BAD+REQUEST+400:+" + url.QueryEscape("the break clause cannot be used before "+date(breakDate)),
+			expectedStatus:    db.LeaseActive,
+		},
+		{
+			name:              "Notice to move out on the break date",
+			tenantEmail:       "notice-at-break@example.com",
+			start:             today.AddDate(0, -4, 0),
+			breakClauseMonths: 6,
+			form:              url.Values{"leaseAction": {"give-notice"}, "moveOutDate": {date(breakDate)}},
+			expectedLocation:  "/tenant/dashboard/account",
+			expectedStatus:    db.LeaseNotice,
+		},
+		{
+			name:              "Notice served inside the break window",
+			tenantEmail:       "notice-in-break@example.com",
+			start:             today.AddDate(0, -8, 0),
+			breakClauseMonths: 6,
+			form:              url.Values{"leaseAction": {"give-notice"}, "moveOutDate": {date(today.AddDate(0, 0, 30))}},
+			expectedLocation:  "/tenant/dashboard/account",
+			expectedStatus:    db.LeaseNotice,
+		},
+		{
+			name:             "Invalid lease action",
+			tenantEmail:      "notice-invalid@example.com",
+			start:            today.AddDate(0, -10, 0),
+			form:             url.Values{"leaseAction": {"end-lease"}},
+			expectedLocation: "/tenant/dashboard/account?validationError=BAD+REQUEST+400:+Invalid+lease+action",
+			expectedStatus:   db.LeaseActive,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// each case has a 12 month lease with 30 days notice of its own, as giving notice changes it
+			addTenantInOwnRoom(t, tc.name, tc.tenantEmail)
+			tenantId, _ := testutil.TestEnvironment.DB.GetTenantIdByEmail(context.Background(), utils.HashData(tc.tenantEmail))
+			err := testutil.TestEnvironment.DB.CreateLease(context.Background(), tenantId, date(tc.start), 12, tc.breakClauseMonths, 30)
+			if err != nil {
+				t.Fatalf("Failed to create lease: %s", err.Error())
+			}
+
+			rr := testutil.ServeTestRequest(testutil.TenantRequest(http.MethodPost, "/tenant/dashboard/account/lease", tc.form, tc.tenantEmail))
+
+			if status := rr.Code; status != http.StatusSeeOther {
+				t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusSeeOther)
+			}
+			if location := rr.Header().Get("Location"); location != tc.expectedLocation {
+				t.Errorf("Handler returned wrong redirect location: got '%s' want '%s'", location, tc.expectedLocation)
+			}
+
+			lease := tenantLease(t, tc.tenantEmail)
+			if lease.Status != tc.expectedStatus {
+				t.Fatalf("Expected lease status '%s', got '%s'", tc.expectedStatus, lease.Status)
+			}
+			if tc.expectedStatus != db.LeaseNotice {
+				return
+			}
+			if lease.NoticeGivenBy.String != "tenant" || date(lease.NoticeDate.Time) != date(today) {
+				t.Errorf("Expected notice given by the tenant today, got '%s' on %s", lease.NoticeGivenBy.String, date(lease.NoticeDate.Time))
+			}
+			if moveOut := date(lease.MoveOutDate.Time); moveOut != tc.form.Get("moveOutDate") {
+				t.Errorf("Expected the move out date %s, got %s", tc.form.Get("moveOutDate"), moveOut)
+			}
+		})
+	}
+
+	t.Run("Tenant without a lease", func(t *testing.T) {
+		addTenantInOwnRoom(t, "No Lease Tenant", "no-lease@example.com")
+		form := url.Values{"leaseAction": {"give-notice"}, "moveOutDate": {date(today.AddDate(0, 2, 0))}}
+		rr := testutil.ServeTestRequest(testutil.TenantRequest(http.MethodPost, "/tenant/dashboard/account/lease", form, "no-lease@example.com"))
+
+		expectedLocation := "/tenant/dashboard/account?validationError=NOT+FOUND+404:+Lease+not+found"
+		if location := rr.Header().Get("Location"); location != expectedLocation {
+			t.Errorf("Handler returned wrong redirect location: got '%s' want '%s'", location, expectedLocation)
+		}
+	})
+}
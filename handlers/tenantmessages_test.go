@@ -0,0 +1,174 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestTenantMessages(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// a message from the landlord for the tenant to read
+	testutil.TestEnvironment.DB.SendMessage(context.Background(), 1, "landlord", 1, "tenant", "Your rent is due on the 1st")
+
+	// Define test cases
+	testCases := []struct {
+		name               string
+		request            func() *http.Request
+		expectedStatusCode int
+		expectedLocation   string
+		expectedBody       string
+		failDB             bool
+		failDBReason       string
+	}{
+		{
+			name: "Valid session",
+			request: func() *http.Request {
+				return testutil.TenantRequest(http.MethodGet, "/tenant/dashboard/messages", nil, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "Your rent is due on the 1st",
+		},
+		{
+			name: "Missing session cookie",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/tenant/dashboard/messages", nil)
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant",
+		},
+		{
+			name: "Database error when validating session",
+			request: func() *http.Request {
+				return testutil.TenantRequest(http.MethodGet, "/tenant/dashboard/messages", nil, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant",
+			failDB:             true,
+			failDBReason:       "database error",
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.request()
+
+			// Set up mock database to fail if needed
+			if tc.failDB {
+				testutil.TestEnvironment.DB.SetFailNextOperation(tc.failDBReason)
+			}
+
+			rr := testutil.ServeTestRequest(req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location if applicable
+			if tc.expectedLocation != "" {
+				location := rr.Header().Get("Location")
+				if location != tc.expectedLocation {
+					t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+				}
+			}
+
+			// Check the decrypted messages are shown if applicable
+			if tc.expectedBody != "" && !strings.Contains(rr.Body.String(), tc.expectedBody) {
+				t.Errorf("Expected response body to contain '%s'", tc.expectedBody)
+			}
+		})
+	}
+}
+
+func TestSendMessageToLandlord(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// Define test cases, none of which send an email
+	testCases := []struct {
+		name               string
+		request            func() *http.Request
+		expectedStatusCode int
+		expectedLocation   string
+		failDB             bool
+		failDBReason       string
+	}{
+		{
+			name: "Missing session cookie",
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/tenant/send-message", strings.NewReader("tenantMessage=Hello"))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant",
+		},
+		{
+			name: "Invalid CSRF token",
+			request: func() *http.Request {
+				form := url.Values{"tenantMessage": {"Hello"}, "csrf_token": {"invalid_csrf_token"}}
+				return testutil.TenantRequest(http.MethodPost, "/tenant/send-message", form, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "Invalid request method",
+			request: func() *http.Request {
+				return testutil.TenantRequest(http.MethodGet, "/tenant/send-message", nil, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedLocation:   "/login/tenant?badRequest=BAD+REQUEST+400:+Invalid+request+method",
+		},
+		{
+			name: "Database error when checking the form token",
+			request: func() *http.Request {
+				form := url.Values{"tenantMessage": {"Hello"}}
+				return testutil.TenantRequest(http.MethodPost, "/tenant/send-message", form, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			failDB:             true,
+			failDBReason:       "database error",
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.request()
+
+			// Set up mock database to fail if needed
+			if tc.failDB {
+				testutil.TestEnvironment.DB.SetFailNextOperation(tc.failDBReason)
+			}
+
+			rr := testutil.ServeTestRequest(req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location if applicable
+			if tc.expectedLocation != "" {
+				location := rr.Header().Get("Location")
+				if location != tc.expectedLocation {
+					t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/email"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+func (s *Server) TenantNewMaintenanceTicket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to tenant login page.", r.Method))
+		http.Redirect(w, r, "/login/tenant?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get the tenant (hashed email) authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	tenantEmail := principal.Email
+
+	// parse form data, including any photos
+	r.Body = http.MaxBytesReader(w, r.Body, maxTicketUploadSize)
+	err := r.ParseMultipartForm(maxTicketUploadSize)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s. Redirecting back to tenant maintenance page", err.Error()))
+		http.Redirect(w, r, "/tenant/dashboard/maintenance?validationError=BAD+REQUEST+400:+Photos+must+be+no+larger+than+5MB+each", http.StatusSeeOther)
+		return
+	}
+
+	// extract data from form
+	category := r.FormValue("category")
+	priority := r.FormValue("priority")
+	description := r.FormValue("description")
+
+	err = utils.ValidateMaintenanceTicket(category, priority, description)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid maintenance ticket: %s. Redirecting back to tenant maintenance page", err.Error()))
+		http.Redirect(w, r, "/tenant/dashboard/maintenance?validationError=BAD+REQUEST+400:+Please+choose+a+category+and+priority+and+describe+the+problem", http.StatusSeeOther)
+		return
+	}
+
+	photos, err := readTicketPhotos(r)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid maintenance ticket photos: %s. Redirecting back to tenant maintenance page", err.Error()))
+		http.Redirect(w, r, "/tenant/dashboard/maintenance?validationError="+url.QueryEscape("BAD REQUEST 400: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	ticketId, err := s.store.CreateMaintenanceTicket(r.Context(), tenantEmail, category, priority, description, photos)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to create maintenance ticket: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to create maintenance ticket: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// the ticket is saved, so a failed notification is logged rather than shown to the tenant
+	err = s.notifyLandlordNewMaintenanceTicket(r.Context(), tenantEmail, ticketId, category, priority)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to notify landlord of maintenance ticket %d: %s", ticketId, err.Error()))
+	}
+
+	http.Redirect(w, r, "/tenant/dashboard/maintenance", http.StatusSeeOther)
+}
+
+/*
+notifyLandlordNewMaintenanceTicket emails the landlord of the tenant's property about a new maintenance ticket.
+*/
+func (s *Server) notifyLandlordNewMaintenanceTicket(ctx context.Context, tenantEmail string, ticketId int, category, priority string) error {
+	_, landlordEmail, err := s.store.GetLandlordByTenantHashEmail(ctx, tenantEmail)
+	if err != nil {
+		return err
+	}
+
+	encryptedTenantName, err := s.store.GetTenantNameByHashEmail(ctx, tenantEmail)
+	if err != nil {
+		return err
+	}
+	tenantFullName, err := utils.Decrypt([]byte(encryptedTenantName))
+	if err != nil {
+		return err
+	}
+
+	return email.NotifyLandlordNewMaintenanceTicket(landlordEmail, string(tenantFullName), ticketId, category, priority)
+}
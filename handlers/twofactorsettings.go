@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+// the name authenticator apps show next to the account
+const twoFactorIssuer = "Lily's Hidden Paradise"
+
+// twoFactorSettingsPath returns the two-factor settings page of a landlord or tenant.
+func twoFactorSettingsPath(role string) string {
+	if role == TENANT {
+		return "/tenant/dashboard/account/security"
+	}
+	return "/landlord/dashboard/security"
+}
+
+// twoFactorSettingsTemplate returns the template of the two-factor settings page, which has the landlord or tenant navigation.
+func twoFactorSettingsTemplate(role string) string {
+	if role == TENANT {
+		return "tenantSecurity.html"
+	}
+	return "landlordSecurity.html"
+}
+
+// twoFactorAccountName returns the email shown next to the account in the user's authenticator app.
+// Tenants are logged in by the hash of their email, so their email is decrypted from their tenant record.
+func (s *Server) twoFactorAccountName(ctx context.Context, role, userKey string) (string, error) {
+	if role == LANDLORD {
+		return userKey, nil
+	}
+	tenantInfo, err := s.store.GetTenantInformationByHashEmail(ctx, userKey)
+	if err != nil {
+		return "", err
+	}
+	tenantEmail, err := utils.Decrypt(tenantInfo.Email)
+	if err != nil {
+		return "", err
+	}
+	return string(tenantEmail), nil
+}
+
+// renderTwoFactorSettings shows the two-factor settings page with the user's current settings.
+func (s *Server) renderTwoFactorSettings(ctx context.Context, w http.ResponseWriter, principal middleware.Principal, showData ShowTwoFactorSettings) {
+	role, userKey := principal.Role, principal.Email
+	showData.Role = role
+	showData.Path = twoFactorSettingsPath(role)
+	showData.CSRFToken = principal.CSRFToken
+
+	status, err := s.store.GetTwoFactorStatus(ctx, role, userKey)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to get two-factor status: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Failed to get two-factor status: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	showData.Enabled = status.Enabled
+	showData.RecoveryCodesLeft = status.RecoveryCodesLeft
+	if status.Enabled {
+		showData.EnabledAt = status.EnabledAt.Format("2006-01-02")
+	}
+
+	// while setup is waiting to be confirmed, show the secret so it can be added to an authenticator app
+	if status.PendingSecret != "" {
+		accountName, err := s.twoFactorAccountName(ctx, role, userKey)
+		if err != nil {
+			if middleware.StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Failed to get account name for two-factor setup: %s", err.Error()))
+			http.Error(w, fmt.Sprintf("Failed to get account name for two-factor setup: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		showData.Secret = status.PendingSecret
+		showData.OTPAuthURI = template.URL(utils.TOTPURI(twoFactorIssuer, accountName, status.PendingSecret))
+	}
+
+	// the page can show the secret or recovery codes, which must not be cached
+	w.Header().Set("Cache-Control", "no-store")
+
+	err = Templates.ExecuteTemplate(w, twoFactorSettingsTemplate(role), showData)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Unable to load two-factor settings page: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Unable to load two-factor settings page: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) TwoFactorSettings(w http.ResponseWriter, r *http.Request) {
+	// get the user authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+
+	if r.Method != http.MethodGet {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to %s login page.", r.Method, principal.Role))
+		http.Redirect(w, r, "/login/"+principal.Role+"?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// get any error messages
+	var showData ShowTwoFactorSettings
+	showData.Error.ValidationError = r.URL.Query().Get("validationError")
+	showData.Error.InternalServerError = r.URL.Query().Get("internalServerError")
+
+	s.renderTwoFactorSettings(r.Context(), w, principal, showData)
+}
+
+func (s *Server) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// get the user authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	settingsPath := twoFactorSettingsPath(principal.Role)
+
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to %s login page.", r.Method, principal.Role))
+		http.Redirect(w, r, "/login/"+principal.Role+"?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	_, err := s.store.BeginTwoFactorSetup(r.Context(), principal.Role, principal.Email)
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to start two-factor setup: %s", err.Error()))
+		http.Redirect(w, r, settingsPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+start+two-factor+setup", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, settingsPath, http.StatusSeeOther)
+}
+
+func (s *Server) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// get the user authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	settingsPath := twoFactorSettingsPath(principal.Role)
+
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to %s login page.", r.Method, principal.Role))
+		http.Redirect(w, r, "/login/"+principal.Role+"?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	recoveryCodes, err := s.store.ConfirmTwoFactorSetup(r.Context(), principal.Role, principal.Email, r.FormValue("code"))
+	if err == db.ErrInvalidTwoFactorCode {
+		http.Redirect(w, r, settingsPath+"?validationError=BAD+REQUEST+400:+The+code+is+incorrect.+Check+the+time+on+your+phone+and+try+again.", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to confirm two-factor setup: %s", err.Error()))
+		http.Redirect(w, r, settingsPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+turn+on+two-factor+authentication", http.StatusSeeOther)
+		return
+	}
+
+	middleware.RecordAudit(s.store, r, db.AuditTwoFactorEnabled, principal.Role, "", "")
+
+	// the recovery codes are only ever shown here, so the page is rendered rather than redirected to
+	s.renderTwoFactorSettings(r.Context(), w, principal, ShowTwoFactorSettings{RecoveryCodes: recoveryCodes})
+}
+
+func (s *Server) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// get the user authenticated by the session middleware
+	principal, _ := middleware.GetPrincipal(r)
+	settingsPath := twoFactorSettingsPath(principal.Role)
+
+	if r.Method != http.MethodPost {
+		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to %s login page.", r.Method, principal.Role))
+		http.Redirect(w, r, "/login/"+principal.Role+"?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
+		return
+	}
+
+	// parse form data
+	err := r.ParseForm()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
+		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	// turning two-factor authentication off needs the password as well as a code
+	authenticate := s.store.AuthenticateLandlord
+	if principal.Role == TENANT {
+		authenticate = s.store.AuthenticateTenant
+	}
+	ok, err := authenticate(r.Context(), principal.Email, r.FormValue("password"))
+	if err != nil || !ok {
+		logs.Logs(logWarn, "Wrong password entered to turn off two-factor authentication")
+		http.Redirect(w, r, settingsPath+"?validationError=UNAUTHORIZED+401:+Your+password+is+incorrect", http.StatusSeeOther)
+		return
+	}
+
+	err = s.store.DisableTwoFactor(r.Context(), principal.Role, principal.Email, r.FormValue("code"))
+	if err == db.ErrInvalidTwoFactorCode {
+		http.Redirect(w, r, settingsPath+"?validationError=UNAUTHORIZED+401:+The+code+is+incorrect", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
+		logs.Logs(logErr, fmt.Sprintf("Failed to turn off two-factor authentication: %s", err.Error()))
+		http.Redirect(w, r, settingsPath+"?internalServerError=INTERNAL+SERVER+ERROR+500:+Failed+to+turn+off+two-factor+authentication", http.StatusSeeOther)
+		return
+	}
+	middleware.RecordAudit(s.store, r, db.AuditTwoFactorDisabled, principal.Role, "", "")
+
+	http.Redirect(w, r, settingsPath, http.StatusSeeOther)
+}
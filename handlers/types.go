@@ -12,6 +12,8 @@ const (
 	logErr    = 3
 	LANDLORD  = "landlord"
 	TENANT    = "tenant"
+	siteURL   = "https://lilyshiddenparadise.com" // used to build links sent by email
+	piiHidden = "Hidden"                          // shown to staff in place of personal details they are not allowed to see
 )
 
 var (
@@ -29,6 +31,7 @@ type ErrorMessages struct {
 	InternalServerError string
 	CookieError         string
 	// Tenancy form error messages
+	PropertyError        string
 	AgeError             string
 	EvictedError         string
 	ConvictedError       string
@@ -41,6 +44,10 @@ type ErrorMessages struct {
 	EmailError           string
 	// Landlord tenant applications error messages
 	ValidationError string
+	// Password reset confirmation shown on the login pages
+	PasswordReset string
+	// New account confirmation shown on the landlord login page
+	AccountCreated string
 }
 
 // TODO: Create a struct for the tenancy form data
@@ -73,16 +80,19 @@ type ShowLandlordApplications struct {
 	RefusedReason           string `json:"refused_reason"`
 	UnstableIncome          string `json:"unstable_income"`
 	UnstableReason          string `json:"unstable_reason"`
+	PropertyName            string `json:"property_name"`
 	CreatedAt               string `json:"created_at"`
 }
 
 type ShowTenantInformation struct {
-	Email       string `json:"email"`
-	RoomType    string `json:"room_type"`
-	MoveInDate  string `json:"move_in_date"`
-	RentDueDate string `json:"rent_due"`
-	MonthlyRent string `json:"monthly_rent"`
-	Currency    string `json:"currency"`
+	Email       string     `json:"email"`
+	RoomType    string     `json:"room_type"`
+	MoveInDate  string     `json:"move_in_date"`
+	RentDueDate string     `json:"rent_due"`
+	MonthlyRent string     `json:"monthly_rent"`
+	Currency    string     `json:"currency"`
+	Lease       *ShowLease `json:"lease"`
+	CSRFToken   string     `json:"-"` // rendered into the page's forms, see middleware.ProtectForms
 	Error       ErrorMessages
 }
 
@@ -101,3 +111,301 @@ type ShowMessages struct {
 	Message      string    `json:"message"`
 	SentAt       time.Time `json:"sent_at"`
 }
+
+type ShowMessagePage struct {
+	TenantID  string         `json:"tenant_id"`
+	Messages  []ShowMessages `json:"messages"`
+	CSRFToken string         `json:"-"`
+}
+
+type ShowRentLedgerEntry struct {
+	Date      string `json:"date"`
+	EntryType string `json:"entry_type"`
+	Period    string `json:"period"`
+	Charge    string `json:"charge"`
+	Payment   string `json:"payment"`
+	Method    string `json:"method"`
+	Reference string `json:"reference"`
+	Balance   string `json:"balance"`
+}
+
+type ShowRentLedger struct {
+	TenantID     int                   `json:"tenant_id"`
+	TenantName   string                `json:"tenant_name"`
+	Currency     string                `json:"currency"`
+	TotalCharged string                `json:"total_charged"`
+	TotalPaid    string                `json:"total_paid"`
+	Balance      string                `json:"balance"`
+	Arrears      string                `json:"arrears"`
+	InArrears    bool                  `json:"in_arrears"`
+	LastPayment  string                `json:"last_payment"`
+	Entries      []ShowRentLedgerEntry `json:"entries"`
+}
+
+type ShowLandlordRentLedger struct {
+	Tenants        []ShowRentLedger `json:"tenants"`
+	SelectedTenant *ShowRentLedger  `json:"selected_tenant"`
+	CSRFToken      string           `json:"-"`
+	Error          ErrorMessages
+}
+
+type ShowTenantDashboard struct {
+	RentLedger ShowRentLedger `json:"rent_ledger"`
+	Error      ErrorMessages
+}
+
+type ShowLeaseRenewal struct {
+	TermMonths  int    `json:"term_months"`
+	MonthlyRent string `json:"monthly_rent"`
+	NewEndDate  string `json:"new_end_date"`
+	OfferedAt   string `json:"offered_at"`
+}
+
+type ShowLease struct {
+	TenantID          int               `json:"tenant_id"`
+	TenantName        string            `json:"tenant_name"`
+	HasLease          bool              `json:"has_lease"`
+	Status            string            `json:"status"`
+	StartDate         string            `json:"start_date"`
+	EndDate           string            `json:"end_date"`
+	TermMonths        int               `json:"term_months"`
+	BreakClauseMonths int               `json:"break_clause_months"`
+	BreakDate         string            `json:"break_date"`
+	NoticeDays        int               `json:"notice_days"`
+	NoticeGivenBy     string            `json:"notice_given_by"`
+	NoticeDate        string            `json:"notice_date"`
+	MoveOutDate       string            `json:"move_out_date"`
+	Renewal           *ShowLeaseRenewal `json:"renewal"`
+}
+
+type ShowLandlordLeases struct {
+	Leases    []ShowLease `json:"leases"`
+	CSRFToken string      `json:"-"`
+	Error     ErrorMessages
+}
+
+type ShowRoom struct {
+	ID           int    `json:"id"`
+	PropertyName string `json:"property_name"`
+	Unit         string `json:"unit"`
+	Name         string `json:"name"`
+	RoomType     string `json:"room_type"`
+	Capacity     int    `json:"capacity"`
+	Amenities    string `json:"amenities"`
+	DefaultRent  string `json:"default_rent"`
+	Currency     string `json:"currency"`
+	Occupants    int    `json:"occupants"`
+	Vacancies    int    `json:"vacancies"`
+}
+
+type ShowProperty struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Address   string     `json:"address"`
+	Rooms     []ShowRoom `json:"rooms"`
+	Capacity  int        `json:"capacity"`
+	Occupants int        `json:"occupants"`
+	Vacancies int        `json:"vacancies"`
+}
+
+type ShowLandlordProperties struct {
+	Properties []ShowProperty `json:"properties"`
+	CSRFToken  string         `json:"-"`
+	Error      ErrorMessages
+}
+
+type ShowNewTenant struct {
+	VacantRooms []ShowRoom `json:"vacant_rooms"`
+	CSRFToken   string     `json:"-"`
+	Error       ErrorMessages
+}
+
+type ShowLandlordDashboard struct {
+	Properties []ShowProperty `json:"properties"`
+	Capacity   int            `json:"capacity"`
+	Occupants  int            `json:"occupants"`
+	Vacancies  int            `json:"vacancies"`
+	IsOwner    bool           `json:"is_owner"`
+	// staff see what they have been allowed to do, account holders can manage their staff
+	IsAccountHolder  bool     `json:"is_account_holder"`
+	StaffPermissions []string `json:"staff_permissions"`
+}
+
+// the tenancy form shows its error messages directly, so they are embedded rather than nested under Error
+type ShowTenancyForm struct {
+	ErrorMessages
+	Properties []ShowProperty `json:"properties"`
+}
+
+type ShowMaintenanceAttachment struct {
+	ID       int    `json:"id"`
+	FileName string `json:"file_name"`
+}
+
+type ShowMaintenanceEvent struct {
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	ChangedBy  string `json:"changed_by"`
+	Note       string `json:"note"`
+	ChangedAt  string `json:"changed_at"`
+}
+
+type ShowMaintenanceTicket struct {
+	ID           int                         `json:"id"`
+	TenantName   string                      `json:"tenant_name"`
+	PropertyName string                      `json:"property_name"`
+	RoomName     string                      `json:"room_name"`
+	Category     string                      `json:"category"`
+	Priority     string                      `json:"priority"`
+	Description  string                      `json:"description"`
+	Status       string                      `json:"status"`
+	ScheduledFor string                      `json:"scheduled_for"`
+	CreatedAt    string                      `json:"created_at"`
+	UpdatedAt    string                      `json:"updated_at"`
+	Resolved     bool                        `json:"resolved"`
+	Attachments  []ShowMaintenanceAttachment `json:"attachments"`
+	Events       []ShowMaintenanceEvent      `json:"events"`
+}
+
+type ShowMaintenanceTickets struct {
+	Tickets   []ShowMaintenanceTicket `json:"tickets"`
+	CSRFToken string                  `json:"-"`
+	Error     ErrorMessages
+}
+
+type ShowPasswordReset struct {
+	Role  string `json:"role"`
+	Token string `json:"token"`
+	Sent  bool   `json:"sent"`
+	Error ErrorMessages
+}
+
+type ShowTwoFactorLogin struct {
+	Role  string `json:"role"`
+	Error ErrorMessages
+}
+
+type ShowTwoFactorSettings struct {
+	Role              string       `json:"role"`
+	Path              string       `json:"path"`
+	Enabled           bool         `json:"enabled"`
+	EnabledAt         string       `json:"enabled_at"`
+	RecoveryCodesLeft int          `json:"recovery_codes_left"`
+	Secret            string       `json:"secret"`
+	OTPAuthURI        template.URL `json:"otpauth_uri"` // otpauth:// is not a scheme html/template trusts, so it is marked safe once built
+	RecoveryCodes     []string     `json:"recovery_codes"`
+	CSRFToken         string       `json:"-"`
+	Error             ErrorMessages
+}
+
+type ShowLockedAccount struct {
+	TenantID    int    `json:"tenant_id"`
+	TenantName  string `json:"tenant_name"`
+	Failures    int    `json:"failures"`
+	LockedUntil string `json:"locked_until"`
+}
+
+type ShowLockedLandlordAccount struct {
+	ID          int    `json:"id"`
+	Email       string `json:"email"`
+	AccountRole string `json:"account_role"`
+	Failures    int    `json:"failures"`
+	LockedUntil string `json:"locked_until"`
+}
+
+type ShowLandlordTenantsPage struct {
+	LockedAccounts []ShowLockedAccount `json:"locked_accounts"`
+	CSRFToken      string              `json:"-"`
+	Error          ErrorMessages
+}
+
+// the new landlord page shows its error messages directly, so they are embedded rather than nested under Error
+type ShowNewLandlord struct {
+	ErrorMessages
+	RegistrationOpen bool `json:"registration_open"`
+}
+
+type ShowLandlordInvitation struct {
+	Token     string `json:"token"`
+	Email     string `json:"email"`
+	InvitedBy string `json:"invited_by"`
+	ExpiresAt string `json:"expires_at"`
+	Error     ErrorMessages
+}
+
+type ShowLandlordAccount struct {
+	ID          int    `json:"id"`
+	Email       string `json:"email"`
+	AccountRole string `json:"account_role"`
+	WorksFor    string `json:"works_for"`
+	CreatedAt   string `json:"created_at"`
+	SuspendedAt string `json:"suspended_at"`
+	IsOwner     bool   `json:"is_owner"`
+}
+
+type ShowPendingInvitation struct {
+	ID          int    `json:"id"`
+	Email       string `json:"email"`
+	AccountRole string `json:"account_role"`
+	InvitedBy   string `json:"invited_by"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+type ShowLandlordAdmin struct {
+	Accounts       []ShowLandlordAccount       `json:"accounts"`
+	LockedAccounts []ShowLockedLandlordAccount `json:"locked_accounts"`
+	Invitations    []ShowPendingInvitation     `json:"invitations"`
+	InviteSent     string                      `json:"invite_sent"`
+	CSRFToken      string                      `json:"-"`
+	Error          ErrorMessages
+}
+
+type ShowStaffPermission struct {
+	Name    string `json:"name"`
+	Label   string `json:"label"`
+	Granted bool   `json:"granted"`
+}
+
+type ShowStaffAccount struct {
+	ID          int                   `json:"id"`
+	Email       string                `json:"email"`
+	CreatedAt   string                `json:"created_at"`
+	SuspendedAt string                `json:"suspended_at"`
+	Permissions []ShowStaffPermission `json:"permissions"`
+}
+
+type ShowLandlordStaff struct {
+	Staff          []ShowStaffAccount          `json:"staff"`
+	LockedAccounts []ShowLockedLandlordAccount `json:"locked_accounts"`
+	Invitations    []ShowPendingInvitation     `json:"invitations"`
+	InviteSent     string                      `json:"invite_sent"`
+	CSRFToken      string                      `json:"-"`
+	Error          ErrorMessages
+}
+
+type ShowAuditEntry struct {
+	ID         int64  `json:"id"`
+	CreatedAt  string `json:"created_at"`
+	ActorType  string `json:"actor_type"`
+	Actor      string `json:"actor"`
+	Action     string `json:"action"`
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	Details    string `json:"details"`
+	IPAddress  string `json:"ip_address"`
+	UserAgent  string `json:"user_agent"`
+}
+
+type ShowLandlordAuditLog struct {
+	Entries   []ShowAuditEntry `json:"entries"`
+	Truncated bool             `json:"truncated"` // there are more entries than are shown
+	BrokenAt  int64            `json:"broken_at"` // the first tampered entry, 0 if the log is intact
+	Actions   []string         `json:"actions"`
+	Actor     string           `json:"actor"`
+	Action    string           `json:"action"`
+	Target    string           `json:"target"`
+	From      string           `json:"from"`
+	To        string           `json:"to"`
+	ExportURL string           `json:"export_url"` // the CSV export with the same filter
+	Error     ErrorMessages
+}
@@ -10,60 +10,19 @@ import (
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
 )
 
-func UpdateTenantPassword(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateTenantPassword(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		logs.Logs(logErr, fmt.Sprintf("Invalid request method: %s. Redirecting back to tenant login page.", r.Method))
 		http.Redirect(w, r, "/login/tenant?badRequest=BAD+REQUEST+400:+Invalid+request+method", http.StatusBadRequest)
 		return
 	}
 
-	// deny the request if the authorization fails
-	err := middleware.AuthenticateTenantRequest(r)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error authenticating tenant: %s. Redirecting to tenant login page", err.Error()))
-		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant", http.StatusSeeOther)
-		return
-	}
-
-	// get session cookie
-	sessionToken, err := utils.CheckSessionToken(r)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error getting session token: %s. Redirecting to tenant login page", err.Error()))
-		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant.+Failed+to+get+session+token", http.StatusSeeOther)
-		return
-	}
-
-	// get tenant email from session cookie
-	tenantEmail, err := db.GetHashedEmailFromTenantSessionToken(sessionToken.Value)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error getting tenant email from session token: %s. Redirecting to tenant login page", err.Error()))
-		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant.+Failed+to+get+tenant+email+from+session+token", http.StatusSeeOther)
-		return
-	}
-
-	newSessionToken, newCsrfToken, newExpiryTime, err := db.UpdateTenantSessionTokens(tenantEmail)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error updating tenant session tokens: %s. Redirecting to tenant login page", err.Error()))
-		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant.+Failed+to+update+session+tokens", http.StatusSeeOther)
-		return
-	}
-
-	// set cookie to redirect the user back to tenant dashboard
-	createTenantDashboardSessionCookie := middleware.TenantDashboardSessionCookie(w, newSessionToken, newExpiryTime)
-	if !createTenantDashboardSessionCookie {
-		logs.Logs(logErr, "Error creating tenant dashboard session cookie. Redirecting to tenant login page")
-		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant.+Failed+to+create+tenant+dashboard+session+cookie", http.StatusSeeOther)
-		return
-	}
-	createTenantDashboardCsrfCookie := middleware.TenantDashboardCSRFTokenCookie(w, newCsrfToken, newExpiryTime)
-	if !createTenantDashboardCsrfCookie {
-		logs.Logs(logErr, "Error creating tenant dashboard csrf cookie. Redirecting to tenant login page")
-		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant.+Failed+to+create+tenant+dashboard+csrf+cookie", http.StatusSeeOther)
-		return
-	}
+	// get the tenant (hashed email) authenticated by the session middleware, only their own password can be changed
+	principal, _ := middleware.GetPrincipal(r)
+	hashTenantEmail := principal.Email
 
 	// parse form data
-	err = r.ParseForm()
+	err := r.ParseForm()
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Error parsing form data: %s", err.Error()))
 		http.Error(w, fmt.Sprintf("Error parsing form data: %s", err.Error()), http.StatusInternalServerError)
@@ -71,22 +30,21 @@ func UpdateTenantPassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// extract data from form
-	formTenantEmail := r.FormValue("tenantEmail")
 	formOldPassword := r.FormValue("oldPassword")
 	formNewPassword := r.FormValue("newPassword")
 	formConfirmPassword := r.FormValue("confirmPassword")
 
-	// TODO: make sure old password is correct
-	hashTenantEmail := utils.HashData(formTenantEmail)
-	hashTenantPassword := utils.HashData(formOldPassword)
-	exists, err := db.AuthenticateTenant(hashTenantEmail, hashTenantPassword)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error authenticating tenant: %s. Redirecting to tenant login page", err.Error()))
-		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant", http.StatusSeeOther)
+	// the old password is throttled like a login, so a session cannot be used to guess it
+	if s.loginLockedOut(w, r, TENANT, hashTenantEmail) {
 		return
 	}
 
-	if !exists {
+	exists, err := s.store.AuthenticateTenant(r.Context(), hashTenantEmail, formOldPassword)
+	if err != nil && middleware.StoreUnavailable(w, err) {
+		return
+	}
+	if err != nil || !exists {
+		s.recordLoginFailure(r, TENANT, hashTenantEmail, s.tenantAlertEmail(r, hashTenantEmail))
 		logs.Logs(logErr, "Tenant email or password is incorrect. Redirecting to tenant login page")
 		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Tenant+email+or+password+is+incorrect", http.StatusSeeOther)
 		return
@@ -100,15 +58,33 @@ func UpdateTenantPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: update password in database (will also need to encrypt updated password)
-	newPasswordHash := utils.HashData(formNewPassword)
-	err = db.UpdateTenantPassword(hashTenantEmail, newPasswordHash, formNewPassword)
+	// update password in database, it is hashed with bcrypt before it is stored
+	err = s.store.UpdateTenantPassword(r.Context(), hashTenantEmail, formNewPassword)
 	if err != nil {
+		if middleware.StoreUnavailable(w, err) {
+			return
+		}
 		logs.Logs(logErr, fmt.Sprintf("Error updating tenant password: %s. Redirecting to tenant login page", err.Error()))
 		http.Redirect(w, r, "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+updating+tenant+password", http.StatusSeeOther)
 		return
 	}
+	middleware.RecordAudit(s.store, r, db.AuditTenantPasswordChanged, "tenant", "", "")
 
 	// redirect to tenant dashboard
 	http.Redirect(w, r, "/tenant/dashboard", http.StatusSeeOther)
 }
+
+// tenantAlertEmail gets the email address a tenant is alerted at about failed logins, or an empty string if it cannot be read.
+func (s *Server) tenantAlertEmail(r *http.Request, hashTenantEmail string) string {
+	tenantInfo, err := s.store.GetTenantInformationByHashEmail(r.Context(), hashTenantEmail)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to get tenant information: %s", err.Error()))
+		return ""
+	}
+	tenantEmail, err := utils.Decrypt(tenantInfo.Email)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to decrypt tenant email: %s", err.Error()))
+		return ""
+	}
+	return string(tenantEmail)
+}
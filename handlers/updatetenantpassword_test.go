@@ -0,0 +1,225 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+func TestUpdateTenantPassword(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	// another tenant, whose account the logged in tenant must not be able to use, in a room of its own
+	ctx := context.Background()
+	otherHashEmail := utils.HashData("other-tenant@example.com")
+	testutil.AddTestApplication("Other Tenant", "other-tenant@example.com")
+	testutil.TestEnvironment.DB.CreateRoom(ctx, "test@example.com", 1, "3", "Room 3", "Single Room", 1, "", "900", "USD")
+	rooms, _ := testutil.TestEnvironment.DB.GetRoomsByLandlordEmail(ctx, "test@example.com")
+	err := testutil.TestEnvironment.DB.CreateNewTenant(ctx, "test@example.com", rooms[len(rooms)-1].ID, "other-tenant@example.com", "otherpassword", "2025-01-01", "1", "900", "USD")
+	if err != nil {
+		t.Fatalf("Failed to create tenant: %s", err.Error())
+	}
+
+	// the other tests log in with the original password, and are not slowed down by the failures here
+	hashEmail := utils.HashData("tenant@example.com")
+	t.Cleanup(func() {
+		testutil.TestEnvironment.DB.UpdateTenantPassword(ctx, hashEmail, "password123")
+		testutil.TestEnvironment.DB.ClearLoginThrottles()
+	})
+
+	// Define test cases
+	testCases := []struct {
+		name               string
+		request            func() *http.Request
+		expectedStatusCode int
+		expectedLocation   string
+		expectedPassword   string
+		failDB             bool
+		failDBReason       string
+		timeoutDB          bool
+	}{
+		{
+			name: "Missing session cookie",
+			request: func() *http.Request {
+				form := url.Values{"oldPassword": {"password123"}, "newPassword": {"newpassword123"}, "confirmPassword": {"newpassword123"}}
+				req := httptest.NewRequest(http.MethodPost, "/tenant/update-password", strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/tenant?authenticationError=UNAUTHORIZED+401:+Error+authenticating+tenant",
+			expectedPassword:   "password123",
+		},
+		{
+			name: "Invalid CSRF token",
+			request: func() *http.Request {
+				form := url.Values{"oldPassword": {"password123"}, "newPassword": {"newpassword123"}, "confirmPassword": {"newpassword123"}, "csrf_token": {"invalid_csrf_token"}}
+				return testutil.TenantRequest(http.MethodPost, "/tenant/update-password", form, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedPassword:   "password123",
+		},
+		{
+			name: "Wrong old password",
+			request: func() *http.Request {
+				form := url.Values{"oldPassword": {"wrongpassword"}, "newPassword": {"newpassword123"}, "confirmPassword": {"newpassword123"}}
+				return testutil.TenantRequest(http.MethodPost, "/tenant/update-password", form, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/tenant?authenticationError=UNAUTHORIZED+401:+Tenant+email+or+password+is+incorrect",
+			expectedPassword:   "password123",
+		},
+		{
+			name: "Another tenant's email and password",
+			request: func() *http.Request {
+				form := url.Values{"tenantEmail": {"other-tenant@example.com"}, "oldPassword": {"otherpassword"}, "newPassword": {"newpassword123"}, "confirmPassword": {"newpassword123"}}
+				return testutil.TenantRequest(http.MethodPost, "/tenant/update-password", form, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/login/tenant?authenticationError=UNAUTHORIZED+401:+Tenant+email+or+password+is+incorrect",
+			expectedPassword:   "password123",
+		},
+		{
+			name: "Passwords don't match",
+			request: func() *http.Request {
+				form := url.Values{"oldPassword": {"password123"}, "newPassword": {"newpassword123"}, "confirmPassword": {"differentpassword"}}
+				return testutil.TenantRequest(http.MethodPost, "/tenant/update-password", form, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/tenant/dashboard/account?authenticationError=UNAUTHORIZED+401:+New+password+and+confirmed+password+do+not+match",
+			expectedPassword:   "password123",
+		},
+		{
+			name: "Database error when checking the form token",
+			request: func() *http.Request {
+				form := url.Values{"oldPassword": {"password123"}, "newPassword": {"newpassword123"}, "confirmPassword": {"newpassword123"}}
+				return testutil.TenantRequest(http.MethodPost, "/tenant/update-password", form, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedPassword:   "password123",
+			failDB:             true,
+			failDBReason:       "database error",
+		},
+		{
+			name: "Database timeout when checking the form token",
+			request: func() *http.Request {
+				form := url.Values{"oldPassword": {"password123"}, "newPassword": {"newpassword123"}, "confirmPassword": {"newpassword123"}}
+				return testutil.TenantRequest(http.MethodPost, "/tenant/update-password", form, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedPassword:   "password123",
+			timeoutDB:          true,
+		},
+		{
+			name: "Valid password update",
+			request: func() *http.Request {
+				form := url.Values{"oldPassword": {"password123"}, "newPassword": {"newpassword123"}, "confirmPassword": {"newpassword123"}}
+				return testutil.TenantRequest(http.MethodPost, "/tenant/update-password", form, "tenant@example.com")
+			},
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "/tenant/dashboard",
+			expectedPassword:   "newpassword123",
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.request()
+
+			// Set up mock database to fail if needed
+			if tc.failDB {
+				testutil.TestEnvironment.DB.SetFailNextOperation(tc.failDBReason)
+			}
+			if tc.timeoutDB {
+				testutil.TestEnvironment.DB.SetTimeoutNextOperation()
+			}
+
+			rr := testutil.ServeTestRequest(req)
+
+			// Check the status code
+			if rr.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, rr.Code)
+			}
+
+			// Check the redirect location if applicable
+			if tc.expectedLocation != "" {
+				location := rr.Header().Get("Location")
+				if location != tc.expectedLocation {
+					t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+				}
+			}
+
+			// Check which password the tenant can log in with
+			ok, err := testutil.TestEnvironment.DB.AuthenticateTenant(ctx, hashEmail, tc.expectedPassword)
+			if err != nil || !ok {
+				t.Errorf("Expected tenant to log in with password '%s'", tc.expectedPassword)
+			}
+			// the other tenant's password is never changed
+			ok, err = testutil.TestEnvironment.DB.AuthenticateTenant(ctx, otherHashEmail, "otherpassword")
+			if err != nil || !ok {
+				t.Errorf("Expected the other tenant to keep their password")
+			}
+		})
+	}
+}
+
+func TestUpdateTenantPasswordThrottle(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+
+	go logs.LogProcessor()
+
+	useLoginPolicy(t, config.LoginThrottlePolicy{
+		AccountFreeAttempts: 3,
+		IPFreeAttempts:      100,
+		BaseDelay:           time.Minute,
+		LockoutThreshold:    10,
+		LockoutDuration:     time.Hour,
+		AlertThreshold:      100,
+		FailureWindow:       24 * time.Hour,
+	})
+	hashEmail := utils.HashData("tenant@example.com")
+	t.Cleanup(func() {
+		testutil.TestEnvironment.DB.UpdateTenantPassword(context.Background(), hashEmail, "password123")
+	})
+
+	updatePassword := func(oldPassword string) *httptest.ResponseRecorder {
+		form := url.Values{"oldPassword": {oldPassword}, "newPassword": {"newpassword123"}, "confirmPassword": {"newpassword123"}}
+		return testutil.ServeTestRequest(testutil.TenantRequest(http.MethodPost, "/tenant/update-password", form, "tenant@example.com"))
+	}
+
+	// wrong old passwords count as failed logins on the tenant's account
+	for i := 0; i < 3; i++ {
+		updatePassword("wrongpassword")
+	}
+	if failures := testutil.TestEnvironment.DB.LoginThrottles(); len(failures) == 0 {
+		t.Fatalf("Expected the wrong passwords to be counted as failed logins")
+	}
+
+	// the right password is not checked while the account has to wait, on the login page or here
+	if rr := updatePassword("password123"); !lockedOut(rr) {
+		t.Errorf("Expected the password change to wait after 3 failures, got '%s'", rr.Header().Get("Location"))
+	}
+	form := url.Values{"tenantEmail": {"tenant@example.com"}, "tenantPassword": {"password123"}}
+	req := httptest.NewRequest(http.MethodPost, "/login/tenant/submit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if rr := testutil.ServeTestRequest(req); !lockedOut(rr) {
+		t.Errorf("Expected the tenant login to wait too, got '%s'", rr.Header().Get("Location"))
+	}
+	if ok, err := testutil.TestEnvironment.DB.AuthenticateTenant(context.Background(), hashEmail, "password123"); err != nil || !ok {
+		t.Errorf("Expected the password not to be changed while the account has to wait")
+	}
+}
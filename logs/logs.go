@@ -2,7 +2,8 @@ package logs
 
 import "log"
 
-var logChannel = make(chan string) // channel to send logs to
+var logChannel = make(chan string)          // channel to send logs to
+var flushChannel = make(chan chan struct{}) // channel to wait for the logs sent so far to be written
 
 const (
 	info   = "INFO: "
@@ -13,11 +14,24 @@ const (
 )
 
 func LogProcessor() {
-	for logMessage := range logChannel {
-		log.Println(logMessage)
+	for {
+		select {
+		case logMessage := <-logChannel:
+			log.Println(logMessage)
+		case flushed := <-flushChannel:
+			close(flushed)
+		}
 	}
 }
 
+// Flush waits until the log messages sent before it have been written by LogProcessor,
+// so they are not lost when the program exits straight after.
+func Flush() {
+	flushed := make(chan struct{})
+	flushChannel <- flushed
+	<-flushed
+}
+
 /*
 Logs writes a log message to the log channel, prefixed with one of the log levels defined as constants above.
 The log levels are:
@@ -1,29 +1,220 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
+	"os"
+	"text/tabwriter"
 
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/email"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/handlers"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
 )
 
 const (
 	logInfo  = 1
+	logErr   = 3
 	logDbErr = 5
 )
 
 func main() {
+	// run with -reencrypt after rotating the encryption key to move existing data onto the new key
+	reencrypt := flag.Bool("reencrypt", false, "re-encrypt every encrypted column with the active encryption key, then exit")
+	batchSize := flag.Int("reencrypt-batch-size", 500, "number of rows to re-encrypt in each transaction")
+	// run with -export-subject or -erase-subject to answer a tenant or applicant's subject access or erasure request
+	exportSubject := flag.String("export-subject", "", "export everything held about the tenant or applicant with this email to a ZIP bundle, then exit")
+	exportFile := flag.String("export-file", "subject-export.zip", "file to write the -export-subject bundle to")
+	eraseSubject := flag.String("erase-subject", "", "erase the personal data of the tenant or applicant with this email, keeping financial records, then exit")
+	// run with -retention-report to see what the retention rules would purge, without purging anything
+	retentionReport := flag.Bool("retention-report", false, "report the records past their retention period without purging them, then exit")
+	// run with -migrate, -migrate-down or -migrate-status to manage the database schema, e.g. before deploying a new version
+	migrate := flag.Bool("migrate", false, "apply pending database migrations, then exit")
+	migrateDown := flag.Int("migrate-down", 0, "roll back this many of the most recently applied database migrations, then exit")
+	migrateStatus := flag.Bool("migrate-status", false, "print which database migrations have been applied, then exit")
+	flag.Parse()
+
 	go logs.LogProcessor()
 	logs.Logs(logInfo, "Welcome to Lily's Hidden Paradise, a web app to manage tenants and landlords.")
 
-	err := db.ConnectDB()
+	err := config.Load()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error loading configuration: %s", err.Error()))
+		exitWithError()
+	}
+
+	err = db.ConnectDB()
 	if err != nil {
 		logs.Logs(logDbErr, fmt.Sprintf("Error connecting to database: %s", err.Error()))
+		exitWithError()
+	}
+
+	if *migrate {
+		_, err = db.MigrateUp()
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Error applying migrations: %s", err.Error()))
+			exitWithError()
+		}
+		return
+	}
+	if *migrateDown > 0 {
+		_, err = db.MigrateDown(*migrateDown)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Error rolling back migrations: %s", err.Error()))
+			exitWithError()
+		}
+		return
+	}
+	if *migrateStatus {
+		err = printMigrationStatus()
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Error getting migration status: %s", err.Error()))
+			exitWithError()
+		}
 		return
 	}
 
-	go handlers.StartHTTPServer()
+	if *reencrypt {
+		err = utils.InitEncryption()
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Error initialising encryption functions: %s", err.Error()))
+			exitWithError()
+		}
+		err = db.ReencryptAll(context.Background(), *batchSize)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Error re-encrypting database: %s", err.Error()))
+			exitWithError()
+		}
+		return
+	}
+
+	if *exportSubject != "" || *eraseSubject != "" {
+		err = utils.InitEncryption()
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Error initialising encryption functions: %s", err.Error()))
+			exitWithError()
+		}
+		if *exportSubject != "" {
+			err = exportDataSubject(*exportSubject, *exportFile)
+		} else {
+			err = eraseDataSubject(*eraseSubject)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			logs.Logs(logErr, "No tenant or application was found with that email")
+		}
+		if err != nil {
+			exitWithError()
+		}
+		return
+	}
+
+	if *retentionReport {
+		results, err := db.ApplyRetention(context.Background(), config.Retention, true)
+		if err != nil {
+			logs.Logs(logDbErr, fmt.Sprintf("Error applying retention rules: %s", err.Error()))
+		}
+		db.LogRetentionReport(results)
+		if err != nil {
+			exitWithError()
+		}
+		return
+	}
+
+	go db.StartRetentionScheduler()
+	go email.StartOutboxDispatcher(db.Postgres{})
+	go handlers.NewServer(db.Postgres{}).StartHTTPServer()
 
 	select {} // keeps the program running
 }
+
+// exitWithError exits with status 1, so scripts and deploy pipelines running a command see that it failed.
+// Logs are written by another goroutine, so they are flushed first.
+func exitWithError() {
+	logs.Flush()
+	os.Exit(1)
+}
+
+// exportDataSubject writes everything held about the tenant or applicant with the email to a ZIP bundle at fileName.
+func exportDataSubject(email, fileName string) error {
+	// the bundle holds decrypted personal data, so only the user running the export can read it
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error creating export file: %s", err.Error()))
+		return err
+	}
+
+	export, err := db.ExportDataSubject(context.Background(), email)
+	if err != nil {
+		logs.Logs(logDbErr, fmt.Sprintf("Error exporting data subject: %s", err.Error()))
+	} else {
+		err = export.WriteZip(file)
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Error writing export file: %s", err.Error()))
+		}
+	}
+	closeErr := file.Close()
+	if err == nil && closeErr != nil {
+		err = closeErr
+		logs.Logs(logErr, fmt.Sprintf("Error writing export file: %s", err.Error()))
+	}
+	if err != nil {
+		os.Remove(fileName)
+		return err
+	}
+
+	logs.Logs(logInfo, fmt.Sprintf("Exported %d tables and %d photos to %s", len(export.Records), len(export.Attachments), fileName))
+	return nil
+}
+
+// eraseDataSubject erases the personal data of the tenant or applicant with the email.
+func eraseDataSubject(email string) error {
+	erasure, err := db.EraseDataSubject(context.Background(), email)
+	if err != nil && !errors.Is(err, db.ErrSubjectNotAudited) {
+		logs.Logs(logDbErr, fmt.Sprintf("Error erasing data subject: %s", err.Error()))
+		return err
+	}
+	logs.Logs(logInfo, fmt.Sprintf("Erased tenants %v and applications %v, deleting %d messages and %d photos. Rent ledgers and leases were kept.",
+		erasure.TenantIDs, erasure.ApplicationIDs, erasure.MessagesDeleted, erasure.AttachmentsDeleted))
+	if err != nil {
+		// the erasure cannot be undone, so the operator records it by hand instead
+		logs.Logs(logDbErr, fmt.Sprintf("Error recording erasure in the audit log: %s", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// printMigrationStatus prints each database migration and whether it has been applied.
+func printMigrationStatus() error {
+	states, err := db.GetMigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "VERSION\tNAME\tSTATUS")
+	pending := 0
+	for _, state := range states {
+		name := state.Name
+		if name == "" {
+			name = "(applied by a newer version)"
+		}
+		status := "pending"
+		if state.Applied {
+			status = "applied " + state.AppliedAt.Format("2006-01-02 15:04:05")
+		} else {
+			pending++
+		}
+		fmt.Fprintf(out, "%04d\t%s\t%s\n", state.Version, name, status)
+	}
+	err = out.Flush()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d of %d migrations pending\n", pending, len(states))
+	return nil
+}
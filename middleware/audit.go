@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+)
+
+// user agents are cut down to this length before they are recorded
+const maxAuditUserAgent = 512
+
+/*
+RecordAudit adds a sensitive action taken by the logged in user to the audit log, along with where the request came from.
+Landlord dashboard accounts are recorded by email and account role, and tenants by their ID, so the log holds no tenant emails.
+The entry can be seen by the landlord whose records the action concerns.
+
+Arguments:
+
+- store: Where the entry is added.
+
+- r: The request of the user taking the action.
+
+- action: One of db.AuditActions.
+
+- targetType: The type of record the action was taken on, such as "application" or "tenant".
+
+- targetId: The ID of the record, or a list of IDs.
+
+- details: Anything else worth knowing about the action.
+
+Returns:
+
+- error: An error object if the entry cannot be added. It has already been logged, so callers that have taken the action
+can carry on, but callers about to show sensitive data should not show it.
+*/
+func RecordAudit(store db.AuditStore, r *http.Request, action, targetType, targetId, details string) error {
+	principal, _ := GetPrincipal(r)
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxAuditUserAgent {
+		userAgent = userAgent[:maxAuditUserAgent]
+	}
+	entry := db.AuditEntry{
+		ActorType:     principal.AccountRole,
+		Actor:         principal.Email,
+		LandlordEmail: principal.LandlordEmail,
+		Action:        action,
+		TargetType:    targetType,
+		TargetID:      targetId,
+		Details:       details,
+		IPAddress:     ClientIP(r),
+		UserAgent:     userAgent,
+	}
+	if principal.Role == RoleTenant {
+		tenantId, landlordEmail, err := store.GetTenantAuditIdentity(r.Context(), principal.Email)
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Failed to get tenant for audit log: %s", err.Error()))
+			return err
+		}
+		entry.ActorType = db.AuditActorTenant
+		entry.Actor = fmt.Sprint(tenantId)
+		entry.LandlordEmail = landlordEmail
+	}
+
+	err := store.RecordAudit(r.Context(), entry)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Failed to record %s in audit log: %s", action, err.Error()))
+		return err
+	}
+	return nil
+}
+
+/*
+ClientIP returns the IP address a request came from. Behind the hosting platform's proxy every request comes from the proxy,
+so when config.Login.TrustProxyHeaders is on the address the proxy added to the end of X-Forwarded-For is used instead.
+Earlier addresses in the header are set by the client, so they are never trusted.
+*/
+func ClientIP(r *http.Request) string {
+	if config.Login.TrustProxyHeaders {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
 )
 
-func TestLandlordDashboardCookies(t *testing.T) {
+func TestSessionCookies(t *testing.T) {
 	// Initialize test environment
 	testutil.InitTestEnv()
 	go logs.LogProcessor()
@@ -25,47 +25,26 @@ func TestLandlordDashboardCookies(t *testing.T) {
 		httpOnly       bool
 	}{
 		{
-			name:           "LandlordDashboardSessionCookie",
-			cookieFunction: middleware.LandlordDashboardSessionCookie,
+			name:           "SessionCookie",
+			cookieFunction: middleware.SessionCookie,
 			cookieName:     "session_token",
-			cookiePath:     "/landlord/dashboard",
+			cookiePath:     "/",
 			httpOnly:       true,
 		},
 		{
-			name:           "LandlordDashboardCSRFTokenCookie",
-			cookieFunction: middleware.LandlordDashboardCSRFTokenCookie,
+			name:           "CSRFTokenCookie",
+			cookieFunction: middleware.CSRFTokenCookie,
 			cookieName:     "csrf_token",
-			cookiePath:     "/landlord/dashboard",
+			cookiePath:     "/",
 			httpOnly:       false,
 		},
 		{
-			name:           "LandlordDashboardTenantsSessionCookie",
-			cookieFunction: middleware.LandlordDashboardTenantsSessionCookie,
-			cookieName:     "session_token",
-			cookiePath:     "/landlord/dashboard/tenants",
+			name:           "TwoFactorLoginCookie",
+			cookieFunction: middleware.TwoFactorLoginCookie,
+			cookieName:     "two_factor_token",
+			cookiePath:     "/login",
 			httpOnly:       true,
 		},
-		{
-			name:           "LandlordDashboardTenantsCSRFTokenCookie",
-			cookieFunction: middleware.LandlordDashboardTenantsCSRFTokenCookie,
-			cookieName:     "csrf_token",
-			cookiePath:     "/landlord/dashboard/tenants",
-			httpOnly:       false,
-		},
-		{
-			name:           "TenantDashboardSessionCookie",
-			cookieFunction: middleware.TenantDashboardSessionCookie,
-			cookieName:     "session_token",
-			cookiePath:     "/tenant/dashboard",
-			httpOnly:       true,
-		},
-		{
-			name:           "TenantDashboardCSRFTokenCookie",
-			cookieFunction: middleware.TenantDashboardCSRFTokenCookie,
-			cookieName:     "csrf_token",
-			cookiePath:     "/tenant/dashboard",
-			httpOnly:       false,
-		},
 	}
 
 	for _, tc := range testCases {
@@ -129,26 +108,25 @@ func TestDeleteCookies(t *testing.T) {
 		name           string
 		cookieFunction func(http.ResponseWriter) bool
 		cookieName     string
+		cookiePath     string
 	}{
 		{
-			name:           "DeleteLandlordSessionCookie",
-			cookieFunction: middleware.DeleteLandlordSessionCookie,
+			name:           "DeleteSessionCookie",
+			cookieFunction: middleware.DeleteSessionCookie,
 			cookieName:     "session_token",
+			cookiePath:     "/",
 		},
 		{
-			name:           "DeleteLandlordCSRFCookie",
-			cookieFunction: middleware.DeleteLandlordCSRFCookie,
+			name:           "DeleteCSRFCookie",
+			cookieFunction: middleware.DeleteCSRFCookie,
 			cookieName:     "csrf_token",
+			cookiePath:     "/",
 		},
 		{
-			name:           "DeleteTenantSessionCookie",
-			cookieFunction: middleware.DeleteTenantSessionCookie,
-			cookieName:     "session_token",
-		},
-		{
-			name:           "DeleteTenantCSRFCookie",
-			cookieFunction: middleware.DeleteTenantCSRFCookie,
-			cookieName:     "csrf_token",
+			name:           "DeleteTwoFactorLoginCookie",
+			cookieFunction: middleware.DeleteTwoFactorLoginCookie,
+			cookieName:     "two_factor_token",
+			cookiePath:     "/login",
 		},
 	}
 
@@ -177,8 +155,8 @@ func TestDeleteCookies(t *testing.T) {
 					if cookie.Value != "" {
 						t.Errorf("Expected cookie value to be empty, got '%s'", cookie.Value)
 					}
-					if cookie.Path != "/" {
-						t.Errorf("Expected cookie path to be '/', got '%s'", cookie.Path)
+					if cookie.Path != tc.cookiePath {
+						t.Errorf("Expected cookie path to be '%s', got '%s'", tc.cookiePath, cookie.Path)
 					}
 					// Check if expiry is in the past
 					if !cookie.Expires.Before(time.Now()) {
@@ -193,141 +171,3 @@ func TestDeleteCookies(t *testing.T) {
 		})
 	}
 }
-
-func TestSpecializedCookies(t *testing.T) {
-	// Initialize test environment
-	testutil.InitTestEnv()
-	go logs.LogProcessor()
-
-	// Test LandlordTenantMessagesSessionCookie and LandlordTenantMessagesCSRFTokenCookie
-	t.Run("LandlordTenantMessagesCookies", func(t *testing.T) {
-		// Create a response recorder
-		w := httptest.NewRecorder()
-
-		// Set the cookies
-		tenantID := "123"
-		sessionToken := "test_session_token"
-		csrfToken := "test_csrf_token"
-		expiryTime := time.Now().Add(30 * time.Minute)
-
-		// Set session cookie
-		result1 := middleware.LandlordTenantMessagesSessionCookie(w, tenantID, sessionToken, expiryTime)
-		if !result1 {
-			t.Errorf("Expected LandlordTenantMessagesSessionCookie to return true")
-		}
-
-		// Set CSRF cookie
-		result2 := middleware.LandlordTenantMessagesCSRFTokenCookie(w, tenantID, csrfToken, expiryTime)
-		if !result2 {
-			t.Errorf("Expected LandlordTenantMessagesCSRFTokenCookie to return true")
-		}
-
-		// Get the cookies from the response
-		response := w.Result()
-		cookies := response.Cookies()
-
-		// Expected paths
-		expectedSessionPath := "/landlord/dashboard/messages/tenant/" + tenantID
-		expectedCSRFPath := "/landlord/dashboard/messages/tenant/" + tenantID
-
-		// Check if the cookies were set correctly
-		var sessionFound, csrfFound bool
-		for _, cookie := range cookies {
-			if cookie.Name == "session_token" {
-				sessionFound = true
-				if cookie.Value != sessionToken {
-					t.Errorf("Expected session cookie value to be '%s', got '%s'", sessionToken, cookie.Value)
-				}
-				if cookie.Path != expectedSessionPath {
-					t.Errorf("Expected session cookie path to be '%s', got '%s'", expectedSessionPath, cookie.Path)
-				}
-				if !cookie.HttpOnly {
-					t.Errorf("Expected session cookie HttpOnly to be true")
-				}
-			}
-			if cookie.Name == "csrf_token" {
-				csrfFound = true
-				if cookie.Value != csrfToken {
-					t.Errorf("Expected CSRF cookie value to be '%s', got '%s'", csrfToken, cookie.Value)
-				}
-				if cookie.Path != expectedCSRFPath {
-					t.Errorf("Expected CSRF cookie path to be '%s', got '%s'", expectedCSRFPath, cookie.Path)
-				}
-				if cookie.HttpOnly {
-					t.Errorf("Expected CSRF cookie HttpOnly to be false")
-				}
-			}
-		}
-
-		if !sessionFound {
-			t.Errorf("Session cookie not found in response")
-		}
-		if !csrfFound {
-			t.Errorf("CSRF cookie not found in response")
-		}
-	})
-
-	// Test LogoutLandlordSessionCookie and LogoutLandlordCSRFTokenCookie
-	t.Run("LogoutLandlordCookies", func(t *testing.T) {
-		// Create a response recorder
-		w := httptest.NewRecorder()
-
-		// Set the cookies
-		sessionToken := "test_session_token"
-		csrfToken := "test_csrf_token"
-
-		// Set logout cookies
-		result1 := middleware.LogoutLandlordSessionCookie(w, sessionToken)
-		if !result1 {
-			t.Errorf("Expected LogoutLandlordSessionCookie to return true")
-		}
-
-		result2 := middleware.LogoutLandlordCSRFTokenCookie(w, csrfToken)
-		if !result2 {
-			t.Errorf("Expected LogoutLandlordCSRFTokenCookie to return true")
-		}
-
-		// Get the cookies from the response
-		response := w.Result()
-		cookies := response.Cookies()
-
-		// Expected paths
-		expectedPath := "/logout-landlord"
-
-		// Check if the cookies were set correctly
-		var sessionFound, csrfFound bool
-		for _, cookie := range cookies {
-			if cookie.Name == "session_token" {
-				sessionFound = true
-				if cookie.Value != sessionToken {
-					t.Errorf("Expected session cookie value to be '%s', got '%s'", sessionToken, cookie.Value)
-				}
-				if cookie.Path != expectedPath {
-					t.Errorf("Expected session cookie path to be '%s', got '%s'", expectedPath, cookie.Path)
-				}
-				if !cookie.HttpOnly {
-					t.Errorf("Expected session cookie HttpOnly to be true")
-				}
-			}
-			if cookie.Name == "csrf_token" {
-				csrfFound = true
-				if cookie.Value != csrfToken {
-					t.Errorf("Expected CSRF cookie value to be '%s', got '%s'", csrfToken, cookie.Value)
-				}
-				if cookie.Path != expectedPath {
-					t.Errorf("Expected CSRF cookie path to be '%s', got '%s'", expectedPath, cookie.Path)
-				}
-				if cookie.HttpOnly {
-					t.Errorf("Expected CSRF cookie HttpOnly to be false")
-				}
-			}
-		}
-
-		if !sessionFound {
-			t.Errorf("Session cookie not found in response")
-		}
-		if !csrfFound {
-			t.Errorf("CSRF cookie not found in response")
-		}
-	})
-}
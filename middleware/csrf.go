@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
+)
+
+const (
+	// CSRFFormField is the name of the hidden field every form sent from a logged in page carries its CSRF token in
+	CSRFFormField = "csrf_token"
+
+	// CSRFHeader can carry the CSRF token instead of the form field, for requests that are not sent by a form
+	CSRFHeader = "X-CSRF-Token"
+
+	// MaxFormSize is the largest form body read while looking for the CSRF token, including any file uploads
+	MaxFormSize = 32 << 20
+)
+
+/*
+ProtectForms refuses form posts that come from another site. It wraps every route, so each one only has to
+render the CSRF token into its forms.
+
+For every request that can change something (anything other than GET, HEAD or OPTIONS) it:
+
+- refuses the request if the browser says it came from another site, using the Sec-Fetch-Site header,
+or when that is missing the Origin header, or when that is missing the Referer header.
+
+- when the request has a session cookie, refuses it unless the CSRF token in the form (or the X-CSRF-Token header)
+is the token of that session. Session cookies are SameSite=Strict so other sites cannot send them, and the token
+is a second check that does not depend on the browser.
+
+Requests without a current session, such as logging in, only have the site checks, and logged in pages
+still need the session middleware to authenticate the user. Forms on public pages, such as the login and
+tenancy forms, do not use the session, so they only have the site checks even when a logged in user sends them.
+
+Arguments:
+
+- store: Where the session's CSRF token is read from.
+
+- next: The handler for every route.
+
+- publicForms: The paths of forms that do not use the session, and are not sent with a CSRF token.
+*/
+func ProtectForms(store db.SessionStore, next http.Handler, publicForms ...string) http.Handler {
+	public := make(map[string]bool, len(publicForms))
+	for _, path := range publicForms {
+		public[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		err := checkSameOrigin(r)
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Refused %s request to %s: %s", r.Method, r.URL.Path, err.Error()))
+			http.Error(w, "FORBIDDEN 403: Cross-site requests are not allowed", http.StatusForbidden)
+			return
+		}
+
+		if public[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sessionToken, err := utils.CheckSessionToken(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		csrfToken, err := store.GetCSRFTokenFromSessionToken(r.Context(), sessionToken.Value)
+		if err == sql.ErrNoRows {
+			// not a current session, the session middleware sends logged in pages back to the login page
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			if StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Failed to get CSRF token for %s request to %s: %s", r.Method, r.URL.Path, err.Error()))
+			http.Error(w, "INTERNAL SERVER ERROR 500: Failed to check form token", http.StatusInternalServerError)
+			return
+		}
+
+		formToken, err := requestCSRFToken(w, r)
+		if err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Failed to read form for %s request to %s: %s", r.Method, r.URL.Path, err.Error()))
+			http.Error(w, "BAD REQUEST 400: Failed to read form", http.StatusBadRequest)
+			return
+		}
+		if formToken == "" || subtle.ConstantTimeCompare([]byte(formToken), []byte(csrfToken)) != 1 {
+			logs.Logs(logErr, fmt.Sprintf("Refused %s request to %s: missing or invalid CSRF token", r.Method, r.URL.Path))
+			http.Error(w, "FORBIDDEN 403: Invalid form token. Please reload the page and try again", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkSameOrigin returns an error if the browser says the request was sent from another site.
+// Requests without any of the headers are not from a browser, and are let through.
+func checkSameOrigin(r *http.Request) error {
+	switch site := r.Header.Get("Sec-Fetch-Site"); site {
+	case "same-origin", "none":
+		return nil
+	case "":
+	default:
+		return fmt.Errorf("sent from a %s page", site)
+	}
+
+	// sandboxed frames and some redirects send an origin of "null", which cannot be this site
+	source := r.Header.Get("Origin")
+	if source == "null" {
+		return fmt.Errorf("sent from an opaque origin")
+	}
+	if source == "" {
+		source = r.Referer()
+	}
+	if source == "" {
+		return nil
+	}
+
+	sourceURL, err := url.Parse(source)
+	if err != nil {
+		return fmt.Errorf("invalid origin %q", source)
+	}
+	if !strings.EqualFold(sourceURL.Host, r.Host) {
+		return fmt.Errorf("sent from %s", sourceURL.Host)
+	}
+	return nil
+}
+
+// requestCSRFToken returns the CSRF token sent in the X-CSRF-Token header or the form.
+// The form is parsed here, so handlers that parse it again get the same values.
+func requestCSRFToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if token := r.Header.Get(CSRFHeader); token != "" {
+		return token, nil
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxFormSize)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err := r.ParseMultipartForm(MaxFormSize)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		err := r.ParseForm()
+		if err != nil {
+			return "", err
+		}
+	}
+	return r.PostFormValue(CSRFFormField), nil
+}
@@ -0,0 +1,276 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestProtectForms(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	// Test cases, none of which have a session cookie, so the database is not used
+	testCases := []struct {
+		name           string
+		method         string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{
+			name:           "GET from another site",
+			method:         http.MethodGet,
+			headers:        map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "POST from the same origin",
+			method:         http.MethodPost,
+			headers:        map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://example.com"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "POST from another site",
+			method:         http.MethodPost,
+			headers:        map[string]string{"Sec-Fetch-Site": "cross-site"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "POST from a sibling subdomain",
+			method:         http.MethodPost,
+			headers:        map[string]string{"Sec-Fetch-Site": "same-site"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "POST with a matching Origin",
+			method:         http.MethodPost,
+			headers:        map[string]string{"Origin": "http://example.com"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "POST with another Origin",
+			method:         http.MethodPost,
+			headers:        map[string]string{"Origin": "https://evil.example"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "POST with a null Origin",
+			method:         http.MethodPost,
+			headers:        map[string]string{"Origin": "null"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "POST with another Referer",
+			method:         http.MethodPost,
+			headers:        map[string]string{"Referer": "https://evil.example/form.html"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "POST with a matching Referer",
+			method:         http.MethodPost,
+			headers:        map[string]string{"Referer": "http://example.com/login/landlord"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "POST without any origin headers",
+			method:         http.MethodPost,
+			headers:        map[string]string{},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := middleware.ProtectForms(testutil.TestEnvironment.DB, next)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Create request, httptest sets the host to example.com
+			req := httptest.NewRequest(tc.method, "/login/landlord/submit", nil)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d but got %d", tc.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestProtectFormsSession(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	sessionToken, csrfToken, _, err := testutil.TestEnvironment.DB.StartLandlordSession(context.Background(), "test@example.com", false)
+	if err != nil {
+		t.Fatalf("Failed to start landlord session: %s", err.Error())
+	}
+
+	// multipartForm returns a multipart body with the fields, like the maintenance forms that upload photos
+	multipartForm := func(fields map[string]string) (*bytes.Buffer, string) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for name, value := range fields {
+			writer.WriteField(name, value)
+		}
+		writer.Close()
+		return body, writer.FormDataContentType()
+	}
+
+	// Test cases, each sent with a session cookie from the same origin
+	testCases := []struct {
+		name           string
+		path           string
+		sessionToken   string
+		newRequest     func(path string) *http.Request
+		expectedStatus int
+	}{
+		{
+			name:         "Matching form token",
+			path:         "/landlord/dashboard/properties/add",
+			sessionToken: sessionToken,
+			newRequest: func(path string) *http.Request {
+				form := url.Values{middleware.CSRFFormField: {csrfToken}, "name": {"Garden House"}}
+				req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:         "Matching X-CSRF-Token header",
+			path:         "/landlord/dashboard/properties/add",
+			sessionToken: sessionToken,
+			newRequest: func(path string) *http.Request {
+				req := httptest.NewRequest(http.MethodPost, path, nil)
+				req.Header.Set(middleware.CSRFHeader, csrfToken)
+				return req
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:         "Missing token",
+			path:         "/landlord/dashboard/properties/add",
+			sessionToken: sessionToken,
+			newRequest: func(path string) *http.Request {
+				form := url.Values{"name": {"Garden House"}}
+				req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:         "Wrong token",
+			path:         "/landlord/dashboard/properties/add",
+			sessionToken: sessionToken,
+			newRequest: func(path string) *http.Request {
+				form := url.Values{middleware.CSRFFormField: {"not-the-token"}}
+				req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:         "Wrong X-CSRF-Token header",
+			path:         "/landlord/dashboard/properties/add",
+			sessionToken: sessionToken,
+			newRequest: func(path string) *http.Request {
+				req := httptest.NewRequest(http.MethodPost, path, nil)
+				req.Header.Set(middleware.CSRFHeader, "not-the-token")
+				return req
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:         "Multipart form with matching token",
+			path:         "/tenant/dashboard/maintenance/new",
+			sessionToken: sessionToken,
+			newRequest: func(path string) *http.Request {
+				body, contentType := multipartForm(map[string]string{middleware.CSRFFormField: csrfToken, "name": "Garden House"})
+				req := httptest.NewRequest(http.MethodPost, path, body)
+				req.Header.Set("Content-Type", contentType)
+				return req
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:         "Multipart form with wrong token",
+			path:         "/tenant/dashboard/maintenance/new",
+			sessionToken: sessionToken,
+			newRequest: func(path string) *http.Request {
+				body, contentType := multipartForm(map[string]string{middleware.CSRFFormField: "not-the-token"})
+				req := httptest.NewRequest(http.MethodPost, path, body)
+				req.Header.Set("Content-Type", contentType)
+				return req
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:         "Stale session cookie falls through",
+			path:         "/landlord/dashboard/properties/add",
+			sessionToken: "expired-session-token",
+			newRequest: func(path string) *http.Request {
+				form := url.Values{"name": {"Garden House"}}
+				req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:         "Public form without token",
+			path:         "/login/landlord/submit",
+			sessionToken: sessionToken,
+			newRequest: func(path string) *http.Request {
+				form := url.Values{"name": {"Garden House"}}
+				req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the form has already been read while looking for the token, and must still reach the handler
+		if r.Header.Get(middleware.CSRFHeader) == "" && r.FormValue("name") != "Garden House" {
+			http.Error(w, "form not passed on", http.StatusTeapot)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := middleware.ProtectForms(testutil.TestEnvironment.DB, next, "/login/landlord/submit")
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.newRequest(tc.path)
+			req.Header.Set("Origin", "http://example.com")
+			req.AddCookie(&http.Cookie{Name: "session_token", Value: tc.sessionToken})
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d but got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
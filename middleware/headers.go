@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+)
+
+// permissionsPolicy turns off the browser features the site never uses, so injected content cannot use them either
+const permissionsPolicy = "accelerometer=(), camera=(), geolocation=(), gyroscope=(), magnetometer=(), microphone=(), payment=(), usb=(), interest-cohort=()"
+
+/*
+SecurityHeaders sets the security headers on every response:
+
+- Content-Security-Policy: config.Server.ContentSecurityPolicy, which limits where scripts, styles and images can come from.
+
+- X-Frame-Options: DENY, so other sites cannot show the pages in a frame.
+
+- X-Content-Type-Options: nosniff, so uploaded photos are never run as scripts.
+
+- Referrer-Policy: same-origin, so links with tokens in them, such as password reset links, are not sent to other sites.
+
+- Permissions-Policy: turns off browser features the site does not use.
+
+- Strict-Transport-Security: only on HTTPS requests, served directly or through a trusted proxy,
+telling browsers to only use HTTPS for config.Server.HSTSMaxAge.
+*/
+func SecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers := w.Header()
+		headers.Set("Content-Security-Policy", config.Server.ContentSecurityPolicy)
+		headers.Set("X-Frame-Options", "DENY")
+		headers.Set("X-Content-Type-Options", "nosniff")
+		headers.Set("Referrer-Policy", "same-origin")
+		headers.Set("Permissions-Policy", permissionsPolicy)
+		if isHTTPS(r) && config.Server.HSTSMaxAge > 0 {
+			headers.Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", int64(config.Server.HSTSMaxAge.Seconds())))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isHTTPS reports whether the request reached the site over HTTPS. Behind a proxy that handles HTTPS
+// the X-Forwarded-Proto header it sets is only trusted when config.Login.TrustProxyHeaders is on.
+func isHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return config.Login.TrustProxyHeaders && r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+/*
+RedirectToHTTPS sends every request to the same address over HTTPS. It is served on config.Server.HTTPRedirectPort
+when the site serves HTTPS itself.
+
+Arguments:
+
+- httpsPort: The port HTTPS is served on. It is left out of the redirect when it is the default port 443.
+*/
+func RedirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// swap the HTTP port of the requested host for the HTTPS port
+		host := r.Host
+		if hostname, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = hostname
+		}
+		host = strings.Trim(host, "[]")
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,148 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	defaults := config.Login
+	defer func() { config.Login = defaults }()
+
+	// Test cases
+	testCases := []struct {
+		name         string
+		tls          bool
+		forwardProto string
+		trustProxy   bool
+		expectHSTS   bool
+	}{
+		{
+			name: "Plain HTTP has no HSTS",
+		},
+		{
+			name:       "HTTPS served directly has HSTS",
+			tls:        true,
+			expectHSTS: true,
+		},
+		{
+			name:         "HTTPS through a trusted proxy has HSTS",
+			forwardProto: "https",
+			trustProxy:   true,
+			expectHSTS:   true,
+		},
+		{
+			name:         "Forwarded header is ignored without a trusted proxy",
+			forwardProto: "https",
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := middleware.SecurityHeaders(next)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config.Login.TrustProxyHeaders = tc.trustProxy
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			if tc.forwardProto != "" {
+				req.Header.Set("X-Forwarded-Proto", tc.forwardProto)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			expected := map[string]string{
+				"Content-Security-Policy": config.Server.ContentSecurityPolicy,
+				"X-Frame-Options":         "DENY",
+				"X-Content-Type-Options":  "nosniff",
+				"Referrer-Policy":         "same-origin",
+			}
+			for name, value := range expected {
+				if got := rr.Header().Get(name); got != value {
+					t.Errorf("Expected %s '%s', got '%s'", name, value, got)
+				}
+			}
+			if rr.Header().Get("Permissions-Policy") == "" {
+				t.Errorf("Expected a Permissions-Policy header")
+			}
+			hsts := rr.Header().Get("Strict-Transport-Security")
+			if tc.expectHSTS && hsts != "max-age=31536000; includeSubDomains" {
+				t.Errorf("Expected HSTS header, got '%s'", hsts)
+			}
+			if !tc.expectHSTS && hsts != "" {
+				t.Errorf("Expected no HSTS header, got '%s'", hsts)
+			}
+		})
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	// Test cases
+	testCases := []struct {
+		name             string
+		host             string
+		httpsPort        string
+		target           string
+		expectedLocation string
+	}{
+		{
+			name:             "Default HTTPS port",
+			host:             "lilyshiddenparadise.com",
+			httpsPort:        "443",
+			target:           "/login/landlord?badRequest=x",
+			expectedLocation: "https://lilyshiddenparadise.com/login/landlord?badRequest=x",
+		},
+		{
+			name:             "Other HTTPS port",
+			host:             "localhost:8080",
+			httpsPort:        "9001",
+			target:           "/tenant/dashboard",
+			expectedLocation: "https://localhost:9001/tenant/dashboard",
+		},
+		{
+			name:             "IPv6 host",
+			host:             "[::1]:8080",
+			httpsPort:        "443",
+			target:           "/",
+			expectedLocation: "https://[::1]/",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			req.Host = tc.host
+			rr := httptest.NewRecorder()
+
+			middleware.RedirectToHTTPS(tc.httpsPort).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusMovedPermanently {
+				t.Errorf("Expected status %d, got %d", http.StatusMovedPermanently, rr.Code)
+			}
+			if location := rr.Header().Get("Location"); location != tc.expectedLocation {
+				t.Errorf("Expected redirect to '%s', got '%s'", tc.expectedLocation, location)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
+)
+
+func TestRequireOwnership(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	// Test cases, none of which reach the ownership check, so the database is not used
+	testCases := []struct {
+		name           string
+		method         string
+		target         string
+		form           url.Values
+		id             middleware.IDSource
+		expectedStatus int
+	}{
+		{
+			name:           "No ID in the path",
+			method:         http.MethodGet,
+			target:         "/landlord/dashboard/messages/tenant/",
+			id:             middleware.IDFromPath("/landlord/dashboard/messages/tenant/"),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Path ID that is not a number",
+			method:         http.MethodGet,
+			target:         "/landlord/dashboard/messages/tenant/1%20OR%201=1",
+			id:             middleware.IDFromPath("/landlord/dashboard/messages/tenant/"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Negative query ID",
+			method:         http.MethodGet,
+			target:         "/landlord/dashboard/rent?tenant=-4",
+			id:             middleware.IDFromQuery("tenant"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "No ID in the form",
+			method:         http.MethodPost,
+			target:         "/landlord/dashboard/rent/record-payment",
+			form:           url.Values{"amount": {"500"}},
+			id:             middleware.IDFromForm("tenantId"),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Form ID that is not a number",
+			method:         http.MethodPost,
+			target:         "/landlord/dashboard/rent/record-payment",
+			form:           url.Values{"tenantId": {"abc"}},
+			id:             middleware.IDFromForm("tenantId"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Numeric ID without a logged in landlord",
+			method:         http.MethodPost,
+			target:         "/landlord/dashboard/rent/record-payment?tenantId=7",
+			form:           url.Values{},
+			id:             middleware.IDFromForm("tenantId"),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, nil)
+			if tc.form != nil {
+				req = httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			rr := httptest.NewRecorder()
+
+			middleware.RequireOwnership(testutil.TestEnvironment.DB, db.ResourceTenant, tc.id, next).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d but got %d", tc.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
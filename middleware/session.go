@@ -1,9 +1,11 @@
 package middleware
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
@@ -13,6 +15,9 @@ import (
 const (
 	logInfo = 1
 	logErr  = 3
+
+	RoleLandlord = "landlord"
+	RoleTenant   = "tenant"
 )
 
 var errAuth = errors.New("user not authenticated")
@@ -25,12 +30,13 @@ and CSRF token in the request. If the tokens are invalid, an error is returned.
 	token is missing.
 
 	If the session token or CSRF token do not exist in the database or the database connection
-	is not initialized, an error is returned.
+	is not initialized, an error is returned. It wraps the store's error, so timeouts can be found
+	with db.IsTimeout.
 
 	If the session token or CSRF token is invalid, errAuth is returned with a message indicating
 	the token is invalid.
 */
-func AuthenticateLandlordRequest(r *http.Request) error {
+func AuthenticateLandlordRequest(store db.SessionStore, r *http.Request) error {
 	// get the session token from the cookie
 
 	sessionToken, err := utils.CheckSessionToken(r)
@@ -38,17 +44,17 @@ func AuthenticateLandlordRequest(r *http.Request) error {
 		return fmt.Errorf("%s Session token is missing", err.Error())
 	}
 
-	email, err := db.GetEmailFromLandlordSessionToken(sessionToken.Value)
+	email, err := store.GetEmailFromLandlordSessionToken(r.Context(), sessionToken.Value)
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Failed to get email from session token: %s", err.Error()))
-		return fmt.Errorf("%s! Failed to get email from session token", errAuth)
+		return fmt.Errorf("%s! Failed to get email from session token: %w", errAuth, err)
 	}
 
 	// check if email and session token exists in the database
-	exists, err := db.ValidateLandlordSessionToken(email, sessionToken.Value)
+	exists, err := store.ValidateLandlordSessionToken(r.Context(), email, sessionToken.Value)
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Failed to validate landlord session token: %s", err.Error()))
-		return fmt.Errorf("%s! Failed to validate landlord session token", errAuth)
+		return fmt.Errorf("%s! Failed to validate landlord session token: %w", errAuth, err)
 	}
 	if !exists {
 		logs.Logs(logErr, "Invlaid landlord session token.")
@@ -63,10 +69,10 @@ func AuthenticateLandlordRequest(r *http.Request) error {
 	}
 
 	// check if email and csrf token exists in the database
-	exists, err = db.ValidateLandlordCSRFToken(email, csrfToken.Value)
+	exists, err = store.ValidateLandlordCSRFToken(r.Context(), email, csrfToken.Value)
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Failed to validate landlord CSRF token: %s", err.Error()))
-		return fmt.Errorf("%s! Failed to validate landlord CSRF token", errAuth)
+		return fmt.Errorf("%s! Failed to validate landlord CSRF token: %w", errAuth, err)
 	}
 	if !exists {
 		logs.Logs(logErr, "Invalid landlord CSRF token.")
@@ -77,7 +83,8 @@ func AuthenticateLandlordRequest(r *http.Request) error {
 	return nil
 }
 
-func AuthenticateTenantRequest(r *http.Request) error {
+// AuthenticateTenantRequest authenticates a tenant request in the same way as AuthenticateLandlordRequest.
+func AuthenticateTenantRequest(store db.SessionStore, r *http.Request) error {
 	// get the session token from the cookie
 
 	sessionToken, err := utils.CheckSessionToken(r)
@@ -85,17 +92,17 @@ func AuthenticateTenantRequest(r *http.Request) error {
 		return fmt.Errorf("%s Session token is missing", err.Error())
 	}
 
-	email, err := db.GetHashedEmailFromTenantSessionToken(sessionToken.Value)
+	email, err := store.GetHashedEmailFromTenantSessionToken(r.Context(), sessionToken.Value)
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Failed to get email from session token: %s", err.Error()))
-		return fmt.Errorf("%s! Failed to get email from session token", errAuth)
+		return fmt.Errorf("%s! Failed to get email from session token: %w", errAuth, err)
 	}
 
 	// check if email and session token exists in the database
-	exists, err := db.ValidateTenantSessionToken(email, sessionToken.Value)
+	exists, err := store.ValidateTenantSessionToken(r.Context(), email, sessionToken.Value)
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Failed to validate tenant session token: %s", err.Error()))
-		return fmt.Errorf("%s! Failed to validate tenant session token", errAuth)
+		return fmt.Errorf("%s! Failed to validate tenant session token: %w", errAuth, err)
 	}
 	if !exists {
 		logs.Logs(logErr, "Invlaid tenant session token.")
@@ -110,10 +117,10 @@ func AuthenticateTenantRequest(r *http.Request) error {
 	}
 
 	// check if email and csrf token exists in the database
-	exists, err = db.ValidateTenantCSRFToken(email, csrfToken.Value)
+	exists, err = store.ValidateTenantCSRFToken(r.Context(), email, csrfToken.Value)
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Failed to validate tenant CSRF token: %s", err.Error()))
-		return fmt.Errorf("%s! Failed to validate tenant CSRF token", errAuth)
+		return fmt.Errorf("%s! Failed to validate tenant CSRF token: %w", errAuth, err)
 	}
 	if !exists {
 		logs.Logs(logErr, "Invalid tenant CSRF token.")
@@ -123,3 +130,260 @@ func AuthenticateTenantRequest(r *http.Request) error {
 
 	return nil
 }
+
+/*
+Principal is the user authenticated by RequireLandlord or RequireTenant.
+
+For landlords Email is the landlord's email address. For tenants Email is the tenant's hashed email,
+which is how tenants are identified in the database.
+
+AccountRole is the landlord account's role (owner, landlord or staff). It is empty for tenants.
+
+LandlordEmail is the landlord whose records the account works with: the landlord's own email, or for staff
+the email of the landlord they work for. Permissions are what a staff member has been granted. Both are empty for tenants.
+
+CSRFToken is the session's CSRF token, which pages render into their forms so ProtectForms can check them.
+*/
+type Principal struct {
+	Role          string
+	Email         string
+	AccountRole   string
+	LandlordEmail string
+	Permissions   []string
+	CSRFToken     string
+}
+
+/*
+Can reports whether the principal has a landlord permission. Landlords and the owner can do everything
+with their own records, staff can only do what they have been granted, and tenants have no landlord permissions.
+
+Arguments:
+
+- permission: One of db.StaffPermissions.
+
+Returns:
+
+- bool: True if the principal has the permission.
+*/
+func (p Principal) Can(permission string) bool {
+	if p.Role != RoleLandlord {
+		return false
+	}
+	if p.AccountRole != db.LandlordRoleStaff {
+		return true
+	}
+	for _, granted := range p.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+type principalContextKey struct{}
+
+/*
+GetPrincipal returns the user authenticated by the session middleware for this request.
+
+Returns:
+
+- Principal: The authenticated user.
+
+- bool: False if the request did not pass through RequireLandlord or RequireTenant.
+*/
+func GetPrincipal(r *http.Request) (Principal, bool) {
+	principal, ok := r.Context().Value(principalContextKey{}).(Principal)
+	return principal, ok
+}
+
+// sessionRole describes how to authenticate and rotate the session of one type of user.
+type sessionRole struct {
+	name           string
+	loginPage      string
+	authenticate   func(r *http.Request) error
+	emailFromToken func(ctx context.Context, sessionToken string) (string, error)
+	rotateTokens   func(ctx context.Context, email string) (string, string, time.Time, error)
+	access         func(ctx context.Context, email string) (db.LandlordAccess, error) // nil for users without account roles
+}
+
+// landlordRole authenticates landlord accounts, including staff, against the store.
+func landlordRole(store db.Store) sessionRole {
+	return sessionRole{
+		name:           RoleLandlord,
+		loginPage:      "/login/landlord",
+		authenticate:   func(r *http.Request) error { return AuthenticateLandlordRequest(store, r) },
+		emailFromToken: store.GetEmailFromLandlordSessionToken,
+		rotateTokens:   store.UpdateLandlordSessionTokens,
+		access:         store.GetLandlordAccess,
+	}
+}
+
+// tenantRole authenticates tenants against the store.
+func tenantRole(store db.Store) sessionRole {
+	return sessionRole{
+		name:           RoleTenant,
+		loginPage:      "/login/tenant",
+		authenticate:   func(r *http.Request) error { return AuthenticateTenantRequest(store, r) },
+		emailFromToken: store.GetHashedEmailFromTenantSessionToken,
+		rotateTokens:   store.UpdateTenantSessionTokens,
+	}
+}
+
+/*
+RequireLandlord only lets authenticated landlords through to the next handler.
+
+See requireSession for what happens on each request.
+*/
+func RequireLandlord(store db.Store, next http.Handler) http.Handler {
+	return requireSession(landlordRole(store), next)
+}
+
+/*
+RequireOwner only lets the owner's landlord account through to the next handler.
+Other landlords get a 404, so the owner's pages are not advertised to them.
+
+See requireSession for what happens on each request.
+*/
+func RequireOwner(store db.Store, next http.Handler) http.Handler {
+	return requireSession(landlordRole(store), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := GetPrincipal(r)
+		if principal.AccountRole != db.LandlordRoleOwner {
+			logs.Logs(logErr, fmt.Sprintf("Landlord with account role %s tried to open owner page %s", principal.AccountRole, r.URL.Path))
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+/*
+RequireLandlordPermission only lets landlord accounts with a permission through to the next handler.
+Landlords and the owner have every permission, and staff need to have been granted it.
+Staff without the permission get a 403.
+
+See requireSession for what happens on each request.
+
+Arguments:
+
+- store: Where the session and the account's permissions are read from.
+
+- permission: One of db.StaffPermissions.
+
+- next: The handler to protect.
+*/
+func RequireLandlordPermission(store db.Store, permission string, next http.Handler) http.Handler {
+	return requireSession(landlordRole(store), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := GetPrincipal(r)
+		if !principal.Can(permission) {
+			logs.Logs(logErr, fmt.Sprintf("Staff account without %s permission tried to open %s", permission, r.URL.Path))
+			http.Error(w, "FORBIDDEN 403: You do not have permission to view this page", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+/*
+RequireAccountHolder only lets landlord and owner accounts through to the next handler, not staff.
+It protects pages for running the account itself, such as managing staff.
+Staff get a 403.
+
+See requireSession for what happens on each request.
+*/
+func RequireAccountHolder(store db.Store, next http.Handler) http.Handler {
+	return requireSession(landlordRole(store), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := GetPrincipal(r)
+		if principal.AccountRole == db.LandlordRoleStaff {
+			logs.Logs(logErr, fmt.Sprintf("Staff account tried to open account holder page %s", r.URL.Path))
+			http.Error(w, "FORBIDDEN 403: You do not have permission to view this page", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+/*
+RequireTenant only lets authenticated tenants through to the next handler.
+
+See requireSession for what happens on each request.
+*/
+func RequireTenant(store db.Store, next http.Handler) http.Handler {
+	return requireSession(tenantRole(store), next)
+}
+
+/*
+requireSession wraps a handler that needs a logged in user.
+
+On each request it:
+
+- authenticates the session and CSRF cookies, redirecting to the role's login page if they are not valid.
+
+- rotates the session token and sets the new site-wide cookie pair. The CSRF token stays the same for the whole session.
+
+- puts the authenticated Principal in the request context for the next handler.
+*/
+func requireSession(role sessionRole, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// deny the request if the authorization fails
+		err := role.authenticate(r)
+		if err != nil {
+			if StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Error authenticating %s: %s. Redirecting to %s login page", role.name, err.Error(), role.name))
+			http.Redirect(w, r, fmt.Sprintf("%s?authenticationError=UNAUTHORIZED+401:+Error+authenticating+%s", role.loginPage, role.name), http.StatusSeeOther)
+			return
+		}
+
+		// get the user's email from the session cookie
+		sessionToken, err := utils.CheckSessionToken(r)
+		if err != nil {
+			http.Redirect(w, r, fmt.Sprintf("%s?authenticationError=UNAUTHORIZED+401:+Error+authenticating+%s.+Failed+to+get+session+token", role.loginPage, role.name), http.StatusSeeOther)
+			return
+		}
+		email, err := role.emailFromToken(r.Context(), sessionToken.Value)
+		if err != nil {
+			if StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Error getting %s email from session token: %s. Redirecting to %s login page", role.name, err.Error(), role.name))
+			http.Redirect(w, r, fmt.Sprintf("%s?authenticationError=UNAUTHORIZED+401:+Error+authenticating+%s.+Failed+to+get+email+from+session+token", role.loginPage, role.name), http.StatusSeeOther)
+			return
+		}
+
+		// update the user's session token and expiry time in the database
+		// this will be done for each request
+		newSessionToken, newCsrfToken, newExpiryTime, err := role.rotateTokens(r.Context(), email)
+		if err != nil {
+			if StoreUnavailable(w, err) {
+				return
+			}
+			logs.Logs(logErr, fmt.Sprintf("Error updating %s session tokens: %s. Redirecting to %s login page", role.name, err.Error(), role.name))
+			http.Redirect(w, r, fmt.Sprintf("%s?authenticationError=UNAUTHORIZED+401:+Error+authenticating+%s.+Failed+to+update+session+tokens", role.loginPage, role.name), http.StatusSeeOther)
+			return
+		}
+
+		// one cookie pair covers every page, so handlers no longer set their own cookies
+		SessionCookie(w, newSessionToken, newExpiryTime)
+		CSRFTokenCookie(w, newCsrfToken, newExpiryTime)
+
+		principal := Principal{Role: role.name, Email: email, CSRFToken: newCsrfToken}
+		if role.access != nil {
+			access, err := role.access(r.Context(), email)
+			if err != nil {
+				if StoreUnavailable(w, err) {
+					return
+				}
+				logs.Logs(logErr, fmt.Sprintf("Error getting %s account access: %s. Redirecting to %s login page", role.name, err.Error(), role.name))
+				http.Redirect(w, r, fmt.Sprintf("%s?authenticationError=UNAUTHORIZED+401:+Error+authenticating+%s.+Failed+to+get+account+access", role.loginPage, role.name), http.StatusSeeOther)
+				return
+			}
+			principal.AccountRole = access.AccountRole
+			principal.LandlordEmail = access.LandlordEmail
+			principal.Permissions = access.Permissions
+		}
+
+		ctx := context.WithValue(r.Context(), principalContextKey{}, principal)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
@@ -44,7 +45,7 @@ func TestAuthenticateLandlordRequest(t *testing.T) {
 			tc.setupRequest(req)
 
 			// Call the function
-			err := middleware.AuthenticateLandlordRequest(req)
+			err := middleware.AuthenticateLandlordRequest(testutil.TestEnvironment.DB, req)
 
 			// Check results
 			if tc.expectedError && err == nil {
@@ -95,7 +96,7 @@ func TestAuthenticateTenantRequest(t *testing.T) {
 			tc.setupRequest(req)
 
 			// Call the function
-			err := middleware.AuthenticateTenantRequest(req)
+			err := middleware.AuthenticateTenantRequest(testutil.TestEnvironment.DB, req)
 
 			// Check results
 			if tc.expectedError && err == nil {
@@ -112,3 +113,146 @@ func TestAuthenticateTenantRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireSession(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	// Test cases
+	testCases := []struct {
+		name             string
+		middleware       func(db.Store, http.Handler) http.Handler
+		expectedLocation string
+	}{
+		{
+			name:             "Landlord without session cookie",
+			middleware:       middleware.RequireLandlord,
+			expectedLocation: "/login/landlord",
+		},
+		{
+			name:             "Owner without session cookie",
+			middleware:       middleware.RequireOwner,
+			expectedLocation: "/login/landlord",
+		},
+		{
+			name: "Staff permission without session cookie",
+			middleware: func(store db.Store, next http.Handler) http.Handler {
+				return middleware.RequireLandlordPermission(store, db.PermissionViewApplications, next)
+			},
+			expectedLocation: "/login/landlord",
+		},
+		{
+			name:             "Account holder without session cookie",
+			middleware:       middleware.RequireAccountHolder,
+			expectedLocation: "/login/landlord",
+		},
+		{
+			name:             "Tenant without session cookie",
+			middleware:       middleware.RequireTenant,
+			expectedLocation: "/login/tenant",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			})
+
+			// Create request without any cookies
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			w := httptest.NewRecorder()
+
+			tc.middleware(testutil.TestEnvironment.DB, next).ServeHTTP(w, req)
+
+			// Check results
+			if called {
+				t.Errorf("Expected the next handler not to be called")
+			}
+			if w.Code != http.StatusSeeOther {
+				t.Errorf("Expected status %d but got %d", http.StatusSeeOther, w.Code)
+			}
+			if location := w.Header().Get("Location"); !strings.HasPrefix(location, tc.expectedLocation) {
+				t.Errorf("Expected redirect to '%s' but got '%s'", tc.expectedLocation, location)
+			}
+		})
+	}
+}
+
+func TestGetPrincipal(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	// a request that did not go through the session middleware has no principal
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, ok := middleware.GetPrincipal(req)
+	if ok {
+		t.Errorf("Expected no principal for an unauthenticated request")
+	}
+}
+
+func TestPrincipalCan(t *testing.T) {
+	// Test cases
+	testCases := []struct {
+		name       string
+		principal  middleware.Principal
+		permission string
+		expected   bool
+	}{
+		{
+			name:       "Owner has every permission",
+			principal:  middleware.Principal{Role: middleware.RoleLandlord, AccountRole: db.LandlordRoleOwner},
+			permission: db.PermissionRevealPII,
+			expected:   true,
+		},
+		{
+			name:       "Landlord has every permission",
+			principal:  middleware.Principal{Role: middleware.RoleLandlord, AccountRole: db.LandlordRoleLandlord},
+			permission: db.PermissionViewFinancials,
+			expected:   true,
+		},
+		{
+			name: "Staff with the permission",
+			principal: middleware.Principal{
+				Role:        middleware.RoleLandlord,
+				AccountRole: db.LandlordRoleStaff,
+				Permissions: []string{db.PermissionViewApplications, db.PermissionMessageTenants},
+			},
+			permission: db.PermissionMessageTenants,
+			expected:   true,
+		},
+		{
+			name: "Staff without the permission",
+			principal: middleware.Principal{
+				Role:        middleware.RoleLandlord,
+				AccountRole: db.LandlordRoleStaff,
+				Permissions: []string{db.PermissionViewApplications},
+			},
+			permission: db.PermissionDecideApplications,
+			expected:   false,
+		},
+		{
+			name:       "Staff with no permissions",
+			principal:  middleware.Principal{Role: middleware.RoleLandlord, AccountRole: db.LandlordRoleStaff},
+			permission: db.PermissionViewApplications,
+			expected:   false,
+		},
+		{
+			name:       "Tenant has no landlord permissions",
+			principal:  middleware.Principal{Role: middleware.RoleTenant},
+			permission: db.PermissionViewApplications,
+			expected:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.principal.Can(tc.permission); got != tc.expected {
+				t.Errorf("Expected Can(%s) to be %t but got %t", tc.permission, tc.expected, got)
+			}
+		})
+	}
+}
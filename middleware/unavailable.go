@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+)
+
+/*
+StoreUnavailable answers a request with 503 Service Unavailable if err is from a store call that ran out of time,
+as the database is too busy rather than the request being wrong. The user can try again in a moment.
+
+Arguments:
+
+- w: The response to write the 503 to.
+
+- err: The error returned by the store, or an error wrapping it.
+
+Returns:
+
+- bool: True if the request has been answered and the caller should return.
+*/
+func StoreUnavailable(w http.ResponseWriter, err error) bool {
+	if !db.IsTimeout(err) {
+		return false
+	}
+	logs.Logs(logErr, fmt.Sprintf("Database timed out: %s", err.Error()))
+	w.Header().Set("Retry-After", "5")
+	http.Error(w, "SERVICE UNAVAILABLE 503: The database is busy. Please try again in a moment", http.StatusServiceUnavailable)
+	return true
+}
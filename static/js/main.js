@@ -58,6 +58,25 @@ $(window).on("load resize", function(){
   })
 
 
+/**
+ * ================================
+ * LINK COLOURS & CONFIRMATIONS
+ * ================================
+ */
+// set here rather than in onmouseover/onsubmit attributes, which the content security policy does not run
+
+$("[data-hover-color]").on("mouseenter", function(){
+  $(this).data("color", this.style.color);
+  this.style.color = $(this).data("hover-color");
+}).on("mouseleave", function(){
+  this.style.color = $(this).data("color");
+});
+
+$("form[data-confirm]").on("submit", function(){
+  return confirm($(this).data("confirm"));
+});
+
+
 /**
  * ================================
  * GOOGLE MAPS                    
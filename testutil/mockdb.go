@@ -1,31 +1,64 @@
 package testutil
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
 )
 
-// MockDB is a mock implementation of the database for testing
+// MockDB is an in-memory db.Store for testing. Landlords, tenants, sessions, applications, messages, properties,
+// rooms, leases, maintenance tickets, the audit log, the email outbox, password resets and failed logins are kept in memory;
+// the rest of the store is in mockdb_unsupported.go.
+//
+// Values the real store encrypts are encrypted here too, so handlers decrypt them the same way.
 type MockDB struct {
-	mu                      sync.RWMutex
-	landlords               map[string]*Landlord
-	tenants                 map[string]*Tenant
-	tenantApplications      map[int]*TenantApplication
-	messages                []Message
-	nextTenantAppID         int
-	nextTenantID            int
-	nextMessageID           int
-	failNextOperation       bool
-	failNextOperationReason string
+	mu                   sync.RWMutex
+	landlords            map[string]*Landlord
+	tenants              map[string]*Tenant
+	tenantApplications   map[int]*TenantApplication
+	properties           map[int]*db.Property
+	rooms                map[int]*db.Room
+	leases               map[int]*db.Lease
+	maintenanceTickets   map[int]*MaintenanceTicket
+	messages             []db.Message
+	auditLog             []db.AuditEntry
+	outbox               []db.OutboxEmail
+	passwordResets       []*PasswordReset
+	loginThrottles       map[string]*LoginThrottle
+	nextLandlordID       int
+	nextTenantAppID      int
+	nextTenantID         int
+	nextPropertyID       int
+	nextRoomID           int
+	nextLeaseID          int
+	nextTicketID         int
+	nextAttachmentID     int
+	nextOutboxID         int64
+	failNextOperation    bool
+	failNextOperationErr error
 }
 
+// the mock must be usable wherever the app's store is
+var _ db.Store = (*MockDB)(nil)
+
 // Landlord represents a landlord in the mock database
 type Landlord struct {
 	ID           int
 	Email        string
 	Password     string
+	Suspended    bool
+	CreatedAt    time.Time
 	SessionToken string
 	CSRFToken    string
 	TokenExpiry  time.Time
@@ -33,70 +66,61 @@ type Landlord struct {
 
 // Tenant represents a tenant in the mock database
 type Tenant struct {
-	ID                int
-	LandlordID        int
-	HashEmail         string
-	HashPassword      string
-	EncryptTenantName []byte
-	EncryptEmail      []byte
-	EncryptPassword   []byte
-	EncryptRoomType   []byte
-	EncryptMoveInDate []byte
-	EncryptRentDue    []byte
+	ID                 int
+	LandlordID         int
+	RoomID             int
+	HashEmail          string
+	Password           string
+	EncryptTenantName  []byte
+	EncryptEmail       []byte
+	EncryptRoomType    []byte
+	EncryptMoveInDate  []byte
+	EncryptRentDue     []byte
 	EncryptMonthlyRent []byte
-	Currency          string
-	SessionToken      string
-	CSRFToken         string
-	TokenExpiry       time.Time
-	CreatedAt         time.Time
+	Currency           string
+	SessionToken       string
+	CSRFToken          string
+	TokenExpiry        time.Time
+	CreatedAt          time.Time
+}
+
+// LoginThrottle represents the failed logins of an account or IP address in the mock database
+type LoginThrottle struct {
+	Scope         string // "account" or "ip"
+	UserType      string // the account's user type, empty for IP addresses
+	ThrottleKey   string // the account's user key, or the IP address
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+	Alerted       bool
+}
+
+// MaintenanceTicket represents a maintenance ticket in the mock database. Unlike the tickets the store returns,
+// its attachments hold the encrypted photos.
+type MaintenanceTicket struct {
+	db.MaintenanceTicket
+	LandlordID int
+}
+
+// PasswordReset represents a password reset link in the mock database. Like the real table,
+// only a hash of the token is kept.
+type PasswordReset struct {
+	UserType  string
+	UserKey   string
+	TokenHash string
+	ExpiresAt time.Time
+	Used      bool
 }
 
 // TenantApplication represents a tenant application in the mock database
 type TenantApplication struct {
-	ID                      int
-	LandlordID              int
-	HashFullName            string
-	HashDob                 string
-	HashPassportNumber      string
-	HashEmail               string
-	Status                  string
-	EncryptFullName         []byte
-	EncryptDob              []byte
-	EncryptPassportNumber   []byte
-	EncryptPhoneNumber      []byte
-	EncryptEmail            []byte
-	EncryptOccupation       []byte
-	EncryptEmployer         []byte
-	EncryptEmployerNumber   []byte
-	EncryptEmergencyContact []byte
-	EncryptEmergencyNumber  []byte
-	EncryptEmergencyAddress []byte
-	EncryptIfEvicted        []byte
-	EncryptEvictedReason    []byte
-	EncryptIfConvicted      []byte
-	EncryptConvictedReason  []byte
-	EncryptSmoke            []byte
-	EncryptPets             []byte
-	EncryptIfVehicle        []byte
-	EncryptVehicleReg       []byte
-	EncryptHaveChildren     []byte
-	EncryptChildren         []byte
-	EncryptRefusedRent      []byte
-	EncryptRefusedReason    []byte
-	EncryptUnstableIncome   []byte
-	EncryptIncomeReason     []byte
-	CreatedAt               time.Time
-}
-
-// Message represents a message in the mock database
-type Message struct {
-	ID             int
-	SenderID       int
-	SenderType     string
-	ReceiverID     int
-	ReceiverType   string
-	EncryptMessage []byte
-	SentAt         time.Time
+	ID         int
+	LandlordID int
+	PropertyID int
+	HashEmail  string
+	Status     string
+	Encrypted  db.GetLandlordApplications // the encrypted answers, in the form landlords read them
+	CreatedAt  time.Time
 }
 
 // NewMockDB creates a new mock database
@@ -105,37 +129,98 @@ func NewMockDB() *MockDB {
 		landlords:          make(map[string]*Landlord),
 		tenants:            make(map[string]*Tenant),
 		tenantApplications: make(map[int]*TenantApplication),
-		messages:           []Message{},
+		properties:         make(map[int]*db.Property),
+		rooms:              make(map[int]*db.Room),
+		leases:             make(map[int]*db.Lease),
+		maintenanceTickets: make(map[int]*MaintenanceTicket),
+		messages:           []db.Message{},
+		loginThrottles:     make(map[string]*LoginThrottle),
+		nextLandlordID:     1,
 		nextTenantAppID:    1,
 		nextTenantID:       1,
-		nextMessageID:      1,
+		nextPropertyID:     1,
+		nextRoomID:         1,
+		nextLeaseID:        1,
+		nextTicketID:       1,
+		nextAttachmentID:   1,
+		nextOutboxID:       1,
 	}
 }
 
 // SetFailNextOperation sets the mock database to fail the next operation
 func (m *MockDB) SetFailNextOperation(reason string) {
+	m.setFailNextOperation(errors.New(reason))
+}
+
+// SetTimeoutNextOperation sets the mock database to fail the next operation as if its queries ran out of time
+func (m *MockDB) SetTimeoutNextOperation() {
+	m.setFailNextOperation(context.DeadlineExceeded)
+}
+
+// setFailNextOperation sets the error the next operation fails with
+func (m *MockDB) setFailNextOperation(err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.failNextOperation = true
-	m.failNextOperationReason = reason
+	m.failNextOperationErr = err
 }
 
-// checkFailNextOperation checks if the next operation should fail
-func (m *MockDB) checkFailNextOperation() error {
+// checkFailNextOperation checks if the next operation should fail, or if its request has already ended
+func (m *MockDB) checkFailNextOperation(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if m.failNextOperation {
 		m.failNextOperation = false
-		reason := m.failNextOperationReason
-		m.failNextOperationReason = ""
-		return errors.New(reason)
+		err := m.failNextOperationErr
+		m.failNextOperationErr = nil
+		return err
+	}
+	return ctx.Err()
+}
+
+// encryptValues encrypts each value with the app's encryption key
+func encryptValues(values ...string) ([][]byte, error) {
+	encrypted := make([][]byte, len(values))
+	for i, value := range values {
+		data, err := utils.Encrypt([]byte(value))
+		if err != nil {
+			return nil, err
+		}
+		encrypted[i] = data
+	}
+	return encrypted, nil
+}
+
+// landlordByID finds a landlord by ID. The caller must hold the lock.
+func (m *MockDB) landlordByID(id int) *Landlord {
+	for _, landlord := range m.landlords {
+		if landlord.ID == id {
+			return landlord
+		}
 	}
 	return nil
 }
 
+// landlordRoom finds a room that belongs to a landlord. The caller must hold the lock.
+func (m *MockDB) landlordRoom(landlordEmail string, roomId int) (db.Room, error) {
+	landlord, exists := m.landlords[landlordEmail]
+	room, found := m.rooms[roomId]
+	if !exists || !found || m.properties[room.PropertyID].LandlordID != landlord.ID {
+		return db.Room{}, sql.ErrNoRows
+	}
+
+	result := *room
+	for _, tenant := range m.tenants {
+		if tenant.RoomID == roomId {
+			result.Occupants++
+		}
+	}
+	return result, nil
+}
+
 // CreateNewLandlord creates a new landlord in the mock database
-func (m *MockDB) CreateNewLandlord(landlordEmail, landlordPassword string) error {
-	if err := m.checkFailNextOperation(); err != nil {
+func (m *MockDB) CreateNewLandlord(ctx context.Context, landlordEmail, landlordPassword string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return err
 	}
 
@@ -148,17 +233,19 @@ func (m *MockDB) CreateNewLandlord(landlordEmail, landlordPassword string) error
 	}
 
 	m.landlords[landlordEmail] = &Landlord{
-		ID:       len(m.landlords) + 1,
-		Email:    landlordEmail,
-		Password: landlordPassword, // In a real implementation, this would be hashed
+		ID:        m.nextLandlordID,
+		Email:     landlordEmail,
+		Password:  landlordPassword, // In a real implementation, this would be hashed
+		CreatedAt: time.Now(),
 	}
+	m.nextLandlordID++
 
 	return nil
 }
 
 // AuthenticateLandlord checks if the provided email and password match
-func (m *MockDB) AuthenticateLandlord(email, password string) (bool, error) {
-	if err := m.checkFailNextOperation(); err != nil {
+func (m *MockDB) AuthenticateLandlord(ctx context.Context, email, password string) (bool, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return false, err
 	}
 
@@ -174,13 +261,188 @@ func (m *MockDB) AuthenticateLandlord(email, password string) (bool, error) {
 	if landlord.Password != password {
 		return false, errors.New("invalid password")
 	}
+	if landlord.Suspended {
+		return false, db.ErrLandlordSuspended
+	}
 
 	return true, nil
 }
 
+// GetLandlordIdByEmail gets a landlord's ID by email
+func (m *MockDB) GetLandlordIdByEmail(ctx context.Context, email string) (int, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	landlord, exists := m.landlords[email]
+	if !exists {
+		return 0, sql.ErrNoRows
+	}
+
+	return landlord.ID, nil
+}
+
+// CountLandlords counts the landlord accounts
+func (m *MockDB) CountLandlords(ctx context.Context) (int, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.landlords), nil
+}
+
+// GetLandlordAccess gets what a landlord account can access. Every mock landlord manages their own records.
+func (m *MockDB) GetLandlordAccess(ctx context.Context, email string) (db.LandlordAccess, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return db.LandlordAccess{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, exists := m.landlords[email]; !exists {
+		return db.LandlordAccess{}, sql.ErrNoRows
+	}
+
+	return db.LandlordAccess{AccountRole: db.LandlordRoleLandlord, LandlordEmail: email}, nil
+}
+
+// GetLandlordAccounts lists the landlord accounts in order of ID
+func (m *MockDB) GetLandlordAccounts(ctx context.Context) ([]db.LandlordAccount, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var accounts []db.LandlordAccount
+	for id := 1; id < m.nextLandlordID; id++ {
+		landlord := m.landlordByID(id)
+		if landlord == nil {
+			continue
+		}
+		account := db.LandlordAccount{
+			ID:          landlord.ID,
+			Email:       landlord.Email,
+			AccountRole: db.LandlordRoleLandlord,
+			CreatedAt:   landlord.CreatedAt,
+		}
+		if landlord.Suspended {
+			account.SuspendedAt = sql.NullTime{Time: landlord.CreatedAt, Valid: true}
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+// SetLandlordSuspended suspends or reinstates a landlord account, ending its session
+func (m *MockDB) SetLandlordSuspended(ctx context.Context, landlordId int, suspended bool) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	landlord := m.landlordByID(landlordId)
+	if landlord == nil {
+		return sql.ErrNoRows
+	}
+
+	landlord.Suspended = suspended
+	if suspended {
+		landlord.SessionToken = ""
+		landlord.CSRFToken = ""
+		landlord.TokenExpiry = time.Time{}
+	}
+
+	return nil
+}
+
+// RemoveLandlordAccount removes a landlord account that has no tenants or properties
+func (m *MockDB) RemoveLandlordAccount(ctx context.Context, landlordId int) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	landlord := m.landlordByID(landlordId)
+	if landlord == nil {
+		return sql.ErrNoRows
+	}
+	for _, tenant := range m.tenants {
+		if tenant.LandlordID == landlordId {
+			return db.ErrLandlordHasRecords
+		}
+	}
+	for _, property := range m.properties {
+		if property.LandlordID == landlordId {
+			return db.ErrLandlordHasRecords
+		}
+	}
+
+	delete(m.landlords, landlord.Email)
+
+	return nil
+}
+
+// LandlordOwnsResource checks that a tenant, application or property belongs to a landlord
+func (m *MockDB) LandlordOwnsResource(ctx context.Context, landlordEmail, resource string, id int) (bool, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return false, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	landlord, exists := m.landlords[landlordEmail]
+	if !exists {
+		return false, nil
+	}
+
+	switch resource {
+	case db.ResourceTenant:
+		for _, tenant := range m.tenants {
+			if tenant.ID == id {
+				return tenant.LandlordID == landlord.ID, nil
+			}
+		}
+		return false, nil
+	case db.ResourceApplication:
+		app, found := m.tenantApplications[id]
+		return found && app.LandlordID == landlord.ID, nil
+	case db.ResourceProperty:
+		property, found := m.properties[id]
+		return found && property.LandlordID == landlord.ID, nil
+	case db.ResourceMaintenanceTicket:
+		ticket, found := m.maintenanceTickets[id]
+		return found && ticket.LandlordID == landlord.ID, nil
+	case db.ResourceMaintenancePhoto:
+		ticket, _, found := m.maintenanceAttachment(id)
+		return found && ticket.LandlordID == landlord.ID, nil
+	}
+
+	return false, db.ErrUnknownResource
+}
+
+// StartLandlordSession starts a landlord session and gives it new tokens
+func (m *MockDB) StartLandlordSession(ctx context.Context, email string, rememberMe bool) (string, string, time.Time, error) {
+	return m.UpdateLandlordSessionTokens(ctx, email)
+}
+
 // UpdateLandlordSessionTokens updates the session and CSRF tokens for a landlord
-func (m *MockDB) UpdateLandlordSessionTokens(email string) (string, string, time.Time, error) {
-	if err := m.checkFailNextOperation(); err != nil {
+func (m *MockDB) UpdateLandlordSessionTokens(ctx context.Context, email string) (string, string, time.Time, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return "", "", time.Time{}, err
 	}
 
@@ -204,8 +466,8 @@ func (m *MockDB) UpdateLandlordSessionTokens(email string) (string, string, time
 }
 
 // GetEmailFromLandlordSessionToken gets the email associated with a session token
-func (m *MockDB) GetEmailFromLandlordSessionToken(sessionToken string) (string, error) {
-	if err := m.checkFailNextOperation(); err != nil {
+func (m *MockDB) GetEmailFromLandlordSessionToken(ctx context.Context, sessionToken string) (string, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return "", err
 	}
 
@@ -213,7 +475,7 @@ func (m *MockDB) GetEmailFromLandlordSessionToken(sessionToken string) (string,
 	defer m.mu.RUnlock()
 
 	for email, landlord := range m.landlords {
-		if landlord.SessionToken == sessionToken {
+		if sessionToken != "" && landlord.SessionToken == sessionToken {
 			return email, nil
 		}
 	}
@@ -221,9 +483,35 @@ func (m *MockDB) GetEmailFromLandlordSessionToken(sessionToken string) (string,
 	return "", errors.New("user not found")
 }
 
+// GetCSRFTokenFromSessionToken gets the CSRF token of the landlord or tenant session with a session token
+func (m *MockDB) GetCSRFTokenFromSessionToken(ctx context.Context, sessionToken string) (string, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if sessionToken == "" {
+		return "", sql.ErrNoRows
+	}
+	for _, landlord := range m.landlords {
+		if landlord.SessionToken == sessionToken {
+			return landlord.CSRFToken, nil
+		}
+	}
+	for _, tenant := range m.tenants {
+		if tenant.SessionToken == sessionToken {
+			return tenant.CSRFToken, nil
+		}
+	}
+
+	return "", sql.ErrNoRows
+}
+
 // ValidateLandlordSessionToken validates a landlord's session token
-func (m *MockDB) ValidateLandlordSessionToken(email, sessionToken string) (bool, error) {
-	if err := m.checkFailNextOperation(); err != nil {
+func (m *MockDB) ValidateLandlordSessionToken(ctx context.Context, email, sessionToken string) (bool, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return false, err
 	}
 
@@ -235,12 +523,12 @@ func (m *MockDB) ValidateLandlordSessionToken(email, sessionToken string) (bool,
 		return false, errors.New("user not found")
 	}
 
-	return landlord.SessionToken == sessionToken, nil
+	return landlord.SessionToken == sessionToken && time.Now().Before(landlord.TokenExpiry), nil
 }
 
 // ValidateLandlordCSRFToken validates a landlord's CSRF token
-func (m *MockDB) ValidateLandlordCSRFToken(email, csrfToken string) (bool, error) {
-	if err := m.checkFailNextOperation(); err != nil {
+func (m *MockDB) ValidateLandlordCSRFToken(ctx context.Context, email, csrfToken string) (bool, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return false, err
 	}
 
@@ -256,8 +544,8 @@ func (m *MockDB) ValidateLandlordCSRFToken(email, csrfToken string) (bool, error
 }
 
 // LogoutLandlord removes a landlord's session token, CSRF token, and expiry time
-func (m *MockDB) LogoutLandlord(email string) error {
-	if err := m.checkFailNextOperation(); err != nil {
+func (m *MockDB) LogoutLandlord(ctx context.Context, email string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return err
 	}
 
@@ -276,25 +564,10 @@ func (m *MockDB) LogoutLandlord(email string) error {
 	return nil
 }
 
-// GetLandlordIdByEmail gets a landlord's ID by email
-func (m *MockDB) GetLandlordIdByEmail(email string) (int, error) {
-	if err := m.checkFailNextOperation(); err != nil {
-		return 0, err
-	}
-
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	landlord, exists := m.landlords[email]
-	if !exists {
-		return 0, sql.ErrNoRows
-	}
-
-	return landlord.ID, nil
-}
-
-// SaveTenantApplicationForm saves a tenant application form
+// SaveTenantApplicationForm saves a tenant application form for the landlord who owns the property
 func (m *MockDB) SaveTenantApplicationForm(
+	ctx context.Context,
+	propertyId int,
 	fullName,
 	dateOfBirth,
 	passportNumber,
@@ -321,32 +594,62 @@ func (m *MockDB) SaveTenantApplicationForm(
 	unstableIncome,
 	incomeReason string,
 ) error {
-	if err := m.checkFailNextOperation(); err != nil {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return err
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// In a real implementation, this would hash and encrypt the data
+	property, exists := m.properties[propertyId]
+	if !exists {
+		return sql.ErrNoRows
+	}
+
+	e, err := encryptValues(fullName, dateOfBirth, passportNumber, phoneNumber, email, occupation, employer, employerNumber,
+		emergencyContactName, emergencyContactNumber, emergencyContactAddress, ifEvicted, evictedReason, ifConvicted,
+		convictedReason, smoke, pets, ifVehicle, vehicleReg, haveChildren, children, refusedRent, refusedRentReason,
+		unstableIncome, incomeReason)
+	if err != nil {
+		return err
+	}
+
 	app := &TenantApplication{
-		ID:                    m.nextTenantAppID,
-		LandlordID:            1, // Default to landlord ID 1 for testing
-		HashFullName:          "hash_" + fullName,
-		HashDob:               "hash_" + dateOfBirth,
-		HashPassportNumber:    "hash_" + passportNumber,
-		HashEmail:             "hash_" + email,
-		Status:                "pending",
-		EncryptFullName:       []byte(fullName),
-		EncryptDob:            []byte(dateOfBirth),
-		EncryptPassportNumber: []byte(passportNumber),
-		EncryptPhoneNumber:    []byte(phoneNumber),
-		EncryptEmail:          []byte(email),
-		EncryptOccupation:     []byte(occupation),
-		EncryptEmployer:       []byte(employer),
-		EncryptEmployerNumber: []byte(employerNumber),
-		CreatedAt:             time.Now(),
-		// Add other fields as needed
+		ID:         m.nextTenantAppID,
+		LandlordID: property.LandlordID,
+		PropertyID: propertyId,
+		HashEmail:  utils.HashData(email),
+		Status:     "pending",
+		Encrypted: db.GetLandlordApplications{
+			ID:                      m.nextTenantAppID,
+			FullName:                e[0],
+			Dob:                     e[1],
+			PassportNumber:          e[2],
+			PhoneNumber:             e[3],
+			Email:                   e[4],
+			Occupation:              e[5],
+			Employer:                e[6],
+			EmployerNumber:          e[7],
+			EmergencyContact:        e[8],
+			EmergencyContactNumber:  e[9],
+			EmergencyContactAddress: e[10],
+			Evicted:                 e[11],
+			EvictedReason:           e[12],
+			Convicted:               e[13],
+			ConvictedReason:         e[14],
+			Smoke:                   e[15],
+			Pets:                    e[16],
+			Vehicle:                 e[17],
+			VehicleReg:              e[18],
+			HaveChildren:            e[19],
+			Children:                e[20],
+			RefusedRent:             e[21],
+			RefusedReason:           e[22],
+			UnstableIncome:          e[23],
+			UnstableReason:          e[24],
+			PropertyName:            property.Name,
+		},
+		CreatedAt: time.Now(),
 	}
 
 	m.tenantApplications[m.nextTenantAppID] = app
@@ -355,110 +658,261 @@ func (m *MockDB) SaveTenantApplicationForm(
 	return nil
 }
 
-// GetAllTenantApplications gets all tenant applications for a landlord
-func (m *MockDB) GetAllTenantApplications() ([]TenantApplication, error) {
-	if err := m.checkFailNextOperation(); err != nil {
+// GetAllTenantApplications gets the tenant applications made to a landlord, oldest first
+func (m *MockDB) GetAllTenantApplications(ctx context.Context, landlordEmail string) ([]db.GetLandlordApplications, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return nil, err
 	}
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	var applications []TenantApplication
-	for _, app := range m.tenantApplications {
-		applications = append(applications, *app)
+	landlord, exists := m.landlords[landlordEmail]
+	if !exists {
+		return nil, nil
+	}
+
+	var applications []db.GetLandlordApplications
+	for id := 1; id < m.nextTenantAppID; id++ {
+		app, found := m.tenantApplications[id]
+		if !found || app.LandlordID != landlord.ID {
+			continue
+		}
+		application := app.Encrypted
+		application.Status = app.Status
+		application.CreatedAt = app.CreatedAt.Format("2006-01-02 15:04:05")
+		applications = append(applications, application)
 	}
 
 	return applications, nil
 }
 
-// UpdateTenantApplicationStatus updates the status of a tenant application
-func (m *MockDB) UpdateTenantApplicationStatus(id string, status string) error {
-	if err := m.checkFailNextOperation(); err != nil {
+// UpdateTenantApplicationStatus updates the status of a pending tenant application
+func (m *MockDB) UpdateTenantApplicationStatus(ctx context.Context, id string, status string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return err
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	appID := 0
-	// Convert string ID to int
-	for i := range m.tenantApplications {
-		if i == appID {
-			m.tenantApplications[i].Status = status
-			return nil
-		}
+	appID, err := strconv.Atoi(id)
+	if err != nil {
+		return err
 	}
+	app, exists := m.tenantApplications[appID]
+	if !exists {
+		return errors.New("tenant application not found")
+	}
+	if app.Status != "pending" {
+		return db.ErrApplicationDecided
+	}
+
+	app.Status = status
 
-	return errors.New("tenant application not found")
+	return nil
 }
 
-// CreateNewTenant creates a new tenant
-func (m *MockDB) CreateNewTenant(tenantEmail, tenantPassword, roomType, moveInDate, rentDue, monthlyRent, currency string) error {
-	if err := m.checkFailNextOperation(); err != nil {
-		return err
+// GetTenantEmailAndPassportNumberViaApplicationID gets the encrypted email and passport number of an application
+func (m *MockDB) GetTenantEmailAndPassportNumberViaApplicationID(ctx context.Context, id string) (string, string, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return "", "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	appID, err := strconv.Atoi(id)
+	if err != nil {
+		return "", "", err
+	}
+	app, exists := m.tenantApplications[appID]
+	if !exists {
+		return "", "", sql.ErrNoRows
+	}
+
+	return string(app.Encrypted.Email), string(app.Encrypted.PassportNumber), nil
+}
+
+// AcceptTenantApplication accepts a pending application, creating the tenant and their lease and adding their
+// new account emails to the outbox
+func (m *MockDB) AcceptTenantApplication(ctx context.Context, landlordEmail string, acceptance db.TenantAcceptance) (bool, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return false, err
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	hashEmail := "hash_" + tenantEmail
-	hashPassword := "hash_" + tenantPassword
+	appID, err := strconv.Atoi(acceptance.ApplicationID)
+	if err != nil {
+		return false, err
+	}
+	landlord, exists := m.landlords[landlordEmail]
+	app, found := m.tenantApplications[appID]
+	if !exists || !found || app.LandlordID != landlord.ID {
+		return false, sql.ErrNoRows
+	}
+	switch app.Status {
+	case "accepted":
+		return false, nil
+	case "pending":
+	default:
+		return false, db.ErrApplicationDecided
+	}
+
+	e := app.Encrypted
+	var decrypted [3][]byte
+	for i, value := range [][]byte{e.FullName, e.Email, e.PassportNumber} {
+		decrypted[i], err = utils.Decrypt(value)
+		if err != nil {
+			return false, err
+		}
+	}
+	tenantUsername, tenantPassword, err := utils.GenerateTenantUsernamePassportNumberAndPassword(string(decrypted[1]), string(decrypted[2]))
+	if err != nil {
+		return false, err
+	}
+
+	// nothing is changed unless the tenant can be given the room
+	start, err := time.Parse("2006-01-02", acceptance.MoveInDate)
+	if err != nil {
+		return false, err
+	}
+	err = m.addTenant(landlordEmail, acceptance.RoomID, string(decrypted[0]), tenantUsername, tenantPassword, acceptance.MoveInDate, acceptance.RentDue, acceptance.MonthlyRent, acceptance.Currency)
+	if err != nil {
+		return false, err
+	}
+	m.addLease(m.tenants[utils.HashData(tenantUsername)].ID, start, acceptance.TermMonths, acceptance.BreakClauseMonths, acceptance.NoticeDays)
+	app.Status = "accepted"
+
+	payload, err := json.Marshal(db.NewAccountEmail{
+		LandlordEmail:  landlordEmail,
+		TenantUsername: tenantUsername,
+		TenantPassword: tenantPassword,
+		RoomType:       m.rooms[acceptance.RoomID].RoomType,
+		MoveInDate:     acceptance.MoveInDate,
+		RentDue:        acceptance.RentDue,
+		MonthlyRent:    acceptance.MonthlyRent,
+		Currency:       acceptance.Currency,
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, kind := range []string{db.OutboxTenantNewAccount, db.OutboxLandlordNewAccount} {
+		m.outbox = append(m.outbox, db.OutboxEmail{ID: m.nextOutboxID, Kind: kind, Payload: payload})
+		m.nextOutboxID++
+	}
+
+	return true, nil
+}
+
+// addTenant moves a tenant into a landlord's room. The caller must hold the lock.
+func (m *MockDB) addTenant(landlordEmail string, roomId int, tenantName, tenantEmail, tenantPassword, moveInDate, rentDue, monthlyRent, currency string) error {
+	room, err := m.landlordRoom(landlordEmail, roomId)
+	if err != nil {
+		return err
+	}
+	if room.Occupants >= room.Capacity {
+		return db.ErrRoomFull
+	}
 
-	// Check if tenant already exists
+	hashEmail := utils.HashData(tenantEmail)
 	if _, exists := m.tenants[hashEmail]; exists {
 		return errors.New("tenant already exists")
 	}
 
-	m.tenants[hashEmail] = &Tenant{
-		ID:                m.nextTenantID,
-		LandlordID:        1, // Default to landlord ID 1 for testing
-		HashEmail:         hashEmail,
-		HashPassword:      hashPassword,
-		EncryptTenantName: []byte("Tenant " + tenantEmail),
-		EncryptEmail:      []byte(tenantEmail),
-		EncryptPassword:   []byte(tenantPassword),
-		EncryptRoomType:   []byte(roomType),
-		EncryptMoveInDate: []byte(moveInDate),
-		EncryptRentDue:    []byte(rentDue),
-		EncryptMonthlyRent: []byte(monthlyRent),
-		Currency:          currency,
-		CreatedAt:         time.Now(),
+	e, err := encryptValues(tenantName, tenantEmail, room.RoomType, moveInDate, rentDue, monthlyRent)
+	if err != nil {
+		return err
 	}
 
+	m.tenants[hashEmail] = &Tenant{
+		ID:                 m.nextTenantID,
+		LandlordID:         m.landlords[landlordEmail].ID,
+		RoomID:             roomId,
+		HashEmail:          hashEmail,
+		Password:           tenantPassword,
+		EncryptTenantName:  e[0],
+		EncryptEmail:       e[1],
+		EncryptRoomType:    e[2],
+		EncryptMoveInDate:  e[3],
+		EncryptRentDue:     e[4],
+		EncryptMonthlyRent: e[5],
+		Currency:           currency,
+		CreatedAt:          time.Now(),
+	}
 	m.nextTenantID++
 
 	return nil
 }
 
-// AuthenticateTenant checks if the provided username and password match
-func (m *MockDB) AuthenticateTenant(username, password string) (bool, error) {
-	if err := m.checkFailNextOperation(); err != nil {
+// CreateNewTenant creates a tenant from their application, in a room that belongs to the landlord
+func (m *MockDB) CreateNewTenant(ctx context.Context, landlordEmail string, roomId int, tenantEmail, tenantPassword, moveInDate, rentDue, monthlyRent, currency string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the tenant's name is taken from their application
+	hashEmail := utils.HashData(tenantEmail)
+	var tenantName string
+	for _, app := range m.tenantApplications {
+		if app.HashEmail == hashEmail {
+			name, err := utils.Decrypt(app.Encrypted.FullName)
+			if err != nil {
+				return err
+			}
+			tenantName = string(name)
+		}
+	}
+	if tenantName == "" {
+		return sql.ErrNoRows
+	}
+
+	return m.addTenant(landlordEmail, roomId, tenantName, tenantEmail, tenantPassword, moveInDate, rentDue, monthlyRent, currency)
+}
+
+// ManuallyCreateNewTenant creates a tenant who did not apply online, with a password made from their passport number
+func (m *MockDB) ManuallyCreateNewTenant(ctx context.Context, landlordEmail string, roomId int, tenantFullName, tenantPassportID, tenantEmail, moveInDate, rentDue, monthlyRent, currency string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	_, tenantPassword, err := utils.GenerateTenantUsernamePassportNumberAndPassword(tenantEmail, tenantPassportID)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.addTenant(landlordEmail, roomId, tenantFullName, tenantEmail, tenantPassword, moveInDate, rentDue, monthlyRent, currency)
+}
+
+// AuthenticateTenant checks if the provided hashed email and password match
+func (m *MockDB) AuthenticateTenant(ctx context.Context, username, password string) (bool, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return false, err
 	}
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	hashUsername := "hash_" + username
-	hashPassword := "hash_" + password
-
-	tenant, exists := m.tenants[hashUsername]
+	tenant, exists := m.tenants[username]
 	if !exists {
 		return false, sql.ErrNoRows
 	}
 
-	if tenant.HashPassword != hashPassword {
-		return false, errors.New("invalid password")
-	}
-
-	return true, nil
+	return tenant.Password == password, nil
 }
 
-// UpdateTenantSessionTokens updates the session and CSRF tokens for a tenant
-func (m *MockDB) UpdateTenantSessionTokens(hashEmail string) (string, string, time.Time, error) {
-	if err := m.checkFailNextOperation(); err != nil {
-		return "", "", time.Time{}, err
+// UpdateTenantPassword changes a tenant's password
+func (m *MockDB) UpdateTenantPassword(ctx context.Context, hashEmail, newPassword string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
 	}
 
 	m.mu.Lock()
@@ -466,31 +920,215 @@ func (m *MockDB) UpdateTenantSessionTokens(hashEmail string) (string, string, ti
 
 	tenant, exists := m.tenants[hashEmail]
 	if !exists {
-		return "", "", time.Time{}, sql.ErrNoRows
+		return nil
 	}
 
-	sessionToken := "session_token_" + hashEmail
-	csrfToken := "csrf_token_" + hashEmail
-	expiry := time.Now().Add(30 * time.Minute)
-
-	tenant.SessionToken = sessionToken
-	tenant.CSRFToken = csrfToken
-	tenant.TokenExpiry = expiry
+	tenant.Password = newPassword
 
-	return sessionToken, csrfToken, expiry, nil
+	return nil
 }
 
-// GetHashedEmailFromTenantSessionToken gets the hashed email associated with a session token
-func (m *MockDB) GetHashedEmailFromTenantSessionToken(sessionToken string) (string, error) {
-	if err := m.checkFailNextOperation(); err != nil {
+// GetTenantNameByEmail gets the encrypted name from the application made with an email
+func (m *MockDB) GetTenantNameByEmail(ctx context.Context, email string) (string, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return "", err
 	}
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	for hashEmail, tenant := range m.tenants {
-		if tenant.SessionToken == sessionToken {
+	hashEmail := utils.HashData(email)
+	for _, app := range m.tenantApplications {
+		if app.HashEmail == hashEmail {
+			return string(app.Encrypted.FullName), nil
+		}
+	}
+
+	return "", sql.ErrNoRows
+}
+
+// GetTenantNameByHashEmail gets a tenant's encrypted name by hashed email
+func (m *MockDB) GetTenantNameByHashEmail(ctx context.Context, email string) (string, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tenant, exists := m.tenants[email]
+	if !exists {
+		return "", sql.ErrNoRows
+	}
+
+	return string(tenant.EncryptTenantName), nil
+}
+
+// GetTenantIdByEmail gets a tenant's ID by hashed email
+func (m *MockDB) GetTenantIdByEmail(ctx context.Context, email string) (int, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tenant, exists := m.tenants[email]
+	if !exists {
+		return 0, sql.ErrNoRows
+	}
+
+	return tenant.ID, nil
+}
+
+// GetTenantEncryptedEmailById gets a tenant's encrypted email by ID
+func (m *MockDB) GetTenantEncryptedEmailById(ctx context.Context, tenantId int) (string, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, tenant := range m.tenants {
+		if tenant.ID == tenantId {
+			return string(tenant.EncryptEmail), nil
+		}
+	}
+
+	return "", sql.ErrNoRows
+}
+
+// GetTenantInformationByHashEmail gets tenant information by hashed email
+func (m *MockDB) GetTenantInformationByHashEmail(ctx context.Context, hashEmail string) (db.GetTenantInformation, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return db.GetTenantInformation{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tenant, exists := m.tenants[hashEmail]
+	if !exists {
+		return db.GetTenantInformation{}, sql.ErrNoRows
+	}
+
+	return db.GetTenantInformation{
+		Email:       tenant.EncryptEmail,
+		RoomType:    tenant.EncryptRoomType,
+		MoveInDate:  tenant.EncryptMoveInDate,
+		RentDueDate: tenant.EncryptRentDue,
+		MonthlyRent: tenant.EncryptMonthlyRent,
+		Currency:    tenant.Currency,
+	}, nil
+}
+
+// GetTenantsByLandlordEmail gets a landlord's tenants in order of ID
+func (m *MockDB) GetTenantsByLandlordEmail(ctx context.Context, landlordEmail string) ([]db.LandlordTenants, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	landlord, exists := m.landlords[landlordEmail]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	var tenants []db.LandlordTenants
+	for id := 1; id < m.nextTenantID; id++ {
+		for _, tenant := range m.tenants {
+			if tenant.ID == id && tenant.LandlordID == landlord.ID {
+				tenants = append(tenants, db.LandlordTenants{ID: tenant.ID, EncryptTenantName: tenant.EncryptTenantName, Currency: tenant.Currency})
+			}
+		}
+	}
+
+	return tenants, nil
+}
+
+// GetLandlordByTenantHashEmail gets the ID and email of a tenant's landlord
+func (m *MockDB) GetLandlordByTenantHashEmail(ctx context.Context, hashEmail string) (int, string, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return 0, "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tenant, exists := m.tenants[hashEmail]
+	if !exists {
+		return 0, "", sql.ErrNoRows
+	}
+	landlord := m.landlordByID(tenant.LandlordID)
+	if landlord == nil {
+		return 0, "", sql.ErrNoRows
+	}
+
+	return landlord.ID, landlord.Email, nil
+}
+
+// StartTenantSession starts a tenant session and gives it new tokens
+func (m *MockDB) StartTenantSession(ctx context.Context, hashEmail string, rememberMe bool) (string, string, time.Time, error) {
+	return m.UpdateTenantSessionTokens(ctx, hashEmail)
+}
+
+// UpdateTenantSessionTokens updates the session and CSRF tokens for a tenant
+func (m *MockDB) UpdateTenantSessionTokens(ctx context.Context, hashEmail string) (string, string, time.Time, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tenant, exists := m.tenants[hashEmail]
+	if !exists {
+		return "", "", time.Time{}, sql.ErrNoRows
+	}
+
+	sessionToken := "session_token_" + hashEmail
+	csrfToken := "csrf_token_" + hashEmail
+	expiry := time.Now().Add(30 * time.Minute)
+
+	tenant.SessionToken = sessionToken
+	tenant.CSRFToken = csrfToken
+	tenant.TokenExpiry = expiry
+
+	return sessionToken, csrfToken, expiry, nil
+}
+
+// GetEmailFromTenantSessionToken gets the encrypted email of the tenant with a session token
+func (m *MockDB) GetEmailFromTenantSessionToken(ctx context.Context, sessionToken string) (string, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, tenant := range m.tenants {
+		if sessionToken != "" && tenant.SessionToken == sessionToken {
+			return string(tenant.EncryptEmail), nil
+		}
+	}
+
+	return "", errors.New("user not found")
+}
+
+// GetHashedEmailFromTenantSessionToken gets the hashed email associated with a session token
+func (m *MockDB) GetHashedEmailFromTenantSessionToken(ctx context.Context, sessionToken string) (string, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for hashEmail, tenant := range m.tenants {
+		if sessionToken != "" && tenant.SessionToken == sessionToken {
 			return hashEmail, nil
 		}
 	}
@@ -499,8 +1137,8 @@ func (m *MockDB) GetHashedEmailFromTenantSessionToken(sessionToken string) (stri
 }
 
 // ValidateTenantSessionToken validates a tenant's session token
-func (m *MockDB) ValidateTenantSessionToken(hashEmail, sessionToken string) (bool, error) {
-	if err := m.checkFailNextOperation(); err != nil {
+func (m *MockDB) ValidateTenantSessionToken(ctx context.Context, hashEmail, sessionToken string) (bool, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return false, err
 	}
 
@@ -512,12 +1150,12 @@ func (m *MockDB) ValidateTenantSessionToken(hashEmail, sessionToken string) (boo
 		return false, errors.New("user not found")
 	}
 
-	return tenant.SessionToken == sessionToken, nil
+	return tenant.SessionToken == sessionToken && time.Now().Before(tenant.TokenExpiry), nil
 }
 
 // ValidateTenantCSRFToken validates a tenant's CSRF token
-func (m *MockDB) ValidateTenantCSRFToken(hashEmail, csrfToken string) (bool, error) {
-	if err := m.checkFailNextOperation(); err != nil {
+func (m *MockDB) ValidateTenantCSRFToken(ctx context.Context, hashEmail, csrfToken string) (bool, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return false, err
 	}
 
@@ -533,8 +1171,8 @@ func (m *MockDB) ValidateTenantCSRFToken(hashEmail, csrfToken string) (bool, err
 }
 
 // LogoutTenant removes a tenant's session token, CSRF token, and expiry time
-func (m *MockDB) LogoutTenant(hashEmail string) error {
-	if err := m.checkFailNextOperation(); err != nil {
+func (m *MockDB) LogoutTenant(ctx context.Context, hashEmail string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return err
 	}
 
@@ -553,63 +1191,1091 @@ func (m *MockDB) LogoutTenant(hashEmail string) error {
 	return nil
 }
 
-// GetTenantInformationByHashEmail gets tenant information by hashed email
-func (m *MockDB) GetTenantInformationByHashEmail(hashEmail string) (Tenant, error) {
-	if err := m.checkFailNextOperation(); err != nil {
-		return Tenant{}, err
+// SendMessage sends a message between a landlord and tenant
+func (m *MockDB) SendMessage(ctx context.Context, senderId int, senderType string, receiverID int, receiverType string, message string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	e, err := encryptValues(message)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg := db.Message{
+		SenderID:       senderId,
+		SenderType:     senderType,
+		ReceiverID:     receiverID,
+		ReceiverType:   receiverType,
+		EncryptMessage: e[0],
+		SentAt:         time.Now(),
+	}
+	if senderType == "landlord" {
+		msg.LandlordID, msg.TenantID = senderId, receiverID
+	} else {
+		msg.LandlordID, msg.TenantID = receiverID, senderId
+	}
+	m.messages = append(m.messages, msg)
+
+	return nil
+}
+
+// GetMessageBetweenLandlordsAndTenant gets the messages between a landlord and tenant, oldest first
+func (m *MockDB) GetMessageBetweenLandlordsAndTenant(ctx context.Context, landlordId int, tenantID string) ([]db.Message, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return nil, err
 	}
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	tenant, exists := m.tenants[hashEmail]
+	var messages []db.Message
+	for _, msg := range m.messages {
+		if msg.LandlordID == landlordId && strconv.Itoa(msg.TenantID) == tenantID {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages, nil
+}
+
+// CreateProperty creates a property for a landlord
+func (m *MockDB) CreateProperty(ctx context.Context, landlordEmail, name, address string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	landlord, exists := m.landlords[landlordEmail]
 	if !exists {
-		return Tenant{}, sql.ErrNoRows
+		return sql.ErrNoRows
 	}
 
-	return *tenant, nil
+	m.properties[m.nextPropertyID] = &db.Property{ID: m.nextPropertyID, LandlordID: landlord.ID, Name: name, Address: address}
+	m.nextPropertyID++
+
+	return nil
 }
 
-// SendMessage sends a message between a landlord and tenant
-func (m *MockDB) SendMessage(senderID int, senderType string, receiverID int, receiverType string, message string) error {
-	if err := m.checkFailNextOperation(); err != nil {
+// GetAllProperties gets every property in order of ID
+func (m *MockDB) GetAllProperties(ctx context.Context) ([]db.Property, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var properties []db.Property
+	for id := 1; id < m.nextPropertyID; id++ {
+		if property, exists := m.properties[id]; exists {
+			properties = append(properties, *property)
+		}
+	}
+
+	return properties, nil
+}
+
+// GetPropertiesByLandlordEmail gets a landlord's properties in order of ID
+func (m *MockDB) GetPropertiesByLandlordEmail(ctx context.Context, landlordEmail string) ([]db.Property, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	landlord, exists := m.landlords[landlordEmail]
+	if !exists {
+		return nil, nil
+	}
+
+	var properties []db.Property
+	for id := 1; id < m.nextPropertyID; id++ {
+		if property, exists := m.properties[id]; exists && property.LandlordID == landlord.ID {
+			properties = append(properties, *property)
+		}
+	}
+
+	return properties, nil
+}
+
+// GetPropertyById gets a property by ID
+func (m *MockDB) GetPropertyById(ctx context.Context, propertyId int) (db.Property, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return db.Property{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	property, exists := m.properties[propertyId]
+	if !exists {
+		return db.Property{}, sql.ErrNoRows
+	}
+
+	return *property, nil
+}
+
+// GetPropertyLandlordEmail gets the email of the landlord who owns a property
+func (m *MockDB) GetPropertyLandlordEmail(ctx context.Context, propertyId int) (string, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	property, exists := m.properties[propertyId]
+	if !exists {
+		return "", sql.ErrNoRows
+	}
+	landlord := m.landlordByID(property.LandlordID)
+	if landlord == nil {
+		return "", sql.ErrNoRows
+	}
+
+	return landlord.Email, nil
+}
+
+// PropertyBelongsToLandlord checks that a property belongs to a landlord
+func (m *MockDB) PropertyBelongsToLandlord(ctx context.Context, propertyId int, landlordEmail string) (bool, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return false, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	landlord, exists := m.landlords[landlordEmail]
+	property, found := m.properties[propertyId]
+
+	return exists && found && property.LandlordID == landlord.ID, nil
+}
+
+// CreateRoom adds a room to one of a landlord's properties
+func (m *MockDB) CreateRoom(ctx context.Context, landlordEmail string, propertyId int, unit, name, roomType string, capacity int, amenities, defaultRent, currency string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return err
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.messages = append(m.messages, Message{
-		ID:             m.nextMessageID,
-		SenderID:       senderID,
-		SenderType:     senderType,
-		ReceiverID:     receiverID,
-		ReceiverType:   receiverType,
-		EncryptMessage: []byte(message),
-		SentAt:         time.Now(),
-	})
+	landlord, exists := m.landlords[landlordEmail]
+	property, found := m.properties[propertyId]
+	if !exists || !found || property.LandlordID != landlord.ID {
+		return sql.ErrNoRows
+	}
 
-	m.nextMessageID++
+	m.rooms[m.nextRoomID] = &db.Room{
+		ID:           m.nextRoomID,
+		PropertyID:   propertyId,
+		PropertyName: property.Name,
+		Unit:         unit,
+		Name:         name,
+		RoomType:     roomType,
+		Capacity:     capacity,
+		Amenities:    amenities,
+		DefaultRent:  defaultRent,
+		Currency:     currency,
+	}
+	m.nextRoomID++
 
 	return nil
 }
 
-// GetMessageBetweenLandlordsAndTenant gets messages between a landlord and tenant
-func (m *MockDB) GetMessageBetweenLandlordsAndTenant(tenantID string) ([]Message, error) {
-	if err := m.checkFailNextOperation(); err != nil {
+// GetRoomsByLandlordEmail gets the rooms in a landlord's properties in order of ID
+func (m *MockDB) GetRoomsByLandlordEmail(ctx context.Context, landlordEmail string) ([]db.Room, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
 		return nil, err
 	}
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	var messages []Message
-	for _, msg := range m.messages {
-		if (msg.SenderType == "landlord" && msg.ReceiverType == "tenant") ||
-			(msg.SenderType == "tenant" && msg.ReceiverType == "landlord") {
-			messages = append(messages, msg)
+	var rooms []db.Room
+	for id := 1; id < m.nextRoomID; id++ {
+		if room, err := m.landlordRoom(landlordEmail, id); err == nil {
+			rooms = append(rooms, room)
 		}
 	}
 
-	return messages, nil
+	return rooms, nil
+}
+
+// GetLandlordRoom gets a room that belongs to a landlord
+func (m *MockDB) GetLandlordRoom(ctx context.Context, landlordEmail string, roomId int) (db.Room, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return db.Room{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.landlordRoom(landlordEmail, roomId)
+}
+
+// CreateMaintenanceTicket opens a maintenance ticket for the tenant's room, along with its photos
+func (m *MockDB) CreateMaintenanceTicket(ctx context.Context, tenantHashEmail, category, priority, description string, attachments []db.NewMaintenanceAttachment) (int, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return 0, err
+	}
+
+	err := utils.ValidateMaintenanceTicket(category, priority, description)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tenant, exists := m.tenants[tenantHashEmail]
+	if !exists {
+		return 0, sql.ErrNoRows
+	}
+	encryptDescription, err := utils.Encrypt([]byte(description))
+	if err != nil {
+		return 0, err
+	}
+
+	ticket := &MaintenanceTicket{
+		MaintenanceTicket: db.MaintenanceTicket{
+			ID:                 m.nextTicketID,
+			TenantID:           tenant.ID,
+			EncryptTenantName:  tenant.EncryptTenantName,
+			Category:           category,
+			Priority:           priority,
+			EncryptDescription: encryptDescription,
+			Status:             db.TicketOpen,
+			CreatedAt:          time.Now(),
+			UpdatedAt:          time.Now(),
+			Events: []db.MaintenanceEvent{{
+				TicketID:      m.nextTicketID,
+				ToStatus:      db.TicketOpen,
+				ChangedByType: "tenant",
+				ChangedByID:   tenant.ID,
+				CreatedAt:     time.Now(),
+			}},
+		},
+		LandlordID: tenant.LandlordID,
+	}
+	if room, found := m.rooms[tenant.RoomID]; found {
+		ticket.PropertyName = room.PropertyName
+		ticket.RoomName = room.Name
+	}
+	for _, attachment := range attachments {
+		encryptData, err := utils.Encrypt(attachment.Data)
+		if err != nil {
+			return 0, err
+		}
+		ticket.Attachments = append(ticket.Attachments, db.MaintenanceAttachment{
+			ID:          m.nextAttachmentID,
+			TicketID:    ticket.ID,
+			FileName:    attachment.FileName,
+			ContentType: attachment.ContentType,
+			EncryptData: encryptData,
+			CreatedAt:   time.Now(),
+		})
+		m.nextAttachmentID++
+	}
+	m.maintenanceTickets[ticket.ID] = ticket
+	m.nextTicketID++
+
+	return ticket.ID, nil
+}
+
+// matchMaintenanceTickets gets the tickets that match, in order of ID, without the data of their photos. The caller must hold the lock.
+func (m *MockDB) matchMaintenanceTickets(match func(ticket *MaintenanceTicket) bool) []db.MaintenanceTicket {
+	var tickets []db.MaintenanceTicket
+	for id := 1; id < m.nextTicketID; id++ {
+		ticket, exists := m.maintenanceTickets[id]
+		if !exists || !match(ticket) {
+			continue
+		}
+		result := ticket.MaintenanceTicket
+		result.Attachments = nil
+		for _, attachment := range ticket.Attachments {
+			attachment.EncryptData = nil
+			result.Attachments = append(result.Attachments, attachment)
+		}
+		result.Events = append([]db.MaintenanceEvent(nil), ticket.Events...)
+		tickets = append(tickets, result)
+	}
+	return tickets
+}
+
+// GetMaintenanceTicketsByTenantHashEmail gets the tickets a tenant has opened, newest first
+func (m *MockDB) GetMaintenanceTicketsByTenantHashEmail(ctx context.Context, tenantHashEmail string) ([]db.MaintenanceTicket, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tenant, exists := m.tenants[tenantHashEmail]
+	if !exists {
+		return nil, nil
+	}
+	tickets := m.matchMaintenanceTickets(func(ticket *MaintenanceTicket) bool { return ticket.TenantID == tenant.ID })
+	slices.Reverse(tickets)
+	return tickets, nil
+}
+
+// GetMaintenanceTicketsByLandlordEmail gets the tickets raised for a landlord's properties in order of ID
+func (m *MockDB) GetMaintenanceTicketsByLandlordEmail(ctx context.Context, landlordEmail string) ([]db.MaintenanceTicket, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	landlord, exists := m.landlords[landlordEmail]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return m.matchMaintenanceTickets(func(ticket *MaintenanceTicket) bool { return ticket.LandlordID == landlord.ID }), nil
+}
+
+// GetLandlordMaintenanceTicket gets a ticket raised for one of a landlord's properties
+func (m *MockDB) GetLandlordMaintenanceTicket(ctx context.Context, landlordEmail string, ticketId int) (db.MaintenanceTicket, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return db.MaintenanceTicket{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	landlord, exists := m.landlords[landlordEmail]
+	if !exists {
+		return db.MaintenanceTicket{}, sql.ErrNoRows
+	}
+	tickets := m.matchMaintenanceTickets(func(ticket *MaintenanceTicket) bool {
+		return ticket.ID == ticketId && ticket.LandlordID == landlord.ID
+	})
+	if len(tickets) == 0 {
+		return db.MaintenanceTicket{}, sql.ErrNoRows
+	}
+	return tickets[0], nil
+}
+
+// UpdateMaintenanceTicket moves one of a landlord's tickets to a new status, and adds the email telling the tenant to the outbox
+func (m *MockDB) UpdateMaintenanceTicket(ctx context.Context, landlordEmail string, ticketId int, status, priority, scheduledFor, note string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	err := utils.ValidateTicketPriority(priority)
+	if err != nil {
+		return err
+	}
+	var visitDate sql.NullTime
+	if scheduledFor != "" {
+		date, err := time.Parse("2006-01-02", scheduledFor)
+		if err != nil {
+			return fmt.Errorf("invalid visit date: %s", scheduledFor)
+		}
+		visitDate = sql.NullTime{Time: date, Valid: true}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	landlord, exists := m.landlords[landlordEmail]
+	ticket, found := m.maintenanceTickets[ticketId]
+	if !exists || !found || ticket.LandlordID != landlord.ID {
+		return sql.ErrNoRows
+	}
+	err = utils.ValidateTicketTransition(ticket.Status, status)
+	if err != nil {
+		return err
+	}
+	if !visitDate.Valid {
+		visitDate = ticket.ScheduledFor
+	}
+	if status == db.TicketScheduled && !visitDate.Valid {
+		return errors.New("a visit date is required to schedule a maintenance ticket")
+	}
+
+	var tenantEmail []byte
+	var tenantHashEmail string
+	for _, tenant := range m.tenants {
+		if tenant.ID == ticket.TenantID {
+			tenantEmail, err = utils.Decrypt(tenant.EncryptEmail)
+			if err != nil {
+				return err
+			}
+			tenantHashEmail = tenant.HashEmail
+		}
+	}
+	if tenantHashEmail == "" {
+		return sql.ErrNoRows
+	}
+
+	event := db.MaintenanceEvent{
+		TicketID:      ticketId,
+		FromStatus:    sql.NullString{String: ticket.Status, Valid: true},
+		ToStatus:      status,
+		ChangedByType: "landlord",
+		ChangedByID:   landlord.ID,
+		CreatedAt:     time.Now(),
+	}
+	if note != "" {
+		event.EncryptNote, err = utils.Encrypt([]byte(note))
+		if err != nil {
+			return err
+		}
+	}
+
+	update := db.TicketUpdateEmail{
+		TenantEmail: string(tenantEmail),
+		TicketID:    ticketId,
+		Category:    ticket.Category,
+		Status:      status,
+		Note:        note,
+	}
+	if visitDate.Valid {
+		update.ScheduledFor = visitDate.Time.Format("2006-01-02")
+	}
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	ticket.Status = status
+	ticket.Priority = priority
+	ticket.ScheduledFor = visitDate
+	ticket.UpdatedAt = time.Now()
+	ticket.Events = append(ticket.Events, event)
+	m.outbox = append(m.outbox, db.OutboxEmail{ID: m.nextOutboxID, Kind: db.OutboxTenantTicketUpdate, Payload: payload})
+	m.nextOutboxID++
+
+	return nil
+}
+
+// maintenanceAttachment finds a ticket photo and the ticket it is attached to. The caller must hold the lock.
+func (m *MockDB) maintenanceAttachment(attachmentId int) (*MaintenanceTicket, db.MaintenanceAttachment, bool) {
+	for _, ticket := range m.maintenanceTickets {
+		for _, attachment := range ticket.Attachments {
+			if attachment.ID == attachmentId {
+				return ticket, attachment, true
+			}
+		}
+	}
+	return nil, db.MaintenanceAttachment{}, false
+}
+
+// GetTenantMaintenanceAttachment gets a photo attached to one of the tenant's own tickets
+func (m *MockDB) GetTenantMaintenanceAttachment(ctx context.Context, tenantHashEmail string, attachmentId int) (db.MaintenanceAttachment, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return db.MaintenanceAttachment{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tenant, exists := m.tenants[tenantHashEmail]
+	ticket, attachment, found := m.maintenanceAttachment(attachmentId)
+	if !exists || !found || ticket.TenantID != tenant.ID {
+		return db.MaintenanceAttachment{}, sql.ErrNoRows
+	}
+	return attachment, nil
+}
+
+// GetLandlordMaintenanceAttachment gets a photo attached to a ticket raised for one of the landlord's properties
+func (m *MockDB) GetLandlordMaintenanceAttachment(ctx context.Context, landlordEmail string, attachmentId int) (db.MaintenanceAttachment, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return db.MaintenanceAttachment{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	landlord, exists := m.landlords[landlordEmail]
+	ticket, attachment, found := m.maintenanceAttachment(attachmentId)
+	if !exists || !found || ticket.LandlordID != landlord.ID {
+		return db.MaintenanceAttachment{}, sql.ErrNoRows
+	}
+	return attachment, nil
+}
+
+// addLease starts an active lease for a tenant. The caller must hold the lock.
+func (m *MockDB) addLease(tenantId int, start time.Time, termMonths, breakClauseMonths, noticeDays int) {
+	m.leases[m.nextLeaseID] = &db.Lease{
+		ID:                m.nextLeaseID,
+		TenantID:          tenantId,
+		Status:            db.LeaseActive,
+		StartDate:         start,
+		EndDate:           utils.AddMonths(start, termMonths),
+		TermMonths:        termMonths,
+		BreakClauseMonths: breakClauseMonths,
+		NoticeDays:        noticeDays,
+	}
+	m.nextLeaseID++
+}
+
+// CreateLease creates an active lease for a tenant, ending after the term
+func (m *MockDB) CreateLease(ctx context.Context, tenantId int, startDate string, termMonths, breakClauseMonths, noticeDays int) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addLease(tenantId, start, termMonths, breakClauseMonths, noticeDays)
+	return nil
+}
+
+// GetLeaseByTenantId gets a tenant's lease
+func (m *MockDB) GetLeaseByTenantId(ctx context.Context, tenantId int) (db.Lease, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return db.Lease{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, lease := range m.leases {
+		if lease.TenantID == tenantId {
+			return *lease, nil
+		}
+	}
+	return db.Lease{}, sql.ErrNoRows
+}
+
+// ServeLeaseNotice records notice on an active lease, checked against its notice period and break clause.
+// Renewal offers are not kept in memory, so there are none to withdraw.
+func (m *MockDB) ServeLeaseNotice(ctx context.Context, leaseId int, givenBy, noticeDate, moveOutDate string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	if givenBy != "landlord" && givenBy != "tenant" {
+		return fmt.Errorf("invalid notice giver: %s", givenBy)
+	}
+	notice, err := time.Parse("2006-01-02", noticeDate)
+	if err != nil {
+		return fmt.Errorf("invalid notice date: %s", noticeDate)
+	}
+	moveOut, err := time.Parse("2006-01-02", moveOutDate)
+	if err != nil {
+		return fmt.Errorf("invalid move out date: %s", moveOutDate)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lease, exists := m.leases[leaseId]
+	if !exists {
+		return sql.ErrNoRows
+	}
+	if lease.Status != db.LeaseActive {
+		return fmt.Errorf("notice cannot be given on a lease with status %s", lease.Status)
+	}
+
+	err = utils.ValidateLeaseNotice(lease.StartDate, lease.EndDate, lease.BreakClauseMonths, lease.NoticeDays, notice, moveOut)
+	if err != nil {
+		return err
+	}
+
+	lease.Status = db.LeaseNotice
+	lease.NoticeGivenBy = sql.NullString{String: givenBy, Valid: true}
+	lease.NoticeDate = sql.NullTime{Time: notice, Valid: true}
+	lease.MoveOutDate = sql.NullTime{Time: moveOut, Valid: true}
+	return nil
+}
+
+// loginThrottleKey is the key of the failed logins of an account or IP address, matching the unique key of the real table
+func loginThrottleKey(scope, userType, throttleKey string) string {
+	return scope + "/" + userType + "/" + throttleKey
+}
+
+// GetLoginLockout checks whether the account or the IP address has to wait before the next login
+func (m *MockDB) GetLoginLockout(ctx context.Context, userType, userKey, ip string) (time.Time, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var lockedUntil time.Time
+	for _, key := range []string{loginThrottleKey("account", userType, userKey), loginThrottleKey("ip", "", ip)} {
+		throttle, exists := m.loginThrottles[key]
+		if exists && throttle.LockedUntil.After(time.Now()) && throttle.LockedUntil.After(lockedUntil) {
+			lockedUntil = throttle.LockedUntil
+		}
+	}
+	return lockedUntil, nil
+}
+
+// RecordLoginFailure counts a failed login against the account and the IP address following config.Login
+func (m *MockDB) RecordLoginFailure(ctx context.Context, userType, userKey, ip string) (db.LoginFailure, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return db.LoginFailure{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	account := m.recordThrottleFailure("account", userType, userKey, config.Login.AccountDelay)
+	ipDelay := func(failures int) time.Duration { return config.Login.Delay(failures, config.Login.IPFreeAttempts) }
+	ipThrottle := m.recordThrottleFailure("ip", "", ip, ipDelay)
+
+	result := db.LoginFailure{Failures: account.Failures, LockedUntil: account.LockedUntil}
+	if ipThrottle.LockedUntil.After(result.LockedUntil) {
+		result.LockedUntil = ipThrottle.LockedUntil
+	}
+	if account.Failures >= config.Login.AlertThreshold && !account.Alerted {
+		account.Alerted = true
+		result.SendAlert = true
+	}
+	return result, nil
+}
+
+// recordThrottleFailure adds one failure to an account or IP address, forgetting failures older than config.Login.FailureWindow
+func (m *MockDB) recordThrottleFailure(scope, userType, throttleKey string, delay func(int) time.Duration) *LoginThrottle {
+	key := loginThrottleKey(scope, userType, throttleKey)
+	throttle, exists := m.loginThrottles[key]
+	if !exists {
+		throttle = &LoginThrottle{Scope: scope, UserType: userType, ThrottleKey: throttleKey}
+		m.loginThrottles[key] = throttle
+	}
+	if throttle.LastFailureAt.Before(time.Now().Add(-config.Login.FailureWindow)) {
+		throttle.Failures = 0
+		throttle.Alerted = false
+	}
+
+	throttle.Failures++
+	throttle.LastFailureAt = time.Now()
+	throttle.LockedUntil = time.Time{}
+	if wait := delay(throttle.Failures); wait > 0 {
+		throttle.LockedUntil = time.Now().Add(wait)
+	}
+	return throttle
+}
+
+// ClearLoginFailures clears the failed logins of an account, keeping those of IP addresses
+func (m *MockDB) ClearLoginFailures(ctx context.Context, userType, userKey string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.loginThrottles, loginThrottleKey("account", userType, userKey))
+	return nil
+}
+
+// GetLockedTenantAccounts reports that no tenant is locked out
+func (m *MockDB) GetLockedTenantAccounts(ctx context.Context, landlordEmail string) ([]db.LockedAccount, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// UnlockTenantAccount unlocks a tenant account, which is never locked in the mock
+func (m *MockDB) UnlockTenantAccount(ctx context.Context, landlordEmail string, tenantId int) error {
+	return m.checkFailNextOperation(ctx)
+}
+
+// GetLockedLandlordAccounts lists the landlord accounts locked after config.Login.LockoutThreshold failed logins, in order of ID
+func (m *MockDB) GetLockedLandlordAccounts(ctx context.Context) ([]db.LockedLandlordAccount, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var accounts []db.LockedLandlordAccount
+	for id := 1; id < m.nextLandlordID; id++ {
+		landlord := m.landlordByID(id)
+		if landlord == nil {
+			continue
+		}
+		throttle, exists := m.loginThrottles[loginThrottleKey("account", "landlord", landlord.Email)]
+		if !exists || throttle.Failures < config.Login.LockoutThreshold || !throttle.LockedUntil.After(time.Now()) {
+			continue
+		}
+		accounts = append(accounts, db.LockedLandlordAccount{
+			ID:          landlord.ID,
+			Email:       landlord.Email,
+			AccountRole: db.LandlordRoleLandlord,
+			Failures:    throttle.Failures,
+			LockedUntil: throttle.LockedUntil,
+		})
+	}
+	return accounts, nil
+}
+
+// UnlockLandlordAccount clears the failed logins of a landlord account
+func (m *MockDB) UnlockLandlordAccount(ctx context.Context, landlordId int) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	landlord := m.landlordByID(landlordId)
+	if landlord == nil {
+		return sql.ErrNoRows
+	}
+	key := loginThrottleKey("account", "landlord", landlord.Email)
+	if _, exists := m.loginThrottles[key]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(m.loginThrottles, key)
+	return nil
+}
+
+// GetTwoFactorStatus reports that two-factor authentication is off, as the mock does not support it
+func (m *MockDB) GetTwoFactorStatus(ctx context.Context, userType, userKey string) (db.TwoFactorStatus, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return db.TwoFactorStatus{}, err
+	}
+
+	return db.TwoFactorStatus{}, nil
+}
+
+// RecordAudit adds an entry to the audit log
+func (m *MockDB) RecordAudit(ctx context.Context, entry db.AuditEntry) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry.ID = int64(len(m.auditLog) + 1)
+	entry.CreatedAt = time.Now()
+	m.auditLog = append(m.auditLog, entry)
+
+	return nil
+}
+
+// GetAuditLog gets a landlord's audit log entries that match a filter, newest first
+func (m *MockDB) GetAuditLog(ctx context.Context, landlordEmail string, filter db.AuditFilter, limit int) ([]db.AuditEntry, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []db.AuditEntry
+	for i := len(m.auditLog) - 1; i >= 0 && len(entries) < limit; i-- {
+		entry := m.auditLog[i]
+		if entry.LandlordEmail != landlordEmail ||
+			(filter.Actor != "" && !strings.Contains(entry.Actor, filter.Actor)) ||
+			(filter.Action != "" && entry.Action != filter.Action) ||
+			(filter.TargetID != "" && entry.TargetID != filter.TargetID) ||
+			(!filter.From.IsZero() && entry.CreatedAt.Before(filter.From)) ||
+			(!filter.To.IsZero() && !entry.CreatedAt.Before(filter.To)) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// VerifyAuditLog counts the audit log entries. The mock does not chain them, so there is nothing to check.
+func (m *MockDB) VerifyAuditLog(ctx context.Context) (int64, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.auditLog)), nil
+}
+
+// GetTenantAuditIdentity gets the ID of a tenant and the email of their landlord
+func (m *MockDB) GetTenantAuditIdentity(ctx context.Context, hashEmail string) (int, string, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return 0, "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tenant, exists := m.tenants[hashEmail]
+	if !exists {
+		return 0, "", sql.ErrNoRows
+	}
+	landlord := m.landlordByID(tenant.LandlordID)
+	if landlord == nil {
+		return 0, "", sql.ErrNoRows
+	}
+
+	return tenant.ID, landlord.Email, nil
+}
+
+// AuditLog gets every entry in the audit log, oldest first, for tests to check what was recorded
+func (m *MockDB) AuditLog() []db.AuditEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]db.AuditEntry(nil), m.auditLog...)
+}
+
+// ClaimOutboxEmails gets the emails waiting in the outbox, oldest first. Claimed emails stay in the outbox until
+// they are completed or abandoned, and are claimed again by the next call.
+func (m *MockDB) ClaimOutboxEmails(ctx context.Context, limit int) ([]db.OutboxEmail, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.outbox) < limit {
+		limit = len(m.outbox)
+	}
+	return append([]db.OutboxEmail(nil), m.outbox[:limit]...), nil
+}
+
+// CompleteOutboxEmail removes a sent email from the outbox
+func (m *MockDB) CompleteOutboxEmail(ctx context.Context, id int64) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.removeOutboxEmail(id)
+}
+
+// RetryOutboxEmail counts a failed attempt to send an email. The retry time is not kept.
+func (m *MockDB) RetryOutboxEmail(ctx context.Context, id int64, sendErr string, retryAt time.Time) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.outbox {
+		if m.outbox[i].ID == id {
+			m.outbox[i].Attempts++
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// AbandonOutboxEmail removes an email that failed to send too many times from the outbox
+func (m *MockDB) AbandonOutboxEmail(ctx context.Context, id int64, sendErr string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.removeOutboxEmail(id)
+}
+
+// removeOutboxEmail removes an email from the outbox. The caller must hold the lock.
+func (m *MockDB) removeOutboxEmail(id int64) error {
+	for i, email := range m.outbox {
+		if email.ID == id {
+			m.outbox = append(m.outbox[:i], m.outbox[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// OutboxEmails gets every email waiting in the outbox, oldest first, for tests to check what will be sent
+func (m *MockDB) OutboxEmails() []db.OutboxEmail {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]db.OutboxEmail(nil), m.outbox...)
+}
+
+// CreatePasswordReset creates a single-use password reset token for a landlord or tenant,
+// and stops any reset links the user was sent before from working
+func (m *MockDB) CreatePasswordReset(ctx context.Context, userType, userKey string) (string, error) {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch userType {
+	case "landlord":
+		landlord, ok := m.landlords[userKey]
+		if !ok || landlord.Suspended {
+			return "", sql.ErrNoRows
+		}
+	case "tenant":
+		if _, ok := m.tenants[userKey]; !ok {
+			return "", sql.ErrNoRows
+		}
+	default:
+		return "", fmt.Errorf("invalid user type: %s", userType)
+	}
+
+	token, err := utils.GenerateToken(32)
+	if err != nil {
+		return "", err
+	}
+	for _, reset := range m.passwordResets {
+		if reset.UserType == userType && reset.UserKey == userKey {
+			reset.Used = true
+		}
+	}
+	// links expire after an hour, as in the database
+	m.passwordResets = append(m.passwordResets, &PasswordReset{
+		UserType:  userType,
+		UserKey:   userKey,
+		TokenHash: utils.HashData(token),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	return token, nil
+}
+
+// passwordReset finds the usable password reset with the token, or nil if it is unknown, used or expired
+func (m *MockDB) passwordReset(userType, token string) *PasswordReset {
+	tokenHash := utils.HashData(token)
+	for _, reset := range m.passwordResets {
+		if reset.UserType == userType && reset.TokenHash == tokenHash && !reset.Used && reset.ExpiresAt.After(time.Now()) {
+			return reset
+		}
+	}
+	return nil
+}
+
+// ValidatePasswordResetToken checks a password reset token can still be used, without using it up
+func (m *MockDB) ValidatePasswordResetToken(ctx context.Context, userType, token string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.passwordReset(userType, token) == nil {
+		return db.ErrInvalidResetToken
+	}
+	return nil
+}
+
+// ResetPassword uses up a password reset token to set a new password, and ends every session of the user
+func (m *MockDB) ResetPassword(ctx context.Context, userType, token, newPassword string) error {
+	if err := m.checkFailNextOperation(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reset := m.passwordReset(userType, token)
+	if reset == nil {
+		return db.ErrInvalidResetToken
+	}
+	reset.Used = true
+
+	switch userType {
+	case "landlord":
+		landlord, ok := m.landlords[reset.UserKey]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		landlord.Password = newPassword
+		landlord.SessionToken, landlord.CSRFToken, landlord.TokenExpiry = "", "", time.Time{}
+	case "tenant":
+		tenant, ok := m.tenants[reset.UserKey]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		tenant.Password = newPassword
+		tenant.SessionToken, tenant.CSRFToken, tenant.TokenExpiry = "", "", time.Time{}
+	default:
+		return fmt.Errorf("invalid user type: %s", userType)
+	}
+	return nil
+}
+
+// PasswordResets gets every password reset link, oldest first, for tests to check what was stored
+func (m *MockDB) PasswordResets() []PasswordReset {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	resets := make([]PasswordReset, len(m.passwordResets))
+	for i, reset := range m.passwordResets {
+		resets[i] = *reset
+	}
+	return resets
+}
+
+// ExpirePasswordResets moves the expiry of every password reset link back by the duration, for tests of expired links
+func (m *MockDB) ExpirePasswordResets(by time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, reset := range m.passwordResets {
+		reset.ExpiresAt = reset.ExpiresAt.Add(-by)
+	}
+}
+
+// LoginThrottles returns a copy of the failed logins counted against accounts and IP addresses
+func (m *MockDB) LoginThrottles() []LoginThrottle {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	throttles := make([]LoginThrottle, 0, len(m.loginThrottles))
+	for _, throttle := range m.loginThrottles {
+		throttles = append(throttles, *throttle)
+	}
+	return throttles
+}
+
+// AgeLoginFailures moves every failed login and lock back by the duration, for tests of waits running out and failures being forgotten
+func (m *MockDB) AgeLoginFailures(by time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, throttle := range m.loginThrottles {
+		throttle.LastFailureAt = throttle.LastFailureAt.Add(-by)
+		if !throttle.LockedUntil.IsZero() {
+			throttle.LockedUntil = throttle.LockedUntil.Add(-by)
+		}
+	}
+}
+
+// ClearLoginThrottles forgets every failed login, so tests do not lock each other out
+func (m *MockDB) ClearLoginThrottles() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loginThrottles = make(map[string]*LoginThrottle)
+}
+
+// Ping checks the mock database can be reached, which it always can unless it is set to fail
+func (m *MockDB) Ping(ctx context.Context) error {
+	return m.checkFailNextOperation(ctx)
 }
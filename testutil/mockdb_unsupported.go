@@ -0,0 +1,159 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/config"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/db"
+)
+
+// ErrNotMocked is returned by the parts of db.Store that MockDB does not keep in memory.
+// Handlers treat it like any other database error, so tests can check how they fail.
+var ErrNotMocked = errors.New("not supported by MockDB")
+
+// CreateLandlordInvitation is not supported by MockDB
+func (m *MockDB) CreateLandlordInvitation(ctx context.Context, invitedBy, email, accountRole string) (string, time.Time, error) {
+	return "", time.Time{}, ErrNotMocked
+}
+
+// GetLandlordInvitation is not supported by MockDB
+func (m *MockDB) GetLandlordInvitation(ctx context.Context, token string) (db.LandlordInvitation, error) {
+	return db.LandlordInvitation{}, ErrNotMocked
+}
+
+// AcceptLandlordInvitation is not supported by MockDB
+func (m *MockDB) AcceptLandlordInvitation(ctx context.Context, token, password string) (string, error) {
+	return "", ErrNotMocked
+}
+
+// GetPendingLandlordInvitations is not supported by MockDB
+func (m *MockDB) GetPendingLandlordInvitations(ctx context.Context) ([]db.LandlordInvitation, error) {
+	return nil, ErrNotMocked
+}
+
+// RevokeLandlordInvitation is not supported by MockDB
+func (m *MockDB) RevokeLandlordInvitation(ctx context.Context, invitationId int) error {
+	return ErrNotMocked
+}
+
+// GetStaffAccounts is not supported by MockDB
+func (m *MockDB) GetStaffAccounts(ctx context.Context, landlordEmail string) ([]db.StaffAccount, error) {
+	return nil, ErrNotMocked
+}
+
+// SetStaffPermissions is not supported by MockDB
+func (m *MockDB) SetStaffPermissions(ctx context.Context, landlordEmail string, staffId int, permissions []string) error {
+	return ErrNotMocked
+}
+
+// RemoveStaffAccount is not supported by MockDB
+func (m *MockDB) RemoveStaffAccount(ctx context.Context, landlordEmail string, staffId int) error {
+	return ErrNotMocked
+}
+
+// GetPendingStaffInvitations is not supported by MockDB
+func (m *MockDB) GetPendingStaffInvitations(ctx context.Context, landlordEmail string) ([]db.LandlordInvitation, error) {
+	return nil, ErrNotMocked
+}
+
+// RevokeStaffInvitation is not supported by MockDB
+func (m *MockDB) RevokeStaffInvitation(ctx context.Context, landlordEmail string, invitationId int) error {
+	return ErrNotMocked
+}
+
+// GetLockedStaffAccounts is not supported by MockDB
+func (m *MockDB) GetLockedStaffAccounts(ctx context.Context, landlordEmail string) ([]db.LockedLandlordAccount, error) {
+	return nil, ErrNotMocked
+}
+
+// UnlockStaffAccount is not supported by MockDB
+func (m *MockDB) UnlockStaffAccount(ctx context.Context, landlordEmail string, staffId int) error {
+	return ErrNotMocked
+}
+
+// GetLeasesByLandlordEmail is not supported by MockDB
+func (m *MockDB) GetLeasesByLandlordEmail(ctx context.Context, landlordEmail string) ([]db.LandlordLease, error) {
+	return nil, ErrNotMocked
+}
+
+// OfferLeaseRenewal is not supported by MockDB
+func (m *MockDB) OfferLeaseRenewal(ctx context.Context, leaseId, termMonths int, monthlyRent string) error {
+	return ErrNotMocked
+}
+
+// GetOpenRenewalOffer is not supported by MockDB
+func (m *MockDB) GetOpenRenewalOffer(ctx context.Context, leaseId int) (db.LeaseRenewal, error) {
+	return db.LeaseRenewal{}, ErrNotMocked
+}
+
+// RespondToRenewalOffer is not supported by MockDB
+func (m *MockDB) RespondToRenewalOffer(ctx context.Context, leaseId int, accept bool) error {
+	return ErrNotMocked
+}
+
+// EndLease is not supported by MockDB
+func (m *MockDB) EndLease(ctx context.Context, leaseId int) error {
+	return ErrNotMocked
+}
+
+// GenerateRentCharges is not supported by MockDB
+func (m *MockDB) GenerateRentCharges(ctx context.Context, tenantId int) error {
+	return ErrNotMocked
+}
+
+// RecordRentPayment is not supported by MockDB
+func (m *MockDB) RecordRentPayment(ctx context.Context, tenantId int, amount, paidOn, method, reference string) error {
+	return ErrNotMocked
+}
+
+// GetRentLedgerByTenantId is not supported by MockDB
+func (m *MockDB) GetRentLedgerByTenantId(ctx context.Context, tenantId int) ([]db.RentLedgerEntry, error) {
+	return nil, ErrNotMocked
+}
+
+// BeginTwoFactorSetup is not supported by MockDB
+func (m *MockDB) BeginTwoFactorSetup(ctx context.Context, userType, userKey string) (string, error) {
+	return "", ErrNotMocked
+}
+
+// ConfirmTwoFactorSetup is not supported by MockDB
+func (m *MockDB) ConfirmTwoFactorSetup(ctx context.Context, userType, userKey, code string) ([]string, error) {
+	return nil, ErrNotMocked
+}
+
+// DisableTwoFactor is not supported by MockDB
+func (m *MockDB) DisableTwoFactor(ctx context.Context, userType, userKey, code string) error {
+	return ErrNotMocked
+}
+
+// StartTwoFactorLogin is not supported by MockDB
+func (m *MockDB) StartTwoFactorLogin(ctx context.Context, userType, userKey string, rememberMe bool) (string, error) {
+	return "", ErrNotMocked
+}
+
+// CompleteTwoFactorLogin is not supported by MockDB
+func (m *MockDB) CompleteTwoFactorLogin(ctx context.Context, userType, token, code string) (string, bool, error) {
+	return "", false, ErrNotMocked
+}
+
+// ExportDataSubject is not supported by MockDB
+func (m *MockDB) ExportDataSubject(ctx context.Context, email string) (db.SubjectExport, error) {
+	return db.SubjectExport{}, ErrNotMocked
+}
+
+// EraseDataSubject is not supported by MockDB
+func (m *MockDB) EraseDataSubject(ctx context.Context, email string) (db.SubjectErasure, error) {
+	return db.SubjectErasure{}, ErrNotMocked
+}
+
+// ApplyRetention is not supported by MockDB
+func (m *MockDB) ApplyRetention(ctx context.Context, policy config.RetentionPolicy, dryRun bool) ([]db.RetentionResult, error) {
+	return nil, ErrNotMocked
+}
+
+// ReencryptAll is not supported by MockDB
+func (m *MockDB) ReencryptAll(ctx context.Context, batchSize int) error {
+	return ErrNotMocked
+}
@@ -1,19 +1,28 @@
 package testutil
 
 import (
+	"context"
 	"html/template"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/handlers"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/middleware"
+	"github.com/Bevs-n-Devs/lilyshiddenparadise/utils"
 )
 
 // TestEnv holds the test environment
 type TestEnv struct {
 	DB        *MockDB
+	Handlers  *handlers.Server
+	Routes    http.Handler
 	Templates *template.Template
 	Server    *httptest.Server
 }
@@ -21,24 +30,70 @@ type TestEnv struct {
 // Global test environment that can be used across tests
 var TestEnvironment TestEnv
 
+// testMasterKey encrypts the mock database's data when the environment has no encryption keys
+const testMasterKey = "test-master-key-0123456789abcdef"
+
 // SetupTestMain initializes the test environment
 func SetupTestMain() {
+	// Setting up encryption logs, which blocks until something reads the logs
+	go logs.LogProcessor()
+
+	// The mock database encrypts data like the real one, so it needs an encryption key
+	if os.Getenv("MASTER_KEY") == "" && os.Getenv("ENCRYPTION_KEYS") == "" {
+		os.Setenv("MASTER_KEY", testMasterKey)
+	}
+	utils.InitEncryption()
+
 	// Create a mock database
 	mockDB := NewMockDB()
 
-	// Set up test landlord
-	mockDB.CreateNewLandlord("test@example.com", "password123")
+	// Set up test landlord, with a property and a room for tenants
+	mockDB.CreateNewLandlord(context.Background(), "test@example.com", "password123")
+	mockDB.CreateProperty(context.Background(), "test@example.com", "Test House", "1 Test Street")
+	mockDB.CreateRoom(context.Background(), "test@example.com", 1, "1", "Room 1", "Single Room", 2, "", "1000", "USD")
 
-	// Set up test tenant
-	mockDB.CreateNewTenant("tenant@example.com", "password123", "Single Room", "2025-01-01", "1", "1000", "USD")
+	// Set up test tenant, who applied for the property before moving in
+	saveTestApplication(mockDB, "Tenant Doe", "tenant@example.com")
+	mockDB.UpdateTenantApplicationStatus(context.Background(), "1", "accepted")
+	mockDB.CreateNewTenant(context.Background(), "test@example.com", 1, "tenant@example.com", "password123", "2025-01-01", "1", "1000", "USD")
 
 	// Set up test tenant application
-	mockDB.SaveTenantApplicationForm(
-		"John Doe",
+	saveTestApplication(mockDB, "John Doe", "applicant@example.com")
+
+	// Load templates
+	templatesDir := filepath.Join("..", "templates")
+	templates, err := template.ParseGlob(filepath.Join(templatesDir, "*.html"))
+	if err != nil {
+		// If templates can't be loaded, create a minimal template for testing
+		templates = template.New("test")
+		template.Must(templates.New("home.html").Parse("Home Page"))
+		template.Must(templates.New("landlord_dashboard.html").Parse("Landlord Dashboard"))
+		template.Must(templates.New("tenant_dashboard.html").Parse("Tenant Dashboard"))
+		template.Must(templates.New("login_landlord.html").Parse("Landlord Login"))
+		template.Must(templates.New("login_tenant.html").Parse("Tenant Login"))
+	}
+
+	// Set up the global handlers.Templates variable
+	handlers.Templates = templates
+
+	// Create the test environment
+	TestEnvironment = TestEnv{
+		DB:        mockDB,
+		Handlers:  handlers.NewServer(mockDB),
+		Templates: templates,
+	}
+	TestEnvironment.Routes = TestEnvironment.Handlers.Routes()
+}
+
+// saveTestApplication saves an application to the test landlord's property
+func saveTestApplication(mockDB *MockDB, fullName, email string) {
+	mockDB.SaveTenantApplicationForm(context.Background(),
+		1,
+		fullName,
 		"1990-01-01",
 		"AB123456",
 		"1234567890",
-		"applicant@example.com",
+		email,
 		"Software Engineer",
 		"Tech Corp",
 		"0987654321",
@@ -60,28 +115,17 @@ func SetupTestMain() {
 		"No",
 		"",
 	)
+}
 
-	// Load templates
-	templatesDir := filepath.Join("..", "templates")
-	templates, err := template.ParseGlob(filepath.Join(templatesDir, "*.html"))
-	if err != nil {
-		// If templates can't be loaded, create a minimal template for testing
-		templates = template.New("test")
-		template.Must(templates.New("home.html").Parse("Home Page"))
-		template.Must(templates.New("landlord_dashboard.html").Parse("Landlord Dashboard"))
-		template.Must(templates.New("tenant_dashboard.html").Parse("Tenant Dashboard"))
-		template.Must(templates.New("login_landlord.html").Parse("Landlord Login"))
-		template.Must(templates.New("login_tenant.html").Parse("Tenant Login"))
-	}
-
-	// Set up the global handlers.Templates variable
-	handlers.Templates = templates
+// AddTestApplication saves a pending application to the test landlord's property, for tests that decide it.
+// It returns the ID of the application.
+func AddTestApplication(fullName, email string) string {
+	mockDB := TestEnvironment.DB
+	saveTestApplication(mockDB, fullName, email)
 
-	// Create the test environment
-	TestEnvironment = TestEnv{
-		DB:        mockDB,
-		Templates: templates,
-	}
+	mockDB.mu.RLock()
+	defer mockDB.mu.RUnlock()
+	return strconv.Itoa(mockDB.nextTenantAppID - 1)
 }
 
 // TeardownTestMain cleans up the test environment
@@ -108,6 +152,44 @@ func ExecuteTestRequest(t *testing.T, handler http.HandlerFunc, req *http.Reques
 	return rr
 }
 
+// LandlordRequest creates a request from a landlord who is logged in. It starts a session in the mock database and
+// sends its cookies, and a form is sent with the session's CSRF token like the dashboard's forms.
+func LandlordRequest(method, target string, form url.Values, landlordEmail string) *http.Request {
+	sessionToken, csrfToken, _, _ := TestEnvironment.DB.StartLandlordSession(context.Background(), landlordEmail, false)
+	return sessionRequest(method, target, form, sessionToken, csrfToken)
+}
+
+// TenantRequest creates a request from a tenant who is logged in, in the same way as LandlordRequest.
+func TenantRequest(method, target string, form url.Values, tenantEmail string) *http.Request {
+	sessionToken, csrfToken, _, _ := TestEnvironment.DB.StartTenantSession(context.Background(), utils.HashData(tenantEmail), false)
+	return sessionRequest(method, target, form, sessionToken, csrfToken)
+}
+
+// sessionRequest creates a request with session cookies, and a form with the CSRF token if form is not nil
+func sessionRequest(method, target string, form url.Values, sessionToken, csrfToken string) *http.Request {
+	var req *http.Request
+	if form != nil {
+		values := url.Values{middleware.CSRFFormField: {csrfToken}}
+		for key, value := range form {
+			values[key] = value
+		}
+		req = httptest.NewRequest(method, target, strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, target, nil)
+	}
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: sessionToken})
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrfToken})
+	return req
+}
+
+// ServeTestRequest sends a request through the app's routes and middleware, and returns the response
+func ServeTestRequest(req *http.Request) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	TestEnvironment.Routes.ServeHTTP(rr, req)
+	return rr
+}
+
 // InitTestEnv initializes the test environment for a single test
 func InitTestEnv() {
 	// Set up test environment if it hasn't been initialized
@@ -123,7 +205,7 @@ func TestMainWrapper(m *testing.M) {
 		InitTestEnv()
 		return
 	}
-	
+
 	// Set up test environment
 	SetupTestMain()
 
@@ -1,8 +1,13 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/env"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
@@ -12,15 +17,30 @@ const (
 	logInfo = 1
 	logWarn = 2
 	logErr  = 3
+
+	encryptionKeySize = 32       // AES-256
+	masterKeyID       = "master" // the key ID given to MASTER_KEY when it is used to encrypt new data
+
+	totpPeriod        = 30      // seconds each TOTP code is valid for (RFC 6238)
+	totpDigits        = 6       // length of each TOTP code
+	totpModulus       = 1000000 // 10^totpDigits
+	totpSecretSize    = 20      // bytes of TOTP secret, the size of an HMAC-SHA1 key
+	totpSkew          = 1       // codes from this many periods either side of now are accepted, to allow for clock drift
+	recoveryCodeCount = 10      // number of one-time recovery codes given when two-factor authentication is turned on
 )
 
 var (
 	masterKeyStr string // get the MASTER_KEY string from envrionment variable
 	masterKey    []byte // convert the MASTER_KEY into 32 bytes for encrytion & decryption process
+
+	encryptionKeys = map[string][]byte{} // every key that can decrypt data, by key ID
+	activeKeyID    string                // the ID of the key new data is encrypted with
+
+	signingKey []byte // the HMAC key used to sign tokens sent out in links, e.g. landlord invitations
 )
 
 func getMasterKeyStr() error {
-	if os.Getenv("MASTER_KEY") == "" {
+	if os.Getenv("MASTER_KEY") == "" && os.Getenv("ENCRYPTION_KEYS") == "" {
 		logs.Logs(logWarn, "Could not get MASTER_KEY from hosting platform. Loading from .env file...")
 		err := env.LoadEnv("env/.env")
 		if err != nil {
@@ -30,12 +50,102 @@ func getMasterKeyStr() error {
 	}
 
 	masterKeyStr = os.Getenv("MASTER_KEY")
-	if masterKeyStr == "" {
+	if masterKeyStr == "" && os.Getenv("ENCRYPTION_KEYS") == "" {
 		logs.Logs(logErr, "MASTER_KEY is empty!")
 		return fmt.Errorf("MASTER_KEY is empty")
 	}
 
-	masterKey = []byte(masterKeyStr)[:32]
+	masterKey = nil
+	if masterKeyStr != "" {
+		if len(masterKeyStr) < encryptionKeySize {
+			logs.Logs(logErr, "MASTER_KEY is too short!")
+			return fmt.Errorf("MASTER_KEY must be at least %d characters", encryptionKeySize)
+		}
+		masterKey = []byte(masterKeyStr)[:encryptionKeySize]
+	}
+	return nil
+}
+
+/*
+loadEncryptionKeys loads the keys used to encrypt and decrypt data.
+
+MASTER_KEY is the original key. Data encrypted before key IDs were stored can only be decrypted with it,
+so it stays loaded until every column has been re-encrypted under a newer key.
+
+ENCRYPTION_KEYS lists the other keys as comma separated ID:key pairs, where each key is 32 bytes encoded
+in base64 (e.g. "2026-10:3q2+7w...=,2027-04:q83vEj...="). Every listed key can decrypt data, so old keys
+stay listed while the re-encryption job moves data onto the new key.
+
+ENCRYPTION_KEY_ID picks the key used to encrypt new data. It defaults to the last key in ENCRYPTION_KEYS,
+or MASTER_KEY if no other keys are set.
+*/
+func loadEncryptionKeys() error {
+	keys := map[string][]byte{}
+	var lastKeyID string
+
+	if masterKey != nil {
+		keys[masterKeyID] = masterKey
+		lastKeyID = masterKeyID
+	}
+
+	for _, pair := range strings.Split(os.Getenv("ENCRYPTION_KEYS"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		keyID, encodedKey, found := strings.Cut(pair, ":")
+		if !found || keyID == "" || len(keyID) > 255 {
+			return fmt.Errorf("invalid ENCRYPTION_KEYS entry, expected ID:base64key")
+		}
+		if _, exists := keys[keyID]; exists {
+			return fmt.Errorf("encryption key %s is listed more than once", keyID)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return fmt.Errorf("encryption key %s is not valid base64: %s", keyID, err.Error())
+		}
+		if len(key) != encryptionKeySize {
+			return fmt.Errorf("encryption key %s must be %d bytes, got %d", keyID, encryptionKeySize, len(key))
+		}
+
+		keys[keyID] = key
+		lastKeyID = keyID
+	}
+
+	keyID := os.Getenv("ENCRYPTION_KEY_ID")
+	if keyID == "" {
+		keyID = lastKeyID
+	}
+	if _, exists := keys[keyID]; !exists {
+		return fmt.Errorf("ENCRYPTION_KEY_ID %s is not one of the loaded encryption keys", keyID)
+	}
+
+	encryptionKeys = keys
+	activeKeyID = keyID
+	return nil
+}
+
+/*
+loadSigningKey loads the key used to sign tokens sent out in links.
+
+SIGNING_KEY sets the key, which must be at least 32 characters. If it is not set the key is derived from the
+active encryption key, so links signed before the encryption key is rotated stop working once it has been.
+*/
+func loadSigningKey() error {
+	signingKeyStr := os.Getenv("SIGNING_KEY")
+	if signingKeyStr != "" {
+		if len(signingKeyStr) < encryptionKeySize {
+			return fmt.Errorf("SIGNING_KEY must be at least %d characters", encryptionKeySize)
+		}
+		signingKey = []byte(signingKeyStr)
+		return nil
+	}
+
+	mac := hmac.New(sha256.New, encryptionKeys[activeKeyID])
+	mac.Write([]byte("lhp signed tokens"))
+	signingKey = mac.Sum(nil)
 	return nil
 }
 
@@ -46,6 +156,33 @@ func InitEncryption() error {
 		logs.Logs(logErr, fmt.Sprintf("Error getting master key: %s", err.Error()))
 		return err
 	}
-	logs.Logs(logInfo, "Encryption functions successfully initialized.")
+
+	err = loadEncryptionKeys()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error loading encryption keys: %s", err.Error()))
+		return err
+	}
+
+	err = loadSigningKey()
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error loading signing key: %s", err.Error()))
+		return err
+	}
+	logs.Logs(logInfo, fmt.Sprintf("Encryption functions successfully initialized. Encrypting new data with key %s.", activeKeyID))
+	return nil
+}
+
+/*
+EncryptionReady checks InitEncryption has loaded the key new data is encrypted with, for the app's readiness check.
+Without it nothing can be encrypted or decrypted.
+
+Returns:
+
+- error: An error if no encryption key has been loaded.
+*/
+func EncryptionReady() error {
+	if _, exists := encryptionKeys[activeKeyID]; !exists {
+		return errors.New("encryption keys are not loaded")
+	}
 	return nil
 }
@@ -3,15 +3,23 @@ package utils
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha1"
 	"crypto/sha256"
+	"encoding/base32"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
@@ -60,6 +68,23 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+/*
+IsBcryptHash reports whether a stored password hash was made by HashedPassword,
+rather than being a legacy unsalted SHA-256 hash from HashData.
+
+Arguments:
+
+- hash: The stored password hash.
+
+Returns:
+
+- bool: True if the hash is a bcrypt hash.
+*/
+func IsBcryptHash(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
 /*
 HashData takes a string and returns a SHA-256 hash of the string.
 
@@ -84,27 +109,35 @@ func VerifyHash(input string, storedHash string) bool {
 	return input == storedHash // Compare with the stored hash
 }
 
+// the versioned envelope every encrypted value is stored in:
+// magic ("LHP") | version | key ID length | key ID | nonce | ciphertext.
+// The header is authenticated along with the ciphertext, so the key ID cannot be swapped.
+const (
+	envelopeMagic   = "LHP"
+	envelopeVersion = 1
+)
+
 /*
 Encrypts any identifiable data the user enters.
-Will need the MASTER_KEY from envrionment variable to work.
+Will need the MASTER_KEY (or ENCRYPTION_KEYS) from envrionment variable to work.
 
 We need to convert the data into bytes to encrypt it.
+The data is encrypted with the active key and stored in a versioned envelope recording the key ID,
+so the key can be rotated later without losing the data.
 
 Return a list of bytes or an error.
 */
 func Encrypt(data []byte) ([]byte, error) {
-	// create a new AES cipher block using the master key
-	block, err := aes.NewCipher(masterKey)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error creating AES cipher block: %s", err.Error()))
-		return nil, err // Return error if key is invalid
+	key, exists := encryptionKeys[activeKeyID]
+	if !exists {
+		logs.Logs(logErr, "Encryption keys have not been initialised")
+		return nil, errors.New("encryption keys have not been initialised")
 	}
 
-	// Create a GCM (Galois Counter Mode) cipher from the AES block
-	gcm, err := cipher.NewGCM(block)
+	// Create a GCM (Galois Counter Mode) cipher from the active key
+	gcm, err := newGCM(key)
 	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error creating GCM cipher: %s", err.Error()))
-		return nil, err // Return error if GCM initialization fails
+		return nil, err
 	}
 
 	// Generate a nonce (unique number used only once) of required size
@@ -115,57 +148,173 @@ func Encrypt(data []byte) ([]byte, error) {
 		return nil, err // Return error if random generation fails
 	}
 
-	// Encrypt the data using AES-GCM
-	// Seal appends encrypted data to nonce (authentication tag included)
-	ciphertext := gcm.Seal(nil, nonce, data, nil)
+	// Encrypt the data using AES-GCM, authenticating the envelope header with it
+	header := envelopeHeader(activeKeyID)
+	ciphertext := gcm.Seal(nil, nonce, data, header)
 
-	// Return the concatenated nonce + ciphertext
+	// Return the concatenated header + nonce + ciphertext
 	logs.Logs(logInfo, "Data encrypted successfully")
-	return append(nonce, ciphertext...), nil
+	envelope := make([]byte, 0, len(header)+len(nonce)+len(ciphertext))
+	envelope = append(envelope, header...)
+	envelope = append(envelope, nonce...)
+	return append(envelope, ciphertext...), nil
 }
 
 /*
-Decrypt decrypts the given encrypted data using AES-GCM with the master key.
-It expects the data to contain the nonce followed by the ciphertext.
+Decrypt decrypts the given encrypted data using AES-GCM.
+It expects a versioned envelope naming the key the data was encrypted with, or, for data
+encrypted before key IDs were stored, the nonce followed by the ciphertext under the master key.
 
 Parameters:
 
-	data ([]byte): The encrypted data containing the nonce and ciphertext.
+	data ([]byte): The encrypted data.
 
 Returns:
 
 	([]byte): The decrypted plaintext if successful.
-	(error): An error if the decryption process fails, such as an invalid key or corrupted data.
+	(error): An error if the decryption process fails, such as an unknown key or corrupted data.
 */
 func Decrypt(data []byte) ([]byte, error) {
-	// Create a new AES cipher block using the same master key
-	block, err := aes.NewCipher(masterKey)
+	keyID, nonceAndCiphertext, ok := parseEnvelope(data)
+	if ok {
+		key, exists := encryptionKeys[keyID]
+		if exists {
+			plaintext, err := openGCM(key, nonceAndCiphertext, data[:len(data)-len(nonceAndCiphertext)])
+			if err == nil {
+				logs.Logs(logInfo, "Data decrypted successfully")
+				return plaintext, nil
+			}
+			if masterKey == nil {
+				logs.Logs(logErr, fmt.Sprintf("Error decrypting data: %s", err.Error()))
+				return nil, err
+			}
+		} else if masterKey == nil {
+			logs.Logs(logErr, fmt.Sprintf("Error decrypting data: unknown encryption key %s", keyID))
+			return nil, fmt.Errorf("unknown encryption key %s", keyID)
+		}
+		// a legacy nonce can start with the envelope magic by chance, so fall back to the master key
+	}
+
+	if masterKey == nil {
+		logs.Logs(logErr, "Error decrypting data: data has no key ID and MASTER_KEY is not set")
+		return nil, errors.New("data has no key ID and MASTER_KEY is not set")
+	}
+
+	plaintext, err := openGCM(masterKey, data, nil)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error decrypting data: %s", err.Error()))
+		return nil, err // Return error if decryption fails
+	}
+
+	// Return the decrypted plaintext
+	logs.Logs(logInfo, "Data decrypted successfully")
+	return plaintext, nil
+}
+
+/*
+ActiveKeyID returns the ID of the key new data is encrypted with.
+*/
+func ActiveKeyID() string {
+	return activeKeyID
+}
+
+/*
+NeedsReencryption reports whether encrypted data was encrypted with a key other than the active key,
+including data encrypted with the master key before key IDs were stored.
+
+Arguments:
+
+- data: The encrypted data.
+
+Returns:
+
+- bool: True if the data should be re-encrypted with the active key.
+*/
+func NeedsReencryption(data []byte) bool {
+	keyID, nonceAndCiphertext, ok := parseEnvelope(data)
+	if !ok || keyID != activeKeyID {
+		return true
+	}
+	// make sure this is a real envelope and not a legacy nonce that happens to look like one
+	_, err := openGCM(encryptionKeys[activeKeyID], nonceAndCiphertext, data[:len(data)-len(nonceAndCiphertext)])
+	return err != nil
+}
+
+/*
+Reencrypt decrypts data and encrypts it again with the active key.
+
+Arguments:
+
+- data: The encrypted data.
+
+Returns:
+
+- []byte: The data encrypted with the active key.
+
+- error: An error if the data cannot be decrypted or encrypted.
+*/
+func Reencrypt(data []byte) ([]byte, error) {
+	plaintext, err := Decrypt(data)
+	if err != nil {
+		return nil, err
+	}
+	return Encrypt(plaintext)
+}
+
+// envelopeHeader builds the header of an envelope encrypted with the given key.
+func envelopeHeader(keyID string) []byte {
+	header := []byte(envelopeMagic)
+	header = append(header, envelopeVersion, byte(len(keyID)))
+	return append(header, keyID...)
+}
+
+// parseEnvelope splits an envelope into its key ID and the nonce and ciphertext that follow the header.
+func parseEnvelope(data []byte) (string, []byte, bool) {
+	prefixLen := len(envelopeMagic) + 2
+	if len(data) < prefixLen || string(data[:len(envelopeMagic)]) != envelopeMagic || data[len(envelopeMagic)] != envelopeVersion {
+		return "", nil, false
+	}
+	keyIDLen := int(data[len(envelopeMagic)+1])
+	if keyIDLen == 0 || len(data) < prefixLen+keyIDLen {
+		return "", nil, false
+	}
+	return string(data[prefixLen : prefixLen+keyIDLen]), data[prefixLen+keyIDLen:], true
+}
+
+// newGCM creates an AES-GCM cipher from a key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	// create a new AES cipher block using the key
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Error creating AES cipher block: %s", err.Error()))
 		return nil, err // Return error if key is invalid
 	}
 
-	// Create a GCM cipher from the AES block
+	// Create a GCM (Galois Counter Mode) cipher from the AES block
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
 		logs.Logs(logErr, fmt.Sprintf("Error creating GCM cipher: %s", err.Error()))
 		return nil, err // Return error if GCM initialization fails
 	}
+	return gcm, nil
+}
+
+// openGCM decrypts a nonce followed by its ciphertext, checking the additional data it was sealed with.
+func openGCM(key, nonceAndCiphertext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
 
 	// Extract the nonce from the start of the encrypted data
 	nonceSize := gcm.NonceSize()
-	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-
-	// Decrypt the ciphertext using AES-GCM
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		logs.Logs(logErr, fmt.Sprintf("Error decrypting data: %s", err.Error()))
-		return nil, err // Return error if decryption fails
+	if len(nonceAndCiphertext) < nonceSize+gcm.Overhead() {
+		return nil, errors.New("encrypted data is too short")
 	}
+	nonce, ciphertext := nonceAndCiphertext[:nonceSize], nonceAndCiphertext[nonceSize:]
 
-	// Return the decrypted plaintext
-	logs.Logs(logInfo, "Data decrypted successfully")
-	return plaintext, nil
+	// Decrypt the ciphertext using AES-GCM
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
 }
 
 /*
@@ -428,3 +577,577 @@ func GenerateTenantUsernamePassportNumberAndPassword(email, passportNumber strin
 	password := fmt.Sprintf("%s%s", passwordHash, passportNumber)
 	return username, password, nil
 }
+
+/*
+RentDueDates returns every monthly rent due date from the first due date up to and including the until date.
+
+The first due date recurs on the same day every month. When a month is shorter than that day
+(e.g. the 31st in February) the due date falls on the last day of that month instead.
+
+Arguments:
+
+- firstDueDate: The first date rent is due, in the format YYYY-MM-DD.
+
+- until: The last date to generate due dates for.
+
+Returns:
+
+- []time.Time: The due dates in ascending order.
+
+- error: An error if the first due date cannot be parsed.
+*/
+func RentDueDates(firstDueDate string, until time.Time) ([]time.Time, error) {
+	layout := "2006-01-02"
+	first, err := time.Parse(layout, firstDueDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rent due date: %s", firstDueDate)
+	}
+
+	var dueDates []time.Time
+	for month := 0; ; month++ {
+		dueDate := AddMonths(first, month)
+		if dueDate.After(until) {
+			break
+		}
+		dueDates = append(dueDates, dueDate)
+	}
+	return dueDates, nil
+}
+
+/*
+ParseAmount converts a money amount entered on a form (e.g. "1200" or "1200.50") into
+the smallest currency unit (e.g. cents) so balances can be calculated without rounding errors.
+
+Returns:
+
+- int64: The amount in the smallest currency unit.
+
+- error: An error if the amount is empty, negative or has more than two decimal places.
+*/
+func ParseAmount(amount string) (int64, error) {
+	var amountRegex = regexp.MustCompile(`^(\d+)(\.(\d{1,2}))?$`)
+	parts := amountRegex.FindStringSubmatch(strings.TrimSpace(amount))
+	if parts == nil {
+		return 0, fmt.Errorf("invalid amount: %s", amount)
+	}
+
+	whole, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount: %s", amount)
+	}
+
+	fraction := parts[3]
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+	cents, _ := strconv.ParseInt(fraction, 10, 64)
+	return whole*100 + cents, nil
+}
+
+/*
+FormatAmount converts an amount in the smallest currency unit back into a display string with two decimal places.
+*/
+func FormatAmount(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+/*
+ParseLeaseTerms converts the lease fields from the landlord forms into whole numbers.
+Empty fields fall back to a 12 month term, no break clause and a 30 day notice period.
+
+Arguments:
+
+- leaseTerm: The length of the lease in months.
+
+- breakClause: The number of months after the start date when either party may end the lease early. 0 means no break clause.
+
+- noticePeriod: The number of days notice either party must give to end the lease.
+
+Returns:
+
+- int: The lease term in months.
+
+- int: The break clause in months.
+
+- int: The notice period in days.
+
+- error: An error if any of the fields are not valid numbers or the break clause is not within the lease term.
+*/
+func ParseLeaseTerms(leaseTerm, breakClause, noticePeriod string) (int, int, int, error) {
+	termMonths, breakClauseMonths, noticeDays := 12, 0, 30
+
+	var err error
+	if leaseTerm != "" {
+		termMonths, err = strconv.Atoi(leaseTerm)
+		if err != nil || termMonths <= 0 {
+			return 0, 0, 0, fmt.Errorf("invalid lease term: %s", leaseTerm)
+		}
+	}
+	if breakClause != "" {
+		breakClauseMonths, err = strconv.Atoi(breakClause)
+		if err != nil || breakClauseMonths < 0 || breakClauseMonths >= termMonths {
+			return 0, 0, 0, fmt.Errorf("invalid break clause: %s", breakClause)
+		}
+	}
+	if noticePeriod != "" {
+		noticeDays, err = strconv.Atoi(noticePeriod)
+		if err != nil || noticeDays < 0 {
+			return 0, 0, 0, fmt.Errorf("invalid notice period: %s", noticePeriod)
+		}
+	}
+	return termMonths, breakClauseMonths, noticeDays, nil
+}
+
+/*
+AddMonths adds a number of months to a date, keeping the day of the month where possible.
+When the target month is shorter (e.g. 31st January + 1 month) the last day of that month is used.
+*/
+func AddMonths(date time.Time, months int) time.Time {
+	monthStart := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, months, 0)
+	lastDay := monthStart.AddDate(0, 1, -1).Day()
+	day := date.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(monthStart.Year(), monthStart.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+/*
+ValidateLeaseNotice checks that a notice to quit respects the lease terms.
+
+The move out date must leave at least the notice period after the notice date. Moving out before the
+end of the lease is only allowed when the lease has a break clause and the move out date is on or after it.
+
+Arguments:
+
+- startDate: The date the lease started.
+
+- endDate: The date the lease ends.
+
+- breakClauseMonths: The number of months after the start date when the lease may be ended early. 0 means no break clause.
+
+- noticeDays: The number of days notice that must be given.
+
+- noticeDate: The date the notice is given.
+
+- moveOutDate: The date the tenant will move out.
+
+Returns:
+
+- error: An error describing why the notice is not valid.
+*/
+func ValidateLeaseNotice(startDate, endDate time.Time, breakClauseMonths, noticeDays int, noticeDate, moveOutDate time.Time) error {
+	if moveOutDate.Before(noticeDate.AddDate(0, 0, noticeDays)) {
+		return fmt.Errorf("move out date must be at least %d days after the notice date", noticeDays)
+	}
+	if moveOutDate.Before(endDate) {
+		if breakClauseMonths == 0 {
+			return errors.New("this lease has no break clause, so it cannot end before the end date")
+		}
+		breakDate := AddMonths(startDate, breakClauseMonths)
+		if moveOutDate.Before(breakDate) {
+			return fmt.Errorf("the break clause cannot be used before %s", breakDate.Format("2006-01-02"))
+		}
+	}
+	return nil
+}
+
+/*
+ParseRoomDetails validates the room fields from the landlord inventory form.
+An empty capacity falls back to a room for one person and an empty default rent to 0.
+
+Arguments:
+
+- name: The name or number of the room.
+
+- roomType: The type of room (e.g. Single, Double).
+
+- capacity: The number of tenants the room can hold.
+
+- defaultRent: The monthly rent usually charged for the room.
+
+Returns:
+
+- int: The capacity of the room.
+
+- string: The default rent, formatted with two decimal places.
+
+- error: An error if a required field is missing or a number is not valid.
+*/
+func ParseRoomDetails(name, roomType, capacity, defaultRent string) (int, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return 0, "", errors.New("room name is required")
+	}
+	if strings.TrimSpace(roomType) == "" {
+		return 0, "", errors.New("room type is required")
+	}
+
+	roomCapacity := 1
+	var err error
+	if capacity != "" {
+		roomCapacity, err = strconv.Atoi(capacity)
+		if err != nil || roomCapacity <= 0 {
+			return 0, "", fmt.Errorf("invalid capacity: %s", capacity)
+		}
+	}
+
+	rent := 0.0
+	if defaultRent != "" {
+		rent, err = strconv.ParseFloat(defaultRent, 64)
+		if err != nil || rent < 0 {
+			return 0, "", fmt.Errorf("invalid default rent: %s", defaultRent)
+		}
+	}
+	return roomCapacity, strconv.FormatFloat(rent, 'f', 2, 64), nil
+}
+
+// maintenance ticket categories, priorities and statuses, in the order a ticket moves through them
+var (
+	ticketCategories = []string{"plumbing", "electrical", "heating", "appliances", "structural", "pests", "other"}
+	ticketPriorities = []string{"low", "medium", "high", "urgent"}
+	ticketStatuses   = []string{"open", "scheduled", "in_progress", "resolved"}
+)
+
+/*
+ValidateMaintenanceTicket checks the fields of a new maintenance ticket from the tenant dashboard.
+
+Arguments:
+
+- category: The kind of repair needed (e.g. plumbing, electrical).
+
+- priority: How urgent the repair is (low, medium, high or urgent).
+
+- description: The tenant's description of the problem.
+
+Returns:
+
+- error: An error describing the first field that is not valid.
+*/
+func ValidateMaintenanceTicket(category, priority, description string) error {
+	if !slices.Contains(ticketCategories, category) {
+		return fmt.Errorf("invalid category: %s", category)
+	}
+	err := ValidateTicketPriority(priority)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(description) == "" {
+		return errors.New("description is required")
+	}
+	return nil
+}
+
+/*
+ValidateTicketPriority checks the priority of a maintenance ticket is one of low, medium, high or urgent.
+
+Arguments:
+
+- priority: The priority to check.
+
+Returns:
+
+- error: An error if the priority is not valid.
+*/
+func ValidateTicketPriority(priority string) error {
+	if !slices.Contains(ticketPriorities, priority) {
+		return fmt.Errorf("invalid priority: %s", priority)
+	}
+	return nil
+}
+
+/*
+ValidateTicketTransition checks that a maintenance ticket can move from one status to another.
+Tickets only move forward through open, scheduled, in progress and resolved, although a step may be skipped
+(e.g. a small repair can go straight from open to in progress). Keeping the same status is allowed so the
+landlord can reschedule a visit or add a note.
+
+Arguments:
+
+- from: The current status of the ticket.
+
+- to: The new status of the ticket.
+
+Returns:
+
+- error: An error if the new status is unknown or would move the ticket backwards.
+*/
+func ValidateTicketTransition(from, to string) error {
+	fromIndex := slices.Index(ticketStatuses, from)
+	toIndex := slices.Index(ticketStatuses, to)
+	if fromIndex == -1 || toIndex == -1 {
+		return fmt.Errorf("invalid ticket status change from %s to %s", from, to)
+	}
+	if from == "resolved" {
+		return errors.New("resolved tickets cannot be changed")
+	}
+	if toIndex < fromIndex {
+		return fmt.Errorf("a ticket cannot move back from %s to %s", from, to)
+	}
+	return nil
+}
+
+// the base32 alphabet used by authenticator apps, without padding
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+/*
+GenerateTOTPSecret creates a random secret for TOTP two-factor authentication (RFC 6238).
+
+Returns:
+
+- string: The secret encoded in base32, as expected by authenticator apps.
+
+- error: An error if random bytes cannot be generated.
+*/
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Error generating TOTP secret: %s", err.Error()))
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+/*
+TOTPCode returns the TOTP code for a secret at the given time, using HMAC-SHA1, 30 second periods and 6 digits,
+which is what authenticator apps use by default.
+
+Arguments:
+
+- secret: The base32 encoded TOTP secret.
+
+- t: The time to generate the code for.
+
+Returns:
+
+- string: The 6 digit code.
+
+- error: An error if the secret is not valid base32.
+*/
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return totpCodeAt(key, t.Unix()/totpPeriod), nil
+}
+
+/*
+ValidateTOTP checks a code entered by the user against a TOTP secret.
+Codes from the period either side of now are also accepted to allow for clock drift.
+
+The period the code matched is returned so the caller can store it and refuse the same code,
+or any earlier one, the next time. This stops a code that has been seen by someone else being replayed.
+
+Arguments:
+
+- secret: The base32 encoded TOTP secret.
+
+- code: The code entered by the user.
+
+- t: The time to check the code at, normally time.Now().
+
+- lastUsedStep: The period of the last code accepted for this secret, or 0 if none has been used yet.
+
+Returns:
+
+- int64: The period the code matched.
+
+- bool: True if the code is correct and newer than lastUsedStep.
+*/
+func ValidateTOTP(secret, code string, t time.Time, lastUsedStep int64) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		logs.Logs(logErr, fmt.Sprintf("Invalid TOTP secret: %s", err.Error()))
+		return 0, false
+	}
+
+	now := t.Unix() / totpPeriod
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		if hmac.Equal([]byte(totpCodeAt(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+/*
+TOTPURI builds the otpauth:// URI used to add a TOTP secret to an authenticator app,
+either by opening the link on a phone or by showing it as a QR code.
+
+Arguments:
+
+- issuer: The name of the service, shown in the authenticator app.
+
+- accountName: The account the secret belongs to, normally the user's email.
+
+- secret: The base32 encoded TOTP secret.
+
+Returns:
+
+- string: The otpauth:// URI.
+*/
+func TOTPURI(issuer, accountName, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(totpDigits))
+	query.Set("period", strconv.Itoa(totpPeriod))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+/*
+GenerateRecoveryCodes creates the one-time recovery codes a user can log in with if they lose their authenticator app.
+
+Returns:
+
+- []string: The recovery codes, in the form xxxxx-xxxxx.
+
+- error: An error if random bytes cannot be generated.
+*/
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		random := make([]byte, 10)
+		if _, err := rand.Read(random); err != nil {
+			logs.Logs(logErr, fmt.Sprintf("Error generating recovery code: %s", err.Error()))
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(random))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
+
+/*
+NormaliseRecoveryCode puts a recovery code entered by the user into the form it was generated in,
+so codes typed in capitals or without the dash still match.
+
+Arguments:
+
+- code: The recovery code entered by the user.
+
+Returns:
+
+- string: The code in the form xxxxx-xxxxx, or the trimmed input if it is not the right length.
+*/
+func NormaliseRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
+
+// decodeTOTPSecret decodes a base32 TOTP secret, ignoring case, spaces and padding as authenticator apps do.
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	return totpEncoding.DecodeString(strings.TrimRight(secret, "="))
+}
+
+// totpCodeAt calculates the HOTP value (RFC 4226) for one TOTP period.
+func totpCodeAt(key []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%totpModulus)
+}
+
+// errors returned by VerifySignedToken
+var (
+	ErrInvalidSignedToken = errors.New("token signature is not valid")
+	ErrExpiredSignedToken = errors.New("token has expired")
+)
+
+/*
+SignToken signs a value so it can be sent out in a link and checked when it comes back, without anything being looked up.
+The signature covers the purpose and expiry as well as the value, so a token signed for one purpose cannot be used for another
+and its expiry cannot be changed.
+
+Arguments:
+
+- purpose: What the token is for, e.g. "landlord-invitation".
+
+- value: The value to sign. It must not contain ".".
+
+- expiresAt: When the token stops being accepted.
+
+Returns:
+
+- string: The signed token, in the form value.expiry.signature.
+*/
+func SignToken(purpose, value string, expiresAt time.Time) string {
+	payload := value + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + tokenSignature(purpose, payload)
+}
+
+/*
+VerifySignedToken checks a token made by SignToken and returns the value that was signed.
+
+Arguments:
+
+- purpose: What the token must have been signed for.
+
+- token: The signed token.
+
+- now: The time to check the expiry against.
+
+Returns:
+
+- string: The signed value.
+
+- error: ErrInvalidSignedToken if the token was not signed with this purpose and key, or ErrExpiredSignedToken if it has expired.
+*/
+func VerifySignedToken(purpose, token string, now time.Time) (string, error) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return "", ErrInvalidSignedToken
+	}
+	expiry, signature, found := strings.Cut(signature, ".")
+	if !found {
+		return "", ErrInvalidSignedToken
+	}
+	payload += "." + expiry
+
+	if !hmac.Equal([]byte(signature), []byte(tokenSignature(purpose, payload))) {
+		return "", ErrInvalidSignedToken
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignedToken
+	}
+	if !now.Before(time.Unix(expiresAt, 0)) {
+		return "", ErrExpiredSignedToken
+	}
+
+	value, _, _ := strings.Cut(payload, ".")
+	return value, nil
+}
+
+// tokenSignature signs the payload of a token for one purpose with the signing key.
+func tokenSignature(purpose, payload string) string {
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write([]byte(purpose + "\n" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -1,7 +1,13 @@
 package utils_test
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/logs"
 	"github.com/Bevs-n-Devs/lilyshiddenparadise/testutil"
@@ -397,3 +403,721 @@ func TestValidateNewPassword(t *testing.T) {
 		})
 	}
 }
+
+func TestRentDueDates(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	// Test cases
+	testCases := []struct {
+		name          string
+		firstDueDate  string
+		until         time.Time
+		expectedDates []string
+		expectError   bool
+	}{
+		{
+			name:          "Monthly due dates up to today",
+			firstDueDate:  "2025-01-05",
+			until:         time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC),
+			expectedDates: []string{"2025-01-05", "2025-02-05", "2025-03-05"},
+		},
+		{
+			name:          "Due date on the until date is included",
+			firstDueDate:  "2025-01-05",
+			until:         time.Date(2025, 2, 5, 0, 0, 0, 0, time.UTC),
+			expectedDates: []string{"2025-01-05", "2025-02-05"},
+		},
+		{
+			name:          "Day clamped to the end of short months",
+			firstDueDate:  "2025-01-31",
+			until:         time.Date(2025, 4, 30, 0, 0, 0, 0, time.UTC),
+			expectedDates: []string{"2025-01-31", "2025-02-28", "2025-03-31", "2025-04-30"},
+		},
+		{
+			name:          "First due date in the future",
+			firstDueDate:  "2025-06-01",
+			until:         time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC),
+			expectedDates: nil,
+		},
+		{
+			name:         "Invalid due date",
+			firstDueDate: "1",
+			until:        time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC),
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dueDates, err := utils.RentDueDates(tc.firstDueDate, tc.until)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(dueDates) != len(tc.expectedDates) {
+				t.Fatalf("Expected %d due dates, got %d", len(tc.expectedDates), len(dueDates))
+			}
+			for i, dueDate := range dueDates {
+				if dueDate.Format("2006-01-02") != tc.expectedDates[i] {
+					t.Errorf("Expected due date %s, got %s", tc.expectedDates[i], dueDate.Format("2006-01-02"))
+				}
+			}
+		})
+	}
+}
+
+func TestParseAndFormatAmount(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		amount         string
+		expectedAmount int64
+		expectedString string
+		expectError    bool
+	}{
+		{
+			name:           "Whole amount",
+			amount:         "1200",
+			expectedAmount: 120000,
+			expectedString: "1200.00",
+		},
+		{
+			name:           "Amount with one decimal place",
+			amount:         "99.5",
+			expectedAmount: 9950,
+			expectedString: "99.50",
+		},
+		{
+			name:           "Amount with two decimal places",
+			amount:         " 0.07 ",
+			expectedAmount: 7,
+			expectedString: "0.07",
+		},
+		{
+			name:        "Negative amount",
+			amount:      "-10",
+			expectError: true,
+		},
+		{
+			name:        "Too many decimal places",
+			amount:      "10.123",
+			expectError: true,
+		},
+		{
+			name:        "Empty amount",
+			amount:      "",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			amount, err := utils.ParseAmount(tc.amount)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if amount != tc.expectedAmount {
+				t.Errorf("Expected amount %d, got %d", tc.expectedAmount, amount)
+			}
+			if utils.FormatAmount(amount) != tc.expectedString {
+				t.Errorf("Expected formatted amount %s, got %s", tc.expectedString, utils.FormatAmount(amount))
+			}
+		})
+	}
+
+	if utils.FormatAmount(-2550) != "-25.50" {
+		t.Errorf("Expected formatted amount -25.50, got %s", utils.FormatAmount(-2550))
+	}
+}
+
+func TestParseLeaseTerms(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	// Test cases
+	testCases := []struct {
+		name          string
+		leaseTerm     string
+		breakClause   string
+		noticePeriod  string
+		expectedTerm  int
+		expectedBreak int
+		expectedDays  int
+		expectError   bool
+	}{
+		{
+			name:          "Defaults when fields are empty",
+			expectedTerm:  12,
+			expectedBreak: 0,
+			expectedDays:  30,
+		},
+		{
+			name:          "Custom lease terms",
+			leaseTerm:     "24",
+			breakClause:   "6",
+			noticePeriod:  "60",
+			expectedTerm:  24,
+			expectedBreak: 6,
+			expectedDays:  60,
+		},
+		{
+			name:        "Break clause after the end of the lease",
+			leaseTerm:   "6",
+			breakClause: "6",
+			expectError: true,
+		},
+		{
+			name:        "Invalid lease term",
+			leaseTerm:   "twelve",
+			expectError: true,
+		},
+		{
+			name:         "Negative notice period",
+			noticePeriod: "-1",
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			term, breakClause, noticeDays, err := utils.ParseLeaseTerms(tc.leaseTerm, tc.breakClause, tc.noticePeriod)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if term != tc.expectedTerm || breakClause != tc.expectedBreak || noticeDays != tc.expectedDays {
+				t.Errorf("Expected %d/%d/%d, got %d/%d/%d", tc.expectedTerm, tc.expectedBreak, tc.expectedDays, term, breakClause, noticeDays)
+			}
+		})
+	}
+}
+
+func TestValidateLeaseNotice(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	date := func(value string) time.Time {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return parsed
+	}
+	startDate := date("2025-01-01")
+	endDate := date("2026-01-01")
+
+	// Test cases
+	testCases := []struct {
+		name              string
+		breakClauseMonths int
+		noticeDays        int
+		noticeDate        string
+		moveOutDate       string
+		expectError       bool
+	}{
+		{
+			name:        "Notice to leave at the end of the lease",
+			noticeDays:  30,
+			noticeDate:  "2025-11-15",
+			moveOutDate: "2026-01-01",
+		},
+		{
+			name:        "Not enough notice",
+			noticeDays:  30,
+			noticeDate:  "2025-12-15",
+			moveOutDate: "2026-01-01",
+			expectError: true,
+		},
+		{
+			name:        "Leaving early without a break clause",
+			noticeDays:  30,
+			noticeDate:  "2025-03-01",
+			moveOutDate: "2025-06-01",
+			expectError: true,
+		},
+		{
+			name:              "Leaving early using the break clause",
+			breakClauseMonths: 6,
+			noticeDays:        30,
+			noticeDate:        "2025-05-01",
+			moveOutDate:       "2025-07-01",
+		},
+		{
+			name:              "Leaving before the break clause",
+			breakClauseMonths: 6,
+			noticeDays:        30,
+			noticeDate:        "2025-03-01",
+			moveOutDate:       "2025-05-01",
+			expectError:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := utils.ValidateLeaseNotice(startDate, endDate, tc.breakClauseMonths, tc.noticeDays, date(tc.noticeDate), date(tc.moveOutDate))
+			if tc.expectError && err == nil {
+				t.Errorf("Expected error but got nil")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseRoomDetails(t *testing.T) {
+	// Initialize test environment
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	// Test cases
+	testCases := []struct {
+		name             string
+		roomName         string
+		roomType         string
+		capacity         string
+		defaultRent      string
+		expectedCapacity int
+		expectedRent     string
+		expectError      bool
+	}{
+		{
+			name:             "Defaults when capacity and rent are empty",
+			roomName:         "Room 1",
+			roomType:         "Single",
+			expectedCapacity: 1,
+			expectedRent:     "0.00",
+		},
+		{
+			name:             "Shared room with default rent",
+			roomName:         "Room 2",
+			roomType:         "Double",
+			capacity:         "2",
+			defaultRent:      "650.5",
+			expectedCapacity: 2,
+			expectedRent:     "650.50",
+		},
+		{
+			name:        "Missing room name",
+			roomType:    "Single",
+			expectError: true,
+		},
+		{
+			name:        "Missing room type",
+			roomName:    "Room 3",
+			expectError: true,
+		},
+		{
+			name:        "Zero capacity",
+			roomName:    "Room 4",
+			roomType:    "Single",
+			capacity:    "0",
+			expectError: true,
+		},
+		{
+			name:        "Negative default rent",
+			roomName:    "Room 5",
+			roomType:    "Single",
+			defaultRent: "-100",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			capacity, rent, err := utils.ParseRoomDetails(tc.roomName, tc.roomType, tc.capacity, tc.defaultRent)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if capacity != tc.expectedCapacity || rent != tc.expectedRent {
+				t.Errorf("Expected %d/%s, got %d/%s", tc.expectedCapacity, tc.expectedRent, capacity, rent)
+			}
+		})
+	}
+}
+
+func TestValidateMaintenanceTicket(t *testing.T) {
+	testCases := []struct {
+		name        string
+		category    string
+		priority    string
+		description string
+		expectError bool
+	}{
+		{
+			name:        "Valid ticket",
+			category:    "plumbing",
+			priority:    "high",
+			description: "The kitchen tap is leaking",
+		},
+		{
+			name:        "Unknown category",
+			category:    "gardening",
+			priority:    "low",
+			description: "The hedge needs trimming",
+			expectError: true,
+		},
+		{
+			name:        "Unknown priority",
+			category:    "electrical",
+			priority:    "whenever",
+			description: "The hallway light is flickering",
+			expectError: true,
+		},
+		{
+			name:        "Blank description",
+			category:    "heating",
+			priority:    "urgent",
+			description: "   ",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := utils.ValidateMaintenanceTicket(tc.category, tc.priority, tc.description)
+			if tc.expectError && err == nil {
+				t.Errorf("Expected error but got nil")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateTicketTransition(t *testing.T) {
+	testCases := []struct {
+		name        string
+		from        string
+		to          string
+		expectError bool
+	}{
+		{name: "Open to scheduled", from: "open", to: "scheduled"},
+		{name: "Open to in progress", from: "open", to: "in_progress"},
+		{name: "Scheduled to resolved", from: "scheduled", to: "resolved"},
+		{name: "Reschedule", from: "scheduled", to: "scheduled"},
+		{name: "In progress back to open", from: "in_progress", to: "open", expectError: true},
+		{name: "Resolved is final", from: "resolved", to: "resolved", expectError: true},
+		{name: "Unknown status", from: "open", to: "closed", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := utils.ValidateTicketTransition(tc.from, tc.to)
+			if tc.expectError && err == nil {
+				t.Errorf("Expected error but got nil")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptionKeyRotation(t *testing.T) {
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	masterKey := "0123456789abcdef0123456789abcdef"
+	oldKey := base64.StdEncoding.EncodeToString([]byte("old-key-old-key-old-key-old-key!"))
+	newKey := base64.StdEncoding.EncodeToString([]byte("new-key-new-key-new-key-new-key!"))
+
+	t.Setenv("MASTER_KEY", masterKey)
+	t.Setenv("ENCRYPTION_KEYS", "2026-01:"+oldKey)
+	t.Setenv("ENCRYPTION_KEY_ID", "")
+	if err := utils.InitEncryption(); err != nil {
+		t.Fatalf("Failed to initialise encryption: %v", err)
+	}
+	if utils.ActiveKeyID() != "2026-01" {
+		t.Fatalf("Expected the last listed key to be active, got %s", utils.ActiveKeyID())
+	}
+
+	// data encrypted before key IDs were stored: nonce + ciphertext under the master key
+	block, _ := aes.NewCipher([]byte(masterKey))
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	legacy := append(nonce, gcm.Seal(nil, nonce, []byte("legacy secret"), nil)...)
+
+	oldCiphertext, err := utils.Encrypt([]byte("old secret"))
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	// rotate to a new key, keeping the old key listed so existing data can still be read
+	t.Setenv("ENCRYPTION_KEYS", "2026-01:"+oldKey+",2026-10:"+newKey)
+	if err := utils.InitEncryption(); err != nil {
+		t.Fatalf("Failed to initialise encryption: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		ciphertext []byte
+		expected   string
+	}{
+		{name: "Legacy master key data", ciphertext: legacy, expected: "legacy secret"},
+		{name: "Data under the previous key", ciphertext: oldCiphertext, expected: "old secret"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !utils.NeedsReencryption(tc.ciphertext) {
+				t.Errorf("Expected data under an old key to need re-encryption")
+			}
+
+			reencrypted, err := utils.Reencrypt(tc.ciphertext)
+			if err != nil {
+				t.Fatalf("Failed to re-encrypt: %v", err)
+			}
+			if utils.NeedsReencryption(reencrypted) {
+				t.Errorf("Expected re-encrypted data to be under the active key")
+			}
+
+			plaintext, err := utils.Decrypt(reencrypted)
+			if err != nil {
+				t.Fatalf("Failed to decrypt: %v", err)
+			}
+			if string(plaintext) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, plaintext)
+			}
+		})
+	}
+
+	t.Run("Tampered key ID", func(t *testing.T) {
+		t.Setenv("MASTER_KEY", "")
+		t.Setenv("ENCRYPTION_KEY_ID", "2026-10")
+		if err := utils.InitEncryption(); err != nil {
+			t.Fatalf("Failed to initialise encryption: %v", err)
+		}
+		ciphertext, _ := utils.Encrypt([]byte("secret"))
+		tampered := bytes.Replace(ciphertext, []byte("2026-10"), []byte("2026-01"), 1)
+		if _, err := utils.Decrypt(tampered); err == nil {
+			t.Errorf("Expected an error decrypting data with a swapped key ID")
+		}
+	})
+
+	t.Run("Unknown active key", func(t *testing.T) {
+		t.Setenv("ENCRYPTION_KEY_ID", "2027-01")
+		if err := utils.InitEncryption(); err == nil {
+			t.Errorf("Expected an error for an active key that is not loaded")
+		}
+	})
+}
+
+func TestIsBcryptHash(t *testing.T) {
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	bcryptHash, err := utils.HashedPassword("password123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		hash     string
+		expected bool
+	}{
+		{name: "Bcrypt hash", hash: bcryptHash, expected: true},
+		{name: "Legacy SHA-256 hash", hash: utils.HashData("password123"), expected: false},
+		{name: "Empty hash", hash: "", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := utils.IsBcryptHash(tc.hash); result != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	// the SHA-1 test secret from RFC 6238, "12345678901234567890" in base32
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	// RFC 6238 test vectors, truncated to 6 digits
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+	for _, v := range vectors {
+		code, err := utils.TOTPCode(secret, time.Unix(v.unix, 0))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if code != v.code {
+			t.Errorf("At %d expected code %s, got %s", v.unix, v.code, code)
+		}
+	}
+
+	now := time.Unix(1234567890, 0)
+	nowStep := now.Unix() / 30
+	previous, _ := utils.TOTPCode(secret, now.Add(-30*time.Second))
+	tooOld, _ := utils.TOTPCode(secret, now.Add(-90*time.Second))
+
+	testCases := []struct {
+		name         string
+		code         string
+		lastUsedStep int64
+		expectedStep int64
+		expected     bool
+	}{
+		{name: "Current code", code: "005924", expectedStep: nowStep, expected: true},
+		{name: "Code with spaces", code: "005 924", expectedStep: nowStep, expected: true},
+		{name: "Previous period allowed for clock drift", code: previous, expectedStep: nowStep - 1, expected: true},
+		{name: "Code outside the drift window", code: tooOld, expected: false},
+		{name: "Replayed code", code: "005924", lastUsedStep: nowStep, expected: false},
+		{name: "Wrong code", code: "123456", expected: false},
+		{name: "Wrong length", code: "5924", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			step, ok := utils.ValidateTOTP(secret, tc.code, now, tc.lastUsedStep)
+			if ok != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, ok)
+			}
+			if ok && step != tc.expectedStep {
+				t.Errorf("Expected step %d, got %d", tc.expectedStep, step)
+			}
+		})
+	}
+
+	generated, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("Failed to generate TOTP secret: %v", err)
+	}
+	code, _ := utils.TOTPCode(generated, now)
+	if _, ok := utils.ValidateTOTP(generated, code, now, 0); !ok {
+		t.Errorf("Expected a code from a generated secret to be valid")
+	}
+
+	uri := utils.TOTPURI("Lily's Hidden Paradise", "landlord@example.com", secret)
+	expectedURI := "otpauth://totp/Lily%27s%20Hidden%20Paradise:landlord@example.com?algorithm=SHA1&digits=6&issuer=Lily%27s+Hidden+Paradise&period=30&secret=" + secret
+	if uri != expectedURI {
+		t.Errorf("Expected URI %s, got %s", expectedURI, uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	codes, err := utils.GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("Failed to generate recovery codes: %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("Expected 10 recovery codes, got %d", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("Unexpected recovery code format: %s", code)
+		}
+		if seen[code] {
+			t.Errorf("Duplicate recovery code: %s", code)
+		}
+		seen[code] = true
+
+		// codes typed in capitals, without the dash or with spaces still match
+		for _, typed := range []string{strings.ToUpper(code), strings.ReplaceAll(code, "-", ""), " " + code + " "} {
+			if normalised := utils.NormaliseRecoveryCode(typed); normalised != code {
+				t.Errorf("Expected %s to normalise to %s, got %s", typed, code, normalised)
+			}
+		}
+	}
+}
+
+func TestSignedToken(t *testing.T) {
+	testutil.InitTestEnv()
+	go logs.LogProcessor()
+
+	t.Setenv("MASTER_KEY", "0123456789abcdef0123456789abcdef")
+	t.Setenv("ENCRYPTION_KEYS", "")
+	t.Setenv("ENCRYPTION_KEY_ID", "")
+	t.Setenv("SIGNING_KEY", "")
+	if err := utils.InitEncryption(); err != nil {
+		t.Fatalf("Failed to initialise encryption: %v", err)
+	}
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	token := utils.SignToken("landlord-invitation", "abc123", now.Add(time.Hour))
+
+	value, err := utils.VerifySignedToken("landlord-invitation", token, now)
+	if err != nil || value != "abc123" {
+		t.Fatalf("Expected abc123, got %q, %v", value, err)
+	}
+
+	// value, expiry and signature
+	parts := strings.Split(token, ".")
+	tests := []struct {
+		name     string
+		purpose  string
+		token    string
+		now      time.Time
+		expected error
+	}{
+		{"expired", "landlord-invitation", token, now.Add(time.Hour), utils.ErrExpiredSignedToken},
+		{"other purpose", "password-reset", token, now, utils.ErrInvalidSignedToken},
+		{"changed value", "landlord-invitation", "xyz789." + parts[1] + "." + parts[2], now, utils.ErrInvalidSignedToken},
+		{"changed expiry", "landlord-invitation", parts[0] + ".9999999999." + parts[2], now, utils.ErrInvalidSignedToken},
+		{"not signed", "landlord-invitation", "abc123", now, utils.ErrInvalidSignedToken},
+		{"empty", "landlord-invitation", "", now, utils.ErrInvalidSignedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := utils.VerifySignedToken(tt.purpose, tt.token, tt.now)
+			if err != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	// tokens signed with one key are not accepted with another
+	t.Setenv("SIGNING_KEY", "another-signing-key-another-signing-key")
+	if err := utils.InitEncryption(); err != nil {
+		t.Fatalf("Failed to initialise encryption: %v", err)
+	}
+	if _, err := utils.VerifySignedToken("landlord-invitation", token, now); err != utils.ErrInvalidSignedToken {
+		t.Errorf("Expected a token signed with another key to be rejected, got %v", err)
+	}
+}